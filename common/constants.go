@@ -126,6 +126,8 @@ var CohereSafetySetting string
 
 const (
 	RequestIdKey = "X-Oneapi-Request-Id"
+	// WarningHeader 请求中被忽略的参数等提示信息
+	WarningHeader = "X-Oneapi-Warning"
 )
 
 const (
@@ -174,6 +176,11 @@ const (
 	UserStatusDisabled = 2 // also don't use 0
 )
 
+const (
+	UserBillingModePrepaid  = "prepaid"  // 预付费，额度用完后停止服务
+	UserBillingModePostpaid = "postpaid" // 后付费，允许在信用额度内透支，按月生成账单
+)
+
 const (
 	TokenStatusEnabled   = 1 // don't use 0, 0 is the default value!
 	TokenStatusDisabled  = 2 // also don't use 0
@@ -241,6 +248,11 @@ const (
 	ChannelTypeXinference     = 47
 	ChannelTypeXai            = 48
 	ChannelTypeCoze           = 49
+	ChannelTypeBaichuan       = 50
+	ChannelTypeSageMaker      = 51
+	ChannelTypeAzureAI        = 52
+	ChannelTypeNvidia         = 53
+	ChannelTypeVoyage         = 54
 	ChannelTypeDummy          // this one is only for count, do not add any channel after this
 
 )
@@ -296,4 +308,9 @@ var ChannelBaseURLs = []string{
 	"",                                          //47
 	"https://api.x.ai",                          //48
 	"https://api.coze.cn",                       //49
+	"https://api.baichuan-ai.com",               //50
+	"",                                          //51
+	"https://models.inference.ai.azure.com",     //52
+	"https://integrate.api.nvidia.com",          //53
+	"https://api.voyageai.com",                  //54
 }
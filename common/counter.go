@@ -0,0 +1,91 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 计数器用于按自然周期统计的限制（例如每日请求数、每日消费），启用 Redis 时在多个节点间共享，否则只在本节点内存中计数
+
+type counterEntry struct {
+	value    int64
+	expireAt time.Time
+}
+
+var counters = make(map[string]*counterEntry)
+var countersLock sync.Mutex
+var counterCleanupOnce sync.Once
+
+// CounterGet 返回计数器的值，不存在或已过期时为 0
+func CounterGet(key string) (int64, error) {
+	if RedisEnabled {
+		value, err := RDB.Get(context.Background(), key).Int64()
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return value, err
+	}
+	countersLock.Lock()
+	defer countersLock.Unlock()
+	entry, ok := counters[key]
+	if !ok || time.Now().After(entry.expireAt) {
+		return 0, nil
+	}
+	return entry.value, nil
+}
+
+// CounterIncr 增加计数器的值并返回增加后的值，计数器不存在时创建并设置过期时间。delta 为负数时只减少已存在的计数器，
+// 避免跨过周期的退还影响新周期的统计
+func CounterIncr(key string, delta int64, expiration time.Duration) (int64, error) {
+	if RedisEnabled {
+		ctx := context.Background()
+		if delta < 0 {
+			exists, err := RDB.Exists(ctx, key).Result()
+			if err != nil || exists == 0 {
+				return 0, err
+			}
+		}
+		value, err := RDB.IncrBy(ctx, key, delta).Result()
+		if err != nil {
+			return 0, err
+		}
+		if value == delta {
+			return value, RDB.Expire(ctx, key, expiration).Err()
+		}
+		return value, nil
+	}
+	counterCleanupOnce.Do(startCounterCleanupTask)
+	now := time.Now()
+	countersLock.Lock()
+	defer countersLock.Unlock()
+	entry, ok := counters[key]
+	if !ok || now.After(entry.expireAt) {
+		if delta < 0 {
+			return 0, nil
+		}
+		entry = &counterEntry{expireAt: now.Add(expiration)}
+		counters[key] = entry
+	}
+	entry.value += delta
+	return entry.value, nil
+}
+
+func startCounterCleanupTask() {
+	go func() {
+		for {
+			time.Sleep(time.Hour)
+			now := time.Now()
+			countersLock.Lock()
+			for key, entry := range counters {
+				if now.After(entry.expireAt) {
+					delete(counters, key)
+				}
+			}
+			countersLock.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EmbeddedCacheEnabled serves the Redis backed caches from process memory when
+// REDIS_CONN_STRING is not set. It is only consistent for a single instance.
+var EmbeddedCacheEnabled = false
+
+var ErrEmbeddedCacheNil = errors.New("embedded cache: nil")
+
+type embeddedCacheEntry struct {
+	value    string
+	hash     map[string]string
+	expireAt time.Time
+}
+
+func (e *embeddedCacheEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && now.After(e.expireAt)
+}
+
+type embeddedCache struct {
+	mutex sync.Mutex
+	store map[string]*embeddedCacheEntry
+}
+
+var embeddedStore = &embeddedCache{
+	store: make(map[string]*embeddedCacheEntry),
+}
+var embeddedCleanupOnce sync.Once
+
+// CacheEnabled reports whether hot data (users, tokens) can be read from a
+// cache instead of the database
+func CacheEnabled() bool {
+	return RedisEnabled || EmbeddedCacheEnabled
+}
+
+func initEmbeddedCache() {
+	embeddedCleanupOnce.Do(func() {
+		go func() {
+			for {
+				time.Sleep(time.Minute)
+				embeddedStore.clearExpired()
+			}
+		}()
+	})
+}
+
+func (c *embeddedCache) clearExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now()
+	for key, entry := range c.store {
+		if entry.expired(now) {
+			delete(c.store, key)
+		}
+	}
+}
+
+// get must be called with the mutex held
+func (c *embeddedCache) get(key string) (*embeddedCacheEntry, bool) {
+	entry, ok := c.store[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expired(time.Now()) {
+		delete(c.store, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+func expireAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(expiration)
+}
+
+func embeddedSet(key string, value string, expiration time.Duration) error {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	embeddedStore.store[key] = &embeddedCacheEntry{
+		value:    value,
+		expireAt: expireAt(expiration),
+	}
+	return nil
+}
+
+func embeddedGet(key string) (string, error) {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	entry, ok := embeddedStore.get(key)
+	if !ok || entry.hash != nil {
+		return "", ErrEmbeddedCacheNil
+	}
+	return entry.value, nil
+}
+
+func embeddedDel(key string) error {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	delete(embeddedStore.store, key)
+	return nil
+}
+
+func embeddedHSetObj(key string, obj interface{}, expiration time.Duration) error {
+	hash := make(map[string]string)
+	for field, value := range structToRedisHash(obj) {
+		hash[field] = fmt.Sprintf("%v", value)
+	}
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	embeddedStore.store[key] = &embeddedCacheEntry{
+		hash:     hash,
+		expireAt: expireAt(expiration),
+	}
+	return nil
+}
+
+func embeddedHGetObj(key string, obj interface{}) error {
+	embeddedStore.mutex.Lock()
+	entry, ok := embeddedStore.get(key)
+	var result map[string]string
+	if ok && entry.hash != nil {
+		result = make(map[string]string, len(entry.hash))
+		for field, value := range entry.hash {
+			result[field] = value
+		}
+	}
+	embeddedStore.mutex.Unlock()
+	if len(result) == 0 {
+		return fmt.Errorf("key %s not found in embedded cache", key)
+	}
+	return redisHashToStruct(result, obj)
+}
+
+// embeddedIncr follows RedisIncr and only touches keys that are still alive
+func embeddedIncr(key string, delta int64) error {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	entry, ok := embeddedStore.get(key)
+	if !ok || entry.hash != nil {
+		return nil
+	}
+	value, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("value of %s is not an integer: %w", key, err)
+	}
+	entry.value = strconv.FormatInt(value+delta, 10)
+	return nil
+}
+
+func embeddedHIncrBy(key, field string, delta int64) error {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	entry, ok := embeddedStore.get(key)
+	if !ok || entry.hash == nil {
+		return nil
+	}
+	value, err := strconv.ParseInt(entry.hash[field], 10, 64)
+	if err != nil && entry.hash[field] != "" {
+		return fmt.Errorf("field %s of %s is not an integer: %w", field, key, err)
+	}
+	entry.hash[field] = strconv.FormatInt(value+delta, 10)
+	return nil
+}
+
+func embeddedHSetField(key, field string, value interface{}) error {
+	embeddedStore.mutex.Lock()
+	defer embeddedStore.mutex.Unlock()
+	entry, ok := embeddedStore.get(key)
+	if !ok || entry.hash == nil {
+		return nil
+	}
+	entry.hash[field] = fmt.Sprintf("%v", value)
+	return nil
+}
@@ -5,11 +5,27 @@ import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"io"
+	"mime/multipart"
 	"strings"
 )
 
 const KeyRequestBody = "key_request_body"
 
+// multipartMemoryLimit 解析 multipart 表单时内存中最多保留的大小，超出的文件写入临时文件，请求结束后由 net/http 清理
+const multipartMemoryLimit = 1 << 20
+
+// ParseMultipartFormReusable 解析 multipart 表单，音频等大文件不会整个读入内存，重复调用时返回已解析的表单
+func ParseMultipartFormReusable(c *gin.Context) (*multipart.Form, error) {
+	if c.Request.MultipartForm != nil {
+		return c.Request.MultipartForm, nil
+	}
+	err := c.Request.ParseMultipartForm(multipartMemoryLimit)
+	if err != nil {
+		return nil, err
+	}
+	return c.Request.MultipartForm, nil
+}
+
 func GetRequestBody(c *gin.Context) ([]byte, error) {
 	requestBody, _ := c.Get(KeyRequestBody)
 	if requestBody != nil {
@@ -21,7 +21,8 @@ func printHelp() {
 	fmt.Println("New API " + Version + " - All in one API service for OpenAI API.")
 	fmt.Println("Copyright (C) 2023 JustSong. All rights reserved.")
 	fmt.Println("GitHub: https://github.com/songquanpeng/one-api")
-	fmt.Println("Usage: one-api [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("Usage: one-api [--port <port>] [--log-dir <log directory>] [--version] [--help] [doctor]")
+	fmt.Println("  doctor: check configuration, database, Redis, channel keys, port and tokenizer, then exit")
 }
 
 func LoadEnv() {
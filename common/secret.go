@@ -0,0 +1,32 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretRefPrefix 以该前缀开头的值是对外部密钥的引用，例如 secret://channel-1，
+// 运行时先读取环境变量 SECRET_CHANNEL_1，不存在时读取 SECRET_DIR（默认 /run/secrets）下的同名文件
+const SecretRefPrefix = "secret://"
+
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+func ResolveSecretRef(value string) (string, error) {
+	name := strings.TrimPrefix(value, SecretRefPrefix)
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret reference: %s", value)
+	}
+	envName := "SECRET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	if secret := os.Getenv(envName); secret != "" {
+		return secret, nil
+	}
+	data, err := os.ReadFile(filepath.Join(GetEnvOrDefaultString("SECRET_DIR", "/run/secrets"), name))
+	if err != nil {
+		return "", fmt.Errorf("secret %s not found in env %s or secret dir: %w", name, envName, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
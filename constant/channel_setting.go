@@ -1,7 +1,28 @@
 package constant
 
 var (
-	ForceFormat                     = "force_format"        // ForceFormat 强制格式化为OpenAI格式
-	ChanelSettingProxy              = "proxy"               // Proxy 代理
-	ChannelSettingThinkingToContent = "thinking_to_content" // ThinkingToContent
+	ForceFormat                        = "force_format"           // ForceFormat 强制格式化为OpenAI格式
+	ChanelSettingProxy                 = "proxy"                  // Proxy 代理
+	ChannelSettingThinkingToContent    = "thinking_to_content"    // ThinkingToContent
+	ChannelSettingIgnoreProviderStatus = "ignore_provider_status" // IgnoreProviderStatus 供应商故障时不自动暂停
+	ChannelSettingProviderStatusRegion = "provider_status_region" // ProviderStatusRegion 渠道所在区域，只有状态页事件提到该区域时才暂停
+	ChannelSettingRequestPathTemplate  = "request_path_template"  // RequestPathTemplate 请求地址模板
+	ChannelSettingRemoveParams         = "remove_params"          // RemoveParams 转发前移除的请求参数
+	ChannelSettingUpstreamModelMapping = "upstream_model_mapping" // UpstreamModelMapping 发送给上游的模型名称映射
+	ChannelSettingSageMakerTemplate    = "sagemaker_template"     // SageMakerTemplate SageMaker 请求/响应模板
+	ChannelSettingBatchEnabled         = "batch_enabled"          // BatchEnabled 允许转发批量请求
+	ChannelSettingImageEndpoints       = "image_endpoints"        // ImageEndpoints 支持的图片编辑、变体接口
+	ChannelSettingOutputRules          = "output_rules"           // OutputRules 响应内容后处理规则
+	ChannelSettingRerankBilling        = "rerank_billing"         // RerankBilling 重排序计费方式
+	ChannelSettingEmbeddingBatchSize   = "embedding_batch_size"   // EmbeddingBatchSize 单次嵌入请求的最大输入数量
+	ChannelSettingAssistantsEnabled    = "assistants_enabled"     // AssistantsEnabled 允许转发 Assistants API
+	ChannelSettingChatViaResponses     = "chat_via_responses"     // ChatViaResponses 通过 /v1/responses 转发对话请求
+	ChannelSettingJsonSchemaFallback   = "json_schema_fallback"   // JsonSchemaFallback json_schema 降级为 JSON 模式
+	ChannelSettingImageUrlPassthrough  = "image_url_passthrough"  // ImageUrlPassthrough 远程图片地址直接传给上游
+	ChannelSettingCompletionsViaChat   = "completions_via_chat"   // CompletionsViaChat 通过 /v1/chat/completions 转发 completions 请求
+	ChannelSettingStreamUpstream       = "stream_upstream"        // StreamUpstream 非流式请求以流式调用上游
+	ChannelSettingFakeStream           = "fake_stream"            // FakeStream 流式请求以非流式调用上游，再分段模拟流式返回
+	ChannelSettingReasoningFormat      = "reasoning_format"       // ReasoningFormat 思考内容的返回方式
+	ChannelSettingStripExtraFields     = "strip_extra_fields"     // StripExtraFields 不转发请求中未识别的字段
+	ChannelSettingTransparent          = "transparent"            // Transparent 不需要转换格式时原样返回上游的响应
 )
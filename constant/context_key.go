@@ -7,4 +7,12 @@ const (
 	ContextKeyUserStatus       = "user_status"
 	ContextKeyUserEmail        = "user_email"
 	ContextKeyUserGroup        = "user_group"
+	// ContextKeySubscriptionPlan 用户当前生效的订阅套餐
+	ContextKeySubscriptionPlan = "subscription_plan"
+
+	ContextKeyModerationFlaggedCategories = "moderation_flagged_categories"
+	// ContextKeyInlineImageCache 请求中已下载的远程图片，重试其他渠道时不重复下载
+	ContextKeyInlineImageCache = "inline_image_cache"
+	// ContextKeyServiceTier 上游返回的实际服务等级，上游没有返回时按标准价格计费
+	ContextKeyServiceTier = "service_tier"
 )
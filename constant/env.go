@@ -2,6 +2,7 @@ package constant
 
 import (
 	"one-api/common"
+	"strings"
 )
 
 var StreamingTimeout int
@@ -17,6 +18,28 @@ var NotifyLimitCount int
 var NotificationLimitDurationMinute int
 var GenerateDefaultToken bool
 var ErrorLogEnabled bool
+var CORSEnabled bool
+var CORSMaxAge int
+var RelayCORSAllowOrigins string
+var RelayCORSAllowHeaders string
+var ApiCORSAllowOrigins string
+var ApiCORSAllowHeaders string
+var QuotaHoldExpireMinutes int
+var LegacyKeyAuthMaxExpireSeconds int
+var QuotaHoldTrustEnabled bool
+var BudgetAlertCheckIntervalMinutes int
+var DeploymentEnvironment string
+var ShadowSourceAddress string
+var ShadowSourceAccessToken string
+var ShadowSourceUserId string
+var FileStorage string
+var FileStoragePath string
+var FileStorageS3Endpoint string
+var FileStorageS3Bucket string
+var FileStorageS3Region string
+var FileStorageS3AccessKey string
+var FileStorageS3SecretKey string
+var FileStorageUserQuotaMB int
 
 //var GeminiModelMap = map[string]string{
 //	"gemini-1.0-pro": "v1",
@@ -39,6 +62,36 @@ func InitEnv() {
 	GenerateDefaultToken = common.GetEnvOrDefaultBool("GENERATE_DEFAULT_TOKEN", false)
 	// 是否启用错误日志
 	ErrorLogEnabled = common.GetEnvOrDefaultBool("ERROR_LOG_ENABLED", false)
+	// CORS 配置，仅服务端调用的部署可以直接关闭
+	CORSEnabled = common.GetEnvOrDefaultBool("CORS_ENABLED", true)
+	CORSMaxAge = common.GetEnvOrDefault("CORS_MAX_AGE", 43200)
+	RelayCORSAllowOrigins = common.GetEnvOrDefaultString("RELAY_CORS_ALLOW_ORIGINS", "*")
+	RelayCORSAllowHeaders = common.GetEnvOrDefaultString("RELAY_CORS_ALLOW_HEADERS", "*")
+	ApiCORSAllowOrigins = common.GetEnvOrDefaultString("API_CORS_ALLOW_ORIGINS", "")
+	ApiCORSAllowHeaders = common.GetEnvOrDefaultString("API_CORS_ALLOW_HEADERS", "*")
+	// 签名查询参数的过期时间最多比当前时间晚多少秒
+	LegacyKeyAuthMaxExpireSeconds = common.GetEnvOrDefault("LEGACY_KEY_AUTH_MAX_EXPIRE_SECONDS", 86400)
+	// 预扣额度超过该时长仍未结算时自动退还，0 表示不自动退还
+	QuotaHoldExpireMinutes = common.GetEnvOrDefault("QUOTA_HOLD_EXPIRE_MINUTES", 60)
+	// 用户和令牌额度远大于预估额度时不预扣，默认关闭，所有请求都先预扣再结算
+	QuotaHoldTrustEnabled = common.GetEnvOrDefaultBool("QUOTA_HOLD_TRUST_ENABLED", false)
+	// 预算预警的检查间隔，0 表示不检查
+	BudgetAlertCheckIntervalMinutes = common.GetEnvOrDefault("BUDGET_ALERT_CHECK_INTERVAL_MINUTES", 5)
+	// 部署环境，非 production 环境可以从生产环境导入只读的影子渠道
+	DeploymentEnvironment = common.GetEnvOrDefaultString("DEPLOYMENT_ENVIRONMENT", "production")
+	ShadowSourceAddress = strings.TrimSuffix(common.GetEnvOrDefaultString("SHADOW_SOURCE_ADDRESS", ""), "/")
+	ShadowSourceAccessToken = common.GetEnvOrDefaultString("SHADOW_SOURCE_ACCESS_TOKEN", "")
+	ShadowSourceUserId = common.GetEnvOrDefaultString("SHADOW_SOURCE_USER_ID", "1")
+	// /v1/files 上传的文件保存位置：db（数据库）、local（本地目录）或 s3（S3 兼容的对象存储）
+	FileStorage = common.GetEnvOrDefaultString("FILE_STORAGE", "db")
+	FileStoragePath = common.GetEnvOrDefaultString("FILE_STORAGE_PATH", "files")
+	FileStorageS3Endpoint = strings.TrimSuffix(common.GetEnvOrDefaultString("FILE_STORAGE_S3_ENDPOINT", ""), "/")
+	FileStorageS3Bucket = common.GetEnvOrDefaultString("FILE_STORAGE_S3_BUCKET", "")
+	FileStorageS3Region = common.GetEnvOrDefaultString("FILE_STORAGE_S3_REGION", "us-east-1")
+	FileStorageS3AccessKey = common.GetEnvOrDefaultString("FILE_STORAGE_S3_ACCESS_KEY", "")
+	FileStorageS3SecretKey = common.GetEnvOrDefaultString("FILE_STORAGE_S3_SECRET_KEY", "")
+	// 每个用户上传文件的总大小上限，0 表示不限制
+	FileStorageUserQuotaMB = common.GetEnvOrDefault("FILE_STORAGE_USER_QUOTA_MB", 0)
 
 	//modelVersionMapStr := strings.TrimSpace(os.Getenv("GEMINI_MODEL_MAP"))
 	//if modelVersionMapStr == "" {
@@ -5,6 +5,8 @@ type TaskPlatform string
 const (
 	TaskPlatformSuno       TaskPlatform = "suno"
 	TaskPlatformMidjourney              = "mj"
+	TaskPlatformBatch                   = "batch"
+	TaskPlatformAssistants              = "assistants"
 )
 
 const (
@@ -12,6 +14,16 @@ const (
 	SunoActionLyrics = "LYRICS"
 )
 
+const (
+	BatchActionChatCompletions = "CHAT_COMPLETIONS"
+	// BatchActionGateway 没有开启批量请求的渠道时由网关逐行转发
+	BatchActionGateway = "GATEWAY"
+)
+
+const (
+	AssistantsActionRun = "RUN"
+)
+
 var SunoModel2Action = map[string]string{
 	"suno_music":  SunoActionMusic,
 	"suno_lyrics": SunoActionLyrics,
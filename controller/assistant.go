@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/middleware"
+	"one-api/model"
+	"one-api/relay"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func isAssistantsChannel(channel *model.Channel) bool {
+	if channel.Status != common.ChannelStatusEnabled {
+		return false
+	}
+	enabled, _ := channel.GetSetting()[constant.ChannelSettingAssistantsEnabled].(bool)
+	return enabled
+}
+
+// getObjectChannel 返回用户的 assistant 或 thread 所在的渠道，不属于该用户时返回 404
+func getObjectChannel(userId int, objectId string) (*model.Channel, int, error) {
+	object, err := model.GetAssistantObject(userId, objectId)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("No such object: '%s'", objectId)
+	}
+	channel, err := model.CacheGetChannel(object.ChannelId)
+	if err != nil || !isAssistantsChannel(channel) {
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("对象 %s 所在的渠道 #%d 不可用", objectId, object.ChannelId)
+	}
+	return channel, 0, nil
+}
+
+// getNewObjectChannel 为新建的对象选择渠道：优先使用用户最近创建的对象所在的渠道，
+// 使 assistant 和 thread 尽量位于同一个上游账户，否则按优先级选择第一个开启了 Assistants API 的渠道
+func getNewObjectChannel(c *gin.Context, group string, modelName string) (*model.Channel, error) {
+	if channelId := c.GetInt("channel_id"); channelId != 0 {
+		channel, err := model.CacheGetChannel(channelId)
+		if err != nil {
+			return nil, err
+		}
+		if !isAssistantsChannel(channel) {
+			return nil, fmt.Errorf("渠道 #%d 未开启 Assistants API", channelId)
+		}
+		return channel, nil
+	}
+	var channels []*model.Channel
+	var err error
+	if modelName != "" {
+		channels, err = model.GetSatisfiedChannels(group, modelName)
+	} else {
+		channels, err = model.GetGroupChannels(group)
+	}
+	if err != nil {
+		return nil, err
+	}
+	latest, err := model.GetLatestAssistantObject(c.GetInt("id"))
+	if err != nil {
+		return nil, err
+	}
+	var selected *model.Channel
+	for _, channel := range channels {
+		if !isAssistantsChannel(channel) {
+			continue
+		}
+		if latest != nil && channel.Id == latest.ChannelId {
+			return channel, nil
+		}
+		if selected == nil {
+			selected = channel
+		}
+	}
+	if selected == nil {
+		if modelName != "" {
+			return nil, fmt.Errorf("当前分组 %s 下对于模型 %s 无开启 Assistants API 的渠道", group, modelName)
+		}
+		return nil, fmt.Errorf("当前分组 %s 下无开启 Assistants API 的渠道", group)
+	}
+	return selected, nil
+}
+
+// getAssistantChannel 按请求访问的对象选择渠道，返回的状态码用于错误响应
+func getAssistantChannel(c *gin.Context) (*model.Channel, int, error) {
+	userId := c.GetInt("id")
+	path := strings.Split(strings.Trim(strings.TrimPrefix(c.Request.URL.Path, "/v1/"), "/"), "/")
+	switch {
+	case len(path) >= 2 && !(path[0] == "threads" && path[1] == "runs"):
+		// /v1/assistants/{assistant_id}、/v1/threads/{thread_id}/...
+		if c.Request.Method == http.MethodPost && len(path) == 3 && path[2] == "runs" {
+			// POST /v1/threads/{thread_id}/runs 使用的 assistant 也必须属于该用户
+			var request struct {
+				AssistantId string `json:"assistant_id"`
+			}
+			if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			if request.AssistantId == "" {
+				return nil, http.StatusBadRequest, errors.New("assistant_id is required")
+			}
+			if _, err := model.GetAssistantObject(userId, request.AssistantId); err != nil {
+				return nil, http.StatusNotFound, fmt.Errorf("No such assistant: '%s'", request.AssistantId)
+			}
+		}
+		return getObjectChannel(userId, path[1])
+	case len(path) == 2:
+		// POST /v1/threads/runs 使用 assistant 所在的渠道
+		var request struct {
+			AssistantId string `json:"assistant_id"`
+		}
+		if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if request.AssistantId == "" {
+			return nil, http.StatusBadRequest, errors.New("assistant_id is required")
+		}
+		return getObjectChannel(userId, request.AssistantId)
+	case c.Request.Method == http.MethodGet:
+		// 列出 assistant 时使用最近创建的对象所在的渠道，上游返回的列表会过滤为用户自己的
+		latest, err := model.GetLatestAssistantObject(userId)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if latest == nil {
+			return nil, 0, nil
+		}
+		return getObjectChannel(userId, latest.ObjectId)
+	}
+	channel, err := getNewObjectChannel(c, c.GetString("group"), c.GetString("original_model"))
+	if err != nil {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	return channel, 0, nil
+}
+
+func RelayAssistants(c *gin.Context) {
+	channel, statusCode, err := getAssistantChannel(c)
+	if err != nil {
+		batchError(c, statusCode, "get_channel_failed", err)
+		return
+	}
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"object":   "list",
+			"data":     []any{},
+			"first_id": nil,
+			"last_id":  nil,
+			"has_more": false,
+		})
+		return
+	}
+	middleware.SetupContextForSelectedChannel(c, channel, c.GetString("original_model"))
+	openaiErr := relay.AssistantHelper(c)
+	if openaiErr != nil {
+		openaiErr.Error.Message = common.MessageWithRequestId(openaiErr.Error.Message, c.GetString(common.RequestIdKey))
+		c.JSON(openaiErr.StatusCode, gin.H{
+			"error": openaiErr.Error,
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/middleware"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/channel/task/batch"
+	"one-api/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传时读取整个文件，限制单个文件的大小
+const maxBatchFileSize = 100 << 20
+
+// filePurposes 支持上传的文件用途，目前只有 batch 由网关使用，其余用途的文件供后续转发使用
+var filePurposes = map[string]bool{
+	"batch":      true,
+	"assistants": true,
+	"fine-tune":  true,
+	"vision":     true,
+	"user_data":  true,
+	"evals":      true,
+}
+
+func batchError(c *gin.Context, statusCode int, code string, err error) {
+	c.JSON(statusCode, gin.H{
+		"error": dto.OpenAIError{
+			Message: common.MessageWithRequestId(err.Error(), c.GetString(common.RequestIdKey)),
+			Type:    "invalid_request_error",
+			Code:    code,
+		},
+	})
+}
+
+func file2Dto(file *model.File) *dto.OpenAIFile {
+	return &dto.OpenAIFile{
+		Id:        file.FileId,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+	}
+}
+
+// getBatchOutputFile 旧的批量任务的输出文件和错误文件没有单独保存，通过文件 id 前缀从任务中读取
+func getBatchOutputFile(c *gin.Context, fileId string) (*dto.OpenAIFile, string, bool, error) {
+	var prefix string
+	if strings.HasPrefix(fileId, service.BatchOutputFilePrefix) {
+		prefix = service.BatchOutputFilePrefix
+	} else if strings.HasPrefix(fileId, service.BatchErrorFilePrefix) {
+		prefix = service.BatchErrorFilePrefix
+	} else {
+		return nil, "", false, nil
+	}
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), strings.TrimPrefix(fileId, prefix))
+	if err != nil {
+		return nil, "", true, err
+	}
+	if !exist || task.Platform != constant.TaskPlatformBatch {
+		return nil, "", true, errors.New("文件不存在")
+	}
+	var data dto.BatchTaskData
+	_ = task.GetData(&data)
+	content := data.Output
+	if prefix == service.BatchErrorFilePrefix {
+		content = data.ErrorOutput
+	}
+	if content == "" {
+		return nil, "", true, errors.New("文件不存在")
+	}
+	return &dto.OpenAIFile{
+		Id:        fileId,
+		Object:    "file",
+		Bytes:     len(content),
+		CreatedAt: task.FinishTime,
+		Filename:  fileId + ".jsonl",
+		Purpose:   "batch_output",
+	}, content, true, nil
+}
+
+func UploadFile(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	if !filePurposes[purpose] {
+		batchError(c, http.StatusBadRequest, "invalid_purpose", fmt.Errorf("purpose %s is not supported", purpose))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_file", err)
+		return
+	}
+	if fileHeader.Size > maxBatchFileSize {
+		batchError(c, http.StatusBadRequest, "file_too_large", fmt.Errorf("file size exceeds %d MB", maxBatchFileSize>>20))
+		return
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_file", err)
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_file", err)
+		return
+	}
+	file := &model.File{
+		FileId:    "file-" + common.GetUUID(),
+		UserId:    c.GetInt("id"),
+		Purpose:   purpose,
+		Filename:  fileHeader.Filename,
+		Bytes:     len(content),
+		Content:   content,
+		CreatedAt: common.GetTimestamp(),
+	}
+	err = file.Insert(int64(constant.FileStorageUserQuotaMB) << 20)
+	if errors.Is(err, model.ErrFileStorageExceeded) {
+		batchError(c, http.StatusBadRequest, "file_storage_exceeded", fmt.Errorf("total size of uploaded files exceeds %d MB, please delete unused files", constant.FileStorageUserQuotaMB))
+		return
+	}
+	if err != nil {
+		batchError(c, http.StatusInternalServerError, "save_file_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, file2Dto(file))
+}
+
+func ListFiles(c *gin.Context) {
+	files, err := model.GetUserFiles(c.GetInt("id"), c.Query("purpose"))
+	if err != nil {
+		batchError(c, http.StatusInternalServerError, "list_files_failed", err)
+		return
+	}
+	data := make([]*dto.OpenAIFile, 0, len(files))
+	for _, file := range files {
+		data = append(data, file2Dto(file))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+func RetrieveFile(c *gin.Context) {
+	fileId := c.Param("id")
+	outputFile, _, ok, err := getBatchOutputFile(c, fileId)
+	if ok {
+		if err != nil {
+			batchError(c, http.StatusNotFound, "file_not_found", err)
+			return
+		}
+		c.JSON(http.StatusOK, outputFile)
+		return
+	}
+	file, err := model.GetFileByFileId(c.GetInt("id"), fileId, false)
+	if err != nil {
+		batchError(c, http.StatusNotFound, "file_not_found", err)
+		return
+	}
+	c.JSON(http.StatusOK, file2Dto(file))
+}
+
+func RetrieveFileContent(c *gin.Context) {
+	fileId := c.Param("id")
+	_, content, ok, err := getBatchOutputFile(c, fileId)
+	if ok {
+		if err != nil {
+			batchError(c, http.StatusNotFound, "file_not_found", err)
+			return
+		}
+		c.Data(http.StatusOK, "application/octet-stream", []byte(content))
+		return
+	}
+	file, err := model.GetFileByFileId(c.GetInt("id"), fileId, true)
+	if err != nil {
+		batchError(c, http.StatusNotFound, "file_not_found", err)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", file.Content)
+}
+
+func DeleteFile(c *gin.Context) {
+	fileId := c.Param("id")
+	err := model.DeleteFileByFileId(c.GetInt("id"), fileId)
+	if err != nil {
+		batchError(c, http.StatusNotFound, "file_not_found", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      fileId,
+		"object":  "file",
+		"deleted": true,
+	})
+}
+
+func isBatchChannel(channel *model.Channel) bool {
+	if channel.Type != common.ChannelTypeAnthropic || channel.Status != common.ChannelStatusEnabled {
+		return false
+	}
+	enabled, _ := channel.GetSetting()[constant.ChannelSettingBatchEnabled].(bool)
+	return enabled
+}
+
+// getBatchChannel 指定了渠道时只检查该渠道，否则按优先级选择第一个开启了批量请求的渠道，
+// 有可用渠道但都没有开启批量请求时返回 nil，由网关逐行转发
+func getBatchChannel(c *gin.Context, group string, modelName string) (*model.Channel, error) {
+	if channelId := c.GetInt("channel_id"); channelId != 0 {
+		channel, err := model.CacheGetChannel(channelId)
+		if err != nil {
+			return nil, err
+		}
+		if !isBatchChannel(channel) {
+			return nil, fmt.Errorf("渠道 #%d 未开启批量请求", channelId)
+		}
+		return channel, nil
+	}
+	channels, err := model.GetSatisfiedChannels(group, modelName)
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)
+	}
+	for _, channel := range channels {
+		if isBatchChannel(channel) {
+			return channel, nil
+		}
+	}
+	return nil, nil
+}
+
+func RelayBatch(c *gin.Context) {
+	input, err := service.GetBatchInput(c)
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_request", err)
+		return
+	}
+	channel, err := getBatchChannel(c, c.GetString("group"), input.Model)
+	if err != nil {
+		batchError(c, http.StatusServiceUnavailable, "get_channel_failed", err)
+		return
+	}
+	if channel == nil {
+		task, err := service.CreateGatewayBatch(c, input)
+		if err != nil {
+			batchError(c, http.StatusInternalServerError, "create_batch_failed", err)
+			return
+		}
+		c.JSON(http.StatusOK, service.BatchTask2Dto(task))
+		return
+	}
+	middleware.SetupContextForSelectedChannel(c, channel, input.Model)
+	openaiErr := relay.RelayBatchSubmit(c)
+	if openaiErr != nil {
+		openaiErr.Error.Message = common.MessageWithRequestId(openaiErr.Error.Message, c.GetString(common.RequestIdKey))
+		c.JSON(openaiErr.StatusCode, gin.H{
+			"error": openaiErr.Error,
+		})
+	}
+}
+
+func ListBatches(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	tasks := model.TaskGetAllUserTask(c.GetInt("id"), 0, limit+1, model.SyncTaskQueryParams{
+		Platform: constant.TaskPlatformBatch,
+	})
+	hasMore := len(tasks) > limit
+	if hasMore {
+		tasks = tasks[:limit]
+	}
+	data := make([]*dto.Batch, 0, len(tasks))
+	for _, task := range tasks {
+		data = append(data, service.BatchTask2Dto(task))
+	}
+	response := gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+	}
+	if len(data) > 0 {
+		response["first_id"] = data[0].Id
+		response["last_id"] = data[len(data)-1].Id
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func getBatchTask(c *gin.Context) (*model.Task, bool) {
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		batchError(c, http.StatusInternalServerError, "get_batch_failed", err)
+		return nil, false
+	}
+	if !exist || task.Platform != constant.TaskPlatformBatch {
+		batchError(c, http.StatusNotFound, "batch_not_found", errors.New("批量请求不存在"))
+		return nil, false
+	}
+	return task, true
+}
+
+func RetrieveBatch(c *gin.Context) {
+	task, ok := getBatchTask(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, service.BatchTask2Dto(task))
+}
+
+func CancelBatch(c *gin.Context) {
+	task, ok := getBatchTask(c)
+	if !ok {
+		return
+	}
+	if task.Status.IsFinal() {
+		batchError(c, http.StatusBadRequest, "batch_finished", errors.New("批量请求已结束"))
+		return
+	}
+	if task.Action == constant.BatchActionGateway {
+		// 网关执行的批量请求取消尚未执行的行，执行中的行完成后由执行器生成结果
+		_, err := model.FinishPendingBatchLines(task.TaskID, model.BatchLineStatusCancelled)
+		if err != nil {
+			batchError(c, http.StatusInternalServerError, "cancel_batch_failed", err)
+			return
+		}
+	} else {
+		channel, err := model.CacheGetChannel(task.ChannelId)
+		if err != nil {
+			batchError(c, http.StatusInternalServerError, "get_channel_failed", err)
+			return
+		}
+		err = batch.CancelBatch(channel.GetBaseURL(), channel.Key, task.TaskID)
+		if err != nil {
+			batchError(c, http.StatusInternalServerError, "cancel_batch_failed", err)
+			return
+		}
+	}
+	// 取消后由任务轮询器或执行器生成最终结果，已完成的请求仍按实际用量结算
+	var data dto.BatchTaskData
+	_ = task.GetData(&data)
+	data.CancelingAt = common.GetTimestamp()
+	task.SetData(data)
+	err := task.Update()
+	if err != nil {
+		common.LogError(c, "update batch task failed: "+err.Error())
+	}
+	c.JSON(http.StatusOK, service.BatchTask2Dto(task))
+}
@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/middleware"
+	"one-api/model"
+	"one-api/service"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// RunGatewayBatches 执行由网关转发的批量请求，每一行按普通请求选择渠道、重试和计费，
+// 全部结束后生成输出文件和错误文件，服务重启后从未执行的行继续
+// GATEWAY_BATCH_CONCURRENCY 为所有批量请求同时转发的请求数
+func RunGatewayBatches() {
+	concurrency := common.GetEnvOrDefault("GATEWAY_BATCH_CONCURRENCY", 8)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var running sync.Map
+	for {
+		time.Sleep(time.Duration(10) * time.Second)
+		for _, task := range model.GetAllUnFinishGatewayBatches() {
+			if _, ok := running.LoadOrStore(task.TaskID, true); ok {
+				continue
+			}
+			gopool.Go(func() {
+				defer running.Delete(task.TaskID)
+				err := runGatewayBatch(task.TaskID, semaphore)
+				if err != nil {
+					common.SysError(fmt.Sprintf("批量请求 %s 执行失败: %s", task.TaskID, err.Error()))
+				}
+			})
+		}
+	}
+}
+
+// reloadGatewayBatch 重新读取任务，保留取消请求时写入的状态
+func reloadGatewayBatch(taskId string) (*model.Task, *dto.BatchTaskData, error) {
+	task, exist, err := model.GetByOnlyTaskId(taskId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exist {
+		return nil, nil, errors.New("任务不存在")
+	}
+	var data dto.BatchTaskData
+	_ = task.GetData(&data)
+	return task, &data, nil
+}
+
+func runGatewayBatch(taskId string, semaphore chan struct{}) error {
+	ctx := context.TODO()
+	for {
+		task, data, err := reloadGatewayBatch(taskId)
+		if err != nil {
+			return err
+		}
+		if task.Status.IsFinal() {
+			return nil
+		}
+		if data.ExpiresAt != 0 && common.GetTimestamp() > data.ExpiresAt {
+			if _, err = model.FinishPendingBatchLines(taskId, model.BatchLineStatusExpired); err != nil {
+				return err
+			}
+		}
+		lines, err := model.GetPendingBatchLines(taskId, 100)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return finishGatewayBatch(ctx, task, data)
+		}
+		var wg sync.WaitGroup
+		for _, line := range lines {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			gopool.Go(func() {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+				executeBatchLine(task, data, line)
+				if err := line.Update(); err != nil {
+					common.SysError(fmt.Sprintf("批量请求 %s 保存第 %d 行结果失败: %s", taskId, line.LineIndex+1, err.Error()))
+				}
+			})
+		}
+		wg.Wait()
+
+		task, data, err = reloadGatewayBatch(taskId)
+		if err != nil {
+			return err
+		}
+		counts, err := model.CountBatchLines(taskId)
+		if err != nil {
+			return err
+		}
+		data.RequestCounts = gatewayBatchRequestCounts(counts)
+		result := dto.TaskResult{
+			TaskID: taskId,
+			Status: model.TaskStatusInProgress,
+			// 100% 表示任务已结束，执行中的任务进度最多到 99%
+			Progress: fmt.Sprintf("%d%%", min((data.RequestCounts.Completed+data.RequestCounts.Failed)*100/max(data.RequestCounts.Total, 1), 99)),
+		}
+		if task.StartTime == 0 {
+			result.StartTime = common.GetTimestamp()
+		}
+		result.Data, _ = json.Marshal(data)
+		if err = service.UpdateTaskByResult(ctx, task, result); err != nil {
+			return err
+		}
+	}
+}
+
+func gatewayBatchRequestCounts(counts map[int]int) dto.BatchRequestCounts {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return dto.BatchRequestCounts{
+		Total:     total,
+		Completed: counts[model.BatchLineStatusSucceeded],
+		Failed:    counts[model.BatchLineStatusFailed] + counts[model.BatchLineStatusCancelled] + counts[model.BatchLineStatusExpired],
+	}
+}
+
+// setupGatewayBatchContext 按提交批量请求时的令牌和分组构造请求上下文，令牌或用户失效时不再转发
+func setupGatewayBatchContext(c *gin.Context, task *model.Task, data *dto.BatchTaskData) *dto.OpenAIErrorWithStatusCode {
+	token, err := model.GetTokenById(data.TokenId)
+	if err == nil && token.UserId != task.UserId {
+		err = errors.New("令牌不属于该用户")
+	}
+	if err == nil {
+		token, err = model.ValidateUserToken(token.Key)
+	}
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "invalid_token", http.StatusUnauthorized)
+	}
+	userCache, err := model.GetUserCache(token.UserId)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "get_user_failed", http.StatusInternalServerError)
+	}
+	if userCache.Status != common.UserStatusEnabled {
+		return service.OpenAIErrorWrapperLocal(errors.New("用户已被封禁"), "user_disabled", http.StatusForbidden)
+	}
+	userCache.WriteContext(c)
+	middleware.SetupContextForToken(c, token)
+	c.Set("group", data.Group)
+	channel, err := model.CacheGetRandomSatisfiedChannel(data.Group, data.Model, 0)
+	if err != nil || channel == nil {
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("当前分组 %s 下对于模型 %s 无可用渠道", data.Group, data.Model), "get_channel_failed", http.StatusServiceUnavailable)
+	}
+	c.Set(constant.ContextKeyRequestStartTime, time.Now())
+	middleware.SetupContextForSelectedChannel(c, channel, data.Model)
+	return nil
+}
+
+// executeBatchLine 按普通的 /v1/chat/completions 请求转发一行，结果写回 line
+func executeBatchLine(task *model.Task, data *dto.BatchTaskData, line *model.BatchLine) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	requestId := common.GetTimeString() + common.GetRandomString(8)
+	c.Request = httptest.NewRequest(http.MethodPost, service.BatchEndpointChatCompletions, bytes.NewReader(line.Body))
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), common.RequestIdKey, requestId))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(common.RequestIdKey, requestId)
+	line.RequestId = requestId
+
+	if openaiErr := setupGatewayBatchContext(c, task, data); openaiErr != nil {
+		c.JSON(openaiErr.StatusCode, gin.H{
+			"error": openaiErr.Error,
+		})
+	} else {
+		Relay(c)
+	}
+	line.StatusCode = w.Code
+	line.Response = w.Body.Bytes()
+	line.ChannelId = c.GetInt("channel_id")
+	if w.Code != http.StatusOK {
+		line.Status = model.BatchLineStatusFailed
+		return
+	}
+	line.Status = model.BatchLineStatusSucceeded
+	var response struct {
+		Usage dto.Usage `json:"usage"`
+	}
+	if err := json.Unmarshal(line.Response, &response); err == nil {
+		line.PromptTokens = response.Usage.PromptTokens
+		line.CompletionTokens = response.Usage.CompletionTokens
+	}
+}
+
+// finishGatewayBatch 所有行结束后生成输出文件和错误文件并保存为用户文件，没有成功的请求时批量请求置为失败
+func finishGatewayBatch(ctx context.Context, task *model.Task, data *dto.BatchTaskData) error {
+	var output, errorOutput strings.Builder
+	err := model.FindBatchLines(task.TaskID, func(lines []*model.BatchLine) error {
+		for _, line := range lines {
+			outputLine := dto.BatchOutputLine{
+				Id:       fmt.Sprintf("batch_req_%s", common.GetUUID()),
+				CustomId: line.CustomId,
+			}
+			switch line.Status {
+			case model.BatchLineStatusSucceeded, model.BatchLineStatusFailed:
+				var body any = json.RawMessage(line.Response)
+				if !json.Valid(line.Response) {
+					body = string(line.Response)
+				}
+				outputLine.Response = &dto.BatchOutputResponse{
+					StatusCode: line.StatusCode,
+					RequestId:  line.RequestId,
+					Body:       body,
+				}
+			case model.BatchLineStatusExpired:
+				outputLine.Error = &dto.BatchOutputError{Code: "batch_expired", Message: "request expired"}
+			default:
+				outputLine.Error = &dto.BatchOutputError{Code: "batch_cancelled", Message: "request cancelled"}
+			}
+			lineBytes, err := json.Marshal(outputLine)
+			if err != nil {
+				return err
+			}
+			if line.Status == model.BatchLineStatusSucceeded {
+				output.Write(lineBytes)
+				output.WriteString("\n")
+			} else {
+				errorOutput.Write(lineBytes)
+				errorOutput.WriteString("\n")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	counts, err := model.CountBatchLines(task.TaskID)
+	if err != nil {
+		return err
+	}
+	data.RequestCounts = gatewayBatchRequestCounts(counts)
+	data.Canceled = counts[model.BatchLineStatusCancelled] > 0
+	data.Output = output.String()
+	data.ErrorOutput = errorOutput.String()
+	// 输出文件和错误文件保存为用户文件，任务数据中只保留文件 id
+	if err = service.StoreBatchOutputFiles(task, data); err != nil {
+		return err
+	}
+	result := dto.TaskResult{
+		TaskID:     task.TaskID,
+		Status:     model.TaskStatusSuccess,
+		FinishTime: common.GetTimestamp(),
+	}
+	if task.StartTime == 0 {
+		result.StartTime = result.FinishTime
+	}
+	if data.RequestCounts.Completed == 0 {
+		result.Status = model.TaskStatusFailure
+		result.FailReason = "批量请求中没有成功的请求"
+		if data.Canceled {
+			result.FailReason = "批量请求已取消"
+		}
+	}
+	result.Data, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return service.UpdateTaskByResult(ctx, task, result)
+}
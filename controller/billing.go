@@ -1,50 +1,81 @@
 package controller
 
 import (
-	"github.com/gin-gonic/gin"
+	"fmt"
+	"net/http"
 	"one-api/common"
 	"one-api/dto"
 	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
+// 兼容 OpenAI 旧版账单接口，供 ChatGPT-Next-Web、LobeChat 等客户端显示余额。
+// 开启令牌统计时按当前令牌计算，否则按用户计算；金额在开启以货币显示时单位为美元，否则为额度
+
+func quotaToBillingAmount(quota int) float64 {
+	amount := float64(quota)
+	if common.DisplayInCurrencyEnabled {
+		amount /= common.QuotaPerUnit
+	}
+	return amount
+}
+
+func billingError(c *gin.Context, statusCode int, err error) {
+	c.JSON(statusCode, gin.H{
+		"error": dto.OpenAIError{
+			Message: err.Error(),
+			Type:    "new_api_error",
+		},
+	})
+}
+
+// parseBillingDate 解析 YYYY-MM-DD 格式的 UTC 日期，为空时返回 0
+func parseBillingDate(date string) (int64, error) {
+	if date == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return t.Unix(), nil
+}
+
+// GetSubscription 额度上限为已用额度加可用余额，客户端以上限减去累计用量得到余额
 func GetSubscription(c *gin.Context) {
-	var remainQuota int
+	userId := c.GetInt("id")
+	remainQuota, err := model.GetUserQuota(userId, false)
+	if err != nil {
+		billingError(c, http.StatusOK, err)
+		return
+	}
 	var usedQuota int
-	var err error
-	var token *model.Token
 	var expiredTime int64
 	if common.DisplayTokenStatEnabled {
-		tokenId := c.GetInt("token_id")
-		token, err = model.GetTokenById(tokenId)
+		token, err := model.GetTokenById(c.GetInt("token_id"))
+		if err != nil {
+			billingError(c, http.StatusOK, err)
+			return
+		}
 		expiredTime = token.ExpiredTime
-		remainQuota = token.RemainQuota
 		usedQuota = token.UsedQuota
+		// 令牌可用额度同时受用户余额限制，无限额度的令牌以用户余额为准
+		if !token.UnlimitedQuota {
+			remainQuota = min(remainQuota, token.RemainQuota)
+		}
 	} else {
-		userId := c.GetInt("id")
-		remainQuota, err = model.GetUserQuota(userId, false)
 		usedQuota, err = model.GetUserUsedQuota(userId)
+		if err != nil {
+			billingError(c, http.StatusOK, err)
+			return
+		}
 	}
 	if expiredTime <= 0 {
 		expiredTime = 0
 	}
-	if err != nil {
-		openAIError := dto.OpenAIError{
-			Message: err.Error(),
-			Type:    "upstream_error",
-		}
-		c.JSON(200, gin.H{
-			"error": openAIError,
-		})
-		return
-	}
-	quota := remainQuota + usedQuota
-	amount := float64(quota)
-	if common.DisplayInCurrencyEnabled {
-		amount /= common.QuotaPerUnit
-	}
-	if token != nil && token.UnlimitedQuota {
-		amount = 100000000
-	}
+	amount := quotaToBillingAmount(max(remainQuota, 0) + usedQuota)
 	subscription := OpenAISubscriptionResponse{
 		Object:             "billing_subscription",
 		HasPaymentMethod:   true,
@@ -53,40 +84,70 @@ func GetSubscription(c *gin.Context) {
 		SystemHardLimitUSD: amount,
 		AccessUntil:        expiredTime,
 	}
-	c.JSON(200, subscription)
-	return
+	c.JSON(http.StatusOK, subscription)
 }
 
+// GetUsage 未指定日期时返回累计用量；指定 start_date 或 end_date 时按消费日志统计区间内（不含结束日期）的用量，
+// 并按日期和模型返回 daily_costs。金额单位为 0.01 美元
 func GetUsage(c *gin.Context) {
-	var quota int
-	var err error
-	var token *model.Token
-	if common.DisplayTokenStatEnabled {
-		tokenId := c.GetInt("token_id")
-		token, err = model.GetTokenById(tokenId)
-		quota = token.UsedQuota
-	} else {
-		userId := c.GetInt("id")
-		quota, err = model.GetUserUsedQuota(userId)
-	}
-	if err != nil {
-		openAIError := dto.OpenAIError{
-			Message: err.Error(),
-			Type:    "new_api_error",
+	userId := c.GetInt("id")
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	if startDate == "" && endDate == "" {
+		var quota int
+		if common.DisplayTokenStatEnabled {
+			token, err := model.GetTokenById(c.GetInt("token_id"))
+			if err != nil {
+				billingError(c, http.StatusOK, err)
+				return
+			}
+			quota = token.UsedQuota
+		} else {
+			var err error
+			quota, err = model.GetUserUsedQuota(userId)
+			if err != nil {
+				billingError(c, http.StatusOK, err)
+				return
+			}
 		}
-		c.JSON(200, gin.H{
-			"error": openAIError,
+		c.JSON(http.StatusOK, OpenAIUsageResponse{
+			Object:     "list",
+			TotalUsage: quotaToBillingAmount(quota) * 100,
 		})
 		return
 	}
-	amount := float64(quota)
-	if common.DisplayInCurrencyEnabled {
-		amount /= common.QuotaPerUnit
+
+	startTimestamp, err := parseBillingDate(startDate)
+	if err != nil {
+		billingError(c, http.StatusBadRequest, err)
+		return
+	}
+	endTimestamp, err := parseBillingDate(endDate)
+	if err != nil {
+		billingError(c, http.StatusBadRequest, err)
+		return
+	}
+	tokenId := 0
+	if common.DisplayTokenStatEnabled {
+		tokenId = c.GetInt("token_id")
+	}
+	quotas, err := model.SumDailyModelQuota(userId, tokenId, startTimestamp, endTimestamp)
+	if err != nil {
+		billingError(c, http.StatusOK, err)
+		return
 	}
 	usage := OpenAIUsageResponse{
 		Object:     "list",
-		TotalUsage: amount * 100,
+		DailyCosts: make([]OpenAIUsageDailyCost, 0),
+	}
+	for _, quota := range quotas {
+		cost := quotaToBillingAmount(quota.Quota) * 100
+		if n := len(usage.DailyCosts); n == 0 || usage.DailyCosts[n-1].Timestamp != float64(quota.Day) {
+			usage.DailyCosts = append(usage.DailyCosts, OpenAIUsageDailyCost{Timestamp: float64(quota.Day)})
+		}
+		dailyCost := &usage.DailyCosts[len(usage.DailyCosts)-1]
+		dailyCost.LineItems = append(dailyCost.LineItems, OpenAIUsageLineItem{Name: quota.ModelName, Cost: cost})
+		usage.TotalUsage += cost
 	}
-	c.JSON(200, usage)
-	return
+	c.JSON(http.StatusOK, usage)
 }
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateBudgetAlertRequest struct {
+	TokenId    int    `json:"token_id"`
+	Thresholds []int  `json:"thresholds"`
+	Period     string `json:"period"`
+}
+
+func GetSelfBudgetAlerts(c *gin.Context) {
+	alerts, err := model.GetUserBudgetAlerts(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    alerts,
+	})
+}
+
+// UpdateSelfBudgetAlert 设置用户额度（token_id 为 0）或令牌额度的预算预警
+func UpdateSelfBudgetAlert(c *gin.Context) {
+	var req UpdateBudgetAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	if req.Period == "" {
+		req.Period = model.BudgetAlertPeriodMonth
+	}
+	if !model.IsValidBudgetAlertPeriod(req.Period) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的预警周期",
+		})
+		return
+	}
+	thresholds, err := model.ParseBudgetAlertThresholds(req.Thresholds)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	userId := c.GetInt("id")
+	if req.TokenId != 0 {
+		if _, err := model.GetTokenByIds(req.TokenId, userId); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "令牌不存在",
+			})
+			return
+		}
+	}
+	alert, err := model.UpsertBudgetAlert(userId, req.TokenId, thresholds, req.Period)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    alert,
+	})
+}
+
+func DeleteSelfBudgetAlert(c *gin.Context) {
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	err := model.DeleteBudgetAlert(c.GetInt("id"), tokenId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
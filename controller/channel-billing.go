@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,12 +27,14 @@ type OpenAISubscriptionResponse struct {
 	AccessUntil        int64   `json:"access_until"`
 }
 
+type OpenAIUsageLineItem struct {
+	Name string  `json:"name"`
+	Cost float64 `json:"cost"`
+}
+
 type OpenAIUsageDailyCost struct {
-	Timestamp float64 `json:"timestamp"`
-	LineItems []struct {
-		Name string  `json:"name"`
-		Cost float64 `json:"cost"`
-	}
+	Timestamp float64               `json:"timestamp"`
+	LineItems []OpenAIUsageLineItem `json:"line_items"`
 }
 
 type OpenAICreditGrants struct {
@@ -42,9 +45,9 @@ type OpenAICreditGrants struct {
 }
 
 type OpenAIUsageResponse struct {
-	Object string `json:"object"`
-	//DailyCosts []OpenAIUsageDailyCost `json:"daily_costs"`
-	TotalUsage float64 `json:"total_usage"` // unit: 0.01 dollar
+	Object     string                 `json:"object"`
+	DailyCosts []OpenAIUsageDailyCost `json:"daily_costs,omitempty"`
+	TotalUsage float64                `json:"total_usage"` // unit: 0.01 dollar
 }
 
 type OpenAISBUsageResponse struct {
@@ -400,20 +403,29 @@ func UpdateChannelBalance(c *gin.Context) {
 	return
 }
 
-func updateAllChannelsBalance() error {
-	channels, err := model.GetAllChannels(0, 0, true, false)
-	if err != nil {
-		return err
-	}
+func updateAllChannelsBalance(ctx context.Context, job *service.AdminJob, channels []*model.Channel) error {
 	for _, channel := range channels {
-		if channel.Status != common.ChannelStatusEnabled {
-			continue
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
 		// TODO: support Azure
 		//if channel.Type != common.ChannelTypeOpenAI && channel.Type != common.ChannelTypeCustom {
 		//	continue
 		//}
 		balance, err := updateChannelBalance(channel)
+		if job != nil {
+			result := service.AdminJobResult{
+				Id:      channel.Id,
+				Name:    channel.Name,
+				Success: err == nil,
+			}
+			if err != nil {
+				result.Message = err.Error()
+			} else {
+				result.Message = fmt.Sprintf("%.2f", balance)
+			}
+			job.AddResult(result)
+		}
 		if err != nil {
 			continue
 		} else {
@@ -427,9 +439,22 @@ func updateAllChannelsBalance() error {
 	return nil
 }
 
+func getBalanceChannels() ([]*model.Channel, error) {
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		return nil, err
+	}
+	enabledChannels := make([]*model.Channel, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Status == common.ChannelStatusEnabled {
+			enabledChannels = append(enabledChannels, channel)
+		}
+	}
+	return enabledChannels, nil
+}
+
 func UpdateAllChannelsBalance(c *gin.Context) {
-	// TODO: make it async
-	err := updateAllChannelsBalance()
+	channels, err := getBalanceChannels()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -437,9 +462,13 @@ func UpdateAllChannelsBalance(c *gin.Context) {
 		})
 		return
 	}
+	job := service.StartAdminJob(service.AdminJobTypeUpdateAllBalances, c.GetInt("id"), len(channels), func(ctx context.Context, job *service.AdminJob) error {
+		return updateAllChannelsBalance(ctx, job, channels)
+	})
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    job,
 	})
 	return
 }
@@ -448,7 +477,12 @@ func AutomaticallyUpdateChannels(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Minute)
 		common.SysLog("updating all channels")
-		_ = updateAllChannelsBalance()
+		channels, err := getBalanceChannels()
+		if err != nil {
+			common.SysError("failed to get channels: " + err.Error())
+			continue
+		}
+		_ = updateAllChannelsBalance(context.Background(), nil, channels)
 		common.SysLog("channels update done")
 	}
 }
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,8 +25,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/bytedance/gopkg/util/gopool"
-
 	"github.com/gin-gonic/gin"
 )
 
@@ -154,7 +153,10 @@ func testChannel(channel *model.Channel, testModel string) (err error, openAIErr
 
 	quota := 0
 	if !priceData.UsePrice {
-		quota = usage.PromptTokens + int(math.Round(float64(usage.CompletionTokens)*priceData.CompletionRatio))
+		quota = int(math.Round(float64(usage.CompletionTokens) * priceData.CompletionRatio))
+		if !priceData.FreeInput {
+			quota += usage.PromptTokens
+		}
 		quota = int(math.Round(float64(quota) * priceData.ModelRatio))
 		if priceData.ModelRatio != 0 && quota <= 0 {
 			quota = 1
@@ -253,25 +255,36 @@ func TestChannel(c *gin.Context) {
 var testAllChannelsLock sync.Mutex
 var testAllChannelsRunning bool = false
 
-func testAllChannels(notify bool) error {
+func testAllChannels(notify bool, userId int) (*service.AdminJob, error) {
 
 	testAllChannelsLock.Lock()
 	if testAllChannelsRunning {
 		testAllChannelsLock.Unlock()
-		return errors.New("测试已在运行中")
+		return nil, errors.New("测试已在运行中")
 	}
 	testAllChannelsRunning = true
 	testAllChannelsLock.Unlock()
 	channels, err := model.GetAllChannels(0, 0, true, false)
 	if err != nil {
-		return err
+		testAllChannelsLock.Lock()
+		testAllChannelsRunning = false
+		testAllChannelsLock.Unlock()
+		return nil, err
 	}
 	var disableThreshold = int64(common.ChannelDisableThreshold * 1000)
 	if disableThreshold == 0 {
 		disableThreshold = 10000000 // a impossible value
 	}
-	gopool.Go(func() {
+	job := service.StartAdminJob(service.AdminJobTypeTestAllChannels, userId, len(channels), func(ctx context.Context, job *service.AdminJob) error {
+		defer func() {
+			testAllChannelsLock.Lock()
+			testAllChannelsRunning = false
+			testAllChannelsLock.Unlock()
+		}()
 		for _, channel := range channels {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			isChannelEnabled := channel.Status == common.ChannelStatusEnabled
 			tik := time.Now()
 			err, openaiWithStatusErr := testChannel(channel, "")
@@ -298,25 +311,33 @@ func testAllChannels(notify bool) error {
 			}
 
 			// enable channel
-			if !isChannelEnabled && service.ShouldEnableChannel(err, openaiWithStatusErr, channel.Status) {
+			// 因供应商故障暂停的渠道由状态页检查负责恢复
+			if !isChannelEnabled && service.ShouldEnableChannel(err, openaiWithStatusErr, channel.Status) && channel.GetProviderIncident() == "" {
 				service.EnableChannel(channel.Id, channel.Name)
 			}
 
 			channel.UpdateResponseTime(milliseconds)
+			result := service.AdminJobResult{
+				Id:      channel.Id,
+				Name:    channel.Name,
+				Success: err == nil,
+			}
+			if err != nil {
+				result.Message = err.Error()
+			}
+			job.AddResult(result)
 			time.Sleep(common.RequestInterval)
 		}
-		testAllChannelsLock.Lock()
-		testAllChannelsRunning = false
-		testAllChannelsLock.Unlock()
 		if notify {
 			service.NotifyRootUser(dto.NotifyTypeChannelTest, "通道测试完成", "所有通道测试已完成")
 		}
+		return nil
 	})
-	return nil
+	return job, nil
 }
 
 func TestAllChannels(c *gin.Context) {
-	err := testAllChannels(true)
+	job, err := testAllChannels(true, c.GetInt("id"))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -327,6 +348,7 @@ func TestAllChannels(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    job,
 	})
 	return
 }
@@ -335,7 +357,7 @@ func AutomaticallyTestChannels(frequency int) {
 	for {
 		time.Sleep(time.Duration(frequency) * time.Minute)
 		common.SysLog("testing all channels")
-		_ = testAllChannels(false)
+		_, _ = testAllChannels(false, 0)
 		common.SysLog("channel test finished")
 	}
 }
@@ -2,10 +2,12 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
 	"strings"
 
@@ -107,13 +109,24 @@ func FetchUpstreamModels(c *gin.Context) {
 		return
 	}
 
-	//if channel.Type != common.ChannelTypeOpenAI {
-	//	c.JSON(http.StatusOK, gin.H{
-	//		"success": false,
-	//		"message": "仅支持 OpenAI 类型渠道",
-	//	})
-	//	return
-	//}
+	ids, err := fetchChannelUpstreamModels(channel)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    ids,
+	})
+}
+
+// fetchChannelUpstreamModels 请求渠道上游的模型列表接口，返回模型名称
+func fetchChannelUpstreamModels(channel *model.Channel) ([]string, error) {
 	baseURL := common.ChannelBaseURLs[channel.Type]
 	if channel.GetBaseURL() != "" {
 		baseURL = channel.GetBaseURL()
@@ -127,20 +140,12 @@ func FetchUpstreamModels(c *gin.Context) {
 	}
 	body, err := GetResponseBody("GET", url, channel, GetAuthHeader(channel.Key))
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": err.Error(),
-		})
-		return
+		return nil, err
 	}
 
 	var result OpenAIModelsResponse
 	if err = json.Unmarshal(body, &result); err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("解析响应失败: %s", err.Error()),
-		})
-		return
+		return nil, fmt.Errorf("解析响应失败: %s", err.Error())
 	}
 
 	var ids []string
@@ -151,12 +156,7 @@ func FetchUpstreamModels(c *gin.Context) {
 		}
 		ids = append(ids, id)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "",
-		"data":    ids,
-	})
+	return ids, nil
 }
 
 func FixChannelsAbilities(c *gin.Context) {
@@ -243,6 +243,42 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// GetChannelByFingerprint 根据 system_fingerprint 中的渠道哈希查找渠道
+func GetChannelByFingerprint(c *gin.Context) {
+	tag := service.ParseChannelFingerprintTag(c.Param("tag"))
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	for _, channel := range channels {
+		if service.ChannelFingerprintTag(channel.Id) != tag {
+			continue
+		}
+		channel, err := model.GetChannelById(channel.Id, false)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    channel,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": "未找到对应的渠道",
+	})
+}
+
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
@@ -254,6 +290,8 @@ func AddChannel(c *gin.Context) {
 		return
 	}
 	channel.CreatedTime = common.GetTimestamp()
+	// 影子渠道只能通过同步生产环境创建
+	channel.ShadowSourceId = 0
 	keys := strings.Split(channel.Key, "\n")
 	if channel.Type == common.ChannelTypeVertexAi {
 		if channel.Other == "" {
@@ -312,8 +350,17 @@ func AddChannel(c *gin.Context) {
 	return
 }
 
+var errShadowChannelReadOnly = errors.New("影子渠道为只读，只能通过同步生产环境更新")
+
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
+	if model.IsShadowChannel(id) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": errShadowChannelReadOnly.Error(),
+		})
+		return
+	}
 	channel := model.Channel{Id: id}
 	err := channel.Delete()
 	if err != nil {
@@ -454,6 +501,13 @@ func DeleteChannelBatch(c *gin.Context) {
 		})
 		return
 	}
+	if model.HasShadowChannel(channelBatch.Ids) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": errShadowChannelReadOnly.Error(),
+		})
+		return
+	}
 	err = model.BatchDeleteChannels(channelBatch.Ids)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -480,6 +534,14 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if model.IsShadowChannel(channel.Id) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": errShadowChannelReadOnly.Error(),
+		})
+		return
+	}
+	channel.ShadowSourceId = 0
 	if channel.Type == common.ChannelTypeVertexAi {
 		if channel.Other == "" {
 			c.JSON(http.StatusOK, gin.H{
@@ -608,6 +670,13 @@ func BatchSetChannelTag(c *gin.Context) {
 		})
 		return
 	}
+	if model.HasShadowChannel(channelBatch.Ids) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": errShadowChannelReadOnly.Error(),
+		})
+		return
+	}
 	err = model.BatchSetChannelTag(channelBatch.Ids, channelBatch.Tag)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -623,3 +692,19 @@ func BatchSetChannelTag(c *gin.Context) {
 	})
 	return
 }
+
+func SyncShadowChannels(c *gin.Context) {
+	result, err := service.SyncShadowChannels()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAdminJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetAdminJobs(c.Query("type")),
+	})
+}
+
+func GetAdminJob(c *gin.Context) {
+	job, err := service.GetAdminJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    job,
+	})
+}
+
+func CancelAdminJob(c *gin.Context) {
+	err := service.CancelAdminJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
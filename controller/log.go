@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/setting/operation_setting"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -155,9 +156,10 @@ func GetLogsStat(c *gin.Context) {
 		"success": true,
 		"message": "",
 		"data": gin.H{
-			"quota": stat.Quota,
-			"rpm":   stat.Rpm,
-			"tpm":   stat.Tpm,
+			"quota":  stat.Quota,
+			"amount": operation_setting.QuotaToDisplayAmount(int64(stat.Quota)),
+			"rpm":    stat.Rpm,
+			"tpm":    stat.Tpm,
 		},
 	})
 	return
@@ -178,9 +180,10 @@ func GetLogsSelfStat(c *gin.Context) {
 		"success": true,
 		"message": "",
 		"data": gin.H{
-			"quota": quotaNum.Quota,
-			"rpm":   quotaNum.Rpm,
-			"tpm":   quotaNum.Tpm,
+			"quota":  quotaNum.Quota,
+			"amount": operation_setting.QuotaToDisplayAmount(int64(quotaNum.Quota)),
+			"rpm":    quotaNum.Rpm,
+			"tpm":    quotaNum.Tpm,
 			//"token": tokenNum,
 		},
 	})
@@ -18,155 +18,148 @@ import (
 	"time"
 )
 
-func UpdateMidjourneyTaskBulk() {
-	//imageModel := "midjourney"
-	ctx := context.TODO()
-	for {
-		time.Sleep(time.Duration(15) * time.Second)
+// updateMidjourneyTasks 由通用任务轮询器每轮调用，Midjourney 任务保存在单独的表中，
+// 按渠道分组后交给轮询器并发查询
+func updateMidjourneyTasks(ctx context.Context, run func(func())) {
+	tasks := model.GetAllUnFinishTasks()
+	if len(tasks) == 0 {
+		return
+	}
 
-		tasks := model.GetAllUnFinishTasks()
-		if len(tasks) == 0 {
+	common.LogInfo(ctx, fmt.Sprintf("检测到未完成的任务数有: %v", len(tasks)))
+	taskChannelM := make(map[int][]string)
+	taskM := make(map[string]*model.Midjourney)
+	nullTaskIds := make([]int, 0)
+	for _, task := range tasks {
+		if task.MjId == "" {
+			// 统计失败的未完成任务
+			nullTaskIds = append(nullTaskIds, task.Id)
 			continue
 		}
-
-		common.LogInfo(ctx, fmt.Sprintf("检测到未完成的任务数有: %v", len(tasks)))
-		taskChannelM := make(map[int][]string)
-		taskM := make(map[string]*model.Midjourney)
-		nullTaskIds := make([]int, 0)
-		for _, task := range tasks {
-			if task.MjId == "" {
-				// 统计失败的未完成任务
-				nullTaskIds = append(nullTaskIds, task.Id)
-				continue
-			}
-			taskM[task.MjId] = task
-			taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.MjId)
+		taskM[task.MjId] = task
+		taskChannelM[task.ChannelId] = append(taskChannelM[task.ChannelId], task.MjId)
+	}
+	if len(nullTaskIds) > 0 {
+		err := model.MjBulkUpdateByTaskIds(nullTaskIds, map[string]any{
+			"status":   "FAILURE",
+			"progress": "100%",
+		})
+		if err != nil {
+			common.LogError(ctx, fmt.Sprintf("Fix null mj_id task error: %v", err))
+		} else {
+			common.LogInfo(ctx, fmt.Sprintf("Fix null mj_id task success: %v", nullTaskIds))
 		}
-		if len(nullTaskIds) > 0 {
-			err := model.MjBulkUpdateByTaskIds(nullTaskIds, map[string]any{
-				"status":   "FAILURE",
-				"progress": "100%",
-			})
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("Fix null mj_id task error: %v", err))
-			} else {
-				common.LogInfo(ctx, fmt.Sprintf("Fix null mj_id task success: %v", nullTaskIds))
-			}
+	}
+	for channelId, taskIds := range taskChannelM {
+		run(func() {
+			updateMidjourneyChannelTasks(ctx, channelId, taskIds, taskM)
+		})
+	}
+}
+
+func updateMidjourneyChannelTasks(ctx context.Context, channelId int, taskIds []string, taskM map[string]*model.Midjourney) {
+	common.LogInfo(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
+	midjourneyChannel, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		common.LogError(ctx, fmt.Sprintf("CacheGetChannel: %v", err))
+		err := model.MjBulkUpdate(taskIds, map[string]any{
+			"fail_reason": fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId),
+			"status":      "FAILURE",
+			"progress":    "100%",
+		})
+		if err != nil {
+			common.LogInfo(ctx, fmt.Sprintf("UpdateMidjourneyTask error: %v", err))
 		}
-		if len(taskChannelM) == 0 {
+		return
+	}
+	requestUrl := fmt.Sprintf("%s/mj/task/list-by-condition", *midjourneyChannel.BaseURL)
+
+	body, _ := json.Marshal(map[string]any{
+		"ids": taskIds,
+	})
+	req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(body))
+	if err != nil {
+		common.LogError(ctx, fmt.Sprintf("Get Task error: %v", err))
+		return
+	}
+	// 设置超时时间
+	timeout := time.Second * 15
+	reqCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// 使用带有超时的 context 创建新的请求
+	req = req.WithContext(reqCtx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("mj-api-secret", midjourneyChannel.Key)
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		common.LogError(ctx, fmt.Sprintf("Get Task Do req error: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		common.LogError(ctx, fmt.Sprintf("Get Task status code: %d", resp.StatusCode))
+		return
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		common.LogError(ctx, fmt.Sprintf("Get Task parse body error: %v", err))
+		return
+	}
+	var responseItems []dto.MidjourneyDto
+	err = json.Unmarshal(responseBody, &responseItems)
+	if err != nil {
+		common.LogError(ctx, fmt.Sprintf("Get Task parse body error2: %v, body: %s", err, string(responseBody)))
+		return
+	}
+
+	for _, responseItem := range responseItems {
+		task, ok := taskM[responseItem.MjId]
+		if !ok {
 			continue
 		}
 
-		for channelId, taskIds := range taskChannelM {
-			common.LogInfo(ctx, fmt.Sprintf("渠道 #%d 未完成的任务有: %d", channelId, len(taskIds)))
-			if len(taskIds) == 0 {
-				continue
-			}
-			midjourneyChannel, err := model.CacheGetChannel(channelId)
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("CacheGetChannel: %v", err))
-				err := model.MjBulkUpdate(taskIds, map[string]any{
-					"fail_reason": fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId),
-					"status":      "FAILURE",
-					"progress":    "100%",
-				})
-				if err != nil {
-					common.LogInfo(ctx, fmt.Sprintf("UpdateMidjourneyTask error: %v", err))
-				}
-				continue
-			}
-			requestUrl := fmt.Sprintf("%s/mj/task/list-by-condition", *midjourneyChannel.BaseURL)
-
-			body, _ := json.Marshal(map[string]any{
-				"ids": taskIds,
-			})
-			req, err := http.NewRequest("POST", requestUrl, bytes.NewBuffer(body))
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("Get Task error: %v", err))
-				continue
-			}
-			// 设置超时时间
-			timeout := time.Second * 15
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			// 使用带有超时的 context 创建新的请求
-			req = req.WithContext(ctx)
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("mj-api-secret", midjourneyChannel.Key)
-			resp, err := service.GetHttpClient().Do(req)
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("Get Task Do req error: %v", err))
-				continue
-			}
-			if resp.StatusCode != http.StatusOK {
-				common.LogError(ctx, fmt.Sprintf("Get Task status code: %d", resp.StatusCode))
-				continue
-			}
-			responseBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("Get Task parse body error: %v", err))
-				continue
-			}
-			var responseItems []dto.MidjourneyDto
-			err = json.Unmarshal(responseBody, &responseItems)
-			if err != nil {
-				common.LogError(ctx, fmt.Sprintf("Get Task parse body error2: %v, body: %s", err, string(responseBody)))
-				continue
+		useTime := (time.Now().UnixNano() / int64(time.Millisecond)) - task.SubmitTime
+		// 如果时间超过一小时，且进度不是100%，则认为任务失败
+		if useTime > 3600000 && task.Progress != "100%" {
+			responseItem.FailReason = "上游任务超时（超过1小时）"
+			responseItem.Status = "FAILURE"
+		}
+		if !checkMjTaskNeedUpdate(task, responseItem) {
+			continue
+		}
+		task.Code = 1
+		task.Progress = responseItem.Progress
+		task.PromptEn = responseItem.PromptEn
+		task.State = responseItem.State
+		task.SubmitTime = responseItem.SubmitTime
+		task.StartTime = responseItem.StartTime
+		task.FinishTime = responseItem.FinishTime
+		task.ImageUrl = responseItem.ImageUrl
+		task.Status = responseItem.Status
+		task.FailReason = responseItem.FailReason
+		if responseItem.Properties != nil {
+			propertiesStr, _ := json.Marshal(responseItem.Properties)
+			task.Properties = string(propertiesStr)
+		}
+		if responseItem.Buttons != nil {
+			buttonStr, _ := json.Marshal(responseItem.Buttons)
+			task.Buttons = string(buttonStr)
+		}
+		shouldReturnQuota := false
+		if (task.Progress != "100%" && responseItem.FailReason != "") || (task.Progress == "100%" && task.Status == "FAILURE") {
+			common.LogInfo(ctx, task.MjId+" 构建失败，"+task.FailReason)
+			task.Progress = "100%"
+			if task.Quota != 0 {
+				shouldReturnQuota = true
 			}
-			resp.Body.Close()
-			req.Body.Close()
-			cancel()
-
-			for _, responseItem := range responseItems {
-				task := taskM[responseItem.MjId]
-
-				useTime := (time.Now().UnixNano() / int64(time.Millisecond)) - task.SubmitTime
-				// 如果时间超过一小时，且进度不是100%，则认为任务失败
-				if useTime > 3600000 && task.Progress != "100%" {
-					responseItem.FailReason = "上游任务超时（超过1小时）"
-					responseItem.Status = "FAILURE"
-				}
-				if !checkMjTaskNeedUpdate(task, responseItem) {
-					continue
-				}
-				task.Code = 1
-				task.Progress = responseItem.Progress
-				task.PromptEn = responseItem.PromptEn
-				task.State = responseItem.State
-				task.SubmitTime = responseItem.SubmitTime
-				task.StartTime = responseItem.StartTime
-				task.FinishTime = responseItem.FinishTime
-				task.ImageUrl = responseItem.ImageUrl
-				task.Status = responseItem.Status
-				task.FailReason = responseItem.FailReason
-				if responseItem.Properties != nil {
-					propertiesStr, _ := json.Marshal(responseItem.Properties)
-					task.Properties = string(propertiesStr)
-				}
-				if responseItem.Buttons != nil {
-					buttonStr, _ := json.Marshal(responseItem.Buttons)
-					task.Buttons = string(buttonStr)
-				}
-				shouldReturnQuota := false
-				if (task.Progress != "100%" && responseItem.FailReason != "") || (task.Progress == "100%" && task.Status == "FAILURE") {
-					common.LogInfo(ctx, task.MjId+" 构建失败，"+task.FailReason)
-					task.Progress = "100%"
-					if task.Quota != 0 {
-						shouldReturnQuota = true
-					}
-				}
-				err = task.Update()
-				if err != nil {
-					common.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
-				} else {
-					if shouldReturnQuota {
-						err = model.IncreaseUserQuota(task.UserId, task.Quota, false)
-						if err != nil {
-							common.LogError(ctx, "fail to increase user quota: "+err.Error())
-						}
-						logContent := fmt.Sprintf("构图失败 %s，补偿 %s", task.MjId, common.LogQuota(task.Quota))
-						model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-					}
-				}
+		}
+		err = task.Update()
+		if err != nil {
+			common.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
+		} else {
+			if shouldReturnQuota {
+				service.RefundTaskQuota(ctx, task.UserId, 0, task.ChannelId, service.CoverActionToModelName(task.Action),
+					task.Quota, fmt.Sprintf("构图失败 %s", task.MjId))
 			}
 		}
 	}
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/model"
+	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/operation_setting"
 	"one-api/setting/system_setting"
@@ -59,6 +60,9 @@ func GetStatus(c *gin.Context) {
 			"docs_link":                   operation_setting.GetGeneralSetting().DocsLink,
 			"quota_per_unit":              common.QuotaPerUnit,
 			"display_in_currency":         common.DisplayInCurrencyEnabled,
+			"display_currency":            operation_setting.GetGeneralSetting().DisplayCurrency,
+			"display_currency_symbol":     operation_setting.GetGeneralSetting().DisplayCurrencySymbol,
+			"display_exchange_rate":       operation_setting.GetGeneralSetting().DisplayExchangeRate,
 			"enable_batch_update":         common.BatchUpdateEnabled,
 			"enable_drawing":              common.DrawingEnabled,
 			"enable_task":                 common.TaskEnabled,
@@ -66,6 +70,7 @@ func GetStatus(c *gin.Context) {
 			"data_export_default_time":    common.DataExportDefaultTime,
 			"default_collapse_sidebar":    common.DefaultCollapseSidebar,
 			"enable_online_topup":         setting.PayAddress != "" && setting.EpayId != "" && setting.EpayKey != "",
+			"enable_stripe_topup":         service.StripeEnabled(),
 			"mj_notify_enabled":           setting.MjNotifyEnabled,
 			"chats":                       setting.Chats,
 			"demo_site_enabled":           operation_setting.DemoSiteEnabled,
@@ -10,11 +10,10 @@ import (
 	"one-api/model"
 	"one-api/relay"
 	"one-api/relay/channel/ai360"
-	"one-api/relay/channel/lingyiwanwu"
-	"one-api/relay/channel/minimax"
 	"one-api/relay/channel/moonshot"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
+	"one-api/setting/model_setting"
 )
 
 // https://platform.openai.com/docs/api-reference/models/list
@@ -86,28 +85,6 @@ func init() {
 			Parent:     nil,
 		})
 	}
-	for _, modelName := range lingyiwanwu.ModelList {
-		openAIModels = append(openAIModels, dto.OpenAIModels{
-			Id:         modelName,
-			Object:     "model",
-			Created:    1626777600,
-			OwnedBy:    lingyiwanwu.ChannelName,
-			Permission: permission,
-			Root:       modelName,
-			Parent:     nil,
-		})
-	}
-	for _, modelName := range minimax.ModelList {
-		openAIModels = append(openAIModels, dto.OpenAIModels{
-			Id:         modelName,
-			Object:     "model",
-			Created:    1626777600,
-			OwnedBy:    minimax.ChannelName,
-			Permission: permission,
-			Root:       modelName,
-			Parent:     nil,
-		})
-	}
 	for modelName, _ := range constant.MidjourneyModel2Action {
 		openAIModels = append(openAIModels, dto.OpenAIModels{
 			Id:         modelName,
@@ -136,10 +113,38 @@ func init() {
 	}
 }
 
+// getListModelsGroup 返回令牌分组，令牌未指定分组时返回用户分组
+func getListModelsGroup(c *gin.Context) (string, error) {
+	userGroup, err := model.GetUserGroup(c.GetInt("id"), true)
+	if err != nil {
+		return "", err
+	}
+	if tokenGroup := c.GetString("token_group"); tokenGroup != "" {
+		return tokenGroup, nil
+	}
+	return userGroup, nil
+}
+
 func ListModels(c *gin.Context) {
 	userOpenAiModels := make([]dto.OpenAIModels, 0)
 	permission := getPermission()
 
+	// 开启上游模型列表时只返回渠道上游实际提供的模型
+	var upstreamModels map[string]bool
+	if model_setting.GetGlobalSettings().UpstreamModelListEnabled {
+		group, err := getListModelsGroup(c)
+		if err == nil {
+			upstreamModels, err = getUpstreamGroupModels(group)
+		}
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "get upstream models failed",
+			})
+			return
+		}
+	}
+
 	modelLimitEnable := c.GetBool("token_model_limit_enabled")
 	if modelLimitEnable {
 		s, ok := c.Get("token_model_limit")
@@ -150,6 +155,9 @@ func ListModels(c *gin.Context) {
 			tokenModelLimit = map[string]bool{}
 		}
 		for allowModel, _ := range tokenModelLimit {
+			if upstreamModels != nil && !upstreamModels[allowModel] {
+				continue
+			}
 			if _, ok := openAIModelsMap[allowModel]; ok {
 				userOpenAiModels = append(userOpenAiModels, openAIModelsMap[allowModel])
 			} else {
@@ -165,8 +173,7 @@ func ListModels(c *gin.Context) {
 			}
 		}
 	} else {
-		userId := c.GetInt("id")
-		userGroup, err := model.GetUserGroup(userId, true)
+		group, err := getListModelsGroup(c)
 		if err != nil {
 			c.JSON(http.StatusOK, gin.H{
 				"success": false,
@@ -174,13 +181,11 @@ func ListModels(c *gin.Context) {
 			})
 			return
 		}
-		group := userGroup
-		tokenGroup := c.GetString("token_group")
-		if tokenGroup != "" {
-			group = tokenGroup
-		}
 		models := model.GetGroupModels(group)
 		for _, s := range models {
+			if upstreamModels != nil && !upstreamModels[s] {
+				continue
+			}
 			if _, ok := openAIModelsMap[s]; ok {
 				userOpenAiModels = append(userOpenAiModels, openAIModelsMap[s])
 			} else {
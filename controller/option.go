@@ -6,7 +6,9 @@ import (
 	"one-api/common"
 	"one-api/model"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"one-api/setting/system_setting"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -110,6 +112,24 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "GroupVolumeTiers":
+		err = setting.CheckGroupVolumeTiers(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "GroupModelRatio":
+		err = setting.CheckGroupModelRatio(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value)
 		if err != nil {
@@ -119,6 +139,78 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "TokenRateLimitTiers":
+		err = setting.CheckTokenRateLimitTiers(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "SubscriptionPlans":
+		err = setting.CheckSubscriptionPlans(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "LegacyKeyAuthGroups":
+		err = setting.CheckLegacyKeyAuthGroups(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "ModelPricing":
+		_, err = operation_setting.ParseModelPricing(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "ImagePricing":
+		_, err = operation_setting.ParseImagePricing(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "FreeModels":
+		err = operation_setting.CheckFreeModels(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "general_setting.display_exchange_rate":
+		rate, err := strconv.ParseFloat(option.Value, 64)
+		if err != nil || rate <= 0 {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "汇率必须为大于 0 的数字",
+			})
+			return
+		}
+	case "DefaultCacheRatio":
+		ratio, err := strconv.ParseFloat(option.Value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "默认缓存倍率必须为 0 到 1 之间的数字",
+			})
+			return
+		}
 
 	}
 	err = model.UpdateOption(option.Key, option.Value)
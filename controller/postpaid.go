@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getPostpaidStatements(c *gin.Context, userId int) {
+	statements, err := model.GetPostpaidStatements(userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    statements,
+	})
+}
+
+// GetSelfPostpaidStatements 返回当前用户的后付费月度账单，明细通过用量明细导出接口按账单的起止时间下载
+func GetSelfPostpaidStatements(c *gin.Context) {
+	getPostpaidStatements(c, c.GetInt("id"))
+}
+
+func GetUserPostpaidStatements(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	getPostpaidStatements(c, id)
+}
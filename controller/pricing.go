@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"net/http"
 	"one-api/model"
+	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/operation_setting"
 )
@@ -62,3 +65,102 @@ func ResetModelRatio(c *gin.Context) {
 		"message": "重置模型倍率成功",
 	})
 }
+
+func saveModelPricing(c *gin.Context, pricingMap map[string]operation_setting.ModelPricing, message string) {
+	jsonBytes, err := json.Marshal(pricingMap)
+	if err == nil {
+		err = model.UpdateOption("ModelPricing", string(jsonBytes))
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    pricingMap,
+	})
+}
+
+func GetModelPricing(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    operation_setting.GetModelPricingCopy(),
+	})
+}
+
+type modelPricingRequest struct {
+	Model string `json:"model"`
+	operation_setting.ModelPricing
+}
+
+// UpsertModelPricing 新增或修改一个模型的价格
+func UpsertModelPricing(c *gin.Context) {
+	var req modelPricingRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Model == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	if err := req.ModelPricing.Validate(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	pricingMap := operation_setting.GetModelPricingCopy()
+	pricingMap[req.Model] = req.ModelPricing
+	saveModelPricing(c, pricingMap, "")
+}
+
+// DeleteModelPricing 删除模型的价格，删除后该模型恢复使用倍率设置
+func DeleteModelPricing(c *gin.Context) {
+	modelName := c.Query("model")
+	pricingMap := operation_setting.GetModelPricingCopy()
+	if _, ok := pricingMap[modelName]; !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "模型价格不存在",
+		})
+		return
+	}
+	delete(pricingMap, modelName)
+	saveModelPricing(c, pricingMap, "")
+}
+
+// MigrateModelPricing 把现有的模型倍率和补全倍率换算为价格表，已配置价格的模型保持不变
+func MigrateModelPricing(c *gin.Context) {
+	saveModelPricing(c, operation_setting.ModelPricingFromRatios(), "迁移模型价格成功")
+}
+
+// SyncModelPricing 从上游价格源同步价格，apply 为 true 时写入价格表，否则只返回新增和变化的模型
+func SyncModelPricing(c *gin.Context) {
+	result, err := service.SyncModelPricing(c.Query("apply") == "true")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func GetModelPricingSyncResult(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetLastPricingSyncResult(),
+	})
+}
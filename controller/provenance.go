@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetProvenancePublicKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"algorithm":  "ed25519",
+			"header":     service.ProvenanceHeader,
+			"public_key": service.GetProvenancePublicKey(),
+		},
+	})
+}
+
+type VerifyProvenanceRequest struct {
+	Header string `json:"header" binding:"required"`
+}
+
+func VerifyProvenance(c *gin.Context) {
+	req := VerifyProvenanceRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	provenance, err := service.ParseProvenanceHeader(req.Header)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"valid":      provenance.Verify(),
+			"request_id": provenance.RequestId,
+			"model":      provenance.Model,
+			"timestamp":  provenance.Timestamp,
+		},
+	})
+}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/service"
+	"strings"
+	"time"
+)
+
+// 定时拉取供应商状态页，供应商报告重大故障时暂停直连该供应商官方地址的渠道，故障恢复后自动启用。
+// 分组中最后一个启用的渠道不会被暂停
+
+const (
+	providerStatusFormatStatuspage = "statuspage"
+	providerStatusFormatRSS        = "rss"
+)
+
+type providerStatusSource struct {
+	Name         string
+	URL          string
+	Format       string
+	ChannelTypes []int
+	// Hosts 供应商官方接口的域名，Base URL 为其他地址（代理、自建服务）的渠道不受状态页影响
+	Hosts []string
+	// RSS 源包含整个云平台的事件，只关心标题或描述中包含关键字的条目，且只暂停设置了 provider_status_region
+	// 并且事件提到该区域的渠道
+	Keyword string
+}
+
+var providerStatusSources = []providerStatusSource{
+	{
+		Name:         "OpenAI",
+		URL:          "https://status.openai.com/api/v2/status.json",
+		Format:       providerStatusFormatStatuspage,
+		ChannelTypes: []int{common.ChannelTypeOpenAI},
+		Hosts:        []string{"api.openai.com"},
+	},
+	{
+		Name:         "Anthropic",
+		URL:          "https://status.anthropic.com/api/v2/status.json",
+		Format:       providerStatusFormatStatuspage,
+		ChannelTypes: []int{common.ChannelTypeAnthropic},
+		Hosts:        []string{"api.anthropic.com"},
+	},
+	{
+		Name:         "Azure",
+		URL:          "https://azure.status.microsoft/en-us/status/feed/",
+		Format:       providerStatusFormatRSS,
+		ChannelTypes: []int{common.ChannelTypeAzure},
+		Hosts:        []string{"openai.azure.com", "cognitiveservices.azure.com"},
+		Keyword:      "azure openai",
+	},
+}
+
+type statuspageResponse struct {
+	Status struct {
+		Indicator   string `json:"indicator"`
+		Description string `json:"description"`
+	} `json:"status"`
+}
+
+type providerStatusRSS struct {
+	Channel struct {
+		Items []struct {
+			Title       string `xml:"title"`
+			Description string `xml:"description"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// providerStatusResolvedWords RSS 中已缓解、已恢复的事件和事后报告不视为故障
+var providerStatusResolvedWords = []string{"resolved", "mitigated", "post incident review", "pir"}
+
+// fetchProviderIncidents 返回供应商当前的重大故障描述，状态页只在 major、critical 级别时返回，没有故障时返回空列表
+func fetchProviderIncidents(source providerStatusSource) ([]string, error) {
+	resp, err := service.GetImpatientHttpClient().Get(source.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch source.Format {
+	case providerStatusFormatStatuspage:
+		var status statuspageResponse
+		err = json.Unmarshal(body, &status)
+		if err != nil {
+			return nil, err
+		}
+		switch status.Status.Indicator {
+		case "major", "critical":
+			return []string{status.Status.Description}, nil
+		}
+		return nil, nil
+	case providerStatusFormatRSS:
+		var feed providerStatusRSS
+		err = xml.Unmarshal(body, &feed)
+		if err != nil {
+			return nil, err
+		}
+		var incidents []string
+		for _, item := range feed.Channel.Items {
+			text := strings.ToLower(item.Title + " " + item.Description)
+			if !strings.Contains(text, source.Keyword) || containsAnyWord(strings.ToLower(item.Title), providerStatusResolvedWords) {
+				continue
+			}
+			incidents = append(incidents, item.Title+" "+item.Description)
+		}
+		return incidents, nil
+	}
+	return nil, fmt.Errorf("unknown status format: %s", source.Format)
+}
+
+// containsAnyWord 按单词匹配，避免 pir 之类的短词匹配到其他单词的一部分
+func containsAnyWord(text string, words []string) bool {
+	padded := " " + strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), " ") + " "
+	for _, word := range words {
+		if strings.Contains(padded, " "+word+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isProviderStatusChannel 渠道类型匹配且 Base URL 为供应商官方地址
+func isProviderStatusChannel(source providerStatusSource, channel *model.Channel) bool {
+	matched := false
+	for _, channelType := range source.ChannelTypes {
+		if channel.Type == channelType {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" && channel.Type >= 0 && channel.Type < len(common.ChannelBaseURLs) {
+		baseURL = common.ChannelBaseURLs[channel.Type]
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, h := range source.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// channelIncident 返回影响该渠道的故障，RSS 源只匹配提到渠道所在区域的事件
+func channelIncident(source providerStatusSource, channel *model.Channel, incidents []string) string {
+	if source.Format != providerStatusFormatRSS {
+		if len(incidents) > 0 {
+			return incidents[0]
+		}
+		return ""
+	}
+	region, _ := channel.GetSetting()[constant.ChannelSettingProviderStatusRegion].(string)
+	region = strings.ToLower(strings.TrimSpace(region))
+	if region == "" {
+		return ""
+	}
+	for _, incident := range incidents {
+		if strings.Contains(strings.ToLower(incident), region) {
+			return incident
+		}
+	}
+	return ""
+}
+
+// countEnabledChannelsByGroup 统计每个分组中启用的渠道数
+func countEnabledChannelsByGroup(channels []*model.Channel) map[string]int {
+	counts := make(map[string]int)
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		for _, group := range channel.GetGroups() {
+			counts[group]++
+		}
+	}
+	return counts
+}
+
+// isLastEnabledChannel 渠道是否为某个分组中最后一个启用的渠道
+func isLastEnabledChannel(channel *model.Channel, enabledCounts map[string]int) bool {
+	for _, group := range channel.GetGroups() {
+		if enabledCounts[group] <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func checkProviderStatus(channels []*model.Channel, source providerStatusSource, enabledCounts map[string]int) {
+	incidents, err := fetchProviderIncidents(source)
+	if err != nil {
+		// 拉取失败时不做任何处理，避免误判
+		common.SysError(fmt.Sprintf("failed to fetch %s status: %s", source.Name, err.Error()))
+		return
+	}
+	for _, channel := range channels {
+		if !isProviderStatusChannel(source, channel) {
+			continue
+		}
+		incident := channelIncident(source, channel, incidents)
+		if incident != "" {
+			if channel.Status != common.ChannelStatusEnabled || !channel.GetAutoBan() {
+				continue
+			}
+			if ignore, ok := channel.GetSetting()[constant.ChannelSettingIgnoreProviderStatus].(bool); ok && ignore {
+				continue
+			}
+			if isLastEnabledChannel(channel, enabledCounts) {
+				common.SysLog(fmt.Sprintf("channel #%d is the last enabled channel of its group, skip pausing for %s incident", channel.Id, source.Name))
+				continue
+			}
+			reason := fmt.Sprintf("%s 状态页报告故障：%s", source.Name, incident)
+			service.PauseChannelForProviderIncident(channel.Id, channel.Name, source.Name, reason)
+			channel.Status = common.ChannelStatusAutoDisabled
+			for _, group := range channel.GetGroups() {
+				enabledCounts[group]--
+			}
+		} else if channel.Status == common.ChannelStatusAutoDisabled && channel.GetProviderIncident() == source.Name {
+			service.ResumeChannelAfterProviderIncident(channel.Id, channel.Name)
+			channel.Status = common.ChannelStatusEnabled
+			for _, group := range channel.GetGroups() {
+				enabledCounts[group]++
+			}
+		}
+	}
+}
+
+func AutomaticallyCheckProviderStatus(frequency int) {
+	for {
+		time.Sleep(time.Duration(frequency) * time.Minute)
+		channels, err := model.GetAllChannels(0, 0, true, false)
+		if err != nil {
+			common.SysError("failed to get channels: " + err.Error())
+			continue
+		}
+		enabledCounts := countEnabledChannelsByGroup(channels)
+		for _, source := range providerStatusSources {
+			checkProviderStatus(channels, source, enabledCounts)
+		}
+	}
+}
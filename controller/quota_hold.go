@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuotaHolds 管理员查看所有预扣记录，可按用户和令牌过滤
+func GetQuotaHolds(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetQuotaHolds(userId, tokenId),
+	})
+}
+
+// GetSelfQuotaHolds 用户查看自己的预扣记录
+func GetSelfQuotaHolds(c *gin.Context) {
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    service.GetQuotaHolds(c.GetInt("id"), tokenId),
+	})
+}
+
+func ReleaseQuotaHold(c *gin.Context) {
+	err := service.ReleaseQuotaHold(c.Param("id"), "管理员手动释放预扣额度")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -26,7 +26,7 @@ import (
 func relayHandler(c *gin.Context, relayMode int) *dto.OpenAIErrorWithStatusCode {
 	var err *dto.OpenAIErrorWithStatusCode
 	switch relayMode {
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		err = relay.ImageHelper(c)
 	case relayconstant.RelayModeAudioSpeech:
 		fallthrough
@@ -38,6 +38,8 @@ func relayHandler(c *gin.Context, relayMode int) *dto.OpenAIErrorWithStatusCode
 		err = relay.RerankHelper(c, relayMode)
 	case relayconstant.RelayModeEmbeddings:
 		err = relay.EmbeddingHelper(c)
+	case relayconstant.RelayModeModerations:
+		err = relay.ModerationHelper(c)
 	case relayconstant.RelayModeResponses:
 		err = relay.ResponsesHelper(c)
 	default:
@@ -122,13 +124,12 @@ func WssRelay(c *gin.Context) {
 	// 将 HTTP 连接升级为 WebSocket 连接
 
 	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	defer ws.Close()
-
 	if err != nil {
-		openaiErr := service.OpenAIErrorWrapper(err, "get_channel_failed", http.StatusInternalServerError)
-		helper.WssError(c, ws, openaiErr.Error)
+		// 升级失败时 Upgrade 已经返回了 HTTP 错误响应
+		common.LogError(c, "websocket upgrade failed: "+err.Error())
 		return
 	}
+	defer ws.Close()
 
 	relayMode := constant.Path2RelayMode(c.Request.URL.Path)
 	requestId := c.GetString(common.RequestIdKey)
@@ -261,6 +262,12 @@ func getChannel(c *gin.Context, group, originalModel string, retryCount int) (*m
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("获取重试渠道失败: %s", err.Error()))
 	}
+	if endpoint := service.GetImageEndpoint(c.Request.URL.Path); endpoint != "" {
+		channel, err = service.SelectImageEndpointChannel(channel, group, originalModel, endpoint, retryCount)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("获取重试渠道失败: %s", err.Error()))
+		}
+	}
 	middleware.SetupContextForSelectedChannel(c, channel, originalModel)
 	return channel, nil
 }
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscribePlanRequest struct {
+	Plan    string `json:"plan" binding:"required"`
+	Periods int    `json:"periods"`
+}
+
+func GetSubscriptionPlans(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    setting.GetSubscriptionPlans(),
+	})
+}
+
+func getUserSubscription(c *gin.Context, userId int) {
+	sub, err := model.GetUserSubscription(userId)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    sub,
+	})
+}
+
+func GetSelfSubscription(c *gin.Context) {
+	getUserSubscription(c, c.GetInt("id"))
+}
+
+// PurchaseSubscription 用户消耗自己的额度购买订阅套餐
+func PurchaseSubscription(c *gin.Context) {
+	userId := c.GetInt("id")
+	req := SubscribePlanRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	plan, ok := setting.GetSubscriptionPlan(req.Plan)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "套餐不存在",
+		})
+		return
+	}
+	if plan.Price <= 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "该套餐只能由管理员分配",
+		})
+		return
+	}
+	if req.Periods < 1 {
+		req.Periods = 1
+	}
+	if req.Periods > model.SubscriptionMaxPeriods {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("一次最多购买 %d 个周期", model.SubscriptionMaxPeriods),
+		})
+		return
+	}
+	sub, cost, err := model.SubscribePlan(userId, plan, req.Periods, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "购买失败 " + err.Error(),
+		})
+		return
+	}
+	logContent := fmt.Sprintf("购买订阅套餐 %s %d 个周期，消耗 %s", plan.Name, req.Periods, common.LogQuota(cost))
+	if cost < 0 {
+		logContent = fmt.Sprintf("购买订阅套餐 %s %d 个周期，原套餐未开始的周期退还 %s", plan.Name, req.Periods, common.LogQuota(-cost))
+	}
+	model.RecordLog(userId, model.LogTypeSystem, logContent)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    sub,
+	})
+}
+
+func GetUserSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	getUserSubscription(c, id)
+}
+
+// AssignUserSubscription 管理员为用户分配套餐，不扣除额度，periods 为 0 时不过期
+func AssignUserSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	req := SubscribePlanRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	plan, ok := setting.GetSubscriptionPlan(req.Plan)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "套餐不存在",
+		})
+		return
+	}
+	if req.Periods < 0 {
+		req.Periods = 0
+	}
+	if req.Periods > model.SubscriptionMaxPeriods {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("一次最多分配 %d 个周期", model.SubscriptionMaxPeriods),
+		})
+		return
+	}
+	sub, _, err := model.SubscribePlan(id, plan, req.Periods, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(id, model.LogTypeManage, fmt.Sprintf("管理员分配订阅套餐 %s", plan.Name))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    sub,
+	})
+}
+
+func CancelUserSubscription(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err := model.CancelSubscription(id); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(id, model.LogTypeManage, "管理员取消订阅套餐")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
+	"one-api/setting"
 	"strconv"
 )
 
@@ -123,6 +127,13 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateTokenQuotaLimit(&token); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -145,6 +156,9 @@ func AddToken(c *gin.Context) {
 		ModelLimits:        token.ModelLimits,
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
+		DailyQuotaLimit:    token.DailyQuotaLimit,
+		MonthlyQuotaLimit:  token.MonthlyQuotaLimit,
+		QuotaLimitTimezone: token.QuotaLimitTimezone,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -198,6 +212,13 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateTokenQuotaLimit(&token); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -234,6 +255,9 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.ModelLimits = token.ModelLimits
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
+		cleanToken.DailyQuotaLimit = token.DailyQuotaLimit
+		cleanToken.MonthlyQuotaLimit = token.MonthlyQuotaLimit
+		cleanToken.QuotaLimitTimezone = token.QuotaLimitTimezone
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -250,3 +274,76 @@ func UpdateToken(c *gin.Context) {
 	})
 	return
 }
+
+func GetTokenRateLimitTiers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    setting.GetTokenRateLimitTiers(),
+	})
+}
+
+type PurchaseTokenRateLimitTierRequest struct {
+	Tier string `json:"tier" binding:"required"`
+}
+
+// PurchaseTokenRateLimitTier 用户消耗自己的额度为令牌购买限流等级
+func PurchaseTokenRateLimitTier(c *gin.Context) {
+	userId := c.GetInt("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	req := PurchaseTokenRateLimitTierRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if !setting.ModelRequestRateLimitEnabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "管理员未启用模型请求速率限制，无需购买限流等级",
+		})
+		return
+	}
+	tier, ok := setting.GetTokenRateLimitTier(req.Tier)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "限流等级不存在",
+		})
+		return
+	}
+	token, err := model.PurchaseTokenRateLimitTier(id, userId, tier.Name, tier.Quota, tier.Days)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "购买失败 " + err.Error(),
+		})
+		return
+	}
+	model.RecordLog(userId, model.LogTypeSystem, fmt.Sprintf("令牌 %s 购买限流等级 %s，消耗 %s", token.Name, tier.Name, common.LogQuota(tier.Quota)))
+	token.Clean()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    token,
+	})
+}
+
+func validateTokenQuotaLimit(token *model.Token) error {
+	if token.DailyQuotaLimit < 0 || token.MonthlyQuotaLimit < 0 {
+		return errors.New("令牌消费上限不能为负数")
+	}
+	if _, err := service.LoadTokenSpendLocation(token.QuotaLimitTimezone); err != nil {
+		return fmt.Errorf("无效的时区 %s", token.QuotaLimitTimezone)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// stripeWebhookMaxBodySize Stripe 事件的最大长度
+const stripeWebhookMaxBodySize = 1 << 20
+
+var errStripeAmountMismatch = errors.New("stripe payment amount mismatch")
+
+func getStripePayMoney(amount int64, group string) float64 {
+	dAmount := decimal.NewFromInt(amount)
+	if !common.DisplayInCurrencyEnabled {
+		dAmount = dAmount.Div(decimal.NewFromFloat(common.QuotaPerUnit))
+	}
+	topupGroupRatio := common.GetTopupGroupRatio(group)
+	if topupGroupRatio == 0 {
+		topupGroupRatio = 1
+	}
+	payMoney := dAmount.Mul(decimal.NewFromFloat(setting.StripeUnitPrice)).Mul(decimal.NewFromFloat(topupGroupRatio))
+	return payMoney.InexactFloat64()
+}
+
+func RequestStripeAmount(c *gin.Context) {
+	var req AmountRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	if req.Amount < getMinTopup() {
+		c.JSON(200, gin.H{"message": "error", "data": fmt.Sprintf("充值数量不能小于 %d", getMinTopup())})
+		return
+	}
+	group, err := model.GetUserGroup(c.GetInt("id"), true)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户分组失败"})
+		return
+	}
+	payMoney := getStripePayMoney(req.Amount, group)
+	c.JSON(200, gin.H{"message": "success", "data": decimal.NewFromFloat(payMoney).StringFixed(2), "currency": setting.StripeCurrency})
+}
+
+// RequestStripePay 创建待支付订单和 Stripe Checkout Session，返回支付页面地址
+func RequestStripePay(c *gin.Context) {
+	var req AmountRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "参数错误"})
+		return
+	}
+	if !service.StripeEnabled() {
+		c.JSON(200, gin.H{"message": "error", "data": "当前管理员未配置 Stripe 支付"})
+		return
+	}
+	if req.Amount < getMinTopup() {
+		c.JSON(200, gin.H{"message": "error", "data": fmt.Sprintf("充值数量不能小于 %d", getMinTopup())})
+		return
+	}
+	id := c.GetInt("id")
+	group, err := model.GetUserGroup(id, true)
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "获取用户分组失败"})
+		return
+	}
+	payMoney := getStripePayMoney(req.Amount, group)
+	if service.StripeAmount(payMoney, setting.StripeCurrency) <= 0 {
+		c.JSON(200, gin.H{"message": "error", "data": "充值金额过低"})
+		return
+	}
+	amount := req.Amount
+	if !common.DisplayInCurrencyEnabled {
+		dAmount := decimal.NewFromInt(amount)
+		dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+		amount = dAmount.Div(dQuotaPerUnit).IntPart()
+	}
+	tradeNo := fmt.Sprintf("USR%dNO%s%d", id, common.GetRandomString(6), time.Now().Unix())
+	// 先记录订单再创建支付，保证 Webhook 到达时订单已存在
+	topUp := &model.TopUp{
+		UserId:        id,
+		Amount:        amount,
+		Money:         payMoney,
+		TradeNo:       tradeNo,
+		CreateTime:    time.Now().Unix(),
+		Status:        "pending",
+		PaymentMethod: "stripe",
+	}
+	err = topUp.Insert()
+	if err != nil {
+		c.JSON(200, gin.H{"message": "error", "data": "创建订单失败"})
+		return
+	}
+	session, err := service.CreateStripeCheckoutSession(tradeNo, fmt.Sprintf("TUC%d", req.Amount), payMoney,
+		setting.ServerAddress+"/log", setting.ServerAddress+"/topup")
+	if err != nil {
+		common.SysError("failed to create stripe checkout session: " + err.Error())
+		c.JSON(200, gin.H{"message": "error", "data": "拉起支付失败"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "success", "data": session.Url})
+}
+
+// StripeWebhook 处理支付完成和退款事件，处理失败时返回 5xx 让 Stripe 重试，重复推送的事件不会重复入账
+func StripeWebhook(c *gin.Context) {
+	// 未配置 Stripe 时不接受回调，否则可以用空密钥伪造签名
+	if !service.StripeEnabled() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, stripeWebhookMaxBodySize))
+	if err != nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	event, err := service.ConstructStripeEvent(payload, c.GetHeader("Stripe-Signature"), setting.StripeWebhookSecret)
+	if err != nil {
+		common.SysError("stripe webhook signature verification failed: " + err.Error())
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		var session service.StripeCheckoutSession
+		if err = json.Unmarshal(event.Data.Object, &session); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		// 异步支付方式在 completed 时尚未付款，等待 async_payment_succeeded
+		if session.PaymentStatus != "paid" {
+			break
+		}
+		err = completeStripeTopUp(&session)
+	case "charge.refunded":
+		var charge service.StripeCharge
+		if err = json.Unmarshal(event.Data.Object, &charge); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		err = refundStripeTopUp(&charge)
+	}
+	if errors.Is(err, model.ErrTopUpNotFound) {
+		// 不是本系统创建的支付，重试也无法处理
+		common.SysError(fmt.Sprintf("order of stripe event %s (%s) not found", event.Id, event.Type))
+	} else if errors.Is(err, errStripeAmountMismatch) {
+		common.SysError(fmt.Sprintf("stripe event %s (%s) rejected: %s", event.Id, event.Type, err.Error()))
+	} else if err != nil {
+		common.SysError(fmt.Sprintf("failed to handle stripe event %s (%s): %s", event.Id, event.Type, err.Error()))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+func completeStripeTopUp(session *service.StripeCheckoutSession) error {
+	tradeNo := session.ClientReferenceId
+	if tradeNo == "" {
+		tradeNo = session.Metadata["trade_no"]
+	}
+	paymentId := session.PaymentIntent
+	if paymentId == "" {
+		paymentId = session.Id
+	}
+	order := model.GetTopUpByTradeNo(tradeNo)
+	if order == nil || order.PaymentMethod != "stripe" {
+		return model.ErrTopUpNotFound
+	}
+	// 实付金额与订单金额不一致时不入账
+	if expected := service.StripeAmount(order.Money, session.Currency); session.AmountTotal != expected {
+		return fmt.Errorf("%w: session %s paid %d %s, expected %d", errStripeAmountMismatch, session.Id,
+			session.AmountTotal, session.Currency, expected)
+	}
+	topUp, err := model.CompleteTopUp(tradeNo, "stripe", paymentId)
+	if err != nil || topUp == nil {
+		return err
+	}
+	common.SysLog(fmt.Sprintf("stripe top-up completed, trade no: %s, user: %d", topUp.TradeNo, topUp.UserId))
+	model.RecordLog(topUp.UserId, model.LogTypeTopup, fmt.Sprintf("使用 Stripe 在线充值成功，充值金额: %v，支付金额：%.2f %s",
+		common.LogQuota(topUp.Quota()), topUp.Money, setting.StripeCurrency))
+	return nil
+}
+
+func refundStripeTopUp(charge *service.StripeCharge) error {
+	if charge.PaymentIntent == "" || charge.Amount <= 0 {
+		return nil
+	}
+	ratio := float64(charge.AmountRefunded) / float64(charge.Amount)
+	if charge.Refunded {
+		ratio = 1
+	}
+	topUp, quota, err := model.RefundTopUp(charge.PaymentIntent, ratio)
+	if err != nil || quota == 0 {
+		return err
+	}
+	common.SysLog(fmt.Sprintf("stripe top-up refunded, trade no: %s, user: %d, quota: %d", topUp.TradeNo, topUp.UserId, quota))
+	model.RecordLog(topUp.UserId, model.LogTypeTopup, fmt.Sprintf("Stripe 充值退款，扣除额度: %v，订单号：%s",
+		common.LogQuota(quota), topUp.TradeNo))
+	return nil
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/relay/helper"
+	"one-api/setting/model_setting"
+	"sync"
+	"time"
+)
+
+// 开启上游模型列表后定时拉取每个启用渠道的上游模型列表，/v1/models 只返回至少有一个渠道的上游实际提供的模型，
+// 拉取失败或不支持模型列表接口的渠道仍按配置的模型计算
+
+var channelUpstreamModels = make(map[int]map[string]bool)
+var channelUpstreamModelsLock sync.RWMutex
+
+// DiscoverUpstreamModels 拉取所有启用渠道的上游模型列表，每个节点分别拉取并保存在内存中
+func DiscoverUpstreamModels() {
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		common.SysError("failed to get channels: " + err.Error())
+		return
+	}
+	discovered := make(map[int]map[string]bool)
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		ids, err := fetchChannelUpstreamModels(channel)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to fetch upstream models of channel #%d: %s", channel.Id, err.Error()))
+			continue
+		}
+		models := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			models[id] = true
+		}
+		discovered[channel.Id] = models
+		time.Sleep(common.RequestInterval)
+	}
+	channelUpstreamModelsLock.Lock()
+	channelUpstreamModels = discovered
+	channelUpstreamModelsLock.Unlock()
+	common.SysLog(fmt.Sprintf("upstream models discovered for %d channels", len(discovered)))
+}
+
+func AutomaticallyDiscoverUpstreamModels(frequency int) {
+	for {
+		if model_setting.GetGlobalSettings().UpstreamModelListEnabled {
+			DiscoverUpstreamModels()
+			time.Sleep(time.Duration(frequency) * time.Minute)
+		} else {
+			time.Sleep(time.Minute)
+		}
+	}
+}
+
+// channelUpstreamModelName 按渠道的模型重定向和出站映射得到发送给上游的模型名称
+func channelUpstreamModelName(channel *model.Channel, modelName string) string {
+	if modelMapping := channel.GetModelMapping(); modelMapping != "" && modelMapping != "{}" {
+		if mappedModel, mapped, err := helper.MapModelName(modelMapping, modelName); err == nil && mapped {
+			modelName = mappedModel
+		}
+	}
+	return helper.MapUpstreamModel(channel.GetSetting(), modelName)
+}
+
+// getUpstreamGroupModels 返回分组下至少有一个渠道可以提供的模型
+func getUpstreamGroupModels(group string) (map[string]bool, error) {
+	abilities, err := model.GetGroupAbilities(group)
+	if err != nil {
+		return nil, err
+	}
+	channelUpstreamModelsLock.RLock()
+	defer channelUpstreamModelsLock.RUnlock()
+	models := make(map[string]bool)
+	for _, ability := range abilities {
+		if models[ability.Model] {
+			continue
+		}
+		upstreamModels, ok := channelUpstreamModels[ability.ChannelId]
+		if !ok {
+			models[ability.Model] = true
+			continue
+		}
+		channel, err := model.CacheGetChannel(ability.ChannelId)
+		if err != nil {
+			continue
+		}
+		if upstreamModels[channelUpstreamModelName(channel, ability.Model)] {
+			models[ability.Model] = true
+		}
+	}
+	return models, nil
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"one-api/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSelfUsageStatement 导出当前用户的用量明细
+func ExportSelfUsageStatement(c *gin.Context) {
+	exportUsageStatement(c, c.GetInt("id"))
+}
+
+// ExportUserUsageStatement 管理员导出指定用户的用量明细
+func ExportUserUsageStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	exportUsageStatement(c, id)
+}
+
+// exportUsageStatement 按 format（csv 或 pdf，默认 csv）导出区间 [start_timestamp, end_timestamp) 内按模型汇总的用量，
+// 未指定区间时导出本月的用量；token_id 不为 0 时只导出该令牌的用量
+func exportUsageStatement(c *gin.Context, userId int) {
+	format := c.DefaultQuery("format", service.UsageStatementFormatCSV)
+	if format != service.UsageStatementFormatCSV && format != service.UsageStatementFormatPDF {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "不支持的导出格式",
+		})
+		return
+	}
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp == 0 && endTimestamp == 0 {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		startTimestamp = monthStart.Unix()
+		endTimestamp = monthStart.AddDate(0, 1, 0).Unix()
+	}
+	if endTimestamp != 0 && endTimestamp <= startTimestamp {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "结束时间必须晚于开始时间",
+		})
+		return
+	}
+	statement, err := service.GetUsageStatement(userId, tokenId, startTimestamp, endTimestamp)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == service.UsageStatementFormatPDF {
+		contentType = "application/pdf"
+		err = service.WriteUsageStatementPDF(&buf, statement)
+	} else {
+		err = service.WriteUsageStatementCSV(&buf, statement)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	filename := fmt.Sprintf("usage-statement-%d-%s.%s", userId, time.Unix(startTimestamp, 0).Format("20060102"), format)
+	if tokenId != 0 {
+		filename = fmt.Sprintf("usage-statement-%d-token-%d-%s.%s", userId, tokenId, time.Unix(startTimestamp, 0).Format("20060102"), format)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
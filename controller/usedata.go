@@ -4,9 +4,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"one-api/model"
+	"one-api/setting/operation_setting"
 	"strconv"
 )
 
+// fillQuotaDataAmount 按显示币种和汇率换算每条数据的金额
+func fillQuotaDataAmount(dates []*model.QuotaData) {
+	for _, date := range dates {
+		date.Amount = operation_setting.QuotaToDisplayAmount(int64(date.Quota))
+	}
+}
+
 func GetAllQuotaDates(c *gin.Context) {
 	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
 	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
@@ -19,6 +27,7 @@ func GetAllQuotaDates(c *gin.Context) {
 		})
 		return
 	}
+	fillQuotaDataAmount(dates)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -47,6 +56,7 @@ func GetUserQuotaDates(c *gin.Context) {
 		})
 		return
 	}
+	fillQuotaDataAmount(dates)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -54,3 +64,23 @@ func GetUserQuotaDates(c *gin.Context) {
 	})
 	return
 }
+
+func GetDBMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetDBMetrics(),
+	})
+}
+
+func GetUsageHistograms(c *gin.Context) {
+	since, histograms := model.GetUsageHistograms(c.Query("model_name"))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"since":      since,
+			"histograms": histograms,
+		},
+	})
+}
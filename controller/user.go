@@ -517,6 +517,23 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if updatedUser.BillingMode == "" {
+		updatedUser.BillingMode = common.UserBillingModePrepaid
+	}
+	if updatedUser.BillingMode != common.UserBillingModePrepaid && updatedUser.BillingMode != common.UserBillingModePostpaid {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的计费方式",
+		})
+		return
+	}
+	if updatedUser.CreditLimit < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "信用额度不能为负数",
+		})
+		return
+	}
 	originUser, err := model.GetUserById(updatedUser.Id, false)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -554,6 +571,9 @@ func UpdateUser(c *gin.Context) {
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", common.LogQuota(originUser.Quota), common.LogQuota(updatedUser.Quota)))
 	}
+	if originUser.BillingMode != updatedUser.BillingMode || originUser.CreditLimit != updatedUser.CreditLimit {
+		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户计费方式修改为 %s，信用额度 %s", updatedUser.BillingMode, common.LogQuota(updatedUser.CreditLimit)))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportSelfData 下载个人数据压缩包，包含个人资料、令牌信息和使用日志
+func ExportSelfData(c *gin.Context) {
+	id := c.GetInt("id")
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user-data-%d.zip", id))
+	err := service.WriteUserDataArchive(c.Writer, id)
+	if err != nil {
+		// 响应已经开始写入，只能记录错误
+		common.SysError(fmt.Sprintf("failed to export data of user %d: %s", id, err.Error()))
+		return
+	}
+	model.RecordLog(id, model.LogTypeSystem, "导出个人数据")
+}
+
+func GetSelfErasureRequest(c *gin.Context) {
+	request, err := model.GetLatestUserErasureRequest(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    request,
+	})
+}
+
+// RequestSelfErasure 提交注销申请，管理员审核通过后删除账户
+func RequestSelfErasure(c *gin.Context) {
+	id := c.GetInt("id")
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if user.Role == common.RoleRootUser {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "不能删除超级管理员账户",
+		})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if len([]rune(req.Reason)) > 255 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "注销原因不能超过 255 个字符",
+		})
+		return
+	}
+	request, err := model.CreateUserErasureRequest(id, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	model.RecordLog(id, model.LogTypeSystem, "提交账户注销申请")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    request,
+	})
+}
+
+func CancelSelfErasure(c *gin.Context) {
+	err := model.CancelUserErasureRequest(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+func GetUserErasureRequests(c *gin.Context) {
+	p, _ := strconv.Atoi(c.Query("p"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	status, _ := strconv.Atoi(c.Query("status"))
+	if p < 1 {
+		p = 1
+	}
+	if pageSize <= 0 {
+		pageSize = common.ItemsPerPage
+	}
+	requests, total, err := model.GetUserErasureRequests(status, (p-1)*pageSize, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"items":     requests,
+			"total":     total,
+			"page":      p,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// ProcessUserErasureRequest 管理员审核注销申请，action 为 approve 或 reject
+func ProcessUserErasureRequest(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req struct {
+		Action string `json:"action"`
+		Remark string `json:"remark"`
+	}
+	err := c.ShouldBindJSON(&req)
+	if err != nil || (req.Action != "approve" && req.Action != "reject") {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的参数",
+		})
+		return
+	}
+	err = model.ProcessUserErasureRequest(id, c.GetInt("id"), req.Action == "approve", req.Remark)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
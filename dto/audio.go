@@ -6,6 +6,8 @@ type AudioRequest struct {
 	Voice          string  `json:"voice"`
 	Speed          float64 `json:"speed,omitempty"`
 	ResponseFormat string  `json:"response_format,omitempty"`
+	Instructions   string  `json:"instructions,omitempty"`
+	StreamFormat   string  `json:"stream_format,omitempty"`
 }
 
 type AudioResponse struct {
@@ -0,0 +1,92 @@
+package dto
+
+import "encoding/json"
+
+// OpenAIFile /v1/files 返回的文件对象
+type OpenAIFile struct {
+	Id        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+type BatchRequest struct {
+	InputFileId      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// BatchInputLine 批量请求输入文件中的一行
+type BatchInputLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type Batch struct {
+	Id               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           any                `json:"errors"`
+	InputFileId      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileId     *string            `json:"output_file_id"`
+	ErrorFileId      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+// BatchOutputLine 批量请求输出文件和错误文件中的一行
+type BatchOutputLine struct {
+	Id       string               `json:"id"`
+	CustomId string               `json:"custom_id"`
+	Response *BatchOutputResponse `json:"response"`
+	Error    *BatchOutputError    `json:"error"`
+}
+
+type BatchOutputResponse struct {
+	StatusCode int    `json:"status_code"`
+	RequestId  string `json:"request_id"`
+	Body       any    `json:"body"`
+}
+
+type BatchOutputError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchTaskData 批量任务保存在任务表 data 字段中的上游状态，转换后的结果保存为用户文件，这里只记录文件 id；
+// Output 和 ErrorOutput 只在保存文件前临时使用，旧任务的结果仍保存在这两个字段中
+type BatchTaskData struct {
+	ProcessingStatus string             `json:"processing_status"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Canceled         bool               `json:"canceled,omitempty"`
+	ExpiresAt        int64              `json:"expires_at,omitempty"`
+	CancelingAt      int64              `json:"canceling_at,omitempty"`
+	Output           string             `json:"output,omitempty"`
+	ErrorOutput      string             `json:"error_output,omitempty"`
+	OutputFileId     string             `json:"output_file_id,omitempty"`
+	ErrorFileId      string             `json:"error_file_id,omitempty"`
+	// 网关执行时使用提交批量请求的令牌和分组
+	TokenId int    `json:"token_id,omitempty"`
+	Group   string `json:"group,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
@@ -0,0 +1,53 @@
+package dto
+
+const (
+	BillingItemPrompt        = "prompt"
+	BillingItemCachedPrompt  = "cached_prompt"
+	BillingItemCacheCreation = "cache_creation"
+	BillingItemImageInput    = "image_input"
+	BillingItemAudioInput    = "audio_input"
+	BillingItemCompletion    = "completion"
+	BillingItemReasoning     = "reasoning"
+	BillingItemAudioOutput   = "audio_output"
+	BillingItemWebSearch     = "web_search"
+	BillingItemFileSearch    = "file_search"
+	BillingItemRequest       = "request"
+)
+
+const (
+	BillingUnitTokens   = "tokens"
+	BillingUnitCalls    = "calls"
+	BillingUnitRequests = "requests"
+)
+
+// BillingBreakdown explains how the quota of a consume log was computed.
+// Token items are already multiplied by model ratio, group ratio and service
+// tier ratio, so the sum of item quotas equals Quota before rounding.
+type BillingBreakdown struct {
+	UsePrice   bool    `json:"use_price"`
+	ModelRatio float64 `json:"model_ratio"`
+	ModelPrice float64 `json:"model_price"`
+	GroupRatio float64 `json:"group_ratio"`
+	// ServiceTierRatio is the multiplier of the service tier used by the upstream, omitted for other billing paths
+	ServiceTierRatio float64           `json:"service_tier_ratio,omitempty"`
+	Items            []BillingLineItem `json:"items"`
+	Quota            int               `json:"quota"`
+}
+
+type BillingLineItem struct {
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit"`
+	// Ratio is the multiplier applied on top of model ratio, e.g. completion ratio
+	Ratio float64 `json:"ratio,omitempty"`
+	// Price is the USD price per unit, or per thousand calls for built-in tools
+	Price float64 `json:"price,omitempty"`
+	Quota float64 `json:"quota"`
+}
+
+func (b *BillingBreakdown) AddItem(item BillingLineItem) {
+	if item.Quantity == 0 && item.Quota == 0 {
+		return
+	}
+	b.Items = append(b.Items, item)
+}
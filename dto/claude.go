@@ -24,6 +24,8 @@ type ClaudeMediaMessage struct {
 	Input     any             `json:"input,omitempty"`
 	Content   json.RawMessage `json:"content,omitempty"`
 	ToolUseId string          `json:"tool_use_id,omitempty"`
+	// prompt caching
+	CacheControl any `json:"cache_control,omitempty"`
 }
 
 func (c *ClaudeMediaMessage) SetText(s string) {
@@ -112,6 +114,8 @@ type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
 	InputSchema map[string]interface{} `json:"input_schema"`
+	// CacheControl 提示缓存标记
+	CacheControl any `json:"cache_control,omitempty"`
 }
 
 type InputSchema struct {
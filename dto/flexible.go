@@ -0,0 +1,279 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlexibleInt 兼容上游以浮点数、字符串或 null 返回的整数字段，浮点数截断为整数，null 和空字符串视为 0
+type FlexibleInt int
+
+func (i *FlexibleInt) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		*i = 0
+		return nil
+	}
+	value := string(data)
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			*i = 0
+			return nil
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		*i = FlexibleInt(n)
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer value: %s", data)
+	}
+	*i = FlexibleInt(f)
+	return nil
+}
+
+// UnmarshalJSON 用量中的 token 字段按 FlexibleInt 解析，缺少的字段保留原值
+func (u *Usage) UnmarshalJSON(data []byte) error {
+	type usageAlias Usage
+	aux := struct {
+		*usageAlias
+		PromptTokens             FlexibleInt `json:"prompt_tokens"`
+		CompletionTokens         FlexibleInt `json:"completion_tokens"`
+		TotalTokens              FlexibleInt `json:"total_tokens"`
+		PromptCacheHitTokens     FlexibleInt `json:"prompt_cache_hit_tokens"`
+		InputTokens              FlexibleInt `json:"input_tokens"`
+		OutputTokens             FlexibleInt `json:"output_tokens"`
+		CacheCreationInputTokens FlexibleInt `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     FlexibleInt `json:"cache_read_input_tokens"`
+	}{
+		usageAlias:               (*usageAlias)(u),
+		PromptTokens:             FlexibleInt(u.PromptTokens),
+		CompletionTokens:         FlexibleInt(u.CompletionTokens),
+		TotalTokens:              FlexibleInt(u.TotalTokens),
+		PromptCacheHitTokens:     FlexibleInt(u.PromptCacheHitTokens),
+		InputTokens:              FlexibleInt(u.InputTokens),
+		OutputTokens:             FlexibleInt(u.OutputTokens),
+		CacheCreationInputTokens: FlexibleInt(u.CacheCreationInputTokens),
+		CacheReadInputTokens:     FlexibleInt(u.CacheReadInputTokens),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	u.PromptTokens = int(aux.PromptTokens)
+	u.CompletionTokens = int(aux.CompletionTokens)
+	u.TotalTokens = int(aux.TotalTokens)
+	u.PromptCacheHitTokens = int(aux.PromptCacheHitTokens)
+	u.InputTokens = int(aux.InputTokens)
+	u.OutputTokens = int(aux.OutputTokens)
+	u.CacheCreationInputTokens = int(aux.CacheCreationInputTokens)
+	u.CacheReadInputTokens = int(aux.CacheReadInputTokens)
+	return nil
+}
+
+func (d *InputTokenDetails) UnmarshalJSON(data []byte) error {
+	type inputTokenDetailsAlias InputTokenDetails
+	aux := struct {
+		*inputTokenDetailsAlias
+		CachedTokens FlexibleInt `json:"cached_tokens"`
+		TextTokens   FlexibleInt `json:"text_tokens"`
+		AudioTokens  FlexibleInt `json:"audio_tokens"`
+		ImageTokens  FlexibleInt `json:"image_tokens"`
+	}{
+		inputTokenDetailsAlias: (*inputTokenDetailsAlias)(d),
+		CachedTokens:           FlexibleInt(d.CachedTokens),
+		TextTokens:             FlexibleInt(d.TextTokens),
+		AudioTokens:            FlexibleInt(d.AudioTokens),
+		ImageTokens:            FlexibleInt(d.ImageTokens),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.CachedTokens = int(aux.CachedTokens)
+	d.TextTokens = int(aux.TextTokens)
+	d.AudioTokens = int(aux.AudioTokens)
+	d.ImageTokens = int(aux.ImageTokens)
+	return nil
+}
+
+func (d *OutputTokenDetails) UnmarshalJSON(data []byte) error {
+	type outputTokenDetailsAlias OutputTokenDetails
+	aux := struct {
+		*outputTokenDetailsAlias
+		TextTokens      FlexibleInt `json:"text_tokens"`
+		AudioTokens     FlexibleInt `json:"audio_tokens"`
+		ReasoningTokens FlexibleInt `json:"reasoning_tokens"`
+	}{
+		outputTokenDetailsAlias: (*outputTokenDetailsAlias)(d),
+		TextTokens:              FlexibleInt(d.TextTokens),
+		AudioTokens:             FlexibleInt(d.AudioTokens),
+		ReasoningTokens:         FlexibleInt(d.ReasoningTokens),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.TextTokens = int(aux.TextTokens)
+	d.AudioTokens = int(aux.AudioTokens)
+	d.ReasoningTokens = int(aux.ReasoningTokens)
+	return nil
+}
+
+// FlexibleContent 兼容字符串或内容片段数组形式的内容，部分上游在流式响应中返回片段数组。
+// 片段数组保留原始 JSON，重新序列化时原样输出，不丢失图片、音频等片段
+type FlexibleContent struct {
+	text string
+	// parts 不为空时为原始的片段数组
+	parts json.RawMessage
+}
+
+func NewFlexibleContent(text string) *FlexibleContent {
+	return &FlexibleContent{text: text}
+}
+
+// Text 返回字符串内容，片段数组返回其中文本片段的拼接
+func (c *FlexibleContent) Text() string {
+	if c == nil {
+		return ""
+	}
+	return c.text
+}
+
+func (c *FlexibleContent) IsString() bool {
+	return c == nil || c.parts == nil
+}
+
+// Parts 返回内容片段，字符串内容返回单个文本片段
+func (c *FlexibleContent) Parts() []MediaContent {
+	if c == nil {
+		return nil
+	}
+	if c.parts == nil {
+		return []MediaContent{{Type: ContentTypeText, Text: c.text}}
+	}
+	message := Message{Content: c.parts}
+	return message.ParseContent()
+}
+
+func (c FlexibleContent) MarshalJSON() ([]byte, error) {
+	if c.parts != nil {
+		return c.parts, nil
+	}
+	return json.Marshal(c.text)
+}
+
+func (c *FlexibleContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || string(data) == "null":
+		*c = FlexibleContent{}
+	case data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = FlexibleContent{text: text}
+	case data[0] == '[':
+		parts := make(json.RawMessage, len(data))
+		copy(parts, data)
+		message := Message{Content: parts}
+		*c = FlexibleContent{text: message.StringContent(), parts: parts}
+	default:
+		return fmt.Errorf("content must be a string or an array of content parts: %s", data)
+	}
+	return nil
+}
+
+// ExtractThinking 取出片段数组中的思考片段（type 为 thinking 或 reasoning），返回思考内容和其余的内容，
+// 字符串内容原样返回
+func (c *FlexibleContent) ExtractThinking() (string, *FlexibleContent) {
+	if c.IsString() {
+		return "", c
+	}
+	var parts []json.RawMessage
+	if err := json.Unmarshal(c.parts, &parts); err != nil {
+		return "", c
+	}
+	var thinking strings.Builder
+	kept := make([]json.RawMessage, 0, len(parts))
+	for _, part := range parts {
+		var p struct {
+			Type      string `json:"type"`
+			Text      string `json:"text"`
+			Thinking  string `json:"thinking"`
+			Reasoning string `json:"reasoning"`
+		}
+		_ = json.Unmarshal(part, &p)
+		if p.Type != "thinking" && p.Type != "reasoning" {
+			kept = append(kept, part)
+			continue
+		}
+		thinking.WriteString(p.Thinking + p.Reasoning + p.Text)
+	}
+	if len(kept) == len(parts) {
+		return "", c
+	}
+	if len(kept) == 0 {
+		return thinking.String(), nil
+	}
+	data, _ := json.Marshal(kept)
+	message := Message{Content: data}
+	return thinking.String(), &FlexibleContent{text: message.StringContent(), parts: data}
+}
+
+// FlexibleStop 兼容字符串、字符串数组或 null 形式的 stop，数组中的空字符串和非字符串元素被忽略。
+// 只有一个停止序列时序列化为字符串
+type FlexibleStop []string
+
+func (s *FlexibleStop) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || string(data) == "null":
+		*s = nil
+	case data[0] == '"':
+		var stop string
+		if err := json.Unmarshal(data, &stop); err != nil {
+			return err
+		}
+		*s = nil
+		if stop != "" {
+			*s = FlexibleStop{stop}
+		}
+	case data[0] == '[':
+		var items []any
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		stops := make(FlexibleStop, 0, len(items))
+		for _, item := range items {
+			if stop, ok := item.(string); ok && stop != "" {
+				stops = append(stops, stop)
+			}
+		}
+		*s = stops
+	default:
+		return fmt.Errorf("stop must be a string or an array of strings: %s", data)
+	}
+	return nil
+}
+
+func (s FlexibleStop) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+// Limit 返回最多 n 个停止序列，超出上游限制时截断而不是报错
+func (s FlexibleStop) Limit(n int) FlexibleStop {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFlexibleIntUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    FlexibleInt
+		wantErr bool
+	}{
+		{"integer", `42`, 42, false},
+		{"negative", `-7`, -7, false},
+		{"float", `12.9`, 12, false},
+		{"exponent", `1e3`, 1000, false},
+		{"string", `"128"`, 128, false},
+		{"string float", `"3.5"`, 3, false},
+		{"string with spaces", `" 64 "`, 64, false},
+		{"empty string", `""`, 0, false},
+		{"null", `null`, 0, false},
+		{"invalid string", `"abc"`, 0, true},
+		{"boolean", `true`, 0, true},
+		{"object", `{}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := FlexibleInt(-1)
+			err := json.Unmarshal([]byte(tt.input), &value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s, got %d", tt.input, value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %s: %v", tt.input, err)
+			}
+			if value != tt.want {
+				t.Errorf("got %d, want %d", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageUnmarshalFlexibleTokens(t *testing.T) {
+	var usage Usage
+	data := `{"prompt_tokens":"10","completion_tokens":5.0,"total_tokens":null,` +
+		`"prompt_tokens_details":{"cached_tokens":"4"},"completion_tokens_details":{"reasoning_tokens":2.7}}`
+	if err := json.Unmarshal([]byte(data), &usage); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage.PromptTokens != 10 || usage.CompletionTokens != 5 || usage.TotalTokens != 0 {
+		t.Errorf("got prompt %d, completion %d, total %d", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	}
+	if usage.PromptTokensDetails.CachedTokens != 4 || usage.CompletionTokenDetails.ReasoningTokens != 2 {
+		t.Errorf("got cached %d, reasoning %d", usage.PromptTokensDetails.CachedTokens, usage.CompletionTokenDetails.ReasoningTokens)
+	}
+
+	if err := json.Unmarshal([]byte(`{"prompt_tokens":"x"}`), &usage); err == nil {
+		t.Error("expected error for invalid prompt_tokens")
+	}
+}
+
+func TestFlexibleContentUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantText string
+		isString bool
+		wantJSON string
+		wantErr  bool
+	}{
+		{"string", `"hello"`, "hello", true, `"hello"`, false},
+		{"empty string", `""`, "", true, `""`, false},
+		{"null", `null`, "", true, `""`, false},
+		{"text parts", `[{"type":"text","text":"hello "},{"type":"text","text":"world"}]`, "hello world", false,
+			`[{"type":"text","text":"hello "},{"type":"text","text":"world"}]`, false},
+		{"image part", `[{"type":"text","text":"see"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]`, "see", false,
+			`[{"type":"text","text":"see"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]`, false},
+		{"number", `12`, "", false, "", true},
+		{"object", `{"text":"hello"}`, "", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content FlexibleContent
+			err := json.Unmarshal([]byte(tt.input), &content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %s", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %s: %v", tt.input, err)
+			}
+			if content.Text() != tt.wantText {
+				t.Errorf("text got %q, want %q", content.Text(), tt.wantText)
+			}
+			if content.IsString() != tt.isString {
+				t.Errorf("IsString got %v, want %v", content.IsString(), tt.isString)
+			}
+			data, err := json.Marshal(content)
+			if err != nil {
+				t.Fatalf("marshal error: %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("marshal got %s, want %s", data, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestFlexibleContentParts(t *testing.T) {
+	var content FlexibleContent
+	data := `[{"type":"text","text":"see"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]`
+	if err := json.Unmarshal([]byte(data), &content); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := content.Parts()
+	if len(parts) != 2 || parts[0].Type != ContentTypeText || parts[1].Type != ContentTypeImageURL {
+		t.Fatalf("got parts %+v", parts)
+	}
+
+	text := NewFlexibleContent("hello")
+	parts = text.Parts()
+	if len(parts) != 1 || parts[0].Type != ContentTypeText || parts[0].Text != "hello" {
+		t.Errorf("got parts %+v", parts)
+	}
+
+	var missing *FlexibleContent
+	if missing.Text() != "" || !missing.IsString() || missing.Parts() != nil {
+		t.Error("nil content should behave as empty string content")
+	}
+}
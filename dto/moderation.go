@@ -0,0 +1,39 @@
+package dto
+
+import "sort"
+
+type ModerationRequest struct {
+	Model string `json:"model"`
+	Input any    `json:"input"`
+}
+
+type ModerationResult struct {
+	// Flagged Mistral 等服务商不返回该字段，由任一类别被标记推导
+	Flagged                   *bool               `json:"flagged,omitempty"`
+	Categories                map[string]bool     `json:"categories"`
+	CategoryScores            map[string]float64  `json:"category_scores"`
+	CategoryAppliedInputTypes map[string][]string `json:"category_applied_input_types,omitempty"`
+}
+
+type ModerationResponse struct {
+	Id      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+	Usage   *Usage             `json:"usage,omitempty"`
+}
+
+// FlaggedCategories 返回所有结果中被标记的类别，去重后按名称排序
+func (r *ModerationResponse) FlaggedCategories() []string {
+	categories := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, result := range r.Results {
+		for category, flagged := range result.Categories {
+			if flagged && !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
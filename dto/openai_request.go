@@ -2,6 +2,7 @@ package dto
 
 import (
 	"encoding/json"
+	"reflect"
 	"strings"
 )
 
@@ -23,21 +24,24 @@ type GeneralOpenAIRequest struct {
 	Prompt              any            `json:"prompt,omitempty"`
 	Prefix              any            `json:"prefix,omitempty"`
 	Suffix              any            `json:"suffix,omitempty"`
+	Echo                bool           `json:"echo,omitempty"`
 	Stream              bool           `json:"stream,omitempty"`
 	StreamOptions       *StreamOptions `json:"stream_options,omitempty"`
 	MaxTokens           uint           `json:"max_tokens,omitempty"`
 	MaxCompletionTokens uint           `json:"max_completion_tokens,omitempty"`
 	ReasoningEffort     string         `json:"reasoning_effort,omitempty"`
+	ServiceTier         string         `json:"service_tier,omitempty"`
 	//Reasoning           json.RawMessage   `json:"reasoning,omitempty"`
 	Temperature      *float64          `json:"temperature,omitempty"`
 	TopP             float64           `json:"top_p,omitempty"`
-	TopK             int               `json:"top_k,omitempty"`
-	Stop             any               `json:"stop,omitempty"`
+	TopK             FlexibleInt       `json:"top_k,omitempty"`
+	Stop             FlexibleStop      `json:"stop,omitempty"`
 	N                int               `json:"n,omitempty"`
 	Input            any               `json:"input,omitempty"`
 	Instruction      string            `json:"instruction,omitempty"`
 	Size             string            `json:"size,omitempty"`
 	Functions        any               `json:"functions,omitempty"`
+	FunctionCall     any               `json:"function_call,omitempty"`
 	FrequencyPenalty float64           `json:"frequency_penalty,omitempty"`
 	PresencePenalty  float64           `json:"presence_penalty,omitempty"`
 	ResponseFormat   *ResponseFormat   `json:"response_format,omitempty"`
@@ -53,14 +57,99 @@ type GeneralOpenAIRequest struct {
 	Modalities       any               `json:"modalities,omitempty"`
 	Audio            any               `json:"audio,omitempty"`
 	EnableThinking   any               `json:"enable_thinking,omitempty"` // ali
+	Thinking         *Thinking         `json:"thinking,omitempty"`        // gemini
+	RandomSeed       *int64            `json:"random_seed,omitempty"`     // mistral
 	ExtraBody        any               `json:"extra_body,omitempty"`
 	WebSearchOptions *WebSearchOptions `json:"web_search_options,omitempty"`
+	// AgentTools 由网关执行的工具名称，不会转发到上游
+	AgentTools []string `json:"agent_tools,omitempty"`
+	// ExtraFields 请求中未识别的顶层字段，例如 min_p、repetition_penalty 等供应商扩展参数，序列化时原样转发
+	ExtraFields map[string]json.RawMessage `json:"-"`
+}
+
+var generalOpenAIRequestFields = jsonFieldNames(reflect.TypeOf(GeneralOpenAIRequest{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func (r *GeneralOpenAIRequest) UnmarshalJSON(data []byte) error {
+	type alias GeneralOpenAIRequest
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	r.ExtraFields = nil
+	for key, value := range fields {
+		if generalOpenAIRequestFields[key] {
+			continue
+		}
+		if r.ExtraFields == nil {
+			r.ExtraFields = make(map[string]json.RawMessage)
+		}
+		r.ExtraFields[key] = value
+	}
+	return nil
+}
+
+func (r GeneralOpenAIRequest) MarshalJSON() ([]byte, error) {
+	type alias GeneralOpenAIRequest
+	data, err := json.Marshal(alias(r))
+	if err != nil || len(r.ExtraFields) == 0 {
+		return data, err
+	}
+	return mergeJSONFields(data, r.ExtraFields, false)
+}
+
+// mergeJSONFields 把 fields 合并到 JSON 对象中，override 为 false 时不覆盖已有字段
+func mergeJSONFields(data []byte, fields map[string]json.RawMessage, override bool) ([]byte, error) {
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if _, ok := merged[key]; ok && !override {
+			continue
+		}
+		merged[key] = value
+	}
+	return json.Marshal(merged)
+}
+
+// MarshalWithOverlay 序列化请求并合并适配器附加的字段，附加的字段优先。
+// 嵌入 *GeneralOpenAIRequest 的适配器请求需要通过它实现 MarshalJSON，否则只会序列化嵌入的请求
+func MarshalWithOverlay(request *GeneralOpenAIRequest, overlay any) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	overlayData, err := json.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(overlayData, &fields); err != nil {
+		return nil, err
+	}
+	return mergeJSONFields(data, fields, true)
 }
 
 type ToolCallRequest struct {
 	ID       string          `json:"id,omitempty"`
 	Type     string          `json:"type"`
 	Function FunctionRequest `json:"function"`
+	// CacheControl Anthropic 提示缓存标记，转发给 Claude 渠道
+	CacheControl any `json:"cache_control,omitempty"`
 }
 
 type FunctionRequest struct {
@@ -78,6 +167,28 @@ func (r GeneralOpenAIRequest) GetMaxTokens() int {
 	return int(r.MaxTokens)
 }
 
+// HasAudio 请求包含 input_audio 内容或 modalities 中要求音频输出
+func (r GeneralOpenAIRequest) HasAudio() bool {
+	if modalities, ok := r.Modalities.([]any); ok {
+		for _, modality := range modalities {
+			if modality == "audio" {
+				return true
+			}
+		}
+	}
+	for _, message := range r.Messages {
+		if message.IsStringContent() {
+			continue
+		}
+		for _, content := range message.ParseContent() {
+			if content.Type == ContentTypeInputAudio {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (r GeneralOpenAIRequest) ParseInput() []string {
 	if r.Input == nil {
 		return nil
@@ -106,6 +217,8 @@ type Message struct {
 	Reasoning           string          `json:"reasoning,omitempty"`
 	ToolCalls           json.RawMessage `json:"tool_calls,omitempty"`
 	ToolCallId          string          `json:"tool_call_id,omitempty"`
+	FunctionCall        json.RawMessage `json:"function_call,omitempty"`
+	Audio               json.RawMessage `json:"audio,omitempty"`
 	parsedContent       []MediaContent
 	parsedStringContent *string
 }
@@ -117,6 +230,8 @@ type MediaContent struct {
 	InputAudio any    `json:"input_audio,omitempty"`
 	File       any    `json:"file,omitempty"`
 	VideoUrl   any    `json:"video_url,omitempty"`
+	// CacheControl Anthropic 提示缓存标记，转发给 Claude 渠道
+	CacheControl any `json:"cache_control,omitempty"`
 }
 
 func (m *MediaContent) GetImageMedia() *MessageImageUrl {
@@ -282,6 +397,7 @@ func (m *Message) ParseContent() []MediaContent {
 			if !ok {
 				continue
 			}
+			parsedCount := len(contentList)
 
 			switch contentType {
 			case ContentTypeText:
@@ -364,6 +480,9 @@ func (m *Message) ParseContent() []MediaContent {
 					})
 				}
 			}
+			if cacheControl, ok := contentItem["cache_control"]; ok && len(contentList) > parsedCount {
+				contentList[len(contentList)-1].CacheControl = cacheControl
+			}
 		}
 	}
 
@@ -400,6 +519,18 @@ type OpenAIResponsesRequest struct {
 	User               string               `json:"user,omitempty"`
 }
 
+// ResponsesInputItem /v1/responses 的 input 数组中的一项，消息使用 Role 和 Content，
+// 工具调用和工具结果分别使用 function_call 和 function_call_output 类型
+type ResponsesInputItem struct {
+	Type      string `json:"type,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Content   any    `json:"content,omitempty"`
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    any    `json:"output,omitempty"`
+}
+
 type Reasoning struct {
 	Effort  string `json:"effort,omitempty"`
 	Summary string `json:"summary,omitempty"`
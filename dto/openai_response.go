@@ -3,7 +3,7 @@ package dto
 import "encoding/json"
 
 type SimpleResponse struct {
-	Usage `json:"usage"`
+	Usage Usage        `json:"usage"`
 	Error *OpenAIError `json:"error"`
 }
 
@@ -13,13 +13,43 @@ type TextResponse struct {
 	Created int64                      `json:"created"`
 	Model   string                     `json:"model"`
 	Choices []OpenAITextResponseChoice `json:"choices"`
-	Usage   `json:"usage"`
+	Usage   Usage                      `json:"usage"`
 }
 
 type OpenAITextResponseChoice struct {
 	Index        int `json:"index"`
 	Message      `json:"message"`
 	FinishReason string `json:"finish_reason"`
+	// NativeFinishReason 上游原始的结束原因，仅用于排查问题
+	NativeFinishReason string `json:"native_finish_reason,omitempty"`
+	Logprobs           any    `json:"logprobs,omitempty"`
+}
+
+// ChatLogProbs 其他格式的 logprobs 转换后的 OpenAI 格式
+type ChatLogProbs struct {
+	Content []ChatLogProb `json:"content"`
+}
+
+type ChatLogProb struct {
+	Token       string           `json:"token"`
+	Logprob     float64          `json:"logprob"`
+	Bytes       []int            `json:"bytes"`
+	TopLogprobs []ChatTopLogProb `json:"top_logprobs"`
+}
+
+type ChatTopLogProb struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
+}
+
+// TokenBytes token 的 UTF-8 字节，用于 logprobs 的 bytes 字段
+func TokenBytes(token string) []int {
+	bytes := make([]int, 0, len(token))
+	for _, b := range []byte(token) {
+		bytes = append(bytes, int(b))
+	}
+	return bytes
 }
 
 type OpenAITextResponse struct {
@@ -29,7 +59,11 @@ type OpenAITextResponse struct {
 	Created int64                      `json:"created"`
 	Choices []OpenAITextResponseChoice `json:"choices"`
 	Error   *OpenAIError               `json:"error,omitempty"`
-	Usage   `json:"usage"`
+	Usage   Usage                      `json:"usage"`
+	// SystemFingerprint 上游返回的后端配置标识，开启渠道标记时附加渠道哈希
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// ServiceTier 上游实际使用的服务等级，按该等级的倍率计费
+	ServiceTier string `json:"service_tier,omitempty"`
 }
 
 type OpenAIEmbeddingResponseItem struct {
@@ -42,33 +76,32 @@ type OpenAIEmbeddingResponse struct {
 	Object string                        `json:"object"`
 	Data   []OpenAIEmbeddingResponseItem `json:"data"`
 	Model  string                        `json:"model"`
-	Usage  `json:"usage"`
+	Usage  Usage                         `json:"usage"`
 }
 
 type ChatCompletionsStreamResponseChoice struct {
-	Delta        ChatCompletionsStreamResponseChoiceDelta `json:"delta,omitempty"`
-	Logprobs     *any                                     `json:"logprobs"`
-	FinishReason *string                                  `json:"finish_reason"`
-	Index        int                                      `json:"index"`
+	Delta              ChatCompletionsStreamResponseChoiceDelta `json:"delta,omitempty"`
+	Logprobs           *any                                     `json:"logprobs"`
+	FinishReason       *string                                  `json:"finish_reason"`
+	NativeFinishReason *string                                  `json:"native_finish_reason,omitempty"`
+	Index              int                                      `json:"index"`
 }
 
 type ChatCompletionsStreamResponseChoiceDelta struct {
-	Content          *string            `json:"content,omitempty"`
+	Content          *FlexibleContent   `json:"content,omitempty"`
 	ReasoningContent *string            `json:"reasoning_content,omitempty"`
 	Reasoning        *string            `json:"reasoning,omitempty"`
 	Role             string             `json:"role,omitempty"`
 	ToolCalls        []ToolCallResponse `json:"tool_calls,omitempty"`
+	Audio            json.RawMessage    `json:"audio,omitempty"`
 }
 
 func (c *ChatCompletionsStreamResponseChoiceDelta) SetContentString(s string) {
-	c.Content = &s
+	c.Content = NewFlexibleContent(s)
 }
 
 func (c *ChatCompletionsStreamResponseChoiceDelta) GetContentString() string {
-	if c.Content == nil {
-		return ""
-	}
-	return *c.Content
+	return c.Content.Text()
 }
 
 func (c *ChatCompletionsStreamResponseChoiceDelta) GetReasoningContent() string {
@@ -112,6 +145,7 @@ type ChatCompletionsStreamResponse struct {
 	Created           int64                                 `json:"created"`
 	Model             string                                `json:"model"`
 	SystemFingerprint *string                               `json:"system_fingerprint"`
+	ServiceTier       string                                `json:"service_tier,omitempty"`
 	Choices           []ChatCompletionsStreamResponseChoice `json:"choices"`
 	Usage             *Usage                                `json:"usage"`
 }
@@ -139,6 +173,7 @@ func (c *ChatCompletionsStreamResponse) Copy() *ChatCompletionsStreamResponse {
 		Created:           c.Created,
 		Model:             c.Model,
 		SystemFingerprint: c.SystemFingerprint,
+		ServiceTier:       c.ServiceTier,
 		Choices:           choices,
 		Usage:             c.Usage,
 	}
@@ -167,6 +202,23 @@ type CompletionsStreamResponse struct {
 	} `json:"choices"`
 }
 
+// CompletionsResponse /v1/completions 的响应，流式响应的数据块使用相同格式
+type CompletionsResponse struct {
+	Id      string                      `json:"id"`
+	Object  string                      `json:"object"`
+	Created int64                       `json:"created"`
+	Model   string                      `json:"model"`
+	Choices []CompletionsResponseChoice `json:"choices"`
+	Usage   *Usage                      `json:"usage,omitempty"`
+}
+
+type CompletionsResponseChoice struct {
+	Text         string  `json:"text"`
+	Index        int     `json:"index"`
+	Logprobs     any     `json:"logprobs"`
+	FinishReason *string `json:"finish_reason"`
+}
+
 type Usage struct {
 	PromptTokens         int `json:"prompt_tokens"`
 	CompletionTokens     int `json:"completion_tokens"`
@@ -178,6 +230,9 @@ type Usage struct {
 	InputTokens            int                `json:"input_tokens"`
 	OutputTokens           int                `json:"output_tokens"`
 	InputTokensDetails     *InputTokenDetails `json:"input_tokens_details"`
+	// Claude 提示缓存写入和读取的 token，prompt_tokens 已包含这两部分
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 type InputTokenDetails struct {
@@ -208,6 +263,7 @@ type OpenAIResponsesResponse struct {
 	ParallelToolCalls  bool                 `json:"parallel_tool_calls"`
 	PreviousResponseID string               `json:"previous_response_id"`
 	Reasoning          *Reasoning           `json:"reasoning"`
+	ServiceTier        string               `json:"service_tier,omitempty"`
 	Store              bool                 `json:"store"`
 	Temperature        float64              `json:"temperature"`
 	ToolChoice         string               `json:"tool_choice"`
@@ -221,6 +277,7 @@ type OpenAIResponsesResponse struct {
 
 type IncompleteDetails struct {
 	Reasoning string `json:"reasoning"`
+	Reason    string `json:"reason,omitempty"`
 }
 
 type ResponsesOutput struct {
@@ -229,6 +286,12 @@ type ResponsesOutput struct {
 	Status  string                   `json:"status"`
 	Role    string                   `json:"role"`
 	Content []ResponsesOutputContent `json:"content"`
+	// function_call
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	// reasoning
+	Summary []ResponsesOutputContent `json:"summary,omitempty"`
 }
 
 type ResponsesOutputContent struct {
@@ -246,6 +309,12 @@ const (
 	BuildInCallWebSearchCall = "web_search_call"
 )
 
+const (
+	ResponsesOutputTypeMessage      = "message"
+	ResponsesOutputTypeFunctionCall = "function_call"
+	ResponsesOutputTypeReasoning    = "reasoning"
+)
+
 const (
 	ResponsesOutputTypeItemAdded = "response.output_item.added"
 	ResponsesOutputTypeItemDone  = "response.output_item.done"
@@ -257,4 +326,5 @@ type ResponsesStreamResponse struct {
 	Response *OpenAIResponsesResponse `json:"response,omitempty"`
 	Delta    string                   `json:"delta,omitempty"`
 	Item     *ResponsesOutput         `json:"item,omitempty"`
+	ItemId   string                   `json:"item_id,omitempty"`
 }
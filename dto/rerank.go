@@ -1,10 +1,13 @@
 package dto
 
+import "fmt"
+
 type RerankRequest struct {
 	Documents       []any  `json:"documents"`
 	Query           string `json:"query"`
 	Model           string `json:"model"`
 	TopN            int    `json:"top_n"`
+	TopK            int    `json:"top_k,omitempty"` // Voyage 格式，等同于 top_n
 	ReturnDocuments *bool  `json:"return_documents,omitempty"`
 	MaxChunkPerDoc  int    `json:"max_chunk_per_doc,omitempty"`
 	OverLapTokens   int    `json:"overlap_tokens,omitempty"`
@@ -17,6 +20,31 @@ func (r *RerankRequest) GetReturnDocuments() bool {
 	return *r.ReturnDocuments
 }
 
+// Normalize 统一 Cohere、Jina、Voyage 格式的请求参数
+func (r *RerankRequest) Normalize() {
+	if r.TopN == 0 {
+		r.TopN = r.TopK
+	}
+	r.TopK = 0
+}
+
+// DocumentTexts 返回文档文本，Jina 格式的 {"text": "..."} 文档取其 text 字段
+func (r *RerankRequest) DocumentTexts() []string {
+	texts := make([]string, 0, len(r.Documents))
+	for _, document := range r.Documents {
+		switch d := document.(type) {
+		case string:
+			texts = append(texts, d)
+		case map[string]any:
+			text, _ := d["text"].(string)
+			texts = append(texts, text)
+		default:
+			texts = append(texts, fmt.Sprintf("%v", d))
+		}
+	}
+	return texts
+}
+
 type RerankResponseResult struct {
 	Document       any     `json:"document,omitempty"`
 	Index          int     `json:"index"`
@@ -1,5 +1,7 @@
 package dto
 
+import "encoding/json"
+
 type TaskError struct {
 	Code       string `json:"code"`
 	Message    string `json:"message"`
@@ -8,3 +10,16 @@ type TaskError struct {
 	LocalError bool   `json:"-"`
 	Error      error  `json:"-"`
 }
+
+// TaskResult 异步任务适配器从上游查询到的任务状态，由通用任务轮询器按状态机写回任务表
+type TaskResult struct {
+	TaskID     string
+	Status     string
+	Progress   string
+	FailReason string
+	SubmitTime int64
+	StartTime  int64
+	FinishTime int64
+	Data       json.RawMessage
+	Usage      *Usage // 按量计费的任务（如批量请求）完成时的实际用量
+}
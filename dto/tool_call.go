@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 工具调用归一化：旧版 functions/function_call 转换为 tools/tool_choice，tool_choice 解析为统一的 ToolChoice，
+// 各渠道再从归一化后的请求转换为上游格式（Claude tool_use/tool_result、Gemini functionCall/functionResponse）
+
+const (
+	ToolChoiceModeAuto     = "auto"
+	ToolChoiceModeNone     = "none"
+	ToolChoiceModeRequired = "required"
+	ToolChoiceModeFunction = "function"
+)
+
+type ToolChoice struct {
+	Mode string
+	// Name Mode 为 function 时指定调用的工具
+	Name string
+}
+
+// ParseToolChoice 解析 tool_choice 或旧版 function_call，支持 "auto"、"none"、"required"、
+// {"type":"function","function":{"name":...}} 和 {"name":...}，无法识别时返回 nil
+func ParseToolChoice(choice any) *ToolChoice {
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case ToolChoiceModeAuto, ToolChoiceModeNone, ToolChoiceModeRequired:
+			return &ToolChoice{Mode: v}
+		}
+	case map[string]any:
+		if function, ok := v["function"].(map[string]any); ok {
+			if name, _ := function["name"].(string); name != "" {
+				return &ToolChoice{Mode: ToolChoiceModeFunction, Name: name}
+			}
+		}
+		if name, _ := v["name"].(string); name != "" {
+			return &ToolChoice{Mode: ToolChoiceModeFunction, Name: name}
+		}
+	}
+	return nil
+}
+
+// GetToolChoice 返回归一化的 tool_choice，未指定时返回 nil
+func (r *GeneralOpenAIRequest) GetToolChoice() *ToolChoice {
+	if r.ToolChoice != nil {
+		return ParseToolChoice(r.ToolChoice)
+	}
+	return ParseToolChoice(r.FunctionCall)
+}
+
+// NormalizeTools 把旧版 functions、function_call 和 function 角色的消息转换为 tools、tool_calls 和 tool 角色的消息，
+// 并为缺少 ID 的工具调用补全 ID，使每条工具结果都能对应到调用。不修改原消息
+func (r *GeneralOpenAIRequest) NormalizeTools() {
+	if len(r.Tools) == 0 && r.Functions != nil {
+		var functions []FunctionRequest
+		if data, err := json.Marshal(r.Functions); err == nil {
+			_ = json.Unmarshal(data, &functions)
+		}
+		for _, function := range functions {
+			r.Tools = append(r.Tools, ToolCallRequest{Type: "function", Function: function})
+		}
+	}
+	r.Functions = nil
+	if r.ToolChoice == nil && r.FunctionCall != nil {
+		if choice := ParseToolChoice(r.FunctionCall); choice != nil {
+			if choice.Mode == ToolChoiceModeFunction {
+				r.ToolChoice = map[string]any{"type": "function", "function": map[string]any{"name": choice.Name}}
+			} else {
+				r.ToolChoice = choice.Mode
+			}
+		}
+	}
+	r.FunctionCall = nil
+
+	// 等待结果的工具调用，按调用顺序与后续的工具结果匹配
+	type pendingCall struct {
+		id   string
+		name string
+	}
+	var pending []pendingCall
+	messages := make([]Message, 0, len(r.Messages))
+	for i, message := range r.Messages {
+		switch message.Role {
+		case "assistant":
+			toolCalls := message.ParseToolCalls()
+			changed := false
+			if len(toolCalls) == 0 && len(message.FunctionCall) > 0 {
+				var function FunctionRequest
+				if json.Unmarshal(message.FunctionCall, &function) == nil && function.Name != "" {
+					toolCalls = append(toolCalls, ToolCallRequest{Type: "function", Function: function})
+					changed = true
+				}
+			}
+			message.FunctionCall = nil
+			for j := range toolCalls {
+				if toolCalls[j].ID == "" {
+					toolCalls[j].ID = fmt.Sprintf("call_%d_%d", i, j)
+					changed = true
+				}
+				if toolCalls[j].Type == "" {
+					toolCalls[j].Type = "function"
+				}
+				pending = append(pending, pendingCall{id: toolCalls[j].ID, name: toolCalls[j].Function.Name})
+			}
+			if changed {
+				message.SetToolCalls(toolCalls)
+			}
+		case "tool", "function":
+			message.Role = "tool"
+			if message.ToolCallId == "" {
+				for j, call := range pending {
+					if message.Name == nil || *message.Name == call.name {
+						message.ToolCallId = call.id
+						pending = append(pending[:j:j], pending[j+1:]...)
+						break
+					}
+				}
+			} else {
+				for j, call := range pending {
+					if call.id == message.ToolCallId {
+						if message.Name == nil {
+							name := call.name
+							message.Name = &name
+						}
+						pending = append(pending[:j:j], pending[j+1:]...)
+						break
+					}
+				}
+			}
+		}
+		messages = append(messages, message)
+	}
+	r.Messages = messages
+}
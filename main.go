@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -38,6 +39,9 @@ func main() {
 	}
 
 	common.LoadEnv()
+	if flag.Arg(0) == "doctor" {
+		os.Exit(service.RunDoctor())
+	}
 
 	common.SetupLogger()
 	common.SysLog("New API " + common.Version + " started")
@@ -82,7 +86,7 @@ func main() {
 
 	service.InitTokenEncoders()
 
-	if common.RedisEnabled {
+	if common.CacheEnabled() {
 		// for compatibility with old versions
 		common.MemoryCacheEnabled = true
 	}
@@ -112,6 +116,30 @@ func main() {
 	// 数据看板
 	go model.UpdateQuotaData()
 
+	// 预扣额度只记录在处理请求的节点上，每个节点分别检查超时的预扣
+	gopool.Go(func() {
+		service.AutomaticallyExpireQuotaHolds()
+	})
+
+	// 预算预警、订阅续期、价格同步、后付费账单和用量汇总只在主节点执行，避免重复通知、重复发放额度和重复写入
+	if common.IsMasterNode {
+		gopool.Go(func() {
+			service.AutomaticallyCheckBudgetAlerts()
+		})
+		gopool.Go(func() {
+			service.AutomaticallyRenewSubscriptions()
+		})
+		gopool.Go(func() {
+			service.AutomaticallySyncPricing()
+		})
+		gopool.Go(func() {
+			service.AutomaticallyGeneratePostpaidStatements()
+		})
+		gopool.Go(func() {
+			service.AutomaticallyRollupUsage()
+		})
+	}
+
 	if os.Getenv("CHANNEL_UPDATE_FREQUENCY") != "" {
 		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_UPDATE_FREQUENCY"))
 		if err != nil {
@@ -126,12 +154,28 @@ func main() {
 		}
 		go controller.AutomaticallyTestChannels(frequency)
 	}
+	if os.Getenv("SHADOW_SYNC_FREQUENCY") != "" && common.IsMasterNode && service.ShadowSyncEnabled() {
+		frequency, err := strconv.Atoi(os.Getenv("SHADOW_SYNC_FREQUENCY"))
+		if err != nil {
+			common.FatalLog("failed to parse SHADOW_SYNC_FREQUENCY: " + err.Error())
+		}
+		go service.AutomaticallySyncShadowChannels(frequency)
+	}
+	// 上游模型列表在模型设置中开启后才会拉取
+	go controller.AutomaticallyDiscoverUpstreamModels(common.GetEnvOrDefault("UPSTREAM_MODEL_SYNC_FREQUENCY", 60))
+	if os.Getenv("PROVIDER_STATUS_CHECK_FREQUENCY") != "" {
+		frequency, err := strconv.Atoi(os.Getenv("PROVIDER_STATUS_CHECK_FREQUENCY"))
+		if err != nil {
+			common.FatalLog("failed to parse PROVIDER_STATUS_CHECK_FREQUENCY: " + err.Error())
+		}
+		go controller.AutomaticallyCheckProviderStatus(frequency)
+	}
 	if common.IsMasterNode && constant.UpdateTask {
 		gopool.Go(func() {
-			controller.UpdateMidjourneyTaskBulk()
+			controller.UpdateTaskBulk()
 		})
 		gopool.Go(func() {
-			controller.UpdateTaskBulk()
+			controller.RunGatewayBatches()
 		})
 	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
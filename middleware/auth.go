@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/model"
 	"strconv"
 	"strings"
@@ -182,6 +183,19 @@ func TokenAuth() func(c *gin.Context) {
 				c.Request.Header.Set("Authorization", "Bearer "+key)
 			}
 		}
+		// 不支持设置 Authorization 请求头的旧客户端，按分组开启后可以使用 api-key 请求头或签名查询参数
+		legacyAuthMethod := ""
+		if c.Request.Header.Get("Authorization") == "" && c.Request.Header.Get("mj-api-secret") == "" {
+			legacyKey, method, err := getLegacyKey(c)
+			if err != nil {
+				abortWithOpenAiMessage(c, http.StatusUnauthorized, err.Error())
+				return
+			}
+			if legacyKey != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+legacyKey)
+				legacyAuthMethod = method
+			}
+		}
 		key := c.Request.Header.Get("Authorization")
 		parts := make([]string, 0)
 		key = strings.TrimPrefix(key, "Bearer ")
@@ -219,23 +233,19 @@ func TokenAuth() func(c *gin.Context) {
 		}
 
 		userCache.WriteContext(c)
+		c.Set(constant.ContextKeySubscriptionPlan, model.GetActiveSubscriptionPlan(token.UserId))
 
-		c.Set("id", token.UserId)
-		c.Set("token_id", token.Id)
-		c.Set("token_key", token.Key)
-		c.Set("token_name", token.Name)
-		c.Set("token_unlimited_quota", token.UnlimitedQuota)
-		if !token.UnlimitedQuota {
-			c.Set("token_quota", token.RemainQuota)
-		}
-		if token.ModelLimitsEnabled {
-			c.Set("token_model_limit_enabled", true)
-			c.Set("token_model_limit", token.GetModelLimitsMap())
-		} else {
-			c.Set("token_model_limit_enabled", false)
+		if legacyAuthMethod != "" {
+			group := token.Group
+			if group == "" {
+				group = userCache.Group
+			}
+			if !checkLegacyKeyAuth(c, token.Id, group, legacyAuthMethod) {
+				return
+			}
 		}
-		c.Set("allow_ips", token.GetIpLimitsMap())
-		c.Set("token_group", token.Group)
+
+		SetupContextForToken(c, token)
 		if len(parts) > 1 {
 			if model.IsAdmin(token.UserId) {
 				c.Set("specific_channel_id", parts[1])
@@ -247,3 +257,27 @@ func TokenAuth() func(c *gin.Context) {
 		c.Next()
 	}
 }
+
+// SetupContextForToken 写入令牌相关的上下文，网关执行批量请求时复用
+func SetupContextForToken(c *gin.Context, token *model.Token) {
+	c.Set("id", token.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_key", token.Key)
+	c.Set("token_name", token.Name)
+	c.Set("token_unlimited_quota", token.UnlimitedQuota)
+	if !token.UnlimitedQuota {
+		c.Set("token_quota", token.RemainQuota)
+	}
+	if token.ModelLimitsEnabled {
+		c.Set("token_model_limit_enabled", true)
+		c.Set("token_model_limit", token.GetModelLimitsMap())
+	} else {
+		c.Set("token_model_limit_enabled", false)
+	}
+	c.Set("allow_ips", token.GetIpLimitsMap())
+	c.Set("token_group", token.Group)
+	c.Set("token_rate_limit_tier", token.GetActiveRateLimitTier())
+	c.Set("token_daily_quota_limit", token.DailyQuotaLimit)
+	c.Set("token_monthly_quota_limit", token.MonthlyQuotaLimit)
+	c.Set("token_quota_limit_timezone", token.QuotaLimitTimezone)
+}
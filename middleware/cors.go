@@ -1,15 +1,54 @@
 package middleware
 
 import (
+	"one-api/constant"
+	"strings"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
+// RelayCORS 用于中继接口（/v1、/pg、/dashboard 等令牌鉴权接口），默认允许所有来源
+func RelayCORS() gin.HandlerFunc {
+	return newCORS(constant.RelayCORSAllowOrigins, constant.RelayCORSAllowHeaders)
+}
+
+// ApiCORS 用于管理接口（/api），默认不允许跨域，前后端分离部署时需配置允许的来源
+func ApiCORS() gin.HandlerFunc {
+	return newCORS(constant.ApiCORSAllowOrigins, constant.ApiCORSAllowHeaders)
+}
+
+func newCORS(allowOrigins string, allowHeaders string) gin.HandlerFunc {
+	origins := splitCORSList(allowOrigins)
+	if !constant.CORSEnabled || len(origins) == 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
+	if len(origins) == 1 && origins[0] == "*" {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = origins
+	}
 	config.AllowCredentials = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"*"}
+	config.AllowHeaders = splitCORSList(allowHeaders)
+	if len(config.AllowHeaders) == 0 {
+		config.AllowHeaders = []string{"*"}
+	}
+	config.MaxAge = time.Duration(constant.CORSMaxAge) * time.Second
 	return cors.New(config)
 }
+
+func splitCORSList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -32,6 +32,16 @@ func Distribute() func(c *gin.Context) {
 				return
 			}
 		}
+		err := service.CheckTokenSpendLimit(c.GetInt("token_id"), c.GetInt("token_daily_quota_limit"),
+			c.GetInt("token_monthly_quota_limit"), c.GetString("token_quota_limit_timezone"))
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err := service.CheckUserCredit(c.GetInt("id")); err != nil {
+			abortWithOpenAiMessage(c, http.StatusForbidden, err.Error())
+			return
+		}
 		var channel *model.Channel
 		channelId, ok := c.Get("specific_channel_id")
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
@@ -74,7 +84,8 @@ func Distribute() func(c *gin.Context) {
 			// Select a channel for the user
 			// check token model mapping
 			modelLimitEnable := c.GetBool("token_model_limit_enabled")
-			if modelLimitEnable {
+			// Assistants 的大部分请求不包含模型，只检查指定了模型的请求
+			if modelLimitEnable && !(IsAssistantsPath(c.Request.URL.Path) && modelRequest.Model == "") {
 				s, ok := c.Get("token_model_limit")
 				var tokenModelLimit map[string]bool
 				if ok {
@@ -93,6 +104,12 @@ func Distribute() func(c *gin.Context) {
 					return
 				}
 			}
+			if plan, ok := setting.GetSubscriptionPlan(c.GetString(constant.ContextKeySubscriptionPlan)); ok && modelRequest.Model != "" {
+				if !plan.AllowModel(modelRequest.Model) {
+					abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("订阅套餐 %s 无权访问模型 %s", plan.Name, modelRequest.Model))
+					return
+				}
+			}
 
 			if shouldSelectChannel {
 				channel, err = model.CacheGetRandomSatisfiedChannel(userGroup, modelRequest.Model, 0)
@@ -111,8 +128,18 @@ func Distribute() func(c *gin.Context) {
 					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道（数据库一致性已被破坏）", userGroup, modelRequest.Model))
 					return
 				}
+				if endpoint := service.GetImageEndpoint(c.Request.URL.Path); endpoint != "" {
+					channel, err = service.SelectImageEndpointChannel(channel, userGroup, modelRequest.Model, endpoint, 0)
+					if err != nil {
+						abortWithOpenAiMessage(c, http.StatusServiceUnavailable, err.Error())
+						return
+					}
+				}
 			}
 		}
+		if !checkFreeModelLimit(c, modelRequest.Model) {
+			return
+		}
 		c.Set(constant.ContextKeyRequestStartTime, time.Now())
 		SetupContextForSelectedChannel(c, channel, modelRequest.Model)
 		c.Next()
@@ -162,7 +189,25 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		}
 		c.Set("platform", string(constant.TaskPlatformSuno))
 		c.Set("relay_mode", relayMode)
-	} else if !strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") && !strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") {
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/batches") {
+		// 批量请求的模型在输入文件中，渠道由 controller 在开启了批量请求的渠道中选择
+		batchInput, err := service.GetBatchInput(c)
+		if err != nil {
+			return nil, false, err
+		}
+		modelRequest.Model = batchInput.Model
+		shouldSelectChannel = false
+	} else if IsAssistantsPath(c.Request.URL.Path) {
+		// Assistants 的对象保存在上游账户中，渠道由 controller 根据对象所在的渠道选择
+		if c.Request.Method == http.MethodPost && c.Request.ContentLength != 0 {
+			err = common.UnmarshalBodyReusable(c, &modelRequest)
+		}
+		shouldSelectChannel = false
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") || strings.HasPrefix(c.Request.URL.Path, "/v1/audio/translations") ||
+		service.GetImageEndpoint(c.Request.URL.Path) != "" {
+		// 音频、图片文件可能较大，解析时超出部分写入临时文件，不读取整个请求体
+		_, err = common.ParseMultipartFormReusable(c)
+	} else {
 		err = common.UnmarshalBodyReusable(c, &modelRequest)
 	}
 	if err != nil {
@@ -186,6 +231,8 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e")
 	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") {
 		modelRequest.Model = common.GetStringIfEmpty(c.PostForm("model"), "gpt-image-1")
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
+		modelRequest.Model = common.GetStringIfEmpty(c.PostForm("model"), "dall-e-2")
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/audio") {
 		relayMode := relayconstant.RelayModeAudioSpeech
@@ -205,6 +252,10 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	return &modelRequest, shouldSelectChannel, nil
 }
 
+func IsAssistantsPath(path string) bool {
+	return strings.HasPrefix(path, "/v1/assistants") || strings.HasPrefix(path, "/v1/threads")
+}
+
 func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, modelName string) {
 	c.Set("original_model", modelName) // for retry
 	if channel == nil {
@@ -226,7 +277,7 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	c.Set("base_url", channel.GetBaseURL())
 	// TODO: api_version统一
 	switch channel.Type {
-	case common.ChannelTypeAzure:
+	case common.ChannelTypeAzure, common.ChannelTypeAzureAI:
 		c.Set("api_version", channel.Other)
 	case common.ChannelTypeVertexAi:
 		c.Set("region", channel.Other)
@@ -242,5 +293,7 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 		c.Set("api_version", channel.Other)
 	case common.ChannelTypeCoze:
 		c.Set("bot_id", channel.Other)
+	case common.ChannelTypeMiniMax:
+		c.Set("group_id", channel.Other)
 	}
 }
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FreeModelRateLimitMark = "FREE"
+
+// checkFreeModelLimit 免费模型按用户和模型分别限制每分钟请求数和当日请求数，超过限制时中止请求并返回 false
+func checkFreeModelLimit(c *gin.Context, modelName string) bool {
+	limit, ok := operation_setting.GetFreeModel(modelName)
+	if !ok {
+		return true
+	}
+	userId := c.GetInt("id")
+	if limit.RPM > 0 {
+		key := fmt.Sprintf("rateLimit:%s:%d:%s", FreeModelRateLimitMark, userId, modelName)
+		if common.RedisEnabled {
+			ctx := context.Background()
+			allowed, err := checkRedisRateLimit(ctx, common.RDB, key, limit.RPM, 60)
+			if err != nil {
+				common.SysError("check free model rate limit failed: " + err.Error())
+				abortWithOpenAiMessage(c, http.StatusInternalServerError, "free_model_rate_limit_check_failed")
+				return false
+			}
+			if !allowed {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("免费模型 %s 每分钟最多请求 %d 次", modelName, limit.RPM))
+				return false
+			}
+			recordRedisRequest(ctx, common.RDB, key, limit.RPM)
+		} else {
+			inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+			if !inMemoryRateLimiter.Request(key, limit.RPM, 60) {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("免费模型 %s 每分钟最多请求 %d 次", modelName, limit.RPM))
+				return false
+			}
+		}
+	}
+	if limit.DailyRequests > 0 {
+		key := fmt.Sprintf("free_model_daily:%d:%s:%s", userId, modelName, time.Now().Format("2006-01-02"))
+		// 先计数再按计数结果判断，避免并发请求同时通过检查
+		count, err := common.CounterIncr(key, 1, 48*time.Hour)
+		if err != nil {
+			// 统计不可用时不拒绝请求
+			common.SysError("record free model daily requests failed: " + err.Error())
+		} else if count > int64(limit.DailyRequests) {
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("免费模型 %s 每日最多请求 %d 次", modelName, limit.DailyRequests))
+			return false
+		}
+	}
+	return true
+}
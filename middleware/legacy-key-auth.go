@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/setting"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LegacyKeyAuthHeader = "api-key"
+	LegacyKeyAuthQuery  = "signed-query"
+
+	LegacyKeyAuthRateLimitMark = "LKA"
+)
+
+// LegacyKeyAuthSignature 签名查询参数的签名：以令牌 key（不含 sk- 前缀）为密钥，
+// 对 "请求方法\n请求路径\n规范化查询参数\n过期时间戳" 计算 HMAC-SHA256。
+// 规范化查询参数为去掉 key_id、expires、signature 后按参数名排序并编码的查询字符串
+func LegacyKeyAuthSignature(key string, method string, path string, query url.Values, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(fmt.Sprintf("%s\n%s\n%s\n%d", method, path, query.Encode(), expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getLegacyKey 从 api-key 请求头或 key_id、expires、signature 查询参数中读取令牌，
+// 读取后移除这些参数，避免被转发到上游
+func getLegacyKey(c *gin.Context) (key string, method string, err error) {
+	if key = c.Request.Header.Get(LegacyKeyAuthHeader); key != "" {
+		c.Request.Header.Del(LegacyKeyAuthHeader)
+		return key, LegacyKeyAuthHeader, nil
+	}
+	query := c.Request.URL.Query()
+	keyId := query.Get("key_id")
+	if keyId == "" {
+		return "", "", nil
+	}
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	signature := query.Get("signature")
+	query.Del("key_id")
+	query.Del("expires")
+	query.Del("signature")
+	c.Request.URL.RawQuery = query.Encode()
+
+	now := common.GetTimestamp()
+	if expires < now {
+		return "", "", errors.New("签名已过期")
+	}
+	// 过期时间过远的签名泄露后长期有效，等同于泄露令牌
+	if expires > now+int64(constant.LegacyKeyAuthMaxExpireSeconds) {
+		return "", "", fmt.Errorf("签名有效期不能超过 %d 秒", constant.LegacyKeyAuthMaxExpireSeconds)
+	}
+	tokenId, _ := strconv.Atoi(keyId)
+	tokenKey, err := model.GetTokenKeyById(tokenId)
+	if err != nil {
+		return "", "", errors.New("无效的签名")
+	}
+	expected := LegacyKeyAuthSignature(tokenKey, c.Request.Method, c.Request.URL.Path, query, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", "", errors.New("无效的签名")
+	}
+	return tokenKey, LegacyKeyAuthQuery, nil
+}
+
+// checkLegacyKeyAuth 检查令牌所在分组是否开启了旧客户端认证方式，并按令牌限制每分钟请求次数
+func checkLegacyKeyAuth(c *gin.Context, tokenId int, group string, method string) bool {
+	limit, found := setting.GetLegacyKeyAuthLimit(group)
+	if !found {
+		abortWithOpenAiMessage(c, http.StatusUnauthorized, fmt.Sprintf("分组 %s 不支持通过 %s 传递令牌，请使用 Authorization 请求头", group, method))
+		return false
+	}
+	common.LogWarn(c, fmt.Sprintf("token %d authenticated via %s, group %s", tokenId, method, group))
+
+	key := LegacyKeyAuthRateLimitMark + strconv.Itoa(tokenId)
+	allowed := true
+	if common.RedisEnabled {
+		ctx := context.Background()
+		count, err := common.RDB.Incr(ctx, "rateLimit:"+key).Result()
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusInternalServerError, err.Error())
+			return false
+		}
+		if count == 1 {
+			common.RDB.Expire(ctx, "rateLimit:"+key, time.Minute)
+		}
+		allowed = count <= int64(limit)
+	} else {
+		inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+		allowed = inMemoryRateLimiter.Request(key, limit, 60)
+	}
+	if !allowed {
+		abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("通过 %s 传递令牌时每分钟最多请求 %d 次", method, limit))
+		return false
+	}
+	return true
+}
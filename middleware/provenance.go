@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"one-api/common"
+	"one-api/service"
+	"one-api/setting/model_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Provenance 在响应头中写入网关签名的来源证明，需要在 Distribute 之后使用以获取模型名称
+func Provenance() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if model_setting.GetGlobalSettings().ProvenanceEnabled {
+			provenance := service.NewProvenance(c.GetString(common.RequestIdKey), c.GetString("original_model"))
+			c.Header(service.ProvenanceHeader, provenance.HeaderValue())
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/setting"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const SubscriptionRateLimitMark = "SUBRL"
+
+// SubscriptionRateLimit 订阅套餐的每分钟请求次数限制，与全局和分组的模型请求速率限制分别计数
+func SubscriptionRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		planName, exists := c.Get(constant.ContextKeySubscriptionPlan)
+		if !exists {
+			// 使用用户登录态的路由（如 playground）没有经过 TokenAuth
+			planName = model.GetActiveSubscriptionPlan(c.GetInt("id"))
+		}
+		name, _ := planName.(string)
+		plan, ok := setting.GetSubscriptionPlan(name)
+		if !ok || plan.RPM <= 0 {
+			c.Next()
+			return
+		}
+		key := fmt.Sprintf("rateLimit:%s:%s", SubscriptionRateLimitMark, strconv.Itoa(c.GetInt("id")))
+		if common.RedisEnabled {
+			ctx := context.Background()
+			allowed, err := checkRedisRateLimit(ctx, common.RDB, key, plan.RPM, 60)
+			if err != nil {
+				common.SysError("check subscription rate limit failed: " + err.Error())
+				abortWithOpenAiMessage(c, http.StatusInternalServerError, "subscription_rate_limit_check_failed")
+				return
+			}
+			if !allowed {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("订阅套餐 %s 每分钟最多请求 %d 次", plan.Name, plan.RPM))
+				return
+			}
+			recordRedisRequest(ctx, common.RDB, key, plan.RPM)
+		} else {
+			inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+			if !inMemoryRateLimiter.Request(key, plan.RPM, 60) {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, fmt.Sprintf("订阅套餐 %s 每分钟最多请求 %d 次", plan.Name, plan.RPM))
+				return
+			}
+		}
+		c.Next()
+	}
+}
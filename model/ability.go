@@ -27,6 +27,13 @@ func GetGroupModels(group string) []string {
 	return models
 }
 
+// GetGroupAbilities 返回分组下所有启用的模型和渠道
+func GetGroupAbilities(group string) ([]Ability, error) {
+	var abilities []Ability
+	err := DB.Where(groupCol+" = ? and enabled = ?", group, true).Find(&abilities).Error
+	return abilities, err
+}
+
 func GetEnabledModels() []string {
 	var models []string
 	// Find distinct models
@@ -323,3 +330,26 @@ func FixAbility() (int, error) {
 	InitChannelCache()
 	return count, nil
 }
+
+// GetSatisfiedChannels 返回分组下可用于该模型的所有启用渠道，按优先级降序排列
+func GetSatisfiedChannels(group string, model string) ([]*Channel, error) {
+	trueVal := "1"
+	if common.UsingPostgreSQL {
+		trueVal = "true"
+	}
+	var abilities []Ability
+	err := DB.Where(groupCol+" = ? and model = ? and enabled = "+trueVal, group, model).
+		Order("priority DESC").Order("weight DESC").Find(&abilities).Error
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]*Channel, 0, len(abilities))
+	for _, ability := range abilities {
+		channel, err := CacheGetChannel(ability.ChannelId)
+		if err != nil {
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
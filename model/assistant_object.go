@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// AssistantObject 通过 Assistants API 创建的 assistant 和 thread 保存在上游账户中，
+// 记录创建者和所在渠道，后续请求转发到同一个渠道，并且只有创建者可以访问
+type AssistantObject struct {
+	Id        int    `json:"id"`
+	ObjectId  string `json:"object_id" gorm:"type:varchar(64);uniqueIndex"`
+	Object    string `json:"object" gorm:"type:varchar(32)"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	ChannelId int    `json:"channel_id"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+func CreateAssistantObject(objectId string, object string, userId int, channelId int) error {
+	return DB.Create(&AssistantObject{
+		ObjectId:  objectId,
+		Object:    object,
+		UserId:    userId,
+		ChannelId: channelId,
+		CreatedAt: common.GetTimestamp(),
+	}).Error
+}
+
+// GetAssistantObject 查询用户创建的对象，不属于该用户时按不存在处理
+func GetAssistantObject(userId int, objectId string) (*AssistantObject, error) {
+	var object AssistantObject
+	err := DB.Where("user_id = ? and object_id = ?", userId, objectId).First(&object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("对象不存在")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// GetLatestAssistantObject 返回用户最近创建的对象，没有时返回 nil
+func GetLatestAssistantObject(userId int) (*AssistantObject, error) {
+	var object AssistantObject
+	err := DB.Where("user_id = ?", userId).Order("id desc").First(&object).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+func GetUserAssistantObjectIds(userId int, object string) (map[string]bool, error) {
+	var objectIds []string
+	err := DB.Model(&AssistantObject{}).Where("user_id = ? and object = ?", userId, object).Pluck("object_id", &objectIds).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(objectIds))
+	for _, id := range objectIds {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func DeleteAssistantObject(userId int, objectId string) error {
+	return DB.Where("user_id = ? and object_id = ?", userId, objectId).Delete(&AssistantObject{}).Error
+}
+
+// GetGroupChannels 返回分组下所有启用的渠道，按优先级降序排列
+func GetGroupChannels(group string) ([]*Channel, error) {
+	trueVal := "1"
+	if common.UsingPostgreSQL {
+		trueVal = "true"
+	}
+	var channelIds []int
+	err := DB.Model(&Ability{}).Select("channel_id").Where(groupCol+" = ? and enabled = "+trueVal, group).
+		Group("channel_id").Order("max(priority) DESC").Pluck("channel_id", &channelIds).Error
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]*Channel, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		channel, err := CacheGetChannel(channelId)
+		if err != nil {
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
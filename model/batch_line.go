@@ -0,0 +1,91 @@
+package model
+
+import (
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BatchLineStatusPending   = 1
+	BatchLineStatusSucceeded = 2
+	BatchLineStatusFailed    = 3
+	BatchLineStatusCancelled = 4
+	BatchLineStatusExpired   = 5
+)
+
+// BatchLine 由网关执行的批量请求中的一行，记录每一行的执行状态和上游响应
+type BatchLine struct {
+	Id               int64  `json:"id"`
+	BatchId          string `json:"batch_id" gorm:"type:varchar(50);index"`
+	LineIndex        int    `json:"line_index"`
+	CustomId         string `json:"custom_id" gorm:"type:varchar(255)"`
+	Body             []byte `json:"-"`
+	Status           int    `json:"status" gorm:"type:int;default:1;index"`
+	StatusCode       int    `json:"status_code"`
+	Response         []byte `json:"-"`
+	RequestId        string `json:"request_id" gorm:"type:varchar(64)"`
+	ChannelId        int    `json:"channel_id"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	UpdatedTime      int64  `json:"updated_time" gorm:"bigint"`
+}
+
+func InsertBatchLines(lines []*BatchLine) error {
+	return DB.CreateInBatches(lines, 500).Error
+}
+
+func DeleteBatchLines(batchId string) error {
+	return DB.Where("batch_id = ?", batchId).Delete(&BatchLine{}).Error
+}
+
+// GetPendingBatchLines 按输入文件中的顺序读取尚未执行的行
+func GetPendingBatchLines(batchId string, limit int) ([]*BatchLine, error) {
+	var lines []*BatchLine
+	err := DB.Where("batch_id = ? and status = ?", batchId, BatchLineStatusPending).
+		Order("id").Limit(limit).Find(&lines).Error
+	return lines, err
+}
+
+// Update 保存执行结果，不重写请求内容
+func (line *BatchLine) Update() error {
+	line.UpdatedTime = common.GetTimestamp()
+	return DB.Model(line).Select("status", "status_code", "response", "request_id", "channel_id",
+		"prompt_tokens", "completion_tokens", "updated_time").Updates(line).Error
+}
+
+// FinishPendingBatchLines 把尚未执行的行置为取消或过期，返回受影响的行数
+func FinishPendingBatchLines(batchId string, status int) (int64, error) {
+	result := DB.Model(&BatchLine{}).Where("batch_id = ? and status = ?", batchId, BatchLineStatusPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"updated_time": common.GetTimestamp(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CountBatchLines 按状态统计批量请求的行数
+func CountBatchLines(batchId string) (map[int]int, error) {
+	var rows []struct {
+		Status int
+		Count  int
+	}
+	err := DB.Model(&BatchLine{}).Select("status, count(*) as count").
+		Where("batch_id = ?", batchId).Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// FindBatchLines 按顺序分批读取已结束的行，不读取请求内容
+func FindBatchLines(batchId string, fn func(lines []*BatchLine) error) error {
+	var lines []*BatchLine
+	return DB.Omit("body").Where("batch_id = ?", batchId).FindInBatches(&lines, 500, func(tx *gorm.DB, batch int) error {
+		return fn(lines)
+	}).Error
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BudgetAlertPeriodDay   = "day"
+	BudgetAlertPeriodWeek  = "week"
+	BudgetAlertPeriodMonth = "month"
+)
+
+// BudgetAlert 用户或令牌的预算预警，当前周期内消费的额度占周期预算（周期内消费加剩余额度）的比例达到阈值时通知用户，
+// 同一周期内每个阈值最多通知一次
+type BudgetAlert struct {
+	Id      int `json:"id"`
+	UserId  int `json:"user_id" gorm:"uniqueIndex:idx_budget_alert_user_token"`
+	TokenId int `json:"token_id" gorm:"uniqueIndex:idx_budget_alert_user_token"` // 0 表示用户额度的预警
+	// Thresholds 百分比阈值，逗号分隔，例如 80,95
+	Thresholds        string `json:"thresholds" gorm:"type:varchar(64)"`
+	Period            string `json:"period" gorm:"type:varchar(16);default:'month'"`
+	NotifiedPeriod    string `json:"notified_period" gorm:"type:varchar(16)"`
+	NotifiedThreshold int    `json:"notified_threshold"`
+	UpdatedTime       int64  `json:"updated_time" gorm:"bigint"`
+}
+
+// ParseBudgetAlertThresholds 解析并排序百分比阈值，阈值必须在 1 到 100 之间
+func ParseBudgetAlertThresholds(thresholds []int) (string, error) {
+	if len(thresholds) == 0 {
+		return "", errors.New("预警阈值不能为空")
+	}
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	values := make([]string, 0, len(sorted))
+	for i, threshold := range sorted {
+		if threshold < 1 || threshold > 100 {
+			return "", fmt.Errorf("预警阈值 %d 必须在 1 到 100 之间", threshold)
+		}
+		if i > 0 && threshold == sorted[i-1] {
+			continue
+		}
+		values = append(values, strconv.Itoa(threshold))
+	}
+	return strings.Join(values, ","), nil
+}
+
+func (alert *BudgetAlert) GetThresholds() []int {
+	thresholds := make([]int, 0)
+	for _, value := range strings.Split(alert.Thresholds, ",") {
+		threshold, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	return thresholds
+}
+
+// CurrentPeriod 返回 t 所在周期的标识，周期变化后重新通知
+func (alert *BudgetAlert) CurrentPeriod(t time.Time) string {
+	switch alert.Period {
+	case BudgetAlertPeriodDay:
+		return t.Format("2006-01-02")
+	case BudgetAlertPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// PeriodStart 返回 t 所在周期的开始时间，周按 ISO 周从周一开始
+func (alert *BudgetAlert) PeriodStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch alert.Period {
+	case BudgetAlertPeriodDay:
+		return day
+	case BudgetAlertPeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func IsValidBudgetAlertPeriod(period string) bool {
+	switch period {
+	case BudgetAlertPeriodDay, BudgetAlertPeriodWeek, BudgetAlertPeriodMonth:
+		return true
+	}
+	return false
+}
+
+func GetUserBudgetAlerts(userId int) ([]*BudgetAlert, error) {
+	var alerts []*BudgetAlert
+	err := DB.Where("user_id = ?", userId).Order("token_id asc").Find(&alerts).Error
+	return alerts, err
+}
+
+func GetAllBudgetAlerts() ([]*BudgetAlert, error) {
+	var alerts []*BudgetAlert
+	err := DB.Find(&alerts).Error
+	return alerts, err
+}
+
+// UpsertBudgetAlert 创建或更新用户、令牌的预算预警，更新后重新开始通知
+func UpsertBudgetAlert(userId int, tokenId int, thresholds string, period string) (*BudgetAlert, error) {
+	var alert BudgetAlert
+	err := DB.Where("user_id = ? and token_id = ?", userId, tokenId).First(&alert).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	alert.UserId = userId
+	alert.TokenId = tokenId
+	alert.Thresholds = thresholds
+	alert.Period = period
+	alert.NotifiedPeriod = ""
+	alert.NotifiedThreshold = 0
+	alert.UpdatedTime = common.GetTimestamp()
+	err = DB.Save(&alert).Error
+	return &alert, err
+}
+
+func DeleteBudgetAlert(userId int, tokenId int) error {
+	result := DB.Where("user_id = ? and token_id = ?", userId, tokenId).Delete(&BudgetAlert{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("预算预警不存在")
+	}
+	return nil
+}
+
+func (alert *BudgetAlert) UpdateNotified(period string, threshold int) error {
+	alert.NotifiedPeriod = period
+	alert.NotifiedThreshold = threshold
+	return DB.Model(alert).Select("notified_period", "notified_threshold").Updates(alert).Error
+}
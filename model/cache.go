@@ -92,6 +92,40 @@ func CacheGetRandomSatisfiedChannel(group string, model string, retry int) (*Cha
 	channels := group2model2channels[group][model]
 	channelSyncLock.RUnlock()
 
+	return randomChannelByPriority(channels, retry)
+}
+
+// CacheGetRandomSatisfiedChannelWithFilter 与 CacheGetRandomSatisfiedChannel 的选择规则相同，只在满足 filter 的渠道中选择
+func CacheGetRandomSatisfiedChannelWithFilter(group string, model string, retry int, filter func(*Channel) bool) (*Channel, error) {
+	if strings.HasPrefix(model, "gpt-4-gizmo") {
+		model = "gpt-4-gizmo-*"
+	}
+	if strings.HasPrefix(model, "gpt-4o-gizmo") {
+		model = "gpt-4o-gizmo-*"
+	}
+	var channels []*Channel
+	if !common.MemoryCacheEnabled {
+		var err error
+		channels, err = GetSatisfiedChannels(group, model)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		channelSyncLock.RLock()
+		channels = group2model2channels[group][model]
+		channelSyncLock.RUnlock()
+	}
+	filtered := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if filter(channel) {
+			filtered = append(filtered, channel)
+		}
+	}
+	return randomChannelByPriority(filtered, retry)
+}
+
+// randomChannelByPriority 按重试次数选择对应优先级的渠道，同一优先级内按权重随机选择
+func randomChannelByPriority(channels []*Channel, retry int) (*Channel, error) {
 	if len(channels) == 0 {
 		return nil, errors.New("channel not found")
 	}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 	"one-api/common"
 	"strings"
 	"sync"
@@ -37,6 +38,7 @@ type Channel struct {
 	Tag               *string `json:"tag" gorm:"index"`
 	Setting           *string `json:"setting" gorm:"type:text"`
 	ParamOverride     *string `json:"param_override" gorm:"type:text"`
+	ShadowSourceId    int     `json:"shadow_source_id" gorm:"default:0;index"` // 预发布环境中从生产环境导入的只读影子渠道，对应生产环境的渠道 id
 }
 
 func (channel *Channel) GetModels() []string {
@@ -77,6 +79,12 @@ func (channel *Channel) SetOtherInfo(otherInfo map[string]interface{}) {
 	channel.OtherInfo = string(otherInfoBytes)
 }
 
+// GetProviderIncident 返回因供应商故障暂停该渠道时记录的供应商名称
+func (channel *Channel) GetProviderIncident() string {
+	provider, _ := channel.GetOtherInfo()["provider_incident"].(string)
+	return provider
+}
+
 func (channel *Channel) GetTag() string {
 	if channel.Tag == nil {
 		return ""
@@ -95,6 +103,55 @@ func (channel *Channel) GetAutoBan() bool {
 	return *channel.AutoBan == 1
 }
 
+func (channel *Channel) IsShadow() bool {
+	return channel.ShadowSourceId != 0
+}
+
+// ShadowChannelKeyRef 影子渠道不保存密钥，只保存对密钥管理中对应生产渠道密钥的引用
+func ShadowChannelKeyRef(sourceId int) string {
+	return fmt.Sprintf("%schannel-%d", common.SecretRefPrefix, sourceId)
+}
+
+// AfterFind 读取影子渠道时把密钥引用解析为实际的密钥
+func (channel *Channel) AfterFind(tx *gorm.DB) error {
+	if !channel.IsShadow() || !common.IsSecretRef(channel.Key) {
+		return nil
+	}
+	key, err := common.ResolveSecretRef(channel.Key)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to resolve key of shadow channel #%d: %s", channel.Id, err.Error()))
+		return nil
+	}
+	channel.Key = key
+	return nil
+}
+
+// BeforeSave 保存影子渠道时始终写回密钥引用，避免解析后的密钥落库
+func (channel *Channel) BeforeSave(tx *gorm.DB) error {
+	if channel.IsShadow() {
+		channel.Key = ShadowChannelKeyRef(channel.ShadowSourceId)
+	}
+	return nil
+}
+
+func IsShadowChannel(id int) bool {
+	var shadowSourceId int
+	DB.Model(&Channel{}).Select("shadow_source_id").Where("id = ?", id).Scan(&shadowSourceId)
+	return shadowSourceId != 0
+}
+
+func HasShadowChannel(ids []int) bool {
+	var count int64
+	DB.Model(&Channel{}).Where("id in (?) and shadow_source_id <> 0", ids).Count(&count)
+	return count > 0
+}
+
+func GetShadowChannels() ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Where("shadow_source_id <> 0").Find(&channels).Error
+	return channels, err
+}
+
 func (channel *Channel) Save() error {
 	return DB.Save(channel).Error
 }
@@ -308,6 +365,10 @@ func (channel *Channel) Delete() error {
 var channelStatusLock sync.Mutex
 
 func UpdateChannelStatusById(id int, status int, reason string) bool {
+	// 影子渠道的状态跟随生产环境同步，不因预发布环境的请求或测试自动启用、禁用
+	if IsShadowChannel(id) {
+		return false
+	}
 	if common.MemoryCacheEnabled {
 		channelStatusLock.Lock()
 		defer channelStatusLock.Unlock()
@@ -358,6 +419,21 @@ func UpdateChannelStatusById(id int, status int, reason string) bool {
 	return true
 }
 
+func SetChannelProviderIncident(id int, provider string) error {
+	channel, err := GetChannelById(id, true)
+	if err != nil {
+		return err
+	}
+	info := channel.GetOtherInfo()
+	if provider == "" {
+		delete(info, "provider_incident")
+	} else {
+		info["provider_incident"] = provider
+	}
+	channel.SetOtherInfo(info)
+	return DB.Model(&Channel{}).Where("id = ?", id).Update("other_info", channel.OtherInfo).Error
+}
+
 func EnableChannelByTag(tag string) error {
 	err := DB.Model(&Channel{}).Where("tag = ?", tag).Update("status", common.ChannelStatusEnabled).Error
 	if err != nil {
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 统计数据库连接池状态和各类语句的耗时分布，耗时超过阈值的语句记录到日志
+// 慢查询日志只记录带占位符的 SQL，不记录参数，避免泄露用户数据
+
+const dbMetricsStartKey = "metrics:start_time"
+
+var queryLatencyBounds = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+type DBPoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+type DBMetrics struct {
+	Name        string                     `json:"name"`
+	Pool        DBPoolStats                `json:"pool"`
+	Latency     map[string]*UsageHistogram `json:"latency_ms"`
+	SlowQueries int64                      `json:"slow_queries"`
+}
+
+type dbMetricsCollector struct {
+	name          string
+	db            *gorm.DB
+	slowThreshold time.Duration
+	lock          sync.Mutex
+	latency       map[string]*UsageHistogram
+	slowQueries   int64
+}
+
+var dbMetricsCollectors []*dbMetricsCollector
+
+// registerDBMetrics 为 db 的各类语句注册计时回调，SQL_SLOW_QUERY_THRESHOLD 为慢查询阈值（毫秒），0 表示不记录慢查询
+func registerDBMetrics(db *gorm.DB, name string) error {
+	collector := &dbMetricsCollector{
+		name:          name,
+		db:            db,
+		slowThreshold: time.Duration(common.GetEnvOrDefault("SQL_SLOW_QUERY_THRESHOLD", 1000)) * time.Millisecond,
+		latency:       make(map[string]*UsageHistogram),
+	}
+	callback := db.Callback()
+	registers := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("gorm:create").Register, callback.Create().After("gorm:create").Register},
+		{"query", callback.Query().Before("gorm:query").Register, callback.Query().After("gorm:query").Register},
+		{"update", callback.Update().Before("gorm:update").Register, callback.Update().After("gorm:update").Register},
+		{"delete", callback.Delete().Before("gorm:delete").Register, callback.Delete().After("gorm:delete").Register},
+		{"row", callback.Row().Before("gorm:row").Register, callback.Row().After("gorm:row").Register},
+		{"raw", callback.Raw().Before("gorm:raw").Register, callback.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range registers {
+		collector.latency[r.operation] = newUsageHistogram(queryLatencyBounds)
+		err := r.before("metrics:before_"+r.operation, beforeStatement)
+		if err != nil {
+			return err
+		}
+		err = r.after("metrics:after_"+r.operation, collector.afterStatement(r.operation))
+		if err != nil {
+			return err
+		}
+	}
+	dbMetricsCollectors = append(dbMetricsCollectors, collector)
+	return nil
+}
+
+func beforeStatement(db *gorm.DB) {
+	db.InstanceSet(dbMetricsStartKey, time.Now())
+}
+
+func (m *dbMetricsCollector) afterStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(dbMetricsStartKey)
+		if !ok {
+			return
+		}
+		startTime, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(startTime)
+		isSlow := m.slowThreshold > 0 && elapsed >= m.slowThreshold
+		m.lock.Lock()
+		m.latency[operation].observe(float64(elapsed.Microseconds()) / 1000)
+		if isSlow {
+			m.slowQueries++
+		}
+		m.lock.Unlock()
+		if isSlow {
+			common.SysLog(fmt.Sprintf("slow query on %s db: %dms, %d rows, %d params, sql: %s",
+				m.name, elapsed.Milliseconds(), db.Statement.RowsAffected, len(db.Statement.Vars), db.Statement.SQL.String()))
+		}
+	}
+}
+
+func (m *dbMetricsCollector) metrics() *DBMetrics {
+	metrics := &DBMetrics{
+		Name:    m.name,
+		Latency: make(map[string]*UsageHistogram, len(m.latency)),
+	}
+	if sqlDB, err := m.db.DB(); err == nil {
+		stats := sqlDB.Stats()
+		metrics.Pool = DBPoolStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		}
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for operation, histogram := range m.latency {
+		metrics.Latency[operation] = histogram.clone()
+	}
+	metrics.SlowQueries = m.slowQueries
+	return metrics
+}
+
+// GetDBMetrics 返回主库和日志库（如果单独配置）的连接池状态和语句耗时分布
+func GetDBMetrics() []*DBMetrics {
+	result := make([]*DBMetrics, 0, len(dbMetricsCollectors))
+	for _, collector := range dbMetricsCollectors {
+		result = append(result, collector.metrics())
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// OpenDB 按环境变量连接数据库，不执行迁移，用于启动前诊断
+func OpenDB(envName string) (*gorm.DB, error) {
+	return chooseDB(envName)
+}
+
+// PendingMigrations 返回数据库中缺少的表和字段，为空表示已完成迁移；isLogDB 表示单独的日志数据库
+func PendingMigrations(db *gorm.DB, isLogDB bool) ([]string, error) {
+	models := migrateModels
+	if isLogDB {
+		models = []any{&Log{}}
+	}
+	var pending []string
+	migrator := db.Migrator()
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(m) {
+			pending = append(pending, "表 "+table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			if !migrator.HasColumn(m, field.DBName) {
+				pending = append(pending, fmt.Sprintf("字段 %s.%s", table, field.DBName))
+			}
+		}
+	}
+	return pending, nil
+}
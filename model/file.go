@@ -0,0 +1,144 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+	"one-api/constant"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// File 用户通过 /v1/files 上传的文件，内容保存在数据库、本地目录或对象存储中
+type File struct {
+	Id        int    `json:"-"`
+	FileId    string `json:"id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId    int    `json:"-" gorm:"index"`
+	Purpose   string `json:"purpose" gorm:"type:varchar(32)"`
+	Filename  string `json:"filename"`
+	Bytes     int    `json:"bytes"`
+	Content   []byte `json:"-"`
+	Storage   string `json:"-" gorm:"type:varchar(16)"` // 为空表示保存在数据库中
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+}
+
+var ErrFileStorageExceeded = errors.New("file storage exceeded")
+
+// Insert 按 FILE_STORAGE 保存文件内容，保存到数据库以外的位置时文件记录中不保存内容。
+// limitBytes 大于 0 时限制用户文件的总大小，超过时返回 ErrFileStorageExceeded
+func (file *File) Insert(limitBytes int64) error {
+	if constant.FileStorage == "" || constant.FileStorage == FileStorageDB {
+		return file.create(limitBytes)
+	}
+	storage, err := getFileStorage(constant.FileStorage)
+	if err != nil {
+		return err
+	}
+	if err = storage.put(file.FileId, file.Content); err != nil {
+		return err
+	}
+	content := file.Content
+	file.Content = nil
+	file.Storage = constant.FileStorage
+	err = file.create(limitBytes)
+	file.Content = content
+	if err != nil {
+		_ = storage.delete(file.FileId)
+	}
+	return err
+}
+
+// create 在同一个事务中检查用户文件总大小并写入文件记录，锁定用户行使同一用户的并发上传依次检查
+func (file *File) create(limitBytes int64) error {
+	if limitBytes <= 0 {
+		return DB.Create(file).Error
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", file.UserId).First(&user).Error
+		if err != nil {
+			return err
+		}
+		var used int64
+		err = tx.Model(&File{}).Select("coalesce(sum(bytes), 0)").Where("user_id = ?", file.UserId).Scan(&used).Error
+		if err != nil {
+			return err
+		}
+		if used+int64(file.Bytes) > limitBytes {
+			return ErrFileStorageExceeded
+		}
+		return tx.Create(file).Error
+	})
+}
+
+// GetFileByFileId 查询用户的文件，withContent 为 false 时不读取文件内容
+func GetFileByFileId(userId int, fileId string, withContent bool) (*File, error) {
+	if fileId == "" {
+		return nil, errors.New("file id 为空！")
+	}
+	var file File
+	query := DB.Where("user_id = ? and file_id = ?", userId, fileId)
+	if !withContent {
+		query = query.Omit("content")
+	}
+	err := query.First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("文件不存在")
+		}
+		return nil, err
+	}
+	if withContent && file.Storage != "" && file.Storage != FileStorageDB {
+		storage, err := getFileStorage(file.Storage)
+		if err != nil {
+			return nil, err
+		}
+		file.Content, err = storage.get(file.FileId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &file, nil
+}
+
+func GetUserFiles(userId int, purpose string) ([]*File, error) {
+	var files []*File
+	query := DB.Omit("content").Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	err := query.Order("id desc").Find(&files).Error
+	return files, err
+}
+
+func DeleteFileByFileId(userId int, fileId string) error {
+	file, err := GetFileByFileId(userId, fileId, false)
+	if err != nil {
+		return err
+	}
+	result := DB.Where("id = ?", file.Id).Delete(&File{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("文件不存在")
+	}
+	deleteFileContents([]*File{file})
+	return nil
+}
+
+// deleteFileContents 删除保存在数据库以外的文件内容，文件记录已删除，失败只记录日志
+func deleteFileContents(files []*File) {
+	for _, file := range files {
+		if file.Storage == "" || file.Storage == FileStorageDB {
+			continue
+		}
+		storage, err := getFileStorage(file.Storage)
+		if err == nil {
+			err = storage.delete(file.FileId)
+		}
+		if err != nil {
+			common.SysError("failed to delete file " + file.FileId + ": " + err.Error())
+		}
+	}
+}
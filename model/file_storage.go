@@ -0,0 +1,133 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/constant"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	FileStorageDB    = "db"
+	FileStorageLocal = "local"
+	FileStorageS3    = "s3"
+)
+
+// fileStorage 保存文件内容的后端，文件记录中保存使用的后端，修改 FILE_STORAGE 后旧文件仍从原来的位置读取
+type fileStorage interface {
+	put(key string, content []byte) error
+	get(key string) ([]byte, error)
+	delete(key string) error
+}
+
+func getFileStorage(name string) (fileStorage, error) {
+	switch name {
+	case FileStorageLocal:
+		return localFileStorage{dir: constant.FileStoragePath}, nil
+	case FileStorageS3:
+		if constant.FileStorageS3Endpoint == "" || constant.FileStorageS3Bucket == "" {
+			return nil, errors.New("未配置 FILE_STORAGE_S3_ENDPOINT 或 FILE_STORAGE_S3_BUCKET")
+		}
+		return s3FileStorage{
+			endpoint:  constant.FileStorageS3Endpoint,
+			bucket:    constant.FileStorageS3Bucket,
+			region:    constant.FileStorageS3Region,
+			accessKey: constant.FileStorageS3AccessKey,
+			secretKey: constant.FileStorageS3SecretKey,
+		}, nil
+	}
+	return nil, fmt.Errorf("不支持的文件存储 %s", name)
+}
+
+type localFileStorage struct {
+	dir string
+}
+
+func (s localFileStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.Base(key))
+}
+
+func (s localFileStorage) put(key string, content []byte) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(key), content, 0o644)
+}
+
+func (s localFileStorage) get(key string) ([]byte, error) {
+	return os.ReadFile(s.path(key))
+}
+
+func (s localFileStorage) delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// s3FileStorage 使用路径风格的地址访问 S3 兼容的对象存储（AWS S3、MinIO 等）
+type s3FileStorage struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+var s3HttpClient = &http.Client{Timeout: 5 * time.Minute}
+
+func (s s3FileStorage) do(method string, key string, content []byte) ([]byte, error) {
+	objectURL := fmt.Sprintf("%s/%s/%s", s.endpoint, url.PathEscape(s.bucket), url.PathEscape(key))
+	req, err := http.NewRequest(method, objectURL, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(content)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{AccessKeyID: s.accessKey, SecretAccessKey: s.secretKey}
+	err = v4.NewSigner().SignHTTP(context.Background(), credentials, req, payloadHash, "s3", s.region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign request failed: %w", err)
+	}
+	resp, err := s3HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return nil, fmt.Errorf("s3 %s %s failed, status code: %d, body: %s", method, key, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (s s3FileStorage) put(key string, content []byte) error {
+	_, err := s.do(http.MethodPut, key, content)
+	return err
+}
+
+func (s s3FileStorage) get(key string) ([]byte, error) {
+	return s.do(http.MethodGet, key, nil)
+}
+
+func (s s3FileStorage) delete(key string) error {
+	_, err := s.do(http.MethodDelete, key, nil)
+	return err
+}
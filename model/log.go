@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"one-api/common"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -90,6 +91,47 @@ func RecordLog(userId int, logType int, content string) {
 	}
 }
 
+// RecordTaskConsumeLog 记录异步任务在后台结算时的消费日志，没有请求上下文，用户名和令牌名称从数据库读取
+func RecordTaskConsumeLog(ctx context.Context, userId int, channelId int, promptTokens int, completionTokens int,
+	modelName string, tokenId int, quota int, content string, group string, other map[string]interface{}) {
+	common.LogInfo(ctx, fmt.Sprintf("record task consume log: userId=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenId=%d, quota=%d, content=%s", userId, channelId, promptTokens, completionTokens, modelName, tokenId, quota, content))
+	if !common.LogConsumeEnabled {
+		return
+	}
+	username, _ := GetUsernameById(userId, false)
+	var tokenName string
+	if tokenId != 0 {
+		if token, err := GetTokenById(tokenId); err == nil {
+			tokenName = token.Name
+		}
+	}
+	log := &Log{
+		UserId:           userId,
+		Username:         username,
+		CreatedAt:        common.GetTimestamp(),
+		Type:             LogTypeConsume,
+		Content:          content,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TokenName:        tokenName,
+		ModelName:        modelName,
+		Quota:            quota,
+		ChannelId:        channelId,
+		TokenId:          tokenId,
+		Group:            group,
+		Other:            common.MapToJsonStr(other),
+	}
+	err := LOG_DB.Create(log).Error
+	if err != nil {
+		common.LogError(ctx, "failed to record log: "+err.Error())
+	}
+	if common.DataExportEnabled {
+		gopool.Go(func() {
+			LogQuotaData(userId, username, modelName, quota, common.GetTimestamp(), promptTokens+completionTokens)
+		})
+	}
+}
+
 func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string, tokenName string, content string, tokenId int, useTimeSeconds int,
 	isStream bool, group string, other map[string]interface{}) {
 	common.LogInfo(c, fmt.Sprintf("record error log: userId=%d, channelId=%d, modelName=%s, tokenName=%s, content=%s", userId, channelId, modelName, tokenName, content))
@@ -123,6 +165,9 @@ func RecordConsumeLog(c *gin.Context, userId int, channelId int, promptTokens in
 	modelName string, tokenName string, quota int, content string, tokenId int, userQuota int, useTimeSeconds int,
 	isStream bool, group string, other map[string]interface{}) {
 	common.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, 用户调用前余额=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", userId, userQuota, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
+	if common.DataExportEnabled {
+		LogUsageHistogram(modelName, promptTokens, completionTokens, isStream, useTimeSeconds)
+	}
 	if !common.LogConsumeEnabled {
 		return
 	}
@@ -320,6 +365,94 @@ func SumUsedQuota(logType int, startTimestamp int64, endTimestamp int64, modelNa
 	return stat
 }
 
+type DailyModelQuota struct {
+	Day       int64  `json:"day"`
+	ModelName string `json:"model_name"`
+	Quota     int    `json:"quota"`
+}
+
+// SumDailyModelQuota 按 UTC 自然日和模型统计用户的消费额度，tokenId 不为 0 时只统计该令牌
+func SumDailyModelQuota(userId int, tokenId int, startTimestamp int64, endTimestamp int64) (quotas []*DailyModelQuota, err error) {
+	if startTimestamp%UsageRollupDailyPeriod == 0 && endTimestamp%UsageRollupDailyPeriod == 0 {
+		rollups, err := SumUsageRollup(UsageRollupDailyPeriod, UsageRollupFilter{UserId: userId, TokenId: tokenId}, startTimestamp, endTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		for _, rollup := range rollups {
+			quotas = append(quotas, &DailyModelQuota{Day: rollup.BucketStart, ModelName: rollup.ModelName, Quota: int(rollup.Quota)})
+		}
+		return quotas, nil
+	}
+	tx := LOG_DB.Table("logs").Select("created_at - created_at % 86400 as day, model_name, sum(quota) as quota").
+		Where("user_id = ? and type = ?", userId, LogTypeConsume)
+	if tokenId != 0 {
+		tx = tx.Where("token_id = ?", tokenId)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at < ?", endTimestamp)
+	}
+	err = tx.Group("day, model_name").Order("day").Scan(&quotas).Error
+	return quotas, err
+}
+
+type ModelUsage struct {
+	ModelName        string `json:"model_name"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	Quota            int64  `json:"quota"`
+}
+
+// SumModelUsage 按模型统计用户区间内的请求数、token 数和消费额度，tokenId 不为 0 时只统计该令牌
+func SumModelUsage(userId int, tokenId int, startTimestamp int64, endTimestamp int64) (usages []*ModelUsage, err error) {
+	if startTimestamp%UsageRollupHourlyPeriod == 0 && endTimestamp%UsageRollupHourlyPeriod == 0 {
+		return sumModelUsageFromRollup(userId, tokenId, startTimestamp, endTimestamp)
+	}
+	tx := LOG_DB.Table("logs").Select("model_name, count(*) as requests, sum(prompt_tokens) as prompt_tokens, "+
+		"sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("user_id = ? and type = ?", userId, LogTypeConsume)
+	if tokenId != 0 {
+		tx = tx.Where("token_id = ?", tokenId)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at < ?", endTimestamp)
+	}
+	err = tx.Group("model_name").Order("model_name").Scan(&usages).Error
+	return usages, err
+}
+
+// sumModelUsageFromRollup 起止时间按小时对齐时从按小时汇总的用量中统计
+func sumModelUsageFromRollup(userId int, tokenId int, startTimestamp int64, endTimestamp int64) ([]*ModelUsage, error) {
+	rollups, err := SumUsageRollup(UsageRollupHourlyPeriod, UsageRollupFilter{UserId: userId, TokenId: tokenId}, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	usageMap := make(map[string]*ModelUsage)
+	usages := make([]*ModelUsage, 0)
+	for _, rollup := range rollups {
+		usage, ok := usageMap[rollup.ModelName]
+		if !ok {
+			usage = &ModelUsage{ModelName: rollup.ModelName}
+			usageMap[rollup.ModelName] = usage
+			usages = append(usages, usage)
+		}
+		usage.Requests += rollup.Requests
+		usage.PromptTokens += rollup.PromptTokens
+		usage.CompletionTokens += rollup.CompletionTokens
+		usage.Quota += rollup.Quota
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].ModelName < usages[j].ModelName
+	})
+	return usages, nil
+}
+
 func SumUsedToken(logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string) (token int) {
 	tx := LOG_DB.Table("logs").Select("ifnull(sum(prompt_tokens),0) + ifnull(sum(completion_tokens),0)")
 	if username != "" {
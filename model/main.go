@@ -141,6 +141,10 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = registerDBMetrics(DB, "main")
+		if err != nil {
+			return err
+		}
 		sqlDB.SetMaxIdleConns(common.GetEnvOrDefault("SQL_MAX_IDLE_CONNS", 100))
 		sqlDB.SetMaxOpenConns(common.GetEnvOrDefault("SQL_MAX_OPEN_CONNS", 1000))
 		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(common.GetEnvOrDefault("SQL_MAX_LIFETIME", 60)))
@@ -175,6 +179,10 @@ func InitLogDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = registerDBMetrics(LOG_DB, "log")
+		if err != nil {
+			return err
+		}
 		sqlDB.SetMaxIdleConns(common.GetEnvOrDefault("SQL_MAX_IDLE_CONNS", 100))
 		sqlDB.SetMaxOpenConns(common.GetEnvOrDefault("SQL_MAX_OPEN_CONNS", 1000))
 		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(common.GetEnvOrDefault("SQL_MAX_LIFETIME", 60)))
@@ -197,55 +205,41 @@ func InitLogDB() (err error) {
 	return err
 }
 
+// migrateModels 主数据库中的表，按顺序迁移
+var migrateModels = []any{
+	&Channel{},
+	&Token{},
+	&User{},
+	&Option{},
+	&Redemption{},
+	&Ability{},
+	&Log{},
+	&Midjourney{},
+	&TopUp{},
+	&QuotaData{},
+	&Task{},
+	&Setup{},
+	&File{},
+	&UserErasureRequest{},
+	&BatchLine{},
+	&AssistantObject{},
+	&BudgetAlert{},
+	&UserSubscription{},
+	&PostpaidStatement{},
+	&UsageRollupHourly{},
+	&UsageRollupDaily{},
+	&UsageRollupCursor{},
+}
+
 func migrateDB() error {
-	err := DB.AutoMigrate(&Channel{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Token{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&User{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Option{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Redemption{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Ability{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Log{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Midjourney{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&TopUp{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&QuotaData{})
-	if err != nil {
-		return err
-	}
-	err = DB.AutoMigrate(&Task{})
-	if err != nil {
-		return err
+	for _, m := range migrateModels {
+		if err := DB.AutoMigrate(m); err != nil {
+			return err
+		}
 	}
-	err = DB.AutoMigrate(&Setup{})
 	common.SysLog("database migrated")
 	//err = createRootAccountIfNeed()
-	return err
+	return nil
 }
 
 func migrateLOGDB() error {
@@ -74,6 +74,10 @@ func InitOptionMap() {
 	common.OptionMap["EpayKey"] = ""
 	common.OptionMap["Price"] = strconv.FormatFloat(setting.Price, 'f', -1, 64)
 	common.OptionMap["MinTopUp"] = strconv.Itoa(setting.MinTopUp)
+	common.OptionMap["StripeApiSecret"] = ""
+	common.OptionMap["StripeWebhookSecret"] = ""
+	common.OptionMap["StripeCurrency"] = setting.StripeCurrency
+	common.OptionMap["StripeUnitPrice"] = strconv.FormatFloat(setting.StripeUnitPrice, 'f', -1, 64)
 	common.OptionMap["TopupGroupRatio"] = common.TopupGroupRatio2JSONString()
 	common.OptionMap["Chats"] = setting.Chats2JsonString()
 	common.OptionMap["GitHubClientId"] = ""
@@ -94,10 +98,23 @@ func InitOptionMap() {
 	common.OptionMap["ModelRequestRateLimitDurationMinutes"] = strconv.Itoa(setting.ModelRequestRateLimitDurationMinutes)
 	common.OptionMap["ModelRequestRateLimitSuccessCount"] = strconv.Itoa(setting.ModelRequestRateLimitSuccessCount)
 	common.OptionMap["ModelRequestRateLimitGroup"] = setting.ModelRequestRateLimitGroup2JSONString()
+	common.OptionMap["TokenRateLimitTiers"] = setting.TokenRateLimitTiers2JSONString()
+	common.OptionMap["SubscriptionPlans"] = setting.SubscriptionPlans2JSONString()
+	common.OptionMap["LegacyKeyAuthGroups"] = setting.LegacyKeyAuthGroups2JSONString()
 	common.OptionMap["ModelRatio"] = operation_setting.ModelRatio2JSONString()
 	common.OptionMap["ModelPrice"] = operation_setting.ModelPrice2JSONString()
 	common.OptionMap["CacheRatio"] = operation_setting.CacheRatio2JSONString()
+	common.OptionMap["CreateCacheRatio"] = operation_setting.CreateCacheRatio2JSONString()
+	common.OptionMap["ReasoningRatio"] = operation_setting.ReasoningRatio2JSONString()
+	common.OptionMap["ModelPricing"] = operation_setting.ModelPricing2JSONString()
+	common.OptionMap["ImagePricing"] = operation_setting.ImagePricing2JSONString()
+	common.OptionMap["FreeModels"] = operation_setting.FreeModels2JSONString()
+	common.OptionMap["DefaultCacheRatio"] = strconv.FormatFloat(operation_setting.DefaultCacheRatio, 'f', -1, 64)
+	common.OptionMap["ModelMaxTokens"] = operation_setting.ModelMaxTokens2JSONString()
+	common.OptionMap["ServiceTierRatio"] = operation_setting.ServiceTierRatio2JSONString()
 	common.OptionMap["GroupRatio"] = setting.GroupRatio2JSONString()
+	common.OptionMap["GroupVolumeTiers"] = setting.GroupVolumeTiers2JSONString()
+	common.OptionMap["GroupModelRatio"] = setting.GroupModelRatio2JSONString()
 	common.OptionMap["UserUsableGroups"] = setting.UserUsableGroups2JSONString()
 	common.OptionMap["CompletionRatio"] = operation_setting.CompletionRatio2JSONString()
 	common.OptionMap["TopUpLink"] = common.TopUpLink
@@ -296,6 +313,14 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.Price, _ = strconv.ParseFloat(value, 64)
 	case "MinTopUp":
 		setting.MinTopUp, _ = strconv.Atoi(value)
+	case "StripeApiSecret":
+		setting.StripeApiSecret = value
+	case "StripeWebhookSecret":
+		setting.StripeWebhookSecret = value
+	case "StripeCurrency":
+		setting.StripeCurrency = strings.ToLower(value)
+	case "StripeUnitPrice":
+		setting.StripeUnitPrice, _ = strconv.ParseFloat(value, 64)
 	case "TopupGroupRatio":
 		err = common.UpdateTopupGroupRatioByJSONString(value)
 	case "GitHubClientId":
@@ -344,6 +369,12 @@ func updateOptionMap(key string, value string) (err error) {
 		setting.ModelRequestRateLimitSuccessCount, _ = strconv.Atoi(value)
 	case "ModelRequestRateLimitGroup":
 		err = setting.UpdateModelRequestRateLimitGroupByJSONString(value)
+	case "TokenRateLimitTiers":
+		err = setting.UpdateTokenRateLimitTiersByJSONString(value)
+	case "SubscriptionPlans":
+		err = setting.UpdateSubscriptionPlansByJSONString(value)
+	case "LegacyKeyAuthGroups":
+		err = setting.UpdateLegacyKeyAuthGroupsByJSONString(value)
 	case "RetryTimes":
 		common.RetryTimes, _ = strconv.Atoi(value)
 	case "DataExportInterval":
@@ -354,6 +385,10 @@ func updateOptionMap(key string, value string) (err error) {
 		err = operation_setting.UpdateModelRatioByJSONString(value)
 	case "GroupRatio":
 		err = setting.UpdateGroupRatioByJSONString(value)
+	case "GroupVolumeTiers":
+		err = setting.UpdateGroupVolumeTiersByJSONString(value)
+	case "GroupModelRatio":
+		err = setting.UpdateGroupModelRatioByJSONString(value)
 	case "UserUsableGroups":
 		err = setting.UpdateUserUsableGroupsByJSONString(value)
 	case "CompletionRatio":
@@ -362,6 +397,26 @@ func updateOptionMap(key string, value string) (err error) {
 		err = operation_setting.UpdateModelPriceByJSONString(value)
 	case "CacheRatio":
 		err = operation_setting.UpdateCacheRatioByJSONString(value)
+	case "CreateCacheRatio":
+		err = operation_setting.UpdateCreateCacheRatioByJSONString(value)
+	case "ReasoningRatio":
+		err = operation_setting.UpdateReasoningRatioByJSONString(value)
+	case "ModelPricing":
+		err = operation_setting.UpdateModelPricingByJSONString(value)
+	case "ImagePricing":
+		err = operation_setting.UpdateImagePricingByJSONString(value)
+	case "FreeModels":
+		err = operation_setting.UpdateFreeModelsByJSONString(value)
+	case "DefaultCacheRatio":
+		var ratio float64
+		ratio, err = strconv.ParseFloat(value, 64)
+		if err == nil {
+			operation_setting.DefaultCacheRatio = ratio
+		}
+	case "ModelMaxTokens":
+		err = operation_setting.UpdateModelMaxTokensByJSONString(value)
+	case "ServiceTierRatio":
+		err = operation_setting.UpdateServiceTierRatioByJSONString(value)
 	case "TopUpLink":
 		common.TopUpLink = value
 	//case "ChatLink":
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"errors"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// PostpaidStatement 后付费用户的月度账单，每个用户每月一份，在次月初生成
+type PostpaidStatement struct {
+	Id             int    `json:"id"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_postpaid_statement_user_period,priority:1"`
+	Period         string `json:"period" gorm:"type:varchar(7);uniqueIndex:idx_postpaid_statement_user_period,priority:2"` // 账单月份，如 2025-01
+	StartTimestamp int64  `json:"start_timestamp" gorm:"bigint"`
+	EndTimestamp   int64  `json:"end_timestamp" gorm:"bigint"`
+	Requests       int64  `json:"requests"`
+	UsedQuota      int64  `json:"used_quota"`   // 当月消耗的额度
+	Balance        int    `json:"balance"`      // 生成账单时的余额，为负数时表示欠款
+	CreditLimit    int    `json:"credit_limit"` // 生成账单时的信用额度
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+}
+
+// GetPostpaidUserIds 返回所有后付费用户的 id
+func GetPostpaidUserIds() ([]int, error) {
+	var ids []int
+	err := DB.Model(&User{}).Where("billing_mode = ?", common.UserBillingModePostpaid).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PostpaidStatementExists 判断用户指定月份的账单是否已生成
+func PostpaidStatementExists(userId int, period string) (bool, error) {
+	var statement PostpaidStatement
+	err := DB.Where("user_id = ? and period = ?", userId, period).First(&statement).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (statement *PostpaidStatement) Insert() error {
+	return DB.Create(statement).Error
+}
+
+// GetPostpaidStatements 按月份倒序返回用户的账单
+func GetPostpaidStatements(userId int) ([]*PostpaidStatement, error) {
+	var statements []*PostpaidStatement
+	err := DB.Where("user_id = ?", userId).Order("period desc").Find(&statements).Error
+	return statements, err
+}
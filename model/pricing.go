@@ -14,6 +14,8 @@ type Pricing struct {
 	ModelPrice      float64  `json:"model_price"`
 	OwnerBy         string   `json:"owner_by"`
 	CompletionRatio float64  `json:"completion_ratio"`
+	ReasoningRatio  float64  `json:"reasoning_ratio,omitempty"`
+	FreeInput       bool     `json:"free_input,omitempty"`
 	EnableGroup     []string `json:"enable_groups,omitempty"`
 }
 
@@ -73,6 +75,8 @@ func updatePricing() {
 			modelRatio, _ := operation_setting.GetModelRatio(model)
 			pricing.ModelRatio = modelRatio
 			pricing.CompletionRatio = operation_setting.GetCompletionRatio(model)
+			pricing.ReasoningRatio, _ = operation_setting.GetReasoningRatio(model)
+			pricing.FreeInput = operation_setting.IsFreeInputModel(model)
 			pricing.QuotaType = 0
 		}
 		pricingMap = append(pricingMap, pricing)
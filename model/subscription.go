@@ -0,0 +1,234 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"one-api/common"
+	"one-api/setting"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// UserSubscription 用户订阅的套餐，每个用户同时只有一个套餐。到达 RenewTime 时发放下一个周期的额度，
+// 超过 ExpiredTime 后不再续期
+type UserSubscription struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"uniqueIndex"`
+	Plan        string `json:"plan" gorm:"type:varchar(64);default:''"`
+	StartTime   int64  `json:"start_time" gorm:"bigint"`       // 当前周期的开始时间
+	RenewTime   int64  `json:"renew_time" gorm:"bigint;index"` // 下一个周期的开始时间
+	ExpiredTime int64  `json:"expired_time" gorm:"bigint"`     // 0 表示不过期
+	Period      int64  `json:"period" gorm:"bigint"`           // 周期秒数
+	Price       int    `json:"price"`                          // 每个周期实际支付的额度，更换套餐时按剩余时间折算退还
+	Quota       int    `json:"quota"`                          // 当前周期发放的额度，更换套餐时按剩余时间折算扣回
+}
+
+func (sub *UserSubscription) IsActive(now int64) bool {
+	return sub.Plan != "" && (sub.ExpiredTime == 0 || sub.ExpiredTime > now)
+}
+
+// GetUserSubscription 返回用户的订阅，没有订阅时返回 nil
+func GetUserSubscription(userId int) (*UserSubscription, error) {
+	var sub UserSubscription
+	err := DB.Where("user_id = ?", userId).First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+type cachedSubscriptionPlan struct {
+	plan      string
+	expiresAt int64
+}
+
+var subscriptionPlanCache = make(map[int]cachedSubscriptionPlan)
+var subscriptionPlanCacheLock sync.Mutex
+
+// subscriptionPlanCacheSeconds 请求时读取订阅套餐的缓存时间，其他节点上的订阅变更最多延迟该时间生效
+const subscriptionPlanCacheSeconds = 60
+
+// GetActiveSubscriptionPlan 返回用户当前生效的套餐名称，没有订阅或已过期时返回空字符串
+func GetActiveSubscriptionPlan(userId int) string {
+	now := common.GetTimestamp()
+	subscriptionPlanCacheLock.Lock()
+	cached, ok := subscriptionPlanCache[userId]
+	subscriptionPlanCacheLock.Unlock()
+	if ok && cached.expiresAt > now {
+		return cached.plan
+	}
+	plan := ""
+	sub, err := GetUserSubscription(userId)
+	if err != nil {
+		common.SysError("failed to get user subscription: " + err.Error())
+	} else if sub != nil && sub.IsActive(now) {
+		plan = sub.Plan
+	}
+	subscriptionPlanCacheLock.Lock()
+	subscriptionPlanCache[userId] = cachedSubscriptionPlan{plan: plan, expiresAt: now + subscriptionPlanCacheSeconds}
+	subscriptionPlanCacheLock.Unlock()
+	return plan
+}
+
+func invalidateSubscriptionPlanCache(userId int) {
+	subscriptionPlanCacheLock.Lock()
+	delete(subscriptionPlanCache, userId)
+	subscriptionPlanCacheLock.Unlock()
+	if err := invalidateUserCache(userId); err != nil {
+		common.SysError("failed to invalidate user cache: " + err.Error())
+	}
+}
+
+// SubscriptionMaxPeriods 一次最多订阅的周期数
+const SubscriptionMaxPeriods = 120
+
+// SubscribePlan 为用户订阅 periods 个周期的套餐（0 表示不过期），charge 为 true 时从用户额度中扣除套餐价格。
+// 续订当前套餐时从原到期时间顺延；订阅新套餐时立即发放第一个周期的额度，原套餐尚未开始的周期退还已支付的价格，
+// 当前周期按剩余时间折算，退还对应的价格并扣回对应的已发放额度。返回订阅和实际扣除的额度（负数表示退还）
+func SubscribePlan(userId int, plan setting.SubscriptionPlan, periods int, charge bool) (*UserSubscription, int, error) {
+	if periods < 0 || periods > SubscriptionMaxPeriods {
+		return nil, 0, fmt.Errorf("订阅周期数需在 0 到 %d 之间", SubscriptionMaxPeriods)
+	}
+	sub := &UserSubscription{UserId: userId}
+	cost := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userId).First(sub).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		now := common.GetTimestamp()
+		period := plan.GetPeriodSeconds()
+		price := 0
+		if charge {
+			price = plan.Price
+		}
+		if price < 0 || (price > 0 && periods > math.MaxInt/price) {
+			return errors.New("套餐价格过高")
+		}
+		cost = price * periods
+		grant := 0
+		if sub.IsActive(now) && sub.Plan == plan.Name {
+			if periods == 0 {
+				sub.ExpiredTime = 0
+			} else if sub.ExpiredTime != 0 {
+				sub.ExpiredTime += int64(periods) * period
+			}
+		} else {
+			// 更换套餐，退还原套餐尚未开始的周期；当前周期只退还剩余时间的价格，同时扣回剩余时间的额度，
+			// 避免反复更换套餐重复获得额度
+			if sub.IsActive(now) && sub.Period > 0 {
+				if sub.ExpiredTime > sub.RenewTime && sub.Price > 0 {
+					cost -= int(int64(sub.Price) * ((sub.ExpiredTime - sub.RenewTime) / sub.Period))
+				}
+				periodEnd := sub.RenewTime
+				if sub.ExpiredTime != 0 && sub.ExpiredTime < periodEnd {
+					periodEnd = sub.ExpiredTime
+				}
+				if remaining := periodEnd - now; remaining > 0 {
+					remaining = min(remaining, sub.Period)
+					cost -= int((int64(sub.Price) - int64(sub.Quota)) * remaining / sub.Period)
+				}
+			}
+			sub.Plan = plan.Name
+			sub.StartTime = now
+			sub.RenewTime = now + period
+			sub.Period = period
+			sub.ExpiredTime = 0
+			if periods > 0 {
+				sub.ExpiredTime = now + int64(periods)*period
+			}
+			grant = plan.Quota
+			sub.Quota = plan.Quota
+		}
+		sub.Price = price
+		if cost > 0 {
+			// 管理员分配套餐时不要求额度足够扣回当前周期的剩余额度
+			query := tx.Model(&User{}).Where("id = ?", userId)
+			if charge {
+				query = query.Where("quota >= ?", cost)
+			}
+			result := query.Update("quota", gorm.Expr("quota - ?", cost))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("用户额度不足")
+			}
+		}
+		credit := grant
+		if cost < 0 {
+			credit -= cost
+		}
+		if credit > 0 {
+			err = tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota + ?", credit)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(sub).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	invalidateSubscriptionPlanCache(userId)
+	return sub, cost, nil
+}
+
+// CancelSubscription 取消用户的订阅，已发放的额度和已支付的价格不退还
+func CancelSubscription(userId int) error {
+	result := DB.Model(&UserSubscription{}).Where("user_id = ? and plan <> ''", userId).Update("plan", "")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("用户没有订阅套餐")
+	}
+	invalidateSubscriptionPlanCache(userId)
+	return nil
+}
+
+// GetDueSubscriptions 返回已到续期时间的订阅
+func GetDueSubscriptions(now int64) ([]*UserSubscription, error) {
+	var subs []*UserSubscription
+	err := DB.Where("plan <> '' and renew_time <= ?", now).Find(&subs).Error
+	return subs, err
+}
+
+// RenewSubscription 进入下一个周期并发放额度，quota 为 0 时只推进周期。按原续期时间条件更新，避免多次发放
+func RenewSubscription(sub *UserSubscription, quota int) (bool, error) {
+	renewed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UserSubscription{}).Where("id = ? and renew_time = ?", sub.Id, sub.RenewTime).
+			Updates(map[string]interface{}{"start_time": sub.RenewTime, "renew_time": sub.RenewTime + sub.Period, "quota": quota})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		renewed = true
+		if quota > 0 {
+			return tx.Model(&User{}).Where("id = ?", sub.UserId).Update("quota", gorm.Expr("quota + ?", quota)).Error
+		}
+		return nil
+	})
+	if err != nil || !renewed {
+		return false, err
+	}
+	if err := invalidateUserCache(sub.UserId); err != nil {
+		common.SysError("failed to invalidate user cache: " + err.Error())
+	}
+	return true, nil
+}
+
+// ExpireSubscription 取消已到期的订阅
+func ExpireSubscription(sub *UserSubscription) error {
+	err := DB.Model(&UserSubscription{}).Where("id = ? and renew_time = ?", sub.Id, sub.RenewTime).Update("plan", "").Error
+	if err != nil {
+		return err
+	}
+	invalidateSubscriptionPlanCache(sub.UserId)
+	return nil
+}
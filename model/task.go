@@ -20,6 +20,37 @@ const (
 	TaskStatusUnknown               = "UNKNOWN"
 )
 
+// taskStatusStage 任务状态所处的阶段，状态只能向后流转
+var taskStatusStage = map[TaskStatus]int{
+	TaskStatusNotStart:   0,
+	TaskStatusSubmitted:  1,
+	TaskStatusQueued:     2,
+	TaskStatusInProgress: 3,
+	TaskStatusSuccess:    4,
+	TaskStatusFailure:    4,
+}
+
+// IsFinal 成功和失败为终态，终态的任务不再轮询
+func (s TaskStatus) IsFinal() bool {
+	return s == TaskStatusSuccess || s == TaskStatusFailure
+}
+
+// CanTransitionTo 终态不再变化，未知状态不覆盖已有状态，其余状态不能回退
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	if s.IsFinal() {
+		return false
+	}
+	nextStage, ok := taskStatusStage[next]
+	if !ok {
+		return false
+	}
+	stage, ok := taskStatusStage[s]
+	if !ok {
+		return true
+	}
+	return nextStage >= stage
+}
+
 type Task struct {
 	ID         int64                 `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
 	CreatedAt  int64                 `json:"created_at" gorm:"index"`
@@ -27,6 +58,7 @@ type Task struct {
 	TaskID     string                `json:"task_id" gorm:"type:varchar(50);index"`  // 第三方id，不一定有/ song id\ Task id
 	Platform   constant.TaskPlatform `json:"platform" gorm:"type:varchar(30);index"` // 平台
 	UserId     int                   `json:"user_id" gorm:"index"`
+	TokenId    int                   `json:"token_id" gorm:"index"`
 	ChannelId  int                   `json:"channel_id" gorm:"index"`
 	Quota      int                   `json:"quota"`
 	Action     string                `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
@@ -53,6 +85,24 @@ func (t *Task) GetData(v any) error {
 
 type Properties struct {
 	Input string `json:"input"`
+	// NotifyHook 任务进入终态时通知的地址
+	NotifyHook string `json:"notify_hook,omitempty"`
+	// Billing 按量计费的任务提交时的计费倍率，任务完成后按实际用量结算
+	Billing *TaskBilling `json:"billing,omitempty"`
+}
+
+type TaskBilling struct {
+	ModelName       string  `json:"model_name"`
+	ModelRatio      float64 `json:"model_ratio"`
+	CompletionRatio float64 `json:"completion_ratio"`
+	GroupRatio      float64 `json:"group_ratio"`
+	Discount        float64 `json:"discount"`
+	FreeInput       bool    `json:"free_input,omitempty"`
+
+	// 缓存命中和缓存写入的 token 按缓存倍率结算，旧任务没有记录时按输入价格结算
+	CacheRatio         float64 `json:"cache_ratio,omitempty"`
+	CacheCreationRatio float64 `json:"cache_creation_ratio,omitempty"`
+	Group              string  `json:"group,omitempty"`
 }
 
 func (m *Properties) Scan(val interface{}) error {
@@ -80,6 +130,7 @@ type SyncTaskQueryParams struct {
 func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.TaskRelayInfo) *Task {
 	t := &Task{
 		UserId:     relayInfo.UserId,
+		TokenId:    relayInfo.TokenId,
 		SubmitTime: time.Now().Unix(),
 		Status:     TaskStatusNotStart,
 		Progress:   "0%",
@@ -174,7 +225,19 @@ func GetAllUnFinishSyncTasks(limit int) []*Task {
 	var tasks []*Task
 	var err error
 	// get all tasks progress is not 100%
-	err = DB.Where("progress != ?", "100%").Limit(limit).Order("id").Find(&tasks).Error
+	// 网关执行的批量请求不需要查询上游，由批量执行器处理
+	err = DB.Where("progress != ? and action != ?", "100%", constant.BatchActionGateway).Limit(limit).Order("id").Find(&tasks).Error
+	if err != nil {
+		return nil
+	}
+	return tasks
+}
+
+// GetAllUnFinishGatewayBatches 查询由网关执行的未完成批量请求
+func GetAllUnFinishGatewayBatches() []*Task {
+	var tasks []*Task
+	err := DB.Where("platform = ? and action = ? and progress != ?", constant.TaskPlatformBatch, constant.BatchActionGateway, "100%").
+		Order("id").Find(&tasks).Error
 	if err != nil {
 		return nil
 	}
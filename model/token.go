@@ -11,22 +11,27 @@ import (
 )
 
 type Token struct {
-	Id                 int            `json:"id"`
-	UserId             int            `json:"user_id" gorm:"index"`
-	Key                string         `json:"key" gorm:"type:char(48);uniqueIndex"`
-	Status             int            `json:"status" gorm:"default:1"`
-	Name               string         `json:"name" gorm:"index" `
-	CreatedTime        int64          `json:"created_time" gorm:"bigint"`
-	AccessedTime       int64          `json:"accessed_time" gorm:"bigint"`
-	ExpiredTime        int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
-	RemainQuota        int            `json:"remain_quota" gorm:"default:0"`
-	UnlimitedQuota     bool           `json:"unlimited_quota" gorm:"default:false"`
-	ModelLimitsEnabled bool           `json:"model_limits_enabled" gorm:"default:false"`
-	ModelLimits        string         `json:"model_limits" gorm:"type:varchar(1024);default:''"`
-	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
-	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
-	Group              string         `json:"group" gorm:"default:''"`
-	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	Id                       int            `json:"id"`
+	UserId                   int            `json:"user_id" gorm:"index"`
+	Key                      string         `json:"key" gorm:"type:char(48);uniqueIndex"`
+	Status                   int            `json:"status" gorm:"default:1"`
+	Name                     string         `json:"name" gorm:"index" `
+	CreatedTime              int64          `json:"created_time" gorm:"bigint"`
+	AccessedTime             int64          `json:"accessed_time" gorm:"bigint"`
+	ExpiredTime              int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
+	RemainQuota              int            `json:"remain_quota" gorm:"default:0"`
+	UnlimitedQuota           bool           `json:"unlimited_quota" gorm:"default:false"`
+	ModelLimitsEnabled       bool           `json:"model_limits_enabled" gorm:"default:false"`
+	ModelLimits              string         `json:"model_limits" gorm:"type:varchar(1024);default:''"`
+	AllowIps                 *string        `json:"allow_ips" gorm:"default:''"`
+	UsedQuota                int            `json:"used_quota" gorm:"default:0"` // used quota
+	Group                    string         `json:"group" gorm:"default:''"`
+	RateLimitTier            string         `json:"rate_limit_tier" gorm:"type:varchar(64);default:''"`
+	RateLimitTierExpiredTime int64          `json:"rate_limit_tier_expired_time" gorm:"bigint;default:0"`    // 0 means never expired
+	DailyQuotaLimit          int            `json:"daily_quota_limit" gorm:"default:0"`                      // 0 means no limit
+	MonthlyQuotaLimit        int            `json:"monthly_quota_limit" gorm:"default:0"`                    // 0 means no limit
+	QuotaLimitTimezone       string         `json:"quota_limit_timezone" gorm:"type:varchar(64);default:''"` // empty means server timezone
+	DeletedAt                gorm.DeletedAt `gorm:"index"`
 }
 
 func (token *Token) Clean() {
@@ -87,7 +92,7 @@ func ValidateUserToken(key string) (token *Token, err error) {
 			return token, errors.New("该令牌状态不可用")
 		}
 		if token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp() {
-			if !common.RedisEnabled {
+			if !common.CacheEnabled() {
 				token.Status = common.TokenStatusExpired
 				err := token.SelectUpdate()
 				if err != nil {
@@ -97,7 +102,7 @@ func ValidateUserToken(key string) (token *Token, err error) {
 			return token, errors.New("该令牌已过期")
 		}
 		if !token.UnlimitedQuota && token.RemainQuota <= 0 {
-			if !common.RedisEnabled {
+			if !common.CacheEnabled() {
 				// in this case, we can make sure the token is exhausted
 				token.Status = common.TokenStatusExhausted
 				err := token.SelectUpdate()
@@ -141,6 +146,19 @@ func GetTokenById(id int) (*Token, error) {
 	return &token, err
 }
 
+// GetTokenKeyById 按 id 获取令牌 key，优先从缓存读取
+func GetTokenKeyById(id int) (string, error) {
+	if key, ok := cacheGetTokenKeyById(id); ok {
+		return key, nil
+	}
+	token, err := GetTokenById(id)
+	if err != nil {
+		return "", err
+	}
+	cacheSetTokenKeyById(token.Id, token.Key)
+	return token.Key, nil
+}
+
 func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
 	defer func() {
 		// Update Redis cache asynchronously on successful DB read
@@ -152,7 +170,7 @@ func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
 			})
 		}
 	}()
-	if !fromDB && common.RedisEnabled {
+	if !fromDB && common.CacheEnabled() {
 		// Try Redis first
 		token, err := cacheGetTokenByKey(key)
 		if err == nil {
@@ -184,7 +202,8 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group",
+		"daily_quota_limit", "monthly_quota_limit", "quota_limit_timezone").Updates(token).Error
 	return err
 }
 
@@ -238,6 +257,63 @@ func (token *Token) GetModelLimitsMap() map[string]bool {
 	return limitsMap
 }
 
+// GetActiveRateLimitTier 返回令牌当前生效的限流等级，没有购买或已过期时返回空字符串
+func (token *Token) GetActiveRateLimitTier() string {
+	if token.RateLimitTier == "" {
+		return ""
+	}
+	if token.RateLimitTierExpiredTime != 0 && token.RateLimitTierExpiredTime <= common.GetTimestamp() {
+		return ""
+	}
+	return token.RateLimitTier
+}
+
+// PurchaseTokenRateLimitTier 扣除用户额度并为令牌设置限流等级，续费当前生效的等级时从原到期时间顺延，
+// 更换等级时立即生效，原等级剩余时间不退还
+func PurchaseTokenRateLimitTier(tokenId int, userId int, tier string, quota int, days int) (*Token, error) {
+	token := &Token{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.First(token, "id = ? and user_id = ?", tokenId, userId).Error
+		if err != nil {
+			return err
+		}
+		if quota > 0 {
+			result := tx.Model(&User{}).Where("id = ? and quota >= ?", userId, quota).Update("quota", gorm.Expr("quota - ?", quota))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("用户额度不足")
+			}
+		}
+		expiredTime := int64(0)
+		if days > 0 {
+			start := common.GetTimestamp()
+			if token.GetActiveRateLimitTier() == tier && token.RateLimitTierExpiredTime > start {
+				start = token.RateLimitTierExpiredTime
+			}
+			expiredTime = start + int64(days)*24*60*60
+		}
+		token.RateLimitTier = tier
+		token.RateLimitTierExpiredTime = expiredTime
+		return tx.Model(token).Select("rate_limit_tier", "rate_limit_tier_expired_time").Updates(token).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if common.CacheEnabled() {
+		gopool.Go(func() {
+			if err := cacheDecrUserQuota(userId, int64(quota)); err != nil {
+				common.SysError("failed to decrease user quota cache: " + err.Error())
+			}
+			if err := cacheSetToken(*token); err != nil {
+				common.SysError("failed to update token cache: " + err.Error())
+			}
+		})
+	}
+	return token, nil
+}
+
 func DisableModelLimits(tokenId int) error {
 	token, err := GetTokenById(tokenId)
 	if err != nil {
@@ -265,7 +341,7 @@ func IncreaseTokenQuota(id int, key string, quota int) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
-	if common.RedisEnabled {
+	if common.CacheEnabled() {
 		gopool.Go(func() {
 			err := cacheIncrTokenQuota(key, int64(quota))
 			if err != nil {
@@ -295,7 +371,7 @@ func DecreaseTokenQuota(id int, key string, quota int) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
 	}
-	if common.RedisEnabled {
+	if common.CacheEnabled() {
 		gopool.Go(func() {
 			err := cacheDecrTokenQuota(key, int64(quota))
 			if err != nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"one-api/common"
 	"one-api/constant"
+	"sync"
 	"time"
 )
 
@@ -48,10 +49,39 @@ func cacheSetTokenField(key string, field string, value string) error {
 	return nil
 }
 
+type tokenKeyCacheEntry struct {
+	key      string
+	expireAt time.Time
+}
+
+// tokenKeyCache 按 id 缓存令牌 key，令牌 key 创建后不会改变，令牌状态仍由按 key 的查询校验。
+// 明文 key 只缓存在本进程内存中，不写入 Redis
+var tokenKeyCache sync.Map
+
+func cacheGetTokenKeyById(id int) (string, bool) {
+	value, ok := tokenKeyCache.Load(id)
+	if !ok {
+		return "", false
+	}
+	entry := value.(tokenKeyCacheEntry)
+	if time.Now().After(entry.expireAt) {
+		tokenKeyCache.Delete(id)
+		return "", false
+	}
+	return entry.key, true
+}
+
+func cacheSetTokenKeyById(id int, key string) {
+	tokenKeyCache.Store(id, tokenKeyCacheEntry{
+		key:      key,
+		expireAt: time.Now().Add(time.Duration(constant.TokenCacheSeconds) * time.Second),
+	})
+}
+
 // CacheGetTokenByKey 从缓存中获取 token，如果缓存中不存在，则从数据库中获取
 func cacheGetTokenByKey(key string) (*Token, error) {
 	hmacKey := common.GenerateHMAC(key)
-	if !common.RedisEnabled {
+	if !common.CacheEnabled() {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
 	var token Token
@@ -1,13 +1,29 @@
 package model
 
+import (
+	"errors"
+	"one-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTopUpNotFound 支付回调对应的订单不存在
+var ErrTopUpNotFound = errors.New("订单不存在")
+
 type TopUp struct {
-	Id         int     `json:"id"`
-	UserId     int     `json:"user_id" gorm:"index"`
-	Amount     int64   `json:"amount"`
-	Money      float64 `json:"money"`
-	TradeNo    string  `json:"trade_no"`
-	CreateTime int64   `json:"create_time"`
-	Status     string  `json:"status"`
+	Id            int     `json:"id"`
+	UserId        int     `json:"user_id" gorm:"index"`
+	Amount        int64   `json:"amount"`
+	Money         float64 `json:"money"`
+	TradeNo       string  `json:"trade_no"`
+	CreateTime    int64   `json:"create_time"`
+	Status        string  `json:"status"`
+	PaymentMethod string  `json:"payment_method" gorm:"type:varchar(32);default:''"`
+	PaymentId     string  `json:"payment_id" gorm:"type:varchar(255);index;default:''"` // 支付平台的交易号，用于匹配退款
+	RefundedQuota int     `json:"refunded_quota" gorm:"default:0"`                      // 已因退款扣回的额度
 }
 
 func (topUp *TopUp) Insert() error {
@@ -22,6 +38,13 @@ func (topUp *TopUp) Update() error {
 	return err
 }
 
+// Quota 订单充值的额度
+func (topUp *TopUp) Quota() int {
+	dAmount := decimal.NewFromInt(topUp.Amount)
+	dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
+	return int(dAmount.Mul(dQuotaPerUnit).IntPart())
+}
+
 func GetTopUpById(id int) *TopUp {
 	var topUp *TopUp
 	var err error
@@ -41,3 +64,98 @@ func GetTopUpByTradeNo(tradeNo string) *TopUp {
 	}
 	return topUp
 }
+
+// CompleteTopUp 在同一事务中将指定支付方式的待支付订单标记为成功并为用户增加额度，订单已处理过时返回 nil，
+// 支付平台重复推送回调时不会重复充值
+func CompleteTopUp(tradeNo string, paymentMethod string, paymentId string) (*TopUp, error) {
+	topUp := &TopUp{}
+	completed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("trade_no = ? and payment_method = ?", tradeNo, paymentMethod).First(topUp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTopUpNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if topUp.Status != "pending" {
+			return nil
+		}
+		// 只有把订单从 pending 改为 success 的请求才入账，不支持行锁的数据库上并发回调也只会入账一次
+		result := tx.Model(&TopUp{}).Where("id = ? and status = ?", topUp.Id, "pending").
+			Updates(map[string]interface{}{"status": "success", "payment_id": paymentId})
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		topUp.Status = "success"
+		topUp.PaymentId = paymentId
+		completed = true
+		return tx.Model(&User{}).Where("id = ?", topUp.UserId).Update("quota", gorm.Expr("quota + ?", topUp.Quota())).Error
+	})
+	if err != nil || !completed {
+		return nil, err
+	}
+	gopool.Go(func() {
+		err := cacheIncrUserQuota(topUp.UserId, int64(topUp.Quota()))
+		if err != nil {
+			common.SysError("failed to increase user quota: " + err.Error())
+		}
+	})
+	return topUp, nil
+}
+
+// RefundTopUp 按累计退款比例扣回订单充值的额度，refundedRatio 为累计退款金额占支付金额的比例，
+// 返回本次扣回的额度，已扣回的部分不会重复扣除；全额退款后订单标记为 refunded
+func RefundTopUp(paymentId string, refundedRatio float64) (*TopUp, int, error) {
+	if paymentId == "" {
+		return nil, 0, errors.New("交易号为空")
+	}
+	topUp := &TopUp{}
+	delta := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("payment_id = ?", paymentId).First(topUp).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTopUpNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if topUp.Status != "success" {
+			return nil
+		}
+		refundedRatio = min(max(refundedRatio, 0), 1)
+		target := int(decimal.NewFromInt(int64(topUp.Quota())).Mul(decimal.NewFromFloat(refundedRatio)).IntPart())
+		if refundedRatio == 1 {
+			target = topUp.Quota()
+			topUp.Status = "refunded"
+		}
+		if target <= topUp.RefundedQuota && topUp.Status != "refunded" {
+			return nil
+		}
+		delta = max(target-topUp.RefundedQuota, 0)
+		// 以读取时的已退额度为条件更新，并发处理同一退款时只有一个请求扣回额度，另一个返回错误等待重试
+		result := tx.Model(&TopUp{}).Where("id = ? and status = ? and refunded_quota = ?", topUp.Id, "success", topUp.RefundedQuota).
+			Updates(map[string]interface{}{"status": topUp.Status, "refunded_quota": topUp.RefundedQuota + delta})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errors.New("订单已被并发修改")
+		}
+		topUp.RefundedQuota += delta
+		return tx.Model(&User{}).Where("id = ?", topUp.UserId).Update("quota", gorm.Expr("quota - ?", delta)).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if delta > 0 {
+		gopool.Go(func() {
+			err := cacheDecrUserQuota(topUp.UserId, int64(delta))
+			if err != nil {
+				common.SysError("failed to decrease user quota: " + err.Error())
+			}
+		})
+	}
+	return topUp, delta, nil
+}
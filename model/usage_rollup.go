@@ -0,0 +1,215 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// 消费日志按小时和按天（UTC）预先汇总，统计时读取汇总表，只有游标之后尚未汇总的日志需要直接统计
+
+const (
+	UsageRollupHourlyPeriod = 3600
+	UsageRollupDailyPeriod  = 86400
+)
+
+// usageRollupBatchSize 每次最多汇总的日志条数
+const usageRollupBatchSize = 5000
+
+// usageRollupDelay 只汇总写入超过该秒数的日志，避免并发写入时 id 较小的日志晚于游标提交而被跳过
+const usageRollupDelay = 60
+
+// UsageRollup 某个时间段内一个用户、令牌、渠道和模型组合的用量
+type UsageRollup struct {
+	Id               int    `json:"id"`
+	UserId           int    `json:"user_id" gorm:"uniqueIndex:,composite:rollup_key,priority:1"`
+	BucketStart      int64  `json:"bucket_start" gorm:"bigint;index;uniqueIndex:,composite:rollup_key,priority:2"`
+	TokenId          int    `json:"token_id" gorm:"default:0;uniqueIndex:,composite:rollup_key,priority:3"`
+	ChannelId        int    `json:"channel_id" gorm:"default:0;uniqueIndex:,composite:rollup_key,priority:4"`
+	ModelName        string `json:"model_name" gorm:"type:varchar(255);default:'';uniqueIndex:,composite:rollup_key,priority:5"`
+	Username         string `json:"username" gorm:"size:64;default:'';index"`
+	Requests         int64  `json:"requests" gorm:"default:0"`
+	PromptTokens     int64  `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int64  `json:"completion_tokens" gorm:"default:0"`
+	Quota            int64  `json:"quota" gorm:"default:0"`
+}
+
+type UsageRollupHourly struct {
+	UsageRollup
+}
+
+func (UsageRollupHourly) TableName() string {
+	return "usage_rollup_hourly"
+}
+
+type UsageRollupDaily struct {
+	UsageRollup
+}
+
+func (UsageRollupDaily) TableName() string {
+	return "usage_rollup_daily"
+}
+
+// UsageRollupCursor 已汇总的最后一条日志 id，只有一行
+type UsageRollupCursor struct {
+	Id        int `json:"id"`
+	LastLogId int `json:"last_log_id"`
+}
+
+func usageRollupTable(period int64) string {
+	if period == UsageRollupDailyPeriod {
+		return UsageRollupDaily{}.TableName()
+	}
+	return UsageRollupHourly{}.TableName()
+}
+
+func getUsageRollupLastLogId() (int, error) {
+	var cursor UsageRollupCursor
+	err := DB.Where("id = ?", 1).Limit(1).Find(&cursor).Error
+	return cursor.LastLogId, err
+}
+
+// RollupUsageLogs 汇总游标之后的一批日志并推进游标，返回处理的日志条数
+func RollupUsageLogs() (int, error) {
+	lastLogId, err := getUsageRollupLastLogId()
+	if err != nil {
+		return 0, err
+	}
+	var logIds []int
+	err = LOG_DB.Table("logs").Where("id > ? and created_at <= ?", lastLogId, common.GetTimestamp()-usageRollupDelay).
+		Order("id").Limit(usageRollupBatchSize).Pluck("id", &logIds).Error
+	if err != nil || len(logIds) == 0 {
+		return 0, err
+	}
+	maxLogId := logIds[len(logIds)-1]
+
+	var hourly []*UsageRollup
+	err = LOG_DB.Table("logs").Select(fmt.Sprintf("created_at - created_at %% %d as bucket_start, user_id, max(username) as username, token_id, channel_id, model_name, "+
+		"count(*) as requests, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota", UsageRollupHourlyPeriod)).
+		Where("id > ? and id <= ? and type = ?", lastLogId, maxLogId, LogTypeConsume).
+		Group("bucket_start, user_id, token_id, channel_id, model_name").Scan(&hourly).Error
+	if err != nil {
+		return 0, err
+	}
+	daily := make(map[string]*UsageRollup)
+	for _, rollup := range hourly {
+		bucketStart := rollup.BucketStart - rollup.BucketStart%UsageRollupDailyPeriod
+		key := fmt.Sprintf("%d-%d-%d-%d-%s", bucketStart, rollup.UserId, rollup.TokenId, rollup.ChannelId, rollup.ModelName)
+		if d, ok := daily[key]; ok {
+			d.Requests += rollup.Requests
+			d.PromptTokens += rollup.PromptTokens
+			d.CompletionTokens += rollup.CompletionTokens
+			d.Quota += rollup.Quota
+		} else {
+			d := *rollup
+			d.BucketStart = bucketStart
+			daily[key] = &d
+		}
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, rollup := range hourly {
+			if err := increaseUsageRollup(tx, UsageRollupHourlyPeriod, rollup); err != nil {
+				return err
+			}
+		}
+		for _, rollup := range daily {
+			if err := increaseUsageRollup(tx, UsageRollupDailyPeriod, rollup); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&UsageRollupCursor{Id: 1, LastLogId: maxLogId}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(logIds), nil
+}
+
+func increaseUsageRollup(tx *gorm.DB, period int64, rollup *UsageRollup) error {
+	table := usageRollupTable(period)
+	result := tx.Table(table).Where("user_id = ? and bucket_start = ? and token_id = ? and channel_id = ? and model_name = ?",
+		rollup.UserId, rollup.BucketStart, rollup.TokenId, rollup.ChannelId, rollup.ModelName).Updates(map[string]interface{}{
+		"username":          rollup.Username,
+		"requests":          gorm.Expr("requests + ?", rollup.Requests),
+		"prompt_tokens":     gorm.Expr("prompt_tokens + ?", rollup.PromptTokens),
+		"completion_tokens": gorm.Expr("completion_tokens + ?", rollup.CompletionTokens),
+		"quota":             gorm.Expr("quota + ?", rollup.Quota),
+	})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return tx.Table(table).Create(rollup).Error
+}
+
+type UsageRollupFilter struct {
+	UserId   int
+	TokenId  int
+	Username string
+}
+
+// SumUsageRollup 按时间段和模型统计 [startTimestamp, endTimestamp) 内的消费，起止时间需按 period 对齐，为 0 时不限制
+func SumUsageRollup(period int64, filter UsageRollupFilter, startTimestamp int64, endTimestamp int64) ([]*UsageRollup, error) {
+	lastLogId, err := getUsageRollupLastLogId()
+	if err != nil {
+		return nil, err
+	}
+	tx := DB.Table(usageRollupTable(period)).Select("bucket_start, model_name, sum(requests) as requests, " +
+		"sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota")
+	logTx := LOG_DB.Table("logs").Select(fmt.Sprintf("created_at - created_at %% %d as bucket_start, model_name, count(*) as requests, "+
+		"sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota", period)).
+		Where("id > ? and type = ?", lastLogId, LogTypeConsume)
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+		logTx = logTx.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TokenId != 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+		logTx = logTx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.Username != "" {
+		tx = tx.Where("username = ?", filter.Username)
+		logTx = logTx.Where("username = ?", filter.Username)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("bucket_start >= ?", startTimestamp)
+		logTx = logTx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("bucket_start < ?", endTimestamp)
+		logTx = logTx.Where("created_at < ?", endTimestamp)
+	}
+	var rollups, pending []*UsageRollup
+	if err = tx.Group("bucket_start, model_name").Scan(&rollups).Error; err != nil {
+		return nil, err
+	}
+	if err = logTx.Group("bucket_start, model_name").Scan(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*UsageRollup, len(rollups))
+	for _, rollup := range append(rollups, pending...) {
+		key := fmt.Sprintf("%d-%s", rollup.BucketStart, rollup.ModelName)
+		if m, ok := merged[key]; ok {
+			m.Requests += rollup.Requests
+			m.PromptTokens += rollup.PromptTokens
+			m.CompletionTokens += rollup.CompletionTokens
+			m.Quota += rollup.Quota
+		} else {
+			merged[key] = rollup
+		}
+	}
+	result := make([]*UsageRollup, 0, len(merged))
+	for _, rollup := range merged {
+		result = append(result, rollup)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BucketStart != result[j].BucketStart {
+			return result[i].BucketStart < result[j].BucketStart
+		}
+		return result[i].ModelName < result[j].ModelName
+	})
+	return result, nil
+}
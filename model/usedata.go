@@ -18,6 +18,8 @@ type QuotaData struct {
 	TokenUsed int    `json:"token_used" gorm:"default:0"`
 	Count     int    `json:"count" gorm:"default:0"`
 	Quota     int    `json:"quota" gorm:"default:0"`
+	// Amount 以显示币种计的金额，仅在查询时填充
+	Amount float64 `json:"amount" gorm:"-"`
 }
 
 func UpdateQuotaData() {
@@ -106,28 +108,93 @@ func increaseQuotaData(userId int, username string, modelName string, count int,
 	}
 }
 
+// getQuotaDataFromRollup 从按小时汇总的用量中统计所在小时在 [startTime, endTime] 内的数据，按模型和小时合并
+func getQuotaDataFromRollup(filter UsageRollupFilter, startTime int64, endTime int64) ([]*QuotaData, error) {
+	startTimestamp := startTime - startTime%UsageRollupHourlyPeriod
+	if startTimestamp < startTime {
+		startTimestamp += UsageRollupHourlyPeriod
+	}
+	endTimestamp := endTime - endTime%UsageRollupHourlyPeriod + UsageRollupHourlyPeriod
+	rollups, err := SumUsageRollup(UsageRollupHourlyPeriod, filter, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	quotaDatas := make([]*QuotaData, 0, len(rollups))
+	for _, rollup := range rollups {
+		quotaDatas = append(quotaDatas, &QuotaData{
+			UserID:    filter.UserId,
+			Username:  filter.Username,
+			ModelName: rollup.ModelName,
+			CreatedAt: rollup.BucketStart,
+			TokenUsed: int(rollup.PromptTokens + rollup.CompletionTokens),
+			Count:     int(rollup.Requests),
+			Quota:     int(rollup.Quota),
+		})
+	}
+	return quotaDatas, nil
+}
+
 func GetQuotaDataByUsername(username string, startTime int64, endTime int64) (quotaData []*QuotaData, err error) {
-	var quotaDatas []*QuotaData
-	// 从quota_data表中查询数据
-	err = DB.Table("quota_data").Where("username = ? and created_at >= ? and created_at <= ?", username, startTime, endTime).Find(&quotaDatas).Error
-	return quotaDatas, err
+	return getQuotaDataFromRollup(UsageRollupFilter{Username: username}, startTime, endTime)
 }
 
 func GetQuotaDataByUserId(userId int, startTime int64, endTime int64) (quotaData []*QuotaData, err error) {
-	var quotaDatas []*QuotaData
-	// 从quota_data表中查询数据
-	err = DB.Table("quota_data").Where("user_id = ? and created_at >= ? and created_at <= ?", userId, startTime, endTime).Find(&quotaDatas).Error
-	return quotaDatas, err
+	return getQuotaDataFromRollup(UsageRollupFilter{UserId: userId}, startTime, endTime)
 }
 
 func GetAllQuotaDates(startTime int64, endTime int64, username string) (quotaData []*QuotaData, err error) {
-	if username != "" {
-		return GetQuotaDataByUsername(username, startTime, endTime)
+	return getQuotaDataFromRollup(UsageRollupFilter{Username: username}, startTime, endTime)
+}
+
+type monthlyTokenUsage struct {
+	tokens    int64
+	expiresAt int64
+}
+
+var monthlyTokenUsageCache = make(map[int]monthlyTokenUsage)
+var monthlyTokenUsageCacheLock sync.Mutex
+
+// monthlyTokenUsageCacheSeconds 本月用量的缓存时间，阶梯价格允许短时间的延迟
+const monthlyTokenUsageCacheSeconds = 60
+
+// monthlyTokenUsageCacheSize 本月用量最多缓存的用户数，超过时先清理过期的缓存，仍然超过则全部清空
+const monthlyTokenUsageCacheSize = 10000
+
+// GetUserMonthlyTokenUsed 返回用户本月已使用的 token 数，从消费日志的汇总表和尚未汇总的日志中统计，
+// 不依赖数据看板是否开启
+func GetUserMonthlyTokenUsed(userId int) (int64, error) {
+	now := time.Now()
+	monthlyTokenUsageCacheLock.Lock()
+	usage, ok := monthlyTokenUsageCache[userId]
+	monthlyTokenUsageCacheLock.Unlock()
+	if ok && usage.expiresAt > now.Unix() {
+		return usage.tokens, nil
+	}
+
+	// 汇总表按整点统计，时区不是整小时偏移时月初向前取整到整点
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Unix()
+	monthStart -= monthStart % UsageRollupHourlyPeriod
+	rollups, err := SumUsageRollup(UsageRollupHourlyPeriod, UsageRollupFilter{UserId: userId}, monthStart, 0)
+	if err != nil {
+		return 0, err
+	}
+	var tokens int64
+	for _, rollup := range rollups {
+		tokens += rollup.PromptTokens + rollup.CompletionTokens
+	}
+
+	monthlyTokenUsageCacheLock.Lock()
+	if len(monthlyTokenUsageCache) >= monthlyTokenUsageCacheSize {
+		for id, cached := range monthlyTokenUsageCache {
+			if cached.expiresAt <= now.Unix() {
+				delete(monthlyTokenUsageCache, id)
+			}
+		}
+		if len(monthlyTokenUsageCache) >= monthlyTokenUsageCacheSize {
+			monthlyTokenUsageCache = make(map[int]monthlyTokenUsage)
+		}
 	}
-	var quotaDatas []*QuotaData
-	// 从quota_data表中查询数据
-	// only select model_name, sum(count) as count, sum(quota) as quota, model_name, created_at from quota_data group by model_name, created_at;
-	//err = DB.Table("quota_data").Where("created_at >= ? and created_at <= ?", startTime, endTime).Find(&quotaDatas).Error
-	err = DB.Table("quota_data").Select("model_name, sum(count) as count, sum(quota) as quota, sum(token_used) as token_used, created_at").Where("created_at >= ? and created_at <= ?", startTime, endTime).Group("model_name, created_at").Find(&quotaDatas).Error
-	return quotaDatas, err
+	monthlyTokenUsageCache[userId] = monthlyTokenUsage{tokens: tokens, expiresAt: now.Unix() + monthlyTokenUsageCacheSeconds}
+	monthlyTokenUsageCacheLock.Unlock()
+	return tokens, nil
 }
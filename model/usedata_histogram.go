@@ -0,0 +1,99 @@
+package model
+
+import (
+	"one-api/common"
+	"sort"
+	"sync"
+)
+
+// 按模型统计请求长度与流式耗时的分布，只保存计数，不保存任何请求内容和用户信息
+// 数据保存在内存中，重启后清空，多节点部署时为各节点各自的统计
+
+var tokenHistogramBounds = []float64{128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072}
+var durationHistogramBounds = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600}
+
+// UsageHistogram Counts 比 Bounds 多一个元素，最后一个为超出最大上界的数量
+type UsageHistogram struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []int64   `json:"counts"`
+	Count  int64     `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+func newUsageHistogram(bounds []float64) *UsageHistogram {
+	return &UsageHistogram{
+		Bounds: bounds,
+		Counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *UsageHistogram) observe(value float64) {
+	i := sort.SearchFloat64s(h.Bounds, value)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += value
+}
+
+func (h *UsageHistogram) clone() *UsageHistogram {
+	counts := make([]int64, len(h.Counts))
+	copy(counts, h.Counts)
+	return &UsageHistogram{
+		Bounds: h.Bounds,
+		Counts: counts,
+		Count:  h.Count,
+		Sum:    h.Sum,
+	}
+}
+
+type ModelUsageHistograms struct {
+	ModelName        string          `json:"model_name"`
+	PromptTokens     *UsageHistogram `json:"prompt_tokens"`
+	CompletionTokens *UsageHistogram `json:"completion_tokens"`
+	StreamDuration   *UsageHistogram `json:"stream_duration"`
+}
+
+var usageHistograms = make(map[string]*ModelUsageHistograms)
+var usageHistogramsLock sync.Mutex
+var usageHistogramsSince = common.GetTimestamp()
+
+func LogUsageHistogram(modelName string, promptTokens int, completionTokens int, isStream bool, useTimeSeconds int) {
+	usageHistogramsLock.Lock()
+	defer usageHistogramsLock.Unlock()
+	histograms, ok := usageHistograms[modelName]
+	if !ok {
+		histograms = &ModelUsageHistograms{
+			ModelName:        modelName,
+			PromptTokens:     newUsageHistogram(tokenHistogramBounds),
+			CompletionTokens: newUsageHistogram(tokenHistogramBounds),
+			StreamDuration:   newUsageHistogram(durationHistogramBounds),
+		}
+		usageHistograms[modelName] = histograms
+	}
+	histograms.PromptTokens.observe(float64(promptTokens))
+	histograms.CompletionTokens.observe(float64(completionTokens))
+	if isStream {
+		histograms.StreamDuration.observe(float64(useTimeSeconds))
+	}
+}
+
+// GetUsageHistograms 返回统计开始时间和各模型的分布，modelName 为空时返回全部模型
+func GetUsageHistograms(modelName string) (int64, []*ModelUsageHistograms) {
+	usageHistogramsLock.Lock()
+	defer usageHistogramsLock.Unlock()
+	result := make([]*ModelUsageHistograms, 0, len(usageHistograms))
+	for name, histograms := range usageHistograms {
+		if modelName != "" && name != modelName {
+			continue
+		}
+		result = append(result, &ModelUsageHistograms{
+			ModelName:        name,
+			PromptTokens:     histograms.PromptTokens.clone(),
+			CompletionTokens: histograms.CompletionTokens.clone(),
+			StreamDuration:   histograms.StreamDuration.clone(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ModelName < result[j].ModelName
+	})
+	return usageHistogramsSince, result
+}
@@ -41,17 +41,21 @@ type User struct {
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
 	LinuxDOId        string         `json:"linux_do_id" gorm:"column:linux_do_id;index"`
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
+	BillingMode      string         `json:"billing_mode" gorm:"type:varchar(16);default:'prepaid'"`
+	CreditLimit      int            `json:"credit_limit" gorm:"type:int;default:0"` // 后付费用户允许透支的额度
 }
 
 func (user *User) ToBaseUser() *UserBase {
 	cache := &UserBase{
-		Id:       user.Id,
-		Group:    user.Group,
-		Quota:    user.Quota,
-		Status:   user.Status,
-		Username: user.Username,
-		Setting:  user.Setting,
-		Email:    user.Email,
+		Id:          user.Id,
+		Group:       user.Group,
+		Quota:       user.Quota,
+		Status:      user.Status,
+		Username:    user.Username,
+		Setting:     user.Setting,
+		Email:       user.Email,
+		BillingMode: user.BillingMode,
+		CreditLimit: user.CreditLimit,
 	}
 	return cache
 }
@@ -366,6 +370,8 @@ func (user *User) Edit(updatePassword bool) error {
 		"display_name": newUser.DisplayName,
 		"group":        newUser.Group,
 		"quota":        newUser.Quota,
+		"billing_mode": newUser.BillingMode,
+		"credit_limit": newUser.CreditLimit,
 	}
 	if updatePassword {
 		updates["password"] = newUser.Password
@@ -527,7 +533,7 @@ func IsAdmin(userId int) bool {
 //			})
 //		}
 //	}()
-//	if !fromDB && common.RedisEnabled {
+//	if !fromDB && common.CacheEnabled() {
 //		// Try Redis first
 //		status, err := getUserStatusCache(id)
 //		if err == nil {
@@ -569,7 +575,7 @@ func GetUserQuota(id int, fromDB bool) (quota int, err error) {
 			})
 		}
 	}()
-	if !fromDB && common.RedisEnabled {
+	if !fromDB && common.CacheEnabled() {
 		quota, err := getUserQuotaCache(id)
 		if err == nil {
 			return quota, nil
@@ -585,6 +591,19 @@ func GetUserQuota(id int, fromDB bool) (quota int, err error) {
 	return quota, nil
 }
 
+// GetUserSpendableQuota 返回用户可用于请求的额度，后付费用户在余额之外还可以透支信用额度
+func GetUserSpendableQuota(id int) (int, error) {
+	quota, err := GetUserQuota(id, false)
+	if err != nil {
+		return 0, err
+	}
+	user, err := GetUserCache(id)
+	if err != nil {
+		return 0, err
+	}
+	return quota + user.GetCreditLimit(), nil
+}
+
 func GetUserUsedQuota(id int) (quota int, err error) {
 	err = DB.Model(&User{}).Where("id = ?", id).Select("used_quota").Find(&quota).Error
 	return quota, err
@@ -607,7 +626,7 @@ func GetUserGroup(id int, fromDB bool) (group string, err error) {
 			})
 		}
 	}()
-	if !fromDB && common.RedisEnabled {
+	if !fromDB && common.CacheEnabled() {
 		group, err := getUserGroupCache(id)
 		if err == nil {
 			return group, nil
@@ -636,7 +655,7 @@ func GetUserSetting(id int, fromDB bool) (settingMap map[string]interface{}, err
 			})
 		}
 	}()
-	if !fromDB && common.RedisEnabled {
+	if !fromDB && common.CacheEnabled() {
 		setting, err := getUserSettingCache(id)
 		if err == nil {
 			return setting, nil
@@ -780,7 +799,7 @@ func GetUsernameById(id int, fromDB bool) (username string, err error) {
 			})
 		}
 	}()
-	if !fromDB && common.RedisEnabled {
+	if !fromDB && common.CacheEnabled() {
 		username, err := getUserNameCache(id)
 		if err == nil {
 			return username, nil
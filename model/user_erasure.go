@@ -0,0 +1,219 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	UserErasureStatusPending  = 1
+	UserErasureStatusApproved = 2
+	UserErasureStatusRejected = 3
+)
+
+// UserErasureRequest 用户提交的注销申请，管理员审核通过后删除账户并匿名化使用记录
+type UserErasureRequest struct {
+	Id            int    `json:"id"`
+	UserId        int    `json:"user_id" gorm:"index"`
+	Username      string `json:"username"`
+	Reason        string `json:"reason" gorm:"type:varchar(255)"`
+	Status        int    `json:"status" gorm:"type:int;default:1;index"`
+	CreatedTime   int64  `json:"created_time" gorm:"bigint"`
+	ProcessedTime int64  `json:"processed_time" gorm:"bigint"`
+	ProcessorId   int    `json:"processor_id"`
+	Remark        string `json:"remark" gorm:"type:varchar(255)"`
+}
+
+func CreateUserErasureRequest(userId int, reason string) (*UserErasureRequest, error) {
+	var count int64
+	err := DB.Model(&UserErasureRequest{}).Where("user_id = ? and status = ?", userId, UserErasureStatusPending).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("已有待审核的注销申请")
+	}
+	username, err := GetUsernameById(userId, true)
+	if err != nil {
+		return nil, err
+	}
+	request := &UserErasureRequest{
+		UserId:      userId,
+		Username:    username,
+		Reason:      reason,
+		Status:      UserErasureStatusPending,
+		CreatedTime: common.GetTimestamp(),
+	}
+	err = DB.Create(request).Error
+	return request, err
+}
+
+// GetLatestUserErasureRequest 返回用户最近一次注销申请，没有时返回 nil
+func GetLatestUserErasureRequest(userId int) (*UserErasureRequest, error) {
+	var request UserErasureRequest
+	err := DB.Where("user_id = ?", userId).Order("id desc").First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func CancelUserErasureRequest(userId int) error {
+	result := DB.Where("user_id = ? and status = ?", userId, UserErasureStatusPending).Delete(&UserErasureRequest{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有待审核的注销申请")
+	}
+	return nil
+}
+
+func GetUserErasureRequests(status int, startIdx int, num int) (requests []*UserErasureRequest, total int64, err error) {
+	query := DB.Model(&UserErasureRequest{})
+	if status != 0 {
+		query = query.Where("status = ?", status)
+	}
+	err = query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id desc").Limit(num).Offset(startIdx).Find(&requests).Error
+	return requests, total, err
+}
+
+// ProcessUserErasureRequest 审核注销申请，通过时先删除账户，删除失败时申请保持待审核状态
+func ProcessUserErasureRequest(id int, processorId int, approve bool, remark string) error {
+	var request UserErasureRequest
+	err := DB.First(&request, "id = ?", id).Error
+	if err != nil {
+		return err
+	}
+	if request.Status != UserErasureStatusPending {
+		return errors.New("该申请已处理")
+	}
+	request.Status = UserErasureStatusRejected
+	if approve {
+		err = EraseUser(request.UserId)
+		if err != nil {
+			return err
+		}
+		request.Status = UserErasureStatusApproved
+		request.Username = erasedUsername(request.UserId)
+	}
+	request.ProcessedTime = common.GetTimestamp()
+	request.ProcessorId = processorId
+	request.Remark = remark
+	return DB.Save(&request).Error
+}
+
+func erasedUsername(userId int) string {
+	return fmt.Sprintf("deleted_%d", userId)
+}
+
+// EraseUser 删除账户并匿名化个人数据：清除账户的身份信息和登录方式，删除令牌和上传的文件，
+// 使用日志和统计数据只移除用户名、令牌名称，保留额度、模型和 token 数，不影响账单汇总
+func EraseUser(userId int) error {
+	user, err := GetUserById(userId, true)
+	if err != nil {
+		return err
+	}
+	if user.Role == common.RoleRootUser {
+		return errors.New("不能删除超级管理员账户")
+	}
+	var tokens []*Token
+	err = DB.Where("user_id = ?", userId).Find(&tokens).Error
+	if err != nil {
+		return err
+	}
+	files, err := GetUserFiles(userId, "")
+	if err != nil {
+		return err
+	}
+	anonymous := erasedUsername(userId)
+	// 日志可能在单独的数据库中，无法放在同一个事务里，先匿名化日志，账户删除失败时可以重新审核
+	err = LOG_DB.Model(&Log{}).Where("user_id = ?", userId).Updates(map[string]interface{}{
+		"username":   anonymous,
+		"token_name": "",
+	}).Error
+	if err != nil {
+		return err
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+			"username":     anonymous,
+			"password":     "",
+			"display_name": "",
+			"email":        "",
+			"github_id":    "",
+			"oidc_id":      "",
+			"wechat_id":    "",
+			"telegram_id":  "",
+			"linux_do_id":  "",
+			"access_token": nil,
+			"setting":      "",
+			"status":       common.UserStatusDisabled,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Delete(&User{Id: userId}).Error; err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Where("user_id = ?", userId).Delete(&Token{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", userId).Delete(&File{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", userId).Delete(&AssistantObject{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", userId).Delete(&BudgetAlert{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", userId).Delete(&UserSubscription{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Model(&UsageRollupHourly{}).Where("user_id = ?", userId).Update("username", anonymous).Error; err != nil {
+			return err
+		}
+		if err = tx.Model(&UsageRollupDaily{}).Where("user_id = ?", userId).Update("username", anonymous).Error; err != nil {
+			return err
+		}
+		return tx.Model(&QuotaData{}).Where("user_id = ?", userId).Update("username", anonymous).Error
+	})
+	if err != nil {
+		return err
+	}
+	deleteFileContents(files)
+	if common.CacheEnabled() {
+		for _, token := range tokens {
+			if err := cacheDeleteToken(token.Key); err != nil {
+				common.SysError("failed to delete token cache: " + err.Error())
+			}
+		}
+	}
+	return invalidateUserCache(userId)
+}
+
+// ExportUserLogs 分批读取用户的日志，与用户在日志页面看到的内容一致
+func ExportUserLogs(userId int, fn func(logs []*Log) error) error {
+	var logs []*Log
+	return LOG_DB.Where("user_id = ?", userId).FindInBatches(&logs, 1000, func(tx *gorm.DB, batch int) error {
+		// FindInBatches 根据最后一条记录的 id 读取下一批，不能修改原记录
+		exported := make([]*Log, len(logs))
+		for i, log := range logs {
+			l := *log
+			exported[i] = &l
+		}
+		formatUserLogs(exported)
+		return fn(exported)
+	}).Error
+}
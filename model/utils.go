@@ -90,5 +90,5 @@ func RecordExist(err error) (bool, error) {
 }
 
 func shouldUpdateRedis(fromDB bool, err error) bool {
-	return common.RedisEnabled && fromDB && err == nil
+	return common.CacheEnabled() && fromDB && err == nil
 }
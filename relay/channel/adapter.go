@@ -42,6 +42,6 @@ type TaskAdaptor interface {
 	GetModelList() []string
 	GetChannelName() string
 
-	// FetchTask
-	FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error)
+	// FetchTask 查询上游任务状态，任务的生命周期（状态流转、失败退款、回调通知）由通用任务轮询器处理
+	FetchTask(baseUrl, key string, taskIds []string) ([]dto.TaskResult, error)
 }
@@ -36,7 +36,11 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	case constant.RelayModeCompletions:
 		fullRequestURL = fmt.Sprintf("%s/compatible-mode/v1/completions", info.BaseUrl)
 	default:
-		fullRequestURL = fmt.Sprintf("%s/compatible-mode/v1/chat/completions", info.BaseUrl)
+		if isMultimodalModel(info.UpstreamModelName) {
+			fullRequestURL = fmt.Sprintf("%s/api/v1/services/aigc/multimodal-generation/generation", info.BaseUrl)
+		} else {
+			fullRequestURL = fmt.Sprintf("%s/compatible-mode/v1/chat/completions", info.BaseUrl)
+		}
 	}
 	return fullRequestURL, nil
 }
@@ -65,6 +69,9 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 
 	switch info.RelayMode {
 	default:
+		if isMultimodalModel(info.UpstreamModelName) {
+			return requestOpenAI2AliMultimodal(*request, info.IsStream), nil
+		}
 		aliReq := requestOpenAI2Ali(*request)
 		return aliReq, nil
 	}
@@ -104,7 +111,13 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	case constant.RelayModeEmbeddings:
 		err, usage = aliEmbeddingHandler(c, resp)
 	default:
-		if info.IsStream {
+		if isMultimodalModel(info.UpstreamModelName) {
+			if info.IsStream {
+				err, usage = aliMultimodalStreamHandler(c, resp, info)
+			} else {
+				err, usage = aliMultimodalHandler(c, resp, info)
+			}
+		} else if info.IsStream {
 			err, usage = openai.OaiStreamHandler(c, resp, info)
 		} else {
 			err, usage = openai.OpenaiHandler(c, resp, info)
@@ -7,6 +7,9 @@ var ModelList = []string{
 	"qwen-max-longcontext",
 	"qwq-32b",
 	"qwen3-235b-a22b",
+	"qwen-vl-max",
+	"qwen-vl-plus",
+	"qwen-audio-turbo",
 	"text-embedding-v1",
 }
 
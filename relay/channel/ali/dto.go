@@ -12,11 +12,13 @@ type AliInput struct {
 }
 
 type AliParameters struct {
-	TopP              float64 `json:"top_p,omitempty"`
-	TopK              int     `json:"top_k,omitempty"`
-	Seed              uint64  `json:"seed,omitempty"`
-	EnableSearch      bool    `json:"enable_search,omitempty"`
-	IncrementalOutput bool    `json:"incremental_output,omitempty"`
+	TopP              float64  `json:"top_p,omitempty"`
+	TopK              int      `json:"top_k,omitempty"`
+	Seed              uint64   `json:"seed,omitempty"`
+	Temperature       *float64 `json:"temperature,omitempty"`
+	MaxTokens         uint     `json:"max_tokens,omitempty"`
+	EnableSearch      bool     `json:"enable_search,omitempty"`
+	IncrementalOutput bool     `json:"incremental_output,omitempty"`
 }
 
 type AliChatRequest struct {
@@ -30,9 +32,12 @@ type AliEmbeddingRequest struct {
 	Input struct {
 		Texts []string `json:"texts"`
 	} `json:"input"`
-	Parameters *struct {
-		TextType string `json:"text_type,omitempty"`
-	} `json:"parameters,omitempty"`
+	Parameters *AliEmbeddingParameters `json:"parameters,omitempty"`
+}
+
+type AliEmbeddingParameters struct {
+	TextType  string `json:"text_type,omitempty"`
+	Dimension int    `json:"dimension,omitempty"`
 }
 
 type AliEmbedding struct {
@@ -58,6 +63,9 @@ type AliUsage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
 	TotalTokens  int `json:"total_tokens"`
+	// multimodal models report the media part of input_tokens separately
+	ImageTokens int `json:"image_tokens,omitempty"`
+	AudioTokens int `json:"audio_tokens,omitempty"`
 }
 
 type TaskResult struct {
@@ -97,3 +105,35 @@ type AliImageRequest struct {
 	} `json:"parameters,omitempty"`
 	ResponseFormat string `json:"response_format,omitempty"`
 }
+
+type AliMultimodalContent struct {
+	Text  string `json:"text,omitempty"`
+	Image string `json:"image,omitempty"`
+	Audio string `json:"audio,omitempty"`
+}
+
+type AliMultimodalMessage struct {
+	Role    string                 `json:"role"`
+	Content []AliMultimodalContent `json:"content"`
+}
+
+type AliMultimodalRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Messages []AliMultimodalMessage `json:"messages"`
+	} `json:"input"`
+	Parameters AliParameters `json:"parameters,omitempty"`
+}
+
+type AliMultimodalChoice struct {
+	FinishReason string               `json:"finish_reason"`
+	Message      AliMultimodalMessage `json:"message"`
+}
+
+type AliMultimodalResponse struct {
+	Output struct {
+		Choices []AliMultimodalChoice `json:"choices"`
+	} `json:"output"`
+	Usage AliUsage `json:"usage"`
+	AliError
+}
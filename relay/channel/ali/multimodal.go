@@ -0,0 +1,193 @@
+package ali
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// https://help.aliyun.com/zh/model-studio/developer-reference/qwen-vl-api
+// https://help.aliyun.com/zh/model-studio/developer-reference/qwen-audio-api
+
+func isMultimodalModel(model string) bool {
+	return strings.HasPrefix(model, "qwen-vl-") || strings.HasPrefix(model, "qwen-audio-")
+}
+
+func requestOpenAI2AliMultimodal(request dto.GeneralOpenAIRequest, stream bool) *AliMultimodalRequest {
+	var aliRequest AliMultimodalRequest
+	aliRequest.Model = request.Model
+	aliRequest.Parameters = AliParameters{
+		TopP:              request.TopP,
+		TopK:              int(request.TopK),
+		Seed:              uint64(request.Seed),
+		Temperature:       request.Temperature,
+		MaxTokens:         request.MaxTokens,
+		IncrementalOutput: stream,
+	}
+	if request.MaxCompletionTokens != 0 {
+		aliRequest.Parameters.MaxTokens = request.MaxCompletionTokens
+	}
+	for _, message := range request.Messages {
+		aliMessage := AliMultimodalMessage{
+			Role: message.Role,
+		}
+		for _, content := range message.ParseContent() {
+			switch content.Type {
+			case dto.ContentTypeText:
+				aliMessage.Content = append(aliMessage.Content, AliMultimodalContent{Text: content.Text})
+			case dto.ContentTypeImageURL:
+				aliMessage.Content = append(aliMessage.Content, AliMultimodalContent{Image: content.GetImageMedia().Url})
+			case dto.ContentTypeInputAudio:
+				audio := content.GetInputAudio()
+				audioUrl := audio.Data
+				if !strings.HasPrefix(audioUrl, "http") && !strings.HasPrefix(audioUrl, "data:") {
+					audioUrl = fmt.Sprintf("data:audio/%s;base64,%s", audio.Format, audio.Data)
+				}
+				aliMessage.Content = append(aliMessage.Content, AliMultimodalContent{Audio: audioUrl})
+			}
+		}
+		aliRequest.Input.Messages = append(aliRequest.Input.Messages, aliMessage)
+	}
+	return &aliRequest
+}
+
+func usageAliMultimodal2OpenAI(aliUsage AliUsage) dto.Usage {
+	usage := dto.Usage{
+		PromptTokens:     aliUsage.InputTokens,
+		CompletionTokens: aliUsage.OutputTokens,
+		TotalTokens:      aliUsage.InputTokens + aliUsage.OutputTokens,
+	}
+	usage.PromptTokensDetails.ImageTokens = aliUsage.ImageTokens
+	usage.PromptTokensDetails.AudioTokens = aliUsage.AudioTokens
+	usage.PromptTokensDetails.TextTokens = aliUsage.InputTokens - aliUsage.ImageTokens - aliUsage.AudioTokens
+	return usage
+}
+
+func multimodalContentText(message AliMultimodalMessage) string {
+	var texts []string
+	for _, content := range message.Content {
+		if content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return strings.Join(texts, "")
+}
+
+func aliMultimodalStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
+	var usage dto.Usage
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var aliResponse AliMultimodalResponse
+		err := common.DecodeJsonStr(data, &aliResponse)
+		if err != nil {
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			return true
+		}
+		if aliResponse.Code != "" {
+			common.LogError(c, "ali stream error: "+aliResponse.Message)
+			return false
+		}
+		if aliResponse.Usage.InputTokens != 0 || aliResponse.Usage.OutputTokens != 0 {
+			usage = usageAliMultimodal2OpenAI(aliResponse.Usage)
+		}
+		response := dto.ChatCompletionsStreamResponse{
+			Id:      id,
+			Object:  "chat.completion.chunk",
+			Created: createAt,
+			Model:   info.UpstreamModelName,
+		}
+		for i, aliChoice := range aliResponse.Output.Choices {
+			var choice dto.ChatCompletionsStreamResponseChoice
+			choice.Index = i
+			choice.Delta.SetContentString(multimodalContentText(aliChoice.Message))
+			if aliChoice.FinishReason != "" && aliChoice.FinishReason != "null" {
+				finishReason := helper.DefaultFinishReasons.Normalize(aliChoice.FinishReason)
+				choice.FinishReason = &finishReason
+			}
+			response.Choices = append(response.Choices, choice)
+		}
+		err = helper.ObjectData(c, response)
+		if err != nil {
+			common.LogError(c, err.Error())
+		}
+		return true
+	})
+
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, usage)
+		err := helper.ObjectData(c, response)
+		if err != nil {
+			common.SysError("send final response failed: " + err.Error())
+		}
+	}
+	helper.Done(c)
+	return nil, &usage
+}
+
+func aliMultimodalHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	var aliResponse AliMultimodalResponse
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = json.Unmarshal(responseBody, &aliResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if aliResponse.Code != "" {
+		return &dto.OpenAIErrorWithStatusCode{
+			Error: dto.OpenAIError{
+				Message: aliResponse.Message,
+				Type:    aliResponse.Code,
+				Param:   aliResponse.RequestId,
+				Code:    aliResponse.Code,
+			},
+			StatusCode: resp.StatusCode,
+		}, nil
+	}
+	fullTextResponse := dto.OpenAITextResponse{
+		Id:      aliResponse.RequestId,
+		Model:   info.UpstreamModelName,
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Usage:   usageAliMultimodal2OpenAI(aliResponse.Usage),
+	}
+	for i, aliChoice := range aliResponse.Output.Choices {
+		choice := dto.OpenAITextResponseChoice{
+			Index: i,
+			Message: dto.Message{
+				Role: "assistant",
+			},
+			FinishReason: helper.DefaultFinishReasons.Normalize(aliChoice.FinishReason),
+		}
+		if choice.FinishReason == "" || aliChoice.FinishReason == "null" {
+			choice.FinishReason = constant.FinishReasonStop
+		}
+		choice.Message.SetStringContent(multimodalContentText(aliChoice.Message))
+		fullTextResponse.Choices = append(fullTextResponse.Choices, choice)
+	}
+	jsonResponse, err := json.Marshal(fullTextResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, err = c.Writer.Write(jsonResponse)
+	return nil, &fullTextResponse.Usage
+}
@@ -30,7 +30,7 @@ func embeddingRequestOpenAI2Ali(request dto.EmbeddingRequest) *AliEmbeddingReque
 	if request.Model == "" {
 		request.Model = "text-embedding-v1"
 	}
-	return &AliEmbeddingRequest{
+	aliRequest := &AliEmbeddingRequest{
 		Model: request.Model,
 		Input: struct {
 			Texts []string `json:"texts"`
@@ -38,6 +38,10 @@ func embeddingRequestOpenAI2Ali(request dto.EmbeddingRequest) *AliEmbeddingReque
 			Texts: request.ParseInput(),
 		},
 	}
+	if request.Dimensions > 0 {
+		aliRequest.Parameters = &AliEmbeddingParameters{Dimension: request.Dimensions}
+	}
+	return aliRequest
 }
 
 func aliEmbeddingHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
@@ -101,7 +105,7 @@ func responseAli2OpenAI(response *AliResponse) *dto.OpenAITextResponse {
 			Role:    "assistant",
 			Content: content,
 		},
-		FinishReason: response.Output.FinishReason,
+		FinishReason: helper.DefaultFinishReasons.Normalize(response.Output.FinishReason),
 	}
 	fullTextResponse := dto.OpenAITextResponse{
 		Id:      response.RequestId,
@@ -120,8 +124,8 @@ func responseAli2OpenAI(response *AliResponse) *dto.OpenAITextResponse {
 func streamResponseAli2OpenAI(aliResponse *AliResponse) *dto.ChatCompletionsStreamResponse {
 	var choice dto.ChatCompletionsStreamResponseChoice
 	choice.Delta.SetContentString(aliResponse.Output.Text)
-	if aliResponse.Output.FinishReason != "null" {
-		finishReason := aliResponse.Output.FinishReason
+	if aliResponse.Output.FinishReason != "" && aliResponse.Output.FinishReason != "null" {
+		finishReason := helper.DefaultFinishReasons.Normalize(aliResponse.Output.FinishReason)
 		choice.FinishReason = &finishReason
 	}
 	response := dto.ChatCompletionsStreamResponse{
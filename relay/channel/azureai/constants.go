@@ -0,0 +1,17 @@
+package azureai
+
+// serverless 部署的模型名称由用户在 Azure AI Foundry 中选择，这里只列出 models.inference.ai.azure.com 上常用的模型
+var ModelList = []string{
+	"Meta-Llama-3.1-8B-Instruct", "Meta-Llama-3.1-70B-Instruct", "Meta-Llama-3.1-405B-Instruct",
+	"Llama-3.3-70B-Instruct",
+	"Mistral-large-2411", "Mistral-small", "Ministral-3B", "Codestral-2501",
+	"Phi-4", "Phi-3.5-mini-instruct", "Phi-3.5-MoE-instruct",
+	"DeepSeek-R1", "DeepSeek-V3",
+	"Cohere-command-r-plus-08-2024", "Cohere-command-r-08-2024",
+	"Cohere-embed-v3-english", "Cohere-embed-v3-multilingual",
+}
+
+var ChannelName = "azure_ai"
+
+// DefaultApiVersion 渠道未填写 API 版本时使用
+const DefaultApiVersion = "2024-05-01-preview"
@@ -0,0 +1,15 @@
+package baichuan
+
+// https://platform.baichuan-ai.com/docs/api
+
+var ModelList = []string{
+	"Baichuan4",
+	"Baichuan4-Turbo",
+	"Baichuan4-Air",
+	"Baichuan3-Turbo",
+	"Baichuan3-Turbo-128k",
+	"Baichuan2-Turbo",
+	"Baichuan-Text-Embedding",
+}
+
+var ChannelName = "baichuan"
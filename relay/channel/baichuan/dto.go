@@ -0,0 +1,40 @@
+package baichuan
+
+import (
+	"encoding/json"
+	"one-api/dto"
+)
+
+type BaichuanKnowledgeBase struct {
+	Ids []string `json:"ids"`
+}
+
+type BaichuanWebSearch struct {
+	Enable     bool   `json:"enable"`
+	SearchMode string `json:"search_mode,omitempty"`
+}
+
+// BaichuanChatRequest 百川的 tools 除了 function 外还支持 retrieval 和 web_search，
+// 这些工具无法用 dto.ToolCallRequest 表示，因此直接透传原始请求中的 tools
+type BaichuanChatRequest struct {
+	*dto.GeneralOpenAIRequest
+	Tools         []json.RawMessage      `json:"tools,omitempty"`
+	KnowledgeBase *BaichuanKnowledgeBase `json:"knowledge_base,omitempty"`
+}
+
+func (r BaichuanChatRequest) MarshalJSON() ([]byte, error) {
+	return dto.MarshalWithOverlay(r.GeneralOpenAIRequest, baichuanExtraRequest{
+		Tools:         r.Tools,
+		KnowledgeBase: r.KnowledgeBase,
+	})
+}
+
+type baichuanExtraRequest struct {
+	Tools         []json.RawMessage      `json:"tools,omitempty"`
+	KnowledgeBase *BaichuanKnowledgeBase `json:"knowledge_base,omitempty"`
+}
+
+type baichuanTool struct {
+	Type      string             `json:"type"`
+	WebSearch *BaichuanWebSearch `json:"web_search,omitempty"`
+}
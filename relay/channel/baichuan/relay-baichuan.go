@@ -0,0 +1,49 @@
+package baichuan
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func requestOpenAI2Baichuan(c *gin.Context, request *dto.GeneralOpenAIRequest) (*BaichuanChatRequest, error) {
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, err
+	}
+	var extra baichuanExtraRequest
+	err = json.Unmarshal(body, &extra)
+	if err != nil {
+		return nil, err
+	}
+	baichuanRequest := &BaichuanChatRequest{
+		GeneralOpenAIRequest: request,
+		Tools:                extra.Tools,
+		KnowledgeBase:        extra.KnowledgeBase,
+	}
+	// 兼容 OpenAI 的 web_search_options，转换为百川的 web_search 工具
+	if request.WebSearchOptions != nil && !hasTool(extra.Tools, "web_search") {
+		tool, _ := json.Marshal(baichuanTool{
+			Type: "web_search",
+			WebSearch: &BaichuanWebSearch{
+				Enable:     true,
+				SearchMode: "performance_first",
+			},
+		})
+		baichuanRequest.Tools = append(baichuanRequest.Tools, tool)
+	}
+	request.WebSearchOptions = nil
+	return baichuanRequest, nil
+}
+
+func hasTool(tools []json.RawMessage, toolType string) bool {
+	for _, raw := range tools {
+		var tool baichuanTool
+		if json.Unmarshal(raw, &tool) == nil && tool.Type == toolType {
+			return true
+		}
+	}
+	return false
+}
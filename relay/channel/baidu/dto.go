@@ -6,21 +6,46 @@ import (
 )
 
 type BaiduMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role         string             `json:"role"`
+	Content      string             `json:"content"`
+	Name         string             `json:"name,omitempty"`
+	FunctionCall *BaiduFunctionCall `json:"function_call,omitempty"`
+}
+
+type BaiduFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type BaiduFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Thoughts  string `json:"thoughts,omitempty"`
+}
+
+type BaiduToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+type BaiduToolChoice struct {
+	Type     string                  `json:"type"`
+	Function BaiduToolChoiceFunction `json:"function"`
 }
 
 type BaiduChatRequest struct {
-	Messages        []BaiduMessage `json:"messages"`
-	Temperature     *float64       `json:"temperature,omitempty"`
-	TopP            float64        `json:"top_p,omitempty"`
-	PenaltyScore    float64        `json:"penalty_score,omitempty"`
-	Stream          bool           `json:"stream,omitempty"`
-	System          string         `json:"system,omitempty"`
-	DisableSearch   bool           `json:"disable_search,omitempty"`
-	EnableCitation  bool           `json:"enable_citation,omitempty"`
-	MaxOutputTokens *int           `json:"max_output_tokens,omitempty"`
-	UserId          string         `json:"user_id,omitempty"`
+	Messages        []BaiduMessage   `json:"messages"`
+	Temperature     *float64         `json:"temperature,omitempty"`
+	TopP            float64          `json:"top_p,omitempty"`
+	PenaltyScore    float64          `json:"penalty_score,omitempty"`
+	Stream          bool             `json:"stream,omitempty"`
+	System          string           `json:"system,omitempty"`
+	DisableSearch   bool             `json:"disable_search,omitempty"`
+	EnableCitation  bool             `json:"enable_citation,omitempty"`
+	MaxOutputTokens *int             `json:"max_output_tokens,omitempty"`
+	UserId          string           `json:"user_id,omitempty"`
+	Functions       []BaiduFunction  `json:"functions,omitempty"`
+	ToolChoice      *BaiduToolChoice `json:"tool_choice,omitempty"`
 }
 
 type Error struct {
@@ -29,13 +54,14 @@ type Error struct {
 }
 
 type BaiduChatResponse struct {
-	Id               string    `json:"id"`
-	Object           string    `json:"object"`
-	Created          int64     `json:"created"`
-	Result           string    `json:"result"`
-	IsTruncated      bool      `json:"is_truncated"`
-	NeedClearHistory bool      `json:"need_clear_history"`
-	Usage            dto.Usage `json:"usage"`
+	Id               string             `json:"id"`
+	Object           string             `json:"object"`
+	Created          int64              `json:"created"`
+	Result           string             `json:"result"`
+	IsTruncated      bool               `json:"is_truncated"`
+	NeedClearHistory bool               `json:"need_clear_history"`
+	FunctionCall     *BaiduFunctionCall `json:"function_call,omitempty"`
+	Usage            dto.Usage          `json:"usage"`
 	Error
 }
 
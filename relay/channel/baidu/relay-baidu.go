@@ -11,6 +11,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
 	"strings"
@@ -39,19 +40,96 @@ func requestOpenAI2Baidu(request dto.GeneralOpenAIRequest) *BaiduChatRequest {
 		}
 		baiduRequest.MaxOutputTokens = &maxTokens
 	}
+	// ERNIE only accepts a single system field, fold every system message into it
+	var systemContents []string
+	toolCallNames := make(map[string]string)
 	for _, message := range request.Messages {
-		if message.Role == "system" {
-			baiduRequest.System = message.StringContent()
-		} else {
+		switch message.Role {
+		case "system", "developer":
+			systemContents = append(systemContents, message.StringContent())
+		case "assistant":
+			baiduMessage := BaiduMessage{
+				Role:    message.Role,
+				Content: message.StringContent(),
+			}
+			// ERNIE supports one function call per assistant turn
+			toolCalls := message.ParseToolCalls()
+			for _, toolCall := range toolCalls {
+				toolCallNames[toolCall.ID] = toolCall.Function.Name
+			}
+			if len(toolCalls) > 0 {
+				baiduMessage.FunctionCall = &BaiduFunctionCall{
+					Name:      toolCalls[0].Function.Name,
+					Arguments: toolCalls[0].Function.Arguments,
+				}
+			}
+			baiduRequest.Messages = append(baiduRequest.Messages, baiduMessage)
+		case "tool", "function":
+			name := toolCallNames[message.ToolCallId]
+			if name == "" && message.Name != nil {
+				name = *message.Name
+			}
+			baiduRequest.Messages = append(baiduRequest.Messages, BaiduMessage{
+				Role:    "function",
+				Name:    name,
+				Content: message.StringContent(),
+			})
+		default:
 			baiduRequest.Messages = append(baiduRequest.Messages, BaiduMessage{
 				Role:    message.Role,
 				Content: message.StringContent(),
 			})
 		}
 	}
+	baiduRequest.System = strings.Join(systemContents, "\n")
+	for _, tool := range request.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		baiduRequest.Functions = append(baiduRequest.Functions, BaiduFunction{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+	baiduRequest.ToolChoice = toolChoiceOpenAI2Baidu(request.ToolChoice)
 	return &baiduRequest
 }
 
+// toolChoiceOpenAI2Baidu only maps a named function choice, ERNIE has no
+// equivalent for "none", "auto" or "required"
+func toolChoiceOpenAI2Baidu(toolChoice any) *BaiduToolChoice {
+	choice, ok := toolChoice.(map[string]any)
+	if !ok {
+		return nil
+	}
+	function, ok := choice["function"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	name, ok := function["name"].(string)
+	if !ok || name == "" {
+		return nil
+	}
+	return &BaiduToolChoice{
+		Type: "function",
+		Function: BaiduToolChoiceFunction{
+			Name: name,
+		},
+	}
+}
+
+func functionCallBaidu2OpenAI(functionCall *BaiduFunctionCall) dto.ToolCallResponse {
+	return dto.ToolCallResponse{
+		ID:   fmt.Sprintf("call_%s", common.GetUUID()),
+		Type: "function",
+		Function: dto.FunctionResponse{
+			Name:      functionCall.Name,
+			Arguments: functionCall.Arguments,
+		},
+	}
+}
+
 func responseBaidu2OpenAI(response *BaiduChatResponse) *dto.OpenAITextResponse {
 	content, _ := json.Marshal(response.Result)
 	choice := dto.OpenAITextResponseChoice{
@@ -62,6 +140,10 @@ func responseBaidu2OpenAI(response *BaiduChatResponse) *dto.OpenAITextResponse {
 		},
 		FinishReason: "stop",
 	}
+	if response.FunctionCall != nil {
+		choice.Message.SetToolCalls([]dto.ToolCallResponse{functionCallBaidu2OpenAI(response.FunctionCall)})
+		choice.FinishReason = constant.FinishReasonToolCalls
+	}
 	fullTextResponse := dto.OpenAITextResponse{
 		Id:      response.Id,
 		Object:  "chat.completion",
@@ -75,8 +157,17 @@ func responseBaidu2OpenAI(response *BaiduChatResponse) *dto.OpenAITextResponse {
 func streamResponseBaidu2OpenAI(baiduResponse *BaiduChatStreamResponse) *dto.ChatCompletionsStreamResponse {
 	var choice dto.ChatCompletionsStreamResponseChoice
 	choice.Delta.SetContentString(baiduResponse.Result)
+	if baiduResponse.FunctionCall != nil {
+		toolCall := functionCallBaidu2OpenAI(baiduResponse.FunctionCall)
+		toolCall.SetIndex(0)
+		choice.Delta.ToolCalls = []dto.ToolCallResponse{toolCall}
+	}
 	if baiduResponse.IsEnd {
-		choice.FinishReason = &constant.FinishReasonStop
+		if baiduResponse.FunctionCall != nil {
+			choice.FinishReason = &constant.FinishReasonToolCalls
+		} else {
+			choice.FinishReason = &constant.FinishReasonStop
+		}
 	}
 	response := dto.ChatCompletionsStreamResponse{
 		Id:      baiduResponse.Id,
@@ -111,8 +202,11 @@ func embeddingResponseBaidu2OpenAI(response *BaiduEmbeddingResponse) *dto.OpenAI
 	return &openAIEmbeddingResponse
 }
 
-func baiduStreamHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+func baiduStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
 	var usage dto.Usage
+	var responseText strings.Builder
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		if atEOF && len(data) == 0 {
@@ -155,6 +249,7 @@ func baiduStreamHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWi
 				usage.CompletionTokens = baiduResponse.Usage.TotalTokens - baiduResponse.Usage.PromptTokens
 			}
 			response := streamResponseBaidu2OpenAI(&baiduResponse)
+			responseText.WriteString(baiduResponse.Result)
 			jsonResponse, err := json.Marshal(response)
 			if err != nil {
 				common.SysError("error marshalling stream response: " + err.Error())
@@ -163,6 +258,18 @@ func baiduStreamHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWi
 			c.Render(-1, common.CustomEvent{Data: "data: " + string(jsonResponse)})
 			return true
 		case <-stopChan:
+			// 上游未返回 usage 时按输出文本估算
+			if usage.TotalTokens == 0 {
+				textUsage, _ := service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+				usage = *textUsage
+			}
+			if info.ShouldIncludeUsage {
+				response := helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, usage)
+				err := helper.ObjectData(c, response)
+				if err != nil {
+					common.SysError("send final response failed: " + err.Error())
+				}
+			}
 			c.Render(-1, common.CustomEvent{Data: "data: [DONE]"})
 			return false
 		}
@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
+	"one-api/relay/channel/openai"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
@@ -16,19 +18,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// claudeFinishReasons end_turn、max_tokens、tool_use、refusal 等由默认映射处理
+var claudeFinishReasons = helper.NewFinishReasonTable(map[string]string{
+	"pause_turn":                    constant.FinishReasonStop,
+	"model_context_window_exceeded": constant.FinishReasonLength,
+})
+
+// stopReasonClaude2OpenAI 上游未返回结束原因时返回空字符串，未知的结束原因按 stop 处理
 func stopReasonClaude2OpenAI(reason string) string {
-	switch reason {
-	case "stop_sequence":
-		return "stop"
-	case "end_turn":
-		return "stop"
-	case "max_tokens":
-		return "max_tokens"
-	case "tool_use":
-		return "tool_calls"
-	default:
-		return reason
-	}
+	return claudeFinishReasons.Normalize(reason)
 }
 
 func RequestOpenAI2ClaudeComplete(textRequest dto.GeneralOpenAIRequest) *dto.ClaudeRequest {
@@ -39,7 +37,7 @@ func RequestOpenAI2ClaudeComplete(textRequest dto.GeneralOpenAIRequest) *dto.Cla
 		StopSequences: nil,
 		Temperature:   textRequest.Temperature,
 		TopP:          textRequest.TopP,
-		TopK:          textRequest.TopK,
+		TopK:          int(textRequest.TopK),
 		Stream:        textRequest.Stream,
 	}
 	if claudeRequest.MaxTokensToSample == 0 {
@@ -62,14 +60,30 @@ func RequestOpenAI2ClaudeComplete(textRequest dto.GeneralOpenAIRequest) *dto.Cla
 	return &claudeRequest
 }
 
+// StructuredOutputToolName 通过强制调用工具模拟 response_format 时使用的工具名称，
+// 响应中该工具的参数转换为文本内容
+const StructuredOutputToolName = "json_response"
+
 func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.ClaudeRequest, error) {
+	textRequest.NormalizeTools()
+	// 结构化输出：没有其他工具且未开启思考时通过强制调用工具实现（思考模式不支持指定工具），否则写入系统提示
+	structuredOutputTool := false
+	if service.IsStructuredOutputRequest(&textRequest) {
+		thinking := model_setting.GetClaudeSettings().ThinkingAdapterEnabled && strings.HasSuffix(textRequest.Model, "-thinking")
+		if len(textRequest.Tools) == 0 && !thinking {
+			structuredOutputTool = true
+		} else {
+			service.AppendSystemInstruction(&textRequest, service.StructuredOutputInstruction(&textRequest))
+		}
+	}
 	claudeTools := make([]dto.Tool, 0, len(textRequest.Tools))
 
 	for _, tool := range textRequest.Tools {
 		if params, ok := tool.Function.Parameters.(map[string]any); ok {
 			claudeTool := dto.Tool{
-				Name:        tool.Function.Name,
-				Description: tool.Function.Description,
+				Name:         tool.Function.Name,
+				Description:  tool.Function.Description,
+				CacheControl: tool.CacheControl,
 			}
 			claudeTool.InputSchema = make(map[string]interface{})
 			if params["type"] != nil {
@@ -93,7 +107,7 @@ func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.Cla
 		StopSequences: nil,
 		Temperature:   textRequest.Temperature,
 		TopP:          textRequest.TopP,
-		TopK:          textRequest.TopK,
+		TopK:          int(textRequest.TopK),
 		Stream:        textRequest.Stream,
 		Tools:         claudeTools,
 	}
@@ -122,18 +136,12 @@ func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.Cla
 		claudeRequest.Model = strings.TrimSuffix(textRequest.Model, "-thinking")
 	}
 
-	if textRequest.Stop != nil {
-		// stop maybe string/array string, convert to array string
-		switch textRequest.Stop.(type) {
-		case string:
-			claudeRequest.StopSequences = []string{textRequest.Stop.(string)}
-		case []interface{}:
-			stopSequences := make([]string, 0)
-			for _, stop := range textRequest.Stop.([]interface{}) {
-				stopSequences = append(stopSequences, stop.(string))
-			}
-			claudeRequest.StopSequences = stopSequences
-		}
+	if len(claudeTools) > 0 {
+		claudeRequest.ToolChoice = claudeToolChoice(&textRequest, claudeRequest.Thinking != nil)
+	}
+
+	if len(textRequest.Stop) > 0 {
+		claudeRequest.StopSequences = textRequest.Stop
 	}
 	formatMessages := make([]dto.Message, 0)
 	lastMessage := dto.Message{
@@ -178,12 +186,28 @@ func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.Cla
 			} else {
 				contents := message.ParseContent()
 				content := ""
+				cacheControl := false
 				for _, ctx := range contents {
 					if ctx.Type == "text" {
 						content += ctx.Text
+						cacheControl = cacheControl || ctx.CacheControl != nil
 					}
 				}
 				claudeRequest.System = content
+				// 带有 cache_control 时保留文本块，以便转发缓存标记
+				if cacheControl {
+					systemBlocks := make([]dto.ClaudeMediaMessage, 0, len(contents))
+					for _, ctx := range contents {
+						if ctx.Type == "text" && ctx.Text != "" {
+							systemBlocks = append(systemBlocks, dto.ClaudeMediaMessage{
+								Type:         "text",
+								Text:         common.GetPointer[string](ctx.Text),
+								CacheControl: ctx.CacheControl,
+							})
+						}
+					}
+					claudeRequest.System = systemBlocks
+				}
 			}
 		} else {
 			if isFirstMessage {
@@ -238,33 +262,35 @@ func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.Cla
 			} else {
 				claudeMediaMessages := make([]dto.ClaudeMediaMessage, 0)
 				for _, mediaMessage := range message.ParseContent() {
+					// Claude 不接受空的文本块，调用工具的 assistant 消息 content 通常为空
+					if mediaMessage.Type == "text" && mediaMessage.Text == "" {
+						continue
+					}
 					claudeMediaMessage := dto.ClaudeMediaMessage{
-						Type: mediaMessage.Type,
+						Type:         mediaMessage.Type,
+						CacheControl: mediaMessage.CacheControl,
 					}
 					if mediaMessage.Type == "text" {
 						claudeMediaMessage.Text = common.GetPointer[string](mediaMessage.Text)
 					} else {
 						imageUrl := mediaMessage.GetImageMedia()
 						claudeMediaMessage.Type = "image"
-						claudeMediaMessage.Source = &dto.ClaudeMessageSource{
-							Type: "base64",
-						}
-						// 判断是否是url
-						if strings.HasPrefix(imageUrl.Url, "http") {
-							// 是url，获取图片的类型和base64编码的数据
-							fileData, err := service.GetFileBase64FromUrl(imageUrl.Url)
-							if err != nil {
-								return nil, fmt.Errorf("get file base64 from url failed: %s", err.Error())
+						// 远程图片一般已由网关下载为 data URL，开启了 image_url_passthrough 时直接传递地址
+						if imageUrl.IsRemoteImage() {
+							claudeMediaMessage.Source = &dto.ClaudeMessageSource{
+								Type: "url",
+								Url:  imageUrl.Url,
 							}
-							claudeMediaMessage.Source.MediaType = fileData.MimeType
-							claudeMediaMessage.Source.Data = fileData.Base64Data
 						} else {
-							_, format, base64String, err := service.DecodeBase64ImageData(imageUrl.Url)
+							fileData, err := service.GetImageData(imageUrl.Url, service.ClaudeImageLimit)
 							if err != nil {
 								return nil, err
 							}
-							claudeMediaMessage.Source.MediaType = "image/" + format
-							claudeMediaMessage.Source.Data = base64String
+							claudeMediaMessage.Source = &dto.ClaudeMessageSource{
+								Type:      "base64",
+								MediaType: fileData.MimeType,
+								Data:      fileData.Base64Data,
+							}
 						}
 					}
 					claudeMediaMessages = append(claudeMediaMessages, claudeMediaMessage)
@@ -291,9 +317,49 @@ func RequestOpenAI2ClaudeMessage(textRequest dto.GeneralOpenAIRequest) (*dto.Cla
 	}
 	claudeRequest.Prompt = ""
 	claudeRequest.Messages = claudeMessages
+	if structuredOutputTool {
+		inputSchema, ok := service.StructuredOutputSchema(&textRequest).(map[string]any)
+		if !ok {
+			inputSchema = map[string]any{"type": "object"}
+		}
+		claudeRequest.Tools = []dto.Tool{{
+			Name:        StructuredOutputToolName,
+			Description: "Respond with a JSON object as the input of this tool.",
+			InputSchema: inputSchema,
+		}}
+		claudeRequest.ToolChoice = map[string]any{"type": "tool", "name": StructuredOutputToolName}
+	}
 	return &claudeRequest, nil
 }
 
+// claudeToolChoice 转换 tool_choice 和 parallel_tool_calls，思考模式下 Claude 只支持 auto 和 none
+func claudeToolChoice(textRequest *dto.GeneralOpenAIRequest, thinking bool) any {
+	disableParallel := textRequest.ParallelTooCalls != nil && !*textRequest.ParallelTooCalls
+	choice := textRequest.GetToolChoice()
+	if choice == nil && !disableParallel {
+		return nil
+	}
+	toolChoice := map[string]any{"type": "auto"}
+	if choice != nil {
+		switch choice.Mode {
+		case dto.ToolChoiceModeNone:
+			return map[string]any{"type": "none"}
+		case dto.ToolChoiceModeRequired:
+			if !thinking {
+				toolChoice["type"] = "any"
+			}
+		case dto.ToolChoiceModeFunction:
+			if !thinking {
+				toolChoice = map[string]any{"type": "tool", "name": choice.Name}
+			}
+		}
+	}
+	if disableParallel {
+		toolChoice["disable_parallel_tool_use"] = true
+	}
+	return toolChoice
+}
+
 func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto.ChatCompletionsStreamResponse {
 	var response dto.ChatCompletionsStreamResponse
 	response.Object = "chat.completion.chunk"
@@ -311,8 +377,9 @@ func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse
 	if reqMode == RequestModeCompletion {
 		choice.Delta.SetContentString(claudeResponse.Completion)
 		finishReason := stopReasonClaude2OpenAI(claudeResponse.StopReason)
-		if finishReason != "null" {
+		if finishReason != "" {
 			choice.FinishReason = &finishReason
+			choice.NativeFinishReason = common.GetPointer(claudeResponse.StopReason)
 		}
 	} else {
 		if claudeResponse.Type == "message_start" {
@@ -340,7 +407,9 @@ func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse
 			}
 		} else if claudeResponse.Type == "content_block_delta" {
 			if claudeResponse.Delta != nil {
-				choice.Delta.Content = claudeResponse.Delta.Text
+				if claudeResponse.Delta.Text != nil {
+					choice.Delta.SetContentString(*claudeResponse.Delta.Text)
+				}
 				switch claudeResponse.Delta.Type {
 				case "input_json_delta":
 					tools = append(tools, dto.ToolCallResponse{
@@ -360,9 +429,12 @@ func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse
 				}
 			}
 		} else if claudeResponse.Type == "message_delta" {
-			finishReason := stopReasonClaude2OpenAI(*claudeResponse.Delta.StopReason)
-			if finishReason != "null" {
-				choice.FinishReason = &finishReason
+			if claudeResponse.Delta != nil && claudeResponse.Delta.StopReason != nil {
+				finishReason := stopReasonClaude2OpenAI(*claudeResponse.Delta.StopReason)
+				if finishReason != "" {
+					choice.FinishReason = &finishReason
+					choice.NativeFinishReason = claudeResponse.Delta.StopReason
+				}
 			}
 			//claudeUsage = &claudeResponse.Usage
 		} else if claudeResponse.Type == "message_stop" {
@@ -395,6 +467,7 @@ func ResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto
 	}
 	tools := make([]dto.ToolCallResponse, 0)
 	thinkingContent := ""
+	structuredOutput := false
 
 	if reqMode == RequestModeCompletion {
 		content, _ := json.Marshal(strings.TrimPrefix(claudeResponse.Completion, " "))
@@ -405,7 +478,8 @@ func ResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto
 				Content: content,
 				Name:    nil,
 			},
-			FinishReason: stopReasonClaude2OpenAI(claudeResponse.StopReason),
+			FinishReason:       stopReasonClaude2OpenAI(claudeResponse.StopReason),
+			NativeFinishReason: claudeResponse.StopReason,
 		}
 		choices = append(choices, choice)
 	} else {
@@ -414,6 +488,11 @@ func ResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto
 			switch message.Type {
 			case "tool_use":
 				args, _ := json.Marshal(message.Input)
+				if message.Name == StructuredOutputToolName {
+					responseText = string(args)
+					structuredOutput = true
+					continue
+				}
 				tools = append(tools, dto.ToolCallResponse{
 					ID:   message.Id,
 					Type: "function", // compatible with other OpenAI derivative applications
@@ -435,7 +514,11 @@ func ResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto
 		Message: dto.Message{
 			Role: "assistant",
 		},
-		FinishReason: stopReasonClaude2OpenAI(claudeResponse.StopReason),
+		FinishReason:       stopReasonClaude2OpenAI(claudeResponse.StopReason),
+		NativeFinishReason: claudeResponse.StopReason,
+	}
+	if structuredOutput && choice.FinishReason == constant.FinishReasonToolCalls {
+		choice.FinishReason = constant.FinishReasonStop
 	}
 	choice.SetStringContent(responseText)
 	if len(responseThinking) > 0 {
@@ -457,6 +540,69 @@ type ClaudeResponseInfo struct {
 	Model        string
 	ResponseText strings.Builder
 	Usage        *dto.Usage
+	// StructuredOutput 响应中调用了模拟结构化输出的工具
+	StructuredOutput bool
+	// ToolCallIndexes 内容块序号到 tool_calls 序号的映射，按 tool_use 块出现的顺序编号
+	ToolCallIndexes map[int]int
+	// outputRules 转换为 OpenAI 格式的流式响应按输出规则处理，第一个数据块时加载
+	outputRules       *openai.OutputRuleStream
+	outputRulesLoaded bool
+}
+
+// setOpenAIPromptUsage 按 OpenAI 的口径设置输入 token：prompt_tokens 包含缓存读取和缓存写入的 token，
+// 两者同时单独返回，计费时分别使用缓存倍率和缓存创建倍率
+func setOpenAIPromptUsage(usage *dto.Usage, claudeUsage *dto.ClaudeUsage) {
+	if claudeUsage == nil {
+		return
+	}
+	usage.PromptTokens = claudeUsage.InputTokens + claudeUsage.CacheReadInputTokens + claudeUsage.CacheCreationInputTokens
+	usage.PromptTokensDetails.CachedTokens = claudeUsage.CacheReadInputTokens
+	usage.PromptTokensDetails.CachedCreationTokens = claudeUsage.CacheCreationInputTokens
+	usage.CacheReadInputTokens = claudeUsage.CacheReadInputTokens
+	usage.CacheCreationInputTokens = claudeUsage.CacheCreationInputTokens
+}
+
+// setToolCallIndex 把工具调用增量的 index 设置为对应 tool_use 块的编号，
+// 并行调用多个工具或 tool_use 前有思考块时客户端才能正确合并增量
+func setToolCallIndex(claudeResponse *dto.ClaudeResponse, response *dto.ChatCompletionsStreamResponse, claudeInfo *ClaudeResponseInfo) {
+	if claudeResponse.Index == nil || response == nil || len(response.Choices) == 0 || len(response.Choices[0].Delta.ToolCalls) == 0 {
+		return
+	}
+	if claudeInfo.ToolCallIndexes == nil {
+		claudeInfo.ToolCallIndexes = make(map[int]int)
+	}
+	index, ok := claudeInfo.ToolCallIndexes[*claudeResponse.Index]
+	if !ok {
+		index = len(claudeInfo.ToolCallIndexes)
+		claudeInfo.ToolCallIndexes[*claudeResponse.Index] = index
+	}
+	for i := range response.Choices[0].Delta.ToolCalls {
+		response.Choices[0].Delta.ToolCalls[i].SetIndex(index)
+	}
+}
+
+// convertStructuredOutputChunk 把模拟结构化输出的工具调用转换为文本内容
+func convertStructuredOutputChunk(claudeResponse *dto.ClaudeResponse, response *dto.ChatCompletionsStreamResponse, claudeInfo *ClaudeResponseInfo) {
+	if response == nil || len(response.Choices) == 0 {
+		return
+	}
+	if claudeResponse.Type == "content_block_start" && claudeResponse.ContentBlock != nil &&
+		claudeResponse.ContentBlock.Type == "tool_use" && claudeResponse.ContentBlock.Name == StructuredOutputToolName {
+		claudeInfo.StructuredOutput = true
+	}
+	if !claudeInfo.StructuredOutput {
+		return
+	}
+	choice := &response.Choices[0]
+	if len(choice.Delta.ToolCalls) > 0 {
+		arguments := choice.Delta.ToolCalls[0].Function.Arguments
+		claudeInfo.ResponseText.WriteString(arguments)
+		choice.Delta.SetContentString(arguments)
+		choice.Delta.ToolCalls = nil
+	}
+	if choice.FinishReason != nil && *choice.FinishReason == constant.FinishReasonToolCalls {
+		choice.FinishReason = common.GetPointer(constant.FinishReasonStop)
+	}
 }
 
 func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeResponse, oaiResponse *dto.ChatCompletionsStreamResponse, claudeInfo *ClaudeResponseInfo) bool {
@@ -467,7 +613,7 @@ func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeRespons
 			// message_start, 获取usage
 			claudeInfo.ResponseId = claudeResponse.Message.Id
 			claudeInfo.Model = claudeResponse.Message.Model
-			claudeInfo.Usage.PromptTokens = claudeResponse.Message.Usage.InputTokens
+			setOpenAIPromptUsage(claudeInfo.Usage, claudeResponse.Message.Usage)
 		} else if claudeResponse.Type == "content_block_delta" {
 			if claudeResponse.Delta.Text != nil {
 				claudeInfo.ResponseText.WriteString(*claudeResponse.Delta.Text)
@@ -475,7 +621,7 @@ func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeRespons
 		} else if claudeResponse.Type == "message_delta" {
 			claudeInfo.Usage.CompletionTokens = claudeResponse.Usage.OutputTokens
 			if claudeResponse.Usage.InputTokens > 0 {
-				claudeInfo.Usage.PromptTokens = claudeResponse.Usage.InputTokens
+				setOpenAIPromptUsage(claudeInfo.Usage, claudeResponse.Usage)
 			}
 			claudeInfo.Usage.TotalTokens = claudeInfo.Usage.PromptTokens + claudeResponse.Usage.OutputTokens
 		} else if claudeResponse.Type == "content_block_start" {
@@ -537,8 +683,14 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		if !FormatClaudeResponseInfo(requestMode, &claudeResponse, response, claudeInfo) {
 			return nil
 		}
+		setToolCallIndex(&claudeResponse, response, claudeInfo)
+		convertStructuredOutputChunk(&claudeResponse, response, claudeInfo)
 
-		err = helper.ObjectData(c, response)
+		if !claudeInfo.outputRulesLoaded {
+			claudeInfo.outputRules = openai.NewOutputRuleStream(info)
+			claudeInfo.outputRulesLoaded = true
+		}
+		err = claudeInfo.outputRules.ObjectData(c, response)
 		if err != nil {
 			common.LogError(c, "send_stream_response_failed: "+err.Error())
 		}
@@ -570,6 +722,7 @@ func HandleStreamFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, clau
 				claudeInfo.Usage, _ = service.ResponseText2Usage(claudeInfo.ResponseText.String(), info.UpstreamModelName, claudeInfo.Usage.PromptTokens)
 			}
 		}
+		claudeInfo.outputRules.Flush(c)
 		if info.ShouldIncludeUsage {
 			response := helper.GenerateFinalUsageResponse(claudeInfo.ResponseId, claudeInfo.Created, info.UpstreamModelName, *claudeInfo.Usage)
 			err := helper.ObjectData(c, response)
@@ -632,15 +785,19 @@ func HandleClaudeResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 	} else {
 		claudeInfo.Usage.PromptTokens = claudeResponse.Usage.InputTokens
 		claudeInfo.Usage.CompletionTokens = claudeResponse.Usage.OutputTokens
-		claudeInfo.Usage.TotalTokens = claudeResponse.Usage.InputTokens + claudeResponse.Usage.OutputTokens
 		claudeInfo.Usage.PromptTokensDetails.CachedTokens = claudeResponse.Usage.CacheReadInputTokens
 		claudeInfo.Usage.PromptTokensDetails.CachedCreationTokens = claudeResponse.Usage.CacheCreationInputTokens
+		if info.RelayFormat == relaycommon.RelayFormatOpenAI {
+			setOpenAIPromptUsage(claudeInfo.Usage, claudeResponse.Usage)
+		}
+		claudeInfo.Usage.TotalTokens = claudeInfo.Usage.PromptTokens + claudeInfo.Usage.CompletionTokens
 	}
 	var responseData []byte
 	switch info.RelayFormat {
 	case relaycommon.RelayFormatOpenAI:
 		openaiResponse := ResponseClaude2OpenAI(requestMode, &claudeResponse)
 		openaiResponse.Usage = *claudeInfo.Usage
+		openai.ApplyOutputRules(info, openaiResponse)
 		responseData, err = json.Marshal(openaiResponse)
 		if err != nil {
 			return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError)
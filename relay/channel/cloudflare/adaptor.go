@@ -1,7 +1,6 @@
 package cloudflare
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +10,7 @@ import (
 	relaycommon "one-api/relay/common"
 	"one-api/relay/constant"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 )
 
@@ -78,15 +78,14 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 	if err != nil {
 		return nil, errors.New("file is required")
 	}
-	defer file.Close()
-	// 打开临时文件用于保存上传的文件内容
-	requestBody := &bytes.Buffer{}
-
-	// 将上传的文件内容复制到临时文件
-	if _, err := io.Copy(requestBody, file); err != nil {
-		return nil, err
-	}
-	return requestBody, nil
+	// 上传的文件直接作为请求体，边读边发送
+	pr, pw := io.Pipe()
+	gopool.Go(func() {
+		defer file.Close()
+		_, err := io.Copy(pw, file)
+		pw.CloseWithError(err)
+	})
+	return pr, nil
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
@@ -9,6 +9,7 @@ type CohereRequest struct {
 	Stream      bool          `json:"stream"`
 	MaxTokens   int           `json:"max_tokens"`
 	SafetyMode  string        `json:"safety_mode,omitempty"`
+	Seed        int64         `json:"seed,omitempty"`
 }
 
 type ChatHistory struct {
@@ -52,6 +53,7 @@ type CohereMeta struct {
 type CohereBilledUnits struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
+	SearchUnits  int `json:"search_units"`
 }
 
 type CohereTokens struct {
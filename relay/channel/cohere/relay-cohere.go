@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
@@ -23,6 +24,7 @@ func requestOpenAI2Cohere(textRequest dto.GeneralOpenAIRequest) *CohereRequest {
 		Message:     "",
 		Stream:      textRequest.Stream,
 		MaxTokens:   textRequest.GetMaxTokens(),
+		Seed:        int64(textRequest.Seed),
 	}
 	if common.CohereSafetySetting != "NONE" {
 		cohereReq.SafetyMode = common.CohereSafetySetting
@@ -66,15 +68,14 @@ func requestConvertRerank2Cohere(rerankRequest dto.RerankRequest) *CohereRerankR
 	return &cohereReq
 }
 
+// cohereFinishReasons COMPLETE、MAX_TOKENS 由默认映射处理，ERROR、USER_CANCEL 等按 stop 处理
+var cohereFinishReasons = helper.NewFinishReasonTable(map[string]string{
+	"ERROR_TOXIC": constant.FinishReasonContentFilter,
+	"ERROR_LIMIT": constant.FinishReasonLength,
+})
+
 func stopReasonCohere2OpenAI(reason string) string {
-	switch reason {
-	case "COMPLETE":
-		return "stop"
-	case "MAX_TOKENS":
-		return "max_tokens"
-	default:
-		return reason
-	}
+	return cohereFinishReasons.Normalize(reason)
 }
 
 func cohereStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
@@ -143,7 +144,7 @@ func cohereStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 					{
 						Delta: dto.ChatCompletionsStreamResponseChoiceDelta{
 							Role:    "assistant",
-							Content: &cohereResp.Text,
+							Content: dto.NewFlexibleContent(cohereResp.Text),
 						},
 						Index: 0,
 					},
@@ -158,13 +159,20 @@ func cohereStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 			c.Render(-1, common.CustomEvent{Data: "data: " + string(jsonStr)})
 			return true
 		case <-stopChan:
+			if usage.PromptTokens == 0 {
+				usage, _ = service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+			}
+			if info.ShouldIncludeUsage {
+				response := helper.GenerateFinalUsageResponse(responseId, createdTime, info.UpstreamModelName, *usage)
+				err := helper.ObjectData(c, response)
+				if err != nil {
+					common.SysError("send final response failed: " + err.Error())
+				}
+			}
 			c.Render(-1, common.CustomEvent{Data: "data: [DONE]"})
 			return false
 		}
 	})
-	if usage.PromptTokens == 0 {
-		usage, _ = service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
-	}
 	return nil, usage
 }
 
@@ -238,6 +246,7 @@ func cohereRerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 		usage.CompletionTokens = cohereResp.Meta.BilledUnits.OutputTokens
 		usage.TotalTokens = cohereResp.Meta.BilledUnits.InputTokens + cohereResp.Meta.BilledUnits.OutputTokens
 	}
+	info.RerankerInfo.SearchUnits = cohereResp.Meta.BilledUnits.SearchUnits
 
 	var rerankResp dto.RerankResponse
 	rerankResp.Results = cohereResp.Results
@@ -140,13 +140,19 @@ func cozeChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycommo
 	if err := scanner.Err(); err != nil {
 		return service.OpenAIErrorWrapper(err, "stream_scanner_error", http.StatusInternalServerError), nil
 	}
-	helper.Done(c)
-
+	// 上游未返回 usage 时按输出文本估算
 	if usage.TotalTokens == 0 {
-		usage.PromptTokens = info.PromptTokens
-		usage.CompletionTokens, _ = service.CountTextToken("gpt-3.5-turbo", responseText)
-		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		textUsage, _ := service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+		usage = *textUsage
+	}
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, common.GetTimestamp(), info.UpstreamModelName, usage)
+		err := helper.ObjectData(c, response)
+		if err != nil {
+			common.SysError("send final response failed: " + err.Error())
+		}
 	}
+	helper.Done(c)
 
 	return nil, &usage
 }
@@ -26,6 +26,9 @@ var ModelList = []string{
 	"embedding-001",
 }
 
+// geminiMaxStopSequences Gemini 最多支持 5 个停止序列
+const geminiMaxStopSequences = 5
+
 var SafetySettingList = []string{
 	"HARM_CATEGORY_HARASSMENT",
 	"HARM_CATEGORY_HATE_SPEECH",
@@ -35,3 +38,11 @@ var SafetySettingList = []string{
 }
 
 var ChannelName = "google gemini"
+
+// reasoningEffortBudget reasoning_effort 对应的 thinkingBudget，与 Gemini 的 OpenAI 兼容接口一致
+var reasoningEffortBudget = map[string]int{
+	"none":   0,
+	"low":    1024,
+	"medium": 8192,
+	"high":   24576,
+}
@@ -5,6 +5,7 @@ type GeminiChatRequest struct {
 	SafetySettings     []GeminiChatSafetySettings `json:"safetySettings,omitempty"`
 	GenerationConfig   GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
 	Tools              []GeminiChatTool           `json:"tools,omitempty"`
+	ToolConfig         *GeminiToolConfig          `json:"toolConfig,omitempty"`
 	SystemInstructions *GeminiChatContent         `json:"systemInstruction,omitempty"`
 }
 
@@ -80,6 +81,15 @@ type GeminiChatTool struct {
 	FunctionDeclarations  any `json:"functionDeclarations,omitempty"`
 }
 
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
 type GeminiChatGenerationConfig struct {
 	Temperature        *float64              `json:"temperature,omitempty"`
 	TopP               float64               `json:"topP,omitempty"`
@@ -92,13 +102,31 @@ type GeminiChatGenerationConfig struct {
 	Seed               int64                 `json:"seed,omitempty"`
 	ResponseModalities []string              `json:"responseModalities,omitempty"`
 	ThinkingConfig     *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
+	ResponseLogprobs   bool                  `json:"responseLogprobs,omitempty"`
+	Logprobs           *int                  `json:"logprobs,omitempty"`
 }
 
 type GeminiChatCandidate struct {
-	Content       GeminiChatContent        `json:"content"`
-	FinishReason  *string                  `json:"finishReason"`
-	Index         int64                    `json:"index"`
-	SafetyRatings []GeminiChatSafetyRating `json:"safetyRatings"`
+	Content        GeminiChatContent        `json:"content"`
+	FinishReason   *string                  `json:"finishReason"`
+	Index          int64                    `json:"index"`
+	SafetyRatings  []GeminiChatSafetyRating `json:"safetyRatings"`
+	LogprobsResult *GeminiLogprobsResult    `json:"logprobsResult,omitempty"`
+}
+
+type GeminiLogprobsResult struct {
+	TopCandidates    []GeminiTopCandidates     `json:"topCandidates"`
+	ChosenCandidates []GeminiLogprobsCandidate `json:"chosenCandidates"`
+}
+
+type GeminiTopCandidates struct {
+	Candidates []GeminiLogprobsCandidate `json:"candidates"`
+}
+
+type GeminiLogprobsCandidate struct {
+	Token          string  `json:"token"`
+	TokenId        int     `json:"tokenId"`
+	LogProbability float64 `json:"logProbability"`
 }
 
 type GeminiChatSafetyRating struct {
@@ -121,6 +149,8 @@ type GeminiUsageMetadata struct {
 	CandidatesTokenCount int `json:"candidatesTokenCount"`
 	TotalTokenCount      int `json:"totalTokenCount"`
 	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	// CachedContentTokenCount 命中上下文缓存的 token，包含在 PromptTokenCount 中
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
 }
 
 // Imagen related structs
@@ -8,6 +8,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	"one-api/relay/channel/openai"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
@@ -20,14 +21,20 @@ import (
 
 // Setting safety to the lowest possible values since Gemini is already powerless enough
 func CovertGemini2OpenAI(textRequest dto.GeneralOpenAIRequest, info *relaycommon.RelayInfo) (*GeminiChatRequest, error) {
+	textRequest.NormalizeTools()
+	// Gemini 没有 parallel_tool_calls，通过系统提示模拟
+	service.EmulateParallelToolCalls(&textRequest)
 
 	geminiRequest := GeminiChatRequest{
 		Contents: make([]GeminiChatContent, 0, len(textRequest.Messages)),
 		GenerationConfig: GeminiChatGenerationConfig{
 			Temperature:     textRequest.Temperature,
 			TopP:            textRequest.TopP,
+			TopK:            float64(textRequest.TopK),
 			MaxOutputTokens: textRequest.MaxTokens,
 			Seed:            int64(textRequest.Seed),
+			CandidateCount:  textRequest.N,
+			StopSequences:   textRequest.Stop.Limit(geminiMaxStopSequences),
 		},
 	}
 
@@ -61,6 +68,10 @@ func CovertGemini2OpenAI(textRequest dto.GeneralOpenAIRequest, info *relaycommon
 			}
 		}
 	}
+	// 请求中指定的 thinking 或 reasoning_effort 优先于模型名后缀
+	if thinkingConfig := thinkingConfigOpenAI2Gemini(&textRequest); thinkingConfig != nil {
+		geminiRequest.GenerationConfig.ThinkingConfig = thinkingConfig
+	}
 
 	safetySettings := make([]GeminiChatSafetySettings, 0, len(SafetySettingList))
 	for _, category := range SafetySettingList {
@@ -115,16 +126,18 @@ func CovertGemini2OpenAI(textRequest dto.GeneralOpenAIRequest, info *relaycommon
 			geminiRequest.Tools = append(geminiRequest.Tools, GeminiChatTool{
 				FunctionDeclarations: functions,
 			})
+			geminiRequest.ToolConfig = geminiToolConfig(textRequest.GetToolChoice())
 		}
 		// common.SysLog("tools: " + fmt.Sprintf("%+v", geminiRequest.Tools))
 		// json_data, _ := json.Marshal(geminiRequest.Tools)
 		// common.SysLog("tools_json: " + string(json_data))
-	} else if textRequest.Functions != nil {
-		//geminiRequest.Tools = []GeminiChatTool{
-		//	{
-		//		FunctionDeclarations: textRequest.Functions,
-		//	},
-		//}
+	}
+
+	if textRequest.LogProbs {
+		geminiRequest.GenerationConfig.ResponseLogprobs = true
+		if textRequest.TopLogProbs > 0 {
+			geminiRequest.GenerationConfig.Logprobs = common.GetPointer(textRequest.TopLogProbs)
+		}
 	}
 
 	if textRequest.ResponseFormat != nil && (textRequest.ResponseFormat.Type == "json_schema" || textRequest.ResponseFormat.Type == "json_object") {
@@ -213,31 +226,17 @@ func CovertGemini2OpenAI(textRequest dto.GeneralOpenAIRequest, info *relaycommon
 				if constant.GeminiVisionMaxImageNum != -1 && imageNum > constant.GeminiVisionMaxImageNum {
 					return nil, fmt.Errorf("too many images in the message, max allowed is %d", constant.GeminiVisionMaxImageNum)
 				}
-				// 判断是否是url
-				if strings.HasPrefix(part.GetImageMedia().Url, "http") {
-					// 是url，获取图片的类型和base64编码的数据
-					fileData, err := service.GetFileBase64FromUrl(part.GetImageMedia().Url)
-					if err != nil {
-						return nil, fmt.Errorf("get file base64 from url failed: %s", err.Error())
-					}
-					parts = append(parts, GeminiPart{
-						InlineData: &GeminiInlineData{
-							MimeType: fileData.MimeType,
-							Data:     fileData.Base64Data,
-						},
-					})
-				} else {
-					format, base64String, err := service.DecodeBase64FileData(part.GetImageMedia().Url)
-					if err != nil {
-						return nil, fmt.Errorf("decode base64 image data failed: %s", err.Error())
-					}
-					parts = append(parts, GeminiPart{
-						InlineData: &GeminiInlineData{
-							MimeType: format,
-							Data:     base64String,
-						},
-					})
+				// Gemini 只接受内联数据，远程地址由网关下载
+				fileData, err := service.GetImageData(part.GetImageMedia().Url, service.GeminiImageLimit)
+				if err != nil {
+					return nil, fmt.Errorf("get image data failed: %s", err.Error())
 				}
+				parts = append(parts, GeminiPart{
+					InlineData: &GeminiInlineData{
+						MimeType: fileData.MimeType,
+						Data:     fileData.Base64Data,
+					},
+				})
 			} else if part.Type == dto.ContentTypeFile {
 				if part.GetFile().FileId != "" {
 					return nil, fmt.Errorf("only base64 file is supported in gemini")
@@ -291,6 +290,26 @@ func CovertGemini2OpenAI(textRequest dto.GeneralOpenAIRequest, info *relaycommon
 	return &geminiRequest, nil
 }
 
+// geminiToolConfig 把 tool_choice 转换为 functionCallingConfig，未指定时使用上游默认的 AUTO
+func geminiToolConfig(choice *dto.ToolChoice) *GeminiToolConfig {
+	if choice == nil {
+		return nil
+	}
+	config := &GeminiFunctionCallingConfig{}
+	switch choice.Mode {
+	case dto.ToolChoiceModeNone:
+		config.Mode = "NONE"
+	case dto.ToolChoiceModeRequired:
+		config.Mode = "ANY"
+	case dto.ToolChoiceModeFunction:
+		config.Mode = "ANY"
+		config.AllowedFunctionNames = []string{choice.Name}
+	default:
+		config.Mode = "AUTO"
+	}
+	return &GeminiToolConfig{FunctionCallingConfig: config}
+}
+
 // cleanFunctionParameters recursively removes unsupported fields from Gemini function parameters.
 func cleanFunctionParameters(params interface{}) interface{} {
 	if params == nil {
@@ -519,6 +538,47 @@ func getResponseToolCall(item *GeminiPart) *dto.ToolCallResponse {
 	}
 }
 
+// geminiFinishReasons 安全相关的结束原因统一映射为 content_filter，STOP、MAX_TOKENS、SAFETY 由默认映射处理，
+// MALFORMED_FUNCTION_CALL、OTHER、FINISH_REASON_UNSPECIFIED 等按 stop 处理
+var geminiFinishReasons = helper.NewFinishReasonTable(map[string]string{
+	"RECITATION":         constant.FinishReasonContentFilter,
+	"LANGUAGE":           constant.FinishReasonContentFilter,
+	"BLOCKLIST":          constant.FinishReasonContentFilter,
+	"PROHIBITED_CONTENT": constant.FinishReasonContentFilter,
+	"SPII":               constant.FinishReasonContentFilter,
+	"IMAGE_SAFETY":       constant.FinishReasonContentFilter,
+})
+
+func finishReasonGemini2OpenAI(reason string) string {
+	return geminiFinishReasons.Normalize(reason)
+}
+
+// logprobsGemini2OpenAI 转换 logprobsResult，topCandidates 与 chosenCandidates 按位置对应
+func logprobsGemini2OpenAI(result *GeminiLogprobsResult) *dto.ChatLogProbs {
+	logprobs := &dto.ChatLogProbs{
+		Content: make([]dto.ChatLogProb, 0, len(result.ChosenCandidates)),
+	}
+	for i, chosen := range result.ChosenCandidates {
+		logprob := dto.ChatLogProb{
+			Token:       chosen.Token,
+			Logprob:     chosen.LogProbability,
+			Bytes:       dto.TokenBytes(chosen.Token),
+			TopLogprobs: make([]dto.ChatTopLogProb, 0),
+		}
+		if i < len(result.TopCandidates) {
+			for _, candidate := range result.TopCandidates[i].Candidates {
+				logprob.TopLogprobs = append(logprob.TopLogprobs, dto.ChatTopLogProb{
+					Token:   candidate.Token,
+					Logprob: candidate.LogProbability,
+					Bytes:   dto.TokenBytes(candidate.Token),
+				})
+			}
+		}
+		logprobs.Content = append(logprobs.Content, logprob)
+	}
+	return logprobs
+}
+
 func responseGeminiChat2OpenAI(response *GeminiChatResponse) *dto.OpenAITextResponse {
 	fullTextResponse := dto.OpenAITextResponse{
 		Id:      fmt.Sprintf("chatcmpl-%s", common.GetUUID()),
@@ -537,8 +597,12 @@ func responseGeminiChat2OpenAI(response *GeminiChatResponse) *dto.OpenAITextResp
 			},
 			FinishReason: constant.FinishReasonStop,
 		}
+		if candidate.LogprobsResult != nil {
+			choice.Logprobs = logprobsGemini2OpenAI(candidate.LogprobsResult)
+		}
 		if len(candidate.Content.Parts) > 0 {
 			var texts []string
+			var thoughts []string
 			var toolCalls []dto.ToolCallResponse
 			for _, part := range candidate.Content.Parts {
 				if part.FunctionCall != nil {
@@ -547,7 +611,7 @@ func responseGeminiChat2OpenAI(response *GeminiChatResponse) *dto.OpenAITextResp
 						toolCalls = append(toolCalls, *call)
 					}
 				} else if part.Thought {
-					choice.Message.ReasoningContent = part.Text
+					thoughts = append(thoughts, part.Text)
 				} else {
 					if part.ExecutableCode != nil {
 						texts = append(texts, "```"+part.ExecutableCode.Language+"\n"+part.ExecutableCode.Code+"\n```")
@@ -565,18 +629,13 @@ func responseGeminiChat2OpenAI(response *GeminiChatResponse) *dto.OpenAITextResp
 				choice.Message.SetToolCalls(toolCalls)
 				isToolCall = true
 			}
+			choice.Message.ReasoningContent = strings.Join(thoughts, "\n")
 			choice.Message.SetStringContent(strings.Join(texts, "\n"))
 
 		}
 		if candidate.FinishReason != nil {
-			switch *candidate.FinishReason {
-			case "STOP":
-				choice.FinishReason = constant.FinishReasonStop
-			case "MAX_TOKENS":
-				choice.FinishReason = constant.FinishReasonLength
-			default:
-				choice.FinishReason = constant.FinishReasonContentFilter
-			}
+			choice.FinishReason = finishReasonGemini2OpenAI(*candidate.FinishReason)
+			choice.NativeFinishReason = *candidate.FinishReason
 		}
 		if isToolCall {
 			choice.FinishReason = constant.FinishReasonToolCalls
@@ -587,13 +646,39 @@ func responseGeminiChat2OpenAI(response *GeminiChatResponse) *dto.OpenAITextResp
 	return &fullTextResponse
 }
 
-func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.ChatCompletionsStreamResponse, bool, bool) {
+// thinkingConfigOpenAI2Gemini 把 thinking（budget_tokens）或 reasoning_effort 转换为 thinkingConfig，
+// 开启思考时返回思考摘要，都未指定时返回 nil
+func thinkingConfigOpenAI2Gemini(textRequest *dto.GeneralOpenAIRequest) *GeminiThinkingConfig {
+	if textRequest.Thinking != nil {
+		switch textRequest.Thinking.Type {
+		case "enabled":
+			thinkingConfig := &GeminiThinkingConfig{IncludeThoughts: true}
+			if textRequest.Thinking.BudgetTokens > 0 {
+				thinkingConfig.SetThinkingBudget(textRequest.Thinking.BudgetTokens)
+			}
+			return thinkingConfig
+		case "disabled":
+			return &GeminiThinkingConfig{ThinkingBudget: common.GetPointer(0)}
+		}
+	}
+	if budget, ok := reasoningEffortBudget[textRequest.ReasoningEffort]; ok {
+		if budget == 0 {
+			return &GeminiThinkingConfig{ThinkingBudget: common.GetPointer(0)}
+		}
+		return &GeminiThinkingConfig{ThinkingBudget: common.GetPointer(budget), IncludeThoughts: true}
+	}
+	return nil
+}
+
+// streamResponseGeminiChat2OpenAI 返回转换后的数据块、本块中以 STOP 结束的候选序号和是否包含图片
+func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.ChatCompletionsStreamResponse, []int, bool) {
 	choices := make([]dto.ChatCompletionsStreamResponseChoice, 0, len(geminiResponse.Candidates))
-	isStop := false
+	// STOP 在内容发送完后单独发送结束块
+	var stopIndexes []int
 	hasImage := false
 	for _, candidate := range geminiResponse.Candidates {
 		if candidate.FinishReason != nil && *candidate.FinishReason == "STOP" {
-			isStop = true
+			stopIndexes = append(stopIndexes, int(candidate.Index))
 			candidate.FinishReason = nil
 		}
 		choice := dto.ChatCompletionsStreamResponseChoice{
@@ -603,18 +688,15 @@ func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.C
 			},
 		}
 		var texts []string
-		isTools := false
-		isThought := false
+		var thoughts []string
+		if candidate.LogprobsResult != nil {
+			var logprobs any = logprobsGemini2OpenAI(candidate.LogprobsResult)
+			choice.Logprobs = &logprobs
+		}
 		if candidate.FinishReason != nil {
-			// p := GeminiConvertFinishReason(*candidate.FinishReason)
-			switch *candidate.FinishReason {
-			case "STOP":
-				choice.FinishReason = &constant.FinishReasonStop
-			case "MAX_TOKENS":
-				choice.FinishReason = &constant.FinishReasonLength
-			default:
-				choice.FinishReason = &constant.FinishReasonContentFilter
-			}
+			finishReason := finishReasonGemini2OpenAI(*candidate.FinishReason)
+			choice.FinishReason = &finishReason
+			choice.NativeFinishReason = candidate.FinishReason
 		}
 		for _, part := range candidate.Content.Parts {
 			if part.InlineData != nil {
@@ -624,14 +706,11 @@ func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.C
 					hasImage = true
 				}
 			} else if part.FunctionCall != nil {
-				isTools = true
 				if call := getResponseToolCall(&part); call != nil {
-					call.SetIndex(len(choice.Delta.ToolCalls))
 					choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, *call)
 				}
 			} else if part.Thought {
-				isThought = true
-				texts = append(texts, part.Text)
+				thoughts = append(thoughts, part.Text)
 			} else {
 				if part.ExecutableCode != nil {
 					texts = append(texts, "```"+part.ExecutableCode.Language+"\n"+part.ExecutableCode.Code+"\n```\n")
@@ -644,13 +723,12 @@ func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.C
 				}
 			}
 		}
-		if isThought {
-			choice.Delta.SetReasoningContent(strings.Join(texts, "\n"))
-		} else {
-			choice.Delta.SetContentString(strings.Join(texts, "\n"))
+		// 同一数据块可能同时包含思考摘要和正文
+		if len(thoughts) > 0 {
+			choice.Delta.SetReasoningContent(strings.Join(thoughts, "\n"))
 		}
-		if isTools {
-			choice.FinishReason = &constant.FinishReasonToolCalls
+		if len(texts) > 0 || len(thoughts) == 0 {
+			choice.Delta.SetContentString(strings.Join(texts, "\n"))
 		}
 		choices = append(choices, choice)
 	}
@@ -658,7 +736,7 @@ func streamResponseGeminiChat2OpenAI(geminiResponse *GeminiChatResponse) (*dto.C
 	var response dto.ChatCompletionsStreamResponse
 	response.Object = "chat.completion.chunk"
 	response.Choices = choices
-	return &response, isStop, hasImage
+	return &response, stopIndexes, hasImage
 }
 
 func GeminiChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
@@ -667,6 +745,10 @@ func GeminiChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycom
 	createAt := common.GetTimestamp()
 	var usage = &dto.Usage{}
 	var imageCount int
+	var responseText strings.Builder
+	// 每个数据块包含完整的函数调用，index 跨数据块连续编号，结束块的 finish_reason 为 tool_calls
+	var toolCallCount int
+	outputRules := openai.NewOutputRuleStream(info)
 
 	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
 		var geminiResponse GeminiChatResponse
@@ -676,30 +758,51 @@ func GeminiChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycom
 			return false
 		}
 
-		response, isStop, hasImage := streamResponseGeminiChat2OpenAI(&geminiResponse)
+		response, stopIndexes, hasImage := streamResponseGeminiChat2OpenAI(&geminiResponse)
 		if hasImage {
 			imageCount++
 		}
 		response.Id = id
 		response.Created = createAt
 		response.Model = info.UpstreamModelName
+		for i := range response.Choices {
+			responseText.WriteString(response.Choices[i].Delta.GetContentString())
+			for j := range response.Choices[i].Delta.ToolCalls {
+				response.Choices[i].Delta.ToolCalls[j].SetIndex(toolCallCount)
+				toolCallCount++
+			}
+		}
 		if geminiResponse.UsageMetadata.TotalTokenCount != 0 {
 			usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
 			usage.CompletionTokens = geminiResponse.UsageMetadata.CandidatesTokenCount
 			usage.CompletionTokenDetails.ReasoningTokens = geminiResponse.UsageMetadata.ThoughtsTokenCount
+			usage.PromptTokensDetails.CachedTokens = geminiResponse.UsageMetadata.CachedContentTokenCount
 			usage.TotalTokens = geminiResponse.UsageMetadata.TotalTokenCount
 		}
-		err = helper.ObjectData(c, response)
+		err = outputRules.ObjectData(c, response)
 		if err != nil {
 			common.LogError(c, err.Error())
 		}
-		if isStop {
-			response := helper.GenerateStopResponse(id, createAt, info.UpstreamModelName, constant.FinishReasonStop)
-			helper.ObjectData(c, response)
+		if len(stopIndexes) > 0 {
+			finishReason := constant.FinishReasonStop
+			if toolCallCount > 0 {
+				finishReason = constant.FinishReasonToolCalls
+			}
+			response := helper.GenerateStopResponse(id, createAt, info.UpstreamModelName, finishReason)
+			response.Choices[0].NativeFinishReason = common.GetPointer("STOP")
+			// n > 1 时每个候选分别结束
+			stopChoice := response.Choices[0]
+			response.Choices = response.Choices[:0]
+			for _, index := range stopIndexes {
+				stopChoice.Index = index
+				response.Choices = append(response.Choices, stopChoice)
+			}
+			outputRules.ObjectData(c, response)
 		}
 		return true
 	})
 
+	outputRules.Flush(c)
 	var response *dto.ChatCompletionsStreamResponse
 
 	if imageCount != 0 {
@@ -708,6 +811,11 @@ func GeminiChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycom
 		}
 	}
 
+	// 上游未返回 usageMetadata 时按输出文本估算
+	if usage.TotalTokens == 0 && usage.CompletionTokens == 0 {
+		usage, _ = service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+	}
+
 	usage.PromptTokensDetails.TextTokens = usage.PromptTokens
 	usage.CompletionTokens = usage.TotalTokens - usage.PromptTokens
 
@@ -753,6 +861,7 @@ func GeminiChatHandler(c *gin.Context, resp *http.Response, info *relaycommon.Re
 	}
 	fullTextResponse := responseGeminiChat2OpenAI(&geminiResponse)
 	fullTextResponse.Model = info.UpstreamModelName
+	openai.ApplyOutputRules(info, fullTextResponse)
 	usage := dto.Usage{
 		PromptTokens:     geminiResponse.UsageMetadata.PromptTokenCount,
 		CompletionTokens: geminiResponse.UsageMetadata.CandidatesTokenCount,
@@ -760,6 +869,7 @@ func GeminiChatHandler(c *gin.Context, resp *http.Response, info *relaycommon.Re
 	}
 
 	usage.CompletionTokenDetails.ReasoningTokens = geminiResponse.UsageMetadata.ThoughtsTokenCount
+	usage.PromptTokensDetails.CachedTokens = geminiResponse.UsageMetadata.CachedContentTokenCount
 	usage.CompletionTokens = usage.TotalTokens - usage.PromptTokens
 
 	fullTextResponse.Usage = usage
@@ -4,6 +4,7 @@ package lingyiwanwu
 
 var ModelList = []string{
 	"yi-large", "yi-medium", "yi-vision", "yi-medium-200k", "yi-spark", "yi-large-rag", "yi-large-turbo", "yi-large-preview", "yi-large-rag-preview",
+	"yi-lightning", "yi-vision-v2",
 }
 
 var ChannelName = "lingyiwanwu"
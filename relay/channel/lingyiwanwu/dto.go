@@ -0,0 +1,11 @@
+package lingyiwanwu
+
+import "one-api/dto"
+
+// YiErrorResponse 零一万物部分错误不使用 OpenAI 的 error 包装，而是直接返回 code 和 message
+type YiErrorResponse struct {
+	Code    any              `json:"code"`
+	Message string           `json:"message"`
+	Type    string           `json:"type"`
+	Error   *dto.OpenAIError `json:"error"`
+}
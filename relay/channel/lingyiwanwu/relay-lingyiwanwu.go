@@ -0,0 +1,76 @@
+package lingyiwanwu
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/dto"
+	"strings"
+)
+
+func isVisionModel(model string) bool {
+	return strings.Contains(model, "vision")
+}
+
+// convertYiMessages 非视觉模型只接受字符串内容；视觉模型只接受 text 和 image_url，且 image_url 不支持 detail
+func convertYiMessages(request *dto.GeneralOpenAIRequest) {
+	vision := isVisionModel(request.Model)
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		if message.IsStringContent() {
+			continue
+		}
+		if !vision {
+			message.SetStringContent(message.StringContent())
+			continue
+		}
+		var contents []dto.MediaContent
+		for _, content := range message.ParseContent() {
+			switch content.Type {
+			case dto.ContentTypeText:
+				contents = append(contents, dto.MediaContent{
+					Type: dto.ContentTypeText,
+					Text: content.Text,
+				})
+			case dto.ContentTypeImageURL:
+				contents = append(contents, dto.MediaContent{
+					Type: dto.ContentTypeImageURL,
+					ImageUrl: map[string]string{
+						"url": content.GetImageMedia().Url,
+					},
+				})
+			}
+		}
+		message.SetMediaContent(contents)
+	}
+}
+
+// wrapYiErrorResponse 将非 OpenAI 格式的错误转换为 OpenAI 格式，方便后续统一处理
+func wrapYiErrorResponse(resp *http.Response) {
+	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewBuffer(responseBody))
+	if err != nil {
+		return
+	}
+	var yiError YiErrorResponse
+	if json.Unmarshal(responseBody, &yiError) != nil || yiError.Error != nil || yiError.Message == "" {
+		return
+	}
+	errorType := yiError.Type
+	if errorType == "" {
+		errorType = "lingyiwanwu_error"
+	}
+	jsonError, err := json.Marshal(dto.GeneralErrorResponse{
+		Error: dto.OpenAIError{
+			Message: yiError.Message,
+			Type:    errorType,
+			Code:    yiError.Code,
+		},
+	})
+	if err != nil {
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewBuffer(jsonError))
+}
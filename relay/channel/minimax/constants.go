@@ -8,6 +8,10 @@ var ModelList = []string{
 	"abab6-chat",
 	"abab5.5-chat",
 	"abab5.5s-chat",
+	"speech-01-turbo",
+	"speech-01-hd",
+	"speech-02-turbo",
+	"speech-02-hd",
 }
 
 var ChannelName = "minimax"
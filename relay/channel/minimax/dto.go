@@ -0,0 +1,63 @@
+package minimax
+
+import "one-api/dto"
+
+type MiniMaxBaseResp struct {
+	StatusCode int    `json:"status_code"`
+	StatusMsg  string `json:"status_msg"`
+}
+
+type MiniMaxChatResponse struct {
+	dto.OpenAITextResponse
+	InputSensitive      bool            `json:"input_sensitive"`
+	InputSensitiveType  int             `json:"input_sensitive_type"`
+	OutputSensitive     bool            `json:"output_sensitive"`
+	OutputSensitiveType int             `json:"output_sensitive_type"`
+	BaseResp            MiniMaxBaseResp `json:"base_resp"`
+}
+
+type MiniMaxChatStreamResponse struct {
+	dto.ChatCompletionsStreamResponse
+	InputSensitive  bool            `json:"input_sensitive"`
+	OutputSensitive bool            `json:"output_sensitive"`
+	BaseResp        MiniMaxBaseResp `json:"base_resp"`
+}
+
+type MiniMaxVoiceSetting struct {
+	VoiceId string  `json:"voice_id"`
+	Speed   float64 `json:"speed,omitempty"`
+	Vol     float64 `json:"vol,omitempty"`
+	Pitch   int     `json:"pitch,omitempty"`
+}
+
+type MiniMaxAudioSetting struct {
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Bitrate    int    `json:"bitrate,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Channel    int    `json:"channel,omitempty"`
+}
+
+type MiniMaxT2ARequest struct {
+	Model        string              `json:"model"`
+	Text         string              `json:"text"`
+	Stream       bool                `json:"stream"`
+	VoiceSetting MiniMaxVoiceSetting `json:"voice_setting"`
+	AudioSetting MiniMaxAudioSetting `json:"audio_setting"`
+}
+
+type MiniMaxT2AResponse struct {
+	Data struct {
+		// 十六进制编码的音频数据
+		Audio  string `json:"audio"`
+		Status int    `json:"status"`
+	} `json:"data"`
+	ExtraInfo struct {
+		AudioLength     int64  `json:"audio_length"`
+		AudioSampleRate int64  `json:"audio_sample_rate"`
+		AudioSize       int64  `json:"audio_size"`
+		AudioFormat     string `json:"audio_format"`
+		UsageCharacters int    `json:"usage_characters"`
+	} `json:"extra_info"`
+	TraceId  string          `json:"trace_id"`
+	BaseResp MiniMaxBaseResp `json:"base_resp"`
+}
@@ -1,10 +1,219 @@
 package minimax
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
+// https://platform.minimaxi.com/document/ChatCompletion%20v2
+// https://platform.minimaxi.com/document/T2A%20V2
+
 func GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	return fmt.Sprintf("%s/v1/text/chatcompletion_v2", info.BaseUrl), nil
 }
+
+func getT2AURL(info *relaycommon.RelayInfo, groupId string) string {
+	return fmt.Sprintf("%s/v1/t2a_v2?GroupId=%s", info.BaseUrl, groupId)
+}
+
+func minimaxErrorWrapper(baseResp MiniMaxBaseResp, statusCode int) *dto.OpenAIErrorWithStatusCode {
+	return &dto.OpenAIErrorWithStatusCode{
+		Error: dto.OpenAIError{
+			Message: baseResp.StatusMsg,
+			Type:    "minimax_error",
+			Code:    baseResp.StatusCode,
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// 输入命中敏感词时上游不会生成内容，直接返回错误；输出命中时内容已被截断，按 content_filter 结束
+func sensitiveInputError() *dto.OpenAIErrorWithStatusCode {
+	return service.OpenAIErrorWrapperLocal(errors.New("input contains sensitive words"), "sensitive_words_detected", http.StatusBadRequest)
+}
+
+func minimaxHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	var minimaxResponse MiniMaxChatResponse
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = json.Unmarshal(responseBody, &minimaxResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if minimaxResponse.InputSensitive {
+		return sensitiveInputError(), nil
+	}
+	if minimaxResponse.BaseResp.StatusCode != 0 {
+		return minimaxErrorWrapper(minimaxResponse.BaseResp, http.StatusInternalServerError), nil
+	}
+	fullTextResponse := minimaxResponse.OpenAITextResponse
+	if minimaxResponse.OutputSensitive {
+		for i := range fullTextResponse.Choices {
+			fullTextResponse.Choices[i].FinishReason = constant.FinishReasonContentFilter
+		}
+	}
+	if fullTextResponse.Usage.TotalTokens == 0 {
+		var responseText string
+		for _, choice := range fullTextResponse.Choices {
+			responseText += choice.Message.StringContent()
+		}
+		usage, _ := service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+		fullTextResponse.Usage = *usage
+	}
+	jsonResponse, err := json.Marshal(fullTextResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, err = c.Writer.Write(jsonResponse)
+	return nil, &fullTextResponse.Usage
+}
+
+func minimaxStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	var usage dto.Usage
+	var responseText strings.Builder
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var minimaxResponse MiniMaxChatStreamResponse
+		err := common.DecodeJsonStr(data, &minimaxResponse)
+		if err != nil {
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			return true
+		}
+		if minimaxResponse.BaseResp.StatusCode != 0 {
+			common.LogError(c, fmt.Sprintf("minimax stream error: %d %s", minimaxResponse.BaseResp.StatusCode, minimaxResponse.BaseResp.StatusMsg))
+			return false
+		}
+		response := minimaxResponse.ChatCompletionsStreamResponse
+		response.Id = id
+		response.Created = createAt
+		response.Model = info.UpstreamModelName
+		if response.Usage != nil {
+			usage = *response.Usage
+			response.Usage = nil
+		}
+		for i := range response.Choices {
+			responseText.WriteString(response.Choices[i].Delta.GetContentString())
+			if minimaxResponse.InputSensitive || minimaxResponse.OutputSensitive {
+				response.Choices[i].FinishReason = &constant.FinishReasonContentFilter
+			}
+		}
+		if len(response.Choices) == 0 {
+			return true
+		}
+		err = helper.ObjectData(c, response)
+		if err != nil {
+			common.LogError(c, err.Error())
+		}
+		return true
+	})
+
+	if usage.TotalTokens == 0 {
+		textUsage, _ := service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+		usage = *textUsage
+	}
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, usage)
+		err := helper.ObjectData(c, response)
+		if err != nil {
+			common.SysError("send final response failed: " + err.Error())
+		}
+	}
+	helper.Done(c)
+	return nil, &usage
+}
+
+func requestOpenAI2MiniMaxT2A(request dto.AudioRequest) *MiniMaxT2ARequest {
+	t2aRequest := MiniMaxT2ARequest{
+		Model: request.Model,
+		Text:  request.Input,
+		VoiceSetting: MiniMaxVoiceSetting{
+			VoiceId: request.Voice,
+			Speed:   request.Speed,
+		},
+		AudioSetting: MiniMaxAudioSetting{
+			Format: "mp3",
+		},
+	}
+	if t2aRequest.VoiceSetting.VoiceId == "" {
+		t2aRequest.VoiceSetting.VoiceId = "male-qn-qingse"
+	}
+	switch request.ResponseFormat {
+	case "wav", "pcm", "flac":
+		t2aRequest.AudioSetting.Format = request.ResponseFormat
+	}
+	return &t2aRequest
+}
+
+func getAudioContentType(format string) string {
+	switch format {
+	case "wav":
+		return "audio/wav"
+	case "pcm":
+		return "audio/pcm"
+	case "flac":
+		return "audio/flac"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+func minimaxTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	var t2aResponse MiniMaxT2AResponse
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = json.Unmarshal(responseBody, &t2aResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if t2aResponse.BaseResp.StatusCode != 0 {
+		return minimaxErrorWrapper(t2aResponse.BaseResp, http.StatusInternalServerError), nil
+	}
+	audio, err := hex.DecodeString(t2aResponse.Data.Audio)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "decode_audio_failed", http.StatusInternalServerError), nil
+	}
+
+	usage := &dto.Usage{}
+	usage.PromptTokens = info.PromptTokens
+	if t2aResponse.ExtraInfo.UsageCharacters > 0 {
+		usage.PromptTokens = t2aResponse.ExtraInfo.UsageCharacters
+	}
+	usage.TotalTokens = usage.PromptTokens
+
+	c.Writer.Header().Set("Content-Type", getAudioContentType(t2aResponse.ExtraInfo.AudioFormat))
+	c.Writer.WriteHeader(http.StatusOK)
+	_, err = c.Writer.Write(audio)
+	if err != nil {
+		common.LogError(c, err.Error())
+	}
+	return nil, usage
+}
@@ -1,6 +1,7 @@
 package mistral
 
 import (
+	"one-api/common"
 	"one-api/dto"
 )
 
@@ -23,14 +24,22 @@ func requestOpenAI2Mistral(request *dto.GeneralOpenAIRequest) *dto.GeneralOpenAI
 			ToolCallId: message.ToolCallId,
 		})
 	}
+	// Mistral 使用 random_seed 指定随机种子
+	var randomSeed *int64
+	if request.Seed != 0 {
+		randomSeed = common.GetPointer(int64(request.Seed))
+	}
 	return &dto.GeneralOpenAIRequest{
-		Model:       request.Model,
-		Stream:      request.Stream,
-		Messages:    messages,
-		Temperature: request.Temperature,
-		TopP:        request.TopP,
-		MaxTokens:   request.MaxTokens,
-		Tools:       request.Tools,
-		ToolChoice:  request.ToolChoice,
+		Model:            request.Model,
+		Stream:           request.Stream,
+		Messages:         messages,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		MaxTokens:        request.MaxTokens,
+		N:                request.N,
+		Tools:            request.Tools,
+		ToolChoice:       request.ToolChoice,
+		ParallelTooCalls: request.ParallelTooCalls,
+		RandomSeed:       randomSeed,
 	}
 }
@@ -0,0 +1,20 @@
+package nvidia
+
+var ModelList = []string{
+	"meta/llama-3.1-8b-instruct", "meta/llama-3.1-70b-instruct", "meta/llama-3.1-405b-instruct",
+	"meta/llama-3.3-70b-instruct",
+	"nvidia/llama-3.1-nemotron-70b-instruct", "nvidia/llama-3.3-nemotron-super-49b-v1",
+	"mistralai/mistral-large-2-instruct", "mistralai/mixtral-8x22b-instruct-v0.1",
+	"qwen/qwen2.5-coder-32b-instruct", "qwen/qwq-32b",
+	"deepseek-ai/deepseek-r1",
+	"nvidia/nv-embedqa-e5-v5", "nvidia/llama-3.2-nv-embedqa-1b-v2",
+}
+
+var ChannelName = "nvidia"
+
+const (
+	// nvcfStatusURL 生成时间较长时上游返回 202 和 NVCF-REQID，需要轮询该地址获取结果
+	nvcfStatusURL = "https://api.nvcf.nvidia.com/v2/nvcf/pexec/status/%s"
+	// nvcfPollSeconds 每次轮询时上游最多等待的秒数
+	nvcfPollSeconds = 30
+)
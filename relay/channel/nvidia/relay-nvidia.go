@@ -0,0 +1,160 @@
+package nvidia
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"strings"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// pollNvcfResult 上游返回 202 时按 NVCF-REQID 轮询结果，直到返回其他状态码、客户端断开或超过 RELAY_TIMEOUT
+func pollNvcfResult(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*http.Response, error) {
+	var client *http.Client
+	var err error
+	if proxyURL, ok := info.ChannelSetting["proxy"]; ok {
+		client, err = service.NewProxyHttpClient(proxyURL.(string))
+		if err != nil {
+			return nil, fmt.Errorf("new proxy http client failed: %w", err)
+		}
+	} else {
+		client = service.GetHttpClient()
+	}
+	var deadline time.Time
+	if common.RelayTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(common.RelayTimeout) * time.Second)
+	}
+	for resp.StatusCode == http.StatusAccepted {
+		requestId := resp.Header.Get("NVCF-REQID")
+		_ = resp.Body.Close()
+		if requestId == "" {
+			return nil, errors.New("nvcf request id is empty")
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, errors.New("nvcf polling timeout")
+		}
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, fmt.Sprintf(nvcfStatusURL, requestId), nil)
+		if err != nil {
+			return nil, fmt.Errorf("new request failed: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("NVCF-POLL-SECONDS", fmt.Sprintf("%d", nvcfPollSeconds))
+		resp, err = client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("poll nvcf status failed: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// normalizeToolCall 部分模型返回的 arguments 为 JSON 对象而不是字符串，或缺少 type 和 id，返回是否做了修改
+func normalizeToolCall(toolCall map[string]any, needId bool) bool {
+	changed := false
+	if _, ok := toolCall["type"]; !ok {
+		toolCall["type"] = "function"
+		changed = true
+	}
+	if id, _ := toolCall["id"].(string); id == "" && needId {
+		toolCall["id"] = fmt.Sprintf("call_%s", common.GetUUID())
+		changed = true
+	}
+	function, ok := toolCall["function"].(map[string]any)
+	if !ok {
+		return changed
+	}
+	if arguments, ok := function["arguments"]; ok && arguments != nil {
+		if _, isString := arguments.(string); !isString {
+			data, err := json.Marshal(arguments)
+			if err == nil {
+				function["arguments"] = string(data)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// normalizeToolCalls 规范化响应中 message 或 delta 的 tool_calls，没有需要修改的内容时原样返回
+func normalizeToolCalls(data []byte) []byte {
+	if !bytes.Contains(data, []byte(`"tool_calls"`)) {
+		return data
+	}
+	var response map[string]any
+	if err := json.Unmarshal(data, &response); err != nil {
+		return data
+	}
+	choices, _ := response["choices"].([]any)
+	changed := false
+	for _, choice := range choices {
+		choiceMap, ok := choice.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"message", "delta"} {
+			message, ok := choiceMap[key].(map[string]any)
+			if !ok {
+				continue
+			}
+			toolCalls, _ := message["tool_calls"].([]any)
+			for _, toolCall := range toolCalls {
+				if toolCallMap, ok := toolCall.(map[string]any); ok {
+					// 流式响应中只有第一个数据块带有 id，非流式响应缺少 id 时补全
+					if normalizeToolCall(toolCallMap, key == "message") {
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	if !changed {
+		return data
+	}
+	normalized, err := json.Marshal(response)
+	if err != nil {
+		return data
+	}
+	return normalized
+}
+
+// toolCallNormalizeReader 逐行规范化流式响应中的 tool_calls
+func toolCallNormalizeReader(body io.ReadCloser) io.ReadCloser {
+	reader, writer := io.Pipe()
+	gopool.Go(func() {
+		defer body.Close()
+		bufReader := bufio.NewReader(body)
+		for {
+			line, err := bufReader.ReadString('\n')
+			if line != "" {
+				if strings.HasPrefix(line, "data:") {
+					payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+					if payload != "[DONE]" {
+						line = "data: " + string(normalizeToolCalls([]byte(payload))) + "\n"
+					}
+				}
+				if _, writeErr := writer.Write([]byte(line)); writeErr != nil {
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					_ = writer.Close()
+				} else {
+					_ = writer.CloseWithError(err)
+				}
+				return
+			}
+		}
+	})
+	return reader
+}
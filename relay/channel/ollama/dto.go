@@ -10,7 +10,7 @@ type OllamaRequest struct {
 	Seed             float64               `json:"seed,omitempty"`
 	Topp             float64               `json:"top_p,omitempty"`
 	TopK             int                   `json:"top_k,omitempty"`
-	Stop             any                   `json:"stop,omitempty"`
+	Stop             []string              `json:"stop,omitempty"`
 	MaxTokens        uint                  `json:"max_tokens,omitempty"`
 	Tools            []dto.ToolCallRequest `json:"tools,omitempty"`
 	ResponseFormat   any                   `json:"response_format,omitempty"`
@@ -13,6 +13,9 @@ import (
 )
 
 func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaRequest, error) {
+	// Ollama 不支持 tool_choice 和 parallel_tool_calls
+	service.EmulateToolChoice(&request)
+	service.EmulateParallelToolCalls(&request)
 	messages := make([]dto.Message, 0, len(request.Messages))
 	for _, message := range request.Messages {
 		if !message.IsStringContent() {
@@ -22,7 +25,7 @@ func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaRequest, err
 					imageUrl := mediaMessage.GetImageMedia()
 					// check if not base64
 					if strings.HasPrefix(imageUrl.Url, "http") {
-						fileData, err := service.GetFileBase64FromUrl(imageUrl.Url)
+						fileData, err := service.GetImageData(imageUrl.Url, service.DefaultImageLimit)
 						if err != nil {
 							return nil, err
 						}
@@ -41,13 +44,6 @@ func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaRequest, err
 			ToolCallId: message.ToolCallId,
 		})
 	}
-	str, ok := request.Stop.(string)
-	var Stop []string
-	if ok {
-		Stop = []string{str}
-	} else {
-		Stop, _ = request.Stop.([]string)
-	}
 	return &OllamaRequest{
 		Model:            request.Model,
 		Messages:         messages,
@@ -55,8 +51,8 @@ func requestOpenAI2Ollama(request dto.GeneralOpenAIRequest) (*OllamaRequest, err
 		Temperature:      request.Temperature,
 		Seed:             request.Seed,
 		Topp:             request.TopP,
-		TopK:             request.TopK,
-		Stop:             Stop,
+		TopK:             int(request.TopK),
+		Stop:             request.Stop,
 		Tools:            request.Tools,
 		MaxTokens:        request.MaxTokens,
 		ResponseFormat:   request.ResponseFormat,
@@ -14,18 +14,17 @@ import (
 	"one-api/dto"
 	"one-api/relay/channel"
 	"one-api/relay/channel/ai360"
-	"one-api/relay/channel/lingyiwanwu"
-	"one-api/relay/channel/minimax"
 	"one-api/relay/channel/moonshot"
-	"one-api/relay/channel/openrouter"
 	"one-api/relay/channel/xinference"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/common_handler"
 	"one-api/relay/constant"
 	"one-api/service"
+	"one-api/setting/model_setting"
 	"path/filepath"
 	"strings"
 
+	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 )
 
@@ -35,9 +34,6 @@ type Adaptor struct {
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
-	if !strings.Contains(request.Model, "claude") {
-		return nil, fmt.Errorf("you are using openai channel type with path /v1/messages, only claude model supported convert, but got %s", request.Model)
-	}
 	aiRequest, err := service.ClaudeToOpenAIRequest(*request, info)
 	if err != nil {
 		return nil, err
@@ -78,6 +74,12 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 			info.BaseUrl = baseUrl
 		}
 	}
+	if template, ok := info.ChannelSetting[constant2.ChannelSettingRequestPathTemplate].(string); ok && template != "" {
+		return relaycommon.FormatRequestPathTemplate(template, info), nil
+	}
+	if useResponsesAPI(info) {
+		return relaycommon.GetFullRequestURL(info.BaseUrl, "/v1/responses", info.ChannelType), nil
+	}
 	switch info.ChannelType {
 	case common.ChannelTypeAzure:
 		apiVersion := info.ApiVersion
@@ -99,12 +101,8 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 			requestURL = fmt.Sprintf("/openai/realtime?deployment=%s&api-version=%s", model_, apiVersion)
 		}
 		return relaycommon.GetFullRequestURL(info.BaseUrl, requestURL, info.ChannelType), nil
-	case common.ChannelTypeMiniMax:
-		return minimax.GetRequestURL(info)
 	case common.ChannelTypeCustom:
-		url := info.BaseUrl
-		url = strings.Replace(url, "{model}", info.UpstreamModelName, -1)
-		return url, nil
+		return relaycommon.FormatRequestPathTemplate(info.BaseUrl, info), nil
 	default:
 		return relaycommon.GetFullRequestURL(info.BaseUrl, info.RequestURLPath, info.ChannelType), nil
 	}
@@ -138,10 +136,6 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *
 	} else {
 		header.Set("Authorization", "Bearer "+info.ApiKey)
 	}
-	if info.ChannelType == common.ChannelTypeOpenRouter {
-		header.Set("HTTP-Referer", "https://github.com/Calcium-Ion/new-api")
-		header.Set("X-Title", "New API")
-	}
 	return nil
 }
 
@@ -152,9 +146,18 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if info.ChannelType != common.ChannelTypeOpenAI && info.ChannelType != common.ChannelTypeAzure {
 		request.StreamOptions = nil
 	}
+	if info.ChannelType == common.ChannelTypeOpenAI || info.ChannelType == common.ChannelTypeAzure {
+		// OpenAI 不支持 thinking 参数
+		request.Thinking = nil
+		// OpenAI 最多支持 4 个停止序列
+		request.Stop = request.Stop.Limit(4)
+	}
 	if strings.HasPrefix(request.Model, "o") {
-		if request.MaxCompletionTokens == 0 && request.MaxTokens != 0 {
-			request.MaxCompletionTokens = request.MaxTokens
+		// o 系列模型不接受 max_tokens
+		if request.MaxTokens != 0 {
+			if request.MaxCompletionTokens == 0 {
+				request.MaxCompletionTokens = request.MaxTokens
+			}
 			request.MaxTokens = 0
 		}
 		request.Temperature = nil
@@ -178,6 +181,25 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 				request.Messages[0].Role = "developer"
 			}
 		}
+	} else if info.ChannelType != common.ChannelTypeOpenAI && info.ChannelType != common.ChannelTypeAzure {
+		// 其他 OpenAI 兼容的上游大多只识别 max_tokens
+		if request.MaxTokens == 0 && request.MaxCompletionTokens != 0 {
+			request.MaxTokens = request.MaxCompletionTokens
+			request.MaxCompletionTokens = 0
+		}
+	}
+
+	if useResponsesAPI(info) {
+		return chatToResponsesRequest(request)
+	}
+
+	// 模型不支持结构化的工具调用时，按配置的格式把工具写入提示
+	if len(request.Tools) > 0 {
+		profile := model_setting.GetToolCallSettings().GetModelProfile(info.UpstreamModelName)
+		if toolCallProfile, ok := toolCallProfiles[profile]; ok {
+			convertToolsToPrompt(request, toolCallProfile)
+			info.ToolCallProfile = profile
+		}
 	}
 
 	return request, nil
@@ -200,49 +222,59 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 		}
 		return bytes.NewReader(jsonData), nil
 	} else {
-		var requestBody bytes.Buffer
-		writer := multipart.NewWriter(&requestBody)
-
-		writer.WriteField("model", request.Model)
-
-		// 获取所有表单字段
-		formData := c.Request.PostForm
-
-		// 遍历表单字段并打印输出
-		for key, values := range formData {
-			if key == "model" {
-				continue
-			}
-			for _, value := range values {
-				writer.WriteField(key, value)
-			}
-		}
-
-		// 添加文件字段
-		file, header, err := c.Request.FormFile("file")
+		form, err := common.ParseMultipartFormReusable(c)
 		if err != nil {
+			return nil, err
+		}
+		if len(form.File["file"]) == 0 {
 			return nil, errors.New("file is required")
 		}
-		defer file.Close()
+		fileHeader := form.File["file"][0]
 
-		part, err := writer.CreateFormFile("file", header.Filename)
-		if err != nil {
-			return nil, errors.New("create form file failed")
+		// 边读取上传的文件边写入请求体，不在内存中拼接整个 multipart 请求
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		gopool.Go(func() {
+			pw.CloseWithError(writeAudioMultipart(writer, request.Model, form.Value, fileHeader))
+		})
+		return pr, nil
+	}
+}
+
+func writeAudioMultipart(writer *multipart.Writer, model string, values map[string][]string, fileHeader *multipart.FileHeader) error {
+	if err := writer.WriteField("model", model); err != nil {
+		return err
+	}
+	for key, fieldValues := range values {
+		if key == "model" {
+			continue
 		}
-		if _, err := io.Copy(part, file); err != nil {
-			return nil, errors.New("copy file failed")
+		for _, value := range fieldValues {
+			if err := writer.WriteField(key, value); err != nil {
+				return err
+			}
 		}
-
-		// 关闭 multipart 编写器以设置分界线
-		writer.Close()
-		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
-		return &requestBody, nil
 	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	part, err := writer.CreateFormFile("file", fileHeader.Filename)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return err
+	}
+	// 关闭 multipart 编写器以写入结束分界线
+	return writer.Close()
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
 	switch info.RelayMode {
-	case constant.RelayModeImagesEdits:
+	case constant.RelayModeImagesEdits, constant.RelayModeImagesVariations:
 
 		var requestBody bytes.Buffer
 		writer := multipart.NewWriter(&requestBody)
@@ -401,7 +433,8 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
 	if info.RelayMode == constant.RelayModeAudioTranscription ||
 		info.RelayMode == constant.RelayModeAudioTranslation ||
-		info.RelayMode == constant.RelayModeImagesEdits {
+		info.RelayMode == constant.RelayModeImagesEdits ||
+		info.RelayMode == constant.RelayModeImagesVariations {
 		return channel.DoFormRequest(a, c, info, requestBody)
 	} else if info.RelayMode == constant.RelayModeRealtime {
 		return channel.DoWssRequest(a, c, info, requestBody)
@@ -420,10 +453,12 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		fallthrough
 	case constant.RelayModeAudioTranscription:
 		err, usage = OpenaiSTTHandler(c, resp, info, a.ResponseFormat)
-	case constant.RelayModeImagesGenerations, constant.RelayModeImagesEdits:
+	case constant.RelayModeImagesGenerations, constant.RelayModeImagesEdits, constant.RelayModeImagesVariations:
 		err, usage = OpenaiHandlerWithUsage(c, resp, info)
 	case constant.RelayModeRerank:
 		err, usage = common_handler.RerankHandler(c, info, resp)
+	case constant.RelayModeModerations:
+		err, usage = OpenaiModerationHandler(c, resp, info)
 	case constant.RelayModeResponses:
 		if info.IsStream {
 			err, usage = OaiResponsesStreamHandler(c, resp, info)
@@ -431,7 +466,13 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 			err, usage = OaiResponsesHandler(c, resp, info)
 		}
 	default:
-		if info.IsStream {
+		if useResponsesAPI(info) {
+			if info.IsStream {
+				err, usage = ResponsesToChatStreamHandler(c, resp, info)
+			} else {
+				err, usage = ResponsesToChatHandler(c, resp, info)
+			}
+		} else if info.IsStream {
 			err, usage = OaiStreamHandler(c, resp, info)
 		} else {
 			err, usage = OpenaiHandler(c, resp, info)
@@ -446,14 +487,8 @@ func (a *Adaptor) GetModelList() []string {
 		return ai360.ModelList
 	case common.ChannelTypeMoonshot:
 		return moonshot.ModelList
-	case common.ChannelTypeLingYiWanWu:
-		return lingyiwanwu.ModelList
-	case common.ChannelTypeMiniMax:
-		return minimax.ModelList
 	case common.ChannelTypeXinference:
 		return xinference.ModelList
-	case common.ChannelTypeOpenRouter:
-		return openrouter.ModelList
 	default:
 		return ModelList
 	}
@@ -465,14 +500,8 @@ func (a *Adaptor) GetChannelName() string {
 		return ai360.ChannelName
 	case common.ChannelTypeMoonshot:
 		return moonshot.ChannelName
-	case common.ChannelTypeLingYiWanWu:
-		return lingyiwanwu.ChannelName
-	case common.ChannelTypeMiniMax:
-		return minimax.ChannelName
 	case common.ChannelTypeXinference:
 		return xinference.ChannelName
-	case common.ChannelTypeOpenRouter:
-		return openrouter.ChannelName
 	default:
 		return ChannelName
 	}
@@ -24,12 +24,12 @@ var ModelList = []string{
 	"gpt-4o-mini-realtime-preview", "gpt-4o-mini-realtime-preview-2024-12-17",
 	"text-embedding-ada-002", "text-embedding-3-small", "text-embedding-3-large",
 	"text-curie-001", "text-babbage-001", "text-ada-001",
-	"text-moderation-latest", "text-moderation-stable",
+	"text-moderation-latest", "text-moderation-stable", "omni-moderation-latest",
 	"text-davinci-edit-001",
 	"davinci-002", "babbage-002",
 	"dall-e-3",
 	"whisper-1",
-	"tts-1", "tts-1-1106", "tts-1-hd", "tts-1-hd-1106",
+	"tts-1", "tts-1-1106", "tts-1-hd", "tts-1-hd-1106", "gpt-4o-mini-tts",
 }
 
 var ChannelName = "openai"
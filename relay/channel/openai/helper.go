@@ -3,16 +3,25 @@ package openai
 import (
 	"encoding/json"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
+	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// recordServiceTier 记录上游实际使用的服务等级，用于计费
+func recordServiceTier(c *gin.Context, serviceTier string) {
+	if serviceTier != "" {
+		c.Set(constant.ContextKeyServiceTier, serviceTier)
+	}
+}
+
 // 辅助函数
 func handleStreamFormat(c *gin.Context, info *relaycommon.RelayInfo, data string, forceFormat bool, thinkToContent bool) error {
 	info.SendResponseCount++
@@ -167,21 +176,17 @@ func handleFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, lastStream
 		if info.ShouldIncludeUsage && !containStreamUsage {
 			response := helper.GenerateFinalUsageResponse(responseId, createAt, model, *usage)
 			response.SetSystemFingerprint(systemFingerprint)
+			service.TagStreamSystemFingerprint(response, info.ChannelId)
 			helper.ObjectData(c, response)
 		}
 		helper.Done(c)
 
 	case relaycommon.RelayFormatClaude:
+		// 最后一个块的内容已经转换，这里只发送结束事件，上游没有返回任何块时先发送 message_start
+		info.SendResponseCount++
 		info.ClaudeConvertInfo.Done = true
-		var streamResponse dto.ChatCompletionsStreamResponse
-		if err := json.Unmarshal(common.StringToByteSlice(lastStreamData), &streamResponse); err != nil {
-			common.SysError("error unmarshalling stream response: " + err.Error())
-			return
-		}
-
 		info.ClaudeConvertInfo.Usage = usage
-
-		claudeResponses := service.StreamResponseOpenAI2Claude(&streamResponse, info)
+		claudeResponses := service.StreamResponseOpenAI2Claude(&dto.ChatCompletionsStreamResponse{Model: model}, info)
 		for _, resp := range claudeResponses {
 			helper.ClaudeData(c, *resp)
 		}
@@ -194,3 +199,18 @@ func sendResponsesStreamData(c *gin.Context, streamResponse dto.ResponsesStreamR
 	}
 	helper.ResponseChunkData(c, streamResponse, data)
 }
+
+// streamFinishReasonPattern 匹配数据块中的 finish_reason 字段，字符串值中的引号已转义，不会误匹配内容
+var streamFinishReasonPattern = regexp.MustCompile(`"finish_reason"\s*:\s*"([^"\\]*)"`)
+
+// normalizeStreamFinishReason 把数据块中不属于 OpenAI 的结束原因替换为对应的 OpenAI 结束原因，其余内容保持不变
+func normalizeStreamFinishReason(data string) string {
+	return streamFinishReasonPattern.ReplaceAllStringFunc(data, func(field string) string {
+		reason := streamFinishReasonPattern.FindStringSubmatch(field)[1]
+		finishReason := helper.DefaultFinishReasons.Normalize(reason)
+		if finishReason == reason {
+			return field
+		}
+		return `"finish_reason":"` + finishReason + `"`
+	})
+}
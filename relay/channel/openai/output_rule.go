@@ -0,0 +1,329 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/setting/model_setting"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 部分模型的回复中会带有固定的前言、<|im_end|> 等残留标记或大量连续空白，
+// 按模型或渠道配置的规则对回复内容做后处理，流式响应的增量拼接后与非流式响应的处理结果一致
+
+const defaultOutputRuleWindow = 64
+
+// outputRuleMaxPendingWindows 暂存内容超过 window 的倍数后，即使规则改写了已输出的部分也继续输出，避免暂存内容无限增长
+const outputRuleMaxPendingWindows = 4
+
+type outputRule struct {
+	model_setting.OutputRule
+	regex *regexp.Regexp
+}
+
+type outputProcessor struct {
+	rules []outputRule
+	// window 流式响应中暂存的末尾字节数，暂存的内容可能在后续增量到达后被规则改写
+	window int
+}
+
+// getOutputProcessor 模型规则在前、渠道规则在后，没有规则时返回 nil
+func getOutputProcessor(info *relaycommon.RelayInfo) *outputProcessor {
+	rules := model_setting.GetOutputRuleSettings().GetModelRules(info.UpstreamModelName)
+	if channelRules, ok := info.ChannelSetting[constant.ChannelSettingOutputRules]; ok {
+		var parsed []model_setting.OutputRule
+		data, _ := json.Marshal(channelRules)
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			common.SysError(fmt.Sprintf("invalid output rules of channel #%d: %s", info.ChannelId, err.Error()))
+		} else {
+			rules = append(append([]model_setting.OutputRule{}, rules...), parsed...)
+		}
+	}
+	return newOutputProcessor(rules)
+}
+
+func newOutputProcessor(rules []model_setting.OutputRule) *outputProcessor {
+	p := &outputProcessor{}
+	for _, rule := range rules {
+		r := outputRule{OutputRule: rule}
+		switch rule.Type {
+		case model_setting.OutputRuleRegexReplace:
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				common.SysError(fmt.Sprintf("invalid output rule pattern %q: %s", rule.Pattern, err.Error()))
+				continue
+			}
+			r.regex = regex
+			window := rule.Window
+			if window <= 0 {
+				window = defaultOutputRuleWindow
+			}
+			p.window = max(p.window, window)
+		case model_setting.OutputRuleStopTrim, model_setting.OutputRulePrefixStrip:
+			for _, value := range rule.Values {
+				p.window = max(p.window, len(value))
+			}
+		default:
+			common.SysError("unknown output rule type: " + rule.Type)
+			continue
+		}
+		p.rules = append(p.rules, r)
+	}
+	if len(p.rules) == 0 {
+		return nil
+	}
+	return p
+}
+
+func (p *outputProcessor) apply(text string) string {
+	text, _ = p.applyFrom(text, true)
+	return text
+}
+
+// applyFrom 对内容应用规则，atStart 为 false 时内容不在回复开头，不去除前言；返回内容是否在结束标记处截断
+func (p *outputProcessor) applyFrom(text string, atStart bool) (string, bool) {
+	stopped := false
+	for _, rule := range p.rules {
+		switch rule.Type {
+		case model_setting.OutputRulePrefixStrip:
+			if !atStart {
+				continue
+			}
+			for _, prefix := range rule.Values {
+				if prefix != "" && strings.HasPrefix(text, prefix) {
+					text = text[len(prefix):]
+					break
+				}
+			}
+		case model_setting.OutputRuleStopTrim:
+			end := len(text)
+			for _, stop := range rule.Values {
+				if i := strings.Index(text[:end], stop); stop != "" && i >= 0 {
+					end = i
+					stopped = true
+				}
+			}
+			text = text[:end]
+		case model_setting.OutputRuleRegexReplace:
+			text = rule.regex.ReplaceAllString(text, rule.Replacement)
+		}
+	}
+	return text, stopped
+}
+
+// applyOutputRules 处理非流式响应，返回是否修改了响应
+func applyOutputRules(response *dto.OpenAITextResponse, p *outputProcessor) bool {
+	changed := false
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+		if !message.IsStringContent() {
+			continue
+		}
+		content := message.StringContent()
+		processed := p.apply(content)
+		if processed != content {
+			message.SetStringContent(processed)
+			changed = true
+		}
+	}
+	return changed
+}
+
+type outputChoiceState struct {
+	// pending 尚未输出的原始内容，只保留末尾的暂存窗口
+	pending string
+	// started 已经输出过内容，之后的内容不在回复开头
+	started bool
+	// stopped 已遇到结束标记，之后的内容全部丢弃
+	stopped  bool
+	finished bool
+}
+
+// outputStreamProcessor 每个 choice 只暂存末尾 window 字节的原始内容，窗口之前的部分应用规则后输出，
+// 收到结束原因时输出剩余内容
+type outputStreamProcessor struct {
+	processor    *outputProcessor
+	choices      map[int]*outputChoiceState
+	lastResponse *dto.ChatCompletionsStreamResponse
+}
+
+func newOutputStreamProcessor(info *relaycommon.RelayInfo) *outputStreamProcessor {
+	processor := getOutputProcessor(info)
+	if processor == nil {
+		return nil
+	}
+	return &outputStreamProcessor{processor: processor, choices: make(map[int]*outputChoiceState)}
+}
+
+func (s *outputStreamProcessor) step(state *outputChoiceState, delta string, final bool) string {
+	if state.stopped {
+		return ""
+	}
+	state.pending += delta
+	out, stopped := s.processor.applyFrom(state.pending, !state.started)
+	if final || stopped {
+		state.pending = ""
+		state.started = true
+		state.stopped = stopped
+		return out
+	}
+	end := len(state.pending) - s.processor.window
+	for end > 0 && end < len(state.pending) && !utf8.RuneStart(state.pending[end]) {
+		end--
+	}
+	// 从窗口边界向前最多一个窗口寻找拆开处理与整体处理结果一致的位置，规则的匹配不跨越该位置
+	for cut := end; cut > 0 && cut > end-s.processor.window-1; cut-- {
+		if cut < len(state.pending) && !utf8.RuneStart(state.pending[cut]) {
+			continue
+		}
+		head, _ := s.processor.applyFrom(state.pending[:cut], !state.started)
+		rest, _ := s.processor.applyFrom(state.pending[cut:], false)
+		if head+rest == out {
+			state.pending = state.pending[cut:]
+			state.started = true
+			return head
+		}
+	}
+	if end <= 0 || len(state.pending) < outputRuleMaxPendingWindows*max(s.processor.window, 1) {
+		return ""
+	}
+	// 规则的匹配超过了 window，只能按窗口边界输出，已输出的部分可能与整体处理的结果不一致
+	common.SysError("output rules rewrote streamed content, consider increasing the rule window")
+	head, _ := s.processor.applyFrom(state.pending[:end], !state.started)
+	state.pending = state.pending[end:]
+	state.started = true
+	return head
+}
+
+func (s *outputStreamProcessor) rewrite(response *dto.ChatCompletionsStreamResponse) string {
+	empty := response.Usage == nil
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		state, ok := s.choices[choice.Index]
+		if !ok {
+			state = &outputChoiceState{}
+			s.choices[choice.Index] = state
+		}
+		final := choice.FinishReason != nil
+		if final {
+			state.finished = true
+		}
+		if text := s.step(state, choice.Delta.GetContentString(), final); text != "" {
+			choice.Delta.SetContentString(text)
+		} else {
+			choice.Delta.Content = nil
+		}
+		if final || choice.Delta.Role != "" || choice.Delta.Content != nil || choice.Delta.ReasoningContent != nil ||
+			choice.Delta.Reasoning != nil || len(choice.Delta.ToolCalls) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return ""
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Feed 处理一个数据块，返回改写后的数据块，内容被暂存时可能返回空
+func (s *outputStreamProcessor) Feed(data string) []string {
+	var response dto.ChatCompletionsStreamResponse
+	if err := common.DecodeJsonStr(data, &response); err != nil || len(response.Choices) == 0 {
+		return []string{data}
+	}
+	template := response.Copy()
+	template.Usage = nil
+	s.lastResponse = template
+	rewritten := s.rewrite(&response)
+	if rewritten == "" {
+		return nil
+	}
+	return []string{rewritten}
+}
+
+// Flush 上游没有返回结束原因就结束时，输出暂存的内容
+func (s *outputStreamProcessor) Flush() []string {
+	if s.lastResponse == nil {
+		return nil
+	}
+	var result []string
+	for index, state := range s.choices {
+		if state.finished {
+			continue
+		}
+		state.finished = true
+		text := s.step(state, "", true)
+		if text == "" {
+			continue
+		}
+		response := *s.lastResponse
+		choice := dto.ChatCompletionsStreamResponseChoice{Index: index}
+		choice.Delta.SetContentString(text)
+		response.Choices = []dto.ChatCompletionsStreamResponseChoice{choice}
+		data, err := json.Marshal(response)
+		if err == nil {
+			result = append(result, string(data))
+		}
+	}
+	return result
+}
+
+// OutputRuleStream 其他格式转换而来的 OpenAI 流式响应（Claude、Gemini 等）按输出规则处理后发送，
+// 没有规则时为 nil，直接发送
+type OutputRuleStream struct {
+	processor *outputStreamProcessor
+}
+
+func NewOutputRuleStream(info *relaycommon.RelayInfo) *OutputRuleStream {
+	processor := newOutputStreamProcessor(info)
+	if processor == nil {
+		return nil
+	}
+	return &OutputRuleStream{processor: processor}
+}
+
+func (s *OutputRuleStream) ObjectData(c *gin.Context, response *dto.ChatCompletionsStreamResponse) error {
+	if s == nil {
+		return helper.ObjectData(c, response)
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	for _, item := range s.processor.Feed(string(data)) {
+		if err := helper.StringData(c, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush 在发送用量和结束标记前调用，输出暂存的内容
+func (s *OutputRuleStream) Flush(c *gin.Context) {
+	if s == nil {
+		return
+	}
+	for _, item := range s.processor.Flush() {
+		if err := helper.StringData(c, item); err != nil {
+			common.LogError(c, "send_stream_response_failed: "+err.Error())
+		}
+	}
+}
+
+// ApplyOutputRules 处理其他格式转换而来的 OpenAI 非流式响应
+func ApplyOutputRules(info *relaycommon.RelayInfo, response *dto.OpenAITextResponse) {
+	if processor := getOutputProcessor(info); processor != nil {
+		applyOutputRules(response, processor)
+	}
+}
@@ -0,0 +1,119 @@
+package openai
+
+import (
+	"encoding/json"
+	"one-api/dto"
+	"one-api/setting/model_setting"
+	"strings"
+	"testing"
+)
+
+var testOutputRules = []model_setting.OutputRule{
+	{Type: model_setting.OutputRulePrefixStrip, Values: []string{"Sure! Here is the answer:\n"}},
+	{Type: model_setting.OutputRuleStopTrim, Values: []string{"<|im_end|>"}},
+	{Type: model_setting.OutputRuleRegexReplace, Pattern: `[ \t]{2,}`, Replacement: " ", Window: 16},
+}
+
+// splitText 按固定字节数切分，可能切在多字节字符中间，与上游的增量一致按字符切分
+func splitText(text string, size int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > size {
+		chunks = append(chunks, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return append(chunks, string(runes))
+}
+
+func streamText(p *outputProcessor, chunks []string, final bool) string {
+	s := &outputStreamProcessor{processor: p, choices: make(map[int]*outputChoiceState)}
+	state := &outputChoiceState{}
+	var out strings.Builder
+	for _, chunk := range chunks {
+		out.WriteString(s.step(state, chunk, false))
+	}
+	if final {
+		out.WriteString(s.step(state, "", true))
+	}
+	return out.String()
+}
+
+func TestOutputRulesStreamMatchesNonStream(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"prefix", "Sure! Here is the answer:\nhello   world"},
+		{"no prefix", "hello\t\t world, Sure! Here is the answer:\n"},
+		{"stop", "first    line<|im_end|>garbage   after stop"},
+		{"unicode", "你好    世界，这是一个   测试<|im_end|>"},
+		{"long", strings.Repeat("word    ", 200) + "end"},
+		{"empty", ""},
+	}
+	p := newOutputProcessor(testOutputRules)
+	for _, tt := range tests {
+		want := p.apply(tt.text)
+		for _, size := range []int{1, 2, 3, 7, 16, 50, 1000} {
+			if got := streamText(p, splitText(tt.text, size), true); got != want {
+				t.Errorf("%s: chunk size %d got %q, want %q", tt.name, size, got, want)
+			}
+		}
+	}
+}
+
+func TestOutputRulesStreamKeepsBoundedWindow(t *testing.T) {
+	p := newOutputProcessor(testOutputRules)
+	s := &outputStreamProcessor{processor: p, choices: make(map[int]*outputChoiceState)}
+	state := &outputChoiceState{}
+	for _, chunk := range splitText(strings.Repeat("abc def ", 1000), 5) {
+		s.step(state, chunk, false)
+		if len(state.pending) > p.window+5 {
+			t.Fatalf("pending content grew to %d bytes, window is %d", len(state.pending), p.window)
+		}
+	}
+}
+
+func TestOutputRulesStreamDropsContentAfterStop(t *testing.T) {
+	p := newOutputProcessor(testOutputRules)
+	got := streamText(p, []string{"answer<|im_", "end|>", "more", " text"}, false)
+	if got != "answer" {
+		t.Errorf("got %q, want %q", got, "answer")
+	}
+}
+
+func TestOutputStreamProcessorFeed(t *testing.T) {
+	s := &outputStreamProcessor{processor: newOutputProcessor(testOutputRules), choices: make(map[int]*outputChoiceState)}
+	text := "Sure! Here is the answer:\nthe   result   is 42<|im_end|>"
+	var chunks []string
+	for _, chunk := range splitText(text, 4) {
+		response := dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{{}}}
+		response.Choices[0].Delta.SetContentString(chunk)
+		data, _ := json.Marshal(response)
+		chunks = append(chunks, string(data))
+	}
+	stop := "stop"
+	response := dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{{FinishReason: &stop}}}
+	data, _ := json.Marshal(response)
+	chunks = append(chunks, string(data))
+
+	var got strings.Builder
+	finished := false
+	for _, chunk := range chunks {
+		for _, item := range s.Feed(chunk) {
+			var out dto.ChatCompletionsStreamResponse
+			if err := json.Unmarshal([]byte(item), &out); err != nil {
+				t.Fatalf("invalid chunk %q: %v", item, err)
+			}
+			for _, choice := range out.Choices {
+				got.WriteString(choice.Delta.GetContentString())
+				finished = finished || choice.FinishReason != nil
+			}
+		}
+	}
+	if len(s.Flush()) != 0 {
+		t.Error("flush after finish reason should not emit content")
+	}
+	if want := "the result is 42"; got.String() != want || !finished {
+		t.Errorf("got %q (finished %v), want %q", got.String(), finished, want)
+	}
+}
@@ -0,0 +1,276 @@
+package openai
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"strings"
+)
+
+// 不同上游返回思考内容的方式不同：reasoning_content 字段、reasoning 字段、内容片段数组中的 thinking 片段，
+// 或者内容开头的 <think></think> 标签。渠道设置 reasoning_format 后先统一解析为思考内容，再按设置的方式返回
+
+const (
+	reasoningFormatContent   = "reasoning_content" // 只在 reasoning_content 字段返回
+	reasoningFormatReasoning = "reasoning"         // 只在 reasoning 字段返回
+	reasoningFormatThinkTags = "think_tags"        // 以 <think> 标签拼接到内容中返回
+	reasoningFormatNone      = "none"              // 不返回思考内容
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// getReasoningFormat 只处理对话请求，未设置或设置无效时返回空
+func getReasoningFormat(info *relaycommon.RelayInfo) string {
+	if info.RelayMode != relayconstant.RelayModeChatCompletions {
+		return ""
+	}
+	format, _ := info.ChannelSetting[constant.ChannelSettingReasoningFormat].(string)
+	switch format {
+	case reasoningFormatContent, reasoningFormatReasoning, reasoningFormatThinkTags, reasoningFormatNone:
+		return format
+	}
+	return ""
+}
+
+// splitThinkTags 内容以 <think> 开头时拆分出思考内容，没有结束标签时全部作为思考内容
+func splitThinkTags(content string) (string, string, bool) {
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, thinkOpenTag) {
+		return "", content, false
+	}
+	body := strings.TrimLeft(trimmed[len(thinkOpenTag):], "\r\n")
+	end := strings.Index(body, thinkCloseTag)
+	if end < 0 {
+		return body, "", true
+	}
+	return body[:end], strings.TrimLeft(body[end+len(thinkCloseTag):], "\r\n"), true
+}
+
+// applyReasoningFormat 按设置的方式改写非流式响应中的思考内容
+func applyReasoningFormat(response *dto.OpenAITextResponse, format string) {
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+		reasoning := message.ReasoningContent
+		if reasoning == "" {
+			reasoning = message.Reasoning
+		}
+		if !message.IsStringContent() {
+			var content dto.FlexibleContent
+			if err := json.Unmarshal(message.Content, &content); err == nil {
+				if thinking, rest := content.ExtractThinking(); thinking != "" {
+					reasoning += thinking
+					if rest == nil {
+						message.SetNullContent()
+					} else {
+						message.Content, _ = json.Marshal(rest)
+					}
+				}
+			}
+		} else if thinking, rest, ok := splitThinkTags(message.StringContent()); ok {
+			reasoning += thinking
+			message.SetStringContent(rest)
+		}
+		message.ReasoningContent = ""
+		message.Reasoning = ""
+		switch format {
+		case reasoningFormatContent:
+			message.ReasoningContent = reasoning
+		case reasoningFormatReasoning:
+			message.Reasoning = reasoning
+		case reasoningFormatThinkTags:
+			if reasoning != "" && message.IsStringContent() {
+				message.SetStringContent(thinkOpenTag + "\n" + reasoning + "\n" + thinkCloseTag + "\n" + message.StringContent())
+			}
+		}
+	}
+}
+
+const (
+	thinkStateStart = iota
+	thinkStateThinking
+	thinkStateContent
+)
+
+type reasoningStreamState struct {
+	state   int
+	pending string
+	// trimLeading 去掉标签后紧跟的换行
+	trimLeading bool
+}
+
+// consume 拆分一段内容中的思考内容和回复内容，内容结尾可能是标签的一部分时暂存
+func (s *reasoningStreamState) consume(content string, final bool) (string, string) {
+	text := s.pending + content
+	s.pending = ""
+	var reasoning, out strings.Builder
+	for text != "" {
+		switch s.state {
+		case thinkStateStart:
+			trimmed := strings.TrimLeft(text, " \t\r\n")
+			if strings.HasPrefix(trimmed, thinkOpenTag) {
+				s.state = thinkStateThinking
+				s.trimLeading = true
+				text = trimmed[len(thinkOpenTag):]
+				continue
+			}
+			if !final && strings.HasPrefix(thinkOpenTag, trimmed) {
+				s.pending = text
+				text = ""
+				continue
+			}
+			s.state = thinkStateContent
+		case thinkStateThinking:
+			if s.trimLeading {
+				if text = strings.TrimLeft(text, "\r\n"); text == "" {
+					continue
+				}
+				s.trimLeading = false
+			}
+			end := strings.Index(text, thinkCloseTag)
+			if end < 0 {
+				keep := 0
+				if !final {
+					keep = partialPrefixLen(text, thinkCloseTag)
+				}
+				reasoning.WriteString(text[:len(text)-keep])
+				s.pending = text[len(text)-keep:]
+				text = ""
+				continue
+			}
+			reasoning.WriteString(text[:end])
+			text = text[end+len(thinkCloseTag):]
+			s.state = thinkStateContent
+			s.trimLeading = true
+		case thinkStateContent:
+			if s.trimLeading {
+				if text = strings.TrimLeft(text, "\r\n"); text == "" {
+					continue
+				}
+				s.trimLeading = false
+			}
+			out.WriteString(text)
+			text = ""
+		}
+	}
+	return reasoning.String(), out.String()
+}
+
+// reasoningStreamNormalizer 按 choice 分别跟踪 <think> 标签的状态
+type reasoningStreamNormalizer struct {
+	format       string
+	choices      map[int]*reasoningStreamState
+	lastResponse *dto.ChatCompletionsStreamResponse
+}
+
+func newReasoningStreamNormalizer(format string) *reasoningStreamNormalizer {
+	if format == "" {
+		return nil
+	}
+	return &reasoningStreamNormalizer{format: format, choices: make(map[int]*reasoningStreamState)}
+}
+
+func (n *reasoningStreamNormalizer) state(index int) *reasoningStreamState {
+	state, ok := n.choices[index]
+	if !ok {
+		state = &reasoningStreamState{}
+		n.choices[index] = state
+	}
+	return state
+}
+
+func (n *reasoningStreamNormalizer) rewriteChoice(choice *dto.ChatCompletionsStreamResponseChoice, final bool) {
+	delta := &choice.Delta
+	reasoning := delta.GetReasoningContent()
+	if delta.Content != nil && !delta.Content.IsString() {
+		thinking, rest := delta.Content.ExtractThinking()
+		reasoning += thinking
+		delta.Content = rest
+	}
+	state := n.state(choice.Index)
+	if (delta.Content != nil && delta.Content.IsString()) || (final && state.pending != "") {
+		content := delta.GetContentString()
+		thinking, text := state.consume(content, final)
+		reasoning += thinking
+		if text != "" || (delta.Content != nil && content == "") {
+			delta.SetContentString(text)
+		} else {
+			delta.Content = nil
+		}
+	}
+	delta.ReasoningContent = nil
+	delta.Reasoning = nil
+	if reasoning == "" {
+		return
+	}
+	switch n.format {
+	// think_tags 由 thinking_to_content 的逻辑转换为 <think> 标签
+	case reasoningFormatContent, reasoningFormatThinkTags:
+		delta.ReasoningContent = &reasoning
+	case reasoningFormatReasoning:
+		delta.Reasoning = &reasoning
+	}
+}
+
+func (n *reasoningStreamNormalizer) marshal(response *dto.ChatCompletionsStreamResponse) string {
+	empty := response.Usage == nil
+	for _, choice := range response.Choices {
+		if choice.FinishReason != nil || choice.Delta.Role != "" || choice.Delta.Content != nil ||
+			choice.Delta.ReasoningContent != nil || choice.Delta.Reasoning != nil || len(choice.Delta.ToolCalls) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return ""
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Feed 处理一个数据块，内容被暂存时可能返回空
+func (n *reasoningStreamNormalizer) Feed(data string) []string {
+	var response dto.ChatCompletionsStreamResponse
+	if err := common.DecodeJsonStr(data, &response); err != nil || len(response.Choices) == 0 {
+		return []string{data}
+	}
+	template := response.Copy()
+	template.Usage = nil
+	n.lastResponse = template
+	for i := range response.Choices {
+		n.rewriteChoice(&response.Choices[i], response.Choices[i].FinishReason != nil)
+	}
+	rewritten := n.marshal(&response)
+	if rewritten == "" {
+		return nil
+	}
+	return []string{rewritten}
+}
+
+// Flush 上游没有返回结束原因就结束时，输出暂存的内容
+func (n *reasoningStreamNormalizer) Flush() []string {
+	if n.lastResponse == nil {
+		return nil
+	}
+	var items []string
+	for index, state := range n.choices {
+		if state.pending == "" {
+			continue
+		}
+		response := n.lastResponse.Copy()
+		choice := dto.ChatCompletionsStreamResponseChoice{Index: index}
+		n.rewriteChoice(&choice, true)
+		response.Choices = []dto.ChatCompletionsStreamResponseChoice{choice}
+		if rewritten := n.marshal(response); rewritten != "" {
+			items = append(items, rewritten)
+		}
+	}
+	return items
+}
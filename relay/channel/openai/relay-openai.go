@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
-	"mime/multipart"
 	"net/http"
 	"one-api/common"
 	"one-api/constant"
@@ -14,13 +12,12 @@ import (
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
-	"os"
+	"one-api/setting/model_setting"
 	"strings"
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/pkg/errors"
 )
 
 func sendStreamData(c *gin.Context, info *relaycommon.RelayInfo, data string, forceFormat bool, thinkToContent bool) error {
@@ -36,6 +33,7 @@ func sendStreamData(c *gin.Context, info *relaycommon.RelayInfo, data string, fo
 	if err := common.DecodeJsonStr(data, &lastStreamResponse); err != nil {
 		return err
 	}
+	service.TagStreamSystemFingerprint(&lastStreamResponse, info.ChannelId)
 
 	if !thinkToContent {
 		return helper.ObjectData(c, lastStreamResponse)
@@ -109,6 +107,9 @@ func OaiStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 		common.LogError(c, "invalid response or response body")
 		return service.OpenAIErrorWrapper(fmt.Errorf("invalid response"), "invalid_response", http.StatusInternalServerError), nil
 	}
+	if isTransparentResponse(info) {
+		return oaiTransparentStreamHandler(c, resp, info)
+	}
 
 	containStreamUsage := false
 	var responseId string
@@ -126,16 +127,25 @@ func OaiStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	if forceFmt, ok := info.ChannelSetting[constant.ForceFormat].(bool); ok {
 		forceFormat = forceFmt
 	}
+	// 附加渠道哈希需要重新序列化响应块
+	if model_setting.GetGlobalSettings().SystemFingerprintChannelTagEnabled {
+		forceFormat = true
+	}
 
 	if think2Content, ok := info.ChannelSetting[constant.ChannelSettingThinkingToContent].(bool); ok {
 		thinkToContent = think2Content
 	}
+	reasoningFormat := getReasoningFormat(info)
+	if reasoningFormat == reasoningFormatThinkTags {
+		thinkToContent = true
+	}
 
 	var (
 		lastStreamData string
 	)
 
-	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+	handleData := func(data string) {
+		data = normalizeStreamFinishReason(data)
 		if lastStreamData != "" {
 			err := handleStreamFormat(c, info, lastStreamData, forceFormat, thinkToContent)
 			if err != nil {
@@ -144,8 +154,62 @@ func OaiStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 		}
 		lastStreamData = data
 		streamItems = append(streamItems, data)
+	}
+
+	// 工具调用解析后再对文本内容做后处理
+	outputProcessor := newOutputStreamProcessor(info)
+	handleItem := func(data string) {
+		if outputProcessor == nil {
+			handleData(data)
+			return
+		}
+		for _, item := range outputProcessor.Feed(data) {
+			handleData(item)
+		}
+	}
+	toolCallMerger := service.NewToolCallDeltaMerger()
+	identity := newStreamIdentity(info)
+	toolCallParser := newToolCallStreamParser(info)
+	handleParsed := func(data string) {
+		if toolCallParser == nil {
+			handleItem(data)
+			return
+		}
+		for _, item := range toolCallParser.Feed(data) {
+			handleItem(item)
+		}
+	}
+	// 先拆分思考内容，思考内容中的工具调用标记不解析
+	reasoningNormalizer := newReasoningStreamNormalizer(reasoningFormat)
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		data = toolCallMerger.Feed(data)
+		if identity != nil {
+			data = identity.Feed(data)
+		}
+		if reasoningNormalizer == nil {
+			handleParsed(data)
+			return true
+		}
+		for _, item := range reasoningNormalizer.Feed(data) {
+			handleParsed(item)
+		}
 		return true
 	})
+	if reasoningNormalizer != nil {
+		for _, item := range reasoningNormalizer.Flush() {
+			handleParsed(item)
+		}
+	}
+	if toolCallParser != nil {
+		for _, item := range toolCallParser.Flush() {
+			handleItem(item)
+		}
+	}
+	if outputProcessor != nil {
+		for _, item := range outputProcessor.Flush() {
+			handleData(item)
+		}
+	}
 
 	shouldSendLastResp := true
 	var lastStreamResponse dto.ChatCompletionsStreamResponse
@@ -155,6 +219,7 @@ func OaiStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 		createAt = lastStreamResponse.Created
 		systemFingerprint = lastStreamResponse.GetSystemFingerprint()
 		model = lastStreamResponse.Model
+		recordServiceTier(c, lastStreamResponse.ServiceTier)
 		if service.ValidUsage(lastStreamResponse.Usage) {
 			containStreamUsage = true
 			usage = lastStreamResponse.Usage
@@ -169,7 +234,14 @@ func OaiStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 		}
 	}
 
-	if shouldSendLastResp {
+	if info.RelayFormat == relaycommon.RelayFormatClaude {
+		// 转换最后一个块的内容，结束事件由 handleFinalResponse 发送
+		if lastStreamData != "" {
+			if err := handleStreamFormat(c, info, lastStreamData, forceFormat, thinkToContent); err != nil {
+				common.SysError("error handling stream format: " + err.Error())
+			}
+		}
+	} else if shouldSendLastResp {
 		sendStreamData(c, info, lastStreamData, forceFormat, thinkToContent)
 		//err = handleStreamFormat(c, info, lastStreamData, forceFormat, thinkToContent)
 	}
@@ -215,9 +287,12 @@ func OpenaiHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayI
 			StatusCode: resp.StatusCode,
 		}, nil
 	}
+	recordServiceTier(c, simpleResponse.ServiceTier)
 
+	// 透传模式不修改响应内容，只解析用量
+	transparent := isTransparentResponse(info)
 	forceFormat := false
-	if forceFmt, ok := info.ChannelSetting[constant.ForceFormat].(bool); ok {
+	if forceFmt, ok := info.ChannelSetting[constant.ForceFormat].(bool); ok && !transparent {
 		forceFormat = forceFmt
 	}
 
@@ -234,6 +309,32 @@ func OpenaiHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayI
 		}
 	}
 
+	if !transparent {
+		for i, choice := range simpleResponse.Choices {
+			if finishReason := helper.DefaultFinishReasons.Normalize(choice.FinishReason); finishReason != choice.FinishReason {
+				simpleResponse.Choices[i].FinishReason = finishReason
+				forceFormat = true
+			}
+		}
+		if fillResponseIdentity(info, &simpleResponse) {
+			forceFormat = true
+		}
+		if format := getReasoningFormat(info); format != "" {
+			applyReasoningFormat(&simpleResponse, format)
+			forceFormat = true
+		}
+		if profile := getToolCallProfile(info); profile != nil && applyToolCallProfile(&simpleResponse, profile) {
+			forceFormat = true
+		}
+		if processor := getOutputProcessor(info); processor != nil && applyOutputRules(&simpleResponse, processor) {
+			forceFormat = true
+		}
+		if model_setting.GetGlobalSettings().SystemFingerprintChannelTagEnabled {
+			simpleResponse.SystemFingerprint = service.TagSystemFingerprint(simpleResponse.SystemFingerprint, info.ChannelId)
+			forceFormat = true
+		}
+	}
+
 	switch info.RelayFormat {
 	case relaycommon.RelayFormatOpenAI:
 		if forceFormat {
@@ -286,21 +387,33 @@ func OpenaiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	for k, v := range resp.Header {
 		c.Writer.Header().Set(k, v[0])
 	}
+	// 音频边生成边返回，关闭反向代理的缓冲，收到数据后立即写给客户端，不在内存中缓存整个文件
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
 	c.Writer.WriteHeader(resp.StatusCode)
 	c.Writer.WriteHeaderNow()
-	_, err := io.Copy(c.Writer, resp.Body)
-	if err != nil {
-		common.LogError(c, err.Error())
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				common.LogError(c, "write audio response failed: "+writeErr.Error())
+				break
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			if err != io.EOF {
+				common.LogError(c, "read audio response failed: "+err.Error())
+			}
+			break
+		}
 	}
 	return nil, usage
 }
 
 func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, responseFormat string) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
-	// count tokens by audio file duration
-	audioTokens, err := countAudioTokens(c)
-	if err != nil {
-		return service.OpenAIErrorWrapper(err, "count_audio_tokens_failed", http.StatusInternalServerError), nil
-	}
+	// 音频时长在请求前已计算
+	audioTokens := info.PromptTokens
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
@@ -332,47 +445,6 @@ func OpenaiSTTHandler(c *gin.Context, resp *http.Response, info *relaycommon.Rel
 	return nil, usage
 }
 
-func countAudioTokens(c *gin.Context) (int, error) {
-	body, err := common.GetRequestBody(c)
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	var reqBody struct {
-		File *multipart.FileHeader `form:"file" binding:"required"`
-	}
-	c.Request.Body = io.NopCloser(bytes.NewReader(body))
-	if err = c.ShouldBind(&reqBody); err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	reqFp, err := reqBody.File.Open()
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	tmpFp, err := os.CreateTemp("", "audio-*")
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-	defer os.Remove(tmpFp.Name())
-
-	_, err = io.Copy(tmpFp, reqFp)
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-	if err = tmpFp.Close(); err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	duration, err := common.GetAudioDuration(c.Request.Context(), tmpFp.Name())
-	if err != nil {
-		return 0, errors.WithStack(err)
-	}
-
-	return int(math.Round(math.Ceil(duration) / 60.0 * 1000)), nil // 1 minute 相当于 1k tokens
-}
-
 func OpenaiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.RealtimeUsage) {
 	if info == nil || info.ClientWs == nil || info.TargetWs == nil {
 		return service.OpenAIErrorWrapper(fmt.Errorf("invalid websocket connection"), "invalid_connection", http.StatusBadRequest), nil
@@ -480,7 +552,10 @@ func OpenaiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*dto.Op
 				}
 
 				if realtimeEvent.Type == dto.RealtimeEventTypeResponseDone {
-					realtimeUsage := realtimeEvent.Response.Usage
+					var realtimeUsage *dto.RealtimeUsage
+					if realtimeEvent.Response != nil {
+						realtimeUsage = realtimeEvent.Response.Usage
+					}
 					if realtimeUsage != nil {
 						usage.TotalTokens += realtimeUsage.TotalTokens
 						usage.InputTokens += realtimeUsage.InputTokens
@@ -634,15 +709,73 @@ func OpenaiHandlerWithUsage(c *gin.Context, resp *http.Response, info *relaycomm
 		return service.OpenAIErrorWrapper(err, "parse_response_body_failed", http.StatusInternalServerError), nil
 	}
 	// format
-	if usageResp.InputTokens > 0 {
-		usageResp.PromptTokens += usageResp.InputTokens
+	usage := &usageResp.Usage
+	if usage.InputTokens > 0 {
+		usage.PromptTokens += usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		usage.CompletionTokens += usage.OutputTokens
+	}
+	if usage.InputTokensDetails != nil {
+		usage.PromptTokensDetails.ImageTokens += usage.InputTokensDetails.ImageTokens
+		usage.PromptTokensDetails.TextTokens += usage.InputTokensDetails.TextTokens
+	}
+	return nil, usage
+}
+
+// OpenaiModerationHandler 兼容 OpenAI 和 Mistral 的内容审核响应，Mistral 不返回 flagged 时根据类别补全，
+// 被标记的类别写入上下文用于记录日志
+func OpenaiModerationHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
 	}
-	if usageResp.OutputTokens > 0 {
-		usageResp.CompletionTokens += usageResp.OutputTokens
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var moderationResponse dto.ModerationResponse
+	err = common.DecodeJson(responseBody, &moderationResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
 	}
-	if usageResp.InputTokensDetails != nil {
-		usageResp.PromptTokensDetails.ImageTokens += usageResp.InputTokensDetails.ImageTokens
-		usageResp.PromptTokensDetails.TextTokens += usageResp.InputTokensDetails.TextTokens
+	rewrite := false
+	for i := range moderationResponse.Results {
+		result := &moderationResponse.Results[i]
+		if result.Flagged != nil {
+			continue
+		}
+		flagged := false
+		for _, v := range result.Categories {
+			flagged = flagged || v
+		}
+		result.Flagged = &flagged
+		rewrite = true
+	}
+	if rewrite {
+		responseBody, err = json.Marshal(moderationResponse)
+		if err != nil {
+			return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+		}
 	}
-	return nil, &usageResp.Usage
+	c.Set(constant.ContextKeyModerationFlaggedCategories, moderationResponse.FlaggedCategories())
+
+	for k, v := range resp.Header {
+		c.Writer.Header().Set(k, v[0])
+	}
+	c.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", len(responseBody)))
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, err = c.Writer.Write(responseBody)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "write_response_body_failed", http.StatusInternalServerError), nil
+	}
+
+	usage := &dto.Usage{
+		PromptTokens: info.PromptTokens,
+		TotalTokens:  info.PromptTokens,
+	}
+	if moderationResponse.Usage != nil && moderationResponse.Usage.PromptTokens > 0 {
+		usage = moderationResponse.Usage
+	}
+	return nil, usage
 }
@@ -58,13 +58,13 @@ func OaiResponsesHandler(c *gin.Context, resp *http.Response, info *relaycommon.
 	}
 	resp.Body.Close()
 	// compute usage
-	usage := dto.Usage{}
-	usage.PromptTokens = responsesResponse.Usage.InputTokens
-	usage.CompletionTokens = responsesResponse.Usage.OutputTokens
-	usage.TotalTokens = responsesResponse.Usage.TotalTokens
+	recordServiceTier(c, responsesResponse.ServiceTier)
+	usage := responsesUsageToChatUsage(responsesResponse.Usage)
 	// 解析 Tools 用量
 	for _, tool := range responsesResponse.Tools {
-		info.ResponsesUsageInfo.BuiltInTools[tool.Type].CallCount++
+		if builtInTool, ok := info.ResponsesUsageInfo.BuiltInTools[tool.Type]; ok {
+			builtInTool.CallCount++
+		}
 	}
 	return nil, &usage
 }
@@ -86,9 +86,11 @@ func OaiResponsesStreamHandler(c *gin.Context, resp *http.Response, info *relayc
 			sendResponsesStreamData(c, streamResponse, data)
 			switch streamResponse.Type {
 			case "response.completed":
-				usage.PromptTokens = streamResponse.Response.Usage.InputTokens
-				usage.CompletionTokens = streamResponse.Response.Usage.OutputTokens
-				usage.TotalTokens = streamResponse.Response.Usage.TotalTokens
+				if streamResponse.Response != nil {
+					responsesUsage := responsesUsageToChatUsage(streamResponse.Response.Usage)
+					usage = &responsesUsage
+					recordServiceTier(c, streamResponse.Response.ServiceTier)
+				}
 			case "response.output_text.delta":
 				// 处理输出文本
 				responseTextBuilder.WriteString(streamResponse.Delta)
@@ -97,7 +99,9 @@ func OaiResponsesStreamHandler(c *gin.Context, resp *http.Response, info *relayc
 				if streamResponse.Item != nil {
 					switch streamResponse.Item.Type {
 					case dto.BuildInCallWebSearchCall:
-						info.ResponsesUsageInfo.BuiltInTools[dto.BuildInToolWebSearchPreview].CallCount++
+						if webSearchTool, ok := info.ResponsesUsageInfo.BuiltInTools[dto.BuildInToolWebSearchPreview]; ok {
+							webSearchTool.CallCount++
+						}
 					}
 				}
 			}
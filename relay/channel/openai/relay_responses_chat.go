@@ -0,0 +1,383 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/setting/model_setting"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上游只提供 /v1/responses 时（渠道设置 chat_via_responses），把 /v1/chat/completions 请求转换为 Responses 格式，
+// 响应和流式事件再转换回 chat.completion 格式
+
+func useResponsesAPI(info *relaycommon.RelayInfo) bool {
+	if info.RelayMode != relayconstant.RelayModeChatCompletions || info.RelayFormat != relaycommon.RelayFormatOpenAI ||
+		info.ChannelType == common.ChannelTypeAzure {
+		return false
+	}
+	// 透传请求体时无法转换请求格式
+	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+		return false
+	}
+	enabled, _ := info.ChannelSetting[constant.ChannelSettingChatViaResponses].(bool)
+	return enabled
+}
+
+func chatContentToResponsesContent(message *dto.Message, role string) any {
+	if message.IsStringContent() {
+		return message.StringContent()
+	}
+	textType := "input_text"
+	if role == "assistant" {
+		textType = "output_text"
+	}
+	parts := make([]map[string]any, 0)
+	for _, content := range message.ParseContent() {
+		switch content.Type {
+		case dto.ContentTypeText:
+			parts = append(parts, map[string]any{"type": textType, "text": content.Text})
+		case dto.ContentTypeImageURL:
+			image := content.GetImageMedia()
+			parts = append(parts, map[string]any{"type": "input_image", "image_url": image.Url, "detail": image.Detail})
+		case dto.ContentTypeFile:
+			file := content.GetFile()
+			part := map[string]any{"type": "input_file"}
+			if file.FileId != "" {
+				part["file_id"] = file.FileId
+			} else {
+				part["filename"] = file.FileName
+				part["file_data"] = file.FileData
+			}
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func chatToResponsesRequest(request *dto.GeneralOpenAIRequest) (*dto.OpenAIResponsesRequest, error) {
+	input := make([]dto.ResponsesInputItem, 0, len(request.Messages))
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		switch message.Role {
+		case "tool":
+			input = append(input, dto.ResponsesInputItem{
+				Type:   "function_call_output",
+				CallId: message.ToolCallId,
+				Output: message.StringContent(),
+			})
+		case "assistant":
+			if message.Content != nil && string(message.Content) != "null" && message.StringContent() != "" {
+				input = append(input, dto.ResponsesInputItem{
+					Role:    "assistant",
+					Content: chatContentToResponsesContent(message, "assistant"),
+				})
+			}
+			for _, toolCall := range message.ParseToolCalls() {
+				input = append(input, dto.ResponsesInputItem{
+					Type:      dto.ResponsesOutputTypeFunctionCall,
+					CallId:    toolCall.ID,
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				})
+			}
+		default:
+			input = append(input, dto.ResponsesInputItem{
+				Role:    message.Role,
+				Content: chatContentToResponsesContent(message, message.Role),
+			})
+		}
+	}
+	inputJson, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	responsesRequest := &dto.OpenAIResponsesRequest{
+		Model:  request.Model,
+		Input:  inputJson,
+		Stream: request.Stream,
+		TopP:   request.TopP,
+		User:   request.User,
+	}
+	if request.Temperature != nil {
+		responsesRequest.Temperature = *request.Temperature
+	}
+	if request.MaxCompletionTokens != 0 {
+		responsesRequest.MaxOutputTokens = request.MaxCompletionTokens
+	} else {
+		responsesRequest.MaxOutputTokens = request.MaxTokens
+	}
+	if request.ReasoningEffort != "" {
+		responsesRequest.Reasoning = &dto.Reasoning{Effort: request.ReasoningEffort}
+	}
+	if request.ParallelTooCalls != nil {
+		responsesRequest.ParallelToolCalls = *request.ParallelTooCalls
+	}
+	for _, tool := range request.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		parameters, _ := json.Marshal(tool.Function.Parameters)
+		responsesRequest.Tools = append(responsesRequest.Tools, dto.ResponsesToolsCall{
+			Type:        "function",
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  parameters,
+		})
+	}
+	if request.ToolChoice != nil {
+		toolChoice := request.ToolChoice
+		// {"type": "function", "function": {"name": "..."}} 转换为 {"type": "function", "name": "..."}
+		if choice, ok := toolChoice.(map[string]any); ok {
+			if function, ok := choice["function"].(map[string]any); ok {
+				toolChoice = map[string]any{"type": "function", "name": function["name"]}
+			}
+		}
+		responsesRequest.ToolChoice, _ = json.Marshal(toolChoice)
+	}
+	if request.ResponseFormat != nil && request.ResponseFormat.Type != "" && request.ResponseFormat.Type != "text" {
+		format := map[string]any{"type": request.ResponseFormat.Type}
+		if schema := request.ResponseFormat.JsonSchema; schema != nil {
+			format["name"] = schema.Name
+			format["schema"] = schema.Schema
+			if schema.Description != "" {
+				format["description"] = schema.Description
+			}
+			if schema.Strict != nil {
+				format["strict"] = schema.Strict
+			}
+		}
+		responsesRequest.Text, _ = json.Marshal(map[string]any{"format": format})
+	}
+	return responsesRequest, nil
+}
+
+func responsesUsageToChatUsage(responsesUsage *dto.Usage) dto.Usage {
+	usage := dto.Usage{}
+	if responsesUsage == nil {
+		return usage
+	}
+	usage.PromptTokens = responsesUsage.InputTokens
+	usage.CompletionTokens = responsesUsage.OutputTokens
+	usage.TotalTokens = responsesUsage.TotalTokens
+	if responsesUsage.InputTokensDetails != nil {
+		usage.PromptTokensDetails.CachedTokens = responsesUsage.InputTokensDetails.CachedTokens
+	}
+	return usage
+}
+
+func responsesFinishReason(response *dto.OpenAIResponsesResponse, hasToolCalls bool) string {
+	if hasToolCalls {
+		return constant.FinishReasonToolCalls
+	}
+	if response.Status == "incomplete" && response.IncompleteDetails != nil && response.IncompleteDetails.Reason == "max_output_tokens" {
+		return constant.FinishReasonLength
+	}
+	return constant.FinishReasonStop
+}
+
+func responsesToChatResponse(response *dto.OpenAIResponsesResponse) *dto.OpenAITextResponse {
+	var content, reasoning strings.Builder
+	toolCalls := make([]dto.ToolCallResponse, 0)
+	for _, output := range response.Output {
+		switch output.Type {
+		case dto.ResponsesOutputTypeMessage:
+			for _, part := range output.Content {
+				if part.Type == "output_text" {
+					content.WriteString(part.Text)
+				}
+			}
+		case dto.ResponsesOutputTypeReasoning:
+			for _, summary := range output.Summary {
+				reasoning.WriteString(summary.Text)
+			}
+		case dto.ResponsesOutputTypeFunctionCall:
+			toolCalls = append(toolCalls, dto.ToolCallResponse{
+				ID:   output.CallId,
+				Type: "function",
+				Function: dto.FunctionResponse{
+					Name:      output.Name,
+					Arguments: output.Arguments,
+				},
+			})
+		}
+	}
+	message := dto.Message{
+		Role:             "assistant",
+		ReasoningContent: reasoning.String(),
+	}
+	message.SetStringContent(content.String())
+	if len(toolCalls) > 0 {
+		message.SetToolCalls(toolCalls)
+	}
+	return &dto.OpenAITextResponse{
+		Id:      response.ID,
+		Object:  "chat.completion",
+		Created: int64(response.CreatedAt),
+		Model:   response.Model,
+		Choices: []dto.OpenAITextResponseChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: responsesFinishReason(response, len(toolCalls) > 0),
+		}},
+		Usage: responsesUsageToChatUsage(response.Usage),
+	}
+}
+
+// ResponsesToChatHandler 把 /v1/responses 的响应转换为 chat.completion
+func ResponsesToChatHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	resp.Body.Close()
+	var responsesResponse dto.OpenAIResponsesResponse
+	if err = common.DecodeJson(responseBody, &responsesResponse); err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if responsesResponse.Error != nil {
+		return &dto.OpenAIErrorWithStatusCode{
+			Error: dto.OpenAIError{
+				Message: responsesResponse.Error.Message,
+				Type:    "openai_error",
+				Code:    responsesResponse.Error.Code,
+			},
+			StatusCode: resp.StatusCode,
+		}, nil
+	}
+	chatResponse := responsesToChatResponse(&responsesResponse)
+	if chatResponse.Usage.TotalTokens == 0 {
+		message := chatResponse.Choices[0].Message
+		completionTokens, _ := service.CountTextToken(message.StringContent()+message.ReasoningContent, info.UpstreamModelName)
+		chatResponse.Usage = dto.Usage{
+			PromptTokens:     info.PromptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      info.PromptTokens + completionTokens,
+		}
+	}
+	c.JSON(http.StatusOK, chatResponse)
+	return nil, &chatResponse.Usage
+}
+
+// ResponsesToChatStreamHandler 把 /v1/responses 的流式事件转换为 chat.completion.chunk：
+// output_text.delta 转换为 content，reasoning_summary_text.delta 转换为 reasoning_content，
+// function_call 项转换为 tool_calls，response.completed 中的用量用于计费
+func ResponsesToChatStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	if resp == nil || resp.Body == nil {
+		common.LogError(c, "invalid response or response body")
+		return service.OpenAIErrorWrapper(fmt.Errorf("invalid response"), "invalid_response", http.StatusInternalServerError), nil
+	}
+	responseId := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
+	model := info.UpstreamModelName
+	var usage *dto.Usage
+	var responseTextBuilder strings.Builder
+	toolIndexes := make(map[string]int)
+	finishReason := ""
+
+	sendChunk := func(delta dto.ChatCompletionsStreamResponseChoiceDelta) {
+		chunk := dto.ChatCompletionsStreamResponse{
+			Id:      responseId,
+			Object:  "chat.completion.chunk",
+			Created: createAt,
+			Model:   model,
+			Choices: []dto.ChatCompletionsStreamResponseChoice{{Delta: delta}},
+		}
+		if err := helper.ObjectData(c, chunk); err != nil {
+			common.LogError(c, "send stream chunk failed: "+err.Error())
+		}
+	}
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var event dto.ResponsesStreamResponse
+		if err := common.DecodeJsonStr(data, &event); err != nil {
+			common.LogError(c, "unmarshal responses stream event failed: "+err.Error())
+			return true
+		}
+		switch event.Type {
+		case "response.created":
+			if event.Response != nil {
+				responseId = event.Response.ID
+				if event.Response.CreatedAt != 0 {
+					createAt = int64(event.Response.CreatedAt)
+				}
+				model = common.GetStringIfEmpty(event.Response.Model, model)
+			}
+			sendChunk(dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"})
+		case "response.output_text.delta":
+			responseTextBuilder.WriteString(event.Delta)
+			delta := dto.ChatCompletionsStreamResponseChoiceDelta{}
+			delta.SetContentString(event.Delta)
+			sendChunk(delta)
+		case "response.reasoning_summary_text.delta":
+			responseTextBuilder.WriteString(event.Delta)
+			delta := dto.ChatCompletionsStreamResponseChoiceDelta{}
+			delta.ReasoningContent = &event.Delta
+			sendChunk(delta)
+		case dto.ResponsesOutputTypeItemAdded:
+			if event.Item == nil || event.Item.Type != dto.ResponsesOutputTypeFunctionCall {
+				return true
+			}
+			index := len(toolIndexes)
+			toolIndexes[event.Item.ID] = index
+			toolCall := dto.ToolCallResponse{
+				ID:   event.Item.CallId,
+				Type: "function",
+				Function: dto.FunctionResponse{
+					Name:      event.Item.Name,
+					Arguments: event.Item.Arguments,
+				},
+			}
+			toolCall.SetIndex(index)
+			sendChunk(dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{toolCall}})
+		case "response.function_call_arguments.delta":
+			index, ok := toolIndexes[event.ItemId]
+			if !ok {
+				return true
+			}
+			responseTextBuilder.WriteString(event.Delta)
+			toolCall := dto.ToolCallResponse{
+				Function: dto.FunctionResponse{Arguments: event.Delta},
+			}
+			toolCall.SetIndex(index)
+			sendChunk(dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{toolCall}})
+		case "response.completed", "response.incomplete", "response.failed":
+			if event.Response != nil {
+				if event.Response.Usage != nil {
+					responsesUsage := responsesUsageToChatUsage(event.Response.Usage)
+					usage = &responsesUsage
+				}
+				finishReason = responsesFinishReason(event.Response, len(toolIndexes) > 0)
+			}
+		}
+		return true
+	})
+
+	if usage == nil || usage.TotalTokens == 0 {
+		usage, _ = service.ResponseText2Usage(responseTextBuilder.String(), info.UpstreamModelName, info.PromptTokens)
+	}
+	if finishReason == "" {
+		finishReason = constant.FinishReasonStop
+		if len(toolIndexes) > 0 {
+			finishReason = constant.FinishReasonToolCalls
+		}
+	}
+	if err := helper.ObjectData(c, helper.GenerateStopResponse(responseId, createAt, model, finishReason)); err != nil {
+		common.LogError(c, "send stream chunk failed: "+err.Error())
+	}
+	if info.ShouldIncludeUsage {
+		helper.ObjectData(c, helper.GenerateFinalUsageResponse(responseId, createAt, model, *usage))
+	}
+	helper.Done(c)
+	return nil, usage
+}
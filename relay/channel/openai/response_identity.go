@@ -0,0 +1,93 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+)
+
+// 部分自建的上游服务返回的对话响应缺少 id、object 或 created，严格校验的 SDK 会因此报错，
+// 返回给客户端前补全这些字段，流式响应的每个数据块使用相同的 id 和 created
+
+func newResponseId() string {
+	return fmt.Sprintf("chatcmpl-%s", common.GetUUID())
+}
+
+// fillResponseIdentity 补全非流式对话响应缺少的字段，返回是否有修改
+func fillResponseIdentity(info *relaycommon.RelayInfo, response *dto.OpenAITextResponse) bool {
+	if info.RelayMode != relayconstant.RelayModeChatCompletions {
+		return false
+	}
+	changed := false
+	if response.Id == "" {
+		response.Id = newResponseId()
+		changed = true
+	}
+	if response.Object == "" {
+		response.Object = "chat.completion"
+		changed = true
+	}
+	if response.Created == 0 {
+		response.Created = common.GetTimestamp()
+		changed = true
+	}
+	return changed
+}
+
+type streamIdentity struct {
+	id      string
+	created int64
+}
+
+func newStreamIdentity(info *relaycommon.RelayInfo) *streamIdentity {
+	if info.RelayMode != relayconstant.RelayModeChatCompletions {
+		return nil
+	}
+	return &streamIdentity{}
+}
+
+// Feed 补全数据块缺少的字段，字段完整时原样返回
+func (s *streamIdentity) Feed(data string) string {
+	var identity struct {
+		Id      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+	}
+	if err := common.DecodeJsonStr(data, &identity); err != nil {
+		return data
+	}
+	if s.id == "" {
+		s.id = identity.Id
+		s.created = identity.Created
+	}
+	if identity.Id != "" && identity.Object != "" && identity.Created != 0 {
+		return data
+	}
+	if s.id == "" {
+		s.id = newResponseId()
+	}
+	if s.created == 0 {
+		s.created = common.GetTimestamp()
+	}
+	var response dto.ChatCompletionsStreamResponse
+	if err := common.DecodeJsonStr(data, &response); err != nil {
+		return data
+	}
+	if response.Id == "" {
+		response.Id = s.id
+	}
+	if response.Object == "" {
+		response.Object = "chat.completion.chunk"
+	}
+	if response.Created == 0 {
+		response.Created = s.created
+	}
+	rewritten, err := json.Marshal(response)
+	if err != nil {
+		return data
+	}
+	return string(rewritten)
+}
@@ -0,0 +1,467 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/model_setting"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 部分开源模型不支持结构化的 tool_calls，只会在文本中输出约定格式的工具调用，
+// 请求时把 tools 写入系统提示、把历史消息中的工具调用和结果转换为文本，响应时再把文本中的工具调用解析为 tool_calls
+
+type toolCallProfile struct {
+	start string
+	end   string
+	// parse 解析 start 与 end 之间的内容，解析失败时原样作为文本输出
+	parse        func(block string) ([]dto.ToolCallResponse, error)
+	formatCall   func(name string, arguments string) string
+	formatResult func(name string, content string) string
+	// instruction 系统提示中的调用说明，%s 为工具定义
+	instruction string
+}
+
+var toolCallProfiles = map[string]*toolCallProfile{
+	model_setting.ToolCallProfileHermes: {
+		start: "<tool_call>",
+		end:   "</tool_call>",
+		parse: parseJSONToolCalls,
+		formatCall: func(name string, arguments string) string {
+			return fmt.Sprintf("<tool_call>\n{\"name\": %q, \"arguments\": %s}\n</tool_call>", name, argumentsJSON(arguments))
+		},
+		formatResult: func(name string, content string) string {
+			return fmt.Sprintf("<tool_response>\n%s\n</tool_response>", content)
+		},
+		instruction: "# Tools\n\nYou may call one or more functions to assist with the user query.\n\n" +
+			"You are provided with function signatures within <tools></tools> XML tags:\n<tools>\n%s\n</tools>\n\n" +
+			"For each function call, return a json object with function name and arguments within <tool_call></tool_call> XML tags:\n" +
+			"<tool_call>\n{\"name\": <function-name>, \"arguments\": <args-json-object>}\n</tool_call>",
+	},
+	model_setting.ToolCallProfileXML: {
+		start:      "<function_calls>",
+		end:        "</function_calls>",
+		parse:      parseXMLToolCalls,
+		formatCall: formatXMLToolCall,
+		formatResult: func(name string, content string) string {
+			return fmt.Sprintf("<function_results>\n<result>\n<tool_name>%s</tool_name>\n<stdout>\n%s\n</stdout>\n</result>\n</function_results>", name, content)
+		},
+		instruction: "You can call the functions below by writing a <function_calls> block in your reply:\n" +
+			"<function_calls>\n<invoke name=\"FUNCTION_NAME\">\n<parameter name=\"PARAMETER_NAME\">PARAMETER_VALUE</parameter>\n</invoke>\n</function_calls>\n\n" +
+			"Strings and scalar values are written as is, lists and objects are written as JSON.\n\n" +
+			"The available functions in JSON Schema format:\n<functions>\n%s\n</functions>",
+	},
+	model_setting.ToolCallProfileMarkdown: {
+		start: "```json",
+		end:   "```",
+		parse: parseJSONToolCalls,
+		formatCall: func(name string, arguments string) string {
+			return fmt.Sprintf("```json\n{\"name\": %q, \"arguments\": %s}\n```", name, argumentsJSON(arguments))
+		},
+		formatResult: func(name string, content string) string {
+			return fmt.Sprintf("Function `%s` returned:\n```\n%s\n```", name, content)
+		},
+		instruction: "You have access to the following functions:\n\n%s\n\n" +
+			"To call a function, reply with a JSON code block in the following format:\n" +
+			"```json\n{\"name\": \"<function-name>\", \"arguments\": <args-json-object>}\n```",
+	},
+}
+
+func getToolCallProfile(info *relaycommon.RelayInfo) *toolCallProfile {
+	if info.ToolCallProfile == "" {
+		return nil
+	}
+	return toolCallProfiles[info.ToolCallProfile]
+}
+
+func newToolCall(name string, arguments string) dto.ToolCallResponse {
+	return dto.ToolCallResponse{
+		ID:   fmt.Sprintf("call_%s", common.GetUUID()),
+		Type: "function",
+		Function: dto.FunctionResponse{
+			Name:      name,
+			Arguments: arguments,
+		},
+	}
+}
+
+// argumentsJSON 保证参数是合法的 JSON 对象，便于写入提示
+func argumentsJSON(arguments string) string {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return "{}"
+	}
+	return arguments
+}
+
+type textToolCall struct {
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+func parseJSONToolCalls(block string) ([]dto.ToolCallResponse, error) {
+	block = strings.TrimSpace(block)
+	var calls []textToolCall
+	if strings.HasPrefix(block, "[") {
+		if err := json.Unmarshal([]byte(block), &calls); err != nil {
+			return nil, err
+		}
+	} else {
+		var call textToolCall
+		if err := json.Unmarshal([]byte(block), &call); err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	result := make([]dto.ToolCallResponse, 0, len(calls))
+	for _, call := range calls {
+		if call.Name == "" {
+			return nil, errors.New("function name is empty")
+		}
+		arguments := call.Arguments
+		if len(arguments) == 0 {
+			arguments = call.Parameters
+		}
+		result = append(result, newToolCall(call.Name, argumentsString(arguments)))
+	}
+	return result, nil
+}
+
+func argumentsString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "{}"
+	}
+	// 部分模型输出的参数已经是 JSON 字符串
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+var (
+	xmlInvokeRegex    = regexp.MustCompile(`(?s)<invoke name="([^"]+)">(.*?)</invoke>`)
+	xmlParameterRegex = regexp.MustCompile(`(?s)<parameter name="([^"]+)">(.*?)</parameter>`)
+)
+
+func parseXMLToolCalls(block string) ([]dto.ToolCallResponse, error) {
+	invokes := xmlInvokeRegex.FindAllStringSubmatch(block, -1)
+	if len(invokes) == 0 {
+		return nil, errors.New("no invoke found")
+	}
+	result := make([]dto.ToolCallResponse, 0, len(invokes))
+	for _, invoke := range invokes {
+		arguments := make(map[string]any)
+		for _, parameter := range xmlParameterRegex.FindAllStringSubmatch(invoke[2], -1) {
+			value := html.UnescapeString(strings.TrimSpace(parameter[2]))
+			var parsed any
+			if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+				arguments[parameter[1]] = parsed
+			} else {
+				arguments[parameter[1]] = value
+			}
+		}
+		argumentsBytes, err := json.Marshal(arguments)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, newToolCall(invoke[1], string(argumentsBytes)))
+	}
+	return result, nil
+}
+
+func formatXMLToolCall(name string, arguments string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("<function_calls>\n<invoke name=\"%s\">\n", name))
+	var parameters map[string]any
+	if err := json.Unmarshal([]byte(arguments), &parameters); err == nil {
+		keys := make([]string, 0, len(parameters))
+		for key := range parameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := parameters[key].(string)
+			if !ok {
+				valueBytes, _ := json.Marshal(parameters[key])
+				value = string(valueBytes)
+			}
+			builder.WriteString(fmt.Sprintf("<parameter name=\"%s\">%s</parameter>\n", key, value))
+		}
+	}
+	builder.WriteString("</invoke>\n</function_calls>")
+	return builder.String()
+}
+
+// convertToolsToPrompt 把请求中的 tools 写入系统提示，并把历史消息中的工具调用和结果转换为模型输出的文本格式
+func convertToolsToPrompt(request *dto.GeneralOpenAIRequest, profile *toolCallProfile) {
+	prompt := ""
+	if choice, ok := request.ToolChoice.(string); !ok || choice != "none" {
+		definitions := make([]string, 0, len(request.Tools))
+		for _, tool := range request.Tools {
+			definition, _ := json.Marshal(tool)
+			definitions = append(definitions, string(definition))
+		}
+		prompt = fmt.Sprintf(profile.instruction, strings.Join(definitions, "\n"))
+		switch choice := request.ToolChoice.(type) {
+		case string:
+			if choice == "required" {
+				prompt += "\n\nYou must call at least one function."
+			}
+		case map[string]any:
+			if function, ok := choice["function"].(map[string]any); ok {
+				prompt += fmt.Sprintf("\n\nYou must call the function %v.", function["name"])
+			}
+		}
+	}
+
+	messages := make([]dto.Message, 0, len(request.Messages)+1)
+	toolNames := make(map[string]string)
+	lastIsToolResult := false
+	for _, message := range request.Messages {
+		switch {
+		case message.Role == "assistant" && message.ToolCalls != nil:
+			var builder strings.Builder
+			builder.WriteString(message.StringContent())
+			for _, call := range message.ParseToolCalls() {
+				toolNames[call.ID] = call.Function.Name
+				if builder.Len() > 0 {
+					builder.WriteString("\n")
+				}
+				builder.WriteString(profile.formatCall(call.Function.Name, call.Function.Arguments))
+			}
+			message.ToolCalls = nil
+			message.SetStringContent(builder.String())
+			messages = append(messages, message)
+			lastIsToolResult = false
+		case message.Role == "tool":
+			result := profile.formatResult(toolNames[message.ToolCallId], message.StringContent())
+			// 连续的工具结果合并为一条用户消息
+			if lastIsToolResult {
+				last := &messages[len(messages)-1]
+				last.SetStringContent(last.StringContent() + "\n" + result)
+				continue
+			}
+			resultMessage := dto.Message{Role: "user"}
+			resultMessage.SetStringContent(result)
+			messages = append(messages, resultMessage)
+			lastIsToolResult = true
+		default:
+			messages = append(messages, message)
+			lastIsToolResult = false
+		}
+	}
+
+	if prompt != "" {
+		if len(messages) > 0 && (messages[0].Role == "system" || messages[0].Role == "developer") {
+			messages[0].SetStringContent(messages[0].StringContent() + "\n\n" + prompt)
+		} else {
+			systemMessage := dto.Message{Role: "system"}
+			systemMessage.SetStringContent(prompt)
+			messages = append([]dto.Message{systemMessage}, messages...)
+		}
+	}
+	request.Messages = messages
+	request.Tools = nil
+	request.ToolChoice = nil
+}
+
+// extractToolCalls 取出文本中所有的工具调用，返回剩余的文本
+func (p *toolCallProfile) extractToolCalls(text string) (string, []dto.ToolCallResponse) {
+	var rest strings.Builder
+	var calls []dto.ToolCallResponse
+	for {
+		i := strings.Index(text, p.start)
+		if i < 0 {
+			rest.WriteString(text)
+			break
+		}
+		rest.WriteString(text[:i])
+		text = text[i:]
+		body := text[len(p.start):]
+		raw, after := text, ""
+		if j := strings.Index(body, p.end); j >= 0 {
+			body = body[:j]
+			raw = text[:len(p.start)+j+len(p.end)]
+			after = text[len(raw):]
+		}
+		parsed, err := p.parse(body)
+		if err != nil {
+			rest.WriteString(raw)
+		} else {
+			calls = append(calls, parsed...)
+		}
+		text = after
+	}
+	return strings.TrimSpace(rest.String()), calls
+}
+
+// applyToolCallProfile 解析非流式响应中的工具调用，返回是否修改了响应
+func applyToolCallProfile(response *dto.OpenAITextResponse, profile *toolCallProfile) bool {
+	changed := false
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		content, calls := profile.extractToolCalls(choice.Message.StringContent())
+		if len(calls) == 0 {
+			continue
+		}
+		if content == "" {
+			choice.Message.SetNullContent()
+		} else {
+			choice.Message.SetStringContent(content)
+		}
+		choice.Message.SetToolCalls(calls)
+		choice.FinishReason = constant.FinishReasonToolCalls
+		changed = true
+	}
+	return changed
+}
+
+// toolCallStreamParser 流式响应中遇到起始标记后暂存后续内容，直到结束标记出现再解析为 tool_calls，
+// 文本结尾可能是起始标记的一部分时也先暂存，只处理第一个 choice
+type toolCallStreamParser struct {
+	profile      *toolCallProfile
+	pending      string
+	capturing    bool
+	toolIndex    int
+	finished     bool
+	lastResponse *dto.ChatCompletionsStreamResponse
+}
+
+func newToolCallStreamParser(info *relaycommon.RelayInfo) *toolCallStreamParser {
+	profile := getToolCallProfile(info)
+	if profile == nil {
+		return nil
+	}
+	return &toolCallStreamParser{profile: profile}
+}
+
+// partialPrefixLen 返回 text 结尾与 marker 前缀重合的最大长度
+func partialPrefixLen(text string, marker string) int {
+	for k := len(marker) - 1; k > 0; k-- {
+		if strings.HasSuffix(text, marker[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+func (p *toolCallStreamParser) consume(content string, final bool) (string, []dto.ToolCallResponse) {
+	text := p.pending + content
+	p.pending = ""
+	var out strings.Builder
+	var calls []dto.ToolCallResponse
+	for text != "" {
+		if !p.capturing {
+			i := strings.Index(text, p.profile.start)
+			if i < 0 {
+				keep := 0
+				if !final {
+					keep = partialPrefixLen(text, p.profile.start)
+				}
+				out.WriteString(text[:len(text)-keep])
+				p.pending = text[len(text)-keep:]
+				break
+			}
+			out.WriteString(text[:i])
+			text = text[i:]
+			p.capturing = true
+			continue
+		}
+		j := strings.Index(text[len(p.profile.start):], p.profile.end)
+		if j < 0 {
+			if !final {
+				p.pending = text
+				break
+			}
+			// 模型在结束标记前停止输出，尝试解析已有内容
+			if parsed, err := p.profile.parse(text[len(p.profile.start):]); err == nil {
+				calls = append(calls, parsed...)
+			} else {
+				out.WriteString(text)
+			}
+			p.capturing = false
+			break
+		}
+		end := len(p.profile.start) + j + len(p.profile.end)
+		if parsed, err := p.profile.parse(text[len(p.profile.start) : len(p.profile.start)+j]); err == nil {
+			calls = append(calls, parsed...)
+		} else {
+			out.WriteString(text[:end])
+		}
+		text = text[end:]
+		p.capturing = false
+	}
+	for i := range calls {
+		calls[i].SetIndex(p.toolIndex)
+		p.toolIndex++
+	}
+	return out.String(), calls
+}
+
+func (p *toolCallStreamParser) rewrite(response *dto.ChatCompletionsStreamResponse, content string, final bool) string {
+	choice := &response.Choices[0]
+	text, calls := p.consume(content, final)
+	if text != "" {
+		choice.Delta.SetContentString(text)
+	} else {
+		choice.Delta.Content = nil
+	}
+	choice.Delta.ToolCalls = append(choice.Delta.ToolCalls, calls...)
+	if choice.FinishReason != nil && p.toolIndex > 0 {
+		finishReason := constant.FinishReasonToolCalls
+		choice.FinishReason = &finishReason
+	}
+	if response.Usage == nil && choice.FinishReason == nil && choice.Delta.Role == "" && choice.Delta.Content == nil &&
+		choice.Delta.ReasoningContent == nil && choice.Delta.Reasoning == nil && len(choice.Delta.ToolCalls) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Feed 处理一个数据块，返回改写后的数据块，内容被暂存时可能返回空
+func (p *toolCallStreamParser) Feed(data string) []string {
+	var response dto.ChatCompletionsStreamResponse
+	if err := common.DecodeJsonStr(data, &response); err != nil || len(response.Choices) == 0 {
+		return []string{data}
+	}
+	template := response.Copy()
+	template.Usage = nil
+	p.lastResponse = template
+	final := response.Choices[0].FinishReason != nil
+	if final {
+		p.finished = true
+	}
+	rewritten := p.rewrite(&response, response.Choices[0].Delta.GetContentString(), final)
+	if rewritten == "" {
+		return nil
+	}
+	return []string{rewritten}
+}
+
+// Flush 上游没有返回结束原因就结束时，输出暂存的内容
+func (p *toolCallStreamParser) Flush() []string {
+	if p.finished || p.lastResponse == nil || (p.pending == "" && !p.capturing) {
+		return nil
+	}
+	response := p.lastResponse
+	response.Choices = response.Choices[:1]
+	response.Choices[0].Delta = dto.ChatCompletionsStreamResponseChoiceDelta{}
+	response.Choices[0].FinishReason = nil
+	rewritten := p.rewrite(response, "", true)
+	if rewritten == "" {
+		return nil
+	}
+	return []string{rewritten}
+}
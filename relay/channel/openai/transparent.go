@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 透传模式下不需要转换格式的响应原样返回上游的数据，只解析用量用于计费，
+// 避免重新序列化时丢失上游的扩展字段。结束原因、思考内容、工具调用等后处理设置不生效
+
+// isTransparentResponse 渠道开启透传模式且客户端请求的是 OpenAI 格式
+func isTransparentResponse(info *relaycommon.RelayInfo) bool {
+	if info.RelayFormat != relaycommon.RelayFormatOpenAI {
+		return false
+	}
+	transparent, _ := info.ChannelSetting[constant.ChannelSettingTransparent].(bool)
+	return transparent
+}
+
+// transparentStreamChunk 只解析数据块中计费和结束用量数据块需要的字段
+type transparentStreamChunk struct {
+	Id                string     `json:"id"`
+	Created           int64      `json:"created"`
+	Model             string     `json:"model"`
+	SystemFingerprint *string    `json:"system_fingerprint"`
+	ServiceTier       string     `json:"service_tier"`
+	Choices           []any      `json:"choices"`
+	Usage             *dto.Usage `json:"usage"`
+}
+
+func oaiTransparentStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	var streamItems []string
+	var last transparentStreamChunk
+	var usage *dto.Usage
+	model := info.UpstreamModelName
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		streamItems = append(streamItems, data)
+		var chunk transparentStreamChunk
+		if err := common.DecodeJsonStr(data, &chunk); err == nil {
+			last = chunk
+			if service.ValidUsage(chunk.Usage) {
+				usage = chunk.Usage
+				// 网关为了计费要求上游返回用量，客户端没有要求时不返回只包含用量的数据块
+				if !info.ShouldIncludeUsage && len(chunk.Choices) == 0 {
+					return true
+				}
+			}
+		}
+		if err := helper.StringData(c, data); err != nil {
+			common.LogError(c, "error sending stream data: "+err.Error())
+		}
+		return true
+	})
+
+	containStreamUsage := usage != nil
+	if !containStreamUsage {
+		var responseTextBuilder strings.Builder
+		var toolCount int
+		if err := processTokens(info.RelayMode, streamItems, &responseTextBuilder, &toolCount); err != nil {
+			common.SysError("error processing tokens: " + err.Error())
+		}
+		usage, _ = service.ResponseText2Usage(responseTextBuilder.String(), info.UpstreamModelName, info.PromptTokens)
+		usage.CompletionTokens += toolCount * 7
+	} else if info.ChannelType == common.ChannelTypeDeepSeek && usage.PromptCacheHitTokens != 0 {
+		usage.PromptTokensDetails.CachedTokens = usage.PromptCacheHitTokens
+	}
+	recordServiceTier(c, last.ServiceTier)
+	if last.Model != "" {
+		model = last.Model
+	}
+	systemFingerprint := ""
+	if last.SystemFingerprint != nil {
+		systemFingerprint = *last.SystemFingerprint
+	}
+	handleFinalResponse(c, info, "", last.Id, last.Created, model, systemFingerprint, usage, containStreamUsage)
+	return nil, usage
+}
@@ -0,0 +1,126 @@
+package openrouter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/relay/channel"
+	"one-api/relay/channel/openai"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Adaptor struct {
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	switch info.RelayMode {
+	case constant.RelayModeChatCompletions:
+		return fmt.Sprintf("%s/v1/chat/completions", info.BaseUrl), nil
+	case constant.RelayModeCompletions:
+		return fmt.Sprintf("%s/v1/completions", info.BaseUrl), nil
+	case constant.RelayModeEmbeddings:
+		return fmt.Sprintf("%s/v1/embeddings", info.BaseUrl), nil
+	}
+	return "", errors.New("invalid relay mode")
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
+	channel.SetupApiRequestHeader(info, c, req)
+	req.Set("Authorization", "Bearer "+info.ApiKey)
+	req.Set("HTTP-Referer", "https://github.com/Calcium-Ion/new-api")
+	req.Set("X-Title", "New API")
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	return requestOpenAI2OpenRouter(c, request)
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, nil
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return request, nil
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	// TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *dto.OpenAIErrorWithStatusCode) {
+	if info.RelayMode == constant.RelayModeEmbeddings {
+		err, usage = openai.OpenaiHandler(c, resp, info)
+		return
+	}
+	reader := &generationReader{ReadCloser: resp.Body}
+	resp.Body = reader
+	var openaiUsage *dto.Usage
+	if info.IsStream {
+		err, openaiUsage = openai.OaiStreamHandler(c, resp, info)
+	} else {
+		err, openaiUsage = openai.OpenaiHandler(c, resp, info)
+	}
+	if err != nil {
+		return nil, err
+	}
+	id := reader.generationId()
+	if id == "" || openaiUsage == nil {
+		return openaiUsage, nil
+	}
+	c.Set("upstream_generation_id", id)
+	cost, hasCost := reader.usageCost()
+	generation, fetchErr := fetchGeneration(info, id)
+	if fetchErr != nil {
+		common.LogWarn(c, fmt.Sprintf("failed to fetch openrouter generation %s: %s", id, fetchErr.Error()))
+	} else {
+		reconcileUsage(c, openaiUsage, generation)
+		if !hasCost {
+			cost, hasCost = generation.Data.TotalCost, true
+		}
+	}
+	if hasCost {
+		c.Set("upstream_cost", cost)
+	}
+	return openaiUsage, nil
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
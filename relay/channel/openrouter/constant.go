@@ -1,5 +1,15 @@
 package openrouter
 
-var ModelList = []string{}
+var ModelList = []string{
+	"openai/gpt-4o",
+	"openai/gpt-4o-mini",
+	"anthropic/claude-3.7-sonnet",
+	"anthropic/claude-sonnet-4",
+	"google/gemini-2.5-pro-preview",
+	"google/gemini-2.5-flash-preview",
+	"deepseek/deepseek-chat",
+	"deepseek/deepseek-r1",
+	"meta-llama/llama-3.3-70b-instruct",
+}
 
 var ChannelName = "openrouter"
@@ -0,0 +1,63 @@
+package openrouter
+
+import (
+	"encoding/json"
+	"one-api/dto"
+)
+
+type UsageOption struct {
+	Include bool `json:"include"`
+}
+
+// OpenRouterRequest provider、models、route、transforms 为 OpenRouter 的路由参数，原样透传
+type OpenRouterRequest struct {
+	*dto.GeneralOpenAIRequest
+	Provider   json.RawMessage `json:"provider,omitempty"`
+	Models     json.RawMessage `json:"models,omitempty"`
+	Route      json.RawMessage `json:"route,omitempty"`
+	Transforms json.RawMessage `json:"transforms,omitempty"`
+	Usage      *UsageOption    `json:"usage,omitempty"`
+}
+
+func (r OpenRouterRequest) MarshalJSON() ([]byte, error) {
+	type overlay struct {
+		openRouterExtraRequest
+		Usage *UsageOption `json:"usage,omitempty"`
+	}
+	return dto.MarshalWithOverlay(r.GeneralOpenAIRequest, overlay{
+		openRouterExtraRequest: openRouterExtraRequest{
+			Provider:   r.Provider,
+			Models:     r.Models,
+			Route:      r.Route,
+			Transforms: r.Transforms,
+		},
+		Usage: r.Usage,
+	})
+}
+
+type openRouterExtraRequest struct {
+	Provider   json.RawMessage `json:"provider,omitempty"`
+	Models     json.RawMessage `json:"models,omitempty"`
+	Route      json.RawMessage `json:"route,omitempty"`
+	Transforms json.RawMessage `json:"transforms,omitempty"`
+}
+
+type GenerationResponse struct {
+	Data struct {
+		Id                     string  `json:"id"`
+		Model                  string  `json:"model"`
+		ProviderName           string  `json:"provider_name"`
+		TotalCost              float64 `json:"total_cost"`
+		TokensPrompt           int     `json:"tokens_prompt"`
+		TokensCompletion       int     `json:"tokens_completion"`
+		NativeTokensPrompt     int     `json:"native_tokens_prompt"`
+		NativeTokensCompletion int     `json:"native_tokens_completion"`
+		NativeTokensReasoning  int     `json:"native_tokens_reasoning"`
+		NativeTokensCached     int     `json:"native_tokens_cached"`
+	} `json:"data"`
+}
+
+// usageAccounting 开启 usage accounting 后 usage 中返回的本次请求费用（美元）
+type usageAccounting struct {
+	Cost *float64 `json:"cost"`
+}
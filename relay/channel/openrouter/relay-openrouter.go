@@ -0,0 +1,150 @@
+package openrouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generationIdHeadSize、usageCostTailSize 分别为保留的响应开头和结尾的长度，generation id 在开头，usage 在结尾
+const (
+	generationIdHeadSize = 4096
+	usageCostTailSize    = 4096
+)
+
+var generationIdRegex = regexp.MustCompile(`"id"\s*:\s*"(gen-[^"]+)"`)
+
+func requestOpenAI2OpenRouter(c *gin.Context, request *dto.GeneralOpenAIRequest) (*OpenRouterRequest, error) {
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, err
+	}
+	var extra openRouterExtraRequest
+	err = json.Unmarshal(body, &extra)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenRouterRequest{
+		GeneralOpenAIRequest: request,
+		Provider:             extra.Provider,
+		Models:               extra.Models,
+		Route:                extra.Route,
+		Transforms:           extra.Transforms,
+		// 开启 usage accounting，返回的 usage 为上游模型原生 tokenizer 的计数
+		Usage: &UsageOption{Include: true},
+	}, nil
+}
+
+// generationReader 记录响应开头和结尾的内容，用于在响应处理完成后取出 generation id 和 usage 中的费用
+type generationReader struct {
+	io.ReadCloser
+	head []byte
+	tail []byte
+}
+
+func (r *generationReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if remain := generationIdHeadSize - len(r.head); remain > 0 {
+			r.head = append(r.head, p[:min(n, remain)]...)
+		}
+		r.tail = append(r.tail, p[:n]...)
+		// 超过两倍长度时才截断，避免每次读取都复制
+		if len(r.tail) > 2*usageCostTailSize {
+			r.tail = append(r.tail[:0], r.tail[len(r.tail)-usageCostTailSize:]...)
+		}
+	}
+	return n, err
+}
+
+func (r *generationReader) generationId() string {
+	matches := generationIdRegex.FindSubmatch(r.head)
+	if len(matches) < 2 {
+		return ""
+	}
+	return string(matches[1])
+}
+
+// usageCost 开启 usage accounting 后 usage 中带有本次请求的费用（美元），流式响应在最后一个数据块中返回，
+// 从最后一个 usage 字段开始解析完整的 usage 对象
+func (r *generationReader) usageCost() (float64, bool) {
+	index := bytes.LastIndex(r.tail, []byte(`"usage"`))
+	if index < 0 {
+		return 0, false
+	}
+	rest := bytes.TrimLeft(r.tail[index+len(`"usage"`):], " \t\r\n")
+	if !bytes.HasPrefix(rest, []byte(":")) {
+		return 0, false
+	}
+	var usage usageAccounting
+	if err := json.NewDecoder(bytes.NewReader(rest[1:])).Decode(&usage); err != nil || usage.Cost == nil {
+		return 0, false
+	}
+	return *usage.Cost, true
+}
+
+// fetchGeneration 生成记录写入有延迟，查询不到时短暂等待后重试
+func fetchGeneration(info *relaycommon.RelayInfo, id string) (*GenerationResponse, error) {
+	requestURL := fmt.Sprintf("%s/v1/generation?id=%s", info.BaseUrl, url.QueryEscape(id))
+	var lastErr error
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+		resp, err := service.GetImpatientHttpClient().Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("status code: %d", resp.StatusCode)
+			continue
+		}
+		var generation GenerationResponse
+		err = json.Unmarshal(body, &generation)
+		if err != nil {
+			return nil, err
+		}
+		return &generation, nil
+	}
+	return nil, lastErr
+}
+
+// reconcileUsage 使用 OpenRouter 记录的原生 token 数修正计费用量
+func reconcileUsage(c *gin.Context, usage *dto.Usage, generation *GenerationResponse) {
+	data := generation.Data
+	if data.NativeTokensPrompt == 0 && data.NativeTokensCompletion == 0 {
+		return
+	}
+	if usage.PromptTokens != data.NativeTokensPrompt || usage.CompletionTokens != data.NativeTokensCompletion {
+		common.LogInfo(c, fmt.Sprintf("openrouter generation %s usage reconciled: prompt %d -> %d, completion %d -> %d",
+			data.Id, usage.PromptTokens, data.NativeTokensPrompt, usage.CompletionTokens, data.NativeTokensCompletion))
+	}
+	usage.PromptTokens = data.NativeTokensPrompt
+	usage.CompletionTokens = data.NativeTokensCompletion
+	usage.TotalTokens = data.NativeTokensPrompt + data.NativeTokensCompletion
+	usage.CompletionTokenDetails.ReasoningTokens = data.NativeTokensReasoning
+	usage.PromptTokensDetails.CachedTokens = data.NativeTokensCached
+}
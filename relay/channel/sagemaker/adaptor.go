@@ -0,0 +1,165 @@
+package sagemaker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/dto"
+	"one-api/relay/channel"
+	"one-api/relay/channel/openai"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/constant"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/gin-gonic/gin"
+)
+
+type Adaptor struct {
+	template *SageMakerTemplate
+	body     []byte
+}
+
+// parseKey 密钥格式与 AWS Claude 渠道相同：Ak|Sk|Region
+func parseKey(key string) (ak string, sk string, region string, err error) {
+	parts := strings.Split(key, "|")
+	if len(parts) != 3 {
+		return "", "", "", errors.New("invalid aws secret key")
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	//TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+// GetRequestURL 模型名称即终端节点名称，渠道设置了代理地址时使用代理地址代替默认域名
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	_, _, region, err := parseKey(info.ApiKey)
+	if err != nil {
+		return "", err
+	}
+	baseUrl := fmt.Sprintf("https://runtime.sagemaker.%s.amazonaws.com", region)
+	if info.BaseUrl != "" {
+		baseUrl = strings.TrimSuffix(info.BaseUrl, "/")
+	}
+	action := "invocations"
+	if info.IsStream {
+		action = "invocations-response-stream"
+	}
+	return fmt.Sprintf("%s/endpoints/%s/%s", baseUrl, url.PathEscape(info.UpstreamModelName), action), nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
+	ak, sk, region, err := parseKey(info.ApiKey)
+	if err != nil {
+		return err
+	}
+	req.Set("Content-Type", "application/json")
+	if info.IsStream {
+		req.Set("X-Amzn-SageMaker-Accept", "application/json")
+	} else {
+		req.Set("Accept", "application/json")
+	}
+	fullRequestURL, err := a.GetRequestURL(info)
+	if err != nil {
+		return err
+	}
+	// 签名只需要请求地址和请求体，在临时请求上签名后复制签名相关的请求头
+	signReq, err := http.NewRequest(http.MethodPost, fullRequestURL, nil)
+	if err != nil {
+		return err
+	}
+	signReq.Header = req.Clone()
+	hash := sha256.Sum256(a.body)
+	payloadHash := hex.EncodeToString(hash[:])
+	credentials := aws.Credentials{AccessKeyID: ak, SecretAccessKey: sk}
+	err = v4.NewSigner().SignHTTP(context.Background(), credentials, signReq, payloadHash, "sagemaker", region, time.Now())
+	if err != nil {
+		return fmt.Errorf("sign request failed: %w", err)
+	}
+	for _, key := range []string{"Authorization", "X-Amz-Date", "X-Amz-Content-Sha256"} {
+		if value := signReq.Header.Get(key); value != "" {
+			req.Set(key, value)
+		}
+	}
+	return nil
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	template, err := getTemplate(info)
+	if err != nil {
+		return nil, err
+	}
+	a.template = template
+	return requestOpenAI2SageMaker(info, request, template)
+}
+
+func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
+	return nil, nil
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	return request, nil
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
+	// TODO implement me
+	return nil, errors.New("not implemented")
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	// SigV4 签名需要请求体的哈希，先读出请求体
+	body, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("read request body failed: %w", err)
+	}
+	a.body = body
+	return channel.DoApiRequest(a, c, info, bytes.NewReader(body))
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *dto.OpenAIErrorWithStatusCode) {
+	if info.IsStream {
+		err, usage = sagemakerStreamHandler(c, resp, info, a.template)
+		return
+	}
+	if a.template == nil || info.RelayMode == constant.RelayModeEmbeddings {
+		err, usage = openai.OpenaiHandler(c, resp, info)
+		return
+	}
+	err, usage = sagemakerHandler(c, resp, info, a.template)
+	return
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
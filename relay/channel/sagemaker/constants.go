@@ -0,0 +1,6 @@
+package sagemaker
+
+// SageMaker 的模型名称为终端节点名称，需要在渠道中手动填写
+var ModelList = []string{}
+
+var ChannelName = "sagemaker"
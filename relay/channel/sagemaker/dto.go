@@ -0,0 +1,17 @@
+package sagemaker
+
+import "encoding/json"
+
+// SageMakerTemplate 描述如何把 OpenAI 请求包装成终端节点的请求，以及如何从响应中取出文本，
+// 未配置时按 OpenAI 格式收发（适用于 vLLM、LMI 等提供 OpenAI 兼容接口的模型服务）
+type SageMakerTemplate struct {
+	// Request 请求模板，值为 "{{messages}}"、"{{prompt}}"、"{{model}}"、"{{max_tokens}}"、
+	// "{{temperature}}"、"{{top_p}}"、"{{stop}}"、"{{stream}}" 的字符串会被替换为对应的值
+	Request json.RawMessage `json:"request,omitempty"`
+	// ResponsePath 非流式响应中文本的路径，例如 "0.generated_text"
+	ResponsePath string `json:"response_path,omitempty"`
+	// StreamPath 流式响应每一行中文本的路径，例如 "token.text"
+	StreamPath           string `json:"stream_path,omitempty"`
+	PromptTokensPath     string `json:"prompt_tokens_path,omitempty"`
+	CompletionTokensPath string `json:"completion_tokens_path,omitempty"`
+}
@@ -0,0 +1,362 @@
+package sagemaker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/gin-gonic/gin"
+)
+
+func getTemplate(info *relaycommon.RelayInfo) (*SageMakerTemplate, error) {
+	setting, ok := info.ChannelSetting[constant.ChannelSettingSageMakerTemplate]
+	if !ok || setting == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(setting)
+	if err != nil {
+		return nil, err
+	}
+	var template SageMakerTemplate
+	err = json.Unmarshal(data, &template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sagemaker template: %w", err)
+	}
+	return &template, nil
+}
+
+func messagesToPrompt(messages []dto.Message) string {
+	var builder strings.Builder
+	for _, message := range messages {
+		builder.WriteString(message.Role)
+		builder.WriteString(": ")
+		builder.WriteString(message.StringContent())
+		builder.WriteString("\n")
+	}
+	builder.WriteString("assistant: ")
+	return builder.String()
+}
+
+func templateVariables(info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) map[string]any {
+	variables := map[string]any{
+		"messages": request.Messages,
+		"model":    info.UpstreamModelName,
+		"stream":   request.Stream,
+	}
+	if len(request.Messages) > 0 {
+		variables["prompt"] = messagesToPrompt(request.Messages)
+	} else if prompt, ok := request.Prompt.(string); ok {
+		variables["prompt"] = prompt
+	}
+	if request.MaxTokens > 0 {
+		variables["max_tokens"] = request.MaxTokens
+	} else if request.MaxCompletionTokens > 0 {
+		variables["max_tokens"] = request.MaxCompletionTokens
+	}
+	if request.Temperature != nil {
+		variables["temperature"] = *request.Temperature
+	}
+	if request.TopP != 0 {
+		variables["top_p"] = request.TopP
+	}
+	if len(request.Stop) > 0 {
+		variables["stop"] = request.Stop
+	}
+	return variables
+}
+
+// fillTemplate 递归替换模板中的变量，整个字符串为变量时替换为对应的 JSON 值，变量没有值时删除该字段
+func fillTemplate(node any, variables map[string]any) (any, bool) {
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			filled, ok := fillTemplate(child, variables)
+			if !ok {
+				delete(v, key)
+				continue
+			}
+			v[key] = filled
+		}
+		return v, true
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, child := range v {
+			if filled, ok := fillTemplate(child, variables); ok {
+				result = append(result, filled)
+			}
+		}
+		return result, true
+	case string:
+		if strings.HasPrefix(v, "{{") && strings.HasSuffix(v, "}}") && strings.Count(v, "{{") == 1 {
+			value, ok := variables[strings.TrimSpace(v[2:len(v)-2])]
+			return value, ok
+		}
+		for name, value := range variables {
+			if s, ok := value.(string); ok {
+				v = strings.ReplaceAll(v, "{{"+name+"}}", s)
+			}
+		}
+		return v, true
+	}
+	return node, true
+}
+
+func requestOpenAI2SageMaker(info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest, template *SageMakerTemplate) (any, error) {
+	if template == nil || len(template.Request) == 0 {
+		return request, nil
+	}
+	var node any
+	err := json.Unmarshal(template.Request, &node)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sagemaker request template: %w", err)
+	}
+	filled, _ := fillTemplate(node, templateVariables(info, request))
+	return filled, nil
+}
+
+// getJSONPath 按 a.b.0.c 形式的路径取值，数字表示数组下标
+func getJSONPath(node any, path string) any {
+	if path == "" {
+		return node
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := node.(type) {
+		case map[string]any:
+			node = v[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			node = v[index]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+func getJSONPathString(node any, path string) string {
+	if s, ok := getJSONPath(node, path).(string); ok {
+		return s
+	}
+	return ""
+}
+
+func getJSONPathInt(node any, path string) int {
+	if path == "" {
+		return 0
+	}
+	if f, ok := getJSONPath(node, path).(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+func templateUsage(node any, template *SageMakerTemplate, info *relaycommon.RelayInfo, responseText string) *dto.Usage {
+	promptTokens := getJSONPathInt(node, template.PromptTokensPath)
+	completionTokens := getJSONPathInt(node, template.CompletionTokensPath)
+	if completionTokens == 0 {
+		usage, _ := service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+		if promptTokens != 0 {
+			usage.PromptTokens = promptTokens
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		}
+		return usage
+	}
+	if promptTokens == 0 {
+		promptTokens = info.PromptTokens
+	}
+	return &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+func sagemakerHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, template *SageMakerTemplate) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var node any
+	err = json.Unmarshal(responseBody, &node)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	responseText := getJSONPathString(node, template.ResponsePath)
+	usage := templateUsage(node, template, info, responseText)
+	choice := dto.OpenAITextResponseChoice{
+		Index: 0,
+		Message: dto.Message{
+			Role: "assistant",
+		},
+		FinishReason: constant.FinishReasonStop,
+	}
+	choice.Message.SetStringContent(responseText)
+	fullTextResponse := dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Model:   info.UpstreamModelName,
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Choices: []dto.OpenAITextResponseChoice{choice},
+		Usage:   *usage,
+	}
+	jsonResponse, err := json.Marshal(fullTextResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+	return nil, usage
+}
+
+// readPayloadLines 解析 invoke-endpoint-with-response-stream 返回的 AWS event stream，
+// PayloadPart 中的字节可能在任意位置被截断，按行重新拼接后交给 handle 处理
+func readPayloadLines(body io.Reader, handle func(line string) bool) error {
+	decoder := eventstream.NewDecoder()
+	var pending bytes.Buffer
+	for {
+		message, err := decoder.Decode(body, nil)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		messageType := message.Headers.Get(":message-type")
+		if messageType != nil && messageType.String() == "exception" {
+			exceptionType := message.Headers.Get(":exception-type")
+			name := "ModelStreamError"
+			if exceptionType != nil {
+				name = exceptionType.String()
+			}
+			return fmt.Errorf("%s: %s", name, string(message.Payload))
+		}
+		eventType := message.Headers.Get(":event-type")
+		if eventType == nil || eventType.String() != "PayloadPart" {
+			continue
+		}
+		pending.Write(message.Payload)
+		for {
+			line, err := pending.ReadString('\n')
+			if err != nil {
+				// 不完整的行放回缓冲区等待后续数据
+				pending.Reset()
+				pending.WriteString(line)
+				break
+			}
+			if !handle(line) {
+				return nil
+			}
+		}
+	}
+	if pending.Len() > 0 {
+		handle(pending.String())
+	}
+	return nil
+}
+
+func sagemakerStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, template *SageMakerTemplate) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	defer resp.Body.Close()
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
+	var responseText strings.Builder
+	var usage *dto.Usage
+	var lastNode any
+	sent := false
+
+	err := readPayloadLines(bufio.NewReader(resp.Body), func(line string) bool {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if line == "" || line == "[DONE]" {
+			return true
+		}
+		if template == nil {
+			var streamResponse dto.ChatCompletionsStreamResponse
+			if err := common.DecodeJsonStr(line, &streamResponse); err != nil {
+				common.LogError(c, "error unmarshalling stream response: "+err.Error())
+				return true
+			}
+			if service.ValidUsage(streamResponse.Usage) {
+				usage = streamResponse.Usage
+				if !info.ShouldIncludeUsage {
+					return true
+				}
+			}
+			for _, choice := range streamResponse.Choices {
+				responseText.WriteString(choice.Delta.GetContentString())
+			}
+			streamResponse.Id = id
+			streamResponse.Model = info.UpstreamModelName
+			if err := helper.ObjectData(c, streamResponse); err != nil {
+				common.LogError(c, err.Error())
+			}
+			sent = true
+			return true
+		}
+		var node any
+		if err := json.Unmarshal([]byte(line), &node); err != nil {
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			return true
+		}
+		lastNode = node
+		text := getJSONPathString(node, template.StreamPath)
+		if text == "" {
+			return true
+		}
+		responseText.WriteString(text)
+		var choice dto.ChatCompletionsStreamResponseChoice
+		choice.Delta.SetContentString(text)
+		streamResponse := dto.ChatCompletionsStreamResponse{
+			Id:      id,
+			Object:  "chat.completion.chunk",
+			Created: createAt,
+			Model:   info.UpstreamModelName,
+			Choices: []dto.ChatCompletionsStreamResponseChoice{choice},
+		}
+		if err := helper.ObjectData(c, streamResponse); err != nil {
+			common.LogError(c, err.Error())
+		}
+		sent = true
+		return true
+	})
+	if err != nil {
+		common.LogError(c, "sagemaker stream error: "+err.Error())
+		if !sent {
+			return service.OpenAIErrorWrapper(err, "sagemaker_stream_error", http.StatusInternalServerError), nil
+		}
+	}
+
+	// 上游返回了 usage 时已随数据块转发，否则需要补发
+	forwardedUsage := usage != nil
+	if template != nil {
+		usage = templateUsage(lastNode, template, info, responseText.String())
+		_ = helper.ObjectData(c, helper.GenerateStopResponse(id, createAt, info.UpstreamModelName, constant.FinishReasonStop))
+	} else if usage == nil {
+		usage, _ = service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+	}
+	if info.ShouldIncludeUsage && !forwardedUsage {
+		_ = helper.ObjectData(c, helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, *usage))
+	}
+	helper.Done(c)
+	return nil, usage
+}
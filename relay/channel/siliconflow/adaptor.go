@@ -1,6 +1,8 @@
 package siliconflow
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -24,13 +26,22 @@ func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
-	//TODO implement me
-	return nil, errors.New("not implemented")
+	if info.RelayMode != constant.RelayModeAudioSpeech {
+		return nil, errors.New("not implemented")
+	}
+	// 语音合成接口兼容 OpenAI 格式
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling object: %w", err)
+	}
+	return bytes.NewReader(jsonData), nil
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
-	//TODO implement me
-	return nil, errors.New("not implemented")
+	if info.RelayMode != constant.RelayModeImagesGenerations {
+		return nil, errors.New("not implemented")
+	}
+	return oaiImage2SiliconFlow(c, request)
 }
 
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
@@ -45,6 +56,10 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 		return fmt.Sprintf("%s/v1/chat/completions", info.BaseUrl), nil
 	} else if info.RelayMode == constant.RelayModeCompletions {
 		return fmt.Sprintf("%s/v1/completions", info.BaseUrl), nil
+	} else if info.RelayMode == constant.RelayModeImagesGenerations {
+		return fmt.Sprintf("%s/v1/images/generations", info.BaseUrl), nil
+	} else if info.RelayMode == constant.RelayModeAudioSpeech {
+		return fmt.Sprintf("%s/v1/audio/speech", info.BaseUrl), nil
 	}
 	return "", errors.New("invalid relay mode")
 }
@@ -90,6 +105,10 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		}
 	case constant.RelayModeEmbeddings:
 		err, usage = openai.OpenaiHandler(c, resp, info)
+	case constant.RelayModeImagesGenerations:
+		err, usage = siliconflowImageHandler(c, resp, info)
+	case constant.RelayModeAudioSpeech:
+		err, usage = openai.OpenaiTTSHandler(c, resp, info)
 	}
 	return
 }
@@ -47,5 +47,25 @@ var ModelList = []string{
 	"Qwen/Qwen2-Math-72B-Instruct",
 	"netease-youdao/bce-reranker-base_v1",
 	"BAAI/bge-reranker-v2-m3",
+	"deepseek-ai/DeepSeek-V3",
+	"deepseek-ai/DeepSeek-R1",
+	"Pro/deepseek-ai/DeepSeek-V3",
+	"Pro/deepseek-ai/DeepSeek-R1",
+	"deepseek-ai/DeepSeek-R1-Distill-Qwen-32B",
+	"Qwen/QwQ-32B",
+	"Qwen/Qwen2.5-72B-Instruct",
+	"Qwen/Qwen2.5-32B-Instruct",
+	"Qwen/Qwen2.5-7B-Instruct",
+	"Pro/Qwen/Qwen2.5-7B-Instruct",
+	"Qwen/Qwen2.5-Coder-32B-Instruct",
+	"Qwen/Qwen2.5-VL-72B-Instruct",
+	"THUDM/GLM-4-9B-0414",
+	"Pro/BAAI/bge-m3",
+	"Pro/BAAI/bge-reranker-v2-m3",
+	"black-forest-labs/FLUX.1-dev",
+	"Pro/black-forest-labs/FLUX.1-schnell",
+	"Kwai-Kolors/Kolors",
+	"stabilityai/stable-diffusion-3-5-large",
+	"FunAudioLLM/CosyVoice2-0.5B",
 }
 var ChannelName = "siliconflow"
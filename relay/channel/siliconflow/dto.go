@@ -15,3 +15,22 @@ type SFRerankResponse struct {
 	Results []dto.RerankResponseResult `json:"results"`
 	Meta    SFMeta                     `json:"meta"`
 }
+
+type SFImageRequest struct {
+	Model             string   `json:"model"`
+	Prompt            string   `json:"prompt"`
+	NegativePrompt    string   `json:"negative_prompt,omitempty"`
+	ImageSize         string   `json:"image_size,omitempty"`
+	BatchSize         int      `json:"batch_size,omitempty"`
+	Seed              *int64   `json:"seed,omitempty"`
+	NumInferenceSteps int      `json:"num_inference_steps,omitempty"`
+	GuidanceScale     *float64 `json:"guidance_scale,omitempty"`
+	Image             string   `json:"image,omitempty"`
+}
+
+type SFImageResponse struct {
+	Images []struct {
+		Url string `json:"url"`
+	} `json:"images"`
+	Seed int64 `json:"seed"`
+}
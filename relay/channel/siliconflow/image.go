@@ -0,0 +1,78 @@
+package siliconflow
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oaiImage2SiliconFlow 保留原始请求中 SiliconFlow 特有的参数（negative_prompt、seed、num_inference_steps 等）
+func oaiImage2SiliconFlow(c *gin.Context, request dto.ImageRequest) (*SFImageRequest, error) {
+	var imageRequest SFImageRequest
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, &imageRequest)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("response_format", request.ResponseFormat)
+	imageRequest.Model = request.Model
+	imageRequest.Prompt = request.Prompt
+	if request.Size != "" {
+		imageRequest.ImageSize = request.Size
+	}
+	if request.N > 0 {
+		imageRequest.BatchSize = request.N
+	}
+	return &imageRequest, nil
+}
+
+func siliconflowImageHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var sfResponse SFImageResponse
+	err = json.Unmarshal(responseBody, &sfResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	responseFormat := c.GetString("response_format")
+	imageResponse := dto.ImageResponse{
+		Created: info.StartTime.Unix(),
+	}
+	for _, image := range sfResponse.Images {
+		imageData := dto.ImageData{
+			Url: image.Url,
+		}
+		if responseFormat == "b64_json" {
+			_, b64, err := service.GetImageFromUrl(image.Url)
+			if err != nil {
+				common.LogError(c, "get_image_data_failed: "+err.Error())
+				continue
+			}
+			imageData.B64Json = b64
+		}
+		imageResponse.Data = append(imageResponse.Data, imageData)
+	}
+	jsonResponse, err := json.Marshal(imageResponse)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+	return nil, &dto.Usage{}
+}
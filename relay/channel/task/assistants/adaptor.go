@@ -0,0 +1,154 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const BetaHeader = "assistants=v2"
+
+// TaskAdaptor 查询通过 Assistants API 创建的 run，run 由透传请求创建，
+// 这里只负责查询状态，run 结束后由通用任务轮询器按 usage 结算
+type TaskAdaptor struct {
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.TaskRelayInfo) {
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.TaskRelayInfo) *dto.TaskError {
+	return service.TaskErrorWrapperLocal(errors.New("runs are created by the assistants passthrough"), "not_supported", http.StatusBadRequest)
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.TaskRelayInfo) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.TaskRelayInfo) error {
+	return errors.New("not supported")
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.TaskRelayInfo) (io.Reader, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.TaskRelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.TaskRelayInfo) (taskID string, taskData []byte, err *dto.TaskError) {
+	return "", nil, service.TaskErrorWrapperLocal(errors.New("not supported"), "not_supported", http.StatusBadRequest)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
+
+// SetupHeader 使用渠道的密钥替换客户端的鉴权信息
+func SetupHeader(header http.Header, key string, organization string) {
+	header.Set("Authorization", "Bearer "+key)
+	if header.Get("OpenAI-Beta") == "" {
+		header.Set("OpenAI-Beta", BetaHeader)
+	}
+	if organization != "" {
+		header.Set("OpenAI-Organization", organization)
+	}
+}
+
+func doRunRequest(method string, url string, key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	SetupHeader(req.Header, key, "")
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// CancelRun 取消上游的 run，用于无法计费的 run
+func CancelRun(baseUrl, key, threadId, runId string) error {
+	_, err := doRunRequest(http.MethodPost, fmt.Sprintf("%s/v1/threads/%s/runs/%s/cancel", strings.TrimSuffix(baseUrl, "/"), threadId, runId), key)
+	return err
+}
+
+func runResult(run *Object, body []byte) *dto.TaskResult {
+	result := &dto.TaskResult{
+		TaskID:     run.Id,
+		SubmitTime: run.CreatedAt,
+		StartTime:  run.StartedAt,
+		Data:       body,
+	}
+	switch run.Status {
+	case "completed", "incomplete":
+		result.Status = model.TaskStatusSuccess
+		result.FinishTime = run.CompletedAt
+		result.Usage = run.Usage
+		if result.Usage == nil {
+			result.Usage = &dto.Usage{}
+		}
+	case "failed", "cancelled", "expired":
+		result.Status = model.TaskStatusFailure
+		result.FinishTime = max(run.FailedAt, run.CancelledAt, run.ExpiredAt)
+		result.FailReason = "run " + run.Status
+		if run.LastError != nil && run.LastError.Message != "" {
+			result.FailReason = run.LastError.Message
+		}
+	case "queued":
+		result.Status = model.TaskStatusQueued
+	default:
+		// in_progress, requires_action, cancelling
+		result.Status = model.TaskStatusInProgress
+	}
+	return result
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, taskIds []string) ([]dto.TaskResult, error) {
+	results := make([]dto.TaskResult, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		task, exist, err := model.GetByOnlyTaskId(taskId)
+		if err != nil || !exist {
+			continue
+		}
+		body, err := doRunRequest(http.MethodGet, fmt.Sprintf("%s/v1/threads/%s/runs/%s", strings.TrimSuffix(baseUrl, "/"), task.Properties.Input, taskId), key)
+		if err != nil {
+			common.SysError(fmt.Sprintf("get run %s failed: %s", taskId, err.Error()))
+			continue
+		}
+		var run Object
+		if err = json.Unmarshal(body, &run); err != nil {
+			common.SysError(fmt.Sprintf("parse run %s failed: %s", taskId, err.Error()))
+			continue
+		}
+		results = append(results, *runResult(&run, body))
+	}
+	return results, nil
+}
@@ -0,0 +1,41 @@
+package assistants
+
+import (
+	"encoding/json"
+	"one-api/dto"
+)
+
+// Assistants API 返回的对象，assistant、thread 和 run 共用，只解析网关需要的字段
+// https://platform.openai.com/docs/api-reference/runs/object
+
+type Object struct {
+	Id          string     `json:"id"`
+	Object      string     `json:"object"`
+	ThreadId    string     `json:"thread_id"`
+	Model       string     `json:"model"`
+	Status      string     `json:"status"`
+	CreatedAt   int64      `json:"created_at"`
+	StartedAt   int64      `json:"started_at"`
+	CompletedAt int64      `json:"completed_at"`
+	FailedAt    int64      `json:"failed_at"`
+	CancelledAt int64      `json:"cancelled_at"`
+	ExpiredAt   int64      `json:"expired_at"`
+	LastError   *RunError  `json:"last_error"`
+	Usage       *dto.Usage `json:"usage"`
+
+	MaxPromptTokens     int `json:"max_prompt_tokens"`
+	MaxCompletionTokens int `json:"max_completion_tokens"`
+}
+
+type RunError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ObjectList struct {
+	Object  string            `json:"object"`
+	Data    []json.RawMessage `json:"data"`
+	FirstId string            `json:"first_id"`
+	LastId  string            `json:"last_id"`
+	HasMore bool              `json:"has_more"`
+}
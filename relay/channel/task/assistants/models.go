@@ -0,0 +1,5 @@
+package assistants
+
+var ModelList = []string{}
+
+var ChannelName = "assistants"
@@ -0,0 +1,147 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/relay/channel"
+	"one-api/relay/channel/claude"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskAdaptor 将 OpenAI Batch API 的请求转发到 Anthropic Message Batches，
+// 批量任务由通用任务轮询器查询，结束后下载结果并转换为 OpenAI 的输出文件格式
+type TaskAdaptor struct {
+	Requests []ClaudeBatchRequestItem
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.TaskRelayInfo) {
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.TaskRelayInfo) *dto.TaskError {
+	input, err := service.GetBatchInput(c)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	a.Requests = make([]ClaudeBatchRequestItem, 0, len(input.Requests))
+	for i, request := range input.Requests {
+		request.Model = info.UpstreamModelName
+		claudeRequest, err := claude.RequestOpenAI2ClaudeMessage(request)
+		if err != nil {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("custom_id %s: %w", input.CustomIds[i], err), "invalid_request", http.StatusBadRequest)
+		}
+		a.Requests = append(a.Requests, ClaudeBatchRequestItem{
+			CustomId: input.CustomIds[i],
+			Params:   claudeRequest,
+		})
+	}
+	info.Action = constant.BatchActionChatCompletions
+	return nil
+}
+
+func getBaseURL(baseUrl string) string {
+	if baseUrl == "" {
+		baseUrl = common.ChannelBaseURLs[common.ChannelTypeAnthropic]
+	}
+	return strings.TrimSuffix(baseUrl, "/")
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.TaskRelayInfo) (string, error) {
+	return fmt.Sprintf("%s/v1/messages/batches", getBaseURL(info.BaseUrl)), nil
+}
+
+func setupHeader(header http.Header, key string) {
+	header.Set("Content-Type", "application/json")
+	header.Set("x-api-key", key)
+	header.Set("anthropic-version", "2023-06-01")
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.TaskRelayInfo) error {
+	setupHeader(req.Header, info.ApiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.TaskRelayInfo) (io.Reader, error) {
+	if len(a.Requests) == 0 {
+		return nil, errors.New("batch requests is empty")
+	}
+	data, err := json.Marshal(ClaudeBatchRequest{Requests: a.Requests})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.TaskRelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.TaskRelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	var batch ClaudeBatch
+	err = json.Unmarshal(responseBody, &batch)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	if batch.Error != nil || batch.Id == "" {
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("create batch failed: %s", string(responseBody)), "create_batch_failed", http.StatusInternalServerError)
+		return
+	}
+	taskData, err = json.Marshal(dto.BatchTaskData{
+		ProcessingStatus: batch.ProcessingStatus,
+		RequestCounts:    dto.BatchRequestCounts{Total: len(a.Requests)},
+		ExpiresAt:        parseTime(batch.ExpiresAt),
+	})
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "marshal_task_data_failed", http.StatusInternalServerError)
+		return
+	}
+	return batch.Id, taskData, nil
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, taskIds []string) ([]dto.TaskResult, error) {
+	results := make([]dto.TaskResult, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		batch, err := getBatch(baseUrl, key, taskId)
+		if err != nil {
+			common.SysError(fmt.Sprintf("get batch %s failed: %s", taskId, err.Error()))
+			continue
+		}
+		result, err := batchResult(baseUrl, key, batch)
+		if err != nil {
+			common.SysError(fmt.Sprintf("get batch %s results failed: %s", taskId, err.Error()))
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// CancelBatch 请求上游取消批量任务，已处理完成的请求仍会返回结果
+func CancelBatch(baseUrl, key, batchId string) error {
+	_, err := doBatchRequest(http.MethodPost, fmt.Sprintf("%s/v1/messages/batches/%s/cancel", getBaseURL(baseUrl), batchId), key)
+	return err
+}
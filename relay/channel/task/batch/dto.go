@@ -0,0 +1,52 @@
+package batch
+
+import (
+	"encoding/json"
+	"one-api/dto"
+)
+
+// Anthropic Message Batches API
+// https://docs.anthropic.com/en/api/creating-message-batches
+
+type ClaudeBatchRequest struct {
+	Requests []ClaudeBatchRequestItem `json:"requests"`
+}
+
+type ClaudeBatchRequestItem struct {
+	CustomId string             `json:"custom_id"`
+	Params   *dto.ClaudeRequest `json:"params"`
+}
+
+type ClaudeBatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+type ClaudeBatch struct {
+	Id                string                   `json:"id"`
+	Type              string                   `json:"type"`
+	ProcessingStatus  string                   `json:"processing_status"`
+	RequestCounts     ClaudeBatchRequestCounts `json:"request_counts"`
+	CreatedAt         string                   `json:"created_at"`
+	EndedAt           string                   `json:"ended_at"`
+	ExpiresAt         string                   `json:"expires_at"`
+	CancelInitiatedAt string                   `json:"cancel_initiated_at"`
+	ResultsUrl        string                   `json:"results_url"`
+	Error             *dto.ClaudeError         `json:"error,omitempty"`
+}
+
+// ClaudeBatchResult results_url 返回的 JSONL 中的一行
+type ClaudeBatchResult struct {
+	CustomId string `json:"custom_id"`
+	Result   struct {
+		Type    string          `json:"type"`
+		Message json.RawMessage `json:"message"`
+		Error   *struct {
+			Type  string          `json:"type"`
+			Error dto.ClaudeError `json:"error"`
+		} `json:"error"`
+	} `json:"result"`
+}
@@ -0,0 +1,7 @@
+package batch
+
+import "one-api/relay/channel/claude"
+
+var ModelList = claude.ModelList
+
+var ChannelName = "batch"
@@ -0,0 +1,210 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel/claude"
+	"one-api/service"
+	"strings"
+	"time"
+)
+
+func parseTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+func doBatchRequest(method string, url string, key string) ([]byte, error) {
+	// 结果文件可能较大，下载的超时时间适当放宽
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setupHeader(req.Header, key)
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func getBatch(baseUrl, key, batchId string) (*ClaudeBatch, error) {
+	body, err := doBatchRequest(http.MethodGet, fmt.Sprintf("%s/v1/messages/batches/%s", getBaseURL(baseUrl), batchId), key)
+	if err != nil {
+		return nil, err
+	}
+	var batch ClaudeBatch
+	err = json.Unmarshal(body, &batch)
+	if err != nil {
+		return nil, fmt.Errorf("parse batch failed: %w", err)
+	}
+	return &batch, nil
+}
+
+func batchResult(baseUrl, key string, batch *ClaudeBatch) (*dto.TaskResult, error) {
+	counts := batch.RequestCounts
+	total := counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired
+	data := dto.BatchTaskData{
+		ProcessingStatus: batch.ProcessingStatus,
+		RequestCounts: dto.BatchRequestCounts{
+			Total:     total,
+			Completed: counts.Succeeded,
+			Failed:    counts.Errored + counts.Canceled + counts.Expired,
+		},
+		Canceled:    batch.CancelInitiatedAt != "",
+		ExpiresAt:   parseTime(batch.ExpiresAt),
+		CancelingAt: parseTime(batch.CancelInitiatedAt),
+	}
+	result := &dto.TaskResult{
+		TaskID:    batch.Id,
+		StartTime: parseTime(batch.CreatedAt),
+	}
+	if batch.ProcessingStatus != "ended" {
+		result.Status = model.TaskStatusInProgress
+		// 100% 表示任务已结束，处理中的任务进度最多到 99%
+		progress := 0
+		if total > 0 {
+			progress = (total - counts.Processing) * 100 / total
+		}
+		result.Progress = fmt.Sprintf("%d%%", min(progress, 99))
+		result.Data, _ = json.Marshal(data)
+		return result, nil
+	}
+	body, err := doBatchRequest(http.MethodGet, batch.ResultsUrl, key)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := convertResults(body, &data)
+	if err != nil {
+		return nil, err
+	}
+	result.FinishTime = parseTime(batch.EndedAt)
+	if counts.Succeeded == 0 {
+		result.Status = model.TaskStatusFailure
+		result.FailReason = "批量请求中没有成功的请求"
+		if data.Canceled {
+			result.FailReason = "批量请求已取消"
+		}
+	} else {
+		result.Status = model.TaskStatusSuccess
+		result.Usage = usage
+	}
+	result.Data, err = json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// convertResults 将 Anthropic 的结果文件转换为 OpenAI 的输出文件和错误文件，返回成功请求的总用量，
+// 缓存命中和缓存写入的 token 单独统计，结算时按缓存倍率计费
+func convertResults(body []byte, data *dto.BatchTaskData) (*dto.Usage, error) {
+	usage := &dto.Usage{}
+	var output, errorOutput strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item ClaudeBatchResult
+		err := json.Unmarshal(line, &item)
+		if err != nil {
+			return nil, fmt.Errorf("parse batch result failed: %w", err)
+		}
+		outputLine := dto.BatchOutputLine{
+			Id:       fmt.Sprintf("batch_req_%s", common.GetUUID()),
+			CustomId: item.CustomId,
+		}
+		switch item.Result.Type {
+		case "succeeded":
+			var claudeResponse dto.ClaudeResponse
+			err = json.Unmarshal(item.Result.Message, &claudeResponse)
+			if err != nil {
+				return nil, fmt.Errorf("parse batch message failed: %w", err)
+			}
+			response := claude.ResponseClaude2OpenAI(claude.RequestModeMessage, &claudeResponse)
+			response.Model = claudeResponse.Model
+			if claudeResponse.Usage != nil {
+				response.Usage.PromptTokens = claudeResponse.Usage.InputTokens + claudeResponse.Usage.CacheReadInputTokens + claudeResponse.Usage.CacheCreationInputTokens
+				response.Usage.PromptTokensDetails.CachedTokens = claudeResponse.Usage.CacheReadInputTokens
+				response.Usage.CompletionTokens = claudeResponse.Usage.OutputTokens
+				response.Usage.TotalTokens = response.Usage.PromptTokens + response.Usage.CompletionTokens
+			}
+			usage.PromptTokens += response.Usage.PromptTokens
+			usage.PromptTokensDetails.CachedTokens += response.Usage.PromptTokensDetails.CachedTokens
+			if claudeResponse.Usage != nil {
+				usage.PromptTokensDetails.CachedCreationTokens += claudeResponse.Usage.CacheCreationInputTokens
+			}
+			usage.CompletionTokens += response.Usage.CompletionTokens
+			usage.TotalTokens += response.Usage.TotalTokens
+			outputLine.Response = &dto.BatchOutputResponse{
+				StatusCode: http.StatusOK,
+				RequestId:  claudeResponse.Id,
+				Body:       response,
+			}
+		case "errored":
+			claudeError := dto.ClaudeError{Type: "upstream_error", Message: "request errored"}
+			if item.Result.Error != nil {
+				claudeError = item.Result.Error.Error
+			}
+			outputLine.Response = &dto.BatchOutputResponse{
+				StatusCode: http.StatusBadRequest,
+				Body: map[string]any{
+					"error": dto.OpenAIError{
+						Message: claudeError.Message,
+						Type:    claudeError.Type,
+					},
+				},
+			}
+		default:
+			// canceled, expired
+			outputLine.Error = &dto.BatchOutputError{
+				Code:    "batch_" + item.Result.Type,
+				Message: fmt.Sprintf("request %s", item.Result.Type),
+			}
+		}
+		lineBytes, err := json.Marshal(outputLine)
+		if err != nil {
+			return nil, err
+		}
+		if outputLine.Error == nil && outputLine.Response.StatusCode == http.StatusOK {
+			output.Write(lineBytes)
+			output.WriteString("\n")
+		} else {
+			errorOutput.Write(lineBytes)
+			errorOutput.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	data.Output = output.String()
+	data.ErrorOutput = errorOutput.String()
+	return usage, nil
+}
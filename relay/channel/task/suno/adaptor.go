@@ -126,9 +126,9 @@ func (a *TaskAdaptor) GetChannelName() string {
 	return ChannelName
 }
 
-func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http.Response, error) {
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, taskIds []string) ([]dto.TaskResult, error) {
 	requestUrl := fmt.Sprintf("%s/suno/fetch", baseUrl)
-	byteBody, err := json.Marshal(body)
+	byteBody, err := json.Marshal(dto.FetchReq{IDs: taskIds})
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +151,35 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any) (*http
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get task status code: %d", resp.StatusCode)
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var responseItems dto.TaskResponse[[]dto.SunoDataResponse]
+	err = json.Unmarshal(responseBody, &responseItems)
+	if err != nil {
+		return nil, fmt.Errorf("parse task response failed: %w, body: %s", err, string(responseBody))
+	}
+	if !responseItems.IsSuccess() {
+		return nil, fmt.Errorf("get task failed: %s", responseItems.Message)
+	}
+	results := make([]dto.TaskResult, 0, len(responseItems.Data))
+	for _, item := range responseItems.Data {
+		results = append(results, dto.TaskResult{
+			TaskID:     item.TaskID,
+			Status:     item.Status,
+			FailReason: item.FailReason,
+			SubmitTime: item.SubmitTime,
+			StartTime:  item.StartTime,
+			FinishTime: item.FinishTime,
+			Data:       item.Data,
+		})
+	}
+	return results, nil
 }
 
 func actionValidate(c *gin.Context, sunoRequest *dto.SunoSubmitReq, action string) (err error) {
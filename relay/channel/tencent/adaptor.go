@@ -9,7 +9,6 @@ import (
 	"one-api/dto"
 	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
-	"one-api/service"
 	"strconv"
 	"strings"
 
@@ -95,12 +94,11 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *dto.OpenAIErrorWithStatusCode) {
-	if info.IsStream {
-		var responseText string
-		err, responseText = tencentStreamHandler(c, resp)
-		usage, _ = service.ResponseText2Usage(responseText, info.UpstreamModelName, info.PromptTokens)
+	// 流式请求出错时上游直接返回 JSON 而不是 SSE
+	if info.IsStream && !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		err, usage = tencentStreamHandler(c, resp, info)
 	} else {
-		err, usage = tencentHandler(c, resp)
+		err, usage = tencentHandler(c, resp, info)
 	}
 	return
 }
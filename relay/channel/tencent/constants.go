@@ -5,6 +5,8 @@ var ModelList = []string{
 	"hunyuan-standard",
 	"hunyuan-standard-256K",
 	"hunyuan-pro",
+	"hunyuan-turbo",
+	"hunyuan-large",
 }
 
 var ChannelName = "tencent"
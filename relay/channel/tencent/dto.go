@@ -41,10 +41,15 @@ type TencentChatRequest struct {
 	// 2. 取值区间为 [0.0, 2.0]，未传值时使用各模型推荐值。
 	// 3. 非必要不建议使用，不合理的取值会影响效果。
 	Temperature *float64 `json:"Temperature,omitempty"`
+	// 说明：
+	// 1. 确保模型的输出是可复现的。
+	// 2. 取值区间为非0正整数，最大值10000。
+	Seed *int64 `json:"Seed,omitempty"`
 }
 
 type TencentError struct {
-	Code    int    `json:"Code"`
+	// API 3.0 的错误码为字符串，例如 AuthFailure.SignatureFailure
+	Code    string `json:"Code"`
 	Message string `json:"Message"`
 }
 
@@ -61,13 +66,14 @@ type TencentResponseChoices struct {
 }
 
 type TencentChatResponse struct {
-	Choices []TencentResponseChoices `json:"Choices,omitempty"` // 结果
-	Created int64                    `json:"Created,omitempty"` // unix 时间戳的字符串
-	Id      string                   `json:"Id,omitempty"`      // 会话 id
-	Usage   TencentUsage             `json:"Usage,omitempty"`   // token 数量
-	Error   TencentError             `json:"Error,omitempty"`   // 错误信息 注意：此字段可能返回 null，表示取不到有效值
-	Note    string                   `json:"Note,omitempty"`    // 注释
-	ReqID   string                   `json:"Req_id,omitempty"`  // 唯一请求 Id，每次请求都会返回。用于反馈接口入参
+	Choices   []TencentResponseChoices `json:"Choices,omitempty"`   // 结果
+	Created   int64                    `json:"Created,omitempty"`   // unix 时间戳的字符串
+	Id        string                   `json:"Id,omitempty"`        // 会话 id
+	Usage     TencentUsage             `json:"Usage,omitempty"`     // token 数量
+	Error     TencentError             `json:"Error,omitempty"`     // 错误信息 注意：此字段可能返回 null，表示取不到有效值
+	Note      string                   `json:"Note,omitempty"`      // 注释
+	ReqID     string                   `json:"Req_id,omitempty"`    // 唯一请求 Id，每次请求都会返回。用于反馈接口入参
+	RequestId string                   `json:"RequestId,omitempty"` // 唯一请求 ID，非流式返回及错误返回时携带
 }
 
 type TencentChatResponseSB struct {
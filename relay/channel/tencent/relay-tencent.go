@@ -1,7 +1,6 @@
 package tencent
 
 import (
-	"bufio"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -12,8 +11,8 @@ import (
 	"io"
 	"net/http"
 	"one-api/common"
-	"one-api/constant"
 	"one-api/dto"
+	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
 	"strconv"
@@ -41,99 +40,118 @@ func requestOpenAI2Tencent(a *Adaptor, request dto.GeneralOpenAIRequest) *Tencen
 		req.TopP = &request.TopP
 	}
 	req.Temperature = request.Temperature
+	if request.Seed > 0 {
+		req.Seed = common.GetPointer(int64(request.Seed))
+	}
 	return &req
 }
 
-func responseTencent2OpenAI(response *TencentChatResponse) *dto.OpenAITextResponse {
+// finishReasonTencent2OpenAI stop、sensitive、tool_calls 均由默认映射处理
+func finishReasonTencent2OpenAI(reason string) string {
+	return helper.DefaultFinishReasons.Normalize(reason)
+}
+
+func usageTencent2OpenAI(usage TencentUsage) dto.Usage {
+	return dto.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func responseTencent2OpenAI(response *TencentChatResponse, info *relaycommon.RelayInfo) *dto.OpenAITextResponse {
 	fullTextResponse := dto.OpenAITextResponse{
 		Id:      response.Id,
+		Model:   info.UpstreamModelName,
 		Object:  "chat.completion",
 		Created: common.GetTimestamp(),
-		Usage: dto.Usage{
-			PromptTokens:     response.Usage.PromptTokens,
-			CompletionTokens: response.Usage.CompletionTokens,
-			TotalTokens:      response.Usage.TotalTokens,
-		},
-	}
-	if len(response.Choices) > 0 {
-		content, _ := json.Marshal(response.Choices[0].Messages.Content)
+		Usage:   usageTencent2OpenAI(response.Usage),
+	}
+	for i, tencentChoice := range response.Choices {
 		choice := dto.OpenAITextResponseChoice{
-			Index: 0,
+			Index: i,
 			Message: dto.Message{
-				Role:    "assistant",
-				Content: content,
+				Role: "assistant",
 			},
-			FinishReason: response.Choices[0].FinishReason,
+			FinishReason: finishReasonTencent2OpenAI(tencentChoice.FinishReason),
 		}
+		choice.Message.SetStringContent(tencentChoice.Messages.Content)
 		fullTextResponse.Choices = append(fullTextResponse.Choices, choice)
 	}
 	return &fullTextResponse
 }
 
-func streamResponseTencent2OpenAI(TencentResponse *TencentChatResponse) *dto.ChatCompletionsStreamResponse {
+func streamResponseTencent2OpenAI(tencentResponse *TencentChatResponse, id string, createAt int64, model string) *dto.ChatCompletionsStreamResponse {
 	response := dto.ChatCompletionsStreamResponse{
+		Id:      id,
 		Object:  "chat.completion.chunk",
-		Created: common.GetTimestamp(),
-		Model:   "tencent-hunyuan",
+		Created: createAt,
+		Model:   model,
 	}
-	if len(TencentResponse.Choices) > 0 {
+	for i, tencentChoice := range tencentResponse.Choices {
 		var choice dto.ChatCompletionsStreamResponseChoice
-		choice.Delta.SetContentString(TencentResponse.Choices[0].Delta.Content)
-		if TencentResponse.Choices[0].FinishReason == "stop" {
-			choice.FinishReason = &constant.FinishReasonStop
+		choice.Index = i
+		choice.Delta.Role = tencentChoice.Delta.Role
+		choice.Delta.SetContentString(tencentChoice.Delta.Content)
+		if tencentChoice.FinishReason != "" {
+			finishReason := finishReasonTencent2OpenAI(tencentChoice.FinishReason)
+			choice.FinishReason = &finishReason
 		}
 		response.Choices = append(response.Choices, choice)
 	}
 	return &response
 }
 
-func tencentStreamHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWithStatusCode, string) {
-	var responseText string
-	scanner := bufio.NewScanner(resp.Body)
-	scanner.Split(bufio.ScanLines)
-
-	helper.SetEventStreamHeaders(c)
-
-	for scanner.Scan() {
-		data := scanner.Text()
-		if len(data) < 5 || !strings.HasPrefix(data, "data:") {
-			continue
-		}
-		data = strings.TrimPrefix(data, "data:")
+func tencentStreamHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
+	var responseText strings.Builder
+	var usage dto.Usage
 
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
 		var tencentResponse TencentChatResponse
-		err := json.Unmarshal([]byte(data), &tencentResponse)
+		err := common.DecodeJsonStr(data, &tencentResponse)
 		if err != nil {
-			common.SysError("error unmarshalling stream response: " + err.Error())
-			continue
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			return true
+		}
+		if tencentResponse.Error.Code != "" {
+			common.LogError(c, fmt.Sprintf("tencent stream error: %s %s", tencentResponse.Error.Code, tencentResponse.Error.Message))
+			return false
+		}
+		if tencentResponse.Usage.TotalTokens != 0 {
+			usage = usageTencent2OpenAI(tencentResponse.Usage)
 		}
 
-		response := streamResponseTencent2OpenAI(&tencentResponse)
-		if len(response.Choices) != 0 {
-			responseText += response.Choices[0].Delta.GetContentString()
+		response := streamResponseTencent2OpenAI(&tencentResponse, id, createAt, info.UpstreamModelName)
+		for _, choice := range response.Choices {
+			responseText.WriteString(choice.Delta.GetContentString())
 		}
 
 		err = helper.ObjectData(c, response)
 		if err != nil {
-			common.SysError(err.Error())
+			common.LogError(c, err.Error())
 		}
-	}
+		return true
+	})
 
-	if err := scanner.Err(); err != nil {
-		common.SysError("error reading stream: " + err.Error())
+	// 上游未返回 usage 时按输出文本估算
+	if usage.TotalTokens == 0 {
+		textUsage, _ := service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+		usage = *textUsage
 	}
-
-	helper.Done(c)
-
-	err := resp.Body.Close()
-	if err != nil {
-		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), ""
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, usage)
+		err := helper.ObjectData(c, response)
+		if err != nil {
+			common.SysError("send final response failed: " + err.Error())
+		}
 	}
-
-	return nil, responseText
+	helper.Done(c)
+	return nil, &usage
 }
 
-func tencentHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+func tencentHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
 	var tencentSb TencentChatResponseSB
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -147,16 +165,18 @@ func tencentHandler(c *gin.Context, resp *http.Response) (*dto.OpenAIErrorWithSt
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
 	}
-	if tencentSb.Response.Error.Code != 0 {
+	if tencentSb.Response.Error.Code != "" {
 		return &dto.OpenAIErrorWithStatusCode{
 			Error: dto.OpenAIError{
 				Message: tencentSb.Response.Error.Message,
+				Type:    "tencent_error",
+				Param:   tencentSb.Response.RequestId,
 				Code:    tencentSb.Response.Error.Code,
 			},
 			StatusCode: resp.StatusCode,
 		}, nil
 	}
-	fullTextResponse := responseTencent2OpenAI(&tencentSb.Response)
+	fullTextResponse := responseTencent2OpenAI(&tencentSb.Response, info)
 	jsonResponse, err := json.Marshal(fullTextResponse)
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
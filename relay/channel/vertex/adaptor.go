@@ -22,6 +22,7 @@ const (
 	RequestModeClaude = 1
 	RequestModeGemini = 2
 	RequestModeLlama  = 3
+	RequestModeImagen = 4
 )
 
 var claudeModelMap = map[string]string{
@@ -58,8 +59,10 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
-	//TODO implement me
-	return nil, errors.New("not implemented")
+	if a.RequestMode != RequestModeImagen {
+		return nil, errors.New("not supported model for image generation")
+	}
+	return requestOpenAI2Imagen(request)
 }
 
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
@@ -69,6 +72,8 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 		a.RequestMode = RequestModeGemini
 	} else if strings.Contains(info.UpstreamModelName, "llama") {
 		a.RequestMode = RequestModeLlama
+	} else if strings.HasPrefix(info.UpstreamModelName, "imagen") {
+		a.RequestMode = RequestModeImagen
 	}
 }
 
@@ -137,6 +142,18 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 			adc.ProjectID,
 			region,
 		), nil
+	} else if a.RequestMode == RequestModeImagen {
+		// Imagen 不提供 global 端点
+		if region == "global" {
+			region = "us-central1"
+		}
+		return fmt.Sprintf(
+			"https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:predict",
+			region,
+			adc.ProjectID,
+			region,
+			info.UpstreamModelName,
+		), nil
 	}
 	return "", errors.New("unsupported request mode")
 }
@@ -207,6 +224,8 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		}
 	} else {
 		switch a.RequestMode {
+		case RequestModeImagen:
+			usage, err = gemini.GeminiImageHandler(c, resp, info)
 		case RequestModeClaude:
 			err, usage = claude.ClaudeHandler(c, resp, claude.RequestModeMessage, info)
 		case RequestModeGemini:
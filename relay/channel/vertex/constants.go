@@ -10,6 +10,8 @@ var ModelList = []string{
 	//"gemini-1.5-pro-001", "gemini-1.5-flash-001", "gemini-pro", "gemini-pro-vision",
 
 	"meta/llama3-405b-instruct-maas",
+
+	"imagen-3.0-generate-001", "imagen-3.0-fast-generate-001",
 }
 
 var ChannelName = "vertex-ai"
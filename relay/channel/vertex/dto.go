@@ -2,6 +2,7 @@ package vertex
 
 import (
 	"one-api/dto"
+	"one-api/relay/channel/gemini"
 )
 
 type VertexAIClaudeRequest struct {
@@ -35,3 +36,22 @@ func copyRequest(req *dto.ClaudeRequest, version string) *VertexAIClaudeRequest
 		Thinking:         req.Thinking,
 	}
 }
+
+// VertexImagenRequest Vertex AI 上的 Imagen 支持 Gemini API 之外的输出格式和提示词增强参数
+type VertexImagenRequest struct {
+	Instances  []gemini.GeminiImageInstance `json:"instances"`
+	Parameters VertexImagenParameters       `json:"parameters"`
+}
+
+type VertexImagenParameters struct {
+	SampleCount      int                  `json:"sampleCount,omitempty"`
+	AspectRatio      string               `json:"aspectRatio,omitempty"`
+	PersonGeneration string               `json:"personGeneration,omitempty"`
+	EnhancePrompt    *bool                `json:"enhancePrompt,omitempty"`
+	OutputOptions    *VertexImagenOptions `json:"outputOptions,omitempty"`
+}
+
+type VertexImagenOptions struct {
+	MimeType           string `json:"mimeType,omitempty"`
+	CompressionQuality int    `json:"compressionQuality,omitempty"`
+}
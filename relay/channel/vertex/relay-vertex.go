@@ -1,6 +1,12 @@
 package vertex
 
-import "one-api/common"
+import (
+	"errors"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/relay/channel/gemini"
+	"strings"
+)
 
 func GetModelRegion(other string, localModelName string) string {
 	// if other is json string
@@ -14,3 +20,68 @@ func GetModelRegion(other string, localModelName string) string {
 	}
 	return other
 }
+
+// imagenMaxSampleCount Imagen 单次最多生成的图片数
+const imagenMaxSampleCount = 4
+
+// imagenAspectRatios OpenAI 的图片尺寸对应的 Imagen 宽高比，也可以直接传入宽高比
+var imagenAspectRatios = map[string]string{
+	"1024x1024": "1:1",
+	"1024x1792": "9:16",
+	"768x1344":  "9:16",
+	"1792x1024": "16:9",
+	"1344x768":  "16:9",
+	"1024x1536": "3:4",
+	"896x1280":  "3:4",
+	"1536x1024": "4:3",
+	"1280x896":  "4:3",
+}
+
+func imagenAspectRatio(size string) (string, error) {
+	if size == "" {
+		return "1:1", nil
+	}
+	if ratio, ok := imagenAspectRatios[size]; ok {
+		return ratio, nil
+	}
+	for _, ratio := range imagenAspectRatios {
+		if ratio == size {
+			return ratio, nil
+		}
+	}
+	return "", errors.New("unsupported size for imagen, use one of 1024x1024, 1024x1792, 1792x1024, 1024x1536, 1536x1024 or an aspect ratio like 16:9")
+}
+
+// requestOpenAI2Imagen quality 为 hd/high 时开启提示词增强，为 low 时输出压缩后的 JPEG
+func requestOpenAI2Imagen(request dto.ImageRequest) (*VertexImagenRequest, error) {
+	if request.N > imagenMaxSampleCount {
+		return nil, errors.New("n must be between 1 and 4 for imagen models")
+	}
+	aspectRatio, err := imagenAspectRatio(request.Size)
+	if err != nil {
+		return nil, err
+	}
+	imagenRequest := &VertexImagenRequest{
+		Instances: []gemini.GeminiImageInstance{
+			{
+				Prompt: request.Prompt,
+			},
+		},
+		Parameters: VertexImagenParameters{
+			SampleCount:      request.N,
+			AspectRatio:      aspectRatio,
+			PersonGeneration: "allow_adult",
+		},
+	}
+	switch strings.ToLower(request.Quality) {
+	case "hd", "high":
+		enhancePrompt := true
+		imagenRequest.Parameters.EnhancePrompt = &enhancePrompt
+	case "low":
+		imagenRequest.Parameters.OutputOptions = &VertexImagenOptions{
+			MimeType:           "image/jpeg",
+			CompressionQuality: 75,
+		}
+	}
+	return imagenRequest, nil
+}
@@ -0,0 +1,8 @@
+package voyage
+
+var ModelList = []string{
+	"voyage-3.5", "voyage-3.5-lite", "voyage-3-large", "voyage-code-3", "voyage-finance-2", "voyage-law-2",
+	"rerank-2", "rerank-2-lite",
+}
+
+var ChannelName = "voyage"
@@ -0,0 +1,29 @@
+package voyage
+
+import "one-api/dto"
+
+type VoyageRerankRequest struct {
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	Model           string   `json:"model"`
+	TopK            int      `json:"top_k,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type VoyageRerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       string  `json:"document,omitempty"`
+}
+
+type VoyageRerankResponse struct {
+	Data  []VoyageRerankResult `json:"data"`
+	Model string               `json:"model"`
+	Usage dto.Usage            `json:"usage"`
+}
+
+type VoyageEmbeddingRequest struct {
+	Input           any    `json:"input"`
+	Model           string `json:"model"`
+	OutputDimension int    `json:"output_dimension,omitempty"`
+}
@@ -0,0 +1,99 @@
+package voyage
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func requestConvertRerank2Voyage(rerankRequest dto.RerankRequest) *VoyageRerankRequest {
+	return &VoyageRerankRequest{
+		Query:           rerankRequest.Query,
+		Documents:       rerankRequest.DocumentTexts(),
+		Model:           rerankRequest.Model,
+		TopK:            rerankRequest.TopN,
+		ReturnDocuments: rerankRequest.GetReturnDocuments(),
+	}
+}
+
+func requestConvertEmbedding2Voyage(request dto.EmbeddingRequest) *VoyageEmbeddingRequest {
+	// encoding_format 由网关处理，上游始终返回浮点数组
+	return &VoyageEmbeddingRequest{
+		Input:           request.Input,
+		Model:           request.Model,
+		OutputDimension: request.Dimensions,
+	}
+}
+
+func voyageRerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var voyageResp VoyageRerankResponse
+	err = common.DecodeJson(responseBody, &voyageResp)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	results := make([]dto.RerankResponseResult, len(voyageResp.Data))
+	for i, result := range voyageResp.Data {
+		results[i] = dto.RerankResponseResult{
+			Index:          result.Index,
+			RelevanceScore: result.RelevanceScore,
+		}
+		if info.ReturnDocuments && result.Index < len(info.Documents) {
+			results[i].Document = info.Documents[result.Index]
+		}
+	}
+	usage := dto.Usage{
+		PromptTokens: voyageResp.Usage.TotalTokens,
+		TotalTokens:  voyageResp.Usage.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.PromptTokens = info.PromptTokens
+		usage.TotalTokens = info.PromptTokens
+	}
+	c.JSON(http.StatusOK, dto.RerankResponse{Results: results, Usage: usage})
+	return nil, &usage
+}
+
+// voyageEmbeddingHandler 响应与 OpenAI 格式相同，但用量中只有 total_tokens
+func voyageEmbeddingHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var voyageResp struct {
+		Usage dto.Usage `json:"usage"`
+	}
+	err = json.Unmarshal(responseBody, &voyageResp)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	usage := dto.Usage{
+		PromptTokens: voyageResp.Usage.TotalTokens,
+		TotalTokens:  voyageResp.Usage.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.PromptTokens = info.PromptTokens
+		usage.TotalTokens = info.PromptTokens
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(responseBody)
+	return nil, &usage
+}
@@ -1,6 +1,7 @@
 package xunfei
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -8,12 +9,12 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"io"
 	"net/http"
 	"net/url"
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
 	"strings"
@@ -79,7 +80,7 @@ func responseXunfei2OpenAI(response *XunfeiChatResponse) *dto.OpenAITextResponse
 	return &fullTextResponse
 }
 
-func streamResponseXunfei2OpenAI(xunfeiResponse *XunfeiChatResponse) *dto.ChatCompletionsStreamResponse {
+func streamResponseXunfei2OpenAI(xunfeiResponse *XunfeiChatResponse, id string, createAt int64, model string) *dto.ChatCompletionsStreamResponse {
 	if len(xunfeiResponse.Payload.Choices.Text) == 0 {
 		xunfeiResponse.Payload.Choices.Text = []XunfeiChatResponseTextItem{
 			{
@@ -93,14 +94,28 @@ func streamResponseXunfei2OpenAI(xunfeiResponse *XunfeiChatResponse) *dto.ChatCo
 		choice.FinishReason = &constant.FinishReasonStop
 	}
 	response := dto.ChatCompletionsStreamResponse{
+		Id:      id,
 		Object:  "chat.completion.chunk",
-		Created: common.GetTimestamp(),
-		Model:   "SparkDesk",
+		Created: createAt,
+		Model:   model,
 		Choices: []dto.ChatCompletionsStreamResponseChoice{choice},
 	}
 	return &response
 }
 
+// xunfeiErrorWrapper 星火的错误通过帧头的 code 返回，code 为 0 表示成功
+func xunfeiErrorWrapper(response *XunfeiChatResponse) *dto.OpenAIErrorWithStatusCode {
+	return &dto.OpenAIErrorWithStatusCode{
+		Error: dto.OpenAIError{
+			Message: response.Header.Message,
+			Type:    "xunfei_error",
+			Param:   response.Header.Sid,
+			Code:    response.Header.Code,
+		},
+		StatusCode: http.StatusInternalServerError,
+	}
+}
+
 func buildXunfeiAuthUrl(hostUrl string, apiKey, apiSecret string) string {
 	HmacWithShaToBase64 := func(algorithm, data, key string) string {
 		mac := hmac.New(sha256.New, []byte(key))
@@ -127,58 +142,72 @@ func buildXunfeiAuthUrl(hostUrl string, apiKey, apiSecret string) string {
 	return callUrl
 }
 
-func xunfeiStreamHandler(c *gin.Context, textRequest dto.GeneralOpenAIRequest, appId string, apiSecret string, apiKey string) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+func xunfeiStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, textRequest dto.GeneralOpenAIRequest, appId string, apiSecret string, apiKey string) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
 	domain, authUrl := getXunfeiAuthUrl(c, apiKey, apiSecret, textRequest.Model)
-	dataChan, stopChan, err := xunfeiMakeRequest(textRequest, domain, authUrl, appId)
+	dataChan, err := xunfeiMakeRequest(c.Request.Context(), textRequest, domain, authUrl, appId)
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "make xunfei request err", http.StatusInternalServerError), nil
 	}
-	helper.SetEventStreamHeaders(c)
+	id := helper.GetResponseID(c)
+	createAt := common.GetTimestamp()
 	var usage dto.Usage
-	c.Stream(func(w io.Writer) bool {
-		select {
-		case xunfeiResponse := <-dataChan:
-			usage.PromptTokens += xunfeiResponse.Payload.Usage.Text.PromptTokens
-			usage.CompletionTokens += xunfeiResponse.Payload.Usage.Text.CompletionTokens
-			usage.TotalTokens += xunfeiResponse.Payload.Usage.Text.TotalTokens
-			response := streamResponseXunfei2OpenAI(&xunfeiResponse)
-			jsonResponse, err := json.Marshal(response)
-			if err != nil {
-				common.SysError("error marshalling stream response: " + err.Error())
-				return true
+	started := false
+	for xunfeiResponse := range dataChan {
+		if xunfeiResponse.Header.Code != 0 {
+			// 尚未向客户端输出时可以直接返回错误
+			if !started {
+				return xunfeiErrorWrapper(&xunfeiResponse), nil
 			}
-			c.Render(-1, common.CustomEvent{Data: "data: " + string(jsonResponse)})
-			return true
-		case <-stopChan:
-			c.Render(-1, common.CustomEvent{Data: "data: [DONE]"})
-			return false
+			common.LogError(c, fmt.Sprintf("xunfei stream error: %d %s", xunfeiResponse.Header.Code, xunfeiResponse.Header.Message))
+			break
+		}
+		if !started {
+			helper.SetEventStreamHeaders(c)
+			started = true
+		}
+		usage.PromptTokens += xunfeiResponse.Payload.Usage.Text.PromptTokens
+		usage.CompletionTokens += xunfeiResponse.Payload.Usage.Text.CompletionTokens
+		usage.TotalTokens += xunfeiResponse.Payload.Usage.Text.TotalTokens
+		response := streamResponseXunfei2OpenAI(&xunfeiResponse, id, createAt, info.UpstreamModelName)
+		err = helper.ObjectData(c, response)
+		if err != nil {
+			common.LogError(c, "error rendering stream response: "+err.Error())
+		}
+	}
+	if !started {
+		helper.SetEventStreamHeaders(c)
+	}
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(id, createAt, info.UpstreamModelName, usage)
+		err = helper.ObjectData(c, response)
+		if err != nil {
+			common.SysError("send final response failed: " + err.Error())
 		}
-	})
+	}
+	helper.Done(c)
 	return nil, &usage
 }
 
-func xunfeiHandler(c *gin.Context, textRequest dto.GeneralOpenAIRequest, appId string, apiSecret string, apiKey string) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
+func xunfeiHandler(c *gin.Context, info *relaycommon.RelayInfo, textRequest dto.GeneralOpenAIRequest, appId string, apiSecret string, apiKey string) (*dto.OpenAIErrorWithStatusCode, *dto.Usage) {
 	domain, authUrl := getXunfeiAuthUrl(c, apiKey, apiSecret, textRequest.Model)
-	dataChan, stopChan, err := xunfeiMakeRequest(textRequest, domain, authUrl, appId)
+	dataChan, err := xunfeiMakeRequest(c.Request.Context(), textRequest, domain, authUrl, appId)
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "make xunfei request err", http.StatusInternalServerError), nil
 	}
 	var usage dto.Usage
 	var content string
 	var xunfeiResponse XunfeiChatResponse
-	stop := false
-	for !stop {
-		select {
-		case xunfeiResponse = <-dataChan:
-			if len(xunfeiResponse.Payload.Choices.Text) == 0 {
-				continue
-			}
-			content += xunfeiResponse.Payload.Choices.Text[0].Content
-			usage.PromptTokens += xunfeiResponse.Payload.Usage.Text.PromptTokens
-			usage.CompletionTokens += xunfeiResponse.Payload.Usage.Text.CompletionTokens
-			usage.TotalTokens += xunfeiResponse.Payload.Usage.Text.TotalTokens
-		case stop = <-stopChan:
+	for xunfeiResponse = range dataChan {
+		if xunfeiResponse.Header.Code != 0 {
+			return xunfeiErrorWrapper(&xunfeiResponse), nil
 		}
+		if len(xunfeiResponse.Payload.Choices.Text) == 0 {
+			continue
+		}
+		content += xunfeiResponse.Payload.Choices.Text[0].Content
+		usage.PromptTokens += xunfeiResponse.Payload.Usage.Text.PromptTokens
+		usage.CompletionTokens += xunfeiResponse.Payload.Usage.Text.CompletionTokens
+		usage.TotalTokens += xunfeiResponse.Payload.Usage.Text.TotalTokens
 	}
 	if len(xunfeiResponse.Payload.Choices.Text) == 0 {
 		xunfeiResponse.Payload.Choices.Text = []XunfeiChatResponseTextItem{
@@ -190,6 +219,8 @@ func xunfeiHandler(c *gin.Context, textRequest dto.GeneralOpenAIRequest, appId s
 	xunfeiResponse.Payload.Choices.Text[0].Content = content
 
 	response := responseXunfei2OpenAI(&xunfeiResponse)
+	response.Id = xunfeiResponse.Header.Sid
+	response.Model = info.UpstreamModelName
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		return service.OpenAIErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
@@ -199,48 +230,62 @@ func xunfeiHandler(c *gin.Context, textRequest dto.GeneralOpenAIRequest, appId s
 	return nil, &usage
 }
 
-func xunfeiMakeRequest(textRequest dto.GeneralOpenAIRequest, domain, authUrl, appId string) (chan XunfeiChatResponse, chan bool, error) {
+// xunfeiMakeRequest 建立 WebSocket 连接并发送请求，返回的通道在收到尾帧、错误帧或客户端断开后关闭
+func xunfeiMakeRequest(ctx context.Context, textRequest dto.GeneralOpenAIRequest, domain, authUrl, appId string) (chan XunfeiChatResponse, error) {
 	d := websocket.Dialer{
 		HandshakeTimeout: 5 * time.Second,
 	}
-	conn, resp, err := d.Dial(authUrl, nil)
-	if err != nil || resp.StatusCode != 101 {
-		return nil, nil, err
+	conn, resp, err := d.DialContext(ctx, authUrl, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("dial xunfei failed with status %d: %w", resp.StatusCode, err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_ = conn.Close()
+		return nil, fmt.Errorf("dial xunfei failed with status %d", resp.StatusCode)
 	}
 	data := requestOpenAI2Xunfei(textRequest, appId, domain)
 	err = conn.WriteJSON(data)
 	if err != nil {
-		return nil, nil, err
+		_ = conn.Close()
+		return nil, err
 	}
 
 	dataChan := make(chan XunfeiChatResponse)
-	stopChan := make(chan bool)
 	go func() {
+		defer close(dataChan)
+		defer func() {
+			err := conn.Close()
+			if err != nil {
+				common.SysError("error closing websocket connection: " + err.Error())
+			}
+		}()
 		for {
 			_, msg, err := conn.ReadMessage()
 			if err != nil {
 				common.SysError("error reading stream response: " + err.Error())
-				break
+				return
 			}
 			var response XunfeiChatResponse
 			err = json.Unmarshal(msg, &response)
 			if err != nil {
 				common.SysError("error unmarshalling stream response: " + err.Error())
-				break
+				return
+			}
+			select {
+			case dataChan <- response:
+			case <-ctx.Done():
+				return
 			}
-			dataChan <- response
-			if response.Payload.Choices.Status == 2 {
-				err := conn.Close()
-				if err != nil {
-					common.SysError("error closing websocket connection: " + err.Error())
-				}
-				break
+			if response.Header.Code != 0 || response.Payload.Choices.Status == 2 {
+				return
 			}
 		}
-		stopChan <- true
 	}()
 
-	return dataChan, stopChan, nil
+	return dataChan, nil
 }
 
 func apiVersion2domain(apiVersion string) string {
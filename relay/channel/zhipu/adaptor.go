@@ -79,7 +79,7 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *dto.OpenAIErrorWithStatusCode) {
 	if info.IsStream {
-		err, usage = zhipuStreamHandler(c, resp)
+		err, usage = zhipuStreamHandler(c, resp, info)
 	} else {
 		err, usage = zhipuHandler(c, resp)
 	}
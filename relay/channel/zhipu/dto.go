@@ -23,7 +23,7 @@ type ZhipuResponseData struct {
 	RequestId  string         `json:"request_id"`
 	TaskStatus string         `json:"task_status"`
 	Choices    []ZhipuMessage `json:"choices"`
-	dto.Usage  `json:"usage"`
+	Usage      dto.Usage      `json:"usage"`
 }
 
 type ZhipuResponse struct {
@@ -34,10 +34,10 @@ type ZhipuResponse struct {
 }
 
 type ZhipuStreamMetaResponse struct {
-	RequestId  string `json:"request_id"`
-	TaskId     string `json:"task_id"`
-	TaskStatus string `json:"task_status"`
-	dto.Usage  `json:"usage"`
+	RequestId  string    `json:"request_id"`
+	TaskId     string    `json:"task_id"`
+	TaskStatus string    `json:"task_status"`
+	Usage      dto.Usage `json:"usage"`
 }
 
 type zhipuTokenData struct {
@@ -5,3 +5,6 @@ var ModelList = []string{
 }
 
 var ChannelName = "zhipu_4v"
+
+// zhipuMaxStopSequences 智谱目前只支持一个停止词
+const zhipuMaxStopSequences = 1
@@ -4,6 +4,7 @@ import (
 	"github.com/golang-jwt/jwt"
 	"one-api/common"
 	"one-api/dto"
+	"one-api/service"
 	"strings"
 	"sync"
 	"time"
@@ -65,6 +66,9 @@ func getZhipuToken(apikey string) string {
 }
 
 func requestOpenAI2Zhipu(request dto.GeneralOpenAIRequest) *dto.GeneralOpenAIRequest {
+	// tool_choice 只支持 auto
+	service.EmulateToolChoice(&request)
+	service.EmulateParallelToolCalls(&request)
 	messages := make([]dto.Message, 0, len(request.Messages))
 	for _, message := range request.Messages {
 		if !message.IsStringContent() {
@@ -92,13 +96,6 @@ func requestOpenAI2Zhipu(request dto.GeneralOpenAIRequest) *dto.GeneralOpenAIReq
 			ToolCallId: message.ToolCallId,
 		})
 	}
-	str, ok := request.Stop.(string)
-	var Stop []string
-	if ok {
-		Stop = []string{str}
-	} else {
-		Stop, _ = request.Stop.([]string)
-	}
 	return &dto.GeneralOpenAIRequest{
 		Model:       request.Model,
 		Stream:      request.Stream,
@@ -106,7 +103,7 @@ func requestOpenAI2Zhipu(request dto.GeneralOpenAIRequest) *dto.GeneralOpenAIReq
 		Temperature: request.Temperature,
 		TopP:        request.TopP,
 		MaxTokens:   request.MaxTokens,
-		Stop:        Stop,
+		Stop:        request.Stop.Limit(zhipuMaxStopSequences),
 		Tools:       request.Tools,
 		ToolChoice:  request.ToolChoice,
 	}
@@ -11,6 +11,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting/model_setting"
@@ -50,6 +51,7 @@ func ClaudeHelper(c *gin.Context) (claudeError *dto.ClaudeErrorWithStatusCode) {
 	if err != nil {
 		return service.ClaudeErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
 	}
+	defer rewriteResponseModel(c, relayInfo)()
 
 	textRequest.Model = relayInfo.UpstreamModelName
 
@@ -80,6 +82,12 @@ func ClaudeHelper(c *gin.Context) (claudeError *dto.ClaudeErrorWithStatusCode) {
 	if adaptor == nil {
 		return service.ClaudeErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
 	}
+	// 没有 Claude 接口的渠道按 OpenAI 对话请求转发，响应再转换回 Claude 格式
+	convertToOpenAI := !supportClaudeRequest(relayInfo)
+	if convertToOpenAI {
+		relayInfo.RelayMode = relayconstant.RelayModeChatCompletions
+		relayInfo.RequestURLPath = "/v1/chat/completions"
+	}
 	adaptor.Init(relayInfo)
 	var requestBody io.Reader
 
@@ -109,7 +117,21 @@ func ClaudeHelper(c *gin.Context) (claudeError *dto.ClaudeErrorWithStatusCode) {
 		relayInfo.UpstreamModelName = textRequest.Model
 	}
 
-	convertedRequest, err := adaptor.ConvertClaudeRequest(c, relayInfo, textRequest)
+	var convertedRequest any
+	if convertToOpenAI {
+		var openAIRequest *dto.GeneralOpenAIRequest
+		openAIRequest, err = service.ClaudeToOpenAIRequest(*textRequest, relayInfo)
+		if err != nil {
+			return service.ClaudeErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
+		}
+		if relayInfo.SupportStreamOptions && openAIRequest.Stream {
+			openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+		}
+		relayInfo.RelayFormat = relaycommon.RelayFormatOpenAI
+		convertedRequest, err = adaptor.ConvertOpenAIRequest(c, relayInfo, openAIRequest)
+	} else {
+		convertedRequest, err = adaptor.ConvertClaudeRequest(c, relayInfo, textRequest)
+	}
 	if err != nil {
 		return service.ClaudeErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
 	}
@@ -140,20 +162,61 @@ func ClaudeHelper(c *gin.Context) (claudeError *dto.ClaudeErrorWithStatusCode) {
 		}
 	}
 
+	var claudeWriter *claudeResponseWriter
+	if convertToOpenAI {
+		claudeWriter = newClaudeResponseWriter(c.Writer, relayInfo)
+		c.Writer = claudeWriter
+	}
 	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	if claudeWriter != nil {
+		c.Writer = claudeWriter.ResponseWriter
+	}
 	//log.Printf("usage: %v", usage)
 	if openaiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return service.OpenAIErrorToClaudeError(openaiErr)
 	}
-	service.PostClaudeConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+	openaiUsage := responseUsage(c, relayInfo, usage)
+	if claudeWriter != nil {
+		claudeWriter.finish(openaiUsage)
+	}
+	if streamAbortedBeforeFirstToken(relayInfo, openaiUsage) {
+		refundPreConsumedQuota(relayInfo, preConsumedQuota, "上游流式响应在返回首个 token 前中断")
+		return service.OpenAIErrorToClaudeError(errStreamAbortedBeforeFirstToken())
+	}
+	if isClaudeUsage(relayInfo) {
+		service.PostClaudeConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
+	} else {
+		// OpenAI 格式的用量，提示词包含缓存部分
+		postConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
+	}
 	return nil
 }
 
+// isClaudeUsage 上游直接返回 Claude 格式的用量
+func isClaudeUsage(info *relaycommon.RelayInfo) bool {
+	switch info.ApiType {
+	case relayconstant.APITypeAnthropic, relayconstant.APITypeAws:
+		return true
+	case relayconstant.APITypeVertexAi:
+		return strings.HasPrefix(info.UpstreamModelName, "claude")
+	}
+	return false
+}
+
+// supportClaudeRequest 渠道适配器可以直接处理 Claude 格式的请求
+func supportClaudeRequest(info *relaycommon.RelayInfo) bool {
+	return isClaudeUsage(info) || info.ApiType == relayconstant.APITypeOpenAI
+}
+
 func getClaudePromptTokens(textRequest *dto.ClaudeRequest, info *relaycommon.RelayInfo) (int, error) {
 	var promptTokens int
 	var err error
+	if skipPromptTokenCount(info) {
+		info.PromptTokens = 0
+		return 0, nil
+	}
 	switch info.RelayMode {
 	default:
 		promptTokens, err = service.CountTokenClaudeRequest(*textRequest, info.UpstreamModelName)
@@ -28,6 +28,7 @@ const (
 type ClaudeConvertInfo struct {
 	LastMessagesType string
 	Index            int
+	ToolCallIndex    int // 当前 tool_use 块对应的 tool_calls 序号
 	Usage            *dto.Usage
 	FinishReason     string
 	Done             bool
@@ -42,6 +43,8 @@ const (
 type RerankerInfo struct {
 	Documents       []any
 	ReturnDocuments bool
+	// SearchUnits 上游返回的搜索单元数，未返回时为 0
+	SearchUnits int
 }
 
 type BuildInToolInfo struct {
@@ -91,6 +94,7 @@ type RelayInfo struct {
 	IsFirstRequest       bool
 	AudioUsage           bool
 	ReasoningEffort      string
+	ToolCallProfile      string // 不为空时工具调用以文本格式与上游交互
 	ChannelSetting       map[string]interface{}
 	ParamOverride        map[string]interface{}
 	UserSetting          map[string]interface{}
@@ -99,6 +103,9 @@ type RelayInfo struct {
 	RelayFormat          string
 	SendResponseCount    int
 	ChannelCreateTime    int64
+	RequestId            string
+	// InlineImageCache 按地址缓存已下载的远程图片，同一请求重试时的 RelayInfo 共享
+	InlineImageCache map[string]*dto.LocalFileData
 	ThinkingContentInfo
 	*ClaudeConvertInfo
 	*RerankerInfo
@@ -118,6 +125,7 @@ var streamSupportedChannels = map[int]bool{
 	common.ChannelTypeXai:        true,
 	common.ChannelTypeDeepSeek:   true,
 	common.ChannelTypeBaiduV2:    true,
+	common.ChannelTypeOpenRouter: true,
 }
 
 func GenRelayInfoWs(c *gin.Context, ws *websocket.Conn) *RelayInfo {
@@ -217,6 +225,7 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 		Organization:      c.GetString("channel_organization"),
 		ChannelSetting:    channelSetting,
 		ChannelCreateTime: c.GetInt64("channel_create_time"),
+		RequestId:         c.GetString(common.RequestIdKey),
 		ParamOverride:     paramOverride,
 		RelayFormat:       RelayFormatOpenAI,
 		ThinkingContentInfo: ThinkingContentInfo{
@@ -224,6 +233,12 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 			SendLastThinkingContent: false,
 		},
 	}
+	if cache, ok := c.Get(constant.ContextKeyInlineImageCache); ok {
+		info.InlineImageCache, _ = cache.(map[string]*dto.LocalFileData)
+	} else {
+		info.InlineImageCache = make(map[string]*dto.LocalFileData)
+		c.Set(constant.ContextKeyInlineImageCache, info.InlineImageCache)
+	}
 	if strings.HasPrefix(c.Request.URL.Path, "/pg") {
 		info.IsPlayground = true
 		info.RequestURLPath = strings.TrimPrefix(info.RequestURLPath, "/pg")
@@ -7,6 +7,7 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"one-api/common"
+	"one-api/constant"
 	"strings"
 )
 
@@ -24,6 +25,36 @@ func GetFullRequestURL(baseURL string, requestURL string, channelType int) strin
 	return fullRequestURL
 }
 
+// FormatRequestPathTemplate 替换请求地址模板中的变量：
+// {base} 渠道 Base URL，{path} 原始请求路径（如 /v1/chat/completions），
+// {endpoint} 去掉 /v1/ 前缀的请求路径（如 chat/completions），{model} 上游模型名称
+func FormatRequestPathTemplate(template string, info *RelayInfo) string {
+	path := info.RequestURLPath
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(path, "/v1"), "/")
+	replacer := strings.NewReplacer(
+		"{base}", strings.TrimSuffix(info.BaseUrl, "/"),
+		"{path}", path,
+		"{endpoint}", endpoint,
+		"{model}", info.UpstreamModelName,
+	)
+	return replacer.Replace(template)
+}
+
+// GetRemoveParams 返回渠道设置中需要在转发前移除的请求参数
+func GetRemoveParams(info *RelayInfo) []string {
+	params, ok := info.ChannelSetting[constant.ChannelSettingRemoveParams].([]interface{})
+	if !ok {
+		return nil
+	}
+	removeParams := make([]string, 0, len(params))
+	for _, param := range params {
+		if name, ok := param.(string); ok && name != "" {
+			removeParams = append(removeParams, name)
+		}
+	}
+	return removeParams
+}
+
 func GetAPIVersion(c *gin.Context) string {
 	query := c.Request.URL.Query()
 	apiVersion := query.Get("api-version")
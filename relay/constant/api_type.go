@@ -34,6 +34,13 @@ const (
 	APITypeXinference
 	APITypeXai
 	APITypeCoze
+	APITypeMiniMax
+	APITypeLingYiWanWu
+	APITypeBaichuan
+	APITypeSageMaker
+	APITypeAzureAI
+	APITypeNvidia
+	APITypeVoyage
 	APITypeDummy // this one is only for count, do not add any channel after this
 )
 
@@ -98,6 +105,20 @@ func ChannelType2APIType(channelType int) (int, bool) {
 		apiType = APITypeXai
 	case common.ChannelTypeCoze:
 		apiType = APITypeCoze
+	case common.ChannelTypeMiniMax:
+		apiType = APITypeMiniMax
+	case common.ChannelTypeLingYiWanWu:
+		apiType = APITypeLingYiWanWu
+	case common.ChannelTypeBaichuan:
+		apiType = APITypeBaichuan
+	case common.ChannelTypeSageMaker:
+		apiType = APITypeSageMaker
+	case common.ChannelTypeAzureAI:
+		apiType = APITypeAzureAI
+	case common.ChannelTypeNvidia:
+		apiType = APITypeNvidia
+	case common.ChannelTypeVoyage:
+		apiType = APITypeVoyage
 	}
 	if apiType == -1 {
 		return APITypeOpenAI, false
@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"one-api/constant"
+	"strings"
+)
+
+// FinishReasonTable 供应商结束原因到 OpenAI 结束原因的映射，键为小写
+type FinishReasonTable map[string]string
+
+// DefaultFinishReasons 各供应商常见的结束原因，OpenAI 兼容渠道的上游可能转发其他供应商的结束原因，也按此转换
+var DefaultFinishReasons = FinishReasonTable{
+	"stop":              constant.FinishReasonStop,
+	"end_turn":          constant.FinishReasonStop,
+	"stop_sequence":     constant.FinishReasonStop,
+	"complete":          constant.FinishReasonStop,
+	"eos":               constant.FinishReasonStop,
+	"length":            constant.FinishReasonLength,
+	"max_tokens":        constant.FinishReasonLength,
+	"max_output_tokens": constant.FinishReasonLength,
+	"tool_calls":        constant.FinishReasonToolCalls,
+	"tool_use":          constant.FinishReasonToolCalls,
+	"function_call":     constant.FinishReasonFunctionCall,
+	"content_filter":    constant.FinishReasonContentFilter,
+	"content_filtered":  constant.FinishReasonContentFilter,
+	"safety":            constant.FinishReasonContentFilter,
+	"refusal":           constant.FinishReasonContentFilter,
+	"sensitive":         constant.FinishReasonContentFilter,
+}
+
+// NewFinishReasonTable 在默认映射的基础上加入适配器特有的结束原因，相同的结束原因以适配器为准
+func NewFinishReasonTable(reasons map[string]string) FinishReasonTable {
+	table := make(FinishReasonTable, len(DefaultFinishReasons)+len(reasons))
+	for reason, finishReason := range DefaultFinishReasons {
+		table[reason] = finishReason
+	}
+	for reason, finishReason := range reasons {
+		table[strings.ToLower(reason)] = finishReason
+	}
+	return table
+}
+
+// Normalize 返回对应的 OpenAI 结束原因，不区分大小写；空字符串表示尚未结束，原样返回，未知的结束原因按 stop 处理
+func (t FinishReasonTable) Normalize(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	if finishReason, ok := t[strings.ToLower(reason)]; ok {
+		return finishReason
+	}
+	return constant.FinishReasonStop
+}
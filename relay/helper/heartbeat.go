@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"context"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// getPingInterval 返回 ping 保活的间隔，未开启时返回 0
+func getPingInterval() time.Duration {
+	generalSettings := operation_setting.GetGeneralSetting()
+	if !generalSettings.PingIntervalEnabled {
+		return 0
+	}
+	pingInterval := time.Duration(generalSettings.PingIntervalSeconds) * time.Second
+	if pingInterval <= 0 {
+		pingInterval = DefaultPingInterval
+	}
+	return pingInterval
+}
+
+// StartPingHeartbeat 开启 ping 保活时，在等待上游首个数据块期间按间隔向客户端发送 SSE 注释，
+// 避免代理和 SDK 因空闲超时断开连接。ping 始终写入调用时的 Writer，调用方替换 c.Writer 截获响应时不受影响；
+// 返回的函数停止发送并等待正在发送的 ping 完成，之后才能向客户端写入
+func StartPingHeartbeat(c *gin.Context) func() {
+	pingInterval := getPingInterval()
+	if pingInterval == 0 {
+		return func() {}
+	}
+	SetEventStreamHeaders(c)
+	writer := c.Writer
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	gopool.Go(func() {
+		defer wg.Done()
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := writer.Write([]byte(": PING\n\n")); err != nil {
+					common.LogError(c, "SSE ping error: "+err.Error())
+					return
+				}
+				writer.Flush()
+				if common.DebugEnabled {
+					println("SSE ping data sent.")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
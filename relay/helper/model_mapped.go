@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"one-api/constant"
 	"one-api/relay/common"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,42 +17,161 @@ func ModelMappedHelper(c *gin.Context, info *common.RelayInfo) error {
 	// map model name
 	modelMapping := c.GetString("model_mapping")
 	if modelMapping != "" && modelMapping != "{}" {
-		modelMap := make(map[string]string)
-		err := json.Unmarshal([]byte(modelMapping), &modelMap)
+		upstreamModel, mapped, err := MapModelName(modelMapping, info.OriginModelName)
 		if err != nil {
-			return fmt.Errorf("unmarshal_model_mapping_failed")
-		}
-
-		// 支持链式模型重定向，最终使用链尾的模型
-		currentModel := info.OriginModelName
-		visitedModels := map[string]bool{
-			currentModel: true,
-		}
-		for {
-			if mappedModel, exists := modelMap[currentModel]; exists && mappedModel != "" {
-				// 模型重定向循环检测，避免无限循环
-				if visitedModels[mappedModel] {
-					if mappedModel == currentModel {
-						if currentModel == info.OriginModelName {
-							info.IsModelMapped = false
-							return nil
-						} else {
-							info.IsModelMapped = true
-							break
-						}
-					}
-					return errors.New("model_mapping_contains_cycle")
-				}
-				visitedModels[mappedModel] = true
-				currentModel = mappedModel
-				info.IsModelMapped = true
-			} else {
+			return err
+		}
+		if mapped {
+			info.IsModelMapped = true
+			info.UpstreamModelName = upstreamModel
+		}
+	}
+	// 出站映射只改变发送给上游的模型名称，计费和渠道选择仍使用请求的模型名称
+	if upstreamModel := mapUpstreamModel(info); upstreamModel != info.UpstreamModelName {
+		info.UpstreamModelName = upstreamModel
+		info.IsModelMapped = true
+	}
+	return nil
+}
+
+// modelMappingRule 通配符或正则表达式的重定向规则
+type modelMappingRule struct {
+	pattern  *regexp.Regexp
+	template string
+	// literal 通配符规则中非通配部分的长度，越长越优先
+	literal int
+}
+
+// parseModelMappingRules 解析模型重定向和渠道出站映射中的规则：键以 regex: 开头时为正则表达式，值中可以使用 $1 引用分组；
+// 键包含 * 时为通配符，值中的 * 依次替换为通配部分匹配的内容。通配符规则按非通配部分长度优先，正则规则按键排序，只有通配符的规则最后匹配。
+// 映射值中的 {model} 替换为映射前的模型名称
+func parseModelMappingRules(modelMap map[string]string) ([]modelMappingRule, error) {
+	var wildcardRules, regexRules []modelMappingRule
+	keys := make([]string, 0, len(modelMap))
+	for key := range modelMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := modelMap[key]
+		if expr, ok := strings.CutPrefix(key, "regex:"); ok {
+			pattern, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid model_mapping regex %s: %w", expr, err)
+			}
+			regexRules = append(regexRules, modelMappingRule{pattern: pattern, template: value})
+			continue
+		}
+		if !strings.Contains(key, "*") {
+			continue
+		}
+		parts := strings.Split(key, "*")
+		quoted := make([]string, len(parts))
+		for i, part := range parts {
+			quoted[i] = regexp.QuoteMeta(part)
+		}
+		valueParts := strings.Split(value, "*")
+		template := strings.ReplaceAll(valueParts[0], "$", "$$")
+		for i := 1; i < len(valueParts); i++ {
+			template += fmt.Sprintf("${%d}", i) + strings.ReplaceAll(valueParts[i], "$", "$$")
+		}
+		wildcardRules = append(wildcardRules, modelMappingRule{
+			pattern:  regexp.MustCompile("^" + strings.Join(quoted, "(.*)") + "$"),
+			template: template,
+			literal:  len(key) - len(parts) + 1,
+		})
+	}
+	sort.SliceStable(wildcardRules, func(i, j int) bool {
+		return wildcardRules[i].literal > wildcardRules[j].literal
+	})
+	// 只有通配符的规则（如 *）匹配所有模型，放在正则规则之后
+	split := len(wildcardRules)
+	for split > 0 && wildcardRules[split-1].literal == 0 {
+		split--
+	}
+	rules := append(wildcardRules[:split:split], regexRules...)
+	return append(rules, wildcardRules[split:]...), nil
+}
+
+// lookupModelMapping 优先精确匹配，其次依次匹配通配符和正则规则，返回映射结果、是否匹配和是否由规则匹配
+func lookupModelMapping(modelMap map[string]string, rules []modelMappingRule, modelName string) (string, bool, bool) {
+	if mappedModel, ok := modelMap[modelName]; ok {
+		return strings.ReplaceAll(mappedModel, "{model}", modelName), true, false
+	}
+	for _, rule := range rules {
+		match := rule.pattern.FindStringSubmatchIndex(modelName)
+		if match != nil {
+			mappedModel := string(rule.pattern.ExpandString(nil, rule.template, modelName, match))
+			return strings.ReplaceAll(mappedModel, "{model}", modelName), true, true
+		}
+	}
+	return "", false, false
+}
+
+// MapModelName 按模型重定向映射模型名称，支持链式重定向，返回链尾的模型名称和是否发生了映射
+func MapModelName(modelMapping string, modelName string) (string, bool, error) {
+	modelMap := make(map[string]string)
+	err := json.Unmarshal([]byte(modelMapping), &modelMap)
+	if err != nil {
+		return "", false, fmt.Errorf("unmarshal_model_mapping_failed")
+	}
+	rules, err := parseModelMappingRules(modelMap)
+	if err != nil {
+		return "", false, err
+	}
+
+	// 支持链式模型重定向，最终使用链尾的模型；规则匹配的结果不再继续重定向，避免通配符反复匹配
+	currentModel := modelName
+	visitedModels := map[string]bool{
+		currentModel: true,
+	}
+	mapped := false
+	for {
+		mappedModel, exists, byRule := lookupModelMapping(modelMap, rules, currentModel)
+		if !exists || mappedModel == "" {
+			break
+		}
+		// 模型重定向循环检测，避免无限循环；映射到自身时停止
+		if visitedModels[mappedModel] {
+			if mappedModel == currentModel {
 				break
 			}
+			return "", false, errors.New("model_mapping_contains_cycle")
 		}
-		if info.IsModelMapped {
-			info.UpstreamModelName = currentModel
+		visitedModels[mappedModel] = true
+		currentModel = mappedModel
+		mapped = true
+		if byRule {
+			break
 		}
 	}
-	return nil
+	return currentModel, mapped, nil
+}
+
+// MapUpstreamModel 按渠道设置 upstream_model_mapping 映射模型名称，用于不经过中继的场景
+func MapUpstreamModel(channelSetting map[string]interface{}, modelName string) string {
+	return mapUpstreamModel(&common.RelayInfo{UpstreamModelName: modelName, ChannelSetting: channelSetting})
+}
+
+// mapUpstreamModel 按渠道设置 upstream_model_mapping 映射模型名称，规则与模型重定向相同，只映射一次
+func mapUpstreamModel(info *common.RelayInfo) string {
+	mapping, ok := info.ChannelSetting[constant.ChannelSettingUpstreamModelMapping].(map[string]interface{})
+	if !ok || len(mapping) == 0 {
+		return info.UpstreamModelName
+	}
+	modelMap := make(map[string]string, len(mapping))
+	for key, value := range mapping {
+		if target, isString := value.(string); isString {
+			modelMap[key] = target
+		}
+	}
+	rules, err := parseModelMappingRules(modelMap)
+	if err != nil {
+		return info.UpstreamModelName
+	}
+	target, ok, _ := lookupModelMapping(modelMap, rules, info.UpstreamModelName)
+	if !ok || target == "" {
+		return info.UpstreamModelName
+	}
+	return target
 }
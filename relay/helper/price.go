@@ -5,9 +5,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"one-api/common"
 	constant2 "one-api/constant"
+	"one-api/model"
 	relaycommon "one-api/relay/common"
 	"one-api/setting"
 	"one-api/setting/operation_setting"
+	"strings"
 )
 
 type PriceData struct {
@@ -16,25 +18,29 @@ type PriceData struct {
 	CompletionRatio        float64
 	CacheRatio             float64
 	CacheCreationRatio     float64
+	ReasoningRatio         float64
 	ImageRatio             float64
 	GroupRatio             float64
 	UsePrice               bool
+	FreeInput              bool
 	ShouldPreConsumedQuota int
 }
 
 func (p PriceData) ToSetting() string {
-	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, ShouldPreConsumedQuota: %d, ImageRatio: %f", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.ShouldPreConsumedQuota, p.ImageRatio)
+	return fmt.Sprintf("ModelPrice: %f, ModelRatio: %f, CompletionRatio: %f, CacheRatio: %f, GroupRatio: %f, UsePrice: %t, CacheCreationRatio: %f, ReasoningRatio: %f, FreeInput: %t, ShouldPreConsumedQuota: %d, ImageRatio: %f", p.ModelPrice, p.ModelRatio, p.CompletionRatio, p.CacheRatio, p.GroupRatio, p.UsePrice, p.CacheCreationRatio, p.ReasoningRatio, p.FreeInput, p.ShouldPreConsumedQuota, p.ImageRatio)
 }
 
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, maxTokens int) (PriceData, error) {
 	modelPrice, usePrice := operation_setting.GetModelPrice(info.OriginModelName, false)
-	groupRatio := setting.GetGroupRatio(info.Group)
+	groupRatio := GetGroupRatio(c, info)
 	var preConsumedQuota int
 	var modelRatio float64
 	var completionRatio float64
 	var cacheRatio float64
 	var imageRatio float64
 	var cacheCreationRatio float64
+	var reasoningRatio float64
+	var freeInput bool
 	if !usePrice {
 		preConsumedTokens := common.PreConsumedQuota
 		if maxTokens != 0 {
@@ -58,6 +64,17 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		cacheRatio, _ = operation_setting.GetCacheRatio(info.OriginModelName)
 		cacheCreationRatio, _ = operation_setting.GetCreateCacheRatio(info.OriginModelName)
 		imageRatio, _ = operation_setting.GetImageRatio(info.OriginModelName)
+		// 思考 token 按单独配置的思考倍率计费，未配置时 Gemini 按 <模型>-thinking 的输出价格计费，其他模型与补全倍率相同
+		var ok bool
+		reasoningRatio, ok = operation_setting.GetReasoningRatio(info.OriginModelName)
+		if !ok {
+			reasoningRatio = completionRatio
+			if info.ChannelType == common.ChannelTypeGemini || info.ChannelType == common.ChannelTypeVertexAi {
+				reasoningRatio = getGeminiThinkingRatio(info.OriginModelName, modelRatio, completionRatio)
+			}
+		}
+		// 输入免费的模型倍率按输出价格换算，结算时输入 token 不计费
+		freeInput = operation_setting.IsFreeInputModel(info.OriginModelName)
 		ratio := modelRatio * groupRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
@@ -70,9 +87,11 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		CompletionRatio:        completionRatio,
 		GroupRatio:             groupRatio,
 		UsePrice:               usePrice,
+		FreeInput:              freeInput,
 		CacheRatio:             cacheRatio,
 		ImageRatio:             imageRatio,
 		CacheCreationRatio:     cacheCreationRatio,
+		ReasoningRatio:         reasoningRatio,
 		ShouldPreConsumedQuota: preConsumedQuota,
 	}
 
@@ -83,6 +102,37 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	return priceData, nil
 }
 
+// GetGroupRatio 返回分组倍率（优先使用分组下该模型单独配置的倍率），分组配置了用量阶梯价格时乘以用户本月用量对应的阶梯倍率
+func GetGroupRatio(c *gin.Context, info *relaycommon.RelayInfo) float64 {
+	groupRatio := setting.GetGroupModelRatio(info.Group, info.OriginModelName)
+	if !setting.HasGroupVolumeTiers(info.Group) {
+		return groupRatio
+	}
+	monthlyTokens, err := model.GetUserMonthlyTokenUsed(info.UserId)
+	if err != nil {
+		common.LogError(c, "get user monthly token usage failed: "+err.Error())
+		return groupRatio
+	}
+	volumeRatio := setting.GetGroupVolumeRatio(info.Group, monthlyTokens)
+	c.Set("volume_tier_ratio", volumeRatio)
+	c.Set("monthly_tokens", monthlyTokens)
+	return groupRatio * volumeRatio
+}
+
+// getGeminiThinkingRatio Gemini 思考 token 相对模型倍率的倍率，配置了 <模型>-thinking 的倍率时按该模型的输出价格计费，
+// 否则与补全倍率相同
+func getGeminiThinkingRatio(modelName string, modelRatio float64, completionRatio float64) float64 {
+	if modelRatio == 0 || strings.HasSuffix(modelName, "-thinking") || strings.HasSuffix(modelName, "-nothinking") {
+		return completionRatio
+	}
+	thinkingModelName := modelName + "-thinking"
+	thinkingModelRatio, ok := operation_setting.GetModelRatio(thinkingModelName)
+	if !ok {
+		return completionRatio
+	}
+	return thinkingModelRatio * operation_setting.GetCompletionRatio(thinkingModelName) / modelRatio
+}
+
 func ContainPriceOrRatio(modelName string) bool {
 	_, ok := operation_setting.GetModelPrice(modelName, false)
 	if ok {
@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// SSE 解析按 https://html.spec.whatwg.org/multipage/server-sent-events.html 处理 LF、CR、CRLF 换行、
+// 多行 data 字段和注释行，并兼容以下不规范的上游：
+//   - 事件之间缺少空行：已缓存的 data 是完整的 JSON 时，新的 data 行作为下一个事件
+//   - event 行出现在未结束的事件之后：作为下一个事件的开始
+//   - 单独一行的 [DONE]
+
+type SSEEvent struct {
+	Event string
+	Data  string
+	Id    string
+}
+
+type SSEReader struct {
+	reader  *bufio.Reader
+	maxSize int
+	lines   []string
+	pending *SSEEvent
+	data    []string
+	err     error
+}
+
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{
+		reader:  bufio.NewReaderSize(r, InitialScannerBufferSize),
+		maxSize: MaxScannerBufferSize,
+	}
+}
+
+// readLine 读取一行，超过 maxSize 时返回 bufio.ErrTooLong
+func (r *SSEReader) readLine() (string, error) {
+	if len(r.lines) > 0 {
+		line := r.lines[0]
+		r.lines = r.lines[1:]
+		return line, nil
+	}
+	var buf []byte
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if len(buf)+len(chunk) > r.maxSize {
+			return "", bufio.ErrTooLong
+		}
+		buf = append(buf, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(buf) == 0) {
+			return "", err
+		}
+		break
+	}
+	buf = bytes.TrimSuffix(buf, []byte("\n"))
+	buf = bytes.TrimSuffix(buf, []byte("\r"))
+	// 单独的 CR 也是换行
+	if bytes.IndexByte(buf, '\r') >= 0 {
+		lines := strings.Split(string(buf), "\r")
+		r.lines = append(r.lines, lines[1:]...)
+		return lines[0], nil
+	}
+	return string(buf), nil
+}
+
+func (r *SSEReader) event() *SSEEvent {
+	if r.pending == nil {
+		r.pending = &SSEEvent{}
+	}
+	return r.pending
+}
+
+// dispatch 返回缓存的事件，没有 data 的事件被丢弃
+func (r *SSEReader) dispatch() *SSEEvent {
+	event := r.pending
+	data := r.data
+	r.pending = nil
+	r.data = nil
+	if event == nil || len(data) == 0 {
+		return nil
+	}
+	event.Data = strings.Join(data, "\n")
+	return event
+}
+
+// dataComplete 已缓存的 data 是否已经是完整的数据
+func (r *SSEReader) dataComplete() bool {
+	if len(r.data) == 0 {
+		return false
+	}
+	data := strings.Join(r.data, "\n")
+	return data == "[DONE]" || json.Valid([]byte(data))
+}
+
+// Next 返回下一个事件，流结束时返回 io.EOF
+func (r *SSEReader) Next() (*SSEEvent, error) {
+	for r.err == nil {
+		line, err := r.readLine()
+		if err != nil {
+			r.err = err
+			break
+		}
+		if line == "" {
+			if event := r.dispatch(); event != nil {
+				return event, nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, found := strings.Cut(line, ":")
+		if !found && line == "[DONE]" {
+			field, value = "data", line
+		}
+		value = strings.TrimPrefix(value, " ")
+		var dispatched *SSEEvent
+		switch field {
+		case "event":
+			if len(r.data) > 0 {
+				dispatched = r.dispatch()
+			}
+			r.event().Event = value
+		case "data":
+			if r.dataComplete() {
+				dispatched = r.dispatch()
+			}
+			r.event()
+			r.data = append(r.data, value)
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.event().Id = value
+			}
+		}
+		if dispatched != nil {
+			return dispatched, nil
+		}
+	}
+	// 流结束时没有空行的最后一个事件
+	if event := r.dispatch(); event != nil {
+		return event, nil
+	}
+	return nil, r.err
+}
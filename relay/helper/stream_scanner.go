@@ -1,14 +1,12 @@
 package helper
 
 import (
-	"bufio"
 	"context"
 	"io"
 	"net/http"
 	"one-api/common"
 	"one-api/constant"
 	relaycommon "one-api/relay/common"
-	"one-api/setting/operation_setting"
 	"strings"
 	"sync"
 	"time"
@@ -40,19 +38,15 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 
 	var (
 		stopChan   = make(chan bool, 2)
-		scanner    = bufio.NewScanner(resp.Body)
 		ticker     = time.NewTicker(streamingTimeout)
 		pingTicker *time.Ticker
 		writeMutex sync.Mutex // Mutex to protect concurrent writes
+		// lastWriteTime 最后一次向客户端写入的时间，只在空闲超过 ping 间隔时发送 ping
+		lastWriteTime = time.Now()
 	)
 
-	generalSettings := operation_setting.GetGeneralSetting()
-	pingEnabled := generalSettings.PingIntervalEnabled
-	pingInterval := time.Duration(generalSettings.PingIntervalSeconds) * time.Second
-	if pingInterval <= 0 {
-		pingInterval = DefaultPingInterval
-	}
-
+	pingInterval := getPingInterval()
+	pingEnabled := pingInterval > 0
 	if pingEnabled {
 		pingTicker = time.NewTicker(pingInterval)
 	}
@@ -64,8 +58,10 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		}
 		close(stopChan)
 	}()
-	scanner.Buffer(make([]byte, InitialScannerBufferSize), MaxScannerBufferSize)
-	scanner.Split(bufio.ScanLines)
+	// 收到任何数据（包括注释行）都重置超时
+	reader := NewSSEReader(&activityReader{reader: resp.Body, onRead: func() {
+		ticker.Reset(streamingTimeout)
+	}})
 	SetEventStreamHeaders(c)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -80,8 +76,16 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 				select {
 				case <-pingTicker.C:
 					writeMutex.Lock() // Lock before writing
+					if idle := time.Since(lastWriteTime); idle < pingInterval {
+						writeMutex.Unlock()
+						// 期间有数据写入，空闲满一个间隔时再检查
+						pingTicker.Reset(pingInterval - idle)
+						continue
+					}
 					err := PingData(c)
+					lastWriteTime = time.Now()
 					writeMutex.Unlock() // Unlock after writing
+					pingTicker.Reset(pingInterval)
 					if err != nil {
 						common.LogError(c, "ping data error: "+err.Error())
 						common.SafeSendBool(stopChan, true)
@@ -101,26 +105,23 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	}
 
 	common.RelayCtxGo(ctx, func() {
-		for scanner.Scan() {
-			ticker.Reset(streamingTimeout)
-			data := scanner.Text()
+		for {
+			event, err := reader.Next()
+			if err != nil {
+				if err != io.EOF {
+					common.LogError(c, "scanner error: "+err.Error())
+				}
+				break
+			}
+			data := strings.TrimLeft(event.Data, " ")
 			if common.DebugEnabled {
 				println(data)
 			}
-
-			if len(data) < 6 {
-				continue
-			}
-			if data[:5] != "data:" && data[:6] != "[DONE]" {
-				continue
-			}
-			data = data[5:]
-			data = strings.TrimLeft(data, " ")
-			data = strings.TrimSuffix(data, "\r")
-			if !strings.HasPrefix(data, "[DONE]") {
+			if data != "" && !strings.HasPrefix(data, "[DONE]") {
 				info.SetFirstResponseTime()
 				writeMutex.Lock() // Lock before writing
 				success := dataHandler(data)
+				lastWriteTime = time.Now()
 				writeMutex.Unlock() // Unlock after writing
 				if !success {
 					break
@@ -128,12 +129,6 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
-			if err != io.EOF {
-				common.LogError(c, "scanner error: "+err.Error())
-			}
-		}
-
 		common.SafeSendBool(stopChan, true)
 	})
 
@@ -147,3 +142,16 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		common.LogInfo(c, "streaming finished")
 	}
 }
+
+type activityReader struct {
+	reader io.Reader
+	onRead func()
+}
+
+func (r *activityReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.onRead()
+	}
+	return n, err
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
 	"one-api/common"
 	"one-api/dto"
@@ -12,9 +13,18 @@ import (
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"strings"
 )
 
+var sttResponseFormats = map[string]bool{
+	"json":         true,
+	"text":         true,
+	"srt":          true,
+	"verbose_json": true,
+	"vtt":          true,
+}
+
 func getAndValidAudioRequest(c *gin.Context, info *relaycommon.RelayInfo) (*dto.AudioRequest, error) {
 	audioRequest := &dto.AudioRequest{}
 	err := common.UnmarshalBodyReusable(c, audioRequest)
@@ -34,22 +44,29 @@ func getAndValidAudioRequest(c *gin.Context, info *relaycommon.RelayInfo) (*dto.
 			}
 		}
 	default:
-		err = c.Request.ParseForm()
+		form, err := common.ParseMultipartFormReusable(c)
 		if err != nil {
 			return nil, err
 		}
-		formData := c.Request.PostForm
-		if audioRequest.Model == "" {
-			audioRequest.Model = formData.Get("model")
+		if audioRequest.Model == "" && len(form.Value["model"]) > 0 {
+			audioRequest.Model = form.Value["model"][0]
 		}
 
 		if audioRequest.Model == "" {
 			return nil, errors.New("model is required")
 		}
-		audioRequest.ResponseFormat = formData.Get("response_format")
+		if len(form.File["file"]) == 0 {
+			return nil, errors.New("file is required")
+		}
+		if len(form.Value["response_format"]) > 0 {
+			audioRequest.ResponseFormat = form.Value["response_format"][0]
+		}
 		if audioRequest.ResponseFormat == "" {
 			audioRequest.ResponseFormat = "json"
 		}
+		if !sttResponseFormats[audioRequest.ResponseFormat] {
+			return nil, fmt.Errorf("unsupported response_format: %s", audioRequest.ResponseFormat)
+		}
 	}
 	return audioRequest, nil
 }
@@ -64,19 +81,51 @@ func AudioHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 	}
 
 	promptTokens := 0
-	preConsumedTokens := common.PreConsumedQuota
+	// 价格表中配置了每分钟或每千字符价格时按价格计费，否则按 token 和模型倍率计费
+	pricing, hasPricing := operation_setting.GetModelPricing(relayInfo.OriginModelName)
+	var audioPrice float64
+	var hasAudioPrice bool
+	var logContent string
 	if relayInfo.RelayMode == relayconstant.RelayModeAudioSpeech {
 		promptTokens, err = service.CountTTSToken(audioRequest.Input, audioRequest.Model)
 		if err != nil {
 			return service.OpenAIErrorWrapper(err, "count_audio_token_failed", http.StatusInternalServerError)
 		}
-		preConsumedTokens = promptTokens
-		relayInfo.PromptTokens = promptTokens
+		if hasPricing {
+			if audioPrice, hasAudioPrice = pricing.SpeechPrice(promptTokens); hasAudioPrice {
+				logContent = fmt.Sprintf("字符数 %d, 每千字符价格 %g %s", promptTokens, pricing.Per1KChars, pricing.GetCurrency())
+			}
+		}
+	} else {
+		// 转录和翻译按音频时长计费
+		duration, err := service.GetAudioFileDuration(c.Request.Context(), c.Request.MultipartForm.File["file"][0])
+		if err != nil {
+			return service.OpenAIErrorWrapper(err, "count_audio_token_failed", http.StatusInternalServerError)
+		}
+		promptTokens = service.AudioFileTokens(duration)
+		if hasPricing {
+			if audioPrice, hasAudioPrice = pricing.TranscriptionPrice(duration); hasAudioPrice {
+				logContent = fmt.Sprintf("音频时长 %.0f 秒, 每分钟价格 %g %s", math.Ceil(duration), pricing.PerMinute, pricing.GetCurrency())
+			}
+		}
 	}
-
-	priceData, err := helper.ModelPriceHelper(c, relayInfo, preConsumedTokens, 0)
-	if err != nil {
-		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+	relayInfo.PromptTokens = promptTokens
+
+	var priceData helper.PriceData
+	// 免费模型不按音频价格计费
+	if hasAudioPrice && !operation_setting.IsFreeModel(relayInfo.OriginModelName) {
+		groupRatio := helper.GetGroupRatio(c, relayInfo)
+		priceData = helper.PriceData{
+			UsePrice:               true,
+			ModelPrice:             audioPrice,
+			GroupRatio:             groupRatio,
+			ShouldPreConsumedQuota: int(audioPrice * common.QuotaPerUnit * groupRatio),
+		}
+	} else {
+		priceData, err = helper.ModelPriceHelper(c, relayInfo, promptTokens, 0)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+		}
 	}
 
 	preConsumedQuota, userQuota, openaiErr := preConsumeQuota(c, priceData.ShouldPreConsumedQuota, relayInfo)
@@ -131,7 +180,7 @@ func AudioHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		return openaiErr
 	}
 
-	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, logContent)
 
 	return nil
 }
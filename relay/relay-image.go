@@ -15,6 +15,7 @@ import (
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -24,8 +25,8 @@ func getAndValidImageRequest(c *gin.Context, info *relaycommon.RelayInfo) (*dto.
 	imageRequest := &dto.ImageRequest{}
 
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesEdits:
-		_, err := c.MultipartForm()
+	case relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
+		form, err := common.ParseMultipartFormReusable(c)
 		if err != nil {
 			return nil, err
 		}
@@ -36,11 +37,34 @@ func getAndValidImageRequest(c *gin.Context, info *relaycommon.RelayInfo) (*dto.
 		imageRequest.Quality = formData.Get("quality")
 		imageRequest.Size = formData.Get("size")
 
+		if len(form.File["image"]) == 0 && len(form.File["image[]"]) == 0 {
+			hasImage := false
+			for fieldName, files := range form.File {
+				if strings.HasPrefix(fieldName, "image[") && len(files) > 0 {
+					hasImage = true
+					break
+				}
+			}
+			if !hasImage {
+				return nil, errors.New("image is required")
+			}
+		}
+		if info.RelayMode == relayconstant.RelayModeImagesVariations {
+			// 变体接口只支持 dall-e-2，不需要提示词
+			if imageRequest.Model == "" {
+				imageRequest.Model = "dall-e-2"
+			}
+		} else if imageRequest.Prompt == "" {
+			return nil, errors.New("prompt is required")
+		}
 		if imageRequest.Model == "gpt-image-1" {
 			if imageRequest.Quality == "" {
 				imageRequest.Quality = "standard"
 			}
 		}
+		if imageRequest.N == 0 {
+			imageRequest.N = 1
+		}
 	default:
 		err := common.UnmarshalBodyReusable(c, imageRequest)
 		if err != nil {
@@ -98,6 +122,55 @@ func getAndValidImageRequest(c *gin.Context, info *relaycommon.RelayInfo) (*dto.
 	return imageRequest, nil
 }
 
+// imagePriceRatio 按次计费时根据尺寸和品质调整单张图片的价格，模型价格为 1024x1024 标准品质单张图片的价格
+func imagePriceRatio(model string, size string, quality string) float64 {
+	// Imagen 按张计费，与尺寸无关
+	if strings.HasPrefix(model, "imagen") {
+		return 1
+	}
+	if model == "dall-e-2" {
+		switch size {
+		case "256x256":
+			return 0.8
+		case "512x512":
+			return 0.9
+		}
+		return 1
+	}
+	if strings.HasPrefix(model, "gpt-image-1") {
+		ratio := 1.0
+		if size == "1024x1536" || size == "1536x1024" {
+			ratio = 1.5
+		}
+		switch quality {
+		case "low":
+			ratio *= 0.25
+		case "high":
+			ratio *= 4
+		}
+		return ratio
+	}
+
+	sizeRatio := 1.0
+	if size == "256x256" {
+		sizeRatio = 0.4
+	} else if size == "512x512" {
+		sizeRatio = 0.45
+	} else if size == "1024x1024" {
+		sizeRatio = 1
+	} else if size == "1024x1792" || size == "1792x1024" {
+		sizeRatio = 2
+	}
+	qualityRatio := 1.0
+	if model == "dall-e-3" && quality == "hd" {
+		qualityRatio = 2.0
+		if size == "1024x1792" || size == "1792x1024" {
+			qualityRatio = 1.5
+		}
+	}
+	return sizeRatio * qualityRatio
+}
+
 func ImageHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
 	relayInfo := relaycommon.GenRelayInfo(c)
 
@@ -114,9 +187,24 @@ func ImageHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
 
 	imageRequest.Model = relayInfo.UpstreamModelName
 
-	priceData, err := helper.ModelPriceHelper(c, relayInfo, len(imageRequest.Prompt), 0)
-	if err != nil {
-		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+	quality := "standard"
+	if imageRequest.Quality != "" {
+		quality = imageRequest.Quality
+	}
+	// 配置了图片价格矩阵时按尺寸、品质和数量计费，优先于模型价格和模型倍率
+	imagePrice, hasImagePrice := operation_setting.GetImagePrice(relayInfo.OriginModelName, imageRequest.Size, quality)
+	var priceData helper.PriceData
+	// 免费模型不按图片价格计费
+	if hasImagePrice && !operation_setting.IsFreeModel(relayInfo.OriginModelName) {
+		priceData = helper.PriceData{
+			UsePrice:   true,
+			GroupRatio: helper.GetGroupRatio(c, relayInfo),
+		}
+	} else {
+		priceData, err = helper.ModelPriceHelper(c, relayInfo, len(imageRequest.Prompt), 0)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+		}
 	}
 	var preConsumedQuota int
 	var quota int
@@ -137,30 +225,14 @@ func ImageHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
 		}()
 
 	} else {
-		sizeRatio := 1.0
-		// Size
-		if imageRequest.Size == "256x256" {
-			sizeRatio = 0.4
-		} else if imageRequest.Size == "512x512" {
-			sizeRatio = 0.45
-		} else if imageRequest.Size == "1024x1024" {
-			sizeRatio = 1
-		} else if imageRequest.Size == "1024x1792" || imageRequest.Size == "1792x1024" {
-			sizeRatio = 2
-		}
-
-		qualityRatio := 1.0
-		if imageRequest.Model == "dall-e-3" && imageRequest.Quality == "hd" {
-			qualityRatio = 2.0
-			if imageRequest.Size == "1024x1792" || imageRequest.Size == "1792x1024" {
-				qualityRatio = 1.5
-			}
-		}
-
 		// reset model price
-		priceData.ModelPrice *= sizeRatio * qualityRatio * float64(imageRequest.N)
+		if hasImagePrice {
+			priceData.ModelPrice = imagePrice * float64(imageRequest.N)
+		} else {
+			priceData.ModelPrice *= imagePriceRatio(imageRequest.Model, imageRequest.Size, imageRequest.Quality) * float64(imageRequest.N)
+		}
 		quota = int(priceData.ModelPrice * priceData.GroupRatio * common.QuotaPerUnit)
-		userQuota, err = model.GetUserQuota(relayInfo.UserId, false)
+		userQuota, err = model.GetUserSpendableQuota(relayInfo.UserId)
 		if err != nil {
 			return service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
 		}
@@ -181,7 +253,7 @@ func ImageHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
 	}
-	if relayInfo.RelayMode == relayconstant.RelayModeImagesEdits {
+	if relayInfo.RelayMode == relayconstant.RelayModeImagesEdits || relayInfo.RelayMode == relayconstant.RelayModeImagesVariations {
 		requestBody = convertedRequest.(io.Reader)
 	} else {
 		jsonData, err := json.Marshal(convertedRequest)
@@ -226,12 +298,10 @@ func ImageHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
 	if usage.(*dto.Usage).PromptTokens == 0 {
 		usage.(*dto.Usage).PromptTokens = imageRequest.N
 	}
-	quality := "standard"
-	if imageRequest.Quality == "hd" {
-		quality = "hd"
-	}
-
 	logContent := fmt.Sprintf("大小 %s, 品质 %s", imageRequest.Size, quality)
+	if hasImagePrice {
+		logContent += fmt.Sprintf(", 数量 %d, 单张价格 $%g", imageRequest.N, imagePrice)
+	}
 	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, logContent)
 	return nil
 }
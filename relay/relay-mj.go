@@ -184,9 +184,9 @@ func RelaySwapFace(c *gin.Context) *dto.MidjourneyResponse {
 			modelPrice = defaultPrice
 		}
 	}
-	groupRatio := setting.GetGroupRatio(group)
+	groupRatio := setting.GetGroupModelRatio(group, modelName)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(userId, false)
+	userQuota, err := model.GetUserSpendableQuota(userId)
 	if err != nil {
 		return &dto.MidjourneyResponse{
 			Code:        4,
@@ -490,9 +490,9 @@ func RelayMidjourneySubmit(c *gin.Context, relayMode int) *dto.MidjourneyRespons
 			modelPrice = defaultPrice
 		}
 	}
-	groupRatio := setting.GetGroupRatio(group)
+	groupRatio := setting.GetGroupModelRatio(group, modelName)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(userId, false)
+	userQuota, err := model.GetUserSpendableQuota(userId)
 	if err != nil {
 		return &dto.MidjourneyResponse{
 			Code:        4,
@@ -41,6 +41,10 @@ func checkInputSensitive(textRequest *dto.OpenAIResponsesRequest, info *relaycom
 }
 
 func getInputTokens(req *dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo) (int, error) {
+	if skipPromptTokenCount(info) {
+		info.PromptTokens = 0
+		return 0, nil
+	}
 	inputTokens, err := service.CountTokenInput(req.Input, req.Model)
 	info.PromptTokens = inputTokens
 	return inputTokens, err
@@ -67,6 +71,7 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusBadRequest)
 	}
+	defer rewriteResponseModel(c, relayInfo)()
 	req.Model = relayInfo.UpstreamModelName
 	if value, exists := c.Get("prompt_tokens"); exists {
 		promptTokens := value.(int)
@@ -83,6 +88,7 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
 	}
+	preConsumeServiceTier(&priceData, req.ServiceTier)
 	// pre consume quota
 	preConsumedQuota, userQuota, openaiErr := preConsumeQuota(c, priceData.ShouldPreConsumedQuota, relayInfo)
 	if openaiErr != nil {
@@ -161,11 +167,18 @@ func ResponsesHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	openaiUsage := responseUsage(c, relayInfo, usage)
+	if streamAbortedBeforeFirstToken(relayInfo, openaiUsage) {
+		refundPreConsumedQuota(relayInfo, preConsumedQuota, "上游流式响应在返回首个 token 前中断")
+		// 已退还，避免返回错误时再次退还
+		preConsumedQuota = 0
+		return errStreamAbortedBeforeFirstToken()
+	}
 
-	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
-		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+	if service.IsAudioUsage(relayInfo, openaiUsage) {
+		service.PostAudioConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
 	} else {
-		postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+		postConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
 	}
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"one-api/constant"
 	"one-api/dto"
 	"one-api/model"
+	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
@@ -44,6 +45,9 @@ func getAndValidateTextRequest(c *gin.Context, relayInfo *relaycommon.RelayInfo)
 	if textRequest.MaxTokens > math.MaxInt32/2 {
 		return nil, errors.New("max_tokens is invalid")
 	}
+	if textRequest.N < 0 || textRequest.N > 128 {
+		return nil, errors.New("n must be between 1 and 128")
+	}
 	if textRequest.Model == "" {
 		return nil, errors.New("model is required")
 	}
@@ -81,6 +85,7 @@ func getAndValidateTextRequest(c *gin.Context, relayInfo *relaycommon.RelayInfo)
 		}
 	}
 	relayInfo.IsStream = textRequest.Stream
+	relayInfo.AudioUsage = textRequest.HasAudio()
 	return textRequest, nil
 }
 
@@ -99,6 +104,11 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		return service.OpenAIErrorWrapperLocal(err, "invalid_text_request", http.StatusBadRequest)
 	}
 
+	agentTools, err := getAgentTools(textRequest, relayInfo)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "invalid_agent_tools", http.StatusBadRequest)
+	}
+
 	if setting.ShouldCheckPromptSensitive() {
 		words, err := checkRequestSensitive(textRequest, relayInfo)
 		if err != nil {
@@ -111,6 +121,7 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
 	}
+	defer rewriteResponseModel(c, relayInfo)()
 
 	textRequest.Model = relayInfo.UpstreamModelName
 
@@ -128,10 +139,17 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		c.Set("prompt_tokens", promptTokens)
 	}
 
-	priceData, err := helper.ModelPriceHelper(c, relayInfo, promptTokens, int(math.Max(float64(textRequest.MaxTokens), float64(textRequest.MaxCompletionTokens))))
+	applyModelMaxTokens(relayInfo, textRequest)
+	maxTokens := int(math.Max(float64(textRequest.MaxTokens), float64(textRequest.MaxCompletionTokens)))
+	// n > 1 时每个 choice 都可能生成 max_tokens
+	if textRequest.N > 1 {
+		maxTokens *= textRequest.N
+	}
+	priceData, err := helper.ModelPriceHelper(c, relayInfo, promptTokens, maxTokens)
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
 	}
+	preConsumeServiceTier(&priceData, textRequest.ServiceTier)
 
 	// pre-consume quota 预消耗配额
 	preConsumedQuota, userQuota, openaiErr := preConsumeQuota(c, priceData.ShouldPreConsumedQuota, relayInfo)
@@ -169,7 +187,57 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 	if adaptor == nil {
 		return service.OpenAIErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
 	}
+	// 只提供对话接口的渠道把 completions 请求转换为对话请求，响应再转换回 text_completion 格式
+	var completionsWriter *completionsResponseWriter
+	if relayInfo.RelayMode == relayconstant.RelayModeCompletions && !supportCompletions(relayInfo) {
+		echo := textRequest.Echo
+		prompt, err := service.CompletionsToChatRequest(textRequest)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "invalid_prompt", http.StatusBadRequest)
+		}
+		if !echo {
+			prompt = ""
+		}
+		relayInfo.RelayMode = relayconstant.RelayModeChatCompletions
+		relayInfo.RequestURLPath = "/v1/chat/completions"
+		completionsWriter = newCompletionsResponseWriter(c.Writer, relayInfo, prompt)
+		c.Writer = completionsWriter
+		defer func() {
+			c.Writer = completionsWriter.ResponseWriter
+		}()
+	}
 	adaptor.Init(relayInfo)
+	if len(agentTools) > 0 {
+		return agentLoopHelper(c, relayInfo, adaptor, textRequest, agentTools, priceData, &preConsumedQuota, userQuota)
+	}
+	if shouldEmulateMultipleChoices(relayInfo, textRequest) {
+		openaiErr = multipleChoicesHelper(c, relayInfo, adaptor, textRequest, priceData, &preConsumedQuota, userQuota)
+		if openaiErr == nil && completionsWriter != nil {
+			completionsWriter.finish()
+		}
+		return openaiErr
+	}
+	if textRequest.Stream && shouldFakeStream(relayInfo) {
+		openaiErr = fakeStreamHelper(c, relayInfo, adaptor, textRequest, priceData, &preConsumedQuota, userQuota)
+		if openaiErr == nil && completionsWriter != nil {
+			completionsWriter.finish()
+		}
+		return openaiErr
+	}
+	// 渠道设置以流式调用上游时，非流式请求改为流式请求，数据块合并为非流式响应，避免长时间生成时上游超时
+	var aggregateWriter *streamAggregateResponseWriter
+	if !textRequest.Stream && shouldStreamUpstream(relayInfo) {
+		textRequest.Stream = true
+		if relayInfo.SupportStreamOptions {
+			textRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+		}
+		relayInfo.IsStream = true
+		aggregateWriter = newStreamAggregateResponseWriter(c.Writer, relayInfo)
+		c.Writer = aggregateWriter
+		defer func() {
+			c.Writer = aggregateWriter.ResponseWriter
+		}()
+	}
 	var requestBody io.Reader
 
 	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
@@ -179,35 +247,10 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		}
 		requestBody = bytes.NewBuffer(body)
 	} else {
-		convertedRequest, err := adaptor.ConvertOpenAIRequest(c, relayInfo, textRequest)
-		if err != nil {
-			return service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
-		}
-		jsonData, err := json.Marshal(convertedRequest)
-		if err != nil {
-			return service.OpenAIErrorWrapperLocal(err, "json_marshal_failed", http.StatusInternalServerError)
-		}
-
-		// apply param override
-		if len(relayInfo.ParamOverride) > 0 {
-			reqMap := make(map[string]interface{})
-			err = json.Unmarshal(jsonData, &reqMap)
-			if err != nil {
-				return service.OpenAIErrorWrapperLocal(err, "param_override_unmarshal_failed", http.StatusInternalServerError)
-			}
-			for key, value := range relayInfo.ParamOverride {
-				reqMap[key] = value
-			}
-			jsonData, err = json.Marshal(reqMap)
-			if err != nil {
-				return service.OpenAIErrorWrapperLocal(err, "param_override_marshal_failed", http.StatusInternalServerError)
-			}
-		}
-
-		if common.DebugEnabled {
-			println("requestBody: ", string(jsonData))
+		requestBody, openaiErr = convertTextRequestBody(c, relayInfo, adaptor, textRequest)
+		if openaiErr != nil {
+			return openaiErr
 		}
-		requestBody = bytes.NewBuffer(jsonData)
 	}
 
 	var httpResp *http.Response
@@ -236,18 +279,212 @@ func TextHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
+	openaiUsage := responseUsage(c, relayInfo, usage)
+	if aggregateWriter != nil {
+		aggregateWriter.finish(openaiUsage)
+	}
+	if completionsWriter != nil {
+		completionsWriter.finish()
+	}
+	if streamAbortedBeforeFirstToken(relayInfo, openaiUsage) {
+		refundPreConsumedQuota(relayInfo, preConsumedQuota, "上游流式响应在返回首个 token 前中断")
+		// 已退还，避免返回错误时再次退还
+		preConsumedQuota = 0
+		return errStreamAbortedBeforeFirstToken()
+	}
 
-	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
-		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+	if service.IsAudioUsage(relayInfo, openaiUsage) {
+		service.PostAudioConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
 	} else {
-		postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+		postConsumeQuota(c, relayInfo, openaiUsage, preConsumedQuota, userQuota, priceData, "")
+	}
+	return nil
+}
+
+// convertTextRequestBody 转换为上游格式并应用渠道的参数覆盖和参数移除
+func convertTextRequestBody(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, textRequest *dto.GeneralOpenAIRequest) (io.Reader, *dto.OpenAIErrorWithStatusCode) {
+	if shouldFallbackJsonSchema(relayInfo) {
+		service.ApplyJsonModeFallback(textRequest)
+	}
+	if limit := inlineImageLimit(relayInfo); limit != nil {
+		if err := service.InlineImageContent(textRequest, *limit, relayInfo.InlineImageCache); err != nil {
+			return nil, service.OpenAIErrorWrapperLocal(err, "invalid_image_content", http.StatusBadRequest)
+		}
+	}
+	// 未识别的字段默认原样转发，上游拒绝未知参数时可以通过渠道设置去除
+	if strip, _ := relayInfo.ChannelSetting[constant.ChannelSettingStripExtraFields].(bool); strip {
+		textRequest.ExtraFields = nil
+	}
+	sanitizeRequestParams(c, relayInfo, textRequest)
+	if textRequest.LogProbs && !logprobsSupported(relayInfo) {
+		textRequest.LogProbs = false
+		textRequest.TopLogProbs = 0
+		c.Header(common.WarningHeader, "logprobs is not supported by the upstream and has been ignored")
+	}
+	convertedRequest, err := adaptor.ConvertOpenAIRequest(c, relayInfo, textRequest)
+	if err != nil {
+		return nil, service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
+	}
+	jsonData, err := json.Marshal(convertedRequest)
+	if err != nil {
+		return nil, service.OpenAIErrorWrapperLocal(err, "json_marshal_failed", http.StatusInternalServerError)
+	}
+
+	// apply param override
+	removeParams := relaycommon.GetRemoveParams(relayInfo)
+	if len(relayInfo.ParamOverride) > 0 || len(removeParams) > 0 {
+		reqMap := make(map[string]interface{})
+		err = json.Unmarshal(jsonData, &reqMap)
+		if err != nil {
+			return nil, service.OpenAIErrorWrapperLocal(err, "param_override_unmarshal_failed", http.StatusInternalServerError)
+		}
+		for _, key := range removeParams {
+			delete(reqMap, key)
+		}
+		for key, value := range relayInfo.ParamOverride {
+			reqMap[key] = value
+		}
+		jsonData, err = json.Marshal(reqMap)
+		if err != nil {
+			return nil, service.OpenAIErrorWrapperLocal(err, "param_override_marshal_failed", http.StatusInternalServerError)
+		}
+	}
+
+	if common.DebugEnabled {
+		println("requestBody: ", string(jsonData))
+	}
+	return bytes.NewBuffer(jsonData), nil
+}
+
+// applyModelMaxTokens 按用户请求的模型的输出 token 限制补全默认值、截断超出上限的值，
+// 默认值写入 max_tokens，由适配器按模型转换为 max_completion_tokens
+func applyModelMaxTokens(info *relaycommon.RelayInfo, textRequest *dto.GeneralOpenAIRequest) {
+	limit, ok := operation_setting.GetModelMaxTokens(info.OriginModelName)
+	if !ok {
+		return
+	}
+	if textRequest.MaxTokens == 0 && textRequest.MaxCompletionTokens == 0 {
+		textRequest.MaxTokens = limit.Default
+	}
+	if limit.Max > 0 {
+		textRequest.MaxTokens = min(textRequest.MaxTokens, limit.Max)
+		textRequest.MaxCompletionTokens = min(textRequest.MaxCompletionTokens, limit.Max)
+	}
+}
+
+// shouldFallbackJsonSchema 上游不支持 json_schema 时降级为 JSON 模式，DeepSeek 默认降级，其他渠道通过渠道设置开启
+func shouldFallbackJsonSchema(info *relaycommon.RelayInfo) bool {
+	if fallback, ok := info.ChannelSetting[constant.ChannelSettingJsonSchemaFallback].(bool); ok {
+		return fallback
+	}
+	return info.ChannelType == common.ChannelTypeDeepSeek
+}
+
+// inlineImageLimit 只接受内联图片的上游返回其限制，远程图片由网关下载；Claude 渠道可以通过渠道设置直接传递图片地址
+func inlineImageLimit(info *relaycommon.RelayInfo) *service.ImageLimit {
+	switch info.ApiType {
+	case relayconstant.APITypeAnthropic:
+		if passthrough, _ := info.ChannelSetting[constant.ChannelSettingImageUrlPassthrough].(bool); passthrough {
+			return nil
+		}
+		return &service.ClaudeImageLimit
+	case relayconstant.APITypeAws:
+		return &service.ClaudeImageLimit
+	case relayconstant.APITypeVertexAi:
+		if strings.HasPrefix(info.UpstreamModelName, "claude") {
+			return &service.ClaudeImageLimit
+		}
+		if strings.HasPrefix(info.UpstreamModelName, "gemini") {
+			return &service.GeminiImageLimit
+		}
+	case relayconstant.APITypeGemini:
+		return &service.GeminiImageLimit
+	case relayconstant.APITypeOllama:
+		return &service.DefaultImageLimit
 	}
 	return nil
 }
 
+// supportCompletions 渠道可以直接转发 /v1/completions 请求，OpenAI 类型的渠道可以通过渠道设置 completions_via_chat 改为转换为对话请求
+func supportCompletions(info *relaycommon.RelayInfo) bool {
+	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+		return true
+	}
+	if viaChat, _ := info.ChannelSetting[constant.ChannelSettingCompletionsViaChat].(bool); viaChat {
+		return false
+	}
+	if viaResponses, _ := info.ChannelSetting[constant.ChannelSettingChatViaResponses].(bool); viaResponses {
+		return false
+	}
+	switch info.ApiType {
+	case relayconstant.APITypeOpenAI, relayconstant.APITypeOllama, relayconstant.APITypeSiliconFlow, relayconstant.APITypeCloudflare,
+		relayconstant.APITypeAli, relayconstant.APITypeDeepSeek, relayconstant.APITypeNvidia, relayconstant.APITypeOpenRouter,
+		relayconstant.APITypeXai:
+		return true
+	}
+	return false
+}
+
+// channelSettingMatchesModel 渠道设置为 true 时对所有模型生效，为模型列表时只对列出的模型生效，以 * 结尾的按前缀匹配
+func channelSettingMatchesModel(info *relaycommon.RelayInfo, key string) bool {
+	switch setting := info.ChannelSetting[key].(type) {
+	case bool:
+		return setting
+	case []any:
+		for _, item := range setting {
+			pattern, _ := item.(string)
+			if pattern == info.OriginModelName {
+				return true
+			}
+			if strings.HasSuffix(pattern, "*") && strings.HasPrefix(info.OriginModelName, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shouldStreamUpstream 渠道设置 stream_upstream 对请求的模型生效时，非流式对话请求以流式调用上游
+func shouldStreamUpstream(info *relaycommon.RelayInfo) bool {
+	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+		return false
+	}
+	if info.RelayMode != relayconstant.RelayModeChatCompletions || info.RelayFormat != relaycommon.RelayFormatOpenAI {
+		return false
+	}
+	return channelSettingMatchesModel(info, constant.ChannelSettingStreamUpstream)
+}
+
+// logprobsSupported 原样转发 OpenAI 请求的渠道交给上游处理，Gemini 转换为 responseLogprobs，
+// 其余转换为自有请求格式的渠道不支持 logprobs
+func logprobsSupported(info *relaycommon.RelayInfo) bool {
+	if viaResponses, _ := info.ChannelSetting[constant.ChannelSettingChatViaResponses].(bool); viaResponses {
+		return false
+	}
+	switch info.ApiType {
+	case relayconstant.APITypeAnthropic, relayconstant.APITypeAws, relayconstant.APITypePaLM, relayconstant.APITypeBaidu,
+		relayconstant.APITypeZhipu, relayconstant.APITypeXunfei, relayconstant.APITypeTencent, relayconstant.APITypeZhipuV4,
+		relayconstant.APITypeOllama, relayconstant.APITypePerplexity, relayconstant.APITypeCohere, relayconstant.APITypeDify,
+		relayconstant.APITypeMistral, relayconstant.APITypeCoze:
+		return false
+	case relayconstant.APITypeVertexAi:
+		return !strings.HasPrefix(info.UpstreamModelName, "claude")
+	}
+	return true
+}
+
+// skipPromptTokenCount 价格表中按次计费的模型不需要预估输入 token，用量以上游返回的为准
+func skipPromptTokenCount(info *relaycommon.RelayInfo) bool {
+	return operation_setting.IsPerRequestPricing(info.OriginModelName)
+}
+
 func getPromptTokens(textRequest *dto.GeneralOpenAIRequest, info *relaycommon.RelayInfo) (int, error) {
 	var promptTokens int
 	var err error
+	if skipPromptTokenCount(info) {
+		info.PromptTokens = 0
+		return 0, nil
+	}
 	switch info.RelayMode {
 	case relayconstant.RelayModeChatCompletions:
 		promptTokens, err = service.CountTokenChatRequest(info, *textRequest)
@@ -283,7 +520,7 @@ func checkRequestSensitive(textRequest *dto.GeneralOpenAIRequest, info *relaycom
 
 // 预扣费并返回用户剩余配额
 func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommon.RelayInfo) (int, int, *dto.OpenAIErrorWithStatusCode) {
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
 	if err != nil {
 		return 0, 0, service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
@@ -294,7 +531,7 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		return 0, 0, service.OpenAIErrorWrapperLocal(fmt.Errorf("chat pre-consumed quota failed, user quota: %s, need quota: %s", common.FormatQuota(userQuota), common.FormatQuota(preConsumedQuota)), "insufficient_user_quota", http.StatusForbidden)
 	}
 	relayInfo.UserQuota = userQuota
-	if userQuota > 100*preConsumedQuota {
+	if constant.QuotaHoldTrustEnabled && userQuota > 100*preConsumedQuota {
 		// 用户额度充足，判断令牌额度是否充足
 		if !relayInfo.TokenUnlimited {
 			// 非无限令牌，判断令牌额度是否充足
@@ -312,29 +549,63 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		}
 	}
 
-	if preConsumedQuota > 0 {
-		err := service.PreConsumeTokenQuota(relayInfo, preConsumedQuota)
+	if err := service.HoldQuota(relayInfo, preConsumedQuota); err != nil {
+		return 0, 0, service.OpenAIErrorWrapperLocal(err, "pre_consume_token_quota_failed", http.StatusForbidden)
+	}
+	return preConsumedQuota, userQuota, nil
+}
+
+func returnPreConsumedQuota(c *gin.Context, relayInfo *relaycommon.RelayInfo, userQuota int, preConsumedQuota int) {
+	refundPreConsumedQuota(relayInfo, preConsumedQuota, "请求失败")
+}
+
+// refundPreConsumedQuota 退还预扣的额度，并在日志中记录退还原因
+func refundPreConsumedQuota(relayInfo *relaycommon.RelayInfo, preConsumedQuota int, reason string) {
+	if preConsumedQuota == 0 {
+		return
+	}
+	relayInfoCopy := *relayInfo
+	gopool.Go(func() {
+		// 预扣已被释放时不再退还
+		refunded, err := service.SettleQuota(&relayInfoCopy, preConsumedQuota, 0, false)
 		if err != nil {
-			return 0, 0, service.OpenAIErrorWrapperLocal(err, "pre_consume_token_quota_failed", http.StatusForbidden)
+			common.SysError("error return pre-consumed quota: " + err.Error())
+			return
 		}
-		err = model.DecreaseUserQuota(relayInfo.UserId, preConsumedQuota)
-		if err != nil {
-			return 0, 0, service.OpenAIErrorWrapperLocal(err, "decrease_user_quota_failed", http.StatusInternalServerError)
+		if refunded != 0 {
+			logContent := fmt.Sprintf("%s，模型 %s 预扣的 %s 已退还", reason, relayInfoCopy.OriginModelName, common.LogQuota(-refunded))
+			model.RecordLog(relayInfoCopy.UserId, model.LogTypeSystem, logContent)
 		}
+	})
+}
+
+// preConsumeServiceTier 请求价格更高的服务等级时按该等级预扣，折扣等级要等上游确认后才在结算时生效
+func preConsumeServiceTier(priceData *helper.PriceData, serviceTier string) {
+	if ratio := operation_setting.GetServiceTierRatio(serviceTier); ratio > 1 {
+		priceData.ShouldPreConsumedQuota = int(float64(priceData.ShouldPreConsumedQuota) * ratio)
 	}
-	return preConsumedQuota, userQuota, nil
 }
 
-func returnPreConsumedQuota(c *gin.Context, relayInfo *relaycommon.RelayInfo, userQuota int, preConsumedQuota int) {
-	if preConsumedQuota != 0 {
-		gopool.Go(func() {
-			relayInfoCopy := *relayInfo
+// streamAbortedBeforeFirstToken 流式响应在返回任何内容前中断（上游断开或超时），这种情况不计费
+func streamAbortedBeforeFirstToken(relayInfo *relaycommon.RelayInfo, usage *dto.Usage) bool {
+	return relayInfo.IsStream && !relayInfo.HasSendResponse() && usage.CompletionTokens == 0
+}
 
-			err := service.PostConsumeQuota(&relayInfoCopy, -preConsumedQuota, 0, false)
-			if err != nil {
-				common.SysError("error return pre-consumed quota: " + err.Error())
-			}
-		})
+// errStreamAbortedBeforeFirstToken 客户端还没有收到任何内容，返回上游错误以便重试其他渠道并记录渠道错误
+func errStreamAbortedBeforeFirstToken() *dto.OpenAIErrorWithStatusCode {
+	return service.OpenAIErrorWrapper(errors.New("upstream stream aborted before the first token"),
+		"upstream_stream_aborted", http.StatusBadGateway)
+}
+
+// responseUsage 适配器没有返回 OpenAI 格式的用量时按本地计算的提示 token 计费
+func responseUsage(c *gin.Context, relayInfo *relaycommon.RelayInfo, usage any) *dto.Usage {
+	if openaiUsage, ok := usage.(*dto.Usage); ok && openaiUsage != nil {
+		return openaiUsage
+	}
+	common.LogWarn(c, fmt.Sprintf("adaptor returned no usage for model %s, using local prompt tokens", relayInfo.OriginModelName))
+	return &dto.Usage{
+		PromptTokens: relayInfo.PromptTokens,
+		TotalTokens:  relayInfo.PromptTokens,
 	}
 }
 
@@ -351,32 +622,70 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	cacheTokens := usage.PromptTokensDetails.CachedTokens
+	cacheCreationTokens := usage.PromptTokensDetails.CachedCreationTokens
+	// 兼容以 Claude 字段返回缓存用量的 OpenAI 格式上游
+	if cacheTokens == 0 {
+		cacheTokens = usage.CacheReadInputTokens
+	}
+	if cacheCreationTokens == 0 {
+		cacheCreationTokens = usage.CacheCreationInputTokens
+	}
 	imageTokens := usage.PromptTokensDetails.ImageTokens
 	completionTokens := usage.CompletionTokens
+	reasoningTokens := usage.CompletionTokenDetails.ReasoningTokens
+	if reasoningTokens > completionTokens {
+		reasoningTokens = completionTokens
+	}
 	modelName := relayInfo.OriginModelName
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := priceData.CompletionRatio
 	cacheRatio := priceData.CacheRatio
+	cacheCreationRatio := priceData.CacheCreationRatio
+	reasoningRatio := priceData.ReasoningRatio
+	if reasoningRatio == 0 {
+		reasoningRatio = completionRatio
+	}
 	imageRatio := priceData.ImageRatio
 	modelRatio := priceData.ModelRatio
 	groupRatio := priceData.GroupRatio
 	modelPrice := priceData.ModelPrice
+	// 按上游实际使用的服务等级计费，例如 flex 折扣、priority 加价
+	serviceTier := ctx.GetString(constant.ContextKeyServiceTier)
+	serviceTierRatio := operation_setting.GetServiceTierRatio(serviceTier)
 
 	// Convert values to decimal for precise calculation
 	dPromptTokens := decimal.NewFromInt(int64(promptTokens))
 	dCacheTokens := decimal.NewFromInt(int64(cacheTokens))
+	dCacheCreationTokens := decimal.NewFromInt(int64(cacheCreationTokens))
 	dImageTokens := decimal.NewFromInt(int64(imageTokens))
 	dCompletionTokens := decimal.NewFromInt(int64(completionTokens))
+	dReasoningTokens := decimal.NewFromInt(int64(reasoningTokens))
 	dCompletionRatio := decimal.NewFromFloat(completionRatio)
+	dReasoningRatio := decimal.NewFromFloat(reasoningRatio)
 	dCacheRatio := decimal.NewFromFloat(cacheRatio)
+	dCacheCreationRatio := decimal.NewFromFloat(cacheCreationRatio)
 	dImageRatio := decimal.NewFromFloat(imageRatio)
 	dModelRatio := decimal.NewFromFloat(modelRatio)
 	dGroupRatio := decimal.NewFromFloat(groupRatio)
+	dServiceTierRatio := decimal.NewFromFloat(serviceTierRatio)
 	dModelPrice := decimal.NewFromFloat(modelPrice)
 	dQuotaPerUnit := decimal.NewFromFloat(common.QuotaPerUnit)
 
-	ratio := dModelRatio.Mul(dGroupRatio)
+	ratio := dModelRatio.Mul(dGroupRatio).Mul(dServiceTierRatio)
+	// 输入免费的模型输入 token 不计费
+	promptRatio := ratio
+	if priceData.FreeInput {
+		promptRatio = decimal.Zero
+	}
+
+	billing := &dto.BillingBreakdown{
+		UsePrice:         priceData.UsePrice,
+		ModelRatio:       modelRatio,
+		ModelPrice:       modelPrice,
+		GroupRatio:       groupRatio,
+		ServiceTierRatio: serviceTierRatio,
+	}
 
 	// openai web search 工具计费
 	var dWebSearchQuota decimal.Decimal
@@ -390,6 +699,13 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 				Div(decimal.NewFromInt(1000)).Mul(dGroupRatio).Mul(dQuotaPerUnit)
 			extraContent += fmt.Sprintf("Web Search 调用 %d 次，上下文大小 %s，调用花费 %s",
 				webSearchTool.CallCount, webSearchTool.SearchContextSize, dWebSearchQuota.String())
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemWebSearch,
+				Quantity: webSearchTool.CallCount,
+				Unit:     dto.BillingUnitCalls,
+				Price:    webSearchPrice,
+				Quota:    dWebSearchQuota.InexactFloat64(),
+			})
 		}
 	} else if strings.HasSuffix(modelName, "search-preview") {
 		// search-preview 模型不支持 response api
@@ -402,6 +718,13 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			Div(decimal.NewFromInt(1000)).Mul(dGroupRatio).Mul(dQuotaPerUnit)
 		extraContent += fmt.Sprintf("Web Search 调用 1 次，上下文大小 %s，调用花费 %s",
 			searchContextSize, dWebSearchQuota.String())
+		billing.AddItem(dto.BillingLineItem{
+			Type:     dto.BillingItemWebSearch,
+			Quantity: 1,
+			Unit:     dto.BillingUnitCalls,
+			Price:    webSearchPrice,
+			Quota:    dWebSearchQuota.InexactFloat64(),
+		})
 	}
 	// file search tool 计费
 	var dFileSearchQuota decimal.Decimal
@@ -414,30 +737,102 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 				Div(decimal.NewFromInt(1000)).Mul(dGroupRatio).Mul(dQuotaPerUnit)
 			extraContent += fmt.Sprintf("File Search 调用 %d 次，调用花费 $%s",
 				fileSearchTool.CallCount, dFileSearchQuota.String())
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemFileSearch,
+				Quantity: fileSearchTool.CallCount,
+				Unit:     dto.BillingUnitCalls,
+				Price:    fileSearchPrice,
+				Quota:    dFileSearchQuota.InexactFloat64(),
+			})
 		}
 	}
 
 	var quotaCalculateDecimal decimal.Decimal
 	if !priceData.UsePrice {
-		nonCachedTokens := dPromptTokens.Sub(dCacheTokens)
+		nonCachedTokens := dPromptTokens.Sub(dCacheTokens).Sub(dCacheCreationTokens)
 		cachedTokensWithRatio := dCacheTokens.Mul(dCacheRatio)
+		cacheCreationTokensWithRatio := dCacheCreationTokens.Mul(dCacheCreationRatio)
 
-		promptQuota := nonCachedTokens.Add(cachedTokensWithRatio)
+		promptQuota := nonCachedTokens.Add(cachedTokensWithRatio).Add(cacheCreationTokensWithRatio)
 		if imageTokens > 0 {
 			nonImageTokens := dPromptTokens.Sub(dImageTokens)
 			imageTokensWithRatio := dImageTokens.Mul(dImageRatio)
 			promptQuota = nonImageTokens.Add(imageTokensWithRatio)
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemPrompt,
+				Quantity: promptTokens - imageTokens,
+				Unit:     dto.BillingUnitTokens,
+				Ratio:    1,
+				Quota:    nonImageTokens.Mul(promptRatio).InexactFloat64(),
+			})
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemImageInput,
+				Quantity: imageTokens,
+				Unit:     dto.BillingUnitTokens,
+				Ratio:    imageRatio,
+				Quota:    imageTokensWithRatio.Mul(promptRatio).InexactFloat64(),
+			})
+		} else {
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemPrompt,
+				Quantity: promptTokens - cacheTokens - cacheCreationTokens,
+				Unit:     dto.BillingUnitTokens,
+				Ratio:    1,
+				Quota:    nonCachedTokens.Mul(promptRatio).InexactFloat64(),
+			})
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemCachedPrompt,
+				Quantity: cacheTokens,
+				Unit:     dto.BillingUnitTokens,
+				Ratio:    cacheRatio,
+				Quota:    cachedTokensWithRatio.Mul(promptRatio).InexactFloat64(),
+			})
+			if cacheCreationTokens > 0 {
+				billing.AddItem(dto.BillingLineItem{
+					Type:     dto.BillingItemCacheCreation,
+					Quantity: cacheCreationTokens,
+					Unit:     dto.BillingUnitTokens,
+					Ratio:    cacheCreationRatio,
+					Quota:    cacheCreationTokensWithRatio.Mul(promptRatio).InexactFloat64(),
+				})
+			}
 		}
 
-		completionQuota := dCompletionTokens.Mul(dCompletionRatio)
+		// 思考 token 单独计费，未配置思考价格时与补全倍率相同
+		textCompletionQuota := dCompletionTokens.Sub(dReasoningTokens).Mul(dCompletionRatio)
+		reasoningQuota := dReasoningTokens.Mul(dReasoningRatio)
+		completionQuota := textCompletionQuota.Add(reasoningQuota)
+		billing.AddItem(dto.BillingLineItem{
+			Type:     dto.BillingItemCompletion,
+			Quantity: completionTokens - reasoningTokens,
+			Unit:     dto.BillingUnitTokens,
+			Ratio:    completionRatio,
+			Quota:    textCompletionQuota.Mul(ratio).InexactFloat64(),
+		})
+		if reasoningTokens > 0 {
+			billing.AddItem(dto.BillingLineItem{
+				Type:     dto.BillingItemReasoning,
+				Quantity: reasoningTokens,
+				Unit:     dto.BillingUnitTokens,
+				Ratio:    reasoningRatio,
+				Quota:    reasoningQuota.Mul(ratio).InexactFloat64(),
+			})
+		}
 
-		quotaCalculateDecimal = promptQuota.Add(completionQuota).Mul(ratio)
+		quotaCalculateDecimal = promptQuota.Mul(promptRatio).Add(completionQuota.Mul(ratio))
 
 		if !ratio.IsZero() && quotaCalculateDecimal.LessThanOrEqual(decimal.Zero) {
 			quotaCalculateDecimal = decimal.NewFromInt(1)
 		}
 	} else {
-		quotaCalculateDecimal = dModelPrice.Mul(dQuotaPerUnit).Mul(dGroupRatio)
+		quotaCalculateDecimal = dModelPrice.Mul(dQuotaPerUnit).Mul(dGroupRatio).Mul(dServiceTierRatio)
+		billing.AddItem(dto.BillingLineItem{
+			Type:     dto.BillingItemRequest,
+			Quantity: 1,
+			Unit:     dto.BillingUnitRequests,
+			Price:    modelPrice,
+			Quota:    quotaCalculateDecimal.InexactFloat64(),
+		})
 	}
 	// 添加 responses tools call 调用的配额
 	quotaCalculateDecimal = quotaCalculateDecimal.Add(dWebSearchQuota)
@@ -452,6 +847,9 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	} else {
 		logContent = fmt.Sprintf("模型价格 %.2f，分组倍率 %.2f", modelPrice, groupRatio)
 	}
+	if serviceTierRatio != 1 {
+		logContent += fmt.Sprintf("，服务等级 %s 倍率 %.2f", serviceTier, serviceTierRatio)
+	}
 
 	// record all the consume log even if quota is 0
 	if totalTokens == 0 {
@@ -466,12 +864,8 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
 	}
 
-	quotaDelta := quota - preConsumedQuota
-	if quotaDelta != 0 {
-		err := service.PostConsumeQuota(relayInfo, quotaDelta, preConsumedQuota, true)
-		if err != nil {
-			common.LogError(ctx, "error consuming token remain quota: "+err.Error())
-		}
+	if _, err := service.SettleQuota(relayInfo, preConsumedQuota, quota, true); err != nil {
+		common.LogError(ctx, "error consuming token remain quota: "+err.Error())
 	}
 
 	logModel := modelName
@@ -487,11 +881,23 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		logContent += ", " + extraContent
 	}
 	other := service.GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, cacheTokens, cacheRatio, modelPrice)
+	if cacheCreationTokens != 0 {
+		other["cache_creation_tokens"] = cacheCreationTokens
+		other["cache_creation_ratio"] = cacheCreationRatio
+	}
+	if reasoningTokens != 0 {
+		other["reasoning_tokens"] = reasoningTokens
+		other["reasoning_ratio"] = reasoningRatio
+	}
 	if imageTokens != 0 {
 		other["image"] = true
 		other["image_ratio"] = imageRatio
 		other["image_output"] = imageTokens
 	}
+	if serviceTier != "" {
+		other["service_tier"] = serviceTier
+		other["service_tier_ratio"] = serviceTierRatio
+	}
 	if !dWebSearchQuota.IsZero() {
 		if relayInfo.ResponsesUsageInfo != nil {
 			if webSearchTool, exists := relayInfo.ResponsesUsageInfo.BuiltInTools[dto.BuildInToolWebSearchPreview]; exists {
@@ -512,6 +918,8 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			other["file_search_price"] = fileSearchPrice
 		}
 	}
+	billing.Quota = quota
+	other["billing"] = billing
 	model.RecordConsumeLog(ctx, relayInfo.UserId, relayInfo.ChannelId, promptTokens, completionTokens, logModel,
 		tokenName, quota, logContent, relayInfo.TokenId, userQuota, int(useTimeSeconds), relayInfo.IsStream, relayInfo.Group, other)
 }
@@ -5,6 +5,8 @@ import (
 	"one-api/relay/channel"
 	"one-api/relay/channel/ali"
 	"one-api/relay/channel/aws"
+	"one-api/relay/channel/azureai"
+	"one-api/relay/channel/baichuan"
 	"one-api/relay/channel/baidu"
 	"one-api/relay/channel/baidu_v2"
 	"one-api/relay/channel/claude"
@@ -15,17 +17,25 @@ import (
 	"one-api/relay/channel/dify"
 	"one-api/relay/channel/gemini"
 	"one-api/relay/channel/jina"
+	"one-api/relay/channel/lingyiwanwu"
+	"one-api/relay/channel/minimax"
 	"one-api/relay/channel/mistral"
 	"one-api/relay/channel/mokaai"
+	"one-api/relay/channel/nvidia"
 	"one-api/relay/channel/ollama"
 	"one-api/relay/channel/openai"
+	"one-api/relay/channel/openrouter"
 	"one-api/relay/channel/palm"
 	"one-api/relay/channel/perplexity"
+	"one-api/relay/channel/sagemaker"
 	"one-api/relay/channel/siliconflow"
+	"one-api/relay/channel/task/assistants"
+	"one-api/relay/channel/task/batch"
 	"one-api/relay/channel/task/suno"
 	"one-api/relay/channel/tencent"
 	"one-api/relay/channel/vertex"
 	"one-api/relay/channel/volcengine"
+	"one-api/relay/channel/voyage"
 	"one-api/relay/channel/xai"
 	"one-api/relay/channel/xunfei"
 	"one-api/relay/channel/zhipu"
@@ -84,13 +94,27 @@ func GetAdaptor(apiType int) channel.Adaptor {
 	case constant.APITypeBaiduV2:
 		return &baidu_v2.Adaptor{}
 	case constant.APITypeOpenRouter:
-		return &openai.Adaptor{}
+		return &openrouter.Adaptor{}
 	case constant.APITypeXinference:
 		return &openai.Adaptor{}
 	case constant.APITypeXai:
 		return &xai.Adaptor{}
 	case constant.APITypeCoze:
 		return &coze.Adaptor{}
+	case constant.APITypeMiniMax:
+		return &minimax.Adaptor{}
+	case constant.APITypeLingYiWanWu:
+		return &lingyiwanwu.Adaptor{}
+	case constant.APITypeBaichuan:
+		return &baichuan.Adaptor{}
+	case constant.APITypeSageMaker:
+		return &sagemaker.Adaptor{}
+	case constant.APITypeAzureAI:
+		return &azureai.Adaptor{}
+	case constant.APITypeNvidia:
+		return &nvidia.Adaptor{}
+	case constant.APITypeVoyage:
+		return &voyage.Adaptor{}
 	}
 	return nil
 }
@@ -101,6 +125,10 @@ func GetTaskAdaptor(platform commonconstant.TaskPlatform) channel.TaskAdaptor {
 	//	return &aiproxy.Adaptor{}
 	case commonconstant.TaskPlatformSuno:
 		return &suno.TaskAdaptor{}
+	case commonconstant.TaskPlatformBatch:
+		return &batch.TaskAdaptor{}
+	case commonconstant.TaskPlatformAssistants:
+		return &assistants.TaskAdaptor{}
 	}
 	return nil
 }
@@ -0,0 +1,283 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/setting/model_setting"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 网关代为执行工具调用：请求中通过 agent_tools 指定管理员定义的 HTTP 工具，模型调用这些工具时由网关请求工具地址，
+// 把结果作为 tool 消息回传给模型，直到模型不再调用工具或达到最大步数，每一步单独计费
+
+// getAgentTools 返回请求指定的工具，"*" 表示所有工具，并从请求中移除 agent_tools
+func getAgentTools(textRequest *dto.GeneralOpenAIRequest, relayInfo *relaycommon.RelayInfo) (map[string]*model_setting.AgentTool, error) {
+	names := textRequest.AgentTools
+	textRequest.AgentTools = nil
+	if len(names) == 0 {
+		return nil, nil
+	}
+	settings := model_setting.GetAgentLoopSettings()
+	if !settings.Enabled {
+		return nil, errors.New("agent loop is not enabled")
+	}
+	if relayInfo.RelayMode != relayconstant.RelayModeChatCompletions || relayInfo.RelayFormat != relaycommon.RelayFormatOpenAI {
+		return nil, errors.New("agent_tools is only supported by /v1/chat/completions")
+	}
+	tools := make(map[string]*model_setting.AgentTool)
+	for _, name := range names {
+		if name == "*" {
+			for i := range settings.Tools {
+				tools[settings.Tools[i].Name] = &settings.Tools[i]
+			}
+			continue
+		}
+		tool := settings.GetTool(name)
+		if tool == nil {
+			return nil, fmt.Errorf("agent tool %s not found", name)
+		}
+		tools[name] = tool
+	}
+	for _, tool := range textRequest.Tools {
+		if _, ok := tools[tool.Function.Name]; ok {
+			return nil, fmt.Errorf("tool %s conflicts with agent tool", tool.Function.Name)
+		}
+	}
+	return tools, nil
+}
+
+// agentToolCalls 返回第一个 choice 中需要网关执行的工具调用，包含客户端自己的工具时返回空，由客户端处理
+func agentToolCalls(response *dto.OpenAITextResponse, tools map[string]*model_setting.AgentTool) []dto.ToolCallRequest {
+	calls := response.Choices[0].Message.ParseToolCalls()
+	for _, call := range calls {
+		if _, ok := tools[call.Function.Name]; !ok {
+			return nil
+		}
+	}
+	return calls
+}
+
+// doNonStreamRequest 发送非流式请求，截获适配器写出的响应并解析为 OpenAI 格式
+func doNonStreamRequest(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, requestBody io.Reader) (*dto.OpenAITextResponse, *dto.Usage, *dto.OpenAIErrorWithStatusCode) {
+	resp, err := adaptor.DoRequest(c, relayInfo, requestBody)
+	if err != nil {
+		return nil, nil, service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	statusCodeMappingStr := c.GetString("status_code_mapping")
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		if httpResp.StatusCode != http.StatusOK {
+			openaiErr := service.RelayErrorHandler(httpResp, false)
+			// reset status code 重置状态码
+			service.ResetStatusCode(openaiErr, statusCodeMappingStr)
+			return nil, nil, openaiErr
+		}
+	}
+
+	writer := newCaptureResponseWriter(c.Writer)
+	c.Writer = writer
+	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	c.Writer = writer.ResponseWriter
+	if openaiErr != nil {
+		// reset status code 重置状态码
+		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
+		return nil, nil, openaiErr
+	}
+	var response dto.OpenAITextResponse
+	err = common.DecodeJson(writer.body.Bytes(), &response)
+	if err != nil {
+		return nil, nil, service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if len(response.Choices) == 0 {
+		return nil, nil, service.OpenAIErrorWrapper(errors.New("no choices in response"), "empty_response", http.StatusInternalServerError)
+	}
+	stepUsage, _ := usage.(*dto.Usage)
+	return &response, stepUsage, nil
+}
+
+// countAgentStepUsage 适配器没有返回用量时按本地计算的提示和补全 token 计费
+func countAgentStepUsage(relayInfo *relaycommon.RelayInfo, stepRequest *dto.GeneralOpenAIRequest, response *dto.OpenAITextResponse) *dto.Usage {
+	promptTokens, err := service.CountTokenMessages(relayInfo, stepRequest.Messages, relayInfo.UpstreamModelName, false)
+	if err != nil {
+		promptTokens = relayInfo.PromptTokens
+	}
+	message := response.Choices[0].Message
+	completionTokens, _ := service.CountTextToken(message.StringContent()+string(message.ToolCalls), relayInfo.UpstreamModelName)
+	return &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
+func agentLoopHelper(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, textRequest *dto.GeneralOpenAIRequest,
+	tools map[string]*model_setting.AgentTool, priceData helper.PriceData, preConsumedQuota *int, userQuota int) *dto.OpenAIErrorWithStatusCode {
+	clientStream := textRequest.Stream
+	textRequest.Stream = false
+	textRequest.StreamOptions = nil
+	relayInfo.IsStream = false
+	// 客户端请求流式响应时，等待最终回复期间 ping 保活
+	stopPinger := func() {}
+	if clientStream {
+		stopPinger = helper.StartPingHeartbeat(c)
+	}
+	defer stopPinger()
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		textRequest.Tools = append(textRequest.Tools, service.AgentToolDefinition(tools[name]))
+	}
+	maxSteps := max(model_setting.GetAgentLoopSettings().MaxSteps, 1)
+
+	totalUsage := dto.Usage{}
+	billed := false
+	// 已经计费的步骤不能重试，避免重复扣费
+	stepError := func(openaiErr *dto.OpenAIErrorWithStatusCode) *dto.OpenAIErrorWithStatusCode {
+		if billed {
+			openaiErr.LocalError = true
+		}
+		return openaiErr
+	}
+	var response *dto.OpenAITextResponse
+	for step := 1; ; step++ {
+		if step == maxSteps {
+			// 最后一步要求模型直接给出回复
+			textRequest.ToolChoice = "none"
+		}
+		// 适配器转换请求时可能修改请求内容，每一步使用副本
+		var stepRequest dto.GeneralOpenAIRequest
+		data, _ := json.Marshal(textRequest)
+		if err := json.Unmarshal(data, &stepRequest); err != nil {
+			return stepError(service.OpenAIErrorWrapperLocal(err, "copy_request_failed", http.StatusInternalServerError))
+		}
+		requestBody, openaiErr := convertTextRequestBody(c, relayInfo, adaptor, &stepRequest)
+		if openaiErr != nil {
+			return stepError(openaiErr)
+		}
+		var usage *dto.Usage
+		response, usage, openaiErr = doNonStreamRequest(c, relayInfo, adaptor, requestBody)
+		if openaiErr != nil {
+			return stepError(openaiErr)
+		}
+		if usage == nil {
+			usage = countAgentStepUsage(relayInfo, &stepRequest, response)
+		}
+		totalUsage.PromptTokens += usage.PromptTokens
+		totalUsage.CompletionTokens += usage.CompletionTokens
+		totalUsage.TotalTokens += usage.TotalTokens
+
+		calls := agentToolCalls(response, tools)
+		extraContent := fmt.Sprintf("Agent 第 %d 步", step)
+		if len(calls) > 0 {
+			names := make([]string, 0, len(calls))
+			for _, call := range calls {
+				names = append(names, call.Function.Name)
+			}
+			extraContent += "，调用工具 " + strings.Join(names, ", ")
+		}
+		postConsumeQuota(c, relayInfo, usage, *preConsumedQuota, userQuota, priceData, extraContent)
+		*preConsumedQuota = 0
+		billed = true
+		if len(calls) == 0 || step >= maxSteps {
+			break
+		}
+		userQuota, openaiErr = runAgentTools(c, relayInfo, textRequest, response, calls, tools)
+		if openaiErr != nil {
+			return stepError(openaiErr)
+		}
+	}
+
+	response.Usage = totalUsage
+	if !clientStream {
+		c.JSON(http.StatusOK, response)
+		return nil
+	}
+	stopPinger()
+	writeStreamFromResponse(c, relayInfo, response)
+	return nil
+}
+
+// runAgentTools 执行工具调用并把调用和结果追加到对话中，返回用户剩余额度
+func runAgentTools(c *gin.Context, relayInfo *relaycommon.RelayInfo, textRequest *dto.GeneralOpenAIRequest, response *dto.OpenAITextResponse,
+	calls []dto.ToolCallRequest, tools map[string]*model_setting.AgentTool) (int, *dto.OpenAIErrorWithStatusCode) {
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
+	if err != nil {
+		return 0, service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota <= 0 {
+		return 0, service.OpenAIErrorWrapperLocal(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+	}
+	assistantMessage := response.Choices[0].Message
+	assistantMessage.Role = "assistant"
+	textRequest.Messages = append(textRequest.Messages, assistantMessage)
+	for _, call := range calls {
+		result := service.ExecuteAgentTool(c.Request.Context(), tools[call.Function.Name], call.Function.Arguments)
+		common.LogInfo(c, fmt.Sprintf("agent tool %s called, result length %d", call.Function.Name, len(result)))
+		toolMessage := dto.Message{Role: "tool", ToolCallId: call.ID}
+		toolMessage.SetStringContent(result)
+		textRequest.Messages = append(textRequest.Messages, toolMessage)
+	}
+	return userQuota, nil
+}
+
+// writeStreamFromResponse 客户端请求流式响应时，把完整的回复按流式格式一次性返回
+func writeStreamFromResponse(c *gin.Context, relayInfo *relaycommon.RelayInfo, response *dto.OpenAITextResponse) {
+	// 包装的 Writer 根据 IsStream 逐行转换响应
+	relayInfo.IsStream = true
+	helper.SetEventStreamHeaders(c)
+	chunk := dto.ChatCompletionsStreamResponse{
+		Id:      response.Id,
+		Object:  "chat.completion.chunk",
+		Created: response.Created,
+		Model:   response.Model,
+	}
+	for _, choice := range response.Choices {
+		streamChoice := dto.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+		streamChoice.Delta.Role = "assistant"
+		if content := choice.Message.StringContent(); content != "" {
+			streamChoice.Delta.SetContentString(content)
+		}
+		streamChoice.Delta.ToolCalls = streamToolCalls(&choice.Message)
+		finishReason := choice.FinishReason
+		streamChoice.FinishReason = &finishReason
+		chunk.Choices = append(chunk.Choices, streamChoice)
+	}
+	_ = helper.ObjectData(c, chunk)
+	if relayInfo.ShouldIncludeUsage {
+		_ = helper.ObjectData(c, helper.GenerateFinalUsageResponse(response.Id, response.Created, response.Model, response.Usage))
+	}
+	helper.Done(c)
+}
+
+// streamToolCalls 把回复中的工具调用转换为流式数据块的格式，带上序号
+func streamToolCalls(message *dto.Message) []dto.ToolCallResponse {
+	if message.ToolCalls == nil {
+		return nil
+	}
+	var toolCalls []dto.ToolCallResponse
+	if err := json.Unmarshal(message.ToolCalls, &toolCalls); err != nil {
+		return nil
+	}
+	for i := range toolCalls {
+		toolCalls[i].SetIndex(i)
+	}
+	return toolCalls
+}
@@ -0,0 +1,256 @@
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel/task/assistants"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Assistants API 透传：请求原样转发到 OpenAI 兼容的渠道，只替换鉴权相关的请求头；
+// 网关记录 assistant 和 thread 所属的用户和渠道，创建的 run 作为异步任务由任务轮询器查询，
+// run 结束后按 usage 中的实际用量结算
+
+// assistantPath 去掉 /v1/ 前缀后按 / 拆分请求路径
+func assistantPath(c *gin.Context) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(c.Request.URL.Path, "/v1/"), "/"), "/")
+}
+
+// isCreateRunRequest 创建 run 的请求：POST /v1/threads/runs 和 POST /v1/threads/{thread_id}/runs
+func isCreateRunRequest(c *gin.Context) bool {
+	if c.Request.Method != http.MethodPost {
+		return false
+	}
+	path := assistantPath(c)
+	return (len(path) == 2 && path[1] == "runs") || (len(path) == 3 && path[2] == "runs")
+}
+
+func AssistantHelper(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
+	relayInfo := relaycommon.GenRelayInfo(c)
+	createRun := isCreateRunRequest(c)
+
+	var requestBody []byte
+	if c.Request.Method == http.MethodPost {
+		var err error
+		requestBody, err = common.GetRequestBody(c)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+		}
+	}
+	fullRequestURL := strings.TrimSuffix(relayInfo.BaseUrl, "/") + c.Request.URL.Path
+	if c.Request.URL.RawQuery != "" {
+		fullRequestURL += "?" + c.Request.URL.RawQuery
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, fullRequestURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	if len(requestBody) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("OpenAI-Beta", c.Request.Header.Get("OpenAI-Beta"))
+	assistants.SetupHeader(req.Header, relayInfo.ApiKey, relayInfo.Organization)
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return service.RelayErrorHandler(resp, false)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return assistantStreamHandler(c, resp, relayInfo, createRun)
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	responseBody, openaiErr := handleAssistantResponse(c, relayInfo, responseBody, createRun)
+	if openaiErr != nil {
+		return openaiErr
+	}
+	c.Data(http.StatusOK, "application/json", responseBody)
+	return nil
+}
+
+// handleAssistantResponse 记录新建的对象和 run，删除对象时移除记录，列出 assistant 时只返回用户自己的
+func handleAssistantResponse(c *gin.Context, relayInfo *relaycommon.RelayInfo, body []byte, createRun bool) ([]byte, *dto.OpenAIErrorWithStatusCode) {
+	path := assistantPath(c)
+	switch c.Request.Method {
+	case http.MethodGet:
+		if len(path) == 1 && path[0] == "assistants" {
+			filtered, err := filterAssistantList(relayInfo.UserId, body)
+			if err != nil {
+				return nil, service.OpenAIErrorWrapper(err, "filter_assistants_failed", http.StatusInternalServerError)
+			}
+			return filtered, nil
+		}
+	case http.MethodDelete:
+		if len(path) == 2 {
+			if err := model.DeleteAssistantObject(relayInfo.UserId, path[1]); err != nil {
+				common.LogError(c, "delete assistant object failed: "+err.Error())
+			}
+		}
+	case http.MethodPost:
+		var object assistants.Object
+		if err := json.Unmarshal(body, &object); err != nil {
+			return nil, service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+		}
+		if len(path) == 1 {
+			// POST /v1/assistants、POST /v1/threads
+			recordAssistantObject(c, relayInfo, object.Id, object.Object)
+		}
+		if createRun {
+			if openaiErr := createAssistantRunTask(c, relayInfo, &object); openaiErr != nil {
+				return nil, openaiErr
+			}
+		}
+	}
+	return body, nil
+}
+
+func recordAssistantObject(c *gin.Context, relayInfo *relaycommon.RelayInfo, objectId string, object string) {
+	if objectId == "" {
+		return
+	}
+	if err := model.CreateAssistantObject(objectId, object, relayInfo.UserId, relayInfo.ChannelId); err != nil {
+		common.LogError(c, fmt.Sprintf("record assistant object %s failed: %s", objectId, err.Error()))
+	}
+}
+
+func filterAssistantList(userId int, body []byte) ([]byte, error) {
+	var list assistants.ObjectList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	owned, err := model.GetUserAssistantObjectIds(userId, "assistant")
+	if err != nil {
+		return nil, err
+	}
+	data := make([]json.RawMessage, 0, len(list.Data))
+	for _, item := range list.Data {
+		var object assistants.Object
+		if json.Unmarshal(item, &object) == nil && owned[object.Id] {
+			data = append(data, item)
+		}
+	}
+	list.Data = data
+	list.FirstId, list.LastId = "", ""
+	if len(data) > 0 {
+		var first, last assistants.Object
+		_ = json.Unmarshal(data[0], &first)
+		_ = json.Unmarshal(data[len(data)-1], &last)
+		list.FirstId, list.LastId = first.Id, last.Id
+	}
+	return json.Marshal(list)
+}
+
+// createAssistantRunTask 把新建的 run 记录为异步任务，run 结束后按 usage 结算；
+// 无法计费的 run（倍率未配置、按次计费）和用户额度不足以支付估算费用的 run 会在上游取消
+func createAssistantRunTask(c *gin.Context, relayInfo *relaycommon.RelayInfo, run *assistants.Object) *dto.OpenAIErrorWithStatusCode {
+	if run.Object != "thread.run" || run.Id == "" {
+		return nil
+	}
+	if len(assistantPath(c)) == 2 {
+		// POST /v1/threads/runs 同时创建了 thread
+		recordAssistantObject(c, relayInfo, run.ThreadId, "thread")
+	}
+	relayInfo.OriginModelName = run.Model
+	relayInfo.UpstreamModelName = run.Model
+	cancelRun := func() {
+		if cancelErr := assistants.CancelRun(relayInfo.BaseUrl, relayInfo.ApiKey, run.ThreadId, run.Id); cancelErr != nil {
+			common.LogError(c, fmt.Sprintf("cancel run %s failed: %s", run.Id, cancelErr.Error()))
+		}
+	}
+	// 按 run 的 token 上限估算费用，未设置上限时按默认预扣额度估算
+	priceData, err := helper.ModelPriceHelper(c, relayInfo, run.MaxPromptTokens, run.MaxCompletionTokens)
+	if err == nil && priceData.UsePrice {
+		err = fmt.Errorf("按次计费的模型 %s 不支持 Assistants API", run.Model)
+	}
+	if err != nil {
+		cancelRun()
+		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusBadRequest)
+	}
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
+	if err != nil {
+		cancelRun()
+		return service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota < priceData.ShouldPreConsumedQuota {
+		cancelRun()
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("user quota is not enough, need quota: %s", common.FormatQuota(priceData.ShouldPreConsumedQuota)),
+			"insufficient_user_quota", http.StatusForbidden)
+	}
+	task := &model.Task{
+		TaskID:     run.Id,
+		Platform:   constant.TaskPlatformAssistants,
+		UserId:     relayInfo.UserId,
+		TokenId:    relayInfo.TokenId,
+		ChannelId:  relayInfo.ChannelId,
+		Action:     constant.AssistantsActionRun,
+		Status:     model.TaskStatusSubmitted,
+		SubmitTime: common.GetTimestamp(),
+		Progress:   "0%",
+	}
+	task.Properties.Input = run.ThreadId
+	task.Properties.Billing = &model.TaskBilling{
+		ModelName:          run.Model,
+		ModelRatio:         priceData.ModelRatio,
+		CompletionRatio:    priceData.CompletionRatio,
+		GroupRatio:         priceData.GroupRatio,
+		Discount:           1,
+		FreeInput:          priceData.FreeInput,
+		CacheRatio:         priceData.CacheRatio,
+		CacheCreationRatio: priceData.CacheCreationRatio,
+		Group:              relayInfo.Group,
+	}
+	if err = task.Insert(); err != nil {
+		return service.OpenAIErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// assistantStreamHandler 原样转发事件流，遇到新建的 run 时记录为异步任务
+func assistantStreamHandler(c *gin.Context, resp *http.Response, relayInfo *relaycommon.RelayInfo, createRun bool) *dto.OpenAIErrorWithStatusCode {
+	helper.SetEventStreamHeaders(c)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	recorded := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if createRun && !recorded && strings.HasPrefix(line, "data: ") {
+			var object assistants.Object
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &object) == nil && object.Object == "thread.run" {
+				recorded = true
+				if openaiErr := createAssistantRunTask(c, relayInfo, &object); openaiErr != nil {
+					common.LogError(c, openaiErr.Error.Message)
+				}
+			}
+		}
+		if _, err := c.Writer.WriteString(line + "\n"); err != nil {
+			break
+		}
+		if line == "" {
+			c.Writer.Flush()
+		}
+	}
+	c.Writer.Flush()
+	if err := scanner.Err(); err != nil {
+		common.LogError(c, "assistant stream read failed: "+err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel/task/batch"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchDiscount 上游对批量请求的折扣，预扣和结算时都按折扣后的倍率计费
+const BatchDiscount = 0.5
+
+func taskErr2OpenAIErr(taskErr *dto.TaskError) *dto.OpenAIErrorWithStatusCode {
+	openaiErr := service.OpenAIErrorWrapper(errors.New(taskErr.Message), taskErr.Code, taskErr.StatusCode)
+	openaiErr.LocalError = taskErr.LocalError
+	return openaiErr
+}
+
+// RelayBatchSubmit 提交批量请求，调用前需要选择支持批量请求的渠道；
+// 提交时按 prompt tokens 和 max_tokens 预扣额度，任务完成后按实际用量结算
+func RelayBatchSubmit(c *gin.Context) *dto.OpenAIErrorWithStatusCode {
+	input, err := service.GetBatchInput(c)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	relayInfo := relaycommon.GenTaskRelayInfo(c)
+	err = helper.ModelMappedHelper(c, relayInfo.RelayInfo)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
+	}
+	priceData, err := helper.ModelPriceHelper(c, relayInfo.RelayInfo, 0, 0)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+	}
+	if priceData.UsePrice {
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("按次计费的模型 %s 不支持批量请求", input.Model), "model_price_not_supported", http.StatusBadRequest)
+	}
+
+	adaptor := &batch.TaskAdaptor{}
+	adaptor.Init(relayInfo)
+	if taskErr := adaptor.ValidateRequestAndSetAction(c, relayInfo); taskErr != nil {
+		return taskErr2OpenAIErr(taskErr)
+	}
+
+	// 预扣：输入按估算的 prompt tokens，输出按 max_tokens
+	var estimatedTokens float64
+	for _, item := range adaptor.Requests {
+		promptTokens, err := service.CountTokenClaudeRequest(*item.Params, relayInfo.UpstreamModelName)
+		if err != nil {
+			return service.OpenAIErrorWrapperLocal(err, "count_token_failed", http.StatusInternalServerError)
+		}
+		estimatedTokens += float64(promptTokens) + float64(item.Params.MaxTokens)*priceData.CompletionRatio
+	}
+	quota := int(estimatedTokens * priceData.ModelRatio * priceData.GroupRatio * BatchDiscount)
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota-quota < 0 {
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("user quota is not enough, need quota: %s", common.FormatQuota(quota)), "insufficient_user_quota", http.StatusForbidden)
+	}
+
+	requestBody, err := adaptor.BuildRequestBody(c, relayInfo)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "build_request_failed", http.StatusInternalServerError)
+	}
+	resp, err := adaptor.DoRequest(c, relayInfo, requestBody)
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return service.OpenAIErrorWrapper(fmt.Errorf("create batch failed: %s", string(responseBody)), "create_batch_failed", resp.StatusCode)
+	}
+	taskID, taskData, taskErr := adaptor.DoResponse(c, resp, relayInfo)
+	if taskErr != nil {
+		return taskErr2OpenAIErr(taskErr)
+	}
+
+	err = service.PostConsumeQuota(relayInfo.RelayInfo, quota, 0, true)
+	if err != nil {
+		common.SysError("error consuming token remain quota: " + err.Error())
+	}
+	task := model.InitTask(constant.TaskPlatformBatch, relayInfo)
+	task.TaskID = taskID
+	task.Action = relayInfo.Action
+	task.Quota = quota
+	task.Data = taskData
+	task.Properties.Input = input.Request.InputFileId
+	task.Properties.Billing = &model.TaskBilling{
+		ModelName:          relayInfo.OriginModelName,
+		ModelRatio:         priceData.ModelRatio,
+		CompletionRatio:    priceData.CompletionRatio,
+		GroupRatio:         priceData.GroupRatio,
+		Discount:           BatchDiscount,
+		FreeInput:          priceData.FreeInput,
+		CacheRatio:         priceData.CacheRatio,
+		CacheCreationRatio: priceData.CacheCreationRatio,
+		Group:              relayInfo.Group,
+	}
+	err = task.Insert()
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
+	}
+
+	logContent := fmt.Sprintf("批量请求 %s 共 %d 个请求，模型倍率 %.2f，补全倍率 %.2f，分组倍率 %.2f，批量折扣 %.2f，预扣额度，完成后按实际用量结算",
+		taskID, len(adaptor.Requests), priceData.ModelRatio, priceData.CompletionRatio, priceData.GroupRatio, BatchDiscount)
+	other := make(map[string]interface{})
+	other["model_ratio"] = priceData.ModelRatio
+	other["completion_ratio"] = priceData.CompletionRatio
+	other["group_ratio"] = priceData.GroupRatio
+	other["batch_discount"] = BatchDiscount
+	model.RecordConsumeLog(c, relayInfo.UserId, relayInfo.ChannelId, 0, 0, relayInfo.OriginModelName,
+		c.GetString("token_name"), quota, logContent, relayInfo.TokenId, userQuota, 0, false, relayInfo.Group, other)
+
+	c.JSON(http.StatusOK, service.BatchTask2Dto(task))
+	return nil
+}
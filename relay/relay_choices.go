@@ -0,0 +1,123 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/setting/model_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上游不支持 n 参数时，网关以 n=1 多次请求上游，把各次回复合并为多个 choice 返回，按所有调用的用量合并计费
+
+// supportMultipleChoices 渠道适配器可以直接把 n 转发给上游
+func supportMultipleChoices(info *relaycommon.RelayInfo) bool {
+	switch info.ApiType {
+	case relayconstant.APITypeOpenAI, relayconstant.APITypeAzureAI, relayconstant.APITypeOpenRouter,
+		relayconstant.APITypeGemini, relayconstant.APITypePaLM, relayconstant.APITypeXai, relayconstant.APITypeMistral:
+		return true
+	case relayconstant.APITypeVertexAi:
+		return !isClaudeUsage(info)
+	}
+	return false
+}
+
+// shouldEmulateMultipleChoices 请求 n > 1 且需要由网关多次调用上游
+func shouldEmulateMultipleChoices(info *relaycommon.RelayInfo, textRequest *dto.GeneralOpenAIRequest) bool {
+	if textRequest.N <= 1 || model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+		return false
+	}
+	if info.RelayMode != relayconstant.RelayModeChatCompletions || info.RelayFormat != relaycommon.RelayFormatOpenAI {
+		return false
+	}
+	return !supportMultipleChoices(info)
+}
+
+func multipleChoicesHelper(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, textRequest *dto.GeneralOpenAIRequest,
+	priceData helper.PriceData, preConsumedQuota *int, userQuota int) *dto.OpenAIErrorWithStatusCode {
+	n := textRequest.N
+	clientStream := textRequest.Stream
+	textRequest.N = 0
+	textRequest.Stream = false
+	textRequest.StreamOptions = nil
+	relayInfo.IsStream = false
+	// 客户端请求流式响应时，等待所有回复期间 ping 保活
+	stopPinger := func() {}
+	if clientStream {
+		stopPinger = helper.StartPingHeartbeat(c)
+	}
+	defer stopPinger()
+
+	totalUsage := dto.Usage{}
+	var response *dto.OpenAITextResponse
+	// 中途失败时已经完成的调用仍需计费，且不能重试，避免重复扣费
+	fail := func(openaiErr *dto.OpenAIErrorWithStatusCode, calls int) *dto.OpenAIErrorWithStatusCode {
+		if calls > 0 {
+			postConsumeQuota(c, relayInfo, &totalUsage, *preConsumedQuota, userQuota, priceData, fmt.Sprintf("n=%d 模拟，第 %d 次调用上游失败", n, calls+1))
+			*preConsumedQuota = 0
+			openaiErr.LocalError = true
+		}
+		return openaiErr
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			quota, err := model.GetUserSpendableQuota(relayInfo.UserId)
+			if err != nil {
+				return fail(service.OpenAIErrorWrapperLocal(err, "get_user_quota_failed", http.StatusInternalServerError), i)
+			}
+			if quota <= 0 {
+				return fail(service.OpenAIErrorWrapperLocal(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden), i)
+			}
+		}
+		// 适配器转换请求时可能修改请求内容，每次使用副本
+		var callRequest dto.GeneralOpenAIRequest
+		data, _ := json.Marshal(textRequest)
+		if err := json.Unmarshal(data, &callRequest); err != nil {
+			return fail(service.OpenAIErrorWrapperLocal(err, "copy_request_failed", http.StatusInternalServerError), i)
+		}
+		requestBody, openaiErr := convertTextRequestBody(c, relayInfo, adaptor, &callRequest)
+		if openaiErr != nil {
+			return fail(openaiErr, i)
+		}
+		callResponse, usage, openaiErr := doNonStreamRequest(c, relayInfo, adaptor, requestBody)
+		if openaiErr != nil {
+			return fail(openaiErr, i)
+		}
+		if usage == nil {
+			// 适配器没有返回用量时与智能体的每一步一样按本地计算的 token 计费
+			usage = countAgentStepUsage(relayInfo, &callRequest, callResponse)
+		}
+		totalUsage.PromptTokens += usage.PromptTokens
+		totalUsage.CompletionTokens += usage.CompletionTokens
+		totalUsage.TotalTokens += usage.TotalTokens
+		totalUsage.PromptTokensDetails.CachedTokens += usage.PromptTokensDetails.CachedTokens
+		totalUsage.CompletionTokenDetails.ReasoningTokens += usage.CompletionTokenDetails.ReasoningTokens
+		choice := callResponse.Choices[0]
+		choice.Index = i
+		if response == nil {
+			response = callResponse
+			response.Choices = make([]dto.OpenAITextResponseChoice, 0, n)
+		}
+		response.Choices = append(response.Choices, choice)
+	}
+
+	postConsumeQuota(c, relayInfo, &totalUsage, *preConsumedQuota, userQuota, priceData, fmt.Sprintf("n=%d 模拟，调用上游 %d 次", n, n))
+	*preConsumedQuota = 0
+	response.Usage = totalUsage
+	if !clientStream {
+		c.JSON(http.StatusOK, response)
+		return nil
+	}
+	stopPinger()
+	writeStreamFromResponse(c, relayInfo, response)
+	return nil
+}
@@ -2,12 +2,17 @@ package relay
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
+	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
@@ -51,6 +56,7 @@ func EmbeddingHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
 	}
+	defer rewriteResponseModel(c, relayInfo)()
 
 	embeddingRequest.Model = relayInfo.UpstreamModelName
 
@@ -78,30 +84,108 @@ func EmbeddingHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	}
 	adaptor.Init(relayInfo)
 
-	convertedRequest, err := adaptor.ConvertEmbeddingRequest(c, relayInfo, *embeddingRequest)
+	batches := embeddingBatches(relayInfo, embeddingRequest.Input)
+	if len(batches) == 1 && embeddingRequest.EncodingFormat != "base64" && embeddingRequest.Dimensions == 0 {
+		usage, openaiErr := doEmbeddingRequest(c, relayInfo, adaptor, *embeddingRequest)
+		if openaiErr != nil {
+			return openaiErr
+		}
+		postConsumeQuota(c, relayInfo, usage, preConsumedQuota, userQuota, priceData, "")
+		return nil
+	}
+
+	// 需要拆分输入或处理响应时，暂存每次上游调用的响应，合并后统一返回并扣费
+	encodingFormat := embeddingRequest.EncodingFormat
+	embeddingRequest.EncodingFormat = ""
+	response := embeddingResponse{Object: "list", Model: embeddingRequest.Model, Data: make([]embeddingItem, 0)}
+	totalPromptTokens := relayInfo.PromptTokens
+	// 中途失败时已经完成的请求仍需计费，且不能重试，避免重复扣费
+	fail := func(openaiErr *dto.OpenAIErrorWithStatusCode, completed int) *dto.OpenAIErrorWithStatusCode {
+		if completed > 0 {
+			content := fmt.Sprintf("输入拆分为 %d 次请求，第 %d 次请求失败", len(batches), completed+1)
+			if completed == len(batches) {
+				content = "处理上游响应失败"
+			}
+			relayInfo.PromptTokens = response.Usage.PromptTokens
+			postConsumeQuota(c, relayInfo, &response.Usage, preConsumedQuota, userQuota, priceData, content)
+			preConsumedQuota = 0
+			openaiErr.LocalError = true
+		}
+		return openaiErr
+	}
+	for i, batch := range batches {
+		batchRequest := *embeddingRequest
+		batchRequest.Input = batch
+		if len(batches) > 1 {
+			relayInfo.PromptTokens = getEmbeddingPromptToken(batchRequest)
+		}
+		writer := newCaptureResponseWriter(c.Writer)
+		c.Writer = writer
+		usage, openaiErr := doEmbeddingRequest(c, relayInfo, adaptor, batchRequest)
+		c.Writer = writer.ResponseWriter
+		if openaiErr != nil {
+			return fail(openaiErr, i)
+		}
+		var batchResponse embeddingResponse
+		err = common.DecodeJson(writer.body.Bytes(), &batchResponse)
+		if err != nil {
+			return fail(service.OpenAIErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), i)
+		}
+		offset := len(response.Data)
+		for _, item := range batchResponse.Data {
+			item.Index += offset
+			response.Data = append(response.Data, item)
+		}
+		if batchResponse.Model != "" {
+			response.Model = batchResponse.Model
+		}
+		// 上游没有返回用量时按本地计算的该次请求的输入 token 计费
+		promptTokens, completionTokens := relayInfo.PromptTokens, 0
+		if usage != nil && usage.PromptTokens > 0 {
+			promptTokens, completionTokens = usage.PromptTokens, usage.CompletionTokens
+		}
+		response.Usage.PromptTokens += promptTokens
+		response.Usage.CompletionTokens += completionTokens
+		response.Usage.TotalTokens += promptTokens + completionTokens
+	}
+	relayInfo.PromptTokens = totalPromptTokens
+	err = formatEmbeddings(response.Data, embeddingRequest.Dimensions, encodingFormat)
+	if err != nil {
+		return fail(service.OpenAIErrorWrapperLocal(err, "format_embedding_failed", http.StatusInternalServerError), len(batches))
+	}
+	c.JSON(http.StatusOK, response)
+	extraContent := ""
+	if len(batches) > 1 {
+		extraContent = fmt.Sprintf("输入拆分为 %d 次请求", len(batches))
+	}
+	postConsumeQuota(c, relayInfo, &response.Usage, preConsumedQuota, userQuota, priceData, extraContent)
+	return nil
+}
 
+func doEmbeddingRequest(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, embeddingRequest dto.EmbeddingRequest) (*dto.Usage, *dto.OpenAIErrorWithStatusCode) {
+	convertedRequest, err := adaptor.ConvertEmbeddingRequest(c, relayInfo, embeddingRequest)
 	if err != nil {
-		return service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
+		return nil, service.OpenAIErrorWrapperLocal(err, "convert_request_failed", http.StatusInternalServerError)
 	}
 	jsonData, err := json.Marshal(convertedRequest)
 	if err != nil {
-		return service.OpenAIErrorWrapperLocal(err, "json_marshal_failed", http.StatusInternalServerError)
+		return nil, service.OpenAIErrorWrapperLocal(err, "json_marshal_failed", http.StatusInternalServerError)
 	}
 	requestBody := bytes.NewBuffer(jsonData)
 	statusCodeMappingStr := c.GetString("status_code_mapping")
 	resp, err := adaptor.DoRequest(c, relayInfo, requestBody)
 	if err != nil {
-		return service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		return nil, service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
 	}
 
 	var httpResp *http.Response
 	if resp != nil {
 		httpResp = resp.(*http.Response)
 		if httpResp.StatusCode != http.StatusOK {
-			openaiErr = service.RelayErrorHandler(httpResp, false)
+			openaiErr := service.RelayErrorHandler(httpResp, false)
 			// reset status code 重置状态码
 			service.ResetStatusCode(openaiErr, statusCodeMappingStr)
-			return openaiErr
+			return nil, openaiErr
 		}
 	}
 
@@ -109,8 +193,81 @@ func EmbeddingHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode)
 	if openaiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
-		return openaiErr
+		return nil, openaiErr
+	}
+	embeddingUsage, _ := usage.(*dto.Usage)
+	return embeddingUsage, nil
+}
+
+type embeddingItem struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding any    `json:"embedding"`
+}
+
+type embeddingResponse struct {
+	Object string          `json:"object"`
+	Data   []embeddingItem `json:"data"`
+	Model  string          `json:"model"`
+	Usage  dto.Usage       `json:"usage"`
+}
+
+// embeddingBatches 渠道设置了 embedding_batch_size 时，把超过上限的数组输入拆分为多次请求
+func embeddingBatches(relayInfo *relaycommon.RelayInfo, input any) []any {
+	limit, _ := relayInfo.ChannelSetting[constant.ChannelSettingEmbeddingBatchSize].(float64)
+	items, ok := input.([]any)
+	if !ok || limit < 1 || len(items) <= int(limit) {
+		return []any{input}
+	}
+	// 整数数组是单个 token 输入，不能拆分
+	if _, isToken := items[0].(float64); isToken {
+		return []any{input}
+	}
+	size := int(limit)
+	batches := make([]any, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		batches = append(batches, items[start:min(start+size, len(items))])
+	}
+	return batches
+}
+
+// formatEmbeddings 上游返回的向量长度超过 dimensions 时截断并重新归一化，encoding_format 为 base64 时
+// 按 OpenAI 的格式编码为 float32 小端字节序列
+func formatEmbeddings(items []embeddingItem, dimensions int, encodingFormat string) error {
+	for i := range items {
+		values, ok := items[i].Embedding.([]any)
+		if !ok {
+			continue
+		}
+		vector := make([]float64, len(values))
+		for j, value := range values {
+			v, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("invalid embedding value at index %d", items[i].Index)
+			}
+			vector[j] = v
+		}
+		if dimensions > 0 && len(vector) > dimensions {
+			vector = vector[:dimensions]
+			var norm float64
+			for _, v := range vector {
+				norm += v * v
+			}
+			if norm = math.Sqrt(norm); norm > 0 {
+				for j := range vector {
+					vector[j] /= norm
+				}
+			}
+		}
+		if encodingFormat != "base64" {
+			items[i].Embedding = vector
+			continue
+		}
+		data := make([]byte, len(vector)*4)
+		for j, v := range vector {
+			binary.LittleEndian.PutUint32(data[j*4:], math.Float32bits(float32(v)))
+		}
+		items[i].Embedding = base64.StdEncoding.EncodeToString(data)
 	}
-	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	return nil
 }
@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/setting/model_setting"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上游不支持流式响应时，客户端的流式请求以非流式调用上游，等待期间 ping 保活，
+// 收到完整回复后按固定长度分段、间隔发送，模拟流式输出
+
+const (
+	fakeStreamChunkRunes = 8
+	fakeStreamInterval   = 20 * time.Millisecond
+)
+
+// shouldFakeStream 渠道设置 fake_stream 对请求的模型生效时，流式对话请求以非流式调用上游
+func shouldFakeStream(info *relaycommon.RelayInfo) bool {
+	if model_setting.GetGlobalSettings().PassThroughRequestEnabled {
+		return false
+	}
+	if info.RelayMode != relayconstant.RelayModeChatCompletions || info.RelayFormat != relaycommon.RelayFormatOpenAI {
+		return false
+	}
+	return channelSettingMatchesModel(info, constant.ChannelSettingFakeStream)
+}
+
+func fakeStreamHelper(c *gin.Context, relayInfo *relaycommon.RelayInfo, adaptor channel.Adaptor, textRequest *dto.GeneralOpenAIRequest,
+	priceData helper.PriceData, preConsumedQuota *int, userQuota int) *dto.OpenAIErrorWithStatusCode {
+	textRequest.Stream = false
+	textRequest.StreamOptions = nil
+	relayInfo.IsStream = false
+	stopPinger := helper.StartPingHeartbeat(c)
+	defer stopPinger()
+
+	requestBody, openaiErr := convertTextRequestBody(c, relayInfo, adaptor, textRequest)
+	if openaiErr != nil {
+		return openaiErr
+	}
+	response, usage, openaiErr := doNonStreamRequest(c, relayInfo, adaptor, requestBody)
+	if openaiErr != nil {
+		return openaiErr
+	}
+	if usage == nil {
+		usage = &response.Usage
+	}
+	response.Usage = *usage
+	stopPinger()
+	writePacedStreamFromResponse(c, relayInfo, response)
+
+	postConsumeQuota(c, relayInfo, usage, *preConsumedQuota, userQuota, priceData, "模拟流式")
+	*preConsumedQuota = 0
+	return nil
+}
+
+// writePacedStreamFromResponse 把完整的回复按流式格式分段返回，思考内容和回复内容每段 fakeStreamChunkRunes 个字符，
+// 工具调用和结束原因在每个 choice 的最后一个数据块中返回。客户端断开时停止发送
+func writePacedStreamFromResponse(c *gin.Context, relayInfo *relaycommon.RelayInfo, response *dto.OpenAITextResponse) {
+	// 包装的 Writer 根据 IsStream 逐行转换响应
+	relayInfo.IsStream = true
+	helper.SetEventStreamHeaders(c)
+	newChunk := func(choice dto.ChatCompletionsStreamResponseChoice) dto.ChatCompletionsStreamResponse {
+		return dto.ChatCompletionsStreamResponse{
+			Id:      response.Id,
+			Object:  "chat.completion.chunk",
+			Created: response.Created,
+			Model:   response.Model,
+			Choices: []dto.ChatCompletionsStreamResponseChoice{choice},
+		}
+	}
+	pace := func() bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-time.After(fakeStreamInterval):
+			return true
+		}
+	}
+	for _, choice := range response.Choices {
+		streamChoice := dto.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+		streamChoice.Delta.Role = "assistant"
+		streamChoice.Delta.SetContentString("")
+		_ = helper.ObjectData(c, newChunk(streamChoice))
+
+		reasoningContent := choice.Message.ReasoningContent
+		if reasoningContent == "" {
+			reasoningContent = choice.Message.Reasoning
+		}
+		for _, piece := range splitRunes(reasoningContent, fakeStreamChunkRunes) {
+			if !pace() {
+				return
+			}
+			streamChoice = dto.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+			streamChoice.Delta.SetReasoningContent(piece)
+			_ = helper.ObjectData(c, newChunk(streamChoice))
+		}
+		for _, piece := range splitRunes(choice.Message.StringContent(), fakeStreamChunkRunes) {
+			if !pace() {
+				return
+			}
+			streamChoice = dto.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+			streamChoice.Delta.SetContentString(piece)
+			_ = helper.ObjectData(c, newChunk(streamChoice))
+		}
+
+		streamChoice = dto.ChatCompletionsStreamResponseChoice{Index: choice.Index}
+		streamChoice.Delta.ToolCalls = streamToolCalls(&choice.Message)
+		finishReason := choice.FinishReason
+		streamChoice.FinishReason = &finishReason
+		_ = helper.ObjectData(c, newChunk(streamChoice))
+	}
+	if relayInfo.ShouldIncludeUsage {
+		_ = helper.ObjectData(c, helper.GenerateFinalUsageResponse(response.Id, response.Created, response.Model, response.Usage))
+	}
+	helper.Done(c)
+}
+
+// splitRunes 按字符数切分字符串，不会切断多字节字符
+func splitRunes(s string, size int) []string {
+	runes := []rune(s)
+	pieces := make([]string, 0, (len(runes)+size-1)/size)
+	for start := 0; start < len(runes); start += size {
+		end := min(start+size, len(runes))
+		pieces = append(pieces, string(runes[start:end]))
+	}
+	return pieces
+}
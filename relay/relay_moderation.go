@@ -0,0 +1,115 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/relay/helper"
+	"one-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func validateModerationRequest(moderationRequest *dto.ModerationRequest) error {
+	if moderationRequest.Input == nil || moderationRequest.Input == "" {
+		return errors.New("field input is required")
+	}
+	if moderationRequest.Model == "" {
+		moderationRequest.Model = "text-moderation-stable"
+	}
+	return nil
+}
+
+// ModerationHelper 转发 /v1/moderations，支持 OpenAI 及兼容渠道和 Mistral 渠道，
+// 未指定模型时默认为 text-moderation-stable，使用其他服务商时可在渠道中把它映射为对应的审核模型
+func ModerationHelper(c *gin.Context) (openaiErr *dto.OpenAIErrorWithStatusCode) {
+	relayInfo := relaycommon.GenRelayInfo(c)
+
+	var moderationRequest *dto.ModerationRequest
+	err := common.UnmarshalBodyReusable(c, &moderationRequest)
+	if err != nil {
+		common.LogError(c, fmt.Sprintf("getAndValidateModerationRequest failed: %s", err.Error()))
+		return service.OpenAIErrorWrapperLocal(err, "invalid_moderation_request", http.StatusBadRequest)
+	}
+	err = validateModerationRequest(moderationRequest)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "invalid_moderation_request", http.StatusBadRequest)
+	}
+	if relayInfo.ApiType != relayconstant.APITypeOpenAI && relayInfo.ApiType != relayconstant.APITypeMistral {
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("channel type %d does not support moderations", relayInfo.ChannelType), "unsupported_channel_type", http.StatusBadRequest)
+	}
+
+	err = helper.ModelMappedHelper(c, relayInfo)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
+	}
+	defer rewriteResponseModel(c, relayInfo)()
+	moderationRequest.Model = relayInfo.UpstreamModelName
+
+	promptToken, _ := service.CountTokenInput(moderationRequest.Input, moderationRequest.Model)
+	relayInfo.PromptTokens = promptToken
+
+	priceData, err := helper.ModelPriceHelper(c, relayInfo, promptToken, 0)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "model_price_error", http.StatusInternalServerError)
+	}
+	// pre-consume quota 预消耗配额
+	preConsumedQuota, userQuota, openaiErr := preConsumeQuota(c, priceData.ShouldPreConsumedQuota, relayInfo)
+	if openaiErr != nil {
+		return openaiErr
+	}
+	defer func() {
+		if openaiErr != nil {
+			returnPreConsumedQuota(c, relayInfo, userQuota, preConsumedQuota)
+		}
+	}()
+
+	adaptor := GetAdaptor(relayInfo.ApiType)
+	if adaptor == nil {
+		return service.OpenAIErrorWrapperLocal(fmt.Errorf("invalid api type: %d", relayInfo.ApiType), "invalid_api_type", http.StatusBadRequest)
+	}
+	adaptor.Init(relayInfo)
+
+	jsonData, err := json.Marshal(moderationRequest)
+	if err != nil {
+		return service.OpenAIErrorWrapperLocal(err, "json_marshal_failed", http.StatusInternalServerError)
+	}
+	statusCodeMappingStr := c.GetString("status_code_mapping")
+	resp, err := adaptor.DoRequest(c, relayInfo, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		if httpResp.StatusCode != http.StatusOK {
+			openaiErr = service.RelayErrorHandler(httpResp, false)
+			// reset status code 重置状态码
+			service.ResetStatusCode(openaiErr, statusCodeMappingStr)
+			return openaiErr
+		}
+	}
+
+	usage, openaiErr := adaptor.DoResponse(c, httpResp, relayInfo)
+	if openaiErr != nil {
+		// reset status code 重置状态码
+		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
+		return openaiErr
+	}
+	extraContent := ""
+	if categories := c.GetStringSlice(constant.ContextKeyModerationFlaggedCategories); len(categories) > 0 {
+		common.LogInfo(c, fmt.Sprintf("moderation flagged categories: %s", strings.Join(categories, ", ")))
+		extraContent = fmt.Sprintf("审核标记类别 %s", strings.Join(categories, ", "))
+	}
+	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, extraContent)
+	return nil
+}
@@ -0,0 +1,105 @@
+package relay
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 各上游采样参数的取值范围不同，超出范围会直接返回 400，转发前按上游的范围修正，
+// 上游不支持的参数直接去除，修改内容记录到日志详情中
+
+type paramRange struct {
+	min float64
+	max float64
+}
+
+func (r paramRange) clamp(value float64) float64 {
+	return min(max(value, r.min), r.max)
+}
+
+type providerParamRules struct {
+	temperature paramRange
+	topP        paramRange
+	// penalty 为 nil 表示上游不支持 presence_penalty 和 frequency_penalty
+	penalty *paramRange
+}
+
+var defaultParamRules = providerParamRules{
+	temperature: paramRange{0, 2},
+	topP:        paramRange{0, 1},
+	penalty:     &paramRange{-2, 2},
+}
+
+func getProviderParamRules(info *relaycommon.RelayInfo) providerParamRules {
+	if isClaudeUsage(info) {
+		return providerParamRules{temperature: paramRange{0, 1}, topP: paramRange{0, 1}}
+	}
+	switch info.ApiType {
+	case relayconstant.APITypeGemini, relayconstant.APITypeVertexAi:
+		return providerParamRules{temperature: paramRange{0, 2}, topP: paramRange{0, 1}}
+	case relayconstant.APITypeZhipuV4:
+		return providerParamRules{temperature: paramRange{0, 1}, topP: paramRange{0, 1}}
+	}
+	return defaultParamRules
+}
+
+func formatParam(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// sanitizeRequestParams 修正超出上游范围的参数，修改记录保存到 param_adjustments
+func sanitizeRequestParams(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) {
+	rules := getProviderParamRules(info)
+	var adjustments []string
+	clamp := func(name string, value *float64, r paramRange) {
+		if clamped := r.clamp(*value); clamped != *value {
+			adjustments = append(adjustments, fmt.Sprintf("%s %s -> %s", name, formatParam(*value), formatParam(clamped)))
+			*value = clamped
+		}
+	}
+	if request.Temperature != nil {
+		temperature := *request.Temperature
+		clamp("temperature", &temperature, rules.temperature)
+		request.Temperature = &temperature
+	}
+	// top_p 为 0 时不会转发
+	if request.TopP != 0 {
+		clamp("top_p", &request.TopP, rules.topP)
+	}
+	penalties := []struct {
+		name  string
+		value *float64
+	}{
+		{"presence_penalty", &request.PresencePenalty},
+		{"frequency_penalty", &request.FrequencyPenalty},
+	}
+	for _, penalty := range penalties {
+		if *penalty.value == 0 {
+			continue
+		}
+		if rules.penalty == nil {
+			adjustments = append(adjustments, fmt.Sprintf("%s removed", penalty.name))
+			*penalty.value = 0
+			continue
+		}
+		clamp(penalty.name, penalty.value, *rules.penalty)
+	}
+	// 只有 OpenAI 支持 service_tier，其他上游按标准服务等级处理和计费
+	if request.ServiceTier != "" && info.ChannelType != common.ChannelTypeOpenAI {
+		adjustments = append(adjustments, fmt.Sprintf("service_tier %s removed", request.ServiceTier))
+		request.ServiceTier = ""
+	}
+	if request.TopK < 0 {
+		adjustments = append(adjustments, fmt.Sprintf("top_k %d removed", request.TopK))
+		request.TopK = 0
+	}
+	if len(adjustments) > 0 {
+		c.Set("param_adjustments", adjustments)
+	}
+}
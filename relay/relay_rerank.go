@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
@@ -24,6 +25,35 @@ func getRerankPromptToken(rerankRequest dto.RerankRequest) int {
 	return token
 }
 
+const (
+	rerankBillingSearchUnits = "search_units"
+	// 一个搜索单元包含一次查询和最多 100 个文档，超过 500 个 token 的文档（包含查询）按多个文档计算
+	rerankSearchUnitDocuments = 100
+	rerankSearchUnitTokens    = 500
+)
+
+func estimateRerankSearchUnits(rerankRequest dto.RerankRequest) int {
+	queryToken, _ := service.CountTokenInput(rerankRequest.Query, rerankRequest.Model)
+	documents := 0
+	for _, document := range rerankRequest.Documents {
+		token, _ := service.CountTokenInput(document, rerankRequest.Model)
+		documents += max((queryToken+token+rerankSearchUnitTokens-1)/rerankSearchUnitTokens, 1)
+	}
+	return max((documents+rerankSearchUnitDocuments-1)/rerankSearchUnitDocuments, 1)
+}
+
+// rerankSearchUnits 渠道按搜索单元计费且模型设置了按次价格时，返回本次请求的搜索单元数，否则返回 0 按 token 计费
+func rerankSearchUnits(relayInfo *relaycommon.RelayInfo, rerankRequest dto.RerankRequest, priceData helper.PriceData) int {
+	billing, _ := relayInfo.ChannelSetting[constant.ChannelSettingRerankBilling].(string)
+	if billing != rerankBillingSearchUnits || !priceData.UsePrice {
+		return 0
+	}
+	if relayInfo.RerankerInfo.SearchUnits > 0 {
+		return relayInfo.RerankerInfo.SearchUnits
+	}
+	return estimateRerankSearchUnits(rerankRequest)
+}
+
 func RerankHelper(c *gin.Context, relayMode int) (openaiErr *dto.OpenAIErrorWithStatusCode) {
 
 	var rerankRequest *dto.RerankRequest
@@ -33,6 +63,7 @@ func RerankHelper(c *gin.Context, relayMode int) (openaiErr *dto.OpenAIErrorWith
 		return service.OpenAIErrorWrapperLocal(err, "invalid_text_request", http.StatusBadRequest)
 	}
 
+	rerankRequest.Normalize()
 	relayInfo := relaycommon.GenRelayInfoRerank(c, rerankRequest)
 
 	if rerankRequest.Query == "" {
@@ -46,6 +77,7 @@ func RerankHelper(c *gin.Context, relayMode int) (openaiErr *dto.OpenAIErrorWith
 	if err != nil {
 		return service.OpenAIErrorWrapperLocal(err, "model_mapped_error", http.StatusInternalServerError)
 	}
+	defer rewriteResponseModel(c, relayInfo)()
 
 	rerankRequest.Model = relayInfo.UpstreamModelName
 
@@ -105,6 +137,11 @@ func RerankHelper(c *gin.Context, relayMode int) (openaiErr *dto.OpenAIErrorWith
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
 		return openaiErr
 	}
-	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
+	extraContent := ""
+	if searchUnits := rerankSearchUnits(relayInfo, *rerankRequest, priceData); searchUnits > 0 {
+		priceData.ModelPrice *= float64(searchUnits)
+		extraContent = fmt.Sprintf("搜索单元 %d", searchUnits)
+	}
+	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, extraContent)
 	return nil
 }
@@ -49,9 +49,9 @@ func RelayTaskSubmit(c *gin.Context, relayMode int) (taskErr *dto.TaskError) {
 	}
 
 	// 预扣
-	groupRatio := setting.GetGroupRatio(relayInfo.Group)
+	groupRatio := setting.GetGroupModelRatio(relayInfo.Group, modelName)
 	ratio := modelPrice * groupRatio
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 		return
@@ -135,12 +135,25 @@ func RelayTaskSubmit(c *gin.Context, relayMode int) (taskErr *dto.TaskError) {
 	if taskErr != nil {
 		return
 	}
+	// 没有任务 id 的任务无法查询，不扣费也不记录
+	if taskID == "" {
+		taskErr = service.TaskErrorWrapper(errors.New("upstream returned empty task id"), "empty_task_id", http.StatusInternalServerError)
+		return
+	}
 	relayInfo.ConsumeQuota = true
 	// insert task
-	task := model.InitTask(constant.TaskPlatformSuno, relayInfo)
+	task := model.InitTask(platform, relayInfo)
 	task.TaskID = taskID
+	task.Action = relayInfo.Action
 	task.Quota = quota
 	task.Data = taskData
+	// 任务进入终态时通知请求中的 notify_hook
+	var hook struct {
+		NotifyHook string `json:"notify_hook"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &hook); err == nil {
+		task.Properties.NotifyHook = hook.NotifyHook
+	}
 	err = task.Insert()
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
@@ -193,7 +206,7 @@ func sunoFetchRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *dto.Ta
 			return
 		}
 		for _, task := range taskModels {
-			tasks = append(tasks, TaskModel2Dto(task))
+			tasks = append(tasks, service.TaskModel2Dto(task))
 		}
 	} else {
 		tasks = make([]any, 0)
@@ -221,21 +234,7 @@ func sunoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *dt
 
 	respBody, err = json.Marshal(dto.TaskResponse[any]{
 		Code: "success",
-		Data: TaskModel2Dto(originTask),
+		Data: service.TaskModel2Dto(originTask),
 	})
 	return
 }
-
-func TaskModel2Dto(task *model.Task) *dto.TaskDto {
-	return &dto.TaskDto{
-		TaskID:     task.TaskID,
-		Action:     task.Action,
-		Status:     string(task.Status),
-		FailReason: task.FailReason,
-		SubmitTime: task.SubmitTime,
-		StartTime:  task.StartTime,
-		FinishTime: task.FinishTime,
-		Progress:   task.Progress,
-		Data:       task.Data,
-	}
-}
@@ -0,0 +1,507 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureResponseWriter 暂存适配器写入的响应，不写入客户端，由调用方处理后再返回
+type captureResponseWriter struct {
+	gin.ResponseWriter
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newCaptureResponseWriter(writer gin.ResponseWriter) *captureResponseWriter {
+	return &captureResponseWriter{ResponseWriter: writer, header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *captureResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *captureResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *captureResponseWriter) WriteHeaderNow() {
+}
+
+func (w *captureResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *captureResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *captureResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *captureResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *captureResponseWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+func (w *captureResponseWriter) Flush() {
+}
+
+// responseModelPattern 匹配 JSON 中的 model 字段，字符串值中的引号已转义，不会误匹配内容
+var responseModelPattern = regexp.MustCompile(`"model"\s*:\s*"(?:[^"\\]|\\.)*"`)
+
+// modelRewriteResponseWriter 把响应中的 model 字段改写为请求的模型名称，用于模型经过重定向的请求，
+// 改写后长度可能变化，写入前移除 Content-Length
+type modelRewriteResponseWriter struct {
+	gin.ResponseWriter
+	replacement []byte
+}
+
+func (w *modelRewriteResponseWriter) WriteHeaderNow() {
+	w.ResponseWriter.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *modelRewriteResponseWriter) Write(data []byte) (int, error) {
+	w.ResponseWriter.Header().Del("Content-Length")
+	if _, err := w.ResponseWriter.Write(responseModelPattern.ReplaceAllLiteral(data, w.replacement)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *modelRewriteResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// rewriteResponseModel 模型经过重定向时改写响应中的模型名称，返回恢复原 Writer 的函数
+func rewriteResponseModel(c *gin.Context, info *relaycommon.RelayInfo) func() {
+	writer := c.Writer
+	if !info.IsModelMapped || info.OriginModelName == "" {
+		return func() {}
+	}
+	modelName, _ := json.Marshal(info.OriginModelName)
+	c.Writer = &modelRewriteResponseWriter{ResponseWriter: writer, replacement: append([]byte(`"model":`), modelName...)}
+	return func() {
+		c.Writer = writer
+	}
+}
+
+// claudeResponseWriter 把适配器写入的 OpenAI 格式响应转换为 Claude 格式后写入客户端，
+// 用于没有 Claude 接口的渠道处理 /v1/messages 请求。流式响应逐行转换，非流式响应在 finish 时转换
+type claudeResponseWriter struct {
+	gin.ResponseWriter
+	info          *relaycommon.RelayInfo
+	header        http.Header
+	status        int
+	buffer        bytes.Buffer
+	headerWritten bool
+}
+
+func newClaudeResponseWriter(writer gin.ResponseWriter, info *relaycommon.RelayInfo) *claudeResponseWriter {
+	return &claudeResponseWriter{ResponseWriter: writer, info: info, header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *claudeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *claudeResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *claudeResponseWriter) WriteHeaderNow() {
+}
+
+func (w *claudeResponseWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	if w.info.IsStream {
+		w.convertStreamLines()
+	}
+	return len(data), nil
+}
+
+func (w *claudeResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *claudeResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *claudeResponseWriter) Written() bool {
+	return w.headerWritten || w.buffer.Len() > 0
+}
+
+func (w *claudeResponseWriter) Flush() {
+	if w.info.IsStream && w.headerWritten {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// writeHeader 复制适配器设置的响应头，长度和类型按转换后的响应重新设置
+func (w *claudeResponseWriter) writeHeader(contentType string) {
+	if w.headerWritten {
+		return
+	}
+	w.headerWritten = true
+	for k, v := range w.header {
+		if k == "Content-Length" || k == "Content-Type" {
+			continue
+		}
+		w.ResponseWriter.Header()[k] = v
+	}
+	w.ResponseWriter.Header().Set("Content-Type", contentType)
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *claudeResponseWriter) writeEvents(claudeResponses []*dto.ClaudeResponse) {
+	if len(claudeResponses) == 0 {
+		return
+	}
+	w.writeHeader("text/event-stream")
+	for _, claudeResponse := range claudeResponses {
+		jsonData, err := json.Marshal(claudeResponse)
+		if err != nil {
+			common.SysError("error marshalling claude stream response: " + err.Error())
+			continue
+		}
+		_, _ = fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", claudeResponse.Type, jsonData)
+	}
+	w.ResponseWriter.Flush()
+}
+
+// convertStreamLines 转换缓冲区中完整的 data 行，不完整的行留到下次写入
+func (w *claudeResponseWriter) convertStreamLines() {
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			// 没有换行符，放回缓冲区
+			w.buffer.Reset()
+			w.buffer.WriteString(line)
+			return
+		}
+		// ping 保活注释转换为 Claude 的 ping 事件
+		if strings.HasPrefix(line, ":") {
+			w.writeHeader("text/event-stream")
+			_, _ = fmt.Fprint(w.ResponseWriter, "event: ping\ndata: {\"type\": \"ping\"}\n\n")
+			w.ResponseWriter.Flush()
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "data:"))
+		if !strings.HasPrefix(strings.TrimSpace(line), "data:") || data == "" || data == "[DONE]" {
+			continue
+		}
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if err := common.DecodeJsonStr(data, &streamResponse); err != nil {
+			continue
+		}
+		w.info.SendResponseCount++
+		w.writeEvents(service.StreamResponseOpenAI2Claude(&streamResponse, w.info))
+	}
+}
+
+// finish 流式响应发送结束事件，非流式响应转换缓冲的响应体后写入客户端
+func (w *claudeResponseWriter) finish(usage *dto.Usage) {
+	if w.info.IsStream {
+		w.convertStreamLines()
+		w.info.SendResponseCount++
+		w.info.ClaudeConvertInfo.Done = true
+		w.info.ClaudeConvertInfo.Usage = usage
+		w.writeEvents(service.StreamResponseOpenAI2Claude(&dto.ChatCompletionsStreamResponse{Model: w.info.UpstreamModelName}, w.info))
+		return
+	}
+	var openAIResponse dto.OpenAITextResponse
+	if err := common.DecodeJson(w.buffer.Bytes(), &openAIResponse); err != nil {
+		common.SysError("error unmarshalling response for claude conversion: " + err.Error())
+		w.writeHeader("application/json")
+		_, _ = w.ResponseWriter.Write(w.buffer.Bytes())
+		return
+	}
+	if usage != nil {
+		openAIResponse.Usage = *usage
+	}
+	if openAIResponse.Model == "" {
+		openAIResponse.Model = w.info.UpstreamModelName
+	}
+	jsonData, err := json.Marshal(service.ResponseOpenAI2Claude(&openAIResponse, w.info))
+	if err != nil {
+		common.SysError("error marshalling claude response: " + err.Error())
+		return
+	}
+	w.writeHeader("application/json")
+	_, _ = w.ResponseWriter.Write(jsonData)
+}
+
+// completionsResponseWriter 把适配器写入的对话格式响应转换为 text_completion 格式后写入客户端，
+// 用于只提供对话接口的渠道处理 /v1/completions 请求。流式响应逐行转换，非流式响应在 finish 时转换
+type completionsResponseWriter struct {
+	gin.ResponseWriter
+	info          *relaycommon.RelayInfo
+	header        http.Header
+	status        int
+	buffer        bytes.Buffer
+	headerWritten bool
+	// echo 请求 echo 时拼接在回复之前的 prompt，流式响应只在每个 choice 的第一个数据块拼接
+	echo   string
+	echoed map[int]bool
+}
+
+func newCompletionsResponseWriter(writer gin.ResponseWriter, info *relaycommon.RelayInfo, echo string) *completionsResponseWriter {
+	return &completionsResponseWriter{ResponseWriter: writer, info: info, header: make(http.Header), status: http.StatusOK,
+		echo: echo, echoed: make(map[int]bool)}
+}
+
+func (w *completionsResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *completionsResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *completionsResponseWriter) WriteHeaderNow() {
+}
+
+func (w *completionsResponseWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	// 模拟流式响应时，等待上游期间的 ping 保活直接写入
+	if w.info.IsStream || bytes.HasPrefix(data, []byte(":")) {
+		w.convertStreamLines()
+	}
+	return len(data), nil
+}
+
+func (w *completionsResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *completionsResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *completionsResponseWriter) Written() bool {
+	return w.headerWritten || w.buffer.Len() > 0
+}
+
+func (w *completionsResponseWriter) Flush() {
+	if w.info.IsStream && w.headerWritten {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// writeHeader 复制适配器设置的响应头，长度和类型按转换后的响应重新设置
+func (w *completionsResponseWriter) writeHeader(contentType string) {
+	if w.headerWritten {
+		return
+	}
+	w.headerWritten = true
+	for k, v := range w.header {
+		if k == "Content-Length" || k == "Content-Type" {
+			continue
+		}
+		w.ResponseWriter.Header()[k] = v
+	}
+	w.ResponseWriter.Header().Set("Content-Type", contentType)
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// convertStreamLines 转换缓冲区中完整的 data 行，不完整的行留到下次写入，[DONE] 原样写入
+func (w *completionsResponseWriter) convertStreamLines() {
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			// 没有换行符，放回缓冲区
+			w.buffer.Reset()
+			w.buffer.WriteString(line)
+			return
+		}
+		// ping 保活注释原样写入
+		if strings.HasPrefix(line, ":") {
+			w.writeHeader("text/event-stream")
+			_, _ = fmt.Fprint(w.ResponseWriter, strings.TrimSpace(line)+"\n\n")
+			w.ResponseWriter.Flush()
+			continue
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "data:"))
+		if data == "[DONE]" {
+			w.writeHeader("text/event-stream")
+			_, _ = fmt.Fprint(w.ResponseWriter, "data: [DONE]\n\n")
+			w.ResponseWriter.Flush()
+			continue
+		}
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if data == "" || common.DecodeJsonStr(data, &streamResponse) != nil {
+			continue
+		}
+		completionsResponse := service.StreamResponseChat2Completions(&streamResponse)
+		choices := completionsResponse.Choices[:0]
+		for _, choice := range completionsResponse.Choices {
+			if !w.echoed[choice.Index] {
+				w.echoed[choice.Index] = true
+				choice.Text = w.echo + choice.Text
+			}
+			// 只有角色或思考内容的数据块没有对应的文本
+			if choice.Text == "" && choice.FinishReason == nil {
+				continue
+			}
+			choices = append(choices, choice)
+		}
+		completionsResponse.Choices = choices
+		if len(choices) == 0 && completionsResponse.Usage == nil {
+			continue
+		}
+		jsonData, err := json.Marshal(completionsResponse)
+		if err != nil {
+			common.SysError("error marshalling completions stream response: " + err.Error())
+			continue
+		}
+		w.writeHeader("text/event-stream")
+		_, _ = fmt.Fprintf(w.ResponseWriter, "data: %s\n\n", jsonData)
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish 流式响应转换剩余的数据，非流式响应转换缓冲的响应体后写入客户端
+func (w *completionsResponseWriter) finish() {
+	if w.info.IsStream {
+		w.buffer.WriteString("\n")
+		w.convertStreamLines()
+		return
+	}
+	var openAIResponse dto.OpenAITextResponse
+	if err := common.DecodeJson(w.buffer.Bytes(), &openAIResponse); err != nil {
+		common.SysError("error unmarshalling response for completions conversion: " + err.Error())
+		w.writeHeader("application/json")
+		_, _ = w.ResponseWriter.Write(w.buffer.Bytes())
+		return
+	}
+	if openAIResponse.Model == "" {
+		openAIResponse.Model = w.info.UpstreamModelName
+	}
+	jsonData, err := json.Marshal(service.ResponseChat2Completions(&openAIResponse, w.echo))
+	if err != nil {
+		common.SysError("error marshalling completions response: " + err.Error())
+		return
+	}
+	w.writeHeader("application/json")
+	_, _ = w.ResponseWriter.Write(jsonData)
+}
+
+// streamAggregateResponseWriter 把适配器写入的流式对话响应合并为非流式响应，
+// 用于渠道以流式调用上游而客户端请求非流式响应的情况，数据块在写入时合并，finish 时写入客户端
+type streamAggregateResponseWriter struct {
+	gin.ResponseWriter
+	info       *relaycommon.RelayInfo
+	header     http.Header
+	status     int
+	buffer     bytes.Buffer
+	aggregator *service.ChatStreamAggregator
+}
+
+func newStreamAggregateResponseWriter(writer gin.ResponseWriter, info *relaycommon.RelayInfo) *streamAggregateResponseWriter {
+	return &streamAggregateResponseWriter{ResponseWriter: writer, info: info, header: make(http.Header), status: http.StatusOK,
+		aggregator: service.NewChatStreamAggregator()}
+}
+
+func (w *streamAggregateResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamAggregateResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *streamAggregateResponseWriter) WriteHeaderNow() {
+}
+
+func (w *streamAggregateResponseWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	w.aggregateStreamLines()
+	return len(data), nil
+}
+
+func (w *streamAggregateResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *streamAggregateResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *streamAggregateResponseWriter) Written() bool {
+	return w.buffer.Len() > 0
+}
+
+func (w *streamAggregateResponseWriter) Flush() {
+}
+
+// aggregateStreamLines 合并缓冲区中完整的 data 行，不完整的行留到下次写入，ping 保活注释直接丢弃
+func (w *streamAggregateResponseWriter) aggregateStreamLines() {
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			// 没有换行符，放回缓冲区
+			w.buffer.Reset()
+			w.buffer.WriteString(line)
+			return
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "data:"))
+		if !strings.HasPrefix(strings.TrimSpace(line), "data:") || data == "" || data == "[DONE]" {
+			continue
+		}
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if err := common.DecodeJsonStr(data, &streamResponse); err != nil {
+			continue
+		}
+		w.aggregator.Add(&streamResponse)
+	}
+}
+
+// aggregateSkippedHeaders 流式响应特有的响应头，合并为非流式响应后不再写入客户端
+var aggregateSkippedHeaders = map[string]bool{
+	"Content-Length": true, "Content-Type": true, "Cache-Control": true, "Connection": true,
+	"Transfer-Encoding": true, "X-Accel-Buffering": true,
+}
+
+// finish 写入合并后的响应。之后按非流式请求处理，外层的格式转换和日志与客户端请求一致
+func (w *streamAggregateResponseWriter) finish(usage *dto.Usage) {
+	w.buffer.WriteString("\n")
+	w.aggregateStreamLines()
+	w.info.IsStream = false
+	response := w.aggregator.Response(usage)
+	if response.Model == "" {
+		response.Model = w.info.UpstreamModelName
+	}
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		common.SysError("error marshalling aggregated response: " + err.Error())
+		return
+	}
+	for k, v := range w.header {
+		if aggregateSkippedHeaders[k] {
+			continue
+		}
+		w.ResponseWriter.Header()[k] = v
+	}
+	w.ResponseWriter.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(jsonData)
+}
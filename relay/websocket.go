@@ -2,12 +2,14 @@ package relay
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"net/http"
 	"one-api/common"
 	"one-api/dto"
+	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
 	"one-api/service"
 	"one-api/setting"
@@ -41,7 +43,7 @@ func WssHelper(c *gin.Context, ws *websocket.Conn) (openaiErr *dto.OpenAIErrorWi
 	}
 	//relayInfo.UpstreamModelName = textRequest.Model
 	modelPrice, getModelPriceSuccess := operation_setting.GetModelPrice(relayInfo.UpstreamModelName, false)
-	groupRatio := setting.GetGroupRatio(relayInfo.Group)
+	groupRatio := setting.GetGroupModelRatio(relayInfo.Group, relayInfo.OriginModelName)
 
 	var preConsumedQuota int
 	var ratio float64
@@ -98,7 +100,13 @@ func WssHelper(c *gin.Context, ws *websocket.Conn) (openaiErr *dto.OpenAIErrorWi
 	statusCodeMappingStr := c.GetString("status_code_mapping")
 	resp, err := adaptor.DoRequest(c, relayInfo, nil)
 	if err != nil {
-		return service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		openaiErr = service.OpenAIErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		var handshakeErr *channel.WssHandshakeError
+		if errors.As(err, &handshakeErr) {
+			openaiErr.StatusCode = handshakeErr.StatusCode
+			service.ResetStatusCode(openaiErr, statusCodeMappingStr)
+		}
+		return openaiErr
 	}
 
 	if resp != nil {
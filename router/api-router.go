@@ -10,6 +10,7 @@ import (
 
 func SetApiRouter(router *gin.Engine) {
 	apiRouter := router.Group("/api")
+	apiRouter.Use(middleware.ApiCORS())
 	apiRouter.Use(gzip.Gzip(gzip.DefaultCompression))
 	apiRouter.Use(middleware.GlobalAPIRateLimit())
 	{
@@ -18,6 +19,9 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/status", controller.GetStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
 		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.GET("/status/db", middleware.RootAuth(), controller.GetDBMetrics)
+		apiRouter.GET("/provenance/public_key", controller.GetProvenancePublicKey)
+		apiRouter.POST("/provenance/verify", middleware.CriticalRateLimit(), controller.VerifyProvenance)
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/about", controller.GetAbout)
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
@@ -43,6 +47,7 @@ func SetApiRouter(router *gin.Engine) {
 			//userRoute.POST("/tokenlog", middleware.CriticalRateLimit(), controller.TokenLog)
 			userRoute.GET("/logout", controller.Logout)
 			userRoute.GET("/epay/notify", controller.EpayNotify)
+			userRoute.POST("/stripe/webhook", controller.StripeWebhook)
 			userRoute.GET("/groups", controller.GetUserGroups)
 
 			selfRoute := userRoute.Group("/")
@@ -58,8 +63,23 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/topup", controller.TopUp)
 				selfRoute.POST("/pay", controller.RequestEpay)
 				selfRoute.POST("/amount", controller.RequestAmount)
+				selfRoute.POST("/stripe/pay", controller.RequestStripePay)
+				selfRoute.POST("/stripe/amount", controller.RequestStripeAmount)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
+				selfRoute.GET("/quota_holds", controller.GetSelfQuotaHolds)
+				selfRoute.GET("/budget_alerts", controller.GetSelfBudgetAlerts)
+				selfRoute.PUT("/budget_alert", controller.UpdateSelfBudgetAlert)
+				selfRoute.DELETE("/budget_alert", controller.DeleteSelfBudgetAlert)
+				selfRoute.GET("/subscription/plans", controller.GetSubscriptionPlans)
+				selfRoute.GET("/subscription", controller.GetSelfSubscription)
+				selfRoute.POST("/subscription", controller.PurchaseSubscription)
+				selfRoute.GET("/self/postpaid_statements", controller.GetSelfPostpaidStatements)
+				selfRoute.GET("/self/export", middleware.CriticalRateLimit(), controller.ExportSelfData)
+				selfRoute.GET("/self/usage_statement", middleware.CriticalRateLimit(), controller.ExportSelfUsageStatement)
+				selfRoute.GET("/self/erasure", controller.GetSelfErasureRequest)
+				selfRoute.POST("/self/erasure", controller.RequestSelfErasure)
+				selfRoute.DELETE("/self/erasure", controller.CancelSelfErasure)
 			}
 
 			adminRoute := userRoute.Group("/")
@@ -68,10 +88,17 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.GET("/", controller.GetAllUsers)
 				adminRoute.GET("/search", controller.SearchUsers)
 				adminRoute.GET("/:id", controller.GetUser)
+				adminRoute.GET("/:id/subscription", controller.GetUserSubscription)
+				adminRoute.POST("/:id/subscription", controller.AssignUserSubscription)
+				adminRoute.DELETE("/:id/subscription", controller.CancelUserSubscription)
+				adminRoute.GET("/:id/postpaid_statements", controller.GetUserPostpaidStatements)
+				adminRoute.GET("/:id/usage_statement", controller.ExportUserUsageStatement)
 				adminRoute.POST("/", controller.CreateUser)
 				adminRoute.POST("/manage", controller.ManageUser)
 				adminRoute.PUT("/", controller.UpdateUser)
 				adminRoute.DELETE("/:id", controller.DeleteUser)
+				adminRoute.GET("/erasure", controller.GetUserErasureRequests)
+				adminRoute.POST("/erasure/:id", controller.ProcessUserErasureRequest)
 			}
 		}
 		optionRoute := apiRouter.Group("/option")
@@ -80,6 +107,12 @@ func SetApiRouter(router *gin.Engine) {
 			optionRoute.GET("/", controller.GetOptions)
 			optionRoute.PUT("/", controller.UpdateOption)
 			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
+			optionRoute.GET("/model_pricing", controller.GetModelPricing)
+			optionRoute.PUT("/model_pricing", controller.UpsertModelPricing)
+			optionRoute.DELETE("/model_pricing", controller.DeleteModelPricing)
+			optionRoute.POST("/model_pricing/migrate", controller.MigrateModelPricing)
+			optionRoute.GET("/model_pricing/sync", controller.GetModelPricingSyncResult)
+			optionRoute.POST("/model_pricing/sync", controller.SyncModelPricing)
 		}
 		channelRoute := apiRouter.Group("/channel")
 		channelRoute.Use(middleware.AdminAuth())
@@ -88,6 +121,7 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/search", controller.SearchChannels)
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
+			channelRoute.GET("/fingerprint/:tag", controller.GetChannelByFingerprint)
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
@@ -105,12 +139,28 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/fetch_models/:id", controller.FetchUpstreamModels)
 			channelRoute.POST("/fetch_models", controller.FetchModels)
 			channelRoute.POST("/batch/tag", controller.BatchSetChannelTag)
+			channelRoute.POST("/shadow/sync", middleware.RootAuth(), controller.SyncShadowChannels)
+		}
+		jobRoute := apiRouter.Group("/job")
+		jobRoute.Use(middleware.AdminAuth())
+		{
+			jobRoute.GET("/", controller.GetAdminJobs)
+			jobRoute.GET("/:id", controller.GetAdminJob)
+			jobRoute.POST("/:id/cancel", controller.CancelAdminJob)
+		}
+		quotaHoldRoute := apiRouter.Group("/quota_hold")
+		quotaHoldRoute.Use(middleware.AdminAuth())
+		{
+			quotaHoldRoute.GET("/", controller.GetQuotaHolds)
+			quotaHoldRoute.POST("/:id/release", controller.ReleaseQuotaHold)
 		}
 		tokenRoute := apiRouter.Group("/token")
 		tokenRoute.Use(middleware.UserAuth())
 		{
 			tokenRoute.GET("/", controller.GetAllTokens)
 			tokenRoute.GET("/search", controller.SearchTokens)
+			tokenRoute.GET("/rate_limit_tiers", controller.GetTokenRateLimitTiers)
+			tokenRoute.POST("/:id/rate_limit_tier", controller.PurchaseTokenRateLimitTier)
 			tokenRoute.GET("/:id", controller.GetToken)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
@@ -138,8 +188,9 @@ func SetApiRouter(router *gin.Engine) {
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
+		dataRoute.GET("/histogram", middleware.AdminAuth(), controller.GetUsageHistograms)
 
-		logRoute.Use(middleware.CORS())
+		logRoute.Use(middleware.RelayCORS())
 		{
 			logRoute.GET("/token", controller.GetLogByKey)
 
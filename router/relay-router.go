@@ -1,6 +1,7 @@
 package router
 
 import (
+	"net/http"
 	"one-api/controller"
 	"one-api/middleware"
 	"one-api/relay"
@@ -9,7 +10,7 @@ import (
 )
 
 func SetRelayRouter(router *gin.Engine) {
-	router.Use(middleware.CORS())
+	router.Use(middleware.RelayCORS())
 	router.Use(middleware.DecompressRequestMiddleware())
 	// https://platform.openai.com/docs/api-reference/introduction
 	modelsRouter := router.Group("/v1/models")
@@ -20,40 +21,56 @@ func SetRelayRouter(router *gin.Engine) {
 	}
 	playgroundRouter := router.Group("/pg")
 	playgroundRouter.Use(middleware.UserAuth())
+	playgroundRouter.Use(middleware.SubscriptionRateLimit())
 	{
 		playgroundRouter.POST("/chat/completions", controller.Playground)
 	}
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
+	relayV1Router.Use(middleware.SubscriptionRateLimit())
 	{
 		// WebSocket 路由
 		wsRouter := relayV1Router.Group("")
 		wsRouter.Use(middleware.Distribute())
 		wsRouter.GET("/realtime", controller.WssRelay)
 	}
+	{
+		// 批量请求的文件和任务查询，不需要选择渠道
+		batchRouter := relayV1Router.Group("")
+		batchRouter.GET("/files", controller.ListFiles)
+		batchRouter.POST("/files", controller.UploadFile)
+		batchRouter.DELETE("/files/:id", controller.DeleteFile)
+		batchRouter.GET("/files/:id", controller.RetrieveFile)
+		batchRouter.GET("/files/:id/content", controller.RetrieveFileContent)
+		batchRouter.GET("/batches", controller.ListBatches)
+		batchRouter.GET("/batches/:id", controller.RetrieveBatch)
+		batchRouter.POST("/batches/:id/cancel", controller.CancelBatch)
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
 		httpRouter.Use(middleware.Distribute())
+		httpRouter.Use(middleware.Provenance())
 		httpRouter.POST("/messages", controller.RelayClaude)
 		httpRouter.POST("/completions", controller.Relay)
 		httpRouter.POST("/chat/completions", controller.Relay)
 		httpRouter.POST("/edits", controller.Relay)
 		httpRouter.POST("/images/generations", controller.Relay)
 		httpRouter.POST("/images/edits", controller.Relay)
-		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
+		httpRouter.POST("/images/variations", controller.Relay)
 		httpRouter.POST("/embeddings", controller.Relay)
 		httpRouter.POST("/engines/:model/embeddings", controller.Relay)
 		httpRouter.POST("/audio/transcriptions", controller.Relay)
 		httpRouter.POST("/audio/translations", controller.Relay)
 		httpRouter.POST("/audio/speech", controller.Relay)
 		httpRouter.POST("/responses", controller.Relay)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
+		httpRouter.POST("/batches", controller.RelayBatch)
+		assistantMethods := []string{http.MethodGet, http.MethodPost, http.MethodDelete}
+		httpRouter.Match(assistantMethods, "/assistants", controller.RelayAssistants)
+		httpRouter.Match(assistantMethods, "/assistants/*path", controller.RelayAssistants)
+		httpRouter.Match(assistantMethods, "/threads", controller.RelayAssistants)
+		httpRouter.Match(assistantMethods, "/threads/*path", controller.RelayAssistants)
 		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
 		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
@@ -72,7 +89,7 @@ func SetRelayRouter(router *gin.Engine) {
 	//relayMjRouter.Use()
 
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.TokenAuth(), middleware.SubscriptionRateLimit(), middleware.Distribute())
 	{
 		relaySunoRouter.POST("/submit/:action", controller.RelayTask)
 		relaySunoRouter.POST("/fetch", controller.RelayTask)
@@ -83,7 +100,7 @@ func SetRelayRouter(router *gin.Engine) {
 
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", relay.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.TokenAuth(), middleware.SubscriptionRateLimit(), middleware.Distribute())
 	{
 		relayMjRouter.POST("/submit/action", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", controller.RelayMidjourney)
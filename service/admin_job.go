@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"sync"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// 耗时较长的管理操作（测试所有渠道、更新所有渠道余额等）以后台任务的方式执行，
+// 接口立即返回任务，前端通过任务接口查询进度和结果，避免长时间阻塞的请求被代理超时断开
+// 任务记录保存在内存中，只保留最近的 maxAdminJobHistory 个
+
+const (
+	AdminJobTypeTestAllChannels   = "test_all_channels"
+	AdminJobTypeUpdateAllBalances = "update_all_balances"
+)
+
+const (
+	AdminJobStatusRunning   = "running"
+	AdminJobStatusSucceeded = "succeeded"
+	AdminJobStatusFailed    = "failed"
+	AdminJobStatusCanceled  = "canceled"
+)
+
+const maxAdminJobHistory = 100
+
+type AdminJobResult struct {
+	Id      int    `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+type AdminJob struct {
+	Id         string           `json:"id"`
+	Type       string           `json:"type"`
+	Status     string           `json:"status"`
+	Total      int              `json:"total"`
+	Done       int              `json:"done"`
+	Failed     int              `json:"failed"`
+	Message    string           `json:"message,omitempty"`
+	Results    []AdminJobResult `json:"results,omitempty"`
+	CreatedBy  int              `json:"created_by"`
+	CreatedAt  int64            `json:"created_at"`
+	FinishedAt int64            `json:"finished_at,omitempty"`
+
+	lock   sync.Mutex
+	cancel context.CancelFunc
+}
+
+var adminJobs []*AdminJob
+var adminJobsLock sync.Mutex
+
+// StartAdminJob 在后台执行 run，run 需要在处理每一项后调用 AddResult，并在 ctx 取消后尽快返回，
+// 返回值为任务创建时的副本
+func StartAdminJob(jobType string, userId int, total int, run func(ctx context.Context, job *AdminJob) error) *AdminJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &AdminJob{
+		Id:        fmt.Sprintf("job-%s", common.GetUUID()),
+		Type:      jobType,
+		Status:    AdminJobStatusRunning,
+		Total:     total,
+		CreatedBy: userId,
+		CreatedAt: common.GetTimestamp(),
+		cancel:    cancel,
+	}
+	adminJobsLock.Lock()
+	adminJobs = append(adminJobs, job)
+	if len(adminJobs) > maxAdminJobHistory {
+		adminJobs = adminJobs[len(adminJobs)-maxAdminJobHistory:]
+	}
+	adminJobsLock.Unlock()
+	gopool.Go(func() {
+		defer cancel()
+		err := run(ctx, job)
+		job.lock.Lock()
+		defer job.lock.Unlock()
+		job.FinishedAt = common.GetTimestamp()
+		if job.Status == AdminJobStatusCanceled {
+			return
+		}
+		if err != nil {
+			job.Status = AdminJobStatusFailed
+			job.Message = err.Error()
+			common.SysError(fmt.Sprintf("admin job %s (%s) failed: %s", job.Id, job.Type, err.Error()))
+			return
+		}
+		job.Status = AdminJobStatusSucceeded
+	})
+	return job.snapshot(false)
+}
+
+func (job *AdminJob) AddResult(result AdminJobResult) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.Done++
+	if !result.Success {
+		job.Failed++
+	}
+	job.Results = append(job.Results, result)
+}
+
+// snapshot 返回任务的副本，withResults 为 false 时不返回各项结果
+func (job *AdminJob) snapshot(withResults bool) *AdminJob {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	copied := &AdminJob{
+		Id:         job.Id,
+		Type:       job.Type,
+		Status:     job.Status,
+		Total:      job.Total,
+		Done:       job.Done,
+		Failed:     job.Failed,
+		Message:    job.Message,
+		CreatedBy:  job.CreatedBy,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if withResults {
+		copied.Results = make([]AdminJobResult, len(job.Results))
+		copy(copied.Results, job.Results)
+	}
+	return copied
+}
+
+func findAdminJob(id string) *AdminJob {
+	adminJobsLock.Lock()
+	defer adminJobsLock.Unlock()
+	for _, job := range adminJobs {
+		if job.Id == id {
+			return job
+		}
+	}
+	return nil
+}
+
+func GetAdminJob(id string) (*AdminJob, error) {
+	job := findAdminJob(id)
+	if job == nil {
+		return nil, errors.New("任务不存在")
+	}
+	return job.snapshot(true), nil
+}
+
+// GetAdminJobs 按创建时间倒序返回任务列表，jobType 为空时返回所有类型
+func GetAdminJobs(jobType string) []*AdminJob {
+	adminJobsLock.Lock()
+	defer adminJobsLock.Unlock()
+	jobs := make([]*AdminJob, 0, len(adminJobs))
+	for i := len(adminJobs) - 1; i >= 0; i-- {
+		if jobType != "" && adminJobs[i].Type != jobType {
+			continue
+		}
+		jobs = append(jobs, adminJobs[i].snapshot(false))
+	}
+	return jobs
+}
+
+func CancelAdminJob(id string) error {
+	job := findAdminJob(id)
+	if job == nil {
+		return errors.New("任务不存在")
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	if job.Status != AdminJobStatusRunning {
+		return errors.New("任务已结束")
+	}
+	job.Status = AdminJobStatusCanceled
+	job.cancel()
+	return nil
+}
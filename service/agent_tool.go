@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/dto"
+	"one-api/setting/model_setting"
+	"strings"
+	"time"
+)
+
+// agentToolMaxResultSize 工具结果超过该长度时截断，避免占满模型上下文
+const agentToolMaxResultSize = 16 * 1024
+
+func AgentToolDefinition(tool *model_setting.AgentTool) dto.ToolCallRequest {
+	parameters := tool.Parameters
+	if parameters == nil {
+		parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return dto.ToolCallRequest{
+		Type: "function",
+		Function: dto.FunctionRequest{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// ExecuteAgentTool 执行工具调用，调用失败时返回错误描述作为工具结果，由模型决定如何处理。
+// ctx 为客户端请求的上下文，客户端断开时取消工具调用
+func ExecuteAgentTool(ctx context.Context, tool *model_setting.AgentTool, arguments string) string {
+	result, err := doAgentToolRequest(ctx, tool, arguments)
+	if err != nil {
+		return fmt.Sprintf("tool %s failed: %s", tool.Name, err.Error())
+	}
+	if len(result) > agentToolMaxResultSize {
+		result = strings.ToValidUTF8(result[:agentToolMaxResultSize], "")
+	}
+	return result
+}
+
+func doAgentToolRequest(ctx context.Context, tool *model_setting.AgentTool, arguments string) (string, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	timeout := tool.Timeout
+	if timeout <= 0 {
+		timeout = 10
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	method := strings.ToUpper(tool.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	var req *http.Request
+	var err error
+	if method == http.MethodGet {
+		var params map[string]any
+		if err = json.Unmarshal([]byte(arguments), &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		requestURL, err := url.Parse(tool.URL)
+		if err != nil {
+			return "", err
+		}
+		query := requestURL.Query()
+		for key, value := range params {
+			query.Set(key, fmt.Sprintf("%v", value))
+		}
+		requestURL.RawQuery = query.Encode()
+		req, err = http.NewRequestWithContext(ctx, method, requestURL.String(), nil)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if !json.Valid([]byte(arguments)) {
+			return "", fmt.Errorf("invalid arguments: %s", arguments)
+		}
+		req, err = http.NewRequestWithContext(ctx, method, tool.URL, bytes.NewBufferString(arguments))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range tool.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, agentToolMaxResultSize+1))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("status code %d: %s", resp.StatusCode, string(body))
+	}
+	return string(body), nil
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"one-api/common"
+	"os"
 )
 
 func parseAudio(audioBase64 string, format string) (duration float64, err error) {
@@ -21,6 +28,18 @@ func parseAudio(audioBase64 string, format string) (duration float64, err error)
 	case "g711_ulaw", "g711_alaw":
 		samplesCount = len(audioData) // 8位 = 1字节每样本
 		sampleRate = 8000             // 8kHz
+	case "wav":
+		// 从 wav 文件头读取字节率，文件头不完整时按 pcm16 处理
+		if len(audioData) > 44 && string(audioData[:4]) == "RIFF" {
+			if byteRate := binary.LittleEndian.Uint32(audioData[28:32]); byteRate > 0 {
+				return float64(len(audioData)-44) / float64(byteRate), nil
+			}
+		}
+		samplesCount = len(audioData) / 2
+		sampleRate = 24000
+	case "mp3":
+		// 按 128kbps 估算
+		return float64(len(audioData)) / 16000, nil
 	default:
 		samplesCount = len(audioData) // 8位 = 1字节每样本
 		sampleRate = 8000             // 8kHz
@@ -29,3 +48,49 @@ func parseAudio(audioBase64 string, format string) (duration float64, err error)
 	duration = float64(samplesCount) / float64(sampleRate)
 	return duration, nil
 }
+
+// AudioFileTokens 按音频时长计算 token，1 分钟相当于 1k tokens
+func AudioFileTokens(seconds float64) int {
+	return int(math.Round(math.Ceil(seconds) / 60.0 * 1000))
+}
+
+// CountAudioFileToken 按音频时长计算 token
+func CountAudioFileToken(ctx context.Context, fileHeader *multipart.FileHeader) (int, error) {
+	duration, err := GetAudioFileDuration(ctx, fileHeader)
+	if err != nil {
+		return 0, err
+	}
+	return AudioFileTokens(duration), nil
+}
+
+// GetAudioFileDuration 返回上传音频的时长（秒），时长由 ffprobe 从文件的容器信息中读取；
+// 较大的上传文件已写入临时文件，直接读取，不再复制
+func GetAudioFileDuration(ctx context.Context, fileHeader *multipart.FileHeader) (float64, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	fileName := ""
+	if osFile, ok := file.(*os.File); ok {
+		fileName = osFile.Name()
+	} else {
+		tmpFile, err := os.CreateTemp("", "audio-*")
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(tmpFile.Name())
+		_, err = io.Copy(tmpFile, file)
+		closeErr := tmpFile.Close()
+		if err != nil {
+			return 0, err
+		}
+		if closeErr != nil {
+			return 0, closeErr
+		}
+		fileName = tmpFile.Name()
+	}
+
+	return common.GetAudioDuration(ctx, fileName)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	BatchEndpointChatCompletions = "/v1/chat/completions"
+	BatchOutputFilePrefix        = "batch_output_"
+	BatchErrorFilePrefix         = "batch_error_"
+	maxBatchRequests             = 100000
+)
+
+// BatchInput 解析后的批量请求，输入文件中的所有请求必须使用同一个模型
+type BatchInput struct {
+	Request   dto.BatchRequest
+	Model     string
+	CustomIds []string
+	Requests  []dto.GeneralOpenAIRequest
+	Bodies    []json.RawMessage
+}
+
+// GetBatchInput 解析批量请求及其输入文件，结果缓存在上下文中供分发和转发时复用
+func GetBatchInput(c *gin.Context) (*BatchInput, error) {
+	if cached, ok := c.Get("batch_input"); ok {
+		return cached.(*BatchInput), nil
+	}
+	var input BatchInput
+	err := common.UnmarshalBodyReusable(c, &input.Request)
+	if err != nil {
+		return nil, err
+	}
+	if input.Request.InputFileId == "" {
+		return nil, errors.New("input_file_id is required")
+	}
+	if input.Request.Endpoint != BatchEndpointChatCompletions {
+		return nil, fmt.Errorf("endpoint %s is not supported, only %s is supported", input.Request.Endpoint, BatchEndpointChatCompletions)
+	}
+	if input.Request.CompletionWindow != "" && input.Request.CompletionWindow != "24h" {
+		return nil, errors.New("completion_window must be 24h")
+	}
+	file, err := model.GetFileByFileId(c.GetInt("id"), input.Request.InputFileId, true)
+	if err != nil {
+		return nil, err
+	}
+	if file.Purpose != "batch" {
+		return nil, fmt.Errorf("file %s is not a batch input file", file.FileId)
+	}
+	customIds := make(map[string]bool)
+	for i, line := range bytes.Split(file.Content, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var inputLine dto.BatchInputLine
+		if err := json.Unmarshal(line, &inputLine); err != nil {
+			return nil, fmt.Errorf("line %d: invalid json: %s", i+1, err.Error())
+		}
+		if inputLine.CustomId == "" || customIds[inputLine.CustomId] {
+			return nil, fmt.Errorf("line %d: custom_id is empty or duplicated", i+1)
+		}
+		customIds[inputLine.CustomId] = true
+		if inputLine.Method != "" && !strings.EqualFold(inputLine.Method, "POST") {
+			return nil, fmt.Errorf("line %d: method must be POST", i+1)
+		}
+		if inputLine.Url != input.Request.Endpoint {
+			return nil, fmt.Errorf("line %d: url must be %s", i+1, input.Request.Endpoint)
+		}
+		var request dto.GeneralOpenAIRequest
+		if err := json.Unmarshal(inputLine.Body, &request); err != nil {
+			return nil, fmt.Errorf("line %d: invalid body: %s", i+1, err.Error())
+		}
+		if request.Model == "" {
+			return nil, fmt.Errorf("line %d: model is required", i+1)
+		}
+		if input.Model == "" {
+			input.Model = request.Model
+		} else if request.Model != input.Model {
+			return nil, fmt.Errorf("line %d: all requests in a batch must use the same model", i+1)
+		}
+		request.Stream = false
+		input.CustomIds = append(input.CustomIds, inputLine.CustomId)
+		input.Requests = append(input.Requests, request)
+		input.Bodies = append(input.Bodies, inputLine.Body)
+	}
+	if len(input.Requests) == 0 {
+		return nil, errors.New("input file is empty")
+	}
+	if len(input.Requests) > maxBatchRequests {
+		return nil, fmt.Errorf("a batch can contain at most %d requests", maxBatchRequests)
+	}
+	c.Set("batch_input", &input)
+	return &input, nil
+}
+
+// CreateGatewayBatch 没有开启批量请求的渠道时，把输入文件拆分保存到 batch_lines 表，由网关的执行器逐行转发，
+// 每一行按普通请求计费，不享受批量折扣
+func CreateGatewayBatch(c *gin.Context, input *BatchInput) (*model.Task, error) {
+	userId := c.GetInt("id")
+	userQuota, err := model.GetUserSpendableQuota(userId)
+	if err != nil {
+		return nil, err
+	}
+	if userQuota <= 0 {
+		return nil, errors.New("user quota is not enough")
+	}
+	now := common.GetTimestamp()
+	task := &model.Task{
+		TaskID:     "batch_" + common.GetUUID(),
+		Platform:   constant.TaskPlatformBatch,
+		UserId:     userId,
+		TokenId:    c.GetInt("token_id"),
+		Action:     constant.BatchActionGateway,
+		Status:     model.TaskStatusSubmitted,
+		SubmitTime: now,
+		Progress:   "0%",
+	}
+	task.Properties.Input = input.Request.InputFileId
+	task.SetData(dto.BatchTaskData{
+		RequestCounts: dto.BatchRequestCounts{Total: len(input.Requests)},
+		ExpiresAt:     now + 24*60*60,
+		TokenId:       c.GetInt("token_id"),
+		Group:         c.GetString("group"),
+		Model:         input.Model,
+	})
+	lines := make([]*model.BatchLine, 0, len(input.Bodies))
+	for i, body := range input.Bodies {
+		// 由网关等待完整响应，不使用流式输出
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, err
+		}
+		delete(fields, "stream")
+		delete(fields, "stream_options")
+		body, err = json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, &model.BatchLine{
+			BatchId:     task.TaskID,
+			LineIndex:   i,
+			CustomId:    input.CustomIds[i],
+			Body:        body,
+			Status:      model.BatchLineStatusPending,
+			UpdatedTime: now,
+		})
+	}
+	if err = model.InsertBatchLines(lines); err != nil {
+		_ = model.DeleteBatchLines(task.TaskID)
+		return nil, err
+	}
+	if err = task.Insert(); err != nil {
+		_ = model.DeleteBatchLines(task.TaskID)
+		return nil, err
+	}
+	model.RecordLog(userId, model.LogTypeSystem, fmt.Sprintf("提交批量请求 %s，共 %d 个请求，由网关逐个转发，按实际请求计费", task.TaskID, len(lines)))
+	return task, nil
+}
+
+func int64Ptr(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// StoreBatchOutputFiles 把批量任务的输出文件和错误文件保存为用户文件，任务数据中只保留文件 id
+func StoreBatchOutputFiles(task *model.Task, data *dto.BatchTaskData) error {
+	store := func(content string, kind string) (string, error) {
+		file := &model.File{
+			FileId:    "file-" + common.GetUUID(),
+			UserId:    task.UserId,
+			Purpose:   "batch_output",
+			Filename:  fmt.Sprintf("%s_%s.jsonl", task.TaskID, kind),
+			Bytes:     len(content),
+			Content:   []byte(content),
+			CreatedAt: common.GetTimestamp(),
+		}
+		if err := file.Insert(0); err != nil {
+			return "", err
+		}
+		return file.FileId, nil
+	}
+	if data.Output != "" {
+		fileId, err := store(data.Output, "output")
+		if err != nil {
+			return err
+		}
+		data.OutputFileId = fileId
+		data.Output = ""
+	}
+	if data.ErrorOutput != "" {
+		fileId, err := store(data.ErrorOutput, "error")
+		if err != nil {
+			return err
+		}
+		data.ErrorFileId = fileId
+		data.ErrorOutput = ""
+	}
+	return nil
+}
+
+// BatchTask2Dto 将批量任务转换为 OpenAI 的 batch 对象，转换后的结果文件通过文件 id 前缀关联到任务
+func BatchTask2Dto(task *model.Task) *dto.Batch {
+	var data dto.BatchTaskData
+	if len(task.Data) > 0 {
+		_ = task.GetData(&data)
+	}
+	batch := &dto.Batch{
+		Id:               task.TaskID,
+		Object:           "batch",
+		Endpoint:         BatchEndpointChatCompletions,
+		InputFileId:      task.Properties.Input,
+		CompletionWindow: "24h",
+		CreatedAt:        task.SubmitTime,
+		InProgressAt:     int64Ptr(task.StartTime),
+		ExpiresAt:        int64Ptr(data.ExpiresAt),
+		CancellingAt:     int64Ptr(data.CancelingAt),
+		RequestCounts:    data.RequestCounts,
+	}
+	if data.OutputFileId != "" {
+		batch.OutputFileId = &data.OutputFileId
+	} else if data.Output != "" {
+		outputFileId := BatchOutputFilePrefix + task.TaskID
+		batch.OutputFileId = &outputFileId
+	}
+	if data.ErrorFileId != "" {
+		batch.ErrorFileId = &data.ErrorFileId
+	} else if data.ErrorOutput != "" {
+		errorFileId := BatchErrorFilePrefix + task.TaskID
+		batch.ErrorFileId = &errorFileId
+	}
+	switch {
+	case task.Status.IsFinal() && data.Canceled:
+		batch.Status = "cancelled"
+		batch.CancelledAt = int64Ptr(task.FinishTime)
+	case task.Status == model.TaskStatusSuccess:
+		batch.Status = "completed"
+		batch.CompletedAt = int64Ptr(task.FinishTime)
+	case task.Status == model.TaskStatusFailure:
+		batch.Status = "failed"
+		batch.FailedAt = int64Ptr(task.FinishTime)
+		batch.Errors = map[string]any{
+			"object": "list",
+			"data": []dto.BatchOutputError{
+				{Code: "batch_failed", Message: task.FailReason},
+			},
+		}
+	case data.CancelingAt != 0:
+		batch.Status = "cancelling"
+	default:
+		batch.Status = "in_progress"
+	}
+	return batch
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	"time"
+)
+
+// AutomaticallyCheckBudgetAlerts 定期检查预算预警，已用额度比例达到阈值时通过用户设置的通知方式发送通知
+func AutomaticallyCheckBudgetAlerts() {
+	if constant.BudgetAlertCheckIntervalMinutes <= 0 {
+		return
+	}
+	for {
+		time.Sleep(time.Duration(constant.BudgetAlertCheckIntervalMinutes) * time.Minute)
+		alerts, err := model.GetAllBudgetAlerts()
+		if err != nil {
+			common.SysError("failed to get budget alerts: " + err.Error())
+			continue
+		}
+		now := time.Now()
+		for _, alert := range alerts {
+			if err := checkBudgetAlert(alert, now); err != nil {
+				common.SysError(fmt.Sprintf("failed to check budget alert %d: %s", alert.Id, err.Error()))
+			}
+		}
+	}
+}
+
+func checkBudgetAlert(alert *model.BudgetAlert, now time.Time) error {
+	user, err := model.GetUserById(alert.UserId, false)
+	if err != nil {
+		return err
+	}
+	target := "您的账户"
+	remainQuota := user.Quota
+	if alert.TokenId != 0 {
+		token, err := model.GetTokenByIds(alert.TokenId, alert.UserId)
+		if err != nil {
+			return err
+		}
+		// 无限额度的令牌没有预算
+		if token.UnlimitedQuota {
+			return nil
+		}
+		target = fmt.Sprintf("令牌 %s ", token.Name)
+		remainQuota = token.RemainQuota
+	}
+	// 汇总表按整点统计，时区不是整小时偏移时周期开始时间向前取整到整点
+	periodStart := alert.PeriodStart(now).Unix()
+	periodStart -= periodStart % model.UsageRollupHourlyPeriod
+	rollups, err := model.SumUsageRollup(model.UsageRollupHourlyPeriod, model.UsageRollupFilter{UserId: alert.UserId, TokenId: alert.TokenId}, periodStart, 0)
+	if err != nil {
+		return err
+	}
+	usedQuota := 0
+	for _, rollup := range rollups {
+		usedQuota += int(rollup.Quota)
+	}
+	if usedQuota <= 0 {
+		return nil
+	}
+	totalQuota := usedQuota + remainQuota
+	if totalQuota <= 0 {
+		return nil
+	}
+	percent := usedQuota * 100 / totalQuota
+
+	period := alert.CurrentPeriod(now)
+	notified := 0
+	if alert.NotifiedPeriod == period {
+		notified = alert.NotifiedThreshold
+	}
+	reached := 0
+	for _, threshold := range alert.GetThresholds() {
+		if percent >= threshold {
+			reached = threshold
+		}
+	}
+	if reached <= notified {
+		return nil
+	}
+
+	title := fmt.Sprintf("额度已使用 %d%%", reached)
+	content := "{{value}}本周期已使用预算的 {{value}}%，本周期已使用 {{value}}，剩余 {{value}}。"
+	err = NotifyUser(user.Id, user.Email, user.GetSetting(), dto.NewNotify(dto.NotifyTypeBudgetAlert, title, content,
+		[]interface{}{target, percent, common.FormatQuota(usedQuota), common.FormatQuota(remainQuota)}))
+	if err != nil {
+		return err
+	}
+	return alert.UpdateNotified(period, reached)
+}
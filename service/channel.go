@@ -33,6 +33,29 @@ func EnableChannel(channelId int, channelName string) {
 	}
 }
 
+// PauseChannelForProviderIncident 供应商状态页报告重大故障时暂停渠道，并记录供应商以便故障恢复后自动启用
+func PauseChannelForProviderIncident(channelId int, channelName string, provider string, reason string) {
+	success := model.UpdateChannelStatusById(channelId, common.ChannelStatusAutoDisabled, reason)
+	if !success {
+		return
+	}
+	err := model.SetChannelProviderIncident(channelId, provider)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to mark channel #%d paused by provider incident: %s", channelId, err.Error()))
+	}
+	subject := fmt.Sprintf("通道「%s」（#%d）因供应商故障已被暂停", channelName, channelId)
+	content := fmt.Sprintf("通道「%s」（#%d）因供应商故障已被暂停，原因：%s", channelName, channelId, reason)
+	NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusAutoDisabled), subject, content)
+}
+
+func ResumeChannelAfterProviderIncident(channelId int, channelName string) {
+	EnableChannel(channelId, channelName)
+	err := model.SetChannelProviderIncident(channelId, "")
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to clear provider incident of channel #%d: %s", channelId, err.Error()))
+	}
+}
+
 func ShouldDisableChannel(channelType int, err *dto.OpenAIErrorWithStatusCode) bool {
 	if !common.AutomaticDisableChannelEnabled {
 		return false
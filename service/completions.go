@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"one-api/dto"
+)
+
+// 只提供对话接口的上游处理 /v1/completions 请求时，prompt 转换为对话消息，suffix 写入系统提示，
+// 响应和流式数据块再转换回 text_completion 格式
+
+const completionsInstruction = "Continue the text provided by the user. Output only the continuation, starting exactly where the text ends, without repeating the text or adding any explanation."
+
+// completionsPromptString 只支持单个文本 prompt，token 数组和多个 prompt 无法转换为对话
+func completionsPromptString(prompt any) (string, error) {
+	switch v := prompt.(type) {
+	case string:
+		return v, nil
+	case []any:
+		if len(v) == 1 {
+			if s, ok := v[0].(string); ok {
+				return s, nil
+			}
+		}
+		return "", errors.New("only a single text prompt is supported by this channel")
+	}
+	return "", fmt.Errorf("unsupported prompt type %T", prompt)
+}
+
+// CompletionsToChatRequest 把 completions 请求转换为对话请求，返回原始 prompt 用于 echo
+func CompletionsToChatRequest(request *dto.GeneralOpenAIRequest) (string, error) {
+	prompt, err := completionsPromptString(request.Prompt)
+	if err != nil {
+		return "", err
+	}
+	instruction := completionsInstruction
+	if suffix, _ := request.Suffix.(string); suffix != "" {
+		instruction += " The continuation will be followed by the text below, so it must connect naturally to it:\n" + suffix
+	}
+	system := dto.Message{Role: "system"}
+	system.SetStringContent(instruction)
+	user := dto.Message{Role: "user"}
+	user.SetStringContent(prompt)
+	request.Messages = []dto.Message{system, user}
+	request.Prompt = nil
+	request.Suffix = nil
+	request.Echo = false
+	return prompt, nil
+}
+
+// ResponseChat2Completions 转换非流式响应，echo 不为空时拼接在每个回复之前
+func ResponseChat2Completions(response *dto.OpenAITextResponse, echo string) *dto.CompletionsResponse {
+	completionsResponse := &dto.CompletionsResponse{
+		Id:      response.Id,
+		Object:  "text_completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]dto.CompletionsResponseChoice, 0, len(response.Choices)),
+		Usage:   &response.Usage,
+	}
+	for _, choice := range response.Choices {
+		finishReason := choice.FinishReason
+		completionsResponse.Choices = append(completionsResponse.Choices, dto.CompletionsResponseChoice{
+			Text:         echo + choice.Message.StringContent(),
+			Index:        choice.Index,
+			FinishReason: &finishReason,
+		})
+	}
+	return completionsResponse
+}
+
+// StreamResponseChat2Completions 转换流式数据块，思考内容和工具调用没有对应格式，直接丢弃
+func StreamResponseChat2Completions(response *dto.ChatCompletionsStreamResponse) *dto.CompletionsResponse {
+	completionsResponse := &dto.CompletionsResponse{
+		Id:      response.Id,
+		Object:  "text_completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Choices: make([]dto.CompletionsResponseChoice, 0, len(response.Choices)),
+		Usage:   response.Usage,
+	}
+	for _, choice := range response.Choices {
+		completionsResponse.Choices = append(completionsResponse.Choices, dto.CompletionsResponseChoice{
+			Text:         choice.Delta.GetContentString(),
+			Index:        choice.Index,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return completionsResponse
+}
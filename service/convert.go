@@ -4,17 +4,44 @@ import (
 	"encoding/json"
 	"fmt"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	"strings"
 )
 
+// claudeToolChoiceToOpenAI 把 Claude 的 tool_choice（auto、any、tool、none）转换为 OpenAI 的 tool_choice 和 parallel_tool_calls
+func claudeToolChoiceToOpenAI(toolChoice any) (any, *bool) {
+	choice, ok := toolChoice.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	var parallelToolCalls *bool
+	if disable, _ := choice["disable_parallel_tool_use"].(bool); disable {
+		parallelToolCalls = common.GetPointer(false)
+	}
+	switch choice["type"] {
+	case "auto":
+		return dto.ToolChoiceModeAuto, parallelToolCalls
+	case "any":
+		return dto.ToolChoiceModeRequired, parallelToolCalls
+	case "none":
+		return dto.ToolChoiceModeNone, nil
+	case "tool":
+		if name, _ := choice["name"].(string); name != "" {
+			return map[string]any{"type": "function", "function": map[string]any{"name": name}}, parallelToolCalls
+		}
+	}
+	return nil, parallelToolCalls
+}
+
 func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.RelayInfo) (*dto.GeneralOpenAIRequest, error) {
 	openAIRequest := dto.GeneralOpenAIRequest{
 		Model:       claudeRequest.Model,
 		MaxTokens:   claudeRequest.MaxTokens,
 		Temperature: claudeRequest.Temperature,
 		TopP:        claudeRequest.TopP,
+		TopK:        dto.FlexibleInt(claudeRequest.TopK),
 		Stream:      claudeRequest.Stream,
 	}
 
@@ -26,11 +53,7 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 	}
 
 	// Convert stop sequences
-	if len(claudeRequest.StopSequences) == 1 {
-		openAIRequest.Stop = claudeRequest.StopSequences[0]
-	} else if len(claudeRequest.StopSequences) > 1 {
-		openAIRequest.Stop = claudeRequest.StopSequences
-	}
+	openAIRequest.Stop = claudeRequest.StopSequences
 
 	// Convert tools
 	tools, _ := common.Any2Type[[]dto.Tool](claudeRequest.Tools)
@@ -47,6 +70,9 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 		openAITools = append(openAITools, openAITool)
 	}
 	openAIRequest.Tools = openAITools
+	if len(openAITools) > 0 {
+		openAIRequest.ToolChoice, openAIRequest.ParallelTooCalls = claudeToolChoiceToOpenAI(claudeRequest.ToolChoice)
+	}
 
 	// Convert messages
 	openAIMessages := make([]dto.Message, 0)
@@ -89,27 +115,13 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 			if err != nil {
 				return nil, err
 			}
-			contents := content
 			var toolCalls []dto.ToolCallRequest
-			mediaMessages := make([]dto.MediaContent, 0, len(contents))
+			mediaMessages := make([]dto.MediaContent, 0, len(content))
+			// tool_result 转换为 tool 消息，需要紧跟在 assistant 的 tool_calls 之后
+			var toolMessages []dto.Message
 
-			for _, mediaMsg := range contents {
+			for _, mediaMsg := range content {
 				switch mediaMsg.Type {
-				case "text":
-					message := dto.MediaContent{
-						Type: "text",
-						Text: mediaMsg.GetText(),
-					}
-					mediaMessages = append(mediaMessages, message)
-				case "image":
-					// Handle image conversion (base64 to URL or keep as is)
-					imageData := fmt.Sprintf("data:%s;base64,%s", mediaMsg.Source.MediaType, mediaMsg.Source.Data)
-					//textContent += fmt.Sprintf("[Image: %s]", imageData)
-					mediaMessage := dto.MediaContent{
-						Type:     "image_url",
-						ImageUrl: &dto.MessageImageUrl{Url: imageData},
-					}
-					mediaMessages = append(mediaMessages, mediaMessage)
 				case "tool_use":
 					toolCall := dto.ToolCallRequest{
 						ID:   mediaMsg.Id,
@@ -121,29 +133,42 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 					}
 					toolCalls = append(toolCalls, toolCall)
 				case "tool_result":
-					// Add tool result as a separate message
 					oaiToolMessage := dto.Message{
 						Role:       "tool",
-						Name:       &mediaMsg.Name,
 						ToolCallId: mediaMsg.ToolUseId,
 					}
-					//oaiToolMessage.SetStringContent(*mediaMsg.GetMediaContent().Text)
 					if mediaMsg.IsStringContent() {
 						oaiToolMessage.SetStringContent(mediaMsg.GetStringContent())
 					} else {
-						mediaContents := mediaMsg.ParseMediaContent()
-						encodeJson, _ := common.EncodeJson(mediaContents)
-						oaiToolMessage.SetStringContent(string(encodeJson))
+						// OpenAI 的 tool 消息只支持文本，结果中的图片等内容放到随后的 user 消息中
+						toolResult := ""
+						for _, resultContent := range mediaMsg.ParseMediaContent() {
+							if resultContent.Type == "text" {
+								toolResult += resultContent.GetText()
+							} else if mediaContent, ok := claudeMediaToOpenAI(resultContent); ok {
+								mediaMessages = append(mediaMessages, mediaContent)
+							}
+						}
+						oaiToolMessage.SetStringContent(toolResult)
+					}
+					toolMessages = append(toolMessages, oaiToolMessage)
+				default:
+					if mediaContent, ok := claudeMediaToOpenAI(mediaMsg); ok {
+						mediaMessages = append(mediaMessages, mediaContent)
 					}
-					openAIMessages = append(openAIMessages, oaiToolMessage)
 				}
 			}
+			openAIMessages = append(openAIMessages, toolMessages...)
 
 			if len(toolCalls) > 0 {
 				openAIMessage.SetToolCalls(toolCalls)
-			}
-
-			if len(mediaMessages) > 0 && len(toolCalls) == 0 {
+				// 调用工具的 assistant 消息只保留文本内容
+				text := ""
+				for _, mediaContent := range mediaMessages {
+					text += mediaContent.Text
+				}
+				openAIMessage.SetStringContent(text)
+			} else if len(mediaMessages) > 0 {
 				openAIMessage.SetMediaContent(mediaMessages)
 			}
 		}
@@ -157,6 +182,43 @@ func ClaudeToOpenAIRequest(claudeRequest dto.ClaudeRequest, info *relaycommon.Re
 	return &openAIRequest, nil
 }
 
+// claudeMediaToOpenAI 转换 Claude 的文本、图片和文档块，其他类型（thinking 等）返回 false
+func claudeMediaToOpenAI(mediaMsg dto.ClaudeMediaMessage) (dto.MediaContent, bool) {
+	switch mediaMsg.Type {
+	case "text":
+		return dto.MediaContent{Type: dto.ContentTypeText, Text: mediaMsg.GetText()}, true
+	case "image":
+		if mediaMsg.Source == nil {
+			return dto.MediaContent{}, false
+		}
+		imageUrl := mediaMsg.Source.Url
+		if mediaMsg.Source.Type == "base64" {
+			imageUrl = fmt.Sprintf("data:%s;base64,%s", mediaMsg.Source.MediaType, mediaMsg.Source.Data)
+		}
+		return dto.MediaContent{
+			Type:     dto.ContentTypeImageURL,
+			ImageUrl: &dto.MessageImageUrl{Url: imageUrl, Detail: "auto"},
+		}, true
+	case "document":
+		if mediaMsg.Source == nil {
+			return dto.MediaContent{}, false
+		}
+		switch mediaMsg.Source.Type {
+		case "text":
+			return dto.MediaContent{Type: dto.ContentTypeText, Text: fmt.Sprintf("%v", mediaMsg.Source.Data)}, true
+		case "base64":
+			return dto.MediaContent{
+				Type: dto.ContentTypeFile,
+				File: &dto.MessageFile{
+					FileName: "document.pdf",
+					FileData: fmt.Sprintf("data:%s;base64,%s", mediaMsg.Source.MediaType, mediaMsg.Source.Data),
+				},
+			}, true
+		}
+	}
+	return dto.MediaContent{}, false
+}
+
 func OpenAIErrorToClaudeError(openAIError *dto.OpenAIErrorWithStatusCode) *dto.ClaudeErrorWithStatusCode {
 	claudeError := dto.ClaudeError{
 		Type:    "new_api_error",
@@ -179,6 +241,53 @@ func ClaudeErrorToOpenAIError(claudeError *dto.ClaudeErrorWithStatusCode) *dto.O
 	}
 }
 
+// startContentBlock 结束当前的内容块并开始新的内容块
+func startContentBlock(info *relaycommon.RelayInfo, messageType string, block *dto.ClaudeMediaMessage) []*dto.ClaudeResponse {
+	var claudeResponses []*dto.ClaudeResponse
+	if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeNone {
+		claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
+		info.ClaudeConvertInfo.Index++
+	}
+	info.ClaudeConvertInfo.LastMessagesType = messageType
+	return append(claudeResponses, &dto.ClaudeResponse{
+		Index:        common.GetPointer[int](info.ClaudeConvertInfo.Index),
+		Type:         "content_block_start",
+		ContentBlock: block,
+	})
+}
+
+// toolCallBlocks 把工具调用增量转换为 tool_use 块，tool_calls 的 index 变化时结束上一个块并开始新块，
+// 并行调用的多个工具各自对应一个块
+func toolCallBlocks(toolCalls []dto.ToolCallResponse, info *relaycommon.RelayInfo) []*dto.ClaudeResponse {
+	var claudeResponses []*dto.ClaudeResponse
+	for _, toolCall := range toolCalls {
+		toolCallIndex := 0
+		if toolCall.Index != nil {
+			toolCallIndex = *toolCall.Index
+		}
+		if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeTools || toolCallIndex != info.ClaudeConvertInfo.ToolCallIndex {
+			claudeResponses = append(claudeResponses, startContentBlock(info, relaycommon.LastMessageTypeTools, &dto.ClaudeMediaMessage{
+				Id:    toolCall.ID,
+				Type:  "tool_use",
+				Name:  toolCall.Function.Name,
+				Input: map[string]interface{}{},
+			})...)
+			info.ClaudeConvertInfo.ToolCallIndex = toolCallIndex
+		}
+		if toolCall.Function.Arguments != "" {
+			claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+				Index: common.GetPointer[int](info.ClaudeConvertInfo.Index),
+				Type:  "content_block_delta",
+				Delta: &dto.ClaudeMediaMessage{
+					Type:        "input_json_delta",
+					PartialJson: common.GetPointer[string](toolCall.Function.Arguments),
+				},
+			})
+		}
+	}
+	return claudeResponses
+}
+
 func generateStopBlock(index int) *dto.ClaudeResponse {
 	return &dto.ClaudeResponse{
 		Type:  "content_block_stop",
@@ -186,6 +295,24 @@ func generateStopBlock(index int) *dto.ClaudeResponse {
 	}
 }
 
+// claudeUsageFromOpenAI OpenAI 的 prompt_tokens 包含缓存的 token，Claude 的 input_tokens 不包含
+func claudeUsageFromOpenAI(usage *dto.Usage) *dto.ClaudeUsage {
+	cacheRead := usage.PromptTokensDetails.CachedTokens
+	cacheCreation := usage.PromptTokensDetails.CachedCreationTokens
+	inputTokens := usage.PromptTokens - cacheRead - cacheCreation
+	if inputTokens < 0 {
+		inputTokens = 0
+	}
+	return &dto.ClaudeUsage{
+		InputTokens:              inputTokens,
+		CacheReadInputTokens:     cacheRead,
+		CacheCreationInputTokens: cacheCreation,
+		OutputTokens:             usage.CompletionTokens,
+	}
+}
+
+// StreamResponseOpenAI2Claude 把 OpenAI 的流式响应块转换为 Claude 的事件，第一个块前发送 message_start，
+// info.Done 为 true 时结束最后的内容块并发送 message_delta（stop_reason 和用量）和 message_stop
 func StreamResponseOpenAI2Claude(openAIResponse *dto.ChatCompletionsStreamResponse, info *relaycommon.RelayInfo) []*dto.ClaudeResponse {
 	var claudeResponses []*dto.ClaudeResponse
 	if info.SendResponseCount == 1 {
@@ -204,143 +331,70 @@ func StreamResponseOpenAI2Claude(openAIResponse *dto.ChatCompletionsStreamRespon
 			Type:    "message_start",
 			Message: msg,
 		})
-		claudeResponses = append(claudeResponses)
-		//claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-		//	Type: "ping",
-		//})
-		if openAIResponse.IsToolCall() {
-			resp := &dto.ClaudeResponse{
-				Type: "content_block_start",
-				ContentBlock: &dto.ClaudeMediaMessage{
-					Id:   openAIResponse.GetFirstToolCall().ID,
-					Type: "tool_use",
-					Name: openAIResponse.GetFirstToolCall().Function.Name,
-				},
-			}
-			resp.SetIndex(0)
-			claudeResponses = append(claudeResponses, resp)
-		} else {
-			//resp := &dto.ClaudeResponse{
-			//	Type: "content_block_start",
-			//	ContentBlock: &dto.ClaudeMediaMessage{
-			//		Type: "text",
-			//		Text: common.GetPointer[string](""),
-			//	},
-			//}
-			//resp.SetIndex(0)
-			//claudeResponses = append(claudeResponses, resp)
+	}
+
+	if info.Done {
+		if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeNone {
+			claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
 		}
-		return claudeResponses
+		usage := &dto.ClaudeUsage{}
+		if info.ClaudeConvertInfo.Usage != nil {
+			usage = claudeUsageFromOpenAI(info.ClaudeConvertInfo.Usage)
+		}
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Type:  "message_delta",
+			Usage: usage,
+			Delta: &dto.ClaudeMediaMessage{
+				StopReason: common.GetPointer[string](stopReasonOpenAI2Claude(info.FinishReason)),
+			},
+		})
+		return append(claudeResponses, &dto.ClaudeResponse{
+			Type: "message_stop",
+		})
 	}
 
 	if len(openAIResponse.Choices) == 0 {
-		// no choices
-		// TODO: handle this case
 		return claudeResponses
-	} else {
-		chosenChoice := openAIResponse.Choices[0]
-		if chosenChoice.FinishReason != nil && *chosenChoice.FinishReason != "" {
-			// should be done
-			info.FinishReason = *chosenChoice.FinishReason
-			return claudeResponses
+	}
+	chosenChoice := openAIResponse.Choices[0]
+	if reasoning := chosenChoice.Delta.GetReasoningContent(); reasoning != "" {
+		if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeThinking {
+			claudeResponses = append(claudeResponses, startContentBlock(info, relaycommon.LastMessageTypeThinking, &dto.ClaudeMediaMessage{
+				Type: "thinking",
+			})...)
 		}
-		if info.Done {
-			claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-			if info.ClaudeConvertInfo.Usage != nil {
-				claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-					Type: "message_delta",
-					Usage: &dto.ClaudeUsage{
-						InputTokens:  info.ClaudeConvertInfo.Usage.PromptTokens,
-						OutputTokens: info.ClaudeConvertInfo.Usage.CompletionTokens,
-					},
-					Delta: &dto.ClaudeMediaMessage{
-						StopReason: common.GetPointer[string](stopReasonOpenAI2Claude(info.FinishReason)),
-					},
-				})
-			}
-			claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-				Type: "message_stop",
-			})
-		} else {
-			var claudeResponse dto.ClaudeResponse
-			var isEmpty bool
-			claudeResponse.Type = "content_block_delta"
-			if len(chosenChoice.Delta.ToolCalls) > 0 {
-				if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeTools {
-					claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-					info.ClaudeConvertInfo.Index++
-					claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-						Index: &info.ClaudeConvertInfo.Index,
-						Type:  "content_block_start",
-						ContentBlock: &dto.ClaudeMediaMessage{
-							Id:    openAIResponse.GetFirstToolCall().ID,
-							Type:  "tool_use",
-							Name:  openAIResponse.GetFirstToolCall().Function.Name,
-							Input: map[string]interface{}{},
-						},
-					})
-				}
-				info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeTools
-				// tools delta
-				claudeResponse.Delta = &dto.ClaudeMediaMessage{
-					Type:        "input_json_delta",
-					PartialJson: &chosenChoice.Delta.ToolCalls[0].Function.Arguments,
-				}
-			} else {
-				reasoning := chosenChoice.Delta.GetReasoningContent()
-				textContent := chosenChoice.Delta.GetContentString()
-				if reasoning != "" || textContent != "" {
-					if reasoning != "" {
-						if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeThinking {
-							//info.ClaudeConvertInfo.Index++
-							claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-								Index: &info.ClaudeConvertInfo.Index,
-								Type:  "content_block_start",
-								ContentBlock: &dto.ClaudeMediaMessage{
-									Type:     "thinking",
-									Thinking: "",
-								},
-							})
-						}
-						info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeThinking
-						// text delta
-						claudeResponse.Delta = &dto.ClaudeMediaMessage{
-							Type:     "thinking_delta",
-							Thinking: reasoning,
-						}
-					} else {
-						if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeText {
-							if info.LastMessagesType == relaycommon.LastMessageTypeThinking || info.LastMessagesType == relaycommon.LastMessageTypeTools {
-								claudeResponses = append(claudeResponses, generateStopBlock(info.ClaudeConvertInfo.Index))
-								info.ClaudeConvertInfo.Index++
-							}
-							claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
-								Index: &info.ClaudeConvertInfo.Index,
-								Type:  "content_block_start",
-								ContentBlock: &dto.ClaudeMediaMessage{
-									Type: "text",
-									Text: common.GetPointer[string](""),
-								},
-							})
-						}
-						info.ClaudeConvertInfo.LastMessagesType = relaycommon.LastMessageTypeText
-						// text delta
-						claudeResponse.Delta = &dto.ClaudeMediaMessage{
-							Type: "text_delta",
-							Text: common.GetPointer[string](textContent),
-						}
-					}
-				} else {
-					isEmpty = true
-				}
-			}
-			claudeResponse.Index = &info.ClaudeConvertInfo.Index
-			if !isEmpty {
-				claudeResponses = append(claudeResponses, &claudeResponse)
-			}
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Index: common.GetPointer[int](info.ClaudeConvertInfo.Index),
+			Type:  "content_block_delta",
+			Delta: &dto.ClaudeMediaMessage{
+				Type:     "thinking_delta",
+				Thinking: reasoning,
+			},
+		})
+	}
+	if textContent := chosenChoice.Delta.GetContentString(); textContent != "" {
+		if info.ClaudeConvertInfo.LastMessagesType != relaycommon.LastMessageTypeText {
+			claudeResponses = append(claudeResponses, startContentBlock(info, relaycommon.LastMessageTypeText, &dto.ClaudeMediaMessage{
+				Type: "text",
+				Text: common.GetPointer[string](""),
+			})...)
 		}
+		claudeResponses = append(claudeResponses, &dto.ClaudeResponse{
+			Index: common.GetPointer[int](info.ClaudeConvertInfo.Index),
+			Type:  "content_block_delta",
+			Delta: &dto.ClaudeMediaMessage{
+				Type: "text_delta",
+				Text: common.GetPointer[string](textContent),
+			},
+		})
+	}
+	// 部分上游在结束块中返回最后的工具调用
+	if len(chosenChoice.Delta.ToolCalls) > 0 {
+		claudeResponses = append(claudeResponses, toolCallBlocks(chosenChoice.Delta.ToolCalls, info)...)
+	}
+	if chosenChoice.FinishReason != nil && *chosenChoice.FinishReason != "" {
+		info.FinishReason = *chosenChoice.FinishReason
 	}
-
 	return claudeResponses
 }
 
@@ -355,45 +409,57 @@ func ResponseOpenAI2Claude(openAIResponse *dto.OpenAITextResponse, info *relayco
 	}
 	for _, choice := range openAIResponse.Choices {
 		stopReason = stopReasonOpenAI2Claude(choice.FinishReason)
-		claudeContent := dto.ClaudeMediaMessage{}
-		if choice.FinishReason == "tool_calls" {
-			claudeContent.Type = "tool_use"
-			claudeContent.Id = choice.Message.ToolCallId
-			claudeContent.Name = choice.Message.ParseToolCalls()[0].Function.Name
-			var mapParams map[string]interface{}
-			if err := json.Unmarshal([]byte(choice.Message.ParseToolCalls()[0].Function.Arguments), &mapParams); err == nil {
+		toolCalls := choice.Message.ParseToolCalls()
+		if reasoning := choice.Message.ReasoningContent + choice.Message.Reasoning; reasoning != "" {
+			contents = append(contents, dto.ClaudeMediaMessage{Type: "thinking", Thinking: reasoning})
+		}
+		if text := choice.Message.StringContent(); text != "" || len(toolCalls) == 0 {
+			claudeContent := dto.ClaudeMediaMessage{Type: "text"}
+			claudeContent.SetText(text)
+			contents = append(contents, claudeContent)
+		}
+		// 并行调用的每个工具对应一个 tool_use 块
+		for _, toolCall := range toolCalls {
+			claudeContent := dto.ClaudeMediaMessage{
+				Type: "tool_use",
+				Id:   toolCall.ID,
+				Name: toolCall.Function.Name,
+			}
+			mapParams := make(map[string]interface{})
+			if toolCall.Function.Arguments == "" {
+				claudeContent.Input = mapParams
+			} else if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &mapParams); err == nil {
 				claudeContent.Input = mapParams
 			} else {
-				claudeContent.Input = choice.Message.ParseToolCalls()[0].Function.Arguments
+				claudeContent.Input = toolCall.Function.Arguments
 			}
-		} else {
-			claudeContent.Type = "text"
-			claudeContent.SetText(choice.Message.StringContent())
+			contents = append(contents, claudeContent)
+		}
+		if len(toolCalls) > 0 {
+			stopReason = stopReasonOpenAI2Claude(constant.FinishReasonToolCalls)
 		}
-		contents = append(contents, claudeContent)
 	}
 	claudeResponse.Content = contents
 	claudeResponse.StopReason = stopReason
-	claudeResponse.Usage = &dto.ClaudeUsage{
-		InputTokens:  openAIResponse.PromptTokens,
-		OutputTokens: openAIResponse.CompletionTokens,
-	}
+	claudeResponse.Usage = claudeUsageFromOpenAI(&openAIResponse.Usage)
 
 	return claudeResponse
 }
 
 func stopReasonOpenAI2Claude(reason string) string {
 	switch reason {
-	case "stop":
+	case "", constant.FinishReasonStop:
 		return "end_turn"
 	case "stop_sequence":
 		return "stop_sequence"
-	case "max_tokens":
+	case "max_tokens", constant.FinishReasonLength:
 		return "max_tokens"
-	case "tool_calls":
+	case constant.FinishReasonToolCalls, constant.FinishReasonFunctionCall:
 		return "tool_use"
+	case constant.FinishReasonContentFilter:
+		return "refusal"
 	default:
-		return reason
+		return "end_turn"
 	}
 }
 
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkoukk/tiktoken-go"
+	"gorm.io/gorm"
+)
+
+// new-api doctor 在启动服务前检查配置，逐项输出检查结果和处理建议，存在错误时以非 0 状态码退出
+
+const (
+	doctorLevelOK    = "OK"
+	doctorLevelWarn  = "WARN"
+	doctorLevelError = "ERROR"
+)
+
+type DoctorFinding struct {
+	Level   string
+	Item    string
+	Message string
+}
+
+type doctor struct {
+	findings []DoctorFinding
+}
+
+func (d *doctor) add(level string, item string, format string, args ...any) {
+	d.findings = append(d.findings, DoctorFinding{Level: level, Item: item, Message: fmt.Sprintf(format, args...)})
+}
+
+// RunDoctor 执行所有检查并输出结果，返回进程退出码
+func RunDoctor() int {
+	constant.InitEnv()
+	d := &doctor{}
+	d.checkConfig()
+	db := d.checkDatabase("SQL_DSN", false)
+	if os.Getenv("LOG_SQL_DSN") != "" {
+		d.checkDatabase("LOG_SQL_DSN", true)
+	}
+	d.checkRedis()
+	if db != nil {
+		d.checkChannelKeys(db)
+	}
+	d.checkPort()
+	d.checkTokenizer()
+
+	errors := 0
+	fmt.Println()
+	for _, finding := range d.findings {
+		if finding.Level == doctorLevelError {
+			errors++
+		}
+		fmt.Printf("[%-5s] %s: %s\n", finding.Level, finding.Item, finding.Message)
+	}
+	if errors > 0 {
+		fmt.Printf("\n发现 %d 个错误，请按提示修改配置后再启动\n", errors)
+		return 1
+	}
+	fmt.Println("\n检查通过")
+	return 0
+}
+
+func (d *doctor) checkConfig() {
+	if os.Getenv("SESSION_SECRET") == "" {
+		d.add(doctorLevelWarn, "SESSION_SECRET", "未设置，每次启动随机生成，重启后需要重新登录，多机部署时登录状态不一致")
+	}
+	if os.Getenv("REDIS_CONN_STRING") != "" && os.Getenv("CRYPTO_SECRET") == "" && os.Getenv("SESSION_SECRET") == "" {
+		d.add(doctorLevelError, "CRYPTO_SECRET", "使用 Redis 时需要设置 CRYPTO_SECRET 或 SESSION_SECRET，否则各节点无法读取 Redis 中的缓存")
+	}
+	for _, name := range []string{"PORT", "SYNC_FREQUENCY", "CHANNEL_UPDATE_FREQUENCY", "CHANNEL_TEST_FREQUENCY",
+		"SHADOW_SYNC_FREQUENCY", "PROVIDER_STATUS_CHECK_FREQUENCY", "BATCH_UPDATE_INTERVAL", "POLLING_INTERVAL"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(value); err != nil {
+			d.add(doctorLevelError, name, "%q 不是有效的整数", value)
+		}
+	}
+
+	slave := !common.IsMasterNode
+	redisEnabled := os.Getenv("REDIS_CONN_STRING") != ""
+	embeddedCache := common.GetEnvOrDefaultBool("EMBEDDED_CACHE_ENABLED", false)
+	if slave && os.Getenv("SQL_DSN") == "" {
+		d.add(doctorLevelError, "NODE_TYPE", "从节点使用本地 SQLite，无法与主节点共享数据，请设置与主节点相同的 SQL_DSN")
+	}
+	if slave && embeddedCache && !redisEnabled {
+		d.add(doctorLevelError, "EMBEDDED_CACHE_ENABLED", "进程内缓存只适用于单机部署，多机部署时请设置 REDIS_CONN_STRING")
+	}
+	if embeddedCache && redisEnabled {
+		d.add(doctorLevelWarn, "EMBEDDED_CACHE_ENABLED", "已设置 REDIS_CONN_STRING，进程内缓存不会生效")
+	}
+	if os.Getenv("SHADOW_SYNC_FREQUENCY") != "" && !ShadowSyncEnabled() {
+		d.add(doctorLevelWarn, "SHADOW_SYNC_FREQUENCY", "只在 DEPLOYMENT_ENVIRONMENT 不为 production 且设置了 SHADOW_SOURCE_ADDRESS 时生效")
+	}
+	if ShadowSyncEnabled() && constant.ShadowSourceAccessToken == "" {
+		d.add(doctorLevelError, "SHADOW_SOURCE_ACCESS_TOKEN", "同步影子渠道需要生产环境 root 用户的系统访问令牌")
+	}
+	switch constant.FileStorage {
+	case model.FileStorageDB, model.FileStorageLocal:
+	case model.FileStorageS3:
+		if constant.FileStorageS3Endpoint == "" || constant.FileStorageS3Bucket == "" {
+			d.add(doctorLevelError, "FILE_STORAGE", "使用 s3 存储文件时需要设置 FILE_STORAGE_S3_ENDPOINT 和 FILE_STORAGE_S3_BUCKET")
+		}
+	default:
+		d.add(doctorLevelError, "FILE_STORAGE", "%q 无效，可选值为 db、local、s3", constant.FileStorage)
+	}
+	if os.Getenv("SQL_DSN") == "" {
+		d.add(doctorLevelOK, "SQL_DSN", "未设置，使用 SQLite：%s", common.SQLitePath)
+	}
+}
+
+func (d *doctor) checkDatabase(envName string, isLogDB bool) *gorm.DB {
+	db, err := model.OpenDB(envName)
+	if err != nil {
+		d.add(doctorLevelError, envName, "连接数据库失败：%s", err.Error())
+		return nil
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = sqlDB.PingContext(ctx)
+		cancel()
+	}
+	if err != nil {
+		d.add(doctorLevelError, envName, "连接数据库失败：%s，请检查地址、账号和网络", err.Error())
+		return nil
+	}
+	d.add(doctorLevelOK, envName, "数据库连接正常")
+
+	pending, err := model.PendingMigrations(db, isLogDB)
+	if err != nil {
+		d.add(doctorLevelError, envName, "检查迁移状态失败：%s", err.Error())
+		return db
+	}
+	if len(pending) == 0 {
+		d.add(doctorLevelOK, envName, "数据库结构已是最新")
+		return db
+	}
+	if common.IsMasterNode {
+		d.add(doctorLevelWarn, envName, "缺少 %s，启动时会自动迁移", strings.Join(pending, "、"))
+	} else {
+		d.add(doctorLevelError, envName, "缺少 %s，从节点不会执行迁移，请先升级并启动主节点", strings.Join(pending, "、"))
+	}
+	if isLogDB {
+		return nil
+	}
+	// 缺少渠道表时无法检查渠道密钥
+	if !db.Migrator().HasTable(&model.Channel{}) {
+		return nil
+	}
+	return db
+}
+
+func (d *doctor) checkRedis() {
+	connString := os.Getenv("REDIS_CONN_STRING")
+	if connString == "" {
+		d.add(doctorLevelOK, "REDIS_CONN_STRING", "未设置，不使用 Redis")
+		return
+	}
+	opt, err := redis.ParseURL(connString)
+	if err != nil {
+		d.add(doctorLevelError, "REDIS_CONN_STRING", "格式错误：%s，格式应为 redis://[:password@]host:port/db", err.Error())
+		return
+	}
+	client := redis.NewClient(opt)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = client.Ping(ctx).Err(); err != nil {
+		d.add(doctorLevelError, "REDIS_CONN_STRING", "无法连接 %s：%s", opt.Addr, err.Error())
+		return
+	}
+	d.add(doctorLevelOK, "REDIS_CONN_STRING", "Redis 连接正常")
+}
+
+// checkChannelKey 按渠道类型检查密钥格式，返回问题描述
+func checkChannelKey(channel *model.Channel) string {
+	key := channel.Key
+	if common.IsSecretRef(key) {
+		if _, err := common.ResolveSecretRef(key); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	if key == "" {
+		if channel.Type == common.ChannelTypeOllama {
+			return ""
+		}
+		return "密钥为空"
+	}
+	if channel.Type == common.ChannelTypeVertexAi {
+		if !json.Valid([]byte(key)) {
+			return "密钥应为服务账号 JSON"
+		}
+		return ""
+	}
+	if strings.TrimSpace(key) != key || strings.ContainsAny(key, "\r\n") {
+		return "密钥包含空白或换行，请删除多余的字符"
+	}
+	parts := 0
+	format := ""
+	switch channel.Type {
+	case common.ChannelTypeAws, common.ChannelTypeSageMaker:
+		parts, format = 3, "AccessKey|SecretAccessKey|Region"
+	case common.ChannelTypeXunfei:
+		parts, format = 3, "APPID|APISecret|APIKey"
+	case common.ChannelTypeTencent:
+		parts, format = 3, "AppId|SecretId|SecretKey"
+	case common.ChannelTypeBaidu:
+		parts, format = 2, "APIKey|SecretKey"
+	}
+	if parts > 0 && len(strings.Split(key, "|")) != parts {
+		return "密钥格式应为 " + format
+	}
+	if channel.Type == common.ChannelTypeZhipu && len(strings.Split(key, ".")) != 2 {
+		return "密钥格式应为 id.secret"
+	}
+	return ""
+}
+
+func (d *doctor) checkChannelKeys(db *gorm.DB) {
+	var channels []*model.Channel
+	err := db.Select("id", "name", "type", "key", "status", "shadow_source_id").Find(&channels).Error
+	if err != nil {
+		d.add(doctorLevelError, "渠道", "读取渠道失败：%s", err.Error())
+		return
+	}
+	invalid := 0
+	for _, channel := range channels {
+		if problem := checkChannelKey(channel); problem != "" {
+			invalid++
+			level := doctorLevelWarn
+			if channel.Status == common.ChannelStatusEnabled {
+				level = doctorLevelError
+			}
+			d.add(level, fmt.Sprintf("渠道 #%d %s", channel.Id, channel.Name), problem)
+		}
+	}
+	if invalid == 0 {
+		d.add(doctorLevelOK, "渠道", "%d 个渠道的密钥格式正确", len(channels))
+	}
+}
+
+func (d *doctor) checkPort() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = strconv.Itoa(*common.Port)
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		d.add(doctorLevelError, "PORT", "端口 %s 不可用：%s，请停止占用该端口的进程或通过 PORT、--port 更换端口", port, err.Error())
+		return
+	}
+	_ = listener.Close()
+	d.add(doctorLevelOK, "PORT", "端口 %s 可用", port)
+}
+
+// checkTokenizer 离线环境中无法下载分词器数据时，需要设置 TIKTOKEN_CACHE_DIR 指向已缓存的目录
+func (d *doctor) checkTokenizer() {
+	for _, encoding := range []string{tiktoken.MODEL_CL100K_BASE, tiktoken.MODEL_O200K_BASE} {
+		if _, err := tiktoken.GetEncoding(encoding); err != nil {
+			d.add(doctorLevelError, "分词器", "加载 %s 失败：%s，离线部署时请设置 TIKTOKEN_CACHE_DIR 指向已缓存的分词器目录", encoding, err.Error())
+			return
+		}
+	}
+	d.add(doctorLevelOK, "分词器", "分词器加载正常")
+}
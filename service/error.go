@@ -74,12 +74,14 @@ func ClaudeErrorWrapperLocal(err error, code string, statusCode int) *dto.Claude
 }
 
 func RelayErrorHandler(resp *http.Response, showBodyWhenFail bool) (errWithStatusCode *dto.OpenAIErrorWithStatusCode) {
+	kind := errorKindByStatus(resp.StatusCode)
 	errWithStatusCode = &dto.OpenAIErrorWithStatusCode{
 		StatusCode: resp.StatusCode,
 		Error: dto.OpenAIError{
-			Type:  "upstream_error",
-			Code:  "bad_response_status_code",
-			Param: strconv.Itoa(resp.StatusCode),
+			Message: fmt.Sprintf("bad response status code %d", resp.StatusCode),
+			Type:    kind.Type,
+			Code:    kind.Code,
+			Param:   strconv.Itoa(resp.StatusCode),
 		},
 	}
 	responseBody, err := io.ReadAll(resp.Body)
@@ -90,24 +92,11 @@ func RelayErrorHandler(resp *http.Response, showBodyWhenFail bool) (errWithStatu
 	if err != nil {
 		return
 	}
-	var errResponse dto.GeneralErrorResponse
-	err = json.Unmarshal(responseBody, &errResponse)
-	if err != nil {
-		if showBodyWhenFail {
-			errWithStatusCode.Error.Message = string(responseBody)
-		} else {
-			errWithStatusCode.Error.Message = fmt.Sprintf("bad response status code %d", resp.StatusCode)
-		}
-		return
-	}
-	if errResponse.Error.Message != "" {
-		// OpenAI format error, so we override the default one
-		errWithStatusCode.Error = errResponse.Error
-	} else {
-		errWithStatusCode.Error.Message = errResponse.ToMessage()
+	if normalized, ok := NormalizeUpstreamError(responseBody, resp.StatusCode, resp.Header); ok {
+		return normalized
 	}
-	if errWithStatusCode.Error.Message == "" {
-		errWithStatusCode.Error.Message = fmt.Sprintf("bad response status code %d", resp.StatusCode)
+	if showBodyWhenFail && len(responseBody) > 0 {
+		errWithStatusCode.Error.Message = MaskUpstreamSecrets(string(responseBody))
 	}
 	return
 }
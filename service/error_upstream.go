@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"one-api/dto"
+	"regexp"
+	"strings"
+)
+
+// 把 Anthropic、Gemini、Bedrock、Azure 等上游的错误响应转换为 OpenAI 错误格式，
+// 上游没有给出 type 或 code 时按错误类别或 HTTP 状态码补全，并去除错误信息中的密钥
+
+type upstreamErrorKind struct {
+	Type       string
+	Code       string
+	StatusCode int
+}
+
+var (
+	errorKindInvalidRequest = upstreamErrorKind{"invalid_request_error", "invalid_request", http.StatusBadRequest}
+	errorKindAuthentication = upstreamErrorKind{"authentication_error", "invalid_api_key", http.StatusUnauthorized}
+	// 403 不一定是密钥的问题，例如模型未开通，不使用会触发自动禁用渠道的 permission_error
+	errorKindPermission = upstreamErrorKind{"invalid_request_error", "permission_denied", http.StatusForbidden}
+	errorKindNotFound   = upstreamErrorKind{"invalid_request_error", "not_found", http.StatusNotFound}
+	errorKindTooLarge   = upstreamErrorKind{"invalid_request_error", "request_too_large", http.StatusRequestEntityTooLarge}
+	errorKindRateLimit  = upstreamErrorKind{"rate_limit_error", "rate_limit_exceeded", http.StatusTooManyRequests}
+	errorKindServer     = upstreamErrorKind{"server_error", "server_error", http.StatusInternalServerError}
+	errorKindOverloaded = upstreamErrorKind{"server_error", "overloaded", http.StatusServiceUnavailable}
+	errorKindTimeout    = upstreamErrorKind{"server_error", "timeout", http.StatusGatewayTimeout}
+)
+
+// anthropicErrorKinds https://docs.anthropic.com/en/api/errors
+var anthropicErrorKinds = map[string]upstreamErrorKind{
+	"invalid_request_error": errorKindInvalidRequest,
+	"authentication_error":  errorKindAuthentication,
+	"permission_error":      {"permission_error", "permission_denied", http.StatusForbidden},
+	"not_found_error":       errorKindNotFound,
+	"request_too_large":     errorKindTooLarge,
+	"rate_limit_error":      errorKindRateLimit,
+	"api_error":             errorKindServer,
+	"overloaded_error":      errorKindOverloaded,
+}
+
+// geminiErrorKinds Gemini 和 Vertex AI 错误中的 status 字段
+var geminiErrorKinds = map[string]upstreamErrorKind{
+	"INVALID_ARGUMENT":    errorKindInvalidRequest,
+	"FAILED_PRECONDITION": errorKindInvalidRequest,
+	"OUT_OF_RANGE":        errorKindInvalidRequest,
+	"UNAUTHENTICATED":     errorKindAuthentication,
+	"PERMISSION_DENIED":   errorKindPermission,
+	"NOT_FOUND":           errorKindNotFound,
+	"RESOURCE_EXHAUSTED":  errorKindRateLimit,
+	"INTERNAL":            errorKindServer,
+	"UNKNOWN":             errorKindServer,
+	"UNAVAILABLE":         errorKindOverloaded,
+	"DEADLINE_EXCEEDED":   errorKindTimeout,
+}
+
+// bedrockErrorKinds Bedrock 错误响应的 __type 字段或 x-amzn-ErrorType 响应头
+var bedrockErrorKinds = map[string]upstreamErrorKind{
+	"ValidationException":           errorKindInvalidRequest,
+	"UnrecognizedClientException":   errorKindAuthentication,
+	"AccessDeniedException":         errorKindPermission,
+	"ResourceNotFoundException":     errorKindNotFound,
+	"ThrottlingException":           errorKindRateLimit,
+	"ServiceQuotaExceededException": errorKindRateLimit,
+	"ModelTimeoutException":         errorKindTimeout,
+	"ModelNotReadyException":        errorKindOverloaded,
+	"ServiceUnavailableException":   errorKindOverloaded,
+	"InternalServerException":       errorKindServer,
+	"ModelErrorException":           errorKindServer,
+}
+
+// errorKindByStatus 上游没有给出错误类别时按 HTTP 状态码判断
+func errorKindByStatus(statusCode int) upstreamErrorKind {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return errorKindInvalidRequest
+	case http.StatusUnauthorized:
+		return errorKindAuthentication
+	case http.StatusForbidden:
+		return errorKindPermission
+	case http.StatusNotFound:
+		return errorKindNotFound
+	case http.StatusRequestEntityTooLarge:
+		return errorKindTooLarge
+	case http.StatusTooManyRequests:
+		return errorKindRateLimit
+	case http.StatusServiceUnavailable, 529:
+		return errorKindOverloaded
+	case http.StatusGatewayTimeout:
+		return errorKindTimeout
+	}
+	if statusCode >= 500 {
+		return errorKindServer
+	}
+	return upstreamErrorKind{"upstream_error", "bad_response_status_code", http.StatusInternalServerError}
+}
+
+var upstreamSecretPatterns = []struct {
+	regex       *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`), "sk-***"},
+	{regexp.MustCompile(`AIza[0-9A-Za-z_\-]{30,}`), "AIza***"},
+	{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), "$1***"},
+	{regexp.MustCompile(`(?i)([?&](?:key|api_key|api-key|access_token)=)[^&\s"']+`), "${1}***"},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/\-]{8,}=*`), "${1}***"},
+}
+
+// MaskUpstreamSecrets 去除上游错误信息中的密钥，例如 OpenAI 密钥、Google API Key、AWS Access Key 和 URL 中的 key 参数
+func MaskUpstreamSecrets(message string) string {
+	for _, pattern := range upstreamSecretPatterns {
+		message = pattern.regex.ReplaceAllString(message, pattern.replacement)
+	}
+	return message
+}
+
+type upstreamErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Param   any    `json:"param"`
+	Code    any    `json:"code"`
+	Status  any    `json:"status"`
+}
+
+type upstreamErrorPayload struct {
+	Error   json.RawMessage `json:"error"`
+	AwsType string          `json:"__type"`
+}
+
+// NormalizeUpstreamError 解析上游的错误响应，无法解析时返回 false。错误响应的状态码不是错误状态码时按错误类别设置
+func NormalizeUpstreamError(body []byte, statusCode int, header http.Header) (*dto.OpenAIErrorWithStatusCode, bool) {
+	body = bytes.TrimSpace(body)
+	// Gemini 部分接口以数组返回错误
+	if len(body) > 0 && body[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil || len(items) == 0 {
+			return nil, false
+		}
+		body = items[0]
+	}
+	var payload upstreamErrorPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false
+	}
+	var detail upstreamErrorDetail
+	if len(payload.Error) > 0 {
+		switch payload.Error[0] {
+		case '{':
+			_ = json.Unmarshal(payload.Error, &detail)
+		case '"':
+			_ = json.Unmarshal(payload.Error, &detail.Message)
+		}
+	}
+	if detail.Message == "" {
+		// error 为字符串时无法解析到 GeneralErrorResponse.Error，其余字段仍会解析
+		var general dto.GeneralErrorResponse
+		_ = json.Unmarshal(body, &general)
+		detail.Message = general.ToMessage()
+	}
+	if detail.Message == "" {
+		return nil, false
+	}
+
+	kind, hasKind := upstreamErrorKind{}, false
+	awsType := payload.AwsType
+	if awsType == "" && header != nil {
+		awsType = header.Get("x-amzn-ErrorType")
+	}
+	// __type 可能带有命名空间前缀，响应头可能带有 :http://... 后缀
+	if i := strings.LastIndex(awsType, "#"); i >= 0 {
+		awsType = awsType[i+1:]
+	}
+	if i := strings.Index(awsType, ":"); i >= 0 {
+		awsType = awsType[:i]
+	}
+	if status, ok := detail.Status.(string); ok {
+		kind, hasKind = geminiErrorKinds[status]
+	}
+	if !hasKind && awsType != "" {
+		kind, hasKind = bedrockErrorKinds[awsType]
+	}
+	if !hasKind && detail.Type != "" {
+		kind, hasKind = anthropicErrorKinds[detail.Type]
+	}
+	if !hasKind {
+		kind = errorKindByStatus(statusCode)
+	}
+
+	openaiErr := dto.OpenAIError{
+		Message: MaskUpstreamSecrets(detail.Message),
+		Type:    detail.Type,
+		Code:    detail.Code,
+	}
+	if param, ok := detail.Param.(string); ok {
+		openaiErr.Param = param
+	}
+	if _, known := anthropicErrorKinds[detail.Type]; openaiErr.Type == "" || known {
+		openaiErr.Type = kind.Type
+	}
+	// Gemini 的 code 为数字状态码，Azure 的 code 为字符串
+	if code, ok := openaiErr.Code.(string); !ok || code == "" {
+		openaiErr.Code = kind.Code
+	}
+	if statusCode < http.StatusBadRequest {
+		statusCode = kind.StatusCode
+	}
+	return &dto.OpenAIErrorWithStatusCode{
+		Error:      openaiErr,
+		StatusCode: statusCode,
+	}, true
+}
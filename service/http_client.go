@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"one-api/common"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/proxy"
@@ -14,6 +15,7 @@ import (
 
 var httpClient *http.Client
 var impatientHTTPClient *http.Client
+var publicOnlyHTTPClient *http.Client
 
 func init() {
 	if common.RelayTimeout == 0 {
@@ -27,6 +29,31 @@ func init() {
 	impatientHTTPClient = &http.Client{
 		Timeout: 5 * time.Second,
 	}
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: rejectNonPublicAddress,
+	}
+	publicOnlyHTTPClient = &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+		},
+	}
+}
+
+// rejectNonPublicAddress 在建立连接时检查解析后的地址，拒绝回环、内网和链路本地地址，避免 DNS 重绑定绕过
+func rejectNonPublicAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("address %s is not a public address", host)
+	}
+	return nil
 }
 
 func GetHttpClient() *http.Client {
@@ -37,6 +64,11 @@ func GetImpatientHttpClient() *http.Client {
 	return impatientHTTPClient
 }
 
+// GetPublicOnlyHttpClient 用于请求用户提供的地址，只允许连接公网地址
+func GetPublicOnlyHttpClient() *http.Client {
+	return publicOnlyHTTPClient
+}
+
 // NewProxyHttpClient 创建支持代理的 HTTP 客户端
 func NewProxyHttpClient(proxyURL string) (*http.Client, error) {
 	if proxyURL == "" {
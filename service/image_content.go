@@ -0,0 +1,191 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/constant"
+	"one-api/dto"
+	"strings"
+)
+
+// 只接受内联图片的上游（Claude、Gemini 等）转发前把 image_url 中的远程地址下载为 data URL，
+// 并按上游的限制检查图片的大小和类型
+
+// ImageLimit 上游对内联图片的限制
+type ImageLimit struct {
+	// MaxBytes 解码后的大小上限，为 0 时使用 MAX_FILE_DOWNLOAD_MB
+	MaxBytes int64
+	// MimeTypes 支持的类型，以 / 结尾的按前缀匹配
+	MimeTypes []string
+}
+
+var (
+	ClaudeImageLimit = ImageLimit{
+		MaxBytes:  5 * 1024 * 1024,
+		MimeTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+	}
+	// GeminiImageLimit Gemini 的 image_url 也可以传入 PDF 和音视频
+	GeminiImageLimit = ImageLimit{
+		MimeTypes: []string{"image/png", "image/jpeg", "image/webp", "image/heic", "image/heif", "application/pdf", "audio/", "video/", "text/"},
+	}
+	DefaultImageLimit = ImageLimit{
+		MimeTypes: []string{"image/"},
+	}
+)
+
+func (l ImageLimit) maxBytes() int64 {
+	if l.MaxBytes > 0 {
+		return l.MaxBytes
+	}
+	return int64(constant.MaxFileDownloadMB) * 1024 * 1024
+}
+
+func (l ImageLimit) allows(mimeType string) bool {
+	for _, allowed := range l.MimeTypes {
+		if mimeType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mimeType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMimeType 去掉参数并统一 image/jpg 等别名
+func normalizeMimeType(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+	switch mimeType {
+	case "image/jpg", "image/pjpeg":
+		return "image/jpeg"
+	}
+	return mimeType
+}
+
+// parseDataUrl 拆分 data URL 的类型和 base64 数据，没有前缀时整体作为 base64 数据
+func parseDataUrl(dataUrl string) (string, string) {
+	if !strings.HasPrefix(dataUrl, "data:") {
+		return "", dataUrl
+	}
+	header, data, found := strings.Cut(strings.TrimPrefix(dataUrl, "data:"), ",")
+	if !found {
+		return "", header
+	}
+	mimeType, _, _ := strings.Cut(header, ";")
+	return mimeType, data
+}
+
+// GetImageData 下载远程图片或解析 data URL，类型缺失或为 application/octet-stream 时根据内容判断
+func GetImageData(imageUrl string, limit ImageLimit) (*dto.LocalFileData, error) {
+	maxBytes := limit.maxBytes()
+	var data []byte
+	var mimeType string
+	if strings.HasPrefix(imageUrl, "http://") || strings.HasPrefix(imageUrl, "https://") {
+		resp, err := DoDownloadRequest(imageUrl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download image: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download image: HTTP %d", resp.StatusCode)
+		}
+		if resp.ContentLength > maxBytes {
+			return nil, fmt.Errorf("image size %d exceeds maximum allowed size of %d bytes", resp.ContentLength, maxBytes)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image data: %w", err)
+		}
+		mimeType = resp.Header.Get("Content-Type")
+	} else {
+		var base64String string
+		mimeType, base64String = parseDataUrl(imageUrl)
+		var err error
+		data, err = base64.StdEncoding.DecodeString(base64String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 image data: %w", err)
+		}
+	}
+	mimeType = normalizeMimeType(mimeType)
+	if mimeType == "" || mimeType == "application/octet-stream" || mimeType == "binary/octet-stream" {
+		mimeType = normalizeMimeType(http.DetectContentType(data))
+	}
+	fileData := &dto.LocalFileData{
+		MimeType:   mimeType,
+		Base64Data: base64.StdEncoding.EncodeToString(data),
+		Url:        imageUrl,
+		Size:       int64(len(data)),
+	}
+	if err := limit.check(fileData); err != nil {
+		return nil, err
+	}
+	return fileData, nil
+}
+
+func (l ImageLimit) check(fileData *dto.LocalFileData) error {
+	if maxBytes := l.maxBytes(); fileData.Size > maxBytes {
+		return fmt.Errorf("image size exceeds maximum allowed size of %d bytes", maxBytes)
+	}
+	if !l.allows(fileData.MimeType) {
+		return fmt.Errorf("unsupported image type: %s", fileData.MimeType)
+	}
+	return nil
+}
+
+// getCachedImageData 远程图片在同一请求内只下载一次，不同渠道的限制不同，命中缓存时按当前限制重新检查
+func getCachedImageData(imageUrl string, limit ImageLimit, cache map[string]*dto.LocalFileData) (*dto.LocalFileData, error) {
+	remote := strings.HasPrefix(imageUrl, "http://") || strings.HasPrefix(imageUrl, "https://")
+	if fileData, ok := cache[imageUrl]; ok && remote {
+		if err := limit.check(fileData); err != nil {
+			return nil, err
+		}
+		return fileData, nil
+	}
+	fileData, err := GetImageData(imageUrl, limit)
+	if err != nil {
+		return nil, err
+	}
+	if remote && cache != nil {
+		cache[imageUrl] = fileData
+	}
+	return fileData, nil
+}
+
+// InlineImageContent 把消息中 image_url 的远程地址下载为 data URL，data URL 同样检查大小和类型，
+// 已下载的图片保存在 cache 中
+func InlineImageContent(request *dto.GeneralOpenAIRequest, limit ImageLimit, cache map[string]*dto.LocalFileData) error {
+	messages := make([]dto.Message, 0, len(request.Messages))
+	for _, message := range request.Messages {
+		if message.IsStringContent() {
+			messages = append(messages, message)
+			continue
+		}
+		mediaContents := message.ParseContent()
+		changed := false
+		for i, mediaContent := range mediaContents {
+			if mediaContent.Type != dto.ContentTypeImageURL {
+				continue
+			}
+			imageUrl := mediaContent.GetImageMedia()
+			if imageUrl == nil || imageUrl.Url == "" {
+				continue
+			}
+			fileData, err := getCachedImageData(imageUrl.Url, limit, cache)
+			if err != nil {
+				return err
+			}
+			imageUrl.Url = fmt.Sprintf("data:%s;base64,%s", fileData.MimeType, fileData.Base64Data)
+			mediaContent.ImageUrl = imageUrl
+			mediaContents[i] = mediaContent
+			changed = true
+		}
+		if changed {
+			message.SetMediaContent(mediaContents)
+		}
+		messages = append(messages, message)
+	}
+	request.Messages = messages
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"one-api/constant"
+	"one-api/model"
+	"strings"
+)
+
+const (
+	ImageEndpointEdits      = "edits"
+	ImageEndpointVariations = "variations"
+)
+
+// GetImageEndpoint 返回请求路径对应的图片编辑或变体接口，其他接口返回空字符串
+func GetImageEndpoint(path string) string {
+	if strings.HasPrefix(path, "/v1/images/edits") {
+		return ImageEndpointEdits
+	}
+	if strings.HasPrefix(path, "/v1/images/variations") {
+		return ImageEndpointVariations
+	}
+	return ""
+}
+
+// ChannelSupportsImageEndpoint 渠道设置了 image_endpoints 时只支持设置中的接口，未设置时默认支持
+func ChannelSupportsImageEndpoint(channel *model.Channel, endpoint string) bool {
+	if endpoint == "" {
+		return true
+	}
+	endpoints, ok := channel.GetSetting()[constant.ChannelSettingImageEndpoints].([]interface{})
+	if !ok {
+		return true
+	}
+	for _, e := range endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectImageEndpointChannel 已选择的渠道不支持该接口时，在支持的渠道中按重试次数对应的优先级和权重重新选择
+func SelectImageEndpointChannel(channel *model.Channel, group string, modelName string, endpoint string, retry int) (*model.Channel, error) {
+	if ChannelSupportsImageEndpoint(channel, endpoint) {
+		return channel, nil
+	}
+	selected, err := model.CacheGetRandomSatisfiedChannelWithFilter(group, modelName, retry, func(c *model.Channel) bool {
+		return ChannelSupportsImageEndpoint(c, endpoint)
+	})
+	if err != nil || selected == nil {
+		return nil, fmt.Errorf("当前分组 %s 下对于模型 %s 无支持 images/%s 接口的渠道", group, modelName, endpoint)
+	}
+	return selected, nil
+}
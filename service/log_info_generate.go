@@ -3,6 +3,7 @@ package service
 import (
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,16 +17,36 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 	other["cache_tokens"] = cacheTokens
 	other["cache_ratio"] = cacheRatio
 	other["model_price"] = modelPrice
+	// 缓存命中的 token 按缓存倍率计费节省的额度，按次计费时 modelPrice 不为 -1
+	if cacheTokens > 0 && cacheRatio < 1 && modelPrice < 0 {
+		other["cache_savings"] = int(float64(cacheTokens) * (1 - cacheRatio) * modelRatio * groupRatio)
+	}
 	other["frt"] = float64(relayInfo.FirstResponseTime.UnixMilli() - relayInfo.StartTime.UnixMilli())
 	if relayInfo.ReasoningEffort != "" {
 		other["reasoning_effort"] = relayInfo.ReasoningEffort
 	}
+	// 分组倍率已包含用量阶梯倍率
+	if volumeRatio, ok := ctx.Get("volume_tier_ratio"); ok {
+		other["volume_tier_ratio"] = volumeRatio
+		other["monthly_tokens"] = ctx.GetInt64("monthly_tokens")
+	}
+	if operation_setting.IsFreeModel(relayInfo.OriginModelName) {
+		other["free_model"] = true
+	}
+	if adjustments := ctx.GetStringSlice("param_adjustments"); len(adjustments) > 0 {
+		other["param_adjustments"] = adjustments
+	}
 	if relayInfo.IsModelMapped {
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
 	adminInfo := make(map[string]interface{})
 	adminInfo["use_channel"] = ctx.GetStringSlice("use_channel")
+	// 上游返回的实际费用（美元），目前仅 OpenRouter 渠道提供
+	if upstreamCost, ok := ctx.Get("upstream_cost"); ok {
+		adminInfo["upstream_cost"] = upstreamCost
+		adminInfo["upstream_generation_id"] = ctx.GetString("upstream_generation_id")
+	}
 	other["admin_info"] = adminInfo
 	return other
 }
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"time"
+)
+
+// CheckUserCredit 后付费用户的欠款达到信用额度后暂停转发请求，结清欠款或提高信用额度后自动恢复，
+// 返回的错误为拒绝请求的原因
+func CheckUserCredit(userId int) error {
+	user, err := model.GetUserCache(userId)
+	if err != nil {
+		// 无法确认时不拒绝请求，预扣费时仍会检查额度
+		common.SysError(fmt.Sprintf("failed to get billing mode of user %d: %s", userId, err.Error()))
+		return nil
+	}
+	if user.BillingMode != common.UserBillingModePostpaid {
+		return nil
+	}
+	quota, err := model.GetUserQuota(userId, false)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get quota of user %d: %s", userId, err.Error()))
+		return nil
+	}
+	if quota+user.GetCreditLimit() <= 0 {
+		return fmt.Errorf("已超出信用额度 %s，请结清账单后继续使用", common.FormatQuota(user.GetCreditLimit()))
+	}
+	return nil
+}
+
+// postpaidStatementPeriod 返回 t 所在月份上一个月的起止时间和月份
+func postpaidStatementPeriod(t time.Time) (int64, int64, string) {
+	end := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	start := end.AddDate(0, -1, 0)
+	return start.Unix(), end.Unix(), start.Format("2006-01")
+}
+
+// GeneratePostpaidStatements 为所有后付费用户生成上个月的账单，已生成的跳过
+func GeneratePostpaidStatements(now time.Time) error {
+	startTimestamp, endTimestamp, period := postpaidStatementPeriod(now)
+	userIds, err := model.GetPostpaidUserIds()
+	if err != nil {
+		return err
+	}
+	for _, userId := range userIds {
+		if err := generatePostpaidStatement(userId, period, startTimestamp, endTimestamp); err != nil {
+			common.SysError(fmt.Sprintf("failed to generate postpaid statement %s of user %d: %s", period, userId, err.Error()))
+		}
+	}
+	return nil
+}
+
+func generatePostpaidStatement(userId int, period string, startTimestamp int64, endTimestamp int64) error {
+	exists, err := model.PostpaidStatementExists(userId, period)
+	if err != nil || exists {
+		return err
+	}
+	usage, err := GetUsageStatement(userId, 0, startTimestamp, endTimestamp)
+	if err != nil {
+		return err
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		return err
+	}
+	statement := &model.PostpaidStatement{
+		UserId:         userId,
+		Period:         period,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+		Requests:       usage.Total.Requests,
+		UsedQuota:      usage.Total.Quota,
+		Balance:        user.Quota,
+		CreditLimit:    user.CreditLimit,
+		CreatedTime:    common.GetTimestamp(),
+	}
+	if err = statement.Insert(); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("已生成 %s 月账单，本月消耗 %s，当前余额 %s", period, common.LogQuota(int(usage.Total.Quota)), common.LogQuota(user.Quota))
+	model.RecordLog(userId, model.LogTypeSystem, content)
+	return nil
+}
+
+// AutomaticallyGeneratePostpaidStatements 每小时检查一次，为后付费用户补齐上个月的账单
+func AutomaticallyGeneratePostpaidStatements() {
+	for {
+		if err := GeneratePostpaidStatements(time.Now()); err != nil {
+			common.SysError("failed to generate postpaid statements: " + err.Error())
+		}
+		time.Sleep(time.Hour)
+	}
+}
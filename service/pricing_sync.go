@@ -0,0 +1,339 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 价格同步从 OpenRouter 或 LiteLLM 格式的价格源拉取每个模型的输入、输出价格，乘以利润倍数后与价格表比较，
+// 只处理渠道中启用的模型和价格表中已有的模型，已有模型的音频价格保持不变。
+// 上游输入、输出价格都为 0 的模型不同步，避免把模型改为免费，只在结果中列出
+
+const (
+	PricingSyncStatusAdded   = "added"
+	PricingSyncStatusChanged = "changed"
+)
+
+type PricingSyncChange struct {
+	Model  string                          `json:"model"`
+	Status string                          `json:"status"`
+	Old    *operation_setting.ModelPricing `json:"old,omitempty"`
+	New    operation_setting.ModelPricing  `json:"new"`
+}
+
+type PricingSyncResult struct {
+	Time      int64               `json:"time"`
+	Source    string              `json:"source"`
+	Changes   []PricingSyncChange `json:"changes"`
+	Unchanged int                 `json:"unchanged"`
+	Skipped   []string            `json:"skipped"`
+	Applied   bool                `json:"applied"`
+}
+
+var lastPricingSyncResult *PricingSyncResult
+var lastPricingSyncResultLock sync.RWMutex
+
+// pricingSyncLock 避免手动同步和定时同步同时写入价格表
+var pricingSyncLock sync.Mutex
+
+// maxPricingSourceBytes 价格源响应的大小上限
+const maxPricingSourceBytes = 32 << 20
+
+type openRouterModelsResponse struct {
+	Data []struct {
+		Id      string `json:"id"`
+		Pricing struct {
+			Prompt     string `json:"prompt"`
+			Completion string `json:"completion"`
+		} `json:"pricing"`
+	} `json:"data"`
+}
+
+type liteLLMModelPricing struct {
+	InputCostPerToken  *float64 `json:"input_cost_per_token"`
+	OutputCostPerToken *float64 `json:"output_cost_per_token"`
+	LiteLLMProvider    string   `json:"litellm_provider"`
+}
+
+// modelVendors 模型名称前缀对应的厂商在价格源中使用的前缀
+var modelVendors = []struct {
+	prefix    string
+	providers []string
+}{
+	{"gpt-", []string{"openai"}},
+	{"chatgpt-", []string{"openai"}},
+	{"o1", []string{"openai"}},
+	{"o3", []string{"openai"}},
+	{"o4", []string{"openai"}},
+	{"text-embedding-", []string{"openai"}},
+	{"claude", []string{"anthropic"}},
+	{"gemini", []string{"google", "gemini"}},
+	{"gemma", []string{"google", "gemini"}},
+	{"deepseek", []string{"deepseek"}},
+	{"grok", []string{"x-ai", "xai"}},
+	{"mistral", []string{"mistralai", "mistral"}},
+	{"codestral", []string{"mistralai", "mistral"}},
+	{"qwen", []string{"qwen", "dashscope"}},
+	{"llama", []string{"meta-llama"}},
+}
+
+// isVendorProvider 判断价格源中的前缀是否是模型厂商自己的前缀
+func isVendorProvider(name string, provider string) bool {
+	name = strings.ToLower(name)
+	provider = strings.ToLower(provider)
+	for _, vendor := range modelVendors {
+		if strings.HasPrefix(name, vendor.prefix) {
+			for _, p := range vendor.providers {
+				if p == provider {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// upstreamPrices 上游每个 token 的输入、输出价格（美元）
+type upstreamPrices struct {
+	prices map[string][2]float64
+	// aliases 去掉前缀登记的名称，值表示价格是否来自模型厂商自己的前缀
+	aliases map[string]bool
+}
+
+func newUpstreamPrices() *upstreamPrices {
+	return &upstreamPrices{
+		prices:  make(map[string][2]float64),
+		aliases: make(map[string]bool),
+	}
+}
+
+// add 同时登记带厂商前缀的名称和去掉前缀的名称，provider 为空时取名称中最后一段前缀。
+// 去掉前缀后重名时优先使用模型厂商自己的价格，其次保留先出现的价格，价格源中不带前缀的名称总是优先
+func (p *upstreamPrices) add(name string, provider string, input float64, output float64) {
+	if input < 0 || output < 0 {
+		return
+	}
+	p.prices[name] = [2]float64{input, output}
+	delete(p.aliases, name)
+	index := strings.LastIndex(name, "/")
+	if index < 0 {
+		return
+	}
+	alias := name[index+1:]
+	if provider == "" {
+		provider = name[:index]
+		if i := strings.LastIndex(provider, "/"); i >= 0 {
+			provider = provider[i+1:]
+		}
+	}
+	vendor := isVendorProvider(alias, provider)
+	matched, isAlias := p.aliases[alias]
+	if _, exists := p.prices[alias]; exists && (!isAlias || matched || !vendor) {
+		return
+	}
+	p.prices[alias] = [2]float64{input, output}
+	p.aliases[alias] = vendor
+}
+
+func (p *upstreamPrices) get(name string) ([2]float64, bool) {
+	price, ok := p.prices[name]
+	return price, ok
+}
+
+func parseOpenRouterPrices(body []byte) (*upstreamPrices, error) {
+	var response openRouterModelsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	prices := newUpstreamPrices()
+	for _, item := range response.Data {
+		input, err := strconv.ParseFloat(item.Pricing.Prompt, 64)
+		if err != nil {
+			continue
+		}
+		output, err := strconv.ParseFloat(item.Pricing.Completion, 64)
+		if err != nil {
+			continue
+		}
+		prices.add(item.Id, "", input, output)
+	}
+	return prices, nil
+}
+
+func parseLiteLLMPrices(body []byte) (*upstreamPrices, error) {
+	var items map[string]json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	// 按名称排序，使去掉前缀后重名时的选择稳定
+	sort.Strings(names)
+	prices := newUpstreamPrices()
+	for _, name := range names {
+		var item liteLLMModelPricing
+		if err := json.Unmarshal(items[name], &item); err != nil || item.InputCostPerToken == nil {
+			continue
+		}
+		output := 0.0
+		if item.OutputCostPerToken != nil {
+			output = *item.OutputCostPerToken
+		}
+		prices.add(name, item.LiteLLMProvider, *item.InputCostPerToken, output)
+	}
+	return prices, nil
+}
+
+func fetchUpstreamPrices(source string, url string) (*upstreamPrices, error) {
+	if url == "" {
+		return nil, errors.New("未设置价格源地址")
+	}
+	resp, err := GetHttpClient().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("price source returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPricingSourceBytes))
+	if err != nil {
+		return nil, err
+	}
+	switch source {
+	case operation_setting.PricingSyncSourceOpenRouter:
+		return parseOpenRouterPrices(body)
+	case operation_setting.PricingSyncSourceLiteLLM:
+		return parseLiteLLMPrices(body)
+	}
+	return nil, fmt.Errorf("不支持的价格源 %s", source)
+}
+
+func roundSyncedPrice(price float64) float64 {
+	return math.Round(price*1e6) / 1e6
+}
+
+// SyncModelPricing 拉取上游价格并与价格表比较，apply 为 true 时把新增和变化的价格写入价格表
+func SyncModelPricing(apply bool) (*PricingSyncResult, error) {
+	pricingSyncLock.Lock()
+	defer pricingSyncLock.Unlock()
+	setting := operation_setting.GetPricingSyncSetting()
+	margin := setting.Margin
+	if margin <= 0 {
+		margin = 1
+	}
+	prices, err := fetchUpstreamPrices(setting.Source, setting.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	pricingMap := operation_setting.GetModelPricingCopy()
+	names := model.GetEnabledModels()
+	for name := range pricingMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := &PricingSyncResult{
+		Time:    common.GetTimestamp(),
+		Source:  setting.Source,
+		Changes: make([]PricingSyncChange, 0),
+		Skipped: make([]string, 0),
+	}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		price, ok := prices.get(name)
+		if !ok {
+			continue
+		}
+		old, exists := pricingMap[name]
+		// 按次计费的模型不同步 token 价格
+		if exists && old.PerRequest > 0 {
+			continue
+		}
+		if price[0] == 0 && price[1] == 0 {
+			result.Skipped = append(result.Skipped, name)
+			continue
+		}
+		synced := old
+		synced.Input = roundSyncedPrice(price[0] * 1e6 * margin)
+		synced.Output = roundSyncedPrice(price[1] * 1e6 * margin)
+		synced.Currency = operation_setting.PricingCurrencyUSD
+		if exists && old.Input == synced.Input && old.Output == synced.Output && old.GetCurrency() == synced.Currency {
+			result.Unchanged++
+			continue
+		}
+		change := PricingSyncChange{Model: name, Status: PricingSyncStatusAdded, New: synced}
+		if exists {
+			change.Status = PricingSyncStatusChanged
+			change.Old = &old
+		}
+		result.Changes = append(result.Changes, change)
+		pricingMap[name] = synced
+	}
+
+	if apply && len(result.Changes) > 0 {
+		jsonBytes, err := json.Marshal(pricingMap)
+		if err != nil {
+			return nil, err
+		}
+		if err = model.UpdateOption("ModelPricing", string(jsonBytes)); err != nil {
+			return nil, err
+		}
+		result.Applied = true
+	}
+	lastPricingSyncResultLock.Lock()
+	lastPricingSyncResult = result
+	lastPricingSyncResultLock.Unlock()
+	return result, nil
+}
+
+// GetLastPricingSyncResult 返回最近一次同步的结果，尚未同步时返回 nil
+func GetLastPricingSyncResult() *PricingSyncResult {
+	lastPricingSyncResultLock.RLock()
+	defer lastPricingSyncResultLock.RUnlock()
+	return lastPricingSyncResult
+}
+
+// AutomaticallySyncPricing 开启价格同步后按设置的间隔定时同步
+func AutomaticallySyncPricing() {
+	for {
+		setting := operation_setting.GetPricingSyncSetting()
+		if !setting.Enabled {
+			time.Sleep(time.Minute)
+			continue
+		}
+		result, err := SyncModelPricing(setting.AutoApply)
+		if err != nil {
+			common.SysError("failed to sync model pricing: " + err.Error())
+		} else if len(result.Changes) > 0 {
+			changed := make([]string, 0, len(result.Changes))
+			for _, change := range result.Changes {
+				changed = append(changed, change.Model)
+			}
+			common.SysLog(fmt.Sprintf("model pricing synced from %s, applied: %t, changed models: %s",
+				result.Source, result.Applied, strings.Join(changed, ", ")))
+		}
+		interval := setting.IntervalMinutes
+		if interval <= 0 {
+			interval = 1440
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 来源证明：网关用 Ed25519 对请求 ID、模型和时间戳签名并写入响应头，下游系统通过 /api/provenance/public_key
+// 获取公钥后即可在本地验证响应确实经过本网关，也可以调用 /api/provenance/verify 验证
+// 签名密钥由 PROVENANCE_SECRET 派生（未设置时使用 CRYPTO_SECRET），多节点部署时需保持一致
+
+const ProvenanceHeader = "X-Provenance"
+
+const provenanceVersion = "1"
+
+type Provenance struct {
+	Version   string `json:"version"`
+	RequestId string `json:"request_id"`
+	Model     string `json:"model"`
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+var (
+	provenanceKey     ed25519.PrivateKey
+	provenanceKeyOnce sync.Once
+)
+
+func getProvenanceKey() ed25519.PrivateKey {
+	provenanceKeyOnce.Do(func() {
+		secret := common.GetEnvOrDefaultString("PROVENANCE_SECRET", common.CryptoSecret)
+		seed := sha256.Sum256([]byte("provenance:" + secret))
+		provenanceKey = ed25519.NewKeyFromSeed(seed[:])
+	})
+	return provenanceKey
+}
+
+// GetProvenancePublicKey 返回 base64 编码的 Ed25519 公钥
+func GetProvenancePublicKey() string {
+	publicKey := getProvenanceKey().Public().(ed25519.PublicKey)
+	return base64.StdEncoding.EncodeToString(publicKey)
+}
+
+func (p *Provenance) signedPayload() []byte {
+	return []byte(fmt.Sprintf("v%s\n%s\n%s\n%d", p.Version, p.RequestId, p.Model, p.Timestamp))
+}
+
+func NewProvenance(requestId string, model string) *Provenance {
+	p := &Provenance{
+		Version:   provenanceVersion,
+		RequestId: requestId,
+		Model:     model,
+		Timestamp: common.GetTimestamp(),
+	}
+	p.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(getProvenanceKey(), p.signedPayload()))
+	return p
+}
+
+// HeaderValue 格式为 v=1; request_id=...; model=...; ts=...; sig=...，模型名称经过 URL 安全的 base64 编码避免包含分隔符
+func (p *Provenance) HeaderValue() string {
+	return fmt.Sprintf("v=%s; request_id=%s; model=%s; ts=%d; sig=%s", p.Version, p.RequestId,
+		base64.RawURLEncoding.EncodeToString([]byte(p.Model)), p.Timestamp, p.Signature)
+}
+
+func ParseProvenanceHeader(value string) (*Provenance, error) {
+	p := &Provenance{}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "v":
+			p.Version = val
+		case "request_id":
+			p.RequestId = val
+		case "model":
+			model, err := base64.RawURLEncoding.DecodeString(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid model: %w", err)
+			}
+			p.Model = string(model)
+		case "ts":
+			ts, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			p.Timestamp = ts
+		case "sig":
+			p.Signature = val
+		}
+	}
+	if p.Version != provenanceVersion {
+		return nil, errors.New("unsupported provenance version")
+	}
+	if p.RequestId == "" || p.Signature == "" {
+		return nil, errors.New("incomplete provenance")
+	}
+	return p, nil
+}
+
+func (p *Provenance) Verify() bool {
+	signature, err := base64.StdEncoding.DecodeString(p.Signature)
+	if err != nil {
+		return false
+	}
+	publicKey := getProvenanceKey().Public().(ed25519.PublicKey)
+	return ed25519.Verify(publicKey, p.signedPayload(), signature)
+}
@@ -23,6 +23,8 @@ import (
 type TokenDetails struct {
 	TextTokens  int
 	AudioTokens int
+	// CachedTokens 命中缓存的文本 token，包含在 TextTokens 中
+	CachedTokens int
 }
 
 type QuotaInfo struct {
@@ -33,6 +35,7 @@ type QuotaInfo struct {
 	ModelPrice    float64
 	ModelRatio    float64
 	GroupRatio    float64
+	CacheRatio    float64
 }
 
 func calculateAudioQuota(info QuotaInfo) int {
@@ -53,15 +56,20 @@ func calculateAudioQuota(info QuotaInfo) int {
 	modelRatio := decimal.NewFromFloat(info.ModelRatio)
 	ratio := groupRatio.Mul(modelRatio)
 
-	inputTextTokens := decimal.NewFromInt(int64(info.InputDetails.TextTokens))
+	inputTextTokens := decimal.NewFromInt(int64(info.InputDetails.TextTokens - info.InputDetails.CachedTokens))
+	cachedTokens := decimal.NewFromInt(int64(info.InputDetails.CachedTokens))
 	outputTextTokens := decimal.NewFromInt(int64(info.OutputDetails.TextTokens))
 	inputAudioTokens := decimal.NewFromInt(int64(info.InputDetails.AudioTokens))
 	outputAudioTokens := decimal.NewFromInt(int64(info.OutputDetails.AudioTokens))
 
 	quota := decimal.Zero
-	quota = quota.Add(inputTextTokens)
+	// 输入免费的模型输入 token 不计费
+	if !operation_setting.IsFreeInputModel(info.ModelName) {
+		quota = quota.Add(inputTextTokens)
+		quota = quota.Add(cachedTokens.Mul(decimal.NewFromFloat(info.CacheRatio)))
+		quota = quota.Add(inputAudioTokens.Mul(audioRatio))
+	}
 	quota = quota.Add(outputTextTokens.Mul(completionRatio))
-	quota = quota.Add(inputAudioTokens.Mul(audioRatio))
 	quota = quota.Add(outputAudioTokens.Mul(audioRatio).Mul(audioCompletionRatio))
 
 	quota = quota.Mul(ratio)
@@ -74,11 +82,59 @@ func calculateAudioQuota(info QuotaInfo) int {
 	return int(quota.Round(0).IntPart())
 }
 
+// audioBillingBreakdown mirrors calculateAudioQuota line by line
+func audioBillingBreakdown(info QuotaInfo, quota int) *dto.BillingBreakdown {
+	billing := &dto.BillingBreakdown{
+		UsePrice:   info.UsePrice,
+		ModelRatio: info.ModelRatio,
+		ModelPrice: info.ModelPrice,
+		GroupRatio: info.GroupRatio,
+		Quota:      quota,
+	}
+	if info.UsePrice {
+		billing.AddItem(dto.BillingLineItem{
+			Type:     dto.BillingItemRequest,
+			Quantity: 1,
+			Unit:     dto.BillingUnitRequests,
+			Price:    info.ModelPrice,
+			Quota:    info.ModelPrice * common.QuotaPerUnit * info.GroupRatio,
+		})
+		return billing
+	}
+
+	completionRatio := operation_setting.GetCompletionRatio(info.ModelName)
+	audioRatio := operation_setting.GetAudioRatio(info.ModelName)
+	audioCompletionRatio := operation_setting.GetAudioCompletionRatio(info.ModelName)
+	ratio := decimal.NewFromFloat(info.GroupRatio).Mul(decimal.NewFromFloat(info.ModelRatio))
+	promptRatio := ratio
+	if operation_setting.IsFreeInputModel(info.ModelName) {
+		promptRatio = decimal.Zero
+	}
+
+	tokenItem := func(itemType string, tokens int, itemRatio float64, ratio decimal.Decimal) dto.BillingLineItem {
+		return dto.BillingLineItem{
+			Type:     itemType,
+			Quantity: tokens,
+			Unit:     dto.BillingUnitTokens,
+			Ratio:    itemRatio,
+			Quota:    decimal.NewFromInt(int64(tokens)).Mul(decimal.NewFromFloat(itemRatio)).Mul(ratio).InexactFloat64(),
+		}
+	}
+	billing.AddItem(tokenItem(dto.BillingItemPrompt, info.InputDetails.TextTokens-info.InputDetails.CachedTokens, 1, promptRatio))
+	if info.InputDetails.CachedTokens > 0 {
+		billing.AddItem(tokenItem(dto.BillingItemCachedPrompt, info.InputDetails.CachedTokens, info.CacheRatio, promptRatio))
+	}
+	billing.AddItem(tokenItem(dto.BillingItemCompletion, info.OutputDetails.TextTokens, completionRatio, ratio))
+	billing.AddItem(tokenItem(dto.BillingItemAudioInput, info.InputDetails.AudioTokens, audioRatio, promptRatio))
+	billing.AddItem(tokenItem(dto.BillingItemAudioOutput, info.OutputDetails.AudioTokens, audioRatio*audioCompletionRatio, ratio))
+	return billing
+}
+
 func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.RealtimeUsage) error {
 	if relayInfo.UsePrice {
 		return nil
 	}
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := model.GetUserSpendableQuota(relayInfo.UserId)
 	if err != nil {
 		return err
 	}
@@ -93,7 +149,7 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 	textOutTokens := usage.OutputTokenDetails.TextTokens
 	audioInputTokens := usage.InputTokenDetails.AudioTokens
 	audioOutTokens := usage.OutputTokenDetails.AudioTokens
-	groupRatio := setting.GetGroupRatio(relayInfo.Group)
+	groupRatio := setting.GetGroupModelRatio(relayInfo.Group, modelName)
 	modelRatio, _ := operation_setting.GetModelRatio(modelName)
 
 	quotaInfo := QuotaInfo{
@@ -132,6 +188,8 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, modelName string,
 	usage *dto.RealtimeUsage, preConsumedQuota int, userQuota int, modelRatio float64, groupRatio float64,
 	modelPrice float64, usePrice bool, extraContent string) {
+	// 实时会话按事件扣费，预扣的额度不再结算，只移除预扣记录
+	RemoveQuotaHold(relayInfo.RequestId)
 
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.InputTokenDetails.TextTokens
@@ -190,6 +248,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 	}
 	other := GenerateWssOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice)
+	other["billing"] = audioBillingBreakdown(quotaInfo, quota)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, relayInfo.ChannelId, usage.InputTokens, usage.OutputTokens, logModel,
 		tokenName, quota, logContent, relayInfo.TokenId, userQuota, int(useTimeSeconds), relayInfo.IsStream, relayInfo.Group, other)
 }
@@ -216,9 +275,12 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 
 	calculateQuota := 0.0
 	if !priceData.UsePrice {
-		calculateQuota = float64(promptTokens)
-		calculateQuota += float64(cacheTokens) * cacheRatio
-		calculateQuota += float64(cacheCreationTokens) * cacheCreationRatio
+		// 输入免费的模型输入 token 不计费
+		if !priceData.FreeInput {
+			calculateQuota = float64(promptTokens)
+			calculateQuota += float64(cacheTokens) * cacheRatio
+			calculateQuota += float64(cacheCreationTokens) * cacheCreationRatio
+		}
 		calculateQuota += float64(completionTokens) * completionRatio
 		calculateQuota = calculateQuota * groupRatio * modelRatio
 	} else {
@@ -247,20 +309,50 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
 	}
 
-	quotaDelta := quota - preConsumedQuota
-	if quotaDelta != 0 {
-		err := PostConsumeQuota(relayInfo, quotaDelta, preConsumedQuota, true)
-		if err != nil {
-			common.LogError(ctx, "error consuming token remain quota: "+err.Error())
-		}
+	if _, err := SettleQuota(relayInfo, preConsumedQuota, quota, true); err != nil {
+		common.LogError(ctx, "error consuming token remain quota: "+err.Error())
 	}
 
 	other := GenerateClaudeOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio,
 		cacheTokens, cacheRatio, cacheCreationTokens, cacheCreationRatio, modelPrice)
+	billing := &dto.BillingBreakdown{
+		UsePrice:   priceData.UsePrice,
+		ModelRatio: modelRatio,
+		ModelPrice: modelPrice,
+		GroupRatio: groupRatio,
+		Quota:      quota,
+	}
+	if !priceData.UsePrice {
+		ratio := groupRatio * modelRatio
+		promptRatio := ratio
+		if priceData.FreeInput {
+			promptRatio = 0
+		}
+		billing.AddItem(dto.BillingLineItem{Type: dto.BillingItemPrompt, Quantity: promptTokens, Unit: dto.BillingUnitTokens,
+			Ratio: 1, Quota: float64(promptTokens) * promptRatio})
+		billing.AddItem(dto.BillingLineItem{Type: dto.BillingItemCachedPrompt, Quantity: cacheTokens, Unit: dto.BillingUnitTokens,
+			Ratio: cacheRatio, Quota: float64(cacheTokens) * cacheRatio * promptRatio})
+		billing.AddItem(dto.BillingLineItem{Type: dto.BillingItemCacheCreation, Quantity: cacheCreationTokens, Unit: dto.BillingUnitTokens,
+			Ratio: cacheCreationRatio, Quota: float64(cacheCreationTokens) * cacheCreationRatio * promptRatio})
+		billing.AddItem(dto.BillingLineItem{Type: dto.BillingItemCompletion, Quantity: completionTokens, Unit: dto.BillingUnitTokens,
+			Ratio: completionRatio, Quota: float64(completionTokens) * completionRatio * ratio})
+	} else {
+		billing.AddItem(dto.BillingLineItem{Type: dto.BillingItemRequest, Quantity: 1, Unit: dto.BillingUnitRequests,
+			Price: modelPrice, Quota: modelPrice * common.QuotaPerUnit * groupRatio})
+	}
+	other["billing"] = billing
 	model.RecordConsumeLog(ctx, relayInfo.UserId, relayInfo.ChannelId, promptTokens, completionTokens, modelName,
 		tokenName, quota, logContent, relayInfo.TokenId, userQuota, int(useTimeSeconds), relayInfo.IsStream, relayInfo.Group, other)
 }
 
+// IsAudioUsage 请求包含音频输入或要求音频输出，或上游返回了音频 token 时按音频倍率计费
+func IsAudioUsage(relayInfo *relaycommon.RelayInfo, usage *dto.Usage) bool {
+	if relayInfo.AudioUsage || strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
+		return true
+	}
+	return usage != nil && (usage.PromptTokensDetails.AudioTokens > 0 || usage.CompletionTokenDetails.AudioTokens > 0)
+}
+
 func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	usage *dto.Usage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
 
@@ -270,6 +362,15 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 
 	audioInputTokens := usage.PromptTokensDetails.AudioTokens
 	audioOutTokens := usage.CompletionTokenDetails.AudioTokens
+	// 上游未返回文本 token 明细时，按总数减去音频 token 计算
+	if textInputTokens == 0 && usage.PromptTokens > audioInputTokens {
+		textInputTokens = usage.PromptTokens - audioInputTokens
+	}
+	if textOutTokens == 0 && usage.CompletionTokens > audioOutTokens {
+		textOutTokens = usage.CompletionTokens - audioOutTokens
+	}
+	// 缓存命中的 token 按文本输入的缓存倍率计费
+	cachedTokens := min(usage.PromptTokensDetails.CachedTokens, textInputTokens)
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(operation_setting.GetCompletionRatio(relayInfo.OriginModelName))
@@ -283,8 +384,9 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 
 	quotaInfo := QuotaInfo{
 		InputDetails: TokenDetails{
-			TextTokens:  textInputTokens,
-			AudioTokens: audioInputTokens,
+			TextTokens:   textInputTokens,
+			AudioTokens:  audioInputTokens,
+			CachedTokens: cachedTokens,
 		},
 		OutputDetails: TokenDetails{
 			TextTokens:  textOutTokens,
@@ -294,6 +396,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		UsePrice:   usePrice,
 		ModelRatio: modelRatio,
 		GroupRatio: groupRatio,
+		CacheRatio: priceData.CacheRatio,
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -320,12 +423,8 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
 	}
 
-	quotaDelta := quota - preConsumedQuota
-	if quotaDelta != 0 {
-		err := PostConsumeQuota(relayInfo, quotaDelta, preConsumedQuota, true)
-		if err != nil {
-			common.LogError(ctx, "error consuming token remain quota: "+err.Error())
-		}
+	if _, err := SettleQuota(relayInfo, preConsumedQuota, quota, true); err != nil {
+		common.LogError(ctx, "error consuming token remain quota: "+err.Error())
 	}
 
 	logModel := relayInfo.OriginModelName
@@ -334,6 +433,14 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	}
 	other := GenerateAudioOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice)
+	if cachedTokens > 0 {
+		other["cache_tokens"] = cachedTokens
+		other["cache_ratio"] = priceData.CacheRatio
+	}
+	if reasoningTokens := usage.CompletionTokenDetails.ReasoningTokens; reasoningTokens > 0 {
+		other["reasoning_tokens"] = reasoningTokens
+	}
+	other["billing"] = audioBillingBreakdown(quotaInfo, quota)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, relayInfo.ChannelId, usage.PromptTokens, usage.CompletionTokens, logModel,
 		tokenName, quota, logContent, relayInfo.TokenId, userQuota, int(useTimeSeconds), relayInfo.IsStream, relayInfo.Group, other)
 }
@@ -359,6 +466,7 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	if err != nil {
 		return err
 	}
+	RecordTokenSpend(relayInfo.TokenId, quota)
 	return nil
 }
 
@@ -382,6 +490,7 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		if err != nil {
 			return err
 		}
+		RecordTokenSpend(relayInfo.TokenId, quota)
 	}
 
 	if sendEmail {
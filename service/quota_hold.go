@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 额度分两个阶段扣除：请求开始时按预估额度预扣（HoldQuota），请求结束时按实际额度结算（SettleQuota），多退少补。
+// 请求开始时预扣的额度在请求结束时结算，结算前记录在内存中，便于查看被占用的额度；
+// 请求异常中断导致预扣额度长时间未结算时，管理员可以手动释放，超过 QUOTA_HOLD_EXPIRE_MINUTES 的预扣会自动退还。
+// 已释放的预扣在请求结算时不再抵扣，按实际用量全额扣费。预扣记录只保存在处理请求的节点上
+
+type QuotaHold struct {
+	RequestId string `json:"request_id"`
+	UserId    int    `json:"user_id"`
+	TokenId   int    `json:"token_id"`
+	ModelName string `json:"model_name"`
+	Quota     int    `json:"quota"`
+	CreatedAt int64  `json:"created_at"`
+	Age       int64  `json:"age"`
+
+	tokenKey     string
+	isPlayground bool
+}
+
+var quotaHolds = make(map[string]*QuotaHold)
+var quotaHoldsLock sync.Mutex
+
+// AddQuotaHold 记录请求预扣的额度，请求 id 为空时不记录
+func AddQuotaHold(relayInfo *relaycommon.RelayInfo, quota int) {
+	if relayInfo.RequestId == "" || quota <= 0 {
+		return
+	}
+	quotaHoldsLock.Lock()
+	defer quotaHoldsLock.Unlock()
+	quotaHolds[relayInfo.RequestId] = &QuotaHold{
+		RequestId:    relayInfo.RequestId,
+		UserId:       relayInfo.UserId,
+		TokenId:      relayInfo.TokenId,
+		ModelName:    relayInfo.OriginModelName,
+		Quota:        quota,
+		CreatedAt:    common.GetTimestamp(),
+		tokenKey:     relayInfo.TokenKey,
+		isPlayground: relayInfo.IsPlayground,
+	}
+}
+
+// HoldQuota 从令牌和用户额度中预扣预估的额度并记录，用户额度扣除失败时退还已扣除的令牌额度
+func HoldQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	err := PreConsumeTokenQuota(relayInfo, quota)
+	if err != nil {
+		return err
+	}
+	err = model.DecreaseUserQuota(relayInfo.UserId, quota)
+	if err != nil {
+		if !relayInfo.IsPlayground {
+			if rollbackErr := model.IncreaseTokenQuota(relayInfo.TokenId, relayInfo.TokenKey, quota); rollbackErr != nil {
+				common.SysError("error rollback token quota: " + rollbackErr.Error())
+			} else {
+				RecordTokenSpend(relayInfo.TokenId, -quota)
+			}
+		}
+		return err
+	}
+	AddQuotaHold(relayInfo, quota)
+	return nil
+}
+
+// SettleQuota 按实际额度结算预扣，返回结算时补扣（正数）或退还（负数）的额度。
+// 预扣已被释放（手动释放或超时退还）时按实际额度全额扣除，补扣的额度不超过用户剩余的可用额度
+// 只退还预扣时 sendEmail 传 false，不发送额度提醒
+func SettleQuota(relayInfo *relaycommon.RelayInfo, preConsumedQuota int, quota int, sendEmail bool) (int, error) {
+	quotaDelta := quota - SettleQuotaHold(relayInfo, preConsumedQuota)
+	if quotaDelta == 0 {
+		return 0, nil
+	}
+	if quotaDelta > 0 {
+		quotaDelta = capQuotaDelta(relayInfo, quotaDelta)
+		if quotaDelta == 0 {
+			return 0, nil
+		}
+	}
+	return quotaDelta, PostConsumeQuota(relayInfo, quotaDelta, preConsumedQuota, sendEmail)
+}
+
+// capQuotaDelta 补扣的额度最多为用户剩余的可用额度，超出预扣和剩余额度的部分不再扣除，避免余额变为负数
+func capQuotaDelta(relayInfo *relaycommon.RelayInfo, quotaDelta int) int {
+	spendable, err := model.GetUserSpendableQuota(relayInfo.UserId)
+	if err != nil || spendable >= quotaDelta {
+		return quotaDelta
+	}
+	capped := max(spendable, 0)
+	logContent := fmt.Sprintf("模型 %s 的实际消耗超出预扣和剩余额度，%s 未扣除", relayInfo.OriginModelName, common.LogQuota(quotaDelta-capped))
+	model.RecordLog(relayInfo.UserId, model.LogTypeSystem, logContent)
+	return capped
+}
+
+// RemoveQuotaHold 请求结束时移除预扣记录，返回预扣是否仍有效，已被释放的预扣返回 false
+func RemoveQuotaHold(requestId string) bool {
+	quotaHoldsLock.Lock()
+	defer quotaHoldsLock.Unlock()
+	if _, ok := quotaHolds[requestId]; !ok {
+		return false
+	}
+	delete(quotaHolds, requestId)
+	return true
+}
+
+// SettleQuotaHold 请求结算时调用，返回结算时应抵扣的预扣额度
+func SettleQuotaHold(relayInfo *relaycommon.RelayInfo, preConsumedQuota int) int {
+	if preConsumedQuota == 0 || relayInfo.RequestId == "" {
+		return preConsumedQuota
+	}
+	if RemoveQuotaHold(relayInfo.RequestId) {
+		return preConsumedQuota
+	}
+	return 0
+}
+
+// GetQuotaHolds 按预扣时间排序返回预扣记录，userId、tokenId 为 0 时不过滤
+func GetQuotaHolds(userId int, tokenId int) []*QuotaHold {
+	now := common.GetTimestamp()
+	quotaHoldsLock.Lock()
+	defer quotaHoldsLock.Unlock()
+	holds := make([]*QuotaHold, 0)
+	for _, hold := range quotaHolds {
+		if userId != 0 && hold.UserId != userId {
+			continue
+		}
+		if tokenId != 0 && hold.TokenId != tokenId {
+			continue
+		}
+		copied := *hold
+		copied.Age = now - hold.CreatedAt
+		holds = append(holds, &copied)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		return holds[i].CreatedAt < holds[j].CreatedAt
+	})
+	return holds
+}
+
+// ReleaseQuotaHold 退还预扣的额度并移除记录
+func ReleaseQuotaHold(requestId string, reason string) error {
+	quotaHoldsLock.Lock()
+	hold, ok := quotaHolds[requestId]
+	if ok {
+		delete(quotaHolds, requestId)
+	}
+	quotaHoldsLock.Unlock()
+	if !ok {
+		return errors.New("预扣记录不存在或已结算")
+	}
+	relayInfo := &relaycommon.RelayInfo{
+		UserId:       hold.UserId,
+		TokenId:      hold.TokenId,
+		TokenKey:     hold.tokenKey,
+		IsPlayground: hold.isPlayground,
+	}
+	err := PostConsumeQuota(relayInfo, -hold.Quota, 0, false)
+	if err != nil {
+		return err
+	}
+	logContent := fmt.Sprintf("%s，请求 %s 预扣的 %s 已退还", reason, hold.RequestId, common.LogQuota(hold.Quota))
+	model.RecordLog(hold.UserId, model.LogTypeSystem, logContent)
+	return nil
+}
+
+// AutomaticallyExpireQuotaHolds 定期退还超时未结算的预扣额度
+func AutomaticallyExpireQuotaHolds() {
+	if constant.QuotaHoldExpireMinutes <= 0 {
+		return
+	}
+	expireSeconds := int64(constant.QuotaHoldExpireMinutes * 60)
+	for {
+		time.Sleep(time.Minute)
+		for _, hold := range GetQuotaHolds(0, 0) {
+			if hold.Age < expireSeconds {
+				continue
+			}
+			err := ReleaseQuotaHold(hold.RequestId, "预扣额度超时未结算")
+			if err != nil {
+				common.SysError(fmt.Sprintf("expire quota hold %s failed: %s", hold.RequestId, err.Error()))
+				continue
+			}
+			common.SysLog(fmt.Sprintf("quota hold %s of user %d expired, %d quota returned", hold.RequestId, hold.UserId, hold.Quota))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"time"
+)
+
+// 预发布环境从生产环境导入渠道定义作为只读的影子渠道，密钥通过密钥管理引用，
+// 影子渠道可以在预发布环境中测试，但状态只跟随生产环境同步，不会被自动启用、禁用
+
+const shadowSourcePageSize = 100
+
+type ShadowSyncResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+}
+
+func ShadowSyncEnabled() bool {
+	return constant.DeploymentEnvironment != "production" && constant.ShadowSourceAddress != ""
+}
+
+func fetchSourceChannels() ([]*model.Channel, error) {
+	channels := make([]*model.Channel, 0)
+	for p := 0; ; p++ {
+		url := fmt.Sprintf("%s/api/channel/?p=%d&page_size=%d&id_sort=true", constant.ShadowSourceAddress, p, shadowSourcePageSize)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", constant.ShadowSourceAccessToken)
+		req.Header.Set("New-Api-User", constant.ShadowSourceUserId)
+		resp, err := GetHttpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Success bool             `json:"success"`
+			Message string           `json:"message"`
+			Data    []*model.Channel `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode source channels failed: %w", err)
+		}
+		if !response.Success {
+			return nil, fmt.Errorf("fetch source channels failed: %s", response.Message)
+		}
+		channels = append(channels, response.Data...)
+		if len(response.Data) < shadowSourcePageSize {
+			return channels, nil
+		}
+	}
+}
+
+// copyChannelDefinition 只复制渠道定义，不复制密钥、用量、余额、响应时间等统计信息
+func copyChannelDefinition(dst *model.Channel, src *model.Channel) {
+	dst.Type = src.Type
+	dst.OpenAIOrganization = src.OpenAIOrganization
+	dst.TestModel = src.TestModel
+	dst.Status = src.Status
+	dst.Name = src.Name
+	dst.Weight = src.Weight
+	dst.BaseURL = src.BaseURL
+	dst.Other = src.Other
+	dst.Models = src.Models
+	dst.Group = src.Group
+	dst.ModelMapping = src.ModelMapping
+	dst.StatusCodeMapping = src.StatusCodeMapping
+	dst.Priority = src.Priority
+	dst.AutoBan = src.AutoBan
+	dst.Setting = src.Setting
+	dst.ParamOverride = src.ParamOverride
+	dst.ShadowSourceId = src.Id
+	dst.Key = model.ShadowChannelKeyRef(src.Id)
+}
+
+func SyncShadowChannels() (*ShadowSyncResult, error) {
+	if !ShadowSyncEnabled() {
+		return nil, errors.New("当前环境未开启影子渠道同步，需要设置 DEPLOYMENT_ENVIRONMENT 和 SHADOW_SOURCE_ADDRESS")
+	}
+	sourceChannels, err := fetchSourceChannels()
+	if err != nil {
+		return nil, err
+	}
+	shadowChannels, err := model.GetShadowChannels()
+	if err != nil {
+		return nil, err
+	}
+	shadowBySource := make(map[int]*model.Channel, len(shadowChannels))
+	for _, channel := range shadowChannels {
+		shadowBySource[channel.ShadowSourceId] = channel
+	}
+
+	result := &ShadowSyncResult{}
+	for _, source := range sourceChannels {
+		// 生产环境中的影子渠道不会再被导入
+		if source.IsShadow() {
+			continue
+		}
+		shadow, ok := shadowBySource[source.Id]
+		if !ok {
+			shadow = &model.Channel{CreatedTime: common.GetTimestamp()}
+			copyChannelDefinition(shadow, source)
+			err = shadow.Insert()
+			if err != nil {
+				return result, fmt.Errorf("create shadow of channel #%d failed: %w", source.Id, err)
+			}
+			result.Created++
+			continue
+		}
+		delete(shadowBySource, source.Id)
+		copyChannelDefinition(shadow, source)
+		err = shadow.Save()
+		if err == nil {
+			err = shadow.UpdateAbilities(nil)
+		}
+		if err != nil {
+			return result, fmt.Errorf("update shadow of channel #%d failed: %w", source.Id, err)
+		}
+		result.Updated++
+	}
+	// 生产环境中已删除的渠道
+	for _, shadow := range shadowBySource {
+		err = shadow.Delete()
+		if err != nil {
+			return result, fmt.Errorf("remove shadow channel #%d failed: %w", shadow.Id, err)
+		}
+		result.Removed++
+	}
+	if common.MemoryCacheEnabled {
+		model.InitChannelCache()
+	}
+	return result, nil
+}
+
+func AutomaticallySyncShadowChannels(frequency int) {
+	for {
+		result, err := SyncShadowChannels()
+		if err != nil {
+			common.SysError("sync shadow channels failed: " + err.Error())
+		} else {
+			common.SysLog(fmt.Sprintf("shadow channels synced, created %d, updated %d, removed %d", result.Created, result.Updated, result.Removed))
+		}
+		time.Sleep(time.Duration(frequency) * time.Minute)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"one-api/dto"
+	"sort"
+	"strings"
+)
+
+// 客户端请求非流式响应而渠道以流式调用上游时，把流式数据块合并为 chat.completion 响应
+
+type aggregateChoice struct {
+	content          strings.Builder
+	reasoningContent strings.Builder
+	toolCalls        []dto.ToolCallResponse
+	finishReason     string
+}
+
+type ChatStreamAggregator struct {
+	response       dto.OpenAITextResponse
+	choices        map[int]*aggregateChoice
+	usage          *dto.Usage
+	toolCallMerger *ToolCallDeltaMerger
+}
+
+func NewChatStreamAggregator() *ChatStreamAggregator {
+	return &ChatStreamAggregator{choices: make(map[int]*aggregateChoice), toolCallMerger: NewToolCallDeltaMerger()}
+}
+
+// Add 合并一个数据块，工具调用增量先按与流式转发相同的规则改写，再按序号合并参数
+func (a *ChatStreamAggregator) Add(chunk *dto.ChatCompletionsStreamResponse) {
+	if a.response.Id == "" {
+		a.response.Id = chunk.Id
+		a.response.Created = chunk.Created
+	}
+	if chunk.Model != "" {
+		a.response.Model = chunk.Model
+	}
+	if chunk.SystemFingerprint != nil && *chunk.SystemFingerprint != "" {
+		a.response.SystemFingerprint = *chunk.SystemFingerprint
+	}
+	if chunk.Usage != nil {
+		a.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		aggregated, ok := a.choices[choice.Index]
+		if !ok {
+			aggregated = &aggregateChoice{}
+			a.choices[choice.Index] = aggregated
+		}
+		aggregated.content.WriteString(choice.Delta.GetContentString())
+		aggregated.reasoningContent.WriteString(choice.Delta.GetReasoningContent())
+		if len(choice.Delta.ToolCalls) > 0 {
+			// 改写会修改 tool_calls 切片，使用副本避免影响调用方的数据块
+			choice.Delta.ToolCalls = append([]dto.ToolCallResponse(nil), choice.Delta.ToolCalls...)
+			a.toolCallMerger.MergeDelta(&choice)
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			index := *toolCall.Index
+			for len(aggregated.toolCalls) <= index {
+				aggregated.toolCalls = append(aggregated.toolCalls, dto.ToolCallResponse{Type: "function"})
+			}
+			merged := &aggregated.toolCalls[index]
+			if toolCall.ID != "" {
+				merged.ID = toolCall.ID
+			}
+			if toolCall.Type != nil && toolCall.Type != "" {
+				merged.Type = toolCall.Type
+			}
+			if toolCall.Function.Name != "" {
+				merged.Function.Name = toolCall.Function.Name
+			}
+			merged.Function.Arguments += toolCall.Function.Arguments
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			aggregated.finishReason = *choice.FinishReason
+		}
+	}
+}
+
+// Response 返回合并后的响应，usage 不为空时优先使用
+func (a *ChatStreamAggregator) Response(usage *dto.Usage) *dto.OpenAITextResponse {
+	response := a.response
+	response.Object = "chat.completion"
+	if usage != nil {
+		response.Usage = *usage
+	} else if a.usage != nil {
+		response.Usage = *a.usage
+	}
+	indexes := make([]int, 0, len(a.choices))
+	for index := range a.choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	response.Choices = make([]dto.OpenAITextResponseChoice, 0, len(indexes))
+	for _, index := range indexes {
+		aggregated := a.choices[index]
+		message := dto.Message{Role: "assistant", ReasoningContent: aggregated.reasoningContent.String()}
+		message.SetStringContent(aggregated.content.String())
+		if len(aggregated.toolCalls) > 0 {
+			message.SetToolCalls(aggregated.toolCalls)
+		}
+		response.Choices = append(response.Choices, dto.OpenAITextResponseChoice{
+			Index:        index,
+			Message:      message,
+			FinishReason: aggregated.finishReason,
+		})
+	}
+	return &response
+}
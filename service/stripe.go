@@ -0,0 +1,170 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"one-api/setting"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 直接调用 Stripe API 创建 Checkout Session，并按 Stripe 的签名规则校验 Webhook
+
+const stripeApiBase = "https://api.stripe.com/v1"
+
+// stripeWebhookTolerance 允许的 Webhook 签名时间偏差，超过则视为重放
+const stripeWebhookTolerance = 5 * time.Minute
+
+// stripeZeroDecimalCurrencies 金额不带小数位的币种，其余币种以最小货币单位（分）计价
+var stripeZeroDecimalCurrencies = map[string]bool{
+	"bif": true, "clp": true, "djf": true, "gnf": true, "jpy": true, "kmf": true, "krw": true, "mga": true,
+	"pyg": true, "rwf": true, "ugx": true, "vnd": true, "vuv": true, "xaf": true, "xof": true, "xpf": true,
+}
+
+type StripeCheckoutSession struct {
+	Id                string            `json:"id"`
+	Url               string            `json:"url"`
+	ClientReferenceId string            `json:"client_reference_id"`
+	PaymentStatus     string            `json:"payment_status"`
+	PaymentIntent     string            `json:"payment_intent"`
+	AmountTotal       int64             `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+type StripeCharge struct {
+	Id             string `json:"id"`
+	PaymentIntent  string `json:"payment_intent"`
+	Amount         int64  `json:"amount"`
+	AmountRefunded int64  `json:"amount_refunded"`
+	Refunded       bool   `json:"refunded"`
+}
+
+type StripeEvent struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func StripeEnabled() bool {
+	return setting.StripeApiSecret != "" && setting.StripeWebhookSecret != ""
+}
+
+// StripeAmount 将金额换算为 Stripe 使用的最小货币单位
+func StripeAmount(money float64, currency string) int64 {
+	if stripeZeroDecimalCurrencies[strings.ToLower(currency)] {
+		return int64(math.Round(money))
+	}
+	return int64(math.Round(money * 100))
+}
+
+// CreateStripeCheckoutSession 创建一次性支付的 Checkout Session，tradeNo 同时写入 client_reference_id 和 metadata
+func CreateStripeCheckoutSession(tradeNo string, name string, money float64, successUrl string, cancelUrl string) (*StripeCheckoutSession, error) {
+	currency := strings.ToLower(setting.StripeCurrency)
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", successUrl)
+	form.Set("cancel_url", cancelUrl)
+	form.Set("client_reference_id", tradeNo)
+	form.Set("metadata[trade_no]", tradeNo)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(StripeAmount(money, currency), 10))
+	form.Set("line_items[0][price_data][product_data][name]", name)
+
+	req, err := http.NewRequest(http.MethodPost, stripeApiBase+"/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(setting.StripeApiSecret, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// 以订单号作为幂等键，避免网络重试时重复创建
+	req.Header.Set("Idempotency-Key", tradeNo)
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errResp stripeErrorResponse
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, errors.New(errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe returned status %d", resp.StatusCode)
+	}
+	var session StripeCheckoutSession
+	if err = json.Unmarshal(body, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ConstructStripeEvent 校验 Stripe-Signature 头并解析事件，签名格式为 t=<时间戳>,v1=<签名>[,v1=<签名>]
+func ConstructStripeEvent(payload []byte, signatureHeader string, secret string) (*StripeEvent, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return nil, errors.New("invalid stripe signature header")
+	}
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid stripe signature timestamp")
+	}
+	if diff := time.Since(time.Unix(t, 0)); diff > stripeWebhookTolerance || diff < -stripeWebhookTolerance {
+		return nil, errors.New("stripe signature timestamp outside tolerance")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	matched := false
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil, errors.New("stripe signature mismatch")
+	}
+	var event StripeEvent
+	if err = json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"one-api/dto"
+)
+
+// 不支持 json_schema 的上游通过 JSON 模式和系统提示实现结构化输出
+
+// IsStructuredOutputRequest 请求要求输出 JSON（json_schema 或 json_object）
+func IsStructuredOutputRequest(request *dto.GeneralOpenAIRequest) bool {
+	if request.ResponseFormat == nil {
+		return false
+	}
+	return request.ResponseFormat.Type == "json_schema" || request.ResponseFormat.Type == "json_object"
+}
+
+// StructuredOutputSchema 返回 json_schema 中的 schema，json_object 或未提供 schema 时返回 nil
+func StructuredOutputSchema(request *dto.GeneralOpenAIRequest) any {
+	if request.ResponseFormat == nil || request.ResponseFormat.JsonSchema == nil {
+		return nil
+	}
+	return request.ResponseFormat.JsonSchema.Schema
+}
+
+// StructuredOutputInstruction 要求模型只输出 JSON 的系统提示，提供了 schema 时附上 schema
+func StructuredOutputInstruction(request *dto.GeneralOpenAIRequest) string {
+	instruction := "Respond only with a valid JSON object, without markdown code fences or any other text."
+	schema := StructuredOutputSchema(request)
+	if schema == nil {
+		return instruction
+	}
+	schemaJson, err := json.Marshal(schema)
+	if err != nil {
+		return instruction
+	}
+	return instruction + " The JSON object must conform to this JSON Schema:\n" + string(schemaJson)
+}
+
+// AppendSystemInstruction 把提示追加到第一条系统消息，没有系统消息时在开头插入一条，不修改原消息
+func AppendSystemInstruction(request *dto.GeneralOpenAIRequest, instruction string) {
+	messages := make([]dto.Message, 0, len(request.Messages)+1)
+	if len(request.Messages) > 0 && (request.Messages[0].Role == "system" || request.Messages[0].Role == "developer") {
+		system := dto.Message{Role: request.Messages[0].Role}
+		content := request.Messages[0].StringContent()
+		if content != "" {
+			content += "\n\n"
+		}
+		system.SetStringContent(content + instruction)
+		messages = append(messages, system)
+		messages = append(messages, request.Messages[1:]...)
+	} else {
+		system := dto.Message{Role: "system"}
+		system.SetStringContent(instruction)
+		messages = append(messages, system)
+		messages = append(messages, request.Messages...)
+	}
+	request.Messages = messages
+}
+
+// ApplyJsonModeFallback 把 json_schema 降级为 json_object，schema 写入系统提示
+func ApplyJsonModeFallback(request *dto.GeneralOpenAIRequest) {
+	if request.ResponseFormat == nil || request.ResponseFormat.Type != "json_schema" {
+		return
+	}
+	AppendSystemInstruction(request, StructuredOutputInstruction(request))
+	request.ResponseFormat = &dto.ResponseFormat{Type: "json_object"}
+}
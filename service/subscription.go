@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting"
+	"time"
+)
+
+// AutomaticallyRenewSubscriptions 定期为到达续期时间的订阅发放下一个周期的额度，到期或套餐已删除的订阅自动取消
+func AutomaticallyRenewSubscriptions() {
+	for {
+		time.Sleep(time.Minute)
+		subs, err := model.GetDueSubscriptions(common.GetTimestamp())
+		if err != nil {
+			common.SysError("failed to get due subscriptions: " + err.Error())
+			continue
+		}
+		for _, sub := range subs {
+			if err := renewSubscription(sub); err != nil {
+				common.SysError(fmt.Sprintf("failed to renew subscription of user %d: %s", sub.UserId, err.Error()))
+			}
+		}
+	}
+}
+
+func renewSubscription(sub *model.UserSubscription) error {
+	plan, ok := setting.GetSubscriptionPlan(sub.Plan)
+	if !ok || (sub.ExpiredTime != 0 && sub.ExpiredTime <= sub.RenewTime) {
+		if err := model.ExpireSubscription(sub); err != nil {
+			return err
+		}
+		model.RecordLog(sub.UserId, model.LogTypeSystem, fmt.Sprintf("订阅套餐 %s 已到期", sub.Plan))
+		return nil
+	}
+	renewed, err := model.RenewSubscription(sub, plan.Quota)
+	if err != nil || !renewed {
+		return err
+	}
+	model.RecordLog(sub.UserId, model.LogTypeSystem, fmt.Sprintf("订阅套餐 %s 进入新周期，发放额度 %s", sub.Plan, common.LogQuota(plan.Quota)))
+	return nil
+}
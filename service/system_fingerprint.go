@@ -0,0 +1,47 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/dto"
+	"one-api/setting/model_setting"
+	"strconv"
+	"strings"
+)
+
+const systemFingerprintChannelPrefix = "ch_"
+
+// ChannelFingerprintTag 渠道的短哈希，使用 CryptoSecret 计算，客户端无法据此推出渠道 ID
+func ChannelFingerprintTag(channelId int) string {
+	return systemFingerprintChannelPrefix + common.GenerateHMAC("channel:" + strconv.Itoa(channelId))[:8]
+}
+
+// TagSystemFingerprint 开启渠道标记时在 system_fingerprint 后附加渠道哈希，上游未返回时只返回渠道哈希
+func TagSystemFingerprint(fingerprint string, channelId int) string {
+	if !model_setting.GetGlobalSettings().SystemFingerprintChannelTagEnabled {
+		return fingerprint
+	}
+	tag := ChannelFingerprintTag(channelId)
+	if fingerprint == "" {
+		return tag
+	}
+	return fingerprint + "+" + tag
+}
+
+// ParseChannelFingerprintTag 从 system_fingerprint 中取出渠道哈希，也接受单独的渠道哈希
+func ParseChannelFingerprintTag(fingerprint string) string {
+	if i := strings.LastIndex(fingerprint, "+"); i >= 0 {
+		fingerprint = fingerprint[i+1:]
+	}
+	if !strings.HasPrefix(fingerprint, systemFingerprintChannelPrefix) {
+		fingerprint = systemFingerprintChannelPrefix + fingerprint
+	}
+	return fingerprint
+}
+
+// TagStreamSystemFingerprint 开启渠道标记时为流式响应块附加渠道哈希
+func TagStreamSystemFingerprint(response *dto.ChatCompletionsStreamResponse, channelId int) {
+	if !model_setting.GetGlobalSettings().SystemFingerprintChannelTagEnabled {
+		return
+	}
+	response.SetSystemFingerprint(TagSystemFingerprint(response.GetSystemFingerprint(), channelId))
+}
@@ -1,10 +1,306 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"one-api/common"
 	"one-api/constant"
+	"one-api/dto"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/setting"
 	"strings"
+
+	"github.com/bytedance/gopkg/util/gopool"
 )
 
 func CoverTaskActionToModelName(platform constant.TaskPlatform, action string) string {
 	return strings.ToLower(string(platform)) + "_" + strings.ToLower(action)
 }
+
+func TaskModel2Dto(task *model.Task) *dto.TaskDto {
+	return &dto.TaskDto{
+		TaskID:     task.TaskID,
+		Action:     task.Action,
+		Status:     string(task.Status),
+		FailReason: task.FailReason,
+		SubmitTime: task.SubmitTime,
+		StartTime:  task.StartTime,
+		FinishTime: task.FinishTime,
+		Progress:   task.Progress,
+		Data:       task.Data,
+	}
+}
+
+// RefundTaskQuota 异步任务失败时退还提交时扣除的额度，tokenId 不为 0 时同时退还令牌额度。
+// 退还记录为负数的消费日志，与提交时的消费日志抵消，用量统计中的消费合计即实际消耗
+func RefundTaskQuota(ctx context.Context, userId int, tokenId int, channelId int, modelName string, quota int, reason string) {
+	if quota == 0 {
+		return
+	}
+	err := PostConsumeQuota(taskRelayInfo(userId, tokenId), -quota, 0, false)
+	if err != nil {
+		common.LogError(ctx, "fail to increase user quota: "+err.Error())
+		return
+	}
+	logContent := fmt.Sprintf("%s，补偿 %s", reason, common.LogQuota(quota))
+	model.RecordTaskConsumeLog(ctx, userId, channelId, 0, 0, modelName, tokenId, -quota, logContent, "", nil)
+}
+
+// taskRelayInfo 还原提交任务的用户和令牌，后台结算时与请求内结算一样同步调整用户和令牌的额度，
+// 令牌已删除时只调整用户额度
+func taskRelayInfo(userId int, tokenId int) *relaycommon.RelayInfo {
+	relayInfo := &relaycommon.RelayInfo{
+		UserId:       userId,
+		TokenId:      tokenId,
+		IsPlayground: true,
+	}
+	if tokenId != 0 {
+		if key, err := model.GetTokenKeyById(tokenId); err == nil {
+			relayInfo.TokenKey = key
+			relayInfo.IsPlayground = false
+		}
+	}
+	return relayInfo
+}
+
+func taskNeedUpdate(task *model.Task, result dto.TaskResult) bool {
+	if result.Status != "" && string(task.Status) != result.Status {
+		return true
+	}
+	if result.Progress != "" && task.Progress != result.Progress {
+		return true
+	}
+	if task.FailReason != result.FailReason && result.FailReason != "" {
+		return true
+	}
+	if task.SubmitTime != result.SubmitTime || task.StartTime != result.StartTime || task.FinishTime != result.FinishTime {
+		return true
+	}
+	if task.Status.IsFinal() && task.Progress != "100%" {
+		return true
+	}
+	return len(result.Data) > 0 && !bytes.Equal(task.Data, result.Data)
+}
+
+// UpdateTaskByResult 按任务状态机写回上游查询到的结果，任务进入终态时退还失败任务的额度并通知回调地址
+func UpdateTaskByResult(ctx context.Context, task *model.Task, result dto.TaskResult) error {
+	if task.Platform == constant.TaskPlatformBatch && len(result.Data) > 0 {
+		if err := storeBatchResultFiles(task, &result); err != nil {
+			return err
+		}
+	}
+	if !taskNeedUpdate(task, result) {
+		return nil
+	}
+	wasFinal := task.Status.IsFinal()
+	next := model.TaskStatus(result.Status)
+	if task.Status.CanTransitionTo(next) {
+		task.Status = next
+	}
+	// 上游返回了失败原因时按失败处理
+	if result.FailReason != "" && !wasFinal {
+		task.FailReason = result.FailReason
+		task.Status = model.TaskStatusFailure
+	}
+	if result.Progress != "" {
+		task.Progress = result.Progress
+	}
+	if result.SubmitTime != 0 {
+		task.SubmitTime = result.SubmitTime
+	}
+	if result.StartTime != 0 {
+		task.StartTime = result.StartTime
+	}
+	if result.FinishTime != 0 {
+		task.FinishTime = result.FinishTime
+	}
+	if len(result.Data) > 0 {
+		task.Data = result.Data
+	}
+	err := saveTask(ctx, task, wasFinal)
+	if err != nil {
+		return err
+	}
+	if !wasFinal && task.Status == model.TaskStatusSuccess && result.Usage != nil {
+		settleTaskQuota(ctx, task, result.Usage)
+	}
+	return nil
+}
+
+// storeBatchResultFiles 批量任务的结果文件保存为用户文件，不写入任务数据；已结束的任务不再重复保存
+func storeBatchResultFiles(task *model.Task, result *dto.TaskResult) error {
+	if task.Status.IsFinal() {
+		result.Data = nil
+		return nil
+	}
+	var data dto.BatchTaskData
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return err
+	}
+	if data.Output == "" && data.ErrorOutput == "" {
+		return nil
+	}
+	if err := StoreBatchOutputFiles(task, &data); err != nil {
+		return err
+	}
+	var err error
+	result.Data, err = json.Marshal(data)
+	return err
+}
+
+// settleTaskQuota 按量计费的任务成功后按实际用量结算，多退少补提交时预扣的额度，
+// 用户和渠道的已用额度在结算时按实际消耗累加
+func settleTaskQuota(ctx context.Context, task *model.Task, usage *dto.Usage) {
+	billing := task.Properties.Billing
+	if billing == nil {
+		return
+	}
+	ratio := billing.ModelRatio * billing.GroupRatio * billing.Discount
+	cacheTokens := usage.PromptTokensDetails.CachedTokens
+	cacheCreationTokens := usage.PromptTokensDetails.CachedCreationTokens
+	promptTokens := float64(usage.PromptTokens)
+	if billing.CacheRatio != 0 {
+		promptTokens -= float64(cacheTokens) * (1 - billing.CacheRatio)
+	}
+	if billing.CacheCreationRatio != 0 {
+		promptTokens -= float64(cacheCreationTokens) * (1 - billing.CacheCreationRatio)
+	}
+	if billing.FreeInput {
+		promptTokens = 0
+	}
+	quota := int((promptTokens + float64(usage.CompletionTokens)*billing.CompletionRatio) * ratio)
+	if ratio != 0 && quota <= 0 {
+		quota = 1
+	}
+	delta := quota - task.Quota
+	if delta != 0 {
+		err := PostConsumeQuota(taskRelayInfo(task.UserId, task.TokenId), delta, task.Quota, false)
+		if err != nil {
+			common.LogError(ctx, fmt.Sprintf("settle task %s quota failed: %s", task.TaskID, err.Error()))
+			return
+		}
+	}
+	task.Quota = quota
+	if err := task.Update(); err != nil {
+		common.LogError(ctx, fmt.Sprintf("update task %s quota failed: %s", task.TaskID, err.Error()))
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(task.UserId, quota)
+	model.UpdateChannelUsedQuota(task.ChannelId, quota)
+	// 提交时已按预扣额度记录过消费日志，结算日志只记录差额，日志中的消费合计即实际消耗
+	logContent := fmt.Sprintf("异步任务 %s 结算，模型 %s，输入 %d tokens，输出 %d tokens，实际消耗 %s，预扣 %s",
+		task.TaskID, billing.ModelName, usage.PromptTokens, usage.CompletionTokens, common.LogQuota(quota), common.LogQuota(quota-delta))
+	other := map[string]interface{}{
+		"task_id":          task.TaskID,
+		"model_ratio":      billing.ModelRatio,
+		"completion_ratio": billing.CompletionRatio,
+		"group_ratio":      billing.GroupRatio,
+		"pre_consumed":     quota - delta,
+	}
+	if cacheTokens != 0 {
+		other["cache_tokens"] = cacheTokens
+		other["cache_ratio"] = billing.CacheRatio
+	}
+	if cacheCreationTokens != 0 {
+		other["cache_creation_tokens"] = cacheCreationTokens
+		other["cache_creation_ratio"] = billing.CacheCreationRatio
+	}
+	model.RecordTaskConsumeLog(ctx, task.UserId, task.ChannelId, usage.PromptTokens, usage.CompletionTokens,
+		billing.ModelName, task.TokenId, delta, logContent, billing.Group, other)
+}
+
+// refundTaskQuota 任务失败时退还提交时预扣的额度，按计费信息记录为负数的消费日志。
+// 没有上游任务 id 的任务提交时没有扣费，不退还
+func refundTaskQuota(ctx context.Context, task *model.Task) {
+	if task.Quota == 0 || task.TaskID == "" {
+		return
+	}
+	reason := fmt.Sprintf("异步任务执行失败 %s", task.TaskID)
+	if task.Properties.Billing == nil {
+		RefundTaskQuota(ctx, task.UserId, task.TokenId, task.ChannelId, CoverTaskActionToModelName(task.Platform, task.Action), task.Quota, reason)
+		return
+	}
+	err := PostConsumeQuota(taskRelayInfo(task.UserId, task.TokenId), -task.Quota, 0, false)
+	if err != nil {
+		common.LogError(ctx, "fail to increase user quota: "+err.Error())
+		return
+	}
+	billing := task.Properties.Billing
+	logContent := fmt.Sprintf("%s，补偿 %s", reason, common.LogQuota(task.Quota))
+	model.RecordTaskConsumeLog(ctx, task.UserId, task.ChannelId, 0, 0, billing.ModelName, task.TokenId, -task.Quota,
+		logContent, billing.Group, map[string]interface{}{"task_id": task.TaskID})
+}
+
+// FailTask 任务无法继续执行（如渠道已删除）时直接置为失败
+func FailTask(ctx context.Context, task *model.Task, reason string) error {
+	wasFinal := task.Status.IsFinal()
+	task.Status = model.TaskStatusFailure
+	task.FailReason = reason
+	return saveTask(ctx, task, wasFinal)
+}
+
+func saveTask(ctx context.Context, task *model.Task, wasFinal bool) error {
+	finished := !wasFinal && task.Status.IsFinal()
+	if task.Status.IsFinal() {
+		task.Progress = "100%"
+		if task.FinishTime == 0 {
+			task.FinishTime = common.GetTimestamp()
+		}
+	}
+	err := task.Update()
+	if err != nil {
+		return err
+	}
+	if !finished {
+		return nil
+	}
+	if task.Status == model.TaskStatusFailure {
+		common.LogInfo(ctx, task.TaskID+" 构建失败，"+task.FailReason)
+		refundTaskQuota(ctx, task)
+	}
+	if task.Properties.NotifyHook != "" {
+		notifyTaskHook(ctx, task.Properties.NotifyHook, TaskModel2Dto(task))
+	}
+	return nil
+}
+
+// notifyTaskHook 异步推送任务结果，失败只记录日志
+func notifyTaskHook(ctx context.Context, hookURL string, payload *dto.TaskDto) {
+	gopool.Go(func() {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		var resp *http.Response
+		if setting.EnableWorker() {
+			resp, err = DoWorkerRequest(&WorkerRequest{
+				URL:    hookURL,
+				Key:    setting.WorkerValidKey,
+				Method: http.MethodPost,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+				},
+				Body: body,
+			})
+		} else {
+			// 未配置 worker 时直接请求用户提供的地址，只允许公网地址
+			if u, parseErr := url.Parse(hookURL); parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				common.LogError(ctx, fmt.Sprintf("notify task %s failed: invalid notify hook", payload.TaskID))
+				return
+			}
+			resp, err = GetPublicOnlyHttpClient().Post(hookURL, "application/json", bytes.NewReader(body))
+		}
+		if err != nil {
+			common.LogError(ctx, fmt.Sprintf("notify task %s failed: %s", payload.TaskID, err.Error()))
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			common.LogError(ctx, fmt.Sprintf("notify task %s failed with status code: %d", payload.TaskID, resp.StatusCode))
+		}
+	})
+}
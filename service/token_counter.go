@@ -85,6 +85,53 @@ func getTokenNum(tokenEncoder *tiktoken.Tiktoken, text string) int {
 	return len(tokenEncoder.Encode(text, nil, nil))
 }
 
+const (
+	imageTokenRuleOpenAI = iota
+	imageTokenRuleClaude
+	imageTokenRuleGemini
+)
+
+// getImageTokenRule 按上游的规则计算图片 token：Claude 按像素数，Gemini 按 258 token 的切块，其余按 OpenAI 的切块规则
+func getImageTokenRule(info *relaycommon.RelayInfo, model string) int {
+	if info.UpstreamModelName != "" {
+		model = info.UpstreamModelName
+	}
+	switch info.ChannelType {
+	case common.ChannelTypeAnthropic, common.ChannelTypeAws:
+		return imageTokenRuleClaude
+	case common.ChannelTypeGemini:
+		return imageTokenRuleGemini
+	case common.ChannelTypeVertexAi:
+		if strings.HasPrefix(model, "claude") {
+			return imageTokenRuleClaude
+		}
+		if strings.HasPrefix(model, "gemini") {
+			return imageTokenRuleGemini
+		}
+	}
+	return imageTokenRuleOpenAI
+}
+
+// claudeImageTokens 长边超过 1568 像素时等比缩小，token 数为 宽×高/750，最多 1600
+func claudeImageTokens(width int, height int) int {
+	if longSide := max(width, height); longSide > 1568 {
+		scale := 1568 / float64(longSide)
+		width = int(float64(width) * scale)
+		height = int(float64(height) * scale)
+	}
+	return min(int(math.Ceil(float64(width*height)/750)), 1600)
+}
+
+// geminiImageTokens 两边都不超过 384 像素时为 258，否则按短边的 2/3（256 到 768 之间）切块，每块 258
+func geminiImageTokens(width int, height int) int {
+	if width <= 384 && height <= 384 {
+		return 258
+	}
+	tileSize := min(max(min(width, height)*2/3, 256), 768)
+	tiles := ((width + tileSize - 1) / tileSize) * ((height + tileSize - 1) / tileSize)
+	return tiles * 258
+}
+
 func getImageToken(info *relaycommon.RelayInfo, imageUrl *dto.MessageImageUrl, model string, stream bool) (int, error) {
 	if imageUrl == nil {
 		return 0, fmt.Errorf("image_url_is_nil")
@@ -93,7 +140,8 @@ func getImageToken(info *relaycommon.RelayInfo, imageUrl *dto.MessageImageUrl, m
 	if model == "glm-4v" {
 		return 1047, nil
 	}
-	if imageUrl.Detail == "low" {
+	rule := getImageTokenRule(info, model)
+	if imageUrl.Detail == "low" && rule == imageTokenRuleOpenAI {
 		return baseTokens, nil
 	}
 	if !constant.GetMediaTokenNotStream && !stream {
@@ -114,9 +162,6 @@ func getImageToken(info *relaycommon.RelayInfo, imageUrl *dto.MessageImageUrl, m
 	if !constant.GetMediaToken {
 		return 3 * baseTokens, nil
 	}
-	if info.ChannelType == common.ChannelTypeGemini || info.ChannelType == common.ChannelTypeVertexAi || info.ChannelType == common.ChannelTypeAnthropic {
-		return 3 * baseTokens, nil
-	}
 	var config image.Config
 	var err error
 	var format string
@@ -141,6 +186,13 @@ func getImageToken(info *relaycommon.RelayInfo, imageUrl *dto.MessageImageUrl, m
 		return 0, errors.New(fmt.Sprintf("fail to decode base64 config: %s", imageUrl.Url))
 	}
 
+	switch rule {
+	case imageTokenRuleClaude:
+		return claudeImageTokens(config.Width, config.Height), nil
+	case imageTokenRuleGemini:
+		return geminiImageTokens(config.Width, config.Height), nil
+	}
+
 	shortSide := config.Width
 	otherSide := config.Height
 	log.Printf("format: %s, width: %d, height: %d", format, config.Width, config.Height)
@@ -402,8 +454,15 @@ func CountTokenMessages(info *relaycommon.RelayInfo, messages []dto.Message, mod
 					tokenNum += imageTokenNum
 					log.Printf("image token num: %d", imageTokenNum)
 				} else if m.Type == dto.ContentTypeInputAudio {
-					// TODO: 音频token数量计算
-					tokenNum += 100
+					audioTokenNum := 100
+					if inputAudio := m.GetInputAudio(); inputAudio != nil {
+						atk, err := CountAudioTokenInput(inputAudio.Data, inputAudio.Format)
+						if err != nil {
+							return 0, err
+						}
+						audioTokenNum = atk
+					}
+					tokenNum += audioTokenNum
 				} else if m.Type == dto.ContentTypeFile {
 					tokenNum += 5000
 				} else if m.Type == dto.ContentTypeVideoUrl {
@@ -455,12 +514,9 @@ func CountTokenStreamChoices(messages []dto.ChatCompletionsStreamResponseChoice,
 	return tokens
 }
 
+// CountTTSToken 语音合成按输入字符数计费，各模型的单价通过模型倍率配置
 func CountTTSToken(text string, model string) (int, error) {
-	if strings.HasPrefix(model, "tts") {
-		return utf8.RuneCountInString(text), nil
-	} else {
-		return CountTextToken(text, model)
-	}
+	return utf8.RuneCountInString(text), nil
 }
 
 func CountAudioTokenInput(audioBase64 string, audioFormat string) (int, error) {
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"sync"
+	"time"
+)
+
+// 令牌的每日、每月消费上限，与令牌总额度相互独立。消费按令牌配置的时区统计，在当地的零点和每月一日重置。
+// 只统计设置了上限的令牌，上限在分发请求时检查，已达到上限后拒绝新的请求
+
+type tokenSpendEntry struct {
+	// loc 为 nil 表示令牌没有设置消费上限
+	loc      *time.Location
+	expireAt time.Time
+}
+
+// tokenSpendLocations 按令牌 id 缓存令牌是否设置了消费上限及其时区，未缓存时从令牌的设置中读取，
+// 其他节点和后台任务结算的额度也按令牌自己的时区统计
+var tokenSpendLocations sync.Map
+
+const (
+	tokenSpendDayExpiration   = 48 * time.Hour
+	tokenSpendMonthExpiration = 32 * 24 * time.Hour
+)
+
+// LoadTokenSpendLocation 返回令牌消费上限使用的时区，为空时使用服务器时区
+func LoadTokenSpendLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+func loadTokenSpendLocation(tokenId int, dailyLimit int, monthlyLimit int, timezone string) *time.Location {
+	var loc *time.Location
+	if dailyLimit > 0 || monthlyLimit > 0 {
+		var err error
+		loc, err = LoadTokenSpendLocation(timezone)
+		if err != nil {
+			common.SysError(fmt.Sprintf("invalid spend limit timezone %s of token %d: %s", timezone, tokenId, err.Error()))
+			loc = time.Local
+		}
+	}
+	tokenSpendLocations.Store(tokenId, tokenSpendEntry{
+		loc:      loc,
+		expireAt: time.Now().Add(time.Duration(constant.TokenCacheSeconds) * time.Second),
+	})
+	return loc
+}
+
+// getTokenSpendLocation 返回令牌统计消费使用的时区，令牌没有设置消费上限时返回 nil
+func getTokenSpendLocation(tokenId int) *time.Location {
+	if value, ok := tokenSpendLocations.Load(tokenId); ok {
+		entry := value.(tokenSpendEntry)
+		if time.Now().Before(entry.expireAt) {
+			return entry.loc
+		}
+	}
+	token, err := model.GetTokenById(tokenId)
+	if err != nil {
+		return nil
+	}
+	return loadTokenSpendLocation(tokenId, token.DailyQuotaLimit, token.MonthlyQuotaLimit, token.QuotaLimitTimezone)
+}
+
+func tokenSpendKeys(tokenId int, loc *time.Location) (string, string) {
+	now := time.Now().In(loc)
+	return fmt.Sprintf("token_spend:%d:%s", tokenId, now.Format("2006-01-02")),
+		fmt.Sprintf("token_spend:%d:%s", tokenId, now.Format("2006-01"))
+}
+
+// CheckTokenSpendLimit 检查令牌今日、本月的消费是否已达到上限，上限为 0 表示不限制，返回的错误为拒绝请求的原因
+func CheckTokenSpendLimit(tokenId int, dailyLimit int, monthlyLimit int, timezone string) error {
+	loc := loadTokenSpendLocation(tokenId, dailyLimit, monthlyLimit, timezone)
+	if loc == nil {
+		return nil
+	}
+	dayKey, monthKey := tokenSpendKeys(tokenId, loc)
+	if dailyLimit > 0 {
+		spent, err := common.CounterGet(dayKey)
+		if err != nil {
+			// 统计不可用时不拒绝请求
+			common.SysError(fmt.Sprintf("failed to get daily spend of token %d: %s", tokenId, err.Error()))
+		}
+		if spent >= int64(dailyLimit) {
+			return fmt.Errorf("令牌今日消费已达上限 %s", common.LogQuota(dailyLimit))
+		}
+	}
+	if monthlyLimit > 0 {
+		spent, err := common.CounterGet(monthKey)
+		if err != nil {
+			// 统计不可用时不拒绝请求
+			common.SysError(fmt.Sprintf("failed to get monthly spend of token %d: %s", tokenId, err.Error()))
+		}
+		if spent >= int64(monthlyLimit) {
+			return fmt.Errorf("令牌本月消费已达上限 %s", common.LogQuota(monthlyLimit))
+		}
+	}
+	return nil
+}
+
+// RecordTokenSpend 统计令牌的消费，quota 为负数时表示退还
+func RecordTokenSpend(tokenId int, quota int) {
+	if quota == 0 || tokenId == 0 {
+		return
+	}
+	loc := getTokenSpendLocation(tokenId)
+	if loc == nil {
+		return
+	}
+	dayKey, monthKey := tokenSpendKeys(tokenId, loc)
+	for key, expiration := range map[string]time.Duration{dayKey: tokenSpendDayExpiration, monthKey: tokenSpendMonthExpiration} {
+		if _, err := common.CounterIncr(key, int64(quota), expiration); err != nil {
+			common.SysError(fmt.Sprintf("failed to record spend of token %d: %s", tokenId, err.Error()))
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+	"strings"
+)
+
+// 部分上游的流式 tool_calls 增量缺少 index，或者每个数据块都重新发送完整的工具调用，
+// 按 id 和 index 跟踪每个 choice 已发送的工具调用，把增量改写为标准格式：每个片段带有 index，
+// id 和函数名只在第一个片段中出现，arguments 只包含新增的部分
+
+type mergedToolCall struct {
+	id        string
+	name      string
+	arguments string
+}
+
+// ToolCallDeltaMerger 同时用于改写转发给客户端的流式响应和把流式响应合并为非流式响应
+type ToolCallDeltaMerger struct {
+	choices map[int][]*mergedToolCall
+}
+
+func NewToolCallDeltaMerger() *ToolCallDeltaMerger {
+	return &ToolCallDeltaMerger{choices: make(map[int][]*mergedToolCall)}
+}
+
+// resolveIndex 已知 id 以 id 为准；新 id 占用的位置已属于其他工具调用时作为新的工具调用；
+// 没有 index 和 id 的片段属于上一个工具调用
+func resolveIndex(calls []*mergedToolCall, toolCall *dto.ToolCallResponse) int {
+	if toolCall.ID != "" {
+		for i, call := range calls {
+			if call.id == toolCall.ID {
+				return i
+			}
+		}
+	}
+	if toolCall.Index != nil && *toolCall.Index >= 0 {
+		index := *toolCall.Index
+		if toolCall.ID != "" && index < len(calls) && calls[index].id != "" {
+			return len(calls)
+		}
+		return index
+	}
+	if toolCall.ID != "" || len(calls) == 0 {
+		return len(calls)
+	}
+	return len(calls) - 1
+}
+
+// MergeDelta 改写一个 choice 的 tool_calls，返回是否有修改
+func (m *ToolCallDeltaMerger) MergeDelta(choice *dto.ChatCompletionsStreamResponseChoice) bool {
+	calls := m.choices[choice.Index]
+	changed := false
+	toolCalls := choice.Delta.ToolCalls[:0]
+	for _, toolCall := range choice.Delta.ToolCalls {
+		index := resolveIndex(calls, &toolCall)
+		if toolCall.Index == nil || *toolCall.Index != index {
+			toolCall.SetIndex(index)
+			changed = true
+		}
+		for len(calls) <= index {
+			calls = append(calls, &mergedToolCall{})
+		}
+		call := calls[index]
+		// 重复出现已发送的 id 或函数名说明上游重新发送了完整的工具调用
+		resent := false
+		if toolCall.ID != "" {
+			if call.id == toolCall.ID {
+				toolCall.ID = ""
+				resent = true
+				changed = true
+			} else if call.id == "" {
+				call.id = toolCall.ID
+			}
+		}
+		if toolCall.Function.Name != "" {
+			if call.name == toolCall.Function.Name {
+				toolCall.Function.Name = ""
+				resent = true
+				changed = true
+			} else if call.name == "" {
+				call.name = toolCall.Function.Name
+			}
+		}
+		arguments := toolCall.Function.Arguments
+		if resent && call.arguments != "" {
+			if strings.HasPrefix(arguments, call.arguments) {
+				arguments = arguments[len(call.arguments):]
+			} else if strings.HasPrefix(call.arguments, arguments) {
+				arguments = ""
+			}
+			if arguments != toolCall.Function.Arguments {
+				toolCall.Function.Arguments = arguments
+				changed = true
+			}
+		}
+		call.arguments += arguments
+		if resent && toolCall.ID == "" && toolCall.Function.Name == "" && arguments == "" {
+			// 没有新增内容的重复片段
+			continue
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+	m.choices[choice.Index] = calls
+	if len(toolCalls) == 0 {
+		toolCalls = nil
+	}
+	choice.Delta.ToolCalls = toolCalls
+	return changed
+}
+
+// Feed 处理一个数据块，没有需要改写的 tool_calls 时原样返回
+func (m *ToolCallDeltaMerger) Feed(data string) string {
+	if !strings.Contains(data, "tool_calls") {
+		return data
+	}
+	var response dto.ChatCompletionsStreamResponse
+	if err := common.DecodeJsonStr(data, &response); err != nil {
+		return data
+	}
+	changed := false
+	for i := range response.Choices {
+		if len(response.Choices[i].Delta.ToolCalls) == 0 {
+			continue
+		}
+		if m.MergeDelta(&response.Choices[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+	rewritten, err := json.Marshal(response)
+	if err != nil {
+		return data
+	}
+	return string(rewritten)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"fmt"
+	"one-api/dto"
+)
+
+// 上游不支持 tool_choice 或 parallel_tool_calls 时，通过调整工具列表和系统提示模拟
+
+// EmulateToolChoice 移除 tool_choice：none 时移除工具，指定函数时只保留该函数，
+// required 和指定函数时在系统提示中要求模型调用
+func EmulateToolChoice(request *dto.GeneralOpenAIRequest) {
+	choice := request.GetToolChoice()
+	request.ToolChoice = nil
+	request.FunctionCall = nil
+	if choice == nil || len(request.Tools) == 0 {
+		return
+	}
+	switch choice.Mode {
+	case dto.ToolChoiceModeNone:
+		request.Tools = nil
+		request.ParallelTooCalls = nil
+	case dto.ToolChoiceModeRequired:
+		AppendSystemInstruction(request, "You must call at least one of the provided functions.")
+	case dto.ToolChoiceModeFunction:
+		for _, tool := range request.Tools {
+			if tool.Function.Name == choice.Name {
+				request.Tools = []dto.ToolCallRequest{tool}
+				break
+			}
+		}
+		AppendSystemInstruction(request, fmt.Sprintf("You must call the function %s.", choice.Name))
+	}
+}
+
+// EmulateParallelToolCalls 移除 parallel_tool_calls，为 false 时在系统提示中要求每次只调用一个函数
+func EmulateParallelToolCalls(request *dto.GeneralOpenAIRequest) {
+	disableParallel := request.ParallelTooCalls != nil && !*request.ParallelTooCalls
+	request.ParallelTooCalls = nil
+	if disableParallel && len(request.Tools) > 0 {
+		AppendSystemInstruction(request, "Call at most one function in each reply.")
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"time"
+)
+
+// usageRollupInterval 汇总消费日志的间隔
+const usageRollupInterval = time.Minute
+
+// RollupUsage 汇总游标之后的全部日志，首次运行时会补齐历史日志
+func RollupUsage() {
+	total := 0
+	for {
+		count, err := model.RollupUsageLogs()
+		if err != nil {
+			common.SysError("failed to roll up usage logs: " + err.Error())
+			break
+		}
+		if count == 0 {
+			break
+		}
+		total += count
+	}
+	if total > 0 {
+		common.SysLog(fmt.Sprintf("rolled up %d usage logs", total))
+	}
+}
+
+// AutomaticallyRollupUsage 定期将消费日志汇总到按小时和按天的用量表，数据看板和用量统计从汇总表读取
+func AutomaticallyRollupUsage() {
+	for {
+		RollupUsage()
+		time.Sleep(usageRollupInterval)
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	UsageStatementFormatCSV = "csv"
+	UsageStatementFormatPDF = "pdf"
+)
+
+// UsageStatement 用户或令牌在区间内按模型汇总的用量明细，用于内部成本分摊
+type UsageStatement struct {
+	UserId         int
+	Username       string
+	TokenId        int
+	TokenName      string
+	StartTimestamp int64
+	EndTimestamp   int64
+	Items          []*model.ModelUsage
+	Total          model.ModelUsage
+}
+
+// GetUsageStatement 统计区间 [startTimestamp, endTimestamp) 内的用量，tokenId 不为 0 时只统计该令牌。
+// 异步任务的结算差额和失败退还也记录为消费日志（退还为负数），消费合计即实际消耗
+func GetUsageStatement(userId int, tokenId int, startTimestamp int64, endTimestamp int64) (*UsageStatement, error) {
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		return nil, err
+	}
+	statement := &UsageStatement{
+		UserId:         userId,
+		Username:       user.Username,
+		TokenId:        tokenId,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+	if tokenId != 0 {
+		token, err := model.GetTokenByIds(tokenId, userId)
+		if err != nil {
+			return nil, err
+		}
+		statement.TokenName = token.Name
+	}
+	statement.Items, err = model.SumModelUsage(userId, tokenId, startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	statement.Total.ModelName = "total"
+	for _, item := range statement.Items {
+		statement.Total.Requests += item.Requests
+		statement.Total.PromptTokens += item.PromptTokens
+		statement.Total.CompletionTokens += item.CompletionTokens
+		statement.Total.Quota += item.Quota
+	}
+	return statement, nil
+}
+
+// usageCost 额度换算为美元金额
+func usageCost(quota int64) string {
+	return strconv.FormatFloat(float64(quota)/common.QuotaPerUnit, 'f', 6, 64)
+}
+
+// csvSafeCell 以 = + - @ 开头的文本在表格软件中会被当作公式执行，加上单引号前缀
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatStatementTime(timestamp int64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
+}
+
+// WriteUsageStatementCSV 每个模型一行，最后一行为合计
+func WriteUsageStatementCSV(w io.Writer, statement *UsageStatement) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"model", "requests", "prompt_tokens", "completion_tokens", "total_tokens", "quota", "cost_usd"})
+	if err != nil {
+		return err
+	}
+	for _, item := range append(statement.Items, &statement.Total) {
+		err = writer.Write([]string{
+			csvSafeCell(item.ModelName),
+			strconv.FormatInt(item.Requests, 10),
+			strconv.FormatInt(item.PromptTokens, 10),
+			strconv.FormatInt(item.CompletionTokens, 10),
+			strconv.FormatInt(item.PromptTokens+item.CompletionTokens, 10),
+			strconv.FormatInt(item.Quota, 10),
+			usageCost(item.Quota),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteUsageStatementPDF 输出等宽排版的明细，ASCII 字符使用 PDF 内置的 Courier 字体，
+// 中文等其他字符使用阅读器自带的 STSong-Light 字体，每个字符占两个 ASCII 字符的宽度
+func WriteUsageStatementPDF(w io.Writer, statement *UsageStatement) error {
+	lines := []string{
+		"Usage Statement",
+		"",
+		fmt.Sprintf("User:      %s (#%d)", statement.Username, statement.UserId),
+	}
+	if statement.TokenId != 0 {
+		lines = append(lines, fmt.Sprintf("Token:     %s (#%d)", statement.TokenName, statement.TokenId))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Period:    %s - %s", formatStatementTime(statement.StartTimestamp), formatStatementTime(statement.EndTimestamp)),
+		fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05")),
+		"",
+	)
+	row := func(item *model.ModelUsage) string {
+		return fmt.Sprintf("%s %9d %12d %12d %12d %12s", padStatementText(item.ModelName, 30), item.Requests, item.PromptTokens,
+			item.CompletionTokens, item.Quota, usageCost(item.Quota))
+	}
+	header := fmt.Sprintf("%-30s %9s %12s %12s %12s %12s", "Model", "Requests", "Prompt", "Completion", "Quota", "Cost (USD)")
+	lines = append(lines, header, strings.Repeat("-", len(header)))
+	for _, item := range statement.Items {
+		lines = append(lines, row(item))
+	}
+	lines = append(lines, strings.Repeat("-", len(header)), row(&statement.Total))
+	_, err := w.Write(renderTextPDF(lines))
+	return err
+}
+
+const (
+	pdfPageWidth     = 595
+	pdfPageHeight    = 842
+	pdfMargin        = 40
+	pdfFontSize      = 8
+	pdfLineHeight    = 11
+	pdfLinesPerPage  = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	pdfFirstObjectId = 7
+)
+
+// statementTextWidth 返回文本占用的 ASCII 字符宽度，非 ASCII 字符占两个
+func statementTextWidth(r rune) int {
+	if r < 128 {
+		return 1
+	}
+	return 2
+}
+
+// padStatementText 按显示宽度截断并补齐文本，超出时按字符截断并以 ... 结尾
+func padStatementText(text string, width int) string {
+	total := 0
+	for _, r := range text {
+		total += statementTextWidth(r)
+	}
+	if total > width {
+		var builder strings.Builder
+		used := 0
+		for _, r := range text {
+			if used+statementTextWidth(r) > width-3 {
+				break
+			}
+			builder.WriteRune(r)
+			used += statementTextWidth(r)
+		}
+		builder.WriteString("...")
+		text, total = builder.String(), used+3
+	}
+	return text + strings.Repeat(" ", width-total)
+}
+
+// pdfTextOperators 将一行文本按字体拆分为多段显示，ASCII 使用 F1（Courier），
+// 其他字符使用 F2（STSong-Light，UCS-2 编码），不在基本平面的字符和控制字符显示为 ?
+func pdfTextOperators(text string) string {
+	var builder, run strings.Builder
+	font := ""
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if font == "F1" {
+			builder.WriteString("/F1 " + strconv.Itoa(pdfFontSize) + " Tf (" + run.String() + ") Tj ")
+		} else {
+			builder.WriteString("/F2 " + strconv.Itoa(pdfFontSize) + " Tf <" + run.String() + "> Tj ")
+		}
+		run.Reset()
+	}
+	for _, r := range text {
+		if r < 32 || r == 127 || r > 0xFFFF {
+			r = '?'
+		}
+		runFont := "F1"
+		if r > 127 {
+			runFont = "F2"
+		}
+		if runFont != font {
+			flush()
+			font = runFont
+		}
+		switch {
+		case runFont == "F2":
+			run.WriteString(fmt.Sprintf("%04X", r))
+		case r == '\\' || r == '(' || r == ')':
+			run.WriteByte('\\')
+			run.WriteRune(r)
+		default:
+			run.WriteRune(r)
+		}
+	}
+	flush()
+	return builder.String()
+}
+
+// renderTextPDF 生成只包含文本行的 PDF，按页面高度自动分页
+func renderTextPDF(lines []string) []byte {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// 1 目录，2 页面树，3 Courier 字体，4-6 中文字体，之后每页依次为页面对象和内容流
+	objects := make([]string, pdfFirstObjectId-1+2*len(pages))
+	kids := make([]string, 0, len(pages))
+	for i, pageLines := range pages {
+		pageId := pdfFirstObjectId + 2*i
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageId))
+		var content strings.Builder
+		content.WriteString(fmt.Sprintf("BT %d TL %d %d Td\n", pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin))
+		for _, line := range pageLines {
+			content.WriteString(pdfTextOperators(line) + "T*\n")
+		}
+		content.WriteString("ET")
+		objects[pageId-1] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pageId+1)
+		objects[pageId] = fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String())
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objects[2] = "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>"
+	// Adobe 标准中文字体不嵌入字形，字宽设为 1200 即两个 Courier 字符的宽度，保持列对齐
+	objects[3] = "<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [5 0 R] >>"
+	objects[4] = "<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /DW 1200 /FontDescriptor 6 0 R >>"
+	objects[5] = "<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>"
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, object))
+	}
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, offset := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset))
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"one-api/model"
+	"time"
+)
+
+// WriteUserDataArchive 把用户的个人资料、令牌信息（不含密钥）和使用日志写入 zip 压缩包
+func WriteUserDataArchive(w io.Writer, userId int) error {
+	user, err := model.GetUserById(userId, true)
+	if err != nil {
+		return err
+	}
+	user.Password = ""
+	user.AccessToken = nil
+	tokens, err := model.GetAllUserTokens(userId, 0, -1)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		token.Clean()
+	}
+
+	archive := zip.NewWriter(w)
+	if err = writeArchiveJson(archive, "profile.json", user); err != nil {
+		return err
+	}
+	if err = writeArchiveJson(archive, "tokens.json", tokens); err != nil {
+		return err
+	}
+	logWriter, err := archive.CreateHeader(&zip.FileHeader{Name: "logs.jsonl", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(logWriter)
+	err = model.ExportUserLogs(userId, func(logs []*model.Log) error {
+		for _, log := range logs {
+			if err := encoder.Encode(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeArchiveJson(archive *zip.Writer, name string, v any) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
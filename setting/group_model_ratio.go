@@ -0,0 +1,64 @@
+package setting
+
+import (
+	"encoding/json"
+	"errors"
+	"one-api/common"
+	"sync"
+)
+
+// groupModelRatio 分组下指定模型的倍率，配置后替代该分组的分组倍率，例如 {"vip": {"gpt-4o": 0.8}}
+var groupModelRatio = make(map[string]map[string]float64)
+var groupModelRatioMutex sync.RWMutex
+
+func GroupModelRatio2JSONString() string {
+	groupModelRatioMutex.RLock()
+	defer groupModelRatioMutex.RUnlock()
+	jsonBytes, err := json.Marshal(groupModelRatio)
+	if err != nil {
+		common.SysError("error marshalling group model ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func parseGroupModelRatio(jsonStr string) (map[string]map[string]float64, error) {
+	ratios := make(map[string]map[string]float64)
+	if err := json.Unmarshal([]byte(jsonStr), &ratios); err != nil {
+		return nil, err
+	}
+	for group, models := range ratios {
+		for model, ratio := range models {
+			if ratio < 0 {
+				return nil, errors.New("group model ratio must be not less than 0: " + group + " " + model)
+			}
+		}
+	}
+	return ratios, nil
+}
+
+func CheckGroupModelRatio(jsonStr string) error {
+	_, err := parseGroupModelRatio(jsonStr)
+	return err
+}
+
+func UpdateGroupModelRatioByJSONString(jsonStr string) error {
+	ratios, err := parseGroupModelRatio(jsonStr)
+	if err != nil {
+		return err
+	}
+	groupModelRatioMutex.Lock()
+	defer groupModelRatioMutex.Unlock()
+	groupModelRatio = ratios
+	return nil
+}
+
+// GetGroupModelRatio 返回分组下模型的倍率，未单独配置时使用分组倍率
+func GetGroupModelRatio(group string, model string) float64 {
+	groupModelRatioMutex.RLock()
+	ratio, ok := groupModelRatio[group][model]
+	groupModelRatioMutex.RUnlock()
+	if ok {
+		return ratio
+	}
+	return GetGroupRatio(group)
+}
@@ -0,0 +1,89 @@
+package setting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"sort"
+	"sync"
+)
+
+// VolumeTier 用户本月已使用的 token 数达到 Tokens 后，分组倍率再乘以 Ratio
+type VolumeTier struct {
+	Tokens int64   `json:"tokens"`
+	Ratio  float64 `json:"ratio"`
+}
+
+// groupVolumeTiers 分组的用量阶梯价格，例如 {"default": [{"tokens": 10000000, "ratio": 0.8}]} 表示本月前 10M token 按原价，之后按 0.8 倍计费
+var groupVolumeTiers = make(map[string][]VolumeTier)
+var groupVolumeTiersMutex sync.RWMutex
+
+func GroupVolumeTiers2JSONString() string {
+	groupVolumeTiersMutex.RLock()
+	defer groupVolumeTiersMutex.RUnlock()
+	jsonBytes, err := json.Marshal(groupVolumeTiers)
+	if err != nil {
+		common.SysError("error marshalling group volume tiers: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func parseGroupVolumeTiers(jsonStr string) (map[string][]VolumeTier, error) {
+	tiers := make(map[string][]VolumeTier)
+	if err := json.Unmarshal([]byte(jsonStr), &tiers); err != nil {
+		return nil, err
+	}
+	for group, groupTiers := range tiers {
+		for _, tier := range groupTiers {
+			if tier.Tokens < 0 || tier.Ratio < 0 {
+				return nil, errors.New("volume tier tokens and ratio must be not less than 0: " + group)
+			}
+		}
+		sort.Slice(groupTiers, func(i, j int) bool {
+			return groupTiers[i].Tokens < groupTiers[j].Tokens
+		})
+		for i := 1; i < len(groupTiers); i++ {
+			if groupTiers[i].Tokens == groupTiers[i-1].Tokens {
+				return nil, fmt.Errorf("duplicate volume tier %d: %s", groupTiers[i].Tokens, group)
+			}
+		}
+	}
+	return tiers, nil
+}
+
+func CheckGroupVolumeTiers(jsonStr string) error {
+	_, err := parseGroupVolumeTiers(jsonStr)
+	return err
+}
+
+func UpdateGroupVolumeTiersByJSONString(jsonStr string) error {
+	tiers, err := parseGroupVolumeTiers(jsonStr)
+	if err != nil {
+		return err
+	}
+	groupVolumeTiersMutex.Lock()
+	defer groupVolumeTiersMutex.Unlock()
+	groupVolumeTiers = tiers
+	return nil
+}
+
+func HasGroupVolumeTiers(group string) bool {
+	groupVolumeTiersMutex.RLock()
+	defer groupVolumeTiersMutex.RUnlock()
+	return len(groupVolumeTiers[group]) > 0
+}
+
+// GetGroupVolumeRatio 返回用户本月用量对应的阶梯倍率，未达到任何阶梯时为 1
+func GetGroupVolumeRatio(group string, monthlyTokens int64) float64 {
+	groupVolumeTiersMutex.RLock()
+	defer groupVolumeTiersMutex.RUnlock()
+	ratio := 1.0
+	for _, tier := range groupVolumeTiers[group] {
+		if monthlyTokens < tier.Tokens {
+			break
+		}
+		ratio = tier.Ratio
+	}
+	return ratio
+}
@@ -0,0 +1,54 @@
+package setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sync"
+)
+
+// LegacyKeyAuthGroups 允许不支持 Authorization 请求头的旧客户端通过 api-key 请求头或签名查询参数传递令牌的分组，
+// 值为该方式下单个令牌每分钟最多请求次数，未配置的分组不允许使用
+var LegacyKeyAuthGroups = map[string]int{}
+var LegacyKeyAuthGroupsMutex sync.RWMutex
+
+func LegacyKeyAuthGroups2JSONString() string {
+	LegacyKeyAuthGroupsMutex.RLock()
+	defer LegacyKeyAuthGroupsMutex.RUnlock()
+
+	jsonBytes, err := json.Marshal(LegacyKeyAuthGroups)
+	if err != nil {
+		common.SysError("error marshalling legacy key auth groups: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateLegacyKeyAuthGroupsByJSONString(jsonStr string) error {
+	LegacyKeyAuthGroupsMutex.Lock()
+	defer LegacyKeyAuthGroupsMutex.Unlock()
+
+	LegacyKeyAuthGroups = make(map[string]int)
+	return json.Unmarshal([]byte(jsonStr), &LegacyKeyAuthGroups)
+}
+
+func GetLegacyKeyAuthLimit(group string) (limit int, found bool) {
+	LegacyKeyAuthGroupsMutex.RLock()
+	defer LegacyKeyAuthGroupsMutex.RUnlock()
+
+	limit, found = LegacyKeyAuthGroups[group]
+	return limit, found
+}
+
+func CheckLegacyKeyAuthGroups(jsonStr string) error {
+	checkGroups := make(map[string]int)
+	err := json.Unmarshal([]byte(jsonStr), &checkGroups)
+	if err != nil {
+		return err
+	}
+	for group, limit := range checkGroups {
+		if limit < 1 {
+			return fmt.Errorf("group %s has invalid legacy key auth rate limit: %d", group, limit)
+		}
+	}
+	return nil
+}
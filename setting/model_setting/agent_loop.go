@@ -0,0 +1,51 @@
+package model_setting
+
+import (
+	"one-api/setting/config"
+)
+
+// AgentTool 管理员定义的 HTTP 工具，模型调用时网关以 JSON 参数请求 URL，响应内容作为工具结果
+type AgentTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	URL         string         `json:"url"`
+	// Method 默认为 POST，参数作为请求体；GET 时参数作为查询参数
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout 超时时间（秒），默认 10
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// AgentLoopSettings 请求中通过 agent_tools 指定工具时，由网关执行模型发起的工具调用并把结果回传给模型，直到模型给出最终回复
+type AgentLoopSettings struct {
+	Enabled bool `json:"enabled"`
+	// MaxSteps 最多请求模型的次数，达到后要求模型不再调用工具
+	MaxSteps int         `json:"max_steps"`
+	Tools    []AgentTool `json:"tools"`
+}
+
+var defaultAgentLoopSettings = AgentLoopSettings{
+	Enabled:  false,
+	MaxSteps: 5,
+	Tools:    []AgentTool{},
+}
+
+var agentLoopSettings = defaultAgentLoopSettings
+
+func init() {
+	config.GlobalConfig.Register("agent_loop", &agentLoopSettings)
+}
+
+func GetAgentLoopSettings() *AgentLoopSettings {
+	return &agentLoopSettings
+}
+
+func (s *AgentLoopSettings) GetTool(name string) *AgentTool {
+	for i := range s.Tools {
+		if s.Tools[i].Name == name {
+			return &s.Tools[i]
+		}
+	}
+	return nil
+}
@@ -6,11 +6,20 @@ import (
 
 type GlobalSettings struct {
 	PassThroughRequestEnabled bool `json:"pass_through_request_enabled"`
+	// ProvenanceEnabled 在中继响应头中写入网关签名的来源证明
+	ProvenanceEnabled bool `json:"provenance_enabled"`
+	// SystemFingerprintChannelTagEnabled 在响应的 system_fingerprint 中附加处理请求的渠道哈希
+	SystemFingerprintChannelTagEnabled bool `json:"system_fingerprint_channel_tag_enabled"`
+	// UpstreamModelListEnabled /v1/models 只返回渠道上游模型列表中实际提供的模型
+	UpstreamModelListEnabled bool `json:"upstream_model_list_enabled"`
 }
 
 // 默认配置
 var defaultOpenaiSettings = GlobalSettings{
-	PassThroughRequestEnabled: false,
+	PassThroughRequestEnabled:          false,
+	ProvenanceEnabled:                  false,
+	SystemFingerprintChannelTagEnabled: false,
+	UpstreamModelListEnabled:           false,
 }
 
 // 全局实例
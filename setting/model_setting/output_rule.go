@@ -0,0 +1,61 @@
+package model_setting
+
+import (
+	"one-api/setting/config"
+	"strings"
+)
+
+const (
+	OutputRuleRegexReplace = "regex_replace" // 正则替换，例如合并连续空白
+	OutputRuleStopTrim     = "stop_trim"     // 截断第一个停止字符串及之后的内容，例如 <|im_end|>
+	OutputRulePrefixStrip  = "prefix_strip"  // 移除回复开头固定的前言
+)
+
+// OutputRule 响应内容后处理规则，按顺序作用于完整的回复内容，流式响应中按相同结果输出增量
+type OutputRule struct {
+	Type        string   `json:"type"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Replacement string   `json:"replacement,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	// Window 流式响应中正则替换需要暂存的末尾字符数，匹配内容不应超过该长度，默认 64
+	Window int `json:"window,omitempty"`
+}
+
+type OutputRuleSettings struct {
+	// ModelRules 模型名称到后处理规则的映射，支持以 * 结尾的前缀匹配
+	ModelRules map[string][]OutputRule `json:"model_rules"`
+}
+
+var defaultOutputRuleSettings = OutputRuleSettings{
+	ModelRules: map[string][]OutputRule{},
+}
+
+var outputRuleSettings = defaultOutputRuleSettings
+
+func init() {
+	config.GlobalConfig.Register("output_rule", &outputRuleSettings)
+}
+
+func GetOutputRuleSettings() *OutputRuleSettings {
+	return &outputRuleSettings
+}
+
+// GetModelRules 返回模型的后处理规则，精确匹配优先，其次为最长前缀匹配
+func (s *OutputRuleSettings) GetModelRules(model string) []OutputRule {
+	if rules, ok := s.ModelRules[model]; ok {
+		return rules
+	}
+	var rules []OutputRule
+	longest := -1
+	for pattern, r := range s.ModelRules {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		if strings.HasPrefix(model, prefix) && len(prefix) > longest {
+			rules = r
+			longest = len(prefix)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,53 @@
+package model_setting
+
+import (
+	"one-api/setting/config"
+	"strings"
+)
+
+const (
+	ToolCallProfileHermes   = "hermes"   // <tool_call>{"name": ..., "arguments": ...}</tool_call>，Qwen、Hermes 等
+	ToolCallProfileXML      = "xml"      // <function_calls><invoke name="..."><parameter name="...">...</parameter></invoke></function_calls>
+	ToolCallProfileMarkdown = "markdown" // ```json {"name": ..., "arguments": ...} ```
+)
+
+// ToolCallSettings 部分开源模型不支持结构化的 tool_calls，而是在文本中输出 XML 或 markdown 格式的工具调用，
+// 为这些模型配置解析格式后，请求中的 tools 会被转换为系统提示，响应中的工具调用会被解析为 OpenAI 格式的 tool_calls
+type ToolCallSettings struct {
+	// ModelProfiles 模型名称到解析格式的映射，支持以 * 结尾的前缀匹配
+	ModelProfiles map[string]string `json:"model_profiles"`
+}
+
+var defaultToolCallSettings = ToolCallSettings{
+	ModelProfiles: map[string]string{},
+}
+
+var toolCallSettings = defaultToolCallSettings
+
+func init() {
+	config.GlobalConfig.Register("tool_call", &toolCallSettings)
+}
+
+func GetToolCallSettings() *ToolCallSettings {
+	return &toolCallSettings
+}
+
+// GetModelProfile 返回模型的解析格式，精确匹配优先，其次为最长前缀匹配，未配置时返回空字符串
+func (s *ToolCallSettings) GetModelProfile(model string) string {
+	if profile, ok := s.ModelProfiles[model]; ok {
+		return profile
+	}
+	profile := ""
+	longest := -1
+	for pattern, p := range s.ModelProfiles {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		if strings.HasPrefix(model, prefix) && len(prefix) > longest {
+			profile = p
+			longest = len(prefix)
+		}
+	}
+	return profile
+}
@@ -62,6 +62,12 @@ var defaultCreateCacheRatio = map[string]float64{
 var cacheRatioMap map[string]float64
 var cacheRatioMapMutex sync.RWMutex
 
+// DefaultCacheRatio 未单独配置缓存倍率的模型使用的缓存倍率，为 1 时缓存命中的 token 按原价计费
+var DefaultCacheRatio = 1.0
+
+var createCacheRatioMap map[string]float64
+var createCacheRatioMapMutex sync.RWMutex
+
 // GetCacheRatioMap returns the cache ratio map
 func GetCacheRatioMap() map[string]float64 {
 	cacheRatioMapMutex.RLock()
@@ -94,13 +100,35 @@ func GetCacheRatio(name string) (float64, bool) {
 	defer cacheRatioMapMutex.RUnlock()
 	ratio, ok := cacheRatioMap[name]
 	if !ok {
-		return 1, false // Default to 1 if not found
+		return DefaultCacheRatio, false
 	}
 	return ratio, true
 }
 
+// CreateCacheRatio2JSONString converts the cache creation ratio map to a JSON string
+func CreateCacheRatio2JSONString() string {
+	createCacheRatioMapMutex.RLock()
+	defer createCacheRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(createCacheRatioMap)
+	if err != nil {
+		common.SysError("error marshalling create cache ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// UpdateCreateCacheRatioByJSONString updates the cache creation ratio map from a JSON string
+func UpdateCreateCacheRatioByJSONString(jsonStr string) error {
+	createCacheRatioMapMutex.Lock()
+	defer createCacheRatioMapMutex.Unlock()
+	createCacheRatioMap = make(map[string]float64)
+	return json.Unmarshal([]byte(jsonStr), &createCacheRatioMap)
+}
+
+// GetCreateCacheRatio returns the cache creation (write) ratio for a model
 func GetCreateCacheRatio(name string) (float64, bool) {
-	ratio, ok := defaultCreateCacheRatio[name]
+	createCacheRatioMapMutex.RLock()
+	defer createCacheRatioMapMutex.RUnlock()
+	ratio, ok := createCacheRatioMap[name]
 	if !ok {
 		return 1.25, false // Default to 1.25 if not found
 	}
@@ -0,0 +1,69 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sync"
+)
+
+// FreeModel 免费模型不扣除额度，按用户单独限制每分钟请求数和每日请求数，0 表示不限制
+type FreeModel struct {
+	RPM           int `json:"rpm"`
+	DailyRequests int `json:"daily_requests"`
+}
+
+// freeModels 免费模型及其限制，例如 {"gpt-4o-mini": {"rpm": 10, "daily_requests": 200}}
+var freeModels = make(map[string]FreeModel)
+var freeModelsMutex sync.RWMutex
+
+func parseFreeModels(jsonStr string) (map[string]FreeModel, error) {
+	models := make(map[string]FreeModel)
+	if err := json.Unmarshal([]byte(jsonStr), &models); err != nil {
+		return nil, err
+	}
+	for name, limit := range models {
+		if limit.RPM < 0 || limit.DailyRequests < 0 {
+			return nil, fmt.Errorf("free model %s: limits must not be negative", name)
+		}
+	}
+	return models, nil
+}
+
+func FreeModels2JSONString() string {
+	freeModelsMutex.RLock()
+	defer freeModelsMutex.RUnlock()
+	jsonBytes, err := json.Marshal(freeModels)
+	if err != nil {
+		common.SysError("error marshalling free models: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func CheckFreeModels(jsonStr string) error {
+	_, err := parseFreeModels(jsonStr)
+	return err
+}
+
+func UpdateFreeModelsByJSONString(jsonStr string) error {
+	models, err := parseFreeModels(jsonStr)
+	if err != nil {
+		return err
+	}
+	freeModelsMutex.Lock()
+	defer freeModelsMutex.Unlock()
+	freeModels = models
+	return nil
+}
+
+func GetFreeModel(name string) (FreeModel, bool) {
+	freeModelsMutex.RLock()
+	defer freeModelsMutex.RUnlock()
+	limit, ok := freeModels[name]
+	return limit, ok
+}
+
+func IsFreeModel(name string) bool {
+	_, ok := GetFreeModel(name)
+	return ok
+}
@@ -1,18 +1,28 @@
 package operation_setting
 
-import "one-api/setting/config"
+import (
+	"one-api/common"
+	"one-api/setting/config"
+)
 
 type GeneralSetting struct {
 	DocsLink            string `json:"docs_link"`
 	PingIntervalEnabled bool   `json:"ping_interval_enabled"`
 	PingIntervalSeconds int    `json:"ping_interval_seconds"`
+	// 以货币形式显示额度时使用的币种、符号和汇率（1 美元兑换的显示币种金额），只影响显示，内部仍以额度计算
+	DisplayCurrency       string  `json:"display_currency"`
+	DisplayCurrencySymbol string  `json:"display_currency_symbol"`
+	DisplayExchangeRate   float64 `json:"display_exchange_rate"`
 }
 
 // 默认配置
 var generalSetting = GeneralSetting{
-	DocsLink:            "https://docs.newapi.pro",
-	PingIntervalEnabled: false,
-	PingIntervalSeconds: 60,
+	DocsLink:              "https://docs.newapi.pro",
+	PingIntervalEnabled:   false,
+	PingIntervalSeconds:   60,
+	DisplayCurrency:       "USD",
+	DisplayCurrencySymbol: "$",
+	DisplayExchangeRate:   1,
 }
 
 func init() {
@@ -23,3 +33,15 @@ func init() {
 func GetGeneralSetting() *GeneralSetting {
 	return &generalSetting
 }
+
+// QuotaToDisplayAmount 额度换算为显示币种的金额，未开启以货币显示时返回额度本身
+func QuotaToDisplayAmount(quota int64) float64 {
+	if !common.DisplayInCurrencyEnabled {
+		return float64(quota)
+	}
+	rate := generalSetting.DisplayExchangeRate
+	if rate <= 0 {
+		rate = 1
+	}
+	return float64(quota) / common.QuotaPerUnit * rate
+}
@@ -0,0 +1,80 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sync"
+)
+
+// ImagePricing 图片模型按尺寸和品质配置的单张图片价格（美元），结构为 模型 -> 尺寸 -> 品质 -> 价格，
+// 尺寸和品质可以使用 * 匹配其余取值，例如：
+//
+//	{"dall-e-3": {"1024x1024": {"standard": 0.04, "hd": 0.08}, "*": {"standard": 0.08, "hd": 0.12}}}
+type ImagePricing map[string]map[string]map[string]float64
+
+const imagePricingWildcard = "*"
+
+var imagePricingMap = make(ImagePricing)
+var imagePricingMapMutex sync.RWMutex
+
+// ParseImagePricing 解析并校验图片价格
+func ParseImagePricing(jsonStr string) (ImagePricing, error) {
+	pricing := make(ImagePricing)
+	if err := json.Unmarshal([]byte(jsonStr), &pricing); err != nil {
+		return nil, err
+	}
+	for model, sizes := range pricing {
+		for size, qualities := range sizes {
+			for quality, price := range qualities {
+				if price < 0 {
+					return nil, fmt.Errorf("model %s size %s quality %s: price must not be negative", model, size, quality)
+				}
+			}
+		}
+	}
+	return pricing, nil
+}
+
+func ImagePricing2JSONString() string {
+	imagePricingMapMutex.RLock()
+	defer imagePricingMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(imagePricingMap)
+	if err != nil {
+		common.SysError("error marshalling image pricing: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateImagePricingByJSONString(jsonStr string) error {
+	pricing, err := ParseImagePricing(jsonStr)
+	if err != nil {
+		return err
+	}
+	imagePricingMapMutex.Lock()
+	defer imagePricingMapMutex.Unlock()
+	imagePricingMap = pricing
+	return nil
+}
+
+// GetImagePrice 返回单张图片的价格，先精确匹配尺寸和品质，再匹配 *
+func GetImagePrice(model string, size string, quality string) (float64, bool) {
+	imagePricingMapMutex.RLock()
+	defer imagePricingMapMutex.RUnlock()
+	sizes, ok := imagePricingMap[model]
+	if !ok {
+		return 0, false
+	}
+	for _, s := range []string{size, imagePricingWildcard} {
+		qualities, ok := sizes[s]
+		if !ok {
+			continue
+		}
+		for _, q := range []string{quality, imagePricingWildcard} {
+			if price, ok := qualities[q]; ok {
+				return price, true
+			}
+		}
+	}
+	return 0, false
+}
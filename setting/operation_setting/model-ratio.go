@@ -94,6 +94,12 @@ var defaultModelRatio = map[string]float64{
 	"tts-1-1106":                                7.5, // 1k characters -> $0.015
 	"tts-1-hd":                                  15,  // 1k characters -> $0.03
 	"tts-1-hd-1106":                             15,  // 1k characters -> $0.03
+	"speech-01-turbo":                           0.2 * RMB, // 1k characters -> ¥0.2
+	"speech-01-hd":                              0.35 * RMB,
+	"speech-02-turbo":                           0.2 * RMB,
+	"speech-02-hd":                              0.35 * RMB,
+	"gpt-4o-mini-tts":                           6,   // 1k characters -> $0.012 (estimated)
+	"FunAudioLLM/CosyVoice2-0.5B":               0.15 * RMB, // ¥50 / 1M UTF-8 bytes, 按中文字符估算
 	"davinci":                                   10,
 	"curie":                                     10,
 	"babbage":                                   10,
@@ -104,6 +110,8 @@ var defaultModelRatio = map[string]float64{
 	"text-search-ada-doc-001":                   10,
 	"text-moderation-stable":                    0.1,
 	"text-moderation-latest":                    0.1,
+	"omni-moderation-latest":                    0.1,
+	"mistral-moderation-latest":                 0.05,
 	"claude-instant-1":                          0.4,   // $0.8 / 1M tokens
 	"claude-2.0":                                4,     // $8 / 1M tokens
 	"claude-2.1":                                4,     // $8 / 1M tokens
@@ -133,6 +141,13 @@ var defaultModelRatio = map[string]float64{
 	"bge-large-zh":                              0.002 * RMB,
 	"bge-large-en":                              0.002 * RMB,
 	"tao-8k":                                    0.002 * RMB,
+	"Baichuan4":                                 0.1 * RMB,
+	"Baichuan4-Turbo":                           0.015 * RMB,
+	"Baichuan4-Air":                             0.00098 * RMB,
+	"Baichuan3-Turbo":                           0.012 * RMB,
+	"Baichuan3-Turbo-128k":                      0.024 * RMB,
+	"Baichuan2-Turbo":                           0.008 * RMB,
+	"Baichuan-Text-Embedding":                   0.0005 * RMB,
 	"PaLM-2":                                    1,
 	"gemini-1.5-pro-latest":                     1.25, // $3.5 / 1M tokens
 	"gemini-1.5-flash-latest":                   0.075,
@@ -189,6 +204,8 @@ var defaultModelRatio = map[string]float64{
 	"yi-large-turbo":         12.0 / 1000 * RMB,
 	"yi-large-preview":       20.0 / 1000 * RMB,
 	"yi-large-rag-preview":   25.0 / 1000 * RMB,
+	"yi-lightning":           0.99 / 1000 * RMB,
+	"yi-vision-v2":           6.0 / 1000 * RMB,
 	"command":                0.5,
 	"command-nightly":        0.5,
 	"command-light":          0.5,
@@ -219,8 +236,11 @@ var defaultModelRatio = map[string]float64{
 var defaultModelPrice = map[string]float64{
 	"suno_music":              0.1,
 	"suno_lyrics":             0.01,
+	"dall-e-2":                0.02,
 	"dall-e-3":                0.04,
 	"imagen-3.0-generate-002": 0.03,
+	"imagen-3.0-generate-001": 0.04,
+	"imagen-3.0-fast-generate-001": 0.02,
 	"gpt-4-gizmo-*":           0.1,
 	"mj_imagine":              0.1,
 	"mj_variation":            0.1,
@@ -283,6 +303,11 @@ func InitRatioSettings() {
 	cacheRatioMap = defaultCacheRatio
 	cacheRatioMapMutex.Unlock()
 
+	// Initialize createCacheRatioMap
+	createCacheRatioMapMutex.Lock()
+	createCacheRatioMap = defaultCreateCacheRatio
+	createCacheRatioMapMutex.Unlock()
+
 	// initialize imageRatioMap
 	imageRatioMapMutex.Lock()
 	imageRatioMap = defaultImageRatio
@@ -314,8 +339,14 @@ func UpdateModelPriceByJSONString(jsonStr string) error {
 	return json.Unmarshal([]byte(jsonStr), &modelPriceMap)
 }
 
-// GetModelPrice 返回模型的价格，如果模型不存在则返回-1，false
+// GetModelPrice 返回模型的价格，如果模型不存在则返回-1，false。免费模型的价格为 0，价格表中按次计费的模型优先使用价格表的单次价格
 func GetModelPrice(name string, printErr bool) (float64, bool) {
+	if IsFreeModel(name) {
+		return 0, true
+	}
+	if pricing, ok := GetModelPricing(name); ok && pricing.PerRequest > 0 {
+		return pricing.RequestPrice(), true
+	}
 	modelPriceMapMutex.RLock()
 	defer modelPriceMapMutex.RUnlock()
 
@@ -349,6 +380,9 @@ func GetModelRatio(name string) (float64, bool) {
 	if strings.HasPrefix(name, "gpt-4-gizmo") {
 		name = "gpt-4-gizmo-*"
 	}
+	if pricing, ok := getTokenPricing(name); ok {
+		return pricing.ModelRatio(), true
+	}
 	ratio, ok := modelRatioMap[name]
 	if !ok {
 		return 37.5, SelfUseModeEnabled
@@ -393,6 +427,9 @@ func UpdateCompletionRatioByJSONString(jsonStr string) error {
 }
 
 func GetCompletionRatio(name string) float64 {
+	if pricing, ok := getTokenPricing(name); ok {
+		return pricing.CompletionRatio()
+	}
 	CompletionRatioMutex.RLock()
 	defer CompletionRatioMutex.RUnlock()
 
@@ -0,0 +1,44 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"sync"
+)
+
+// ModelMaxTokens 模型的输出 token 限制，default 为请求未指定 max_tokens 时使用的值，max 为允许的最大值，0 表示不限制
+type ModelMaxTokens struct {
+	Default uint `json:"default,omitempty"`
+	Max     uint `json:"max,omitempty"`
+}
+
+var modelMaxTokensMap = make(map[string]ModelMaxTokens)
+var modelMaxTokensMapMutex sync.RWMutex
+
+func ModelMaxTokens2JSONString() string {
+	modelMaxTokensMapMutex.RLock()
+	defer modelMaxTokensMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(modelMaxTokensMap)
+	if err != nil {
+		common.SysError("error marshalling model max tokens: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateModelMaxTokensByJSONString(jsonStr string) error {
+	maxTokensMap := make(map[string]ModelMaxTokens)
+	if err := json.Unmarshal([]byte(jsonStr), &maxTokensMap); err != nil {
+		return err
+	}
+	modelMaxTokensMapMutex.Lock()
+	defer modelMaxTokensMapMutex.Unlock()
+	modelMaxTokensMap = maxTokensMap
+	return nil
+}
+
+func GetModelMaxTokens(name string) (ModelMaxTokens, bool) {
+	modelMaxTokensMapMutex.RLock()
+	defer modelMaxTokensMapMutex.RUnlock()
+	maxTokens, ok := modelMaxTokensMap[name]
+	return maxTokens, ok
+}
@@ -0,0 +1,234 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"one-api/common"
+	"strings"
+	"sync"
+)
+
+// 模型价格表按每 1M token 的输入、输出价格配置模型，配置了价格的模型优先使用价格表换算出的模型倍率和补全倍率，
+// 未配置的模型仍使用原有的倍率设置
+
+const (
+	PricingCurrencyUSD = "USD"
+	PricingCurrencyCNY = "CNY"
+)
+
+// ratioUSDPerMillion 模型倍率 1 对应的每 1M token 价格（美元）
+const ratioUSDPerMillion = 2.0
+
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+	// Reasoning 思考 token 每 1M 的价格，未配置时按输出价格计费
+	Reasoning float64 `json:"reasoning,omitempty"`
+	// Currency 价格的币种，默认为 USD
+	Currency string `json:"currency,omitempty"`
+	// PerMinute 语音转文字每分钟音频的价格
+	PerMinute float64 `json:"per_minute,omitempty"`
+	// Per1KChars 文字转语音每 1000 字符的价格
+	Per1KChars float64 `json:"per_1k_chars,omitempty"`
+	// PerRequest 按次计费的单次价格，配置后不再按 token 计费
+	PerRequest float64 `json:"per_request,omitempty"`
+}
+
+// hasTokenPrice 只配置了音频价格或单次价格的模型仍按倍率计算 token 的价格
+func (p ModelPricing) hasTokenPrice() bool {
+	return p.Input > 0 || p.Output > 0 || (p.PerMinute == 0 && p.Per1KChars == 0 && p.PerRequest == 0)
+}
+
+func (p ModelPricing) GetCurrency() string {
+	if p.Currency == "" {
+		return PricingCurrencyUSD
+	}
+	return p.Currency
+}
+
+func (p ModelPricing) toUSD(price float64) float64 {
+	if p.Currency == PricingCurrencyCNY {
+		return price / USD2RMB
+	}
+	return price
+}
+
+// basePrice 换算倍率的基准价格，一般为输入价格，输入免费时为输出价格
+func (p ModelPricing) basePrice() float64 {
+	if p.Input == 0 {
+		return p.Output
+	}
+	return p.Input
+}
+
+// FreeInput 输入免费只收取输出费用，此时模型倍率按输出价格换算，输入 token 不计费
+func (p ModelPricing) FreeInput() bool {
+	return p.Input == 0 && p.Output > 0
+}
+
+func (p ModelPricing) ModelRatio() float64 {
+	return p.toUSD(p.basePrice()) / ratioUSDPerMillion
+}
+
+func (p ModelPricing) CompletionRatio() float64 {
+	if p.basePrice() == 0 {
+		return 1
+	}
+	return p.Output / p.basePrice()
+}
+
+// ReasoningRatio 思考价格相对基准价格的倍数，未配置思考价格时返回 false
+func (p ModelPricing) ReasoningRatio() (float64, bool) {
+	if p.basePrice() == 0 || p.Reasoning == 0 {
+		return 0, false
+	}
+	return p.Reasoning / p.basePrice(), true
+}
+
+// RequestPrice 单次请求的价格（美元）
+func (p ModelPricing) RequestPrice() float64 {
+	return p.toUSD(p.PerRequest)
+}
+
+// TranscriptionPrice 按音频时长计算的价格（美元），不足 1 秒按 1 秒计算
+func (p ModelPricing) TranscriptionPrice(seconds float64) (float64, bool) {
+	if p.PerMinute <= 0 {
+		return 0, false
+	}
+	return p.toUSD(p.PerMinute) * math.Ceil(seconds) / 60, true
+}
+
+// SpeechPrice 按字符数计算的价格（美元）
+func (p ModelPricing) SpeechPrice(chars int) (float64, bool) {
+	if p.Per1KChars <= 0 {
+		return 0, false
+	}
+	return p.toUSD(p.Per1KChars) * float64(chars) / 1000, true
+}
+
+func (p ModelPricing) Validate() error {
+	if p.Input < 0 || p.Output < 0 || p.Reasoning < 0 || p.PerMinute < 0 || p.Per1KChars < 0 || p.PerRequest < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+	// 思考价格按基准价格的倍数计费，输入和输出都免费时无法表示
+	if p.Input == 0 && p.Output == 0 && p.Reasoning > 0 {
+		return fmt.Errorf("input or output price must be greater than 0 when reasoning price is set")
+	}
+	switch p.Currency {
+	case "", PricingCurrencyUSD, PricingCurrencyCNY:
+		return nil
+	}
+	return fmt.Errorf("unsupported currency %s", p.Currency)
+}
+
+var modelPricingMap = make(map[string]ModelPricing)
+var modelPricingMapMutex sync.RWMutex
+
+// ParseModelPricing 解析并校验价格表
+func ParseModelPricing(jsonStr string) (map[string]ModelPricing, error) {
+	pricingMap := make(map[string]ModelPricing)
+	if err := json.Unmarshal([]byte(jsonStr), &pricingMap); err != nil {
+		return nil, err
+	}
+	for name, pricing := range pricingMap {
+		if err := pricing.Validate(); err != nil {
+			return nil, fmt.Errorf("model %s: %w", name, err)
+		}
+	}
+	return pricingMap, nil
+}
+
+func ModelPricing2JSONString() string {
+	modelPricingMapMutex.RLock()
+	defer modelPricingMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(modelPricingMap)
+	if err != nil {
+		common.SysError("error marshalling model pricing: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateModelPricingByJSONString(jsonStr string) error {
+	pricingMap, err := ParseModelPricing(jsonStr)
+	if err != nil {
+		return err
+	}
+	modelPricingMapMutex.Lock()
+	defer modelPricingMapMutex.Unlock()
+	modelPricingMap = pricingMap
+	return nil
+}
+
+func GetModelPricing(name string) (ModelPricing, bool) {
+	modelPricingMapMutex.RLock()
+	defer modelPricingMapMutex.RUnlock()
+	pricing, ok := modelPricingMap[name]
+	return pricing, ok
+}
+
+// getTokenPricing 返回按 token 计费的价格表配置，模型名称与 GetModelRatio 一样先归一化
+func getTokenPricing(name string) (ModelPricing, bool) {
+	if strings.HasPrefix(name, "gpt-4-gizmo") {
+		name = "gpt-4-gizmo-*"
+	}
+	pricing, ok := GetModelPricing(name)
+	return pricing, ok && pricing.hasTokenPrice()
+}
+
+// IsFreeInputModel 价格表中输入免费的模型，输入 token 不计费
+func IsFreeInputModel(name string) bool {
+	pricing, ok := getTokenPricing(name)
+	return ok && pricing.FreeInput()
+}
+
+func GetModelPricingCopy() map[string]ModelPricing {
+	modelPricingMapMutex.RLock()
+	defer modelPricingMapMutex.RUnlock()
+	pricingMap := make(map[string]ModelPricing, len(modelPricingMap))
+	for name, pricing := range modelPricingMap {
+		pricingMap[name] = pricing
+	}
+	return pricingMap
+}
+
+// IsPerRequestPricing 模型在价格表中配置了单次价格，按次计费时不需要计算 token
+func IsPerRequestPricing(name string) bool {
+	pricing, ok := GetModelPricing(name)
+	return ok && pricing.PerRequest > 0
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*1e6) / 1e6
+}
+
+// ModelPricingFromRatios 把模型倍率和补全倍率换算为价格表，已在价格表中的模型和按次计费的模型不处理
+func ModelPricingFromRatios() map[string]ModelPricing {
+	modelRatioMapMutex.RLock()
+	ratios := make(map[string]float64, len(modelRatioMap))
+	for name, ratio := range modelRatioMap {
+		ratios[name] = ratio
+	}
+	modelRatioMapMutex.RUnlock()
+
+	pricingMap := GetModelPricingCopy()
+	for name, ratio := range ratios {
+		if _, ok := pricingMap[name]; ok {
+			continue
+		}
+		if _, ok := GetModelPrice(name, false); ok {
+			continue
+		}
+		input := ratio * ratioUSDPerMillion
+		pricing := ModelPricing{
+			Input:    roundPrice(input),
+			Output:   roundPrice(input * GetCompletionRatio(name)),
+			Currency: PricingCurrencyUSD,
+		}
+		if reasoningRatio, ok := GetReasoningRatio(name); ok {
+			pricing.Reasoning = roundPrice(input * reasoningRatio)
+		}
+		pricingMap[name] = pricing
+	}
+	return pricingMap
+}
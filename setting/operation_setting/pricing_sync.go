@@ -0,0 +1,40 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	PricingSyncSourceOpenRouter = "openrouter"
+	PricingSyncSourceLiteLLM    = "litellm"
+)
+
+// PricingSyncSetting 从上游价格源同步模型价格表
+type PricingSyncSetting struct {
+	Enabled bool `json:"enabled"`
+	// Source 价格源的格式，openrouter 为 OpenRouter 的模型列表接口，litellm 为 LiteLLM 的价格文件
+	Source string `json:"source"`
+	URL    string `json:"url"`
+	// Margin 上游价格乘以该倍数后写入价格表
+	Margin float64 `json:"margin"`
+	// AutoApply 定时同步时直接更新价格表，关闭时只标记价格变化的模型，由管理员确认后应用
+	AutoApply       bool `json:"auto_apply_enabled"`
+	IntervalMinutes int  `json:"interval_minutes"`
+}
+
+// 默认配置
+var pricingSyncSetting = PricingSyncSetting{
+	Enabled:         false,
+	Source:          PricingSyncSourceOpenRouter,
+	URL:             "https://openrouter.ai/api/v1/models",
+	Margin:          1,
+	AutoApply:       false,
+	IntervalMinutes: 1440,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("pricing_sync", &pricingSyncSetting)
+}
+
+func GetPricingSyncSetting() *PricingSyncSetting {
+	return &pricingSyncSetting
+}
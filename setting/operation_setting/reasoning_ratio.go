@@ -0,0 +1,47 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"sync"
+)
+
+// 思考倍率与补全倍率一样相对于模型倍率，用于思考 token（reasoning_tokens）与可见输出价格不同的模型，
+// 未配置的模型思考 token 按补全倍率计费
+
+var reasoningRatioMap = make(map[string]float64)
+var reasoningRatioMapMutex sync.RWMutex
+
+func ReasoningRatio2JSONString() string {
+	reasoningRatioMapMutex.RLock()
+	defer reasoningRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(reasoningRatioMap)
+	if err != nil {
+		common.SysError("error marshalling reasoning ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateReasoningRatioByJSONString(jsonStr string) error {
+	ratioMap := make(map[string]float64)
+	if err := json.Unmarshal([]byte(jsonStr), &ratioMap); err != nil {
+		return err
+	}
+	reasoningRatioMapMutex.Lock()
+	defer reasoningRatioMapMutex.Unlock()
+	reasoningRatioMap = ratioMap
+	return nil
+}
+
+// GetReasoningRatio 返回模型单独配置的思考倍率，价格表中配置了思考价格时优先使用价格表
+func GetReasoningRatio(name string) (float64, bool) {
+	if pricing, ok := getTokenPricing(name); ok {
+		if ratio, ok := pricing.ReasoningRatio(); ok {
+			return ratio, true
+		}
+	}
+	reasoningRatioMapMutex.RLock()
+	defer reasoningRatioMapMutex.RUnlock()
+	ratio, ok := reasoningRatioMap[name]
+	return ratio, ok
+}
@@ -0,0 +1,50 @@
+package operation_setting
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"sync"
+)
+
+// defaultServiceTierRatio 不同服务等级相对标准价格的倍率，未配置的等级（例如 auto、default）按 1 计费
+var defaultServiceTierRatio = map[string]float64{
+	"flex":     0.5,
+	"priority": 2,
+}
+
+var serviceTierRatioMap = defaultServiceTierRatio
+var serviceTierRatioMapMutex sync.RWMutex
+
+func ServiceTierRatio2JSONString() string {
+	serviceTierRatioMapMutex.RLock()
+	defer serviceTierRatioMapMutex.RUnlock()
+	jsonBytes, err := json.Marshal(serviceTierRatioMap)
+	if err != nil {
+		common.SysError("error marshalling service tier ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateServiceTierRatioByJSONString(jsonStr string) error {
+	ratioMap := make(map[string]float64)
+	if err := json.Unmarshal([]byte(jsonStr), &ratioMap); err != nil {
+		return err
+	}
+	serviceTierRatioMapMutex.Lock()
+	defer serviceTierRatioMapMutex.Unlock()
+	serviceTierRatioMap = ratioMap
+	return nil
+}
+
+func GetServiceTierRatio(tier string) float64 {
+	if tier == "" {
+		return 1
+	}
+	serviceTierRatioMapMutex.RLock()
+	defer serviceTierRatioMapMutex.RUnlock()
+	ratio, ok := serviceTierRatioMap[tier]
+	if !ok {
+		return 1
+	}
+	return ratio
+}
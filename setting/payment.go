@@ -6,3 +6,9 @@ var EpayId = ""
 var EpayKey = ""
 var Price = 7.3
 var MinTopUp = 1
+
+// Stripe 支付，StripeUnitPrice 为每单位充值数量在 StripeCurrency 币种下的价格
+var StripeApiSecret = ""
+var StripeWebhookSecret = ""
+var StripeCurrency = "usd"
+var StripeUnitPrice = 1.0
@@ -62,3 +62,80 @@ func CheckModelRequestRateLimitGroup(jsonStr string) error {
 
 	return nil
 }
+
+// TokenRateLimitTier 令牌限流等级，用户可以消耗额度为单个令牌购买，购买后该令牌单独计数，不再受分组和全局限流影响
+type TokenRateLimitTier struct {
+	Name         string `json:"name"`
+	Count        int    `json:"count"`         // 限制周期内最多请求次数（包括失败），0 表示不限制
+	SuccessCount int    `json:"success_count"` // 限制周期内最多请求完成次数
+	Quota        int    `json:"quota"`         // 购买消耗的额度
+	Days         int    `json:"days"`          // 有效天数，0 表示永久有效
+}
+
+var TokenRateLimitTiers = []TokenRateLimitTier{}
+var TokenRateLimitTiersMutex sync.RWMutex
+
+func TokenRateLimitTiers2JSONString() string {
+	TokenRateLimitTiersMutex.RLock()
+	defer TokenRateLimitTiersMutex.RUnlock()
+
+	jsonBytes, err := json.Marshal(TokenRateLimitTiers)
+	if err != nil {
+		common.SysError("error marshalling token rate limit tiers: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateTokenRateLimitTiersByJSONString(jsonStr string) error {
+	TokenRateLimitTiersMutex.Lock()
+	defer TokenRateLimitTiersMutex.Unlock()
+
+	TokenRateLimitTiers = make([]TokenRateLimitTier, 0)
+	return json.Unmarshal([]byte(jsonStr), &TokenRateLimitTiers)
+}
+
+func GetTokenRateLimitTiers() []TokenRateLimitTier {
+	TokenRateLimitTiersMutex.RLock()
+	defer TokenRateLimitTiersMutex.RUnlock()
+
+	tiers := make([]TokenRateLimitTier, len(TokenRateLimitTiers))
+	copy(tiers, TokenRateLimitTiers)
+	return tiers
+}
+
+func GetTokenRateLimitTier(name string) (TokenRateLimitTier, bool) {
+	if name == "" {
+		return TokenRateLimitTier{}, false
+	}
+	TokenRateLimitTiersMutex.RLock()
+	defer TokenRateLimitTiersMutex.RUnlock()
+
+	for _, tier := range TokenRateLimitTiers {
+		if tier.Name == name {
+			return tier, true
+		}
+	}
+	return TokenRateLimitTier{}, false
+}
+
+func CheckTokenRateLimitTiers(jsonStr string) error {
+	var tiers []TokenRateLimitTier
+	err := json.Unmarshal([]byte(jsonStr), &tiers)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, tier := range tiers {
+		if tier.Name == "" {
+			return fmt.Errorf("token rate limit tier name is empty")
+		}
+		if names[tier.Name] {
+			return fmt.Errorf("duplicate token rate limit tier: %s", tier.Name)
+		}
+		names[tier.Name] = true
+		if tier.Count < 0 || tier.SuccessCount < 1 || tier.Quota < 0 || tier.Days < 0 {
+			return fmt.Errorf("token rate limit tier %s has invalid values", tier.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"sync"
+)
+
+const defaultSubscriptionPeriodDays = 30
+
+// SubscriptionPlan 订阅套餐，订阅期间每个周期开始时发放 Quota 额度，只能调用 Models 中的模型（为空不限制），
+// 每分钟最多请求 RPM 次（0 不限制）
+type SubscriptionPlan struct {
+	Name       string   `json:"name"`
+	Quota      int      `json:"quota"` // 每个周期发放的额度
+	Models     []string `json:"models"`
+	RPM        int      `json:"rpm"`
+	Price      int      `json:"price"`       // 购买一个周期消耗的额度，0 表示用户不能购买，只能由管理员分配
+	PeriodDays int      `json:"period_days"` // 周期天数，默认 30 天
+}
+
+func (plan SubscriptionPlan) GetPeriodSeconds() int64 {
+	days := plan.PeriodDays
+	if days <= 0 {
+		days = defaultSubscriptionPeriodDays
+	}
+	return int64(days) * 24 * 60 * 60
+}
+
+func (plan SubscriptionPlan) AllowModel(model string) bool {
+	if len(plan.Models) == 0 {
+		return true
+	}
+	for _, m := range plan.Models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+var subscriptionPlans = []SubscriptionPlan{}
+var subscriptionPlansMutex sync.RWMutex
+
+func SubscriptionPlans2JSONString() string {
+	subscriptionPlansMutex.RLock()
+	defer subscriptionPlansMutex.RUnlock()
+
+	jsonBytes, err := json.Marshal(subscriptionPlans)
+	if err != nil {
+		common.SysError("error marshalling subscription plans: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateSubscriptionPlansByJSONString(jsonStr string) error {
+	subscriptionPlansMutex.Lock()
+	defer subscriptionPlansMutex.Unlock()
+
+	subscriptionPlans = make([]SubscriptionPlan, 0)
+	return json.Unmarshal([]byte(jsonStr), &subscriptionPlans)
+}
+
+func GetSubscriptionPlans() []SubscriptionPlan {
+	subscriptionPlansMutex.RLock()
+	defer subscriptionPlansMutex.RUnlock()
+
+	plans := make([]SubscriptionPlan, len(subscriptionPlans))
+	copy(plans, subscriptionPlans)
+	return plans
+}
+
+func GetSubscriptionPlan(name string) (SubscriptionPlan, bool) {
+	if name == "" {
+		return SubscriptionPlan{}, false
+	}
+	subscriptionPlansMutex.RLock()
+	defer subscriptionPlansMutex.RUnlock()
+
+	for _, plan := range subscriptionPlans {
+		if plan.Name == name {
+			return plan, true
+		}
+	}
+	return SubscriptionPlan{}, false
+}
+
+func CheckSubscriptionPlans(jsonStr string) error {
+	var plans []SubscriptionPlan
+	err := json.Unmarshal([]byte(jsonStr), &plans)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, plan := range plans {
+		if plan.Name == "" {
+			return fmt.Errorf("subscription plan name is empty")
+		}
+		if names[plan.Name] {
+			return fmt.Errorf("duplicate subscription plan: %s", plan.Name)
+		}
+		names[plan.Name] = true
+		if plan.Quota < 0 || plan.RPM < 0 || plan.Price < 0 || plan.PeriodDays < 0 {
+			return fmt.Errorf("subscription plan %s has invalid values", plan.Name)
+		}
+	}
+	return nil
+}
@@ -255,6 +255,19 @@ const ChannelsTable = () => {
       key: COLUMN_KEYS.NAME,
       title: t('名称'),
       dataIndex: 'name',
+      render: (text, record, index) => {
+        if (!record.shadow_source_id) {
+          return text;
+        }
+        return (
+          <Space spacing={2}>
+            {text}
+            <Tag color='grey' size='small'>
+              {t('影子')} #{record.shadow_source_id}
+            </Tag>
+          </Space>
+        );
+      },
     },
     {
       key: COLUMN_KEYS.GROUP,
@@ -1097,12 +1110,28 @@ const ChannelsTable = () => {
     }
   };
 
+  const syncShadowChannels = async () => {
+    const res = await API.post(`/api/channel/shadow/sync`);
+    const { success, message, data } = res.data;
+    if (success) {
+      showSuccess(
+        t('同步完成，新增 ${created} 个，更新 ${updated} 个，移除 ${removed} 个')
+          .replace('${created}', data.created)
+          .replace('${updated}', data.updated)
+          .replace('${removed}', data.removed),
+      );
+      await refresh();
+    } else {
+      showError(message);
+    }
+  };
+
   const updateAllChannelsBalance = async () => {
     setUpdatingBalance(true);
     const res = await API.get(`/api/channel/update_balance`);
     const { success, message } = res.data;
     if (success) {
-      showInfo(t('已更新完毕所有已启用通道余额！'));
+      showInfo(t('已开始更新所有已启用通道余额，请稍后刷新页面查看结果。'));
     } else {
       showError(message);
     }
@@ -1460,6 +1489,22 @@ const ChannelsTable = () => {
                       </Button>
                     </Popconfirm>
                   </Dropdown.Item>
+                  <Dropdown.Item>
+                    <Popconfirm
+                      title={t('确定是否要同步生产环境渠道？')}
+                      content={t('仅预发布环境可用，会以只读影子渠道的形式导入生产环境的渠道定义')}
+                      okType={'secondary'}
+                      onConfirm={syncShadowChannels}
+                    >
+                      <Button
+                        theme='light'
+                        type='secondary'
+                        style={{ width: '100%' }}
+                      >
+                        {t('同步生产环境渠道')}
+                      </Button>
+                    </Popconfirm>
+                  </Dropdown.Item>
                 </Dropdown.Menu>
               }
             >
@@ -33,6 +33,7 @@ import {
   renderClaudeModelPrice,
   renderClaudeModelPriceSimple,
   renderGroup,
+  renderDisplayAmount,
   renderLogContent,
   renderModelPrice,
   renderModelPriceSimple,
@@ -773,6 +774,7 @@ const LogsTable = () => {
 
   const [stat, setStat] = useState({
     quota: 0,
+    amount: 0,
     token: 0,
   });
 
@@ -910,12 +912,24 @@ const LogsTable = () => {
           value: other.cache_tokens,
         });
       }
+      if (other?.cache_savings > 0) {
+        expandDataLocal.push({
+          key: t('缓存节省'),
+          value: renderQuota(other.cache_savings, 6),
+        });
+      }
       if (other?.cache_creation_tokens > 0) {
         expandDataLocal.push({
           key: t('缓存创建 Tokens'),
           value: other.cache_creation_tokens,
         });
       }
+      if (other?.reasoning_tokens > 0) {
+        expandDataLocal.push({
+          key: t('思考 Tokens'),
+          value: other.reasoning_tokens,
+        });
+      }
       if (logs[i].type === 2) {
         expandDataLocal.push({
           key: t('日志详情'),
@@ -1019,6 +1033,30 @@ const LogsTable = () => {
             value: other.reasoning_effort,
           });
         }
+        if (other?.free_model) {
+          expandDataLocal.push({
+            key: t('免费模型'),
+            value: t('不扣除额度'),
+          });
+        }
+        if (other?.volume_tier_ratio !== undefined) {
+          expandDataLocal.push({
+            key: t('用量阶梯倍率'),
+            value: `${other.volume_tier_ratio} (${t('本月已用')} ${other.monthly_tokens} tokens)`,
+          });
+        }
+        if (other?.service_tier) {
+          expandDataLocal.push({
+            key: t('服务等级'),
+            value: `${other.service_tier} (x${other.service_tier_ratio})`,
+          });
+        }
+        if (other?.param_adjustments?.length > 0) {
+          expandDataLocal.push({
+            key: t('参数调整'),
+            value: other.param_adjustments.join(', '),
+          });
+        }
       }
       expandDatesLocal[logs[i].key] = expandDataLocal;
     }
@@ -1070,6 +1108,26 @@ const LogsTable = () => {
       });
   };
 
+  const exportUsageStatement = async (format) => {
+    let localStartTimestamp = Date.parse(start_timestamp) / 1000;
+    let localEndTimestamp = Date.parse(end_timestamp) / 1000;
+    const res = await API.get(
+      `/api/user/self/usage_statement?format=${format}&start_timestamp=${localStartTimestamp}&end_timestamp=${localEndTimestamp}`,
+      { responseType: 'blob' },
+    );
+    if (res.data.type === 'application/json') {
+      const { message } = JSON.parse(await res.data.text());
+      showError(message);
+      return;
+    }
+    const url = URL.createObjectURL(res.data);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = `usage-statement-${localStartTimestamp}.${format}`;
+    link.click();
+    URL.revokeObjectURL(url);
+  };
+
   const refresh = async () => {
     setActivePage(1);
     handleEyeClick();
@@ -1118,7 +1176,7 @@ const LogsTable = () => {
                   boxShadow: '0 2px 8px rgba(0, 0, 0, 0.1)',
                 }}
               >
-                {t('消耗额度')}: {renderQuota(stat.quota)}
+                {t('消耗额度')}: {renderDisplayAmount(stat.amount)}
               </Tag>
               <Tag
                 color='pink'
@@ -1277,6 +1335,22 @@ const LogsTable = () => {
           >
             {t('列设置')}
           </Button>
+          <Button
+            theme='light'
+            type='tertiary'
+            onClick={() => exportUsageStatement('csv')}
+            style={{ marginLeft: 8 }}
+          >
+            {t('导出用量明细 (CSV)')}
+          </Button>
+          <Button
+            theme='light'
+            type='tertiary'
+            onClick={() => exportUsageStatement('pdf')}
+            style={{ marginLeft: 8 }}
+          >
+            {t('导出用量明细 (PDF)')}
+          </Button>
         </div>
         <Table
           style={{ marginTop: 5 }}
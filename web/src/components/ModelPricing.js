@@ -231,6 +231,15 @@ const ModelPricing = () => {
               {record.quota_type === 0 ? completionRatio : t('无')}
             </Text>
             <br />
+            {record.quota_type === 0 && record.reasoning_ratio ? (
+              <>
+                <Text>
+                  {t('思考倍率')}：
+                  {parseFloat(record.reasoning_ratio.toFixed(3))}
+                </Text>
+                <br />
+              </>
+            ) : null}
             <Text>
               {t('分组倍率')}：{groupRatio[selectedGroup]}
             </Text>
@@ -246,13 +255,20 @@ const ModelPricing = () => {
         let content = text;
         if (record.quota_type === 0) {
           // 这里的 *2 是因为 1倍率=0.002刀，请勿删除
-          let inputRatioPrice =
-            record.model_ratio * 2 * groupRatio[selectedGroup];
+          // 输入免费的模型倍率按输出价格换算
+          let inputRatioPrice = record.free_input
+            ? 0
+            : record.model_ratio * 2 * groupRatio[selectedGroup];
           let completionRatioPrice =
             record.model_ratio *
             record.completion_ratio *
             2 *
             groupRatio[selectedGroup];
+          let reasoningRatioPrice =
+            record.model_ratio *
+            record.reasoning_ratio *
+            2 *
+            groupRatio[selectedGroup];
           content = (
             <>
               <Text>
@@ -262,6 +278,14 @@ const ModelPricing = () => {
               <Text>
                 {t('补全')} ${completionRatioPrice} / 1M tokens
               </Text>
+              {record.reasoning_ratio ? (
+                <>
+                  <br />
+                  <Text>
+                    {t('思考')} ${reasoningRatioPrice} / 1M tokens
+                  </Text>
+                </>
+              ) : null}
             </>
           );
         } else {
@@ -6,6 +6,7 @@ import { useTranslation } from 'react-i18next';
 import SettingGeminiModel from '../pages/Setting/Model/SettingGeminiModel.js';
 import SettingClaudeModel from '../pages/Setting/Model/SettingClaudeModel.js';
 import SettingGlobalModel from '../pages/Setting/Model/SettingGlobalModel.js';
+import SettingPricingSync from '../pages/Setting/Model/SettingPricingSync.js';
 
 const ModelSetting = () => {
   const { t } = useTranslation();
@@ -18,10 +19,24 @@ const ModelSetting = () => {
     'claude.default_max_tokens': '',
     'claude.thinking_adapter_budget_tokens_percentage': 0.8,
     'global.pass_through_request_enabled': false,
+    'global.provenance_enabled': false,
+    'global.system_fingerprint_channel_tag_enabled': false,
+    'global.upstream_model_list_enabled': false,
+    'tool_call.model_profiles': '',
+    'output_rule.model_rules': '',
+    'agent_loop.enabled': false,
+    'agent_loop.max_steps': 5,
+    'agent_loop.tools': '',
     'general_setting.ping_interval_enabled': false,
     'general_setting.ping_interval_seconds': 60,
     'gemini.thinking_adapter_enabled': false,
     'gemini.thinking_adapter_budget_tokens_percentage': 0.6,
+    'pricing_sync.enabled': false,
+    'pricing_sync.source': 'openrouter',
+    'pricing_sync.url': '',
+    'pricing_sync.margin': 1,
+    'pricing_sync.auto_apply_enabled': false,
+    'pricing_sync.interval_minutes': 1440,
   });
 
   let [loading, setLoading] = useState(false);
@@ -37,6 +52,9 @@ const ModelSetting = () => {
           item.key === 'gemini.version_settings' ||
           item.key === 'claude.model_headers_settings' ||
           item.key === 'claude.default_max_tokens' ||
+          item.key === 'tool_call.model_profiles' ||
+          item.key === 'output_rule.model_rules' ||
+          item.key === 'agent_loop.tools' ||
           item.key === 'gemini.supported_imagine_models'
         ) {
           if (item.value !== '') {
@@ -87,6 +105,10 @@ const ModelSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingClaudeModel options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 价格同步 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingPricingSync options={inputs} refresh={onRefresh} />
+        </Card>
       </Spin>
     </>
   );
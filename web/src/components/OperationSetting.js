@@ -27,9 +27,18 @@ const OperationSetting = () => {
     StreamCacheQueueLength: 0,
     ModelRatio: '',
     CacheRatio: '',
+    CreateCacheRatio: '',
+    ModelPricing: '',
+    ImagePricing: '',
+    FreeModels: '',
+    ModelMaxTokens: '',
+    ServiceTierRatio: '',
     CompletionRatio: '',
+    ReasoningRatio: '',
     ModelPrice: '',
     GroupRatio: '',
+    GroupModelRatio: '',
+    GroupVolumeTiers: '',
     UserUsableGroups: '',
     TopUpLink: '',
     'general_setting.docs_link': '',
@@ -40,6 +49,9 @@ const OperationSetting = () => {
     ChannelDisableThreshold: 0,
     LogConsumeEnabled: false,
     DisplayInCurrencyEnabled: false,
+    'general_setting.display_currency': '',
+    'general_setting.display_currency_symbol': '',
+    'general_setting.display_exchange_rate': '',
     DisplayTokenStatEnabled: false,
     CheckSensitiveEnabled: false,
     CheckSensitiveOnPromptEnabled: false,
@@ -74,10 +86,19 @@ const OperationSetting = () => {
         if (
           item.key === 'ModelRatio' ||
           item.key === 'GroupRatio' ||
+          item.key === 'GroupModelRatio' ||
+          item.key === 'GroupVolumeTiers' ||
           item.key === 'UserUsableGroups' ||
           item.key === 'CompletionRatio' ||
+          item.key === 'ReasoningRatio' ||
           item.key === 'ModelPrice' ||
-          item.key === 'CacheRatio'
+          item.key === 'CacheRatio' ||
+          item.key === 'CreateCacheRatio' ||
+          item.key === 'ModelPricing' ||
+          item.key === 'ImagePricing' ||
+          item.key === 'FreeModels' ||
+          item.key === 'ModelMaxTokens' ||
+          item.key === 'ServiceTierRatio'
         ) {
           item.value = JSON.stringify(JSON.parse(item.value), null, 2);
         }
@@ -57,6 +57,7 @@ const PersonalSetting = () => {
     email_verification_code: '',
     email: '',
     self_account_deletion_confirmation: '',
+    self_account_deletion_reason: '',
     original_password: '',
     set_new_password: '',
     set_new_password_confirmation: '',
@@ -92,6 +93,12 @@ const PersonalSetting = () => {
     acceptUnsetModelRatioModel: false,
   });
   const [showWebhookDocs, setShowWebhookDocs] = useState(false);
+  // 账户额度的预算预警，百分比阈值以逗号分隔
+  const [budgetAlert, setBudgetAlert] = useState({
+    thresholds: '',
+    period: 'month',
+    exists: false,
+  });
 
   useEffect(() => {
     let status = localStorage.getItem('status');
@@ -108,6 +115,7 @@ const PersonalSetting = () => {
     });
     loadModels().then();
     getAffLink().then();
+    getBudgetAlert().then();
     setTransferAmount(getQuotaPerUnit());
   }, []);
 
@@ -171,6 +179,47 @@ const PersonalSetting = () => {
     }
   };
 
+  const getBudgetAlert = async () => {
+    const res = await API.get('/api/user/budget_alerts');
+    const { success, data } = res.data;
+    if (success) {
+      const alert = (data || []).find((item) => item.token_id === 0);
+      if (alert) {
+        setBudgetAlert({
+          thresholds: alert.thresholds,
+          period: alert.period,
+          exists: true,
+        });
+      }
+    }
+  };
+
+  const saveBudgetAlert = async () => {
+    const thresholds = budgetAlert.thresholds
+      .split(',')
+      .map((value) => value.trim())
+      .filter((value) => value !== '')
+      .map((value) => parseInt(value));
+    if (thresholds.length === 0) {
+      if (budgetAlert.exists) {
+        await API.delete('/api/user/budget_alert?token_id=0');
+        setBudgetAlert({ ...budgetAlert, exists: false });
+      }
+      return true;
+    }
+    const res = await API.put('/api/user/budget_alert', {
+      token_id: 0,
+      thresholds,
+      period: budgetAlert.period,
+    });
+    if (!res.data.success) {
+      showError(res.data.message);
+      return false;
+    }
+    setBudgetAlert({ ...budgetAlert, exists: true });
+    return true;
+  };
+
   const getUserData = async () => {
     let res = await API.get(`/api/user/self`);
     const { success, message, data } = res.data;
@@ -211,20 +260,31 @@ const PersonalSetting = () => {
       return;
     }
 
-    const res = await API.delete('/api/user/self');
+    const res = await API.post('/api/user/self/erasure', {
+      reason: inputs.self_account_deletion_reason,
+    });
     const { success, message } = res.data;
 
     if (success) {
-      showSuccess(t('账户已删除！'));
-      await API.get('/api/user/logout');
-      userDispatch({ type: 'logout' });
-      localStorage.removeItem('user');
-      navigate('/login');
+      showSuccess(t('注销申请已提交，管理员审核通过后账户将被删除'));
+      setShowAccountDeleteModal(false);
     } else {
       showError(message);
     }
   };
 
+  const exportSelfData = async () => {
+    const res = await API.get('/api/user/self/export', {
+      responseType: 'blob',
+    });
+    const url = URL.createObjectURL(res.data);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = `user-data-${userState.user.id}.zip`;
+    link.click();
+    URL.revokeObjectURL(url);
+  };
+
   const bindWeChat = async () => {
     if (inputs.wechat_verification_code === '') return;
     const res = await API.get(
@@ -374,6 +434,9 @@ const PersonalSetting = () => {
       });
 
       if (res.data.success) {
+        if (!(await saveBudgetAlert())) {
+          return;
+        }
         showSuccess(t('通知设置已更新'));
         await getUserData();
       } else {
@@ -786,6 +849,9 @@ const PersonalSetting = () => {
                   >
                     {t('修改密码')}
                   </Button>
+                  <Button onClick={exportSelfData}>
+                    {t('导出个人数据')}
+                  </Button>
                   <Button
                     type={'danger'}
                     onClick={() => {
@@ -1006,6 +1072,40 @@ const PersonalSetting = () => {
                       )}
                     </Typography.Text>
                   </div>
+                  <div style={{ marginTop: 20 }}>
+                    <Typography.Text strong>{t('预算预警')}</Typography.Text>
+                    <div style={{ marginTop: 10, display: 'flex', gap: 8 }}>
+                      <Input
+                        value={budgetAlert.thresholds}
+                        onChange={(val) =>
+                          setBudgetAlert({ ...budgetAlert, thresholds: val })
+                        }
+                        style={{ width: 200 }}
+                        placeholder={t('例如 80,95')}
+                        suffix='%'
+                      />
+                      <Select
+                        value={budgetAlert.period}
+                        onChange={(val) =>
+                          setBudgetAlert({ ...budgetAlert, period: val })
+                        }
+                        style={{ width: 120 }}
+                        optionList={[
+                          { value: 'day', label: t('每天') },
+                          { value: 'week', label: t('每周') },
+                          { value: 'month', label: t('每月') },
+                        ]}
+                      />
+                    </div>
+                    <Typography.Text
+                      type='secondary'
+                      style={{ marginTop: 10, display: 'block' }}
+                    >
+                      {t(
+                        '当前周期内消费的额度占周期预算（周期内消费加剩余额度）的比例达到阈值时发送通知，每个周期内每个阈值只通知一次，留空则不预警',
+                      )}
+                    </Typography.Text>
+                  </div>
                 </TabPane>
                 <TabPane tab={t('价格设置')} itemKey='price'>
                   <div style={{ marginTop: 20 }}>
@@ -1108,7 +1208,9 @@ const PersonalSetting = () => {
               <div style={{ marginTop: 20 }}>
                 <Banner
                   type='danger'
-                  description='您正在删除自己的帐户，将清空所有数据且不可恢复'
+                  description={t(
+                    '您正在申请删除自己的帐户，管理员审核通过后将删除账户和令牌并匿名化使用记录，且不可恢复',
+                  )}
                   closeIcon={null}
                 />
               </div>
@@ -1124,6 +1226,15 @@ const PersonalSetting = () => {
                     )
                   }
                 />
+                <Input
+                  style={{ marginTop: 10 }}
+                  placeholder={t('注销原因（可选）')}
+                  name='self_account_deletion_reason'
+                  value={inputs.self_account_deletion_reason}
+                  onChange={(value) =>
+                    handleInputChange('self_account_deletion_reason', value)
+                  }
+                />
                 {turnstileEnabled ? (
                   <Turnstile
                     sitekey={turnstileSiteKey}
@@ -14,6 +14,9 @@ const RateLimitSetting = () => {
     ModelRequestRateLimitSuccessCount: 1000,
     ModelRequestRateLimitDurationMinutes: 1,
     ModelRequestRateLimitGroup: '',
+    TokenRateLimitTiers: '',
+    SubscriptionPlans: '',
+    LegacyKeyAuthGroups: '',
   });
 
   let [loading, setLoading] = useState(false);
@@ -24,7 +27,12 @@ const RateLimitSetting = () => {
     if (success) {
       let newInputs = {};
       data.forEach((item) => {
-      if (item.key === 'ModelRequestRateLimitGroup') {
+      if (
+        item.key === 'ModelRequestRateLimitGroup' ||
+        item.key === 'TokenRateLimitTiers' ||
+        item.key === 'SubscriptionPlans' ||
+        item.key === 'LegacyKeyAuthGroups'
+      ) {
         item.value = JSON.stringify(JSON.parse(item.value), null, 2);
       }
 
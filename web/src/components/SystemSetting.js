@@ -53,6 +53,10 @@ const SystemSetting = () => {
     TopupGroupRatio: '',
     PayAddress: '',
     CustomCallbackAddress: '',
+    StripeApiSecret: '',
+    StripeWebhookSecret: '',
+    StripeCurrency: 'usd',
+    StripeUnitPrice: 1,
     Footer: '',
     WeChatAuthEnabled: '',
     WeChatServerAddress: '',
@@ -117,6 +121,7 @@ const SystemSetting = () => {
             break;
           case 'Price':
           case 'MinTopUp':
+          case 'StripeUnitPrice':
             item.value = parseFloat(item.value);
             break;
           default:
@@ -260,6 +265,33 @@ const SystemSetting = () => {
     await updateOptions(options);
   };
 
+  const submitStripe = async () => {
+    const options = [];
+    if (inputs.StripeApiSecret !== undefined && inputs.StripeApiSecret !== '') {
+      options.push({ key: 'StripeApiSecret', value: inputs.StripeApiSecret });
+    }
+    if (
+      inputs.StripeWebhookSecret !== undefined &&
+      inputs.StripeWebhookSecret !== ''
+    ) {
+      options.push({
+        key: 'StripeWebhookSecret',
+        value: inputs.StripeWebhookSecret,
+      });
+    }
+    if (inputs.StripeCurrency !== '') {
+      options.push({ key: 'StripeCurrency', value: inputs.StripeCurrency });
+    }
+    if (inputs.StripeUnitPrice !== '') {
+      options.push({
+        key: 'StripeUnitPrice',
+        value: inputs.StripeUnitPrice.toString(),
+      });
+    }
+
+    await updateOptions(options);
+  };
+
   const submitSMTP = async () => {
     const options = [];
 
@@ -662,6 +694,60 @@ const SystemSetting = () => {
                 </Form.Section>
               </Card>
 
+              <Card>
+                <Form.Section text='Stripe 支付设置'>
+                  <Text>
+                    Webhook 地址为{' '}
+                    {removeTrailingSlash(inputs.ServerAddress) +
+                      '/api/user/stripe/webhook'}
+                    ，需要订阅 checkout.session.completed、
+                    checkout.session.async_payment_succeeded 和 charge.refunded
+                    事件
+                  </Text>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field='StripeApiSecret'
+                        label='Stripe API 密钥'
+                        placeholder='敏感信息不会发送到前端显示'
+                        type='password'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field='StripeWebhookSecret'
+                        label='Stripe Webhook 签名密钥'
+                        placeholder='敏感信息不会发送到前端显示'
+                        type='password'
+                      />
+                    </Col>
+                  </Row>
+                  <Row
+                    gutter={{ xs: 8, sm: 16, md: 24, lg: 24, xl: 24, xxl: 24 }}
+                    style={{ marginTop: 16 }}
+                  >
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.Input
+                        field='StripeCurrency'
+                        label='Stripe 支付币种'
+                        placeholder='例如：usd'
+                      />
+                    </Col>
+                    <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                      <Form.InputNumber
+                        field='StripeUnitPrice'
+                        precision={2}
+                        label='Stripe 充值价格（每美金）'
+                        placeholder='例如：1，就是每美金支付 1 单位所选币种'
+                      />
+                    </Col>
+                  </Row>
+                  <Button onClick={submitStripe}>更新 Stripe 设置</Button>
+                </Form.Section>
+              </Card>
+
               <Card>
                 <Form.Section text='配置登录注册'>
                   <Row
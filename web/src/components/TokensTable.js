@@ -284,6 +284,23 @@ const TokensTable = () => {
             >
               {t('编辑')}
             </Button>
+            {rateLimitTiers.length > 0 && (
+              <Dropdown
+                trigger='click'
+                position='bottomRight'
+                menu={rateLimitTiers.map((tier) => ({
+                  node: 'item',
+                  name:
+                    tier.name +
+                    (record.rate_limit_tier === tier.name ? ' ✓' : ''),
+                  onClick: () => purchaseRateLimitTier(record, tier),
+                }))}
+              >
+                <Button theme='light' type='tertiary' style={{ marginRight: 1 }}>
+                  {t('限流等级')}
+                </Button>
+              </Dropdown>
+            )}
           </div>
         );
       },
@@ -379,6 +396,50 @@ const TokensTable = () => {
     window.open(url, '_blank');
   };
 
+  const [rateLimitTiers, setRateLimitTiers] = useState([]);
+
+  const loadRateLimitTiers = async () => {
+    const res = await API.get('/api/token/rate_limit_tiers');
+    const { success, data } = res.data;
+    if (success && Array.isArray(data)) {
+      setRateLimitTiers(data);
+    }
+  };
+
+  const purchaseRateLimitTier = (record, tier) => {
+    Modal.confirm({
+      title: t('购买限流等级'),
+      content: t(
+        '将为令牌 {{token}} 购买限流等级 {{tier}}，消耗 {{quota}}，有效期 {{days}}',
+        {
+          token: record.name,
+          tier: tier.name,
+          quota: renderQuota(tier.quota),
+          days: tier.days > 0 ? tier.days + t('天') : t('永久'),
+        },
+      ),
+      onOk: async () => {
+        const res = await API.post(`/api/token/${record.id}/rate_limit_tier`, {
+          tier: tier.name,
+        });
+        const { success, message, data } = res.data;
+        if (success) {
+          showSuccess(t('购买成功'));
+          record.rate_limit_tier = data.rate_limit_tier;
+          record.rate_limit_tier_expired_time =
+            data.rate_limit_tier_expired_time;
+          setTokensFormat([...tokens]);
+        } else {
+          showError(message);
+        }
+      },
+    });
+  };
+
+  useEffect(() => {
+    loadRateLimitTiers().then();
+  }, []);
+
   useEffect(() => {
     loadTokens(0)
       .then()
@@ -13,6 +13,7 @@ import { ITEMS_PER_PAGE } from '../constants';
 import { renderGroup, renderNumber, renderQuota } from '../helpers/render';
 import AddUser from '../pages/User/AddUser';
 import EditUser from '../pages/User/EditUser';
+import ErasureRequests from '../pages/User/ErasureRequests';
 import { useTranslation } from 'react-i18next';
 
 const UsersTable = () => {
@@ -243,6 +244,7 @@ const UsersTable = () => {
   const [userCount, setUserCount] = useState(ITEMS_PER_PAGE);
   const [showAddUser, setShowAddUser] = useState(false);
   const [showEditUser, setShowEditUser] = useState(false);
+  const [showErasureRequests, setShowErasureRequests] = useState(false);
   const [editingUser, setEditingUser] = useState({
     id: undefined,
   });
@@ -433,6 +435,11 @@ const UsersTable = () => {
         handleClose={closeEditUser}
         editingUser={editingUser}
       ></EditUser>
+      <ErasureRequests
+        refresh={refresh}
+        visible={showErasureRequests}
+        handleClose={() => setShowErasureRequests(false)}
+      ></ErasureRequests>
       <Form
         onSubmit={() => {
           searchUsers(activePage, pageSize, searchKeyword, searchGroup);
@@ -482,6 +489,15 @@ const UsersTable = () => {
             >
               {t('添加用户')}
             </Button>
+            <Button
+              theme='light'
+              type='warning'
+              onClick={() => {
+                setShowErasureRequests(true);
+              }}
+            >
+              {t('注销申请')}
+            </Button>
           </Space>
         </div>
       </Form>
@@ -125,4 +125,29 @@ export const CHANNEL_OPTIONS = [
     color: 'blue',
     label: 'Coze',
   },
+  {
+    value: 50,
+    color: 'orange',
+    label: '百川',
+  },
+  {
+    value: 51,
+    color: 'orange',
+    label: 'AWS SageMaker',
+  },
+  {
+    value: 52,
+    color: 'blue',
+    label: 'Azure AI 模型推理',
+  },
+  {
+    value: 53,
+    color: 'green',
+    label: 'NVIDIA NIM',
+  },
+  {
+    value: 54,
+    color: 'purple',
+    label: 'Voyage AI',
+  },
 ];
@@ -5,6 +5,14 @@ export function setStatusData(data) {
   localStorage.setItem('footer_html', data.footer_html);
   localStorage.setItem('quota_per_unit', data.quota_per_unit);
   localStorage.setItem('display_in_currency', data.display_in_currency);
+  localStorage.setItem(
+    'display_currency_symbol',
+    data.display_currency_symbol || '$',
+  );
+  localStorage.setItem(
+    'display_exchange_rate',
+    data.display_exchange_rate || 1,
+  );
   localStorage.setItem('enable_drawing', data.enable_drawing);
   localStorage.setItem('enable_task', data.enable_task);
   localStorage.setItem('enable_data_export', data.enable_data_export);
@@ -231,11 +231,34 @@ export function renderQuotaNumberWithDigit(num, digits = 2) {
   let displayInCurrency = localStorage.getItem('display_in_currency');
   num = num.toFixed(digits);
   if (displayInCurrency) {
-    return '$' + num;
+    return getDisplayCurrencySymbol() + num;
   }
   return num;
 }
 
+export function getDisplayCurrencySymbol() {
+  return localStorage.getItem('display_currency_symbol') || '$';
+}
+
+// 1 美元兑换的显示币种金额
+export function getDisplayExchangeRate() {
+  let rate = parseFloat(localStorage.getItem('display_exchange_rate'));
+  if (isNaN(rate) || rate <= 0) {
+    return 1;
+  }
+  return rate;
+}
+
+// 渲染服务端已按显示币种换算好的金额
+export function renderDisplayAmount(amount, digits = 2) {
+  let displayInCurrency = localStorage.getItem('display_in_currency');
+  displayInCurrency = displayInCurrency === 'true';
+  if (displayInCurrency) {
+    return getDisplayCurrencySymbol() + Number(amount || 0).toFixed(digits);
+  }
+  return renderNumber(amount || 0);
+}
+
 export function renderNumberWithPoint(num) {
   if (num === undefined) return '';
   num = num.toFixed(2);
@@ -281,14 +304,17 @@ export function renderUnitWithQuota(quota) {
 export function getQuotaWithUnit(quota, digits = 6) {
   let quotaPerUnit = localStorage.getItem('quota_per_unit');
   quotaPerUnit = parseFloat(quotaPerUnit);
-  return (quota / quotaPerUnit).toFixed(digits);
+  return ((quota / quotaPerUnit) * getDisplayExchangeRate()).toFixed(digits);
 }
 
 export function renderQuotaWithAmount(amount) {
   let displayInCurrency = localStorage.getItem('display_in_currency');
   displayInCurrency = displayInCurrency === 'true';
   if (displayInCurrency) {
-    return '$' + amount;
+    return (
+      getDisplayCurrencySymbol() +
+      parseFloat((amount * getDisplayExchangeRate()).toFixed(2))
+    );
   } else {
     return renderUnitWithQuota(amount);
   }
@@ -300,7 +326,10 @@ export function renderQuota(quota, digits = 2) {
   quotaPerUnit = parseFloat(quotaPerUnit);
   displayInCurrency = displayInCurrency === 'true';
   if (displayInCurrency) {
-    return '$' + (quota / quotaPerUnit).toFixed(digits);
+    return (
+      getDisplayCurrencySymbol() +
+      ((quota / quotaPerUnit) * getDisplayExchangeRate()).toFixed(digits)
+    );
   }
   return renderNumber(quota);
 }
@@ -32,6 +32,8 @@ import { IconHelpCircle } from '@douyinfe/semi-icons';
 
 const MODEL_MAPPING_EXAMPLE = {
   'gpt-3.5-turbo': 'gpt-3.5-turbo-0125',
+  'gpt-4o*': 'my-azure-deployment-*',
+  'regex:claude-3-5-(.*)': 'anthropic.claude-3-5-${1}',
 };
 
 const STATUS_CODE_MAPPING_EXAMPLE = {
@@ -55,6 +57,7 @@ function type2secretPrompt(type) {
     case 23:
       return '按照如下格式输入：AppId|SecretId|SecretKey';
     case 33:
+    case 51:
       return '按照如下格式输入：Ak|Sk|Region';
     default:
       return '请输入渠道对应的鉴权密钥';
@@ -534,6 +537,23 @@ const EditChannel = (props) => {
               />
             </>
           )}
+          {inputs.type === 52 && (
+            <>
+              <div style={{ marginTop: 10 }}>
+                <Typography.Text strong>{t('默认 API 版本')}：</Typography.Text>
+              </div>
+              <Input
+                label={t('默认 API 版本')}
+                name='azure_ai_other'
+                placeholder={t('请输入默认 API 版本，例如：2024-05-01-preview')}
+                onChange={(value) => {
+                  handleInputChange('other', value);
+                }}
+                value={inputs.other}
+                autoComplete='new-password'
+              />
+            </>
+          )}
           {inputs.type === 8 && (
             <>
               <div style={{ marginTop: 10 }}>
@@ -546,7 +566,7 @@ const EditChannel = (props) => {
               </div>
               <div style={{ marginTop: 10 }}>
                 <Typography.Text strong>
-                  {t('完整的 Base URL，支持变量{model}')}：
+                  {t('完整的 Base URL，支持变量{model}、{endpoint}、{path}')}：
                 </Typography.Text>
               </div>
               <Input
@@ -838,6 +858,22 @@ const EditChannel = (props) => {
               />
             </>
           )}
+          {inputs.type === 35 && (
+            <>
+              <div style={{ marginTop: 10 }}>
+                <Typography.Text strong>Group ID：</Typography.Text>
+              </div>
+              <Input
+                name='other'
+                placeholder={'请输入 MiniMax 的 Group ID，语音合成接口需要'}
+                onChange={(value) => {
+                  handleInputChange('other', value);
+                }}
+                value={inputs.other}
+                autoComplete='new-password'
+              />
+            </>
+          )}
           {inputs.type === 49 && (
             <>
               <div style={{ marginTop: 10 }}>
@@ -932,7 +968,7 @@ const EditChannel = (props) => {
           <TextArea
             placeholder={
               t(
-                '此项可选，用于修改请求体中的模型名称，为一个 JSON 字符串，键为请求中模型名称，值为要替换的模型名称，例如：',
+                '此项可选，用于修改请求体中的模型名称，为一个 JSON 字符串，键为请求中模型名称，值为要替换的模型名称；键包含 * 时为通配符，值中的 * 替换为匹配的部分，键以 regex: 开头时为正则表达式，值中可用 ${1} 引用分组；响应中的模型名称会改写为请求的模型名称，例如：',
               ) + `\n${JSON.stringify(MODEL_MAPPING_EXAMPLE, null, 2)}`
             }
             name='model_mapping'
@@ -10,12 +10,49 @@ import {
 } from '../../../helpers';
 import { useTranslation } from 'react-i18next';
 
+const TOOL_CALL_MODEL_PROFILES_EXAMPLE = {
+  'qwen2.5-*': 'hermes',
+  'hermes-3-llama-3.1-70b': 'hermes',
+  'deepseek-r1*': 'markdown',
+};
+
+const OUTPUT_RULE_MODEL_RULES_EXAMPLE = {
+  'qwen2.5-*': [
+    { type: 'stop_trim', values: ['<|im_end|>'] },
+    { type: 'regex_replace', pattern: '\\n{3,}', replacement: '\n\n' },
+  ],
+  'some-model': [{ type: 'prefix_strip', values: ['Sure! '] }],
+};
+
+const AGENT_LOOP_TOOLS_EXAMPLE = [
+  {
+    name: 'get_weather',
+    description: 'Get the current weather of a city',
+    parameters: {
+      type: 'object',
+      properties: { city: { type: 'string' } },
+      required: ['city'],
+    },
+    url: 'https://tools.example.com/weather',
+    method: 'GET',
+    timeout: 10,
+  },
+];
+
 export default function SettingGlobalModel(props) {
   const { t } = useTranslation();
 
   const [loading, setLoading] = useState(false);
   const [inputs, setInputs] = useState({
     'global.pass_through_request_enabled': false,
+    'global.provenance_enabled': false,
+    'global.system_fingerprint_channel_tag_enabled': false,
+    'global.upstream_model_list_enabled': false,
+    'tool_call.model_profiles': '',
+    'output_rule.model_rules': '',
+    'agent_loop.enabled': false,
+    'agent_loop.max_steps': 5,
+    'agent_loop.tools': '',
     'general_setting.ping_interval_enabled': false,
     'general_setting.ping_interval_seconds': 60,
   });
@@ -90,8 +127,171 @@ export default function SettingGlobalModel(props) {
                   }
                 />
               </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  label={t('启用响应来源证明')}
+                  field={'global.provenance_enabled'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'global.provenance_enabled': value,
+                    })
+                  }
+                  extraText={t(
+                    '开启后，中继响应头 X-Provenance 中将包含网关签名的请求 ID、模型和时间戳，下游可通过 /api/provenance/public_key 获取公钥验证',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  label={t('在 system_fingerprint 中标记渠道')}
+                  field={'global.system_fingerprint_channel_tag_enabled'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'global.system_fingerprint_channel_tag_enabled': value,
+                    })
+                  }
+                  extraText={t(
+                    '开启后，OpenAI 兼容渠道响应的 system_fingerprint 将附加渠道哈希（如 fp_xxx+ch_1a2b3c4d），可通过 /api/channel/fingerprint/{哈希} 查询对应渠道',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  label={t('模型列表使用上游模型')}
+                  field={'global.upstream_model_list_enabled'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'global.upstream_model_list_enabled': value,
+                    })
+                  }
+                  extraText={t(
+                    '开启后定时拉取各渠道上游的模型列表，/v1/models 只返回上游实际提供的模型，拉取失败的渠道按配置的模型计算',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col span={16}>
+                <Form.TextArea
+                  label={t('模型工具调用解析格式')}
+                  field={'tool_call.model_profiles'}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(TOOL_CALL_MODEL_PROFILES_EXAMPLE, null, 2)
+                  }
+                  extraText={t(
+                    '适用于不支持结构化工具调用的模型，请求中的 tools 会被写入系统提示，响应文本中的工具调用会被解析为 tool_calls。可选格式：hermes、xml、markdown，模型名称支持以 * 结尾的前缀匹配',
+                  )}
+                  autosize={{ minRows: 6, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'tool_call.model_profiles': value,
+                    })
+                  }
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col span={16}>
+                <Form.TextArea
+                  label={t('模型响应内容后处理规则')}
+                  field={'output_rule.model_rules'}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(OUTPUT_RULE_MODEL_RULES_EXAMPLE, null, 2)
+                  }
+                  extraText={t(
+                    '按顺序对回复内容应用规则，同时作用于流式和非流式响应。可选类型：regex_replace（正则替换）、stop_trim（截断停止字符串及之后的内容）、prefix_strip（移除开头的前言），模型名称支持以 * 结尾的前缀匹配，渠道设置中的 output_rules 在模型规则之后应用',
+                  )}
+                  autosize={{ minRows: 6, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'output_rule.model_rules': value,
+                    })
+                  }
+                />
+              </Col>
             </Row>
             
+            <Form.Section text={t('网关工具调用')}>
+              <Row>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.Switch
+                    label={t('启用网关工具调用')}
+                    field={'agent_loop.enabled'}
+                    onChange={(value) =>
+                      setInputs({ ...inputs, 'agent_loop.enabled': value })
+                    }
+                    extraText={t(
+                      '开启后，请求中可以通过 agent_tools 指定下方定义的工具（* 表示全部），模型调用这些工具时由网关执行并把结果回传给模型，每一步单独计费',
+                    )}
+                  />
+                </Col>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.InputNumber
+                    label={t('最大步数')}
+                    field={'agent_loop.max_steps'}
+                    onChange={(value) =>
+                      setInputs({ ...inputs, 'agent_loop.max_steps': value })
+                    }
+                    min={1}
+                    disabled={!inputs['agent_loop.enabled']}
+                  />
+                </Col>
+              </Row>
+              <Row>
+                <Col span={16}>
+                  <Form.TextArea
+                    label={t('工具定义')}
+                    field={'agent_loop.tools'}
+                    placeholder={
+                      t('为一个 JSON 文本，例如：') +
+                      '\n' +
+                      JSON.stringify(AGENT_LOOP_TOOLS_EXAMPLE, null, 2)
+                    }
+                    extraText={t(
+                      '网关以 JSON 请求体（GET 时为查询参数）请求工具地址，响应内容作为工具结果',
+                    )}
+                    autosize={{ minRows: 6, maxRows: 12 }}
+                    trigger='blur'
+                    stopValidateWithError
+                    rules={[
+                      {
+                        validator: (rule, value) => verifyJSON(value),
+                        message: t('不是合法的 JSON 字符串'),
+                      },
+                    ]}
+                    onChange={(value) =>
+                      setInputs({ ...inputs, 'agent_loop.tools': value })
+                    }
+                  />
+                </Col>
+              </Row>
+            </Form.Section>
+
             <Form.Section text={t('连接保活设置')}>
             <Row style={{ marginTop: 10 }}>
                   <Col span={24}>
@@ -107,7 +307,7 @@ export default function SettingGlobalModel(props) {
                     label={t('启用Ping间隔')}
                     field={'general_setting.ping_interval_enabled'}
                     onChange={(value) => setInputs({ ...inputs, 'general_setting.ping_interval_enabled': value })}
-                    extraText={'开启后，将定期发送ping数据保持连接活跃'}
+                    extraText={'开启后，流式请求在等待上游首个数据块和输出空闲期间定期发送 SSE 注释（: PING）保持连接活跃，避免 Cloudflare、nginx 等代理或 SDK 因空闲超时断开'}
                   />
                 </Col>
                 <Col xs={24} sm={12} md={8} lg={8} xl={8}>
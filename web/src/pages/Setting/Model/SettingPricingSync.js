@@ -0,0 +1,275 @@
+import React, { useEffect, useState, useRef } from 'react';
+import {
+  Button,
+  Col,
+  Form,
+  Modal,
+  Row,
+  Space,
+  Spin,
+  Table,
+  Tag,
+} from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+export default function SettingPricingSync(props) {
+  const { t } = useTranslation();
+
+  const [loading, setLoading] = useState(false);
+  const [syncing, setSyncing] = useState(false);
+  const [syncResult, setSyncResult] = useState(null);
+  const [inputs, setInputs] = useState({
+    'pricing_sync.enabled': false,
+    'pricing_sync.source': 'openrouter',
+    'pricing_sync.url': '',
+    'pricing_sync.margin': 1,
+    'pricing_sync.auto_apply_enabled': false,
+    'pricing_sync.interval_minutes': 1440,
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value = String(inputs[item.key]);
+
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (requestQueue.length === 1) {
+          if (res.includes(undefined)) return;
+        } else if (requestQueue.length > 1) {
+          if (res.includes(undefined))
+            return showError(t('部分保存失败，请重试'));
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  async function syncPricing(apply) {
+    setSyncing(true);
+    try {
+      const res = await API.post(`/api/option/model_pricing/sync?apply=${apply}`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setSyncResult(data);
+        if (apply) {
+          showSuccess(t('价格表已更新'));
+        }
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error);
+    } finally {
+      setSyncing(false);
+    }
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  const renderPrice = (pricing) =>
+    pricing ? `${pricing.input} / ${pricing.output}` : '-';
+
+  const columns = [
+    { title: t('模型'), dataIndex: 'model' },
+    {
+      title: t('状态'),
+      dataIndex: 'status',
+      render: (status) =>
+        status === 'added' ? (
+          <Tag color='green'>{t('新增')}</Tag>
+        ) : (
+          <Tag color='orange'>{t('价格变化')}</Tag>
+        ),
+    },
+    {
+      title: t('原价格（输入 / 输出）'),
+      dataIndex: 'old',
+      render: renderPrice,
+    },
+    {
+      title: t('新价格（输入 / 输出）'),
+      dataIndex: 'new',
+      render: renderPrice,
+    },
+  ];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('价格同步')}>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  label={t('启用定时价格同步')}
+                  field={'pricing_sync.enabled'}
+                  onChange={(value) =>
+                    setInputs({ ...inputs, 'pricing_sync.enabled': value })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  label={t('自动应用同步的价格')}
+                  field={'pricing_sync.auto_apply_enabled'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'pricing_sync.auto_apply_enabled': value,
+                    })
+                  }
+                  extraText={t(
+                    '关闭时定时同步只标记价格变化的模型，需要手动应用',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  label={t('价格源格式')}
+                  field={'pricing_sync.source'}
+                  onChange={(value) =>
+                    setInputs({ ...inputs, 'pricing_sync.source': value })
+                  }
+                  optionList={[
+                    { label: 'OpenRouter', value: 'openrouter' },
+                    { label: 'LiteLLM', value: 'litellm' },
+                  ]}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={16} lg={16} xl={16}>
+                <Form.Input
+                  label={t('价格源地址')}
+                  field={'pricing_sync.url'}
+                  placeholder='https://openrouter.ai/api/v1/models'
+                  onChange={(value) =>
+                    setInputs({ ...inputs, 'pricing_sync.url': value })
+                  }
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('价格倍数')}
+                  field={'pricing_sync.margin'}
+                  extraText={t('上游价格乘以该倍数后写入价格表')}
+                  onChange={(value) =>
+                    setInputs({ ...inputs, 'pricing_sync.margin': value })
+                  }
+                  min={0}
+                  step={0.1}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('同步间隔（分钟）')}
+                  field={'pricing_sync.interval_minutes'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'pricing_sync.interval_minutes': value,
+                    })
+                  }
+                  min={1}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Space>
+                <Button size='default' onClick={onSubmit}>
+                  {t('保存')}
+                </Button>
+                <Button loading={syncing} onClick={() => syncPricing(false)}>
+                  {t('立即同步并预览')}
+                </Button>
+              </Space>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+      <Modal
+        title={t('价格同步结果')}
+        visible={syncResult !== null}
+        onCancel={() => setSyncResult(null)}
+        width={800}
+        footer={
+          <Space>
+            <Button onClick={() => setSyncResult(null)}>{t('关闭')}</Button>
+            <Button
+              type='primary'
+              loading={syncing}
+              disabled={
+                !syncResult ||
+                syncResult.applied ||
+                syncResult.changes.length === 0
+              }
+              onClick={() => syncPricing(true)}
+            >
+              {t('应用到价格表')}
+            </Button>
+          </Space>
+        }
+      >
+        {syncResult && (
+          <>
+            <div style={{ marginBottom: 10 }}>
+              {t('价格未变化的模型')}: {syncResult.unchanged}
+            </div>
+            {syncResult.skipped && syncResult.skipped.length > 0 && (
+              <div style={{ marginBottom: 10 }}>
+                {t('上游价格为 0 未同步的模型')}:{' '}
+                {syncResult.skipped.join(', ')}
+              </div>
+            )}
+            <Table
+              columns={columns}
+              dataSource={syncResult.changes}
+              rowKey='model'
+              pagination={{ pageSize: 10 }}
+              size='small'
+            />
+          </>
+        )}
+      </Modal>
+    </>
+  );
+}
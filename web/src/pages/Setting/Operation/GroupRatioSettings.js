@@ -15,6 +15,8 @@ export default function GroupRatioSettings(props) {
   const [loading, setLoading] = useState(false);
   const [inputs, setInputs] = useState({
     GroupRatio: '',
+    GroupModelRatio: '',
+    GroupVolumeTiers: '',
     UserUsableGroups: '',
   });
   const refForm = useRef();
@@ -115,6 +117,56 @@ export default function GroupRatioSettings(props) {
               />
             </Col>
           </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('分组模型倍率')}
+                extraText={t('为分组下的指定模型单独设置倍率，配置后替代该模型的分组倍率')}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"vip": {"gpt-4o": 0.8}}',
+                )}
+                field={'GroupModelRatio'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: t('不是合法的 JSON 字符串'),
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, GroupModelRatio: value })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('用量阶梯价格')}
+                extraText={t(
+                  '用户本月使用的 token 数达到 tokens 后，分组倍率再乘以 ratio；本月用量从消费日志中统计',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"default": [{"tokens": 10000000, "ratio": 0.8}]}',
+                )}
+                field={'GroupVolumeTiers'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: t('不是合法的 JSON 字符串'),
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, GroupVolumeTiers: value })
+                }
+              />
+            </Col>
+          </Row>
           <Row gutter={16}>
             <Col xs={24} sm={16}>
               <Form.TextArea
@@ -22,9 +22,17 @@ export default function ModelRatioSettings(props) {
   const [loading, setLoading] = useState(false);
   const [inputs, setInputs] = useState({
     ModelPrice: '',
+    ModelPricing: '',
+    ImagePricing: '',
+    FreeModels: '',
     ModelRatio: '',
     CacheRatio: '',
+    DefaultCacheRatio: '',
+    CreateCacheRatio: '',
     CompletionRatio: '',
+    ReasoningRatio: '',
+    ModelMaxTokens: '',
+    ServiceTierRatio: '',
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -98,6 +106,20 @@ export default function ModelRatioSettings(props) {
     }
   }
 
+  async function migrateModelPricing() {
+    try {
+      let res = await API.post(`/api/option/model_pricing/migrate`);
+      if (res.data.success) {
+        showSuccess(res.data.message);
+        props.refresh();
+      } else {
+        showError(res.data.message);
+      }
+    } catch (error) {
+      showError(error);
+    }
+  }
+
   useEffect(() => {
     const currentInputs = {};
     for (let key in props.options) {
@@ -142,6 +164,84 @@ export default function ModelRatioSettings(props) {
               />
             </Col>
           </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('模型价格表')}
+                extraText={t(
+                  '每 1M token 的输入、输出价格，思考 token 可配置单独的价格 reasoning，currency 为 USD 或 CNY，优先级大于模型倍率和补全倍率；语音转文字可配置每分钟价格 per_minute，文字转语音可配置每千字符价格 per_1k_chars；按次计费的模型（如图片、搜索模型）可配置单次价格 per_request，配置后不再按 token 计费，仍会记录调用日志',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"gpt-4o": {"input": 2.5, "output": 10, "currency": "USD"}}',
+                )}
+                field={'ModelPricing'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, ModelPricing: value })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('图片价格')}
+                extraText={t(
+                  '按模型、尺寸、品质配置单张图片的价格（美元），尺寸和品质可以用 * 匹配其余取值，优先级大于模型固定价格',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"dall-e-3": {"1024x1024": {"standard": 0.04, "hd": 0.08}, "*": {"standard": 0.08, "hd": 0.12}}}',
+                )}
+                field={'ImagePricing'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, ImagePricing: value })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('免费模型')}
+                extraText={t(
+                  '免费模型不扣除额度，仍记录调用日志；rpm 为每个用户每分钟最多请求次数，daily_requests 为每个用户每日最多请求次数，0 表示不限制',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"gpt-4o-mini": {"rpm": 10, "daily_requests": 200}}',
+                )}
+                field={'FreeModels'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, FreeModels: value })
+                }
+              />
+            </Col>
+          </Row>
           <Row gutter={16}>
             <Col xs={24} sm={16}>
               <Form.TextArea
@@ -184,6 +284,45 @@ export default function ModelRatioSettings(props) {
               />
             </Col>
           </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+              <Form.InputNumber
+                label={t('默认提示缓存倍率')}
+                extraText={t(
+                  '未设置提示缓存倍率的模型，缓存命中的 token 按此倍率计费，1 为原价',
+                )}
+                field={'DefaultCacheRatio'}
+                step={0.1}
+                min={0}
+                max={1}
+                onChange={(value) =>
+                  setInputs({ ...inputs, DefaultCacheRatio: String(value) })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('缓存创建倍率')}
+                extraText={t('写入提示缓存的 token 相对于输入的倍率，未设置的模型默认为 1.25')}
+                placeholder={t('为一个 JSON 文本，键为模型名称，值为倍率')}
+                field={'CreateCacheRatio'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, CreateCacheRatio: value })
+                }
+              />
+            </Col>
+          </Row>
           <Row gutter={16}>
             <Col xs={24} sm={16}>
               <Form.TextArea
@@ -206,6 +345,82 @@ export default function ModelRatioSettings(props) {
               />
             </Col>
           </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('思考倍率')}
+                extraText={t(
+                  '思考 token（reasoning_tokens）相对于模型倍率的倍率，未设置的模型按补全倍率计费',
+                )}
+                placeholder={t('为一个 JSON 文本，键为模型名称，值为倍率')}
+                field={'ReasoningRatio'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, ReasoningRatio: value })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('模型输出 token 限制')}
+                extraText={t(
+                  '请求未指定 max_tokens 时使用 default，超过 max 时截断为 max，同时用于预扣费',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"gpt-4o": {"default": 4096, "max": 16384}}',
+                )}
+                field={'ModelMaxTokens'}
+                autosize={{ minRows: 6, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, ModelMaxTokens: value })
+                }
+              />
+            </Col>
+          </Row>
+          <Row gutter={16}>
+            <Col xs={24} sm={16}>
+              <Form.TextArea
+                label={t('服务等级倍率')}
+                extraText={t(
+                  '按上游返回的 service_tier 计费，未配置的服务等级倍率为 1',
+                )}
+                placeholder={t(
+                  '为一个 JSON 文本，例如 {"flex": 0.5, "priority": 2}',
+                )}
+                field={'ServiceTierRatio'}
+                autosize={{ minRows: 4, maxRows: 12 }}
+                trigger='blur'
+                stopValidateWithError
+                rules={[
+                  {
+                    validator: (rule, value) => verifyJSON(value),
+                    message: '不是合法的 JSON 字符串',
+                  },
+                ]}
+                onChange={(value) =>
+                  setInputs({ ...inputs, ServiceTierRatio: value })
+                }
+              />
+            </Col>
+          </Row>
         </Form.Section>
       </Form>
       <Space>
@@ -219,6 +434,14 @@ export default function ModelRatioSettings(props) {
         >
           <Button type={'danger'}>{t('重置模型倍率')}</Button>
         </Popconfirm>
+        <Popconfirm
+          title={t('确定从模型倍率迁移价格表吗？')}
+          content={t('已配置价格的模型保持不变')}
+          position={'top'}
+          onConfirm={migrateModelPricing}
+        >
+          <Button>{t('从模型倍率迁移价格表')}</Button>
+        </Popconfirm>
       </Space>
     </Spin>
   );
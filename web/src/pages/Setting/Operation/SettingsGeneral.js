@@ -28,6 +28,9 @@ export default function GeneralSettings(props) {
     QuotaPerUnit: '',
     RetryTimes: '',
     DisplayInCurrencyEnabled: false,
+    'general_setting.display_currency': '',
+    'general_setting.display_currency_symbol': '',
+    'general_setting.display_exchange_rate': '',
     DisplayTokenStatEnabled: false,
     DefaultCollapseSidebar: false,
     DemoSiteEnabled: false,
@@ -177,6 +180,47 @@ export default function GeneralSettings(props) {
                 />
               </Col>
             </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'general_setting.display_currency'}
+                  label={t('显示币种')}
+                  initValue={''}
+                  placeholder={t('例如 USD、CNY')}
+                  onChange={handleFieldChange(
+                    'general_setting.display_currency',
+                  )}
+                  showClear
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'general_setting.display_currency_symbol'}
+                  label={t('货币符号')}
+                  initValue={''}
+                  placeholder={t('例如 $、¥')}
+                  onChange={handleFieldChange(
+                    'general_setting.display_currency_symbol',
+                  )}
+                  showClear
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'general_setting.display_exchange_rate'}
+                  label={t('显示汇率')}
+                  initValue={''}
+                  placeholder={t('1 美元兑换的显示币种金额')}
+                  extraText={t(
+                    '仅影响以货币形式显示的金额，额度计算不受影响',
+                  )}
+                  onChange={handleFieldChange(
+                    'general_setting.display_exchange_rate',
+                  )}
+                  showClear
+                />
+              </Col>
+            </Row>
             <Row>
               <Col xs={24} sm={12} md={8} lg={8} xl={8}>
                 <Form.Switch
@@ -20,6 +20,9 @@ export default function RequestRateLimit(props) {
     ModelRequestRateLimitSuccessCount: 1000,
     ModelRequestRateLimitDurationMinutes: 1,
     ModelRequestRateLimitGroup: '',
+    TokenRateLimitTiers: '',
+    SubscriptionPlans: '',
+    LegacyKeyAuthGroups: '',
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -191,6 +194,103 @@ export default function RequestRateLimit(props) {
                 />
               </Col>
             </Row>
+            <Row>
+              <Col xs={24} sm={16}>
+                <Form.TextArea
+                  label={t('令牌限流等级')}
+                  placeholder={t(
+                    '[\n  {"name": "pro", "count": 600, "success_count": 600, "quota": 500000, "days": 30}\n]',
+                  )}
+                  field={'TokenRateLimitTiers'}
+                  autosize={{ minRows: 5, maxRows: 15 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  extraText={
+                    <div>
+                      <p style={{ marginBottom: -15 }}>{t('说明：')}</p>
+                      <ul>
+                        <li>{t('用户可以消耗额度为单个令牌购买限流等级，购买后该令牌单独计数，优先级高于分组速率限制。')}</li>
+                        <li>{t('count 为最多请求次数，success_count 为最多请求完成次数，quota 为价格，days 为有效天数（0 表示永久）。')}</li>
+                        <li>{t('限制周期统一使用上方配置的“限制周期”值。')}</li>
+                      </ul>
+                    </div>
+                  }
+                  onChange={(value) => {
+                    setInputs({ ...inputs, TokenRateLimitTiers: value });
+                  }}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col xs={24} sm={16}>
+                <Form.TextArea
+                  label={t('订阅套餐')}
+                  placeholder={t(
+                    '[\n  {"name": "pro", "quota": 5000000, "models": ["gpt-4o-mini"], "rpm": 60, "price": 2500000, "period_days": 30}\n]',
+                  )}
+                  field={'SubscriptionPlans'}
+                  autosize={{ minRows: 5, maxRows: 15 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  extraText={
+                    <div>
+                      <p style={{ marginBottom: -15 }}>{t('说明：')}</p>
+                      <ul>
+                        <li>{t('订阅期间每个周期开始时发放 quota 额度，models 为可调用的模型（为空不限制），rpm 为每分钟最多请求次数（0 不限制）。')}</li>
+                        <li>{t('price 为用户购买一个周期消耗的额度（0 表示只能由管理员分配），period_days 为周期天数（默认 30）。')}</li>
+                        <li>{t('更换套餐时退还原套餐尚未开始的周期已支付的额度，当前周期按剩余时间折算退还价格并扣回已发放的额度。')}</li>
+                      </ul>
+                    </div>
+                  }
+                  onChange={(value) => {
+                    setInputs({ ...inputs, SubscriptionPlans: value });
+                  }}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col xs={24} sm={16}>
+                <Form.TextArea
+                  label={t('旧客户端认证分组')}
+                  placeholder={t('{\n  "default": 60\n}')}
+                  field={'LegacyKeyAuthGroups'}
+                  autosize={{ minRows: 5, maxRows: 15 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  extraText={
+                    <div>
+                      <p style={{ marginBottom: -15 }}>{t('说明：')}</p>
+                      <ul>
+                        <li>{t('配置的分组允许通过 api-key 请求头或签名查询参数（key_id、expires、signature）传递令牌，用于无法设置 Authorization 请求头的旧客户端。')}</li>
+                        <li>{t('格式为：{"组名": 每分钟最多请求次数}，按令牌计数，值必须大于等于1。')}</li>
+                        <li>{t('signature 为以令牌 key 为密钥，对“请求路径\\n过期时间戳”计算的 HMAC-SHA256 十六进制值。')}</li>
+                      </ul>
+                    </div>
+                  }
+                  onChange={(value) => {
+                    setInputs({ ...inputs, LegacyKeyAuthGroups: value });
+                  }}
+                />
+              </Col>
+            </Row>
             <Row>
               <Button size='default' onClick={onSubmit}>
                 {t('保存模型速率限制')}
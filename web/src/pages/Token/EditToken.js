@@ -38,6 +38,9 @@ const EditToken = (props) => {
     model_limits: [],
     allow_ips: '',
     group: '',
+    daily_quota_limit: 0,
+    monthly_quota_limit: 0,
+    quota_limit_timezone: '',
   };
   const [inputs, setInputs] = useState(originInputs);
   const {
@@ -49,6 +52,9 @@ const EditToken = (props) => {
     model_limits,
     allow_ips,
     group,
+    daily_quota_limit,
+    monthly_quota_limit,
+    quota_limit_timezone,
   } = inputs;
   // const [visible, setVisible] = useState(false);
   const [models, setModels] = useState([]);
@@ -175,6 +181,9 @@ const EditToken = (props) => {
       // 编辑令牌的逻辑保持不变
       let localInputs = { ...inputs };
       localInputs.remain_quota = parseInt(localInputs.remain_quota);
+      localInputs.daily_quota_limit = parseInt(localInputs.daily_quota_limit) || 0;
+      localInputs.monthly_quota_limit =
+        parseInt(localInputs.monthly_quota_limit) || 0;
       if (localInputs.expired_time !== -1) {
         let time = Date.parse(localInputs.expired_time);
         if (isNaN(time)) {
@@ -207,6 +216,10 @@ const EditToken = (props) => {
           localInputs.name = `${inputs.name}-${generateRandomSuffix()}`;
         }
         localInputs.remain_quota = parseInt(localInputs.remain_quota);
+        localInputs.daily_quota_limit =
+          parseInt(localInputs.daily_quota_limit) || 0;
+        localInputs.monthly_quota_limit =
+          parseInt(localInputs.monthly_quota_limit) || 0;
 
         if (localInputs.expired_time !== -1) {
           let time = Date.parse(localInputs.expired_time);
@@ -397,6 +410,41 @@ const EditToken = (props) => {
               {unlimited_quota ? t('取消无限额度') : t('设为无限额度')}
             </Button>
           </div>
+          <div style={{ marginTop: 20 }}>
+            <Typography.Text>{`${t('每日消费上限')}${renderQuotaWithPrompt(daily_quota_limit)}`}</Typography.Text>
+          </div>
+          <Input
+            style={{ marginTop: 8 }}
+            name='daily_quota_limit'
+            placeholder={t('0 表示不限制')}
+            onChange={(value) => handleInputChange('daily_quota_limit', value)}
+            value={daily_quota_limit}
+            autoComplete='new-password'
+            type='number'
+          />
+          <div style={{ marginTop: 20 }}>
+            <Typography.Text>{`${t('每月消费上限')}${renderQuotaWithPrompt(monthly_quota_limit)}`}</Typography.Text>
+          </div>
+          <Input
+            style={{ marginTop: 8 }}
+            name='monthly_quota_limit'
+            placeholder={t('0 表示不限制')}
+            onChange={(value) => handleInputChange('monthly_quota_limit', value)}
+            value={monthly_quota_limit}
+            autoComplete='new-password'
+            type='number'
+          />
+          <div style={{ marginTop: 20 }}>
+            <Typography.Text>{t('消费上限重置时区')}</Typography.Text>
+          </div>
+          <Input
+            style={{ marginTop: 8 }}
+            name='quota_limit_timezone'
+            placeholder={t('例如 Asia/Shanghai，留空使用服务器时区')}
+            onChange={(value) => handleInputChange('quota_limit_timezone', value)}
+            value={quota_limit_timezone}
+            autoComplete='off'
+          />
           <Divider />
           <div style={{ marginTop: 10 }}>
             <Typography.Text>
@@ -16,6 +16,7 @@ import {
   Divider,
   Space,
   Modal,
+  Table,
   Toast,
 } from '@douyinfe/semi-ui';
 import Title from '@douyinfe/semi-ui/lib/es/typography/title';
@@ -33,7 +34,13 @@ const TopUp = () => {
   const [minTopUp, setMinTopUp] = useState(1);
   const [topUpLink, setTopUpLink] = useState('');
   const [enableOnlineTopUp, setEnableOnlineTopUp] = useState(false);
+  const [enableStripeTopUp, setEnableStripeTopUp] = useState(false);
+  const [stripeAmount, setStripeAmount] = useState('');
+  const [stripeCurrency, setStripeCurrency] = useState('');
   const [userQuota, setUserQuota] = useState(0);
+  const [billingMode, setBillingMode] = useState('prepaid');
+  const [creditLimit, setCreditLimit] = useState(0);
+  const [statements, setStatements] = useState([]);
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [open, setOpen] = useState(false);
   const [payWay, setPayWay] = useState('');
@@ -79,11 +86,17 @@ const TopUp = () => {
   };
 
   const preTopUp = async (payment) => {
-    if (!enableOnlineTopUp) {
+    const enabled =
+      payment === 'stripe' ? enableStripeTopUp : enableOnlineTopUp;
+    if (!enabled) {
       showError(t('管理员未开启在线充值！'));
       return;
     }
-    await getAmount();
+    if (payment === 'stripe') {
+      await getStripeAmount();
+    } else {
+      await getAmount();
+    }
     if (topUpCount < minTopUp) {
       showError(t('充值数量不能小于') + minTopUp);
       return;
@@ -92,7 +105,29 @@ const TopUp = () => {
     setOpen(true);
   };
 
+  const stripeTopUp = async () => {
+    setOpen(false);
+    try {
+      const res = await API.post('/api/user/stripe/pay', {
+        amount: parseInt(topUpCount),
+        top_up_code: topUpCode,
+      });
+      const { message, data } = res.data;
+      if (message === 'success') {
+        window.location.href = data;
+      } else {
+        showError(data);
+      }
+    } catch (err) {
+      console.log(err);
+    }
+  };
+
   const onlineTopUp = async () => {
+    if (payWay === 'stripe') {
+      await stripeTopUp();
+      return;
+    }
     if (amount === 0) {
       await getAmount();
     }
@@ -152,11 +187,67 @@ const TopUp = () => {
     const { success, message, data } = res.data;
     if (success) {
       setUserQuota(data.quota);
+      setBillingMode(data.billing_mode);
+      setCreditLimit(data.credit_limit);
+      if (data.billing_mode === 'postpaid') {
+        await loadStatements();
+      }
+    } else {
+      showError(message);
+    }
+  };
+
+  const loadStatements = async () => {
+    const res = await API.get('/api/user/self/postpaid_statements');
+    const { success, message, data } = res.data;
+    if (success) {
+      setStatements(data);
     } else {
       showError(message);
     }
   };
 
+  const downloadStatement = async (statement) => {
+    const res = await API.get(
+      `/api/user/self/usage_statement?format=pdf&start_timestamp=${statement.start_timestamp}&end_timestamp=${statement.end_timestamp}`,
+      { responseType: 'blob' },
+    );
+    if (res.data.type === 'application/json') {
+      const { message } = JSON.parse(await res.data.text());
+      showError(message);
+      return;
+    }
+    const url = URL.createObjectURL(res.data);
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = `statement-${statement.period}.pdf`;
+    link.click();
+    URL.revokeObjectURL(url);
+  };
+
+  const statementColumns = [
+    { title: t('账单月份'), dataIndex: 'period' },
+    {
+      title: t('消耗额度'),
+      dataIndex: 'used_quota',
+      render: (value) => renderQuota(value),
+    },
+    {
+      title: t('出账时余额'),
+      dataIndex: 'balance',
+      render: (value) => renderQuota(value),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (text, record) => (
+        <Button size='small' onClick={() => downloadStatement(record)}>
+          {t('下载明细')}
+        </Button>
+      ),
+    },
+  ];
+
   useEffect(() => {
     let status = localStorage.getItem('status');
     if (status) {
@@ -170,6 +261,9 @@ const TopUp = () => {
       if (status.enable_online_topup) {
         setEnableOnlineTopUp(status.enable_online_topup);
       }
+      if (status.enable_stripe_topup) {
+        setEnableStripeTopUp(status.enable_stripe_topup);
+      }
     }
     getUserQuota().then();
   }, []);
@@ -208,6 +302,25 @@ const TopUp = () => {
     }
   };
 
+  const getStripeAmount = async () => {
+    try {
+      const res = await API.post('/api/user/stripe/amount', {
+        amount: parseFloat(topUpCount),
+        top_up_code: topUpCode,
+      });
+      const { message, data, currency } = res.data;
+      if (message === 'success') {
+        setStripeAmount(data);
+        setStripeCurrency(currency);
+      } else {
+        setStripeAmount('');
+        Toast.error({ content: '错误：' + data, id: 'getAmount' });
+      }
+    } catch (err) {
+      console.log(err);
+    }
+  };
+
   const handleCancel = () => {
     setOpen(false);
   };
@@ -229,10 +342,13 @@ const TopUp = () => {
             centered={true}
           >
             <p>
-              {t('充值数量')}：{topUpCount}
+              {t('充值数量')}：{renderQuotaWithAmount(topUpCount)}
             </p>
             <p>
-              {t('实付金额')}：{renderAmount()}
+              {t('实付金额')}：
+              {payWay === 'stripe'
+                ? stripeAmount + ' ' + stripeCurrency.toUpperCase()
+                : renderAmount()}
             </p>
             <p>{t('是否确认充值？')}</p>
           </Modal>
@@ -281,7 +397,7 @@ const TopUp = () => {
                 <Divider>{t('在线充值')}</Divider>
                 <Form>
                   <Form.Input
-                    disabled={!enableOnlineTopUp}
+                    disabled={!enableOnlineTopUp && !enableStripeTopUp}
                     field={'redemptionCount'}
                     label={t('实付金额：') + ' ' + renderAmount()}
                     placeholder={
@@ -320,9 +436,35 @@ const TopUp = () => {
                     >
                       {t('微信')}
                     </Button>
+                    {enableStripeTopUp ? (
+                      <Button
+                        type={'primary'}
+                        theme={'solid'}
+                        onClick={async () => {
+                          preTopUp('stripe');
+                        }}
+                      >
+                        Stripe
+                      </Button>
+                    ) : null}
                   </Space>
                 </Form>
               </div>
+              {billingMode === 'postpaid' && (
+                <div style={{ marginTop: 20 }}>
+                  <Divider>{t('月度账单')}</Divider>
+                  <Text>
+                    {t('信用额度')}: {renderQuota(creditLimit)}
+                  </Text>
+                  <Table
+                    columns={statementColumns}
+                    dataSource={statements}
+                    rowKey='id'
+                    pagination={{ pageSize: 6 }}
+                    size='small'
+                  />
+                </div>
+              )}
               {/*<div style={{ display: 'flex', justifyContent: 'right' }}>*/}
               {/*    <Text>*/}
               {/*        <Link onClick={*/}
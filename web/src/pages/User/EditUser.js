@@ -31,6 +31,8 @@ const EditUser = (props) => {
     email: '',
     quota: 0,
     group: 'default',
+    billing_mode: 'prepaid',
+    credit_limit: 0,
   });
   const [groupOptions, setGroupOptions] = useState([]);
   const {
@@ -44,6 +46,8 @@ const EditUser = (props) => {
     email,
     quota,
     group,
+    billing_mode,
+    credit_limit,
   } = inputs;
   const handleInputChange = (name, value) => {
     setInputs((inputs) => ({ ...inputs, [name]: value }));
@@ -98,6 +102,9 @@ const EditUser = (props) => {
       if (typeof data.quota === 'string') {
         data.quota = parseInt(data.quota);
       }
+      if (typeof data.credit_limit === 'string') {
+        data.credit_limit = parseInt(data.credit_limit) || 0;
+      }
       res = await API.put(`/api/user/`, data);
     } else {
       res = await API.put(`/api/user/self`, inputs);
@@ -223,6 +230,35 @@ const EditUser = (props) => {
                 />
                 <Button onClick={openAddQuotaModal}>{t('添加额度')}</Button>
               </Space>
+              <div style={{ marginTop: 20 }}>
+                <Typography.Text>{t('计费方式')}</Typography.Text>
+              </div>
+              <Select
+                name='billing_mode'
+                onChange={(value) => handleInputChange('billing_mode', value)}
+                value={billing_mode || 'prepaid'}
+                optionList={[
+                  { label: t('预付费'), value: 'prepaid' },
+                  { label: t('后付费'), value: 'postpaid' },
+                ]}
+              />
+              {billing_mode === 'postpaid' && (
+                <>
+                  <div style={{ marginTop: 20 }}>
+                    <Typography.Text>{`${t('信用额度')}${renderQuotaWithPrompt(credit_limit)}`}</Typography.Text>
+                  </div>
+                  <Input
+                    name='credit_limit'
+                    placeholder={t('余额可透支到负的信用额度，超出后暂停使用')}
+                    onChange={(value) =>
+                      handleInputChange('credit_limit', value)
+                    }
+                    value={credit_limit}
+                    type={'number'}
+                    autoComplete='new-password'
+                  />
+                </>
+              )}
             </>
           )}
           <Divider style={{ marginTop: 20 }}>{t('以下信息不可修改')}</Divider>
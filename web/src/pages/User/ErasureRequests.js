@@ -0,0 +1,155 @@
+import React, { useEffect, useState } from 'react';
+import { API, showError, showSuccess, timestamp2string } from '../../helpers';
+import Title from '@douyinfe/semi-ui/lib/es/typography/title';
+import {
+  Button,
+  Popconfirm,
+  Select,
+  SideSheet,
+  Space,
+  Table,
+  Tag,
+} from '@douyinfe/semi-ui';
+import { useTranslation } from 'react-i18next';
+
+const ErasureRequests = (props) => {
+  const { t } = useTranslation();
+  const [requests, setRequests] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [activePage, setActivePage] = useState(1);
+  const [status, setStatus] = useState(1);
+  const [loading, setLoading] = useState(false);
+  const pageSize = 10;
+
+  const loadRequests = async (page, status) => {
+    setLoading(true);
+    const res = await API.get(
+      `/api/user/erasure?p=${page}&page_size=${pageSize}&status=${status}`,
+    );
+    const { success, message, data } = res.data;
+    if (success) {
+      setRequests(data.items || []);
+      setTotal(data.total);
+    } else {
+      showError(message);
+    }
+    setLoading(false);
+  };
+
+  const processRequest = async (id, action) => {
+    const res = await API.post(`/api/user/erasure/${id}`, { action });
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(t('操作成功完成！'));
+      await loadRequests(activePage, status);
+      props.refresh();
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    if (props.visible) {
+      loadRequests(activePage, status).then();
+    }
+  }, [props.visible, activePage, status]);
+
+  const renderStatus = (status) => {
+    switch (status) {
+      case 1:
+        return <Tag color='yellow'>{t('待审核')}</Tag>;
+      case 2:
+        return <Tag color='green'>{t('已通过')}</Tag>;
+      case 3:
+        return <Tag color='grey'>{t('已拒绝')}</Tag>;
+      default:
+        return <Tag color='grey'>{t('未知状态')}</Tag>;
+    }
+  };
+
+  const columns = [
+    { title: 'ID', dataIndex: 'id' },
+    { title: t('用户ID'), dataIndex: 'user_id' },
+    { title: t('用户名'), dataIndex: 'username' },
+    { title: t('注销原因'), dataIndex: 'reason' },
+    {
+      title: t('申请时间'),
+      dataIndex: 'created_time',
+      render: (text) => timestamp2string(text),
+    },
+    {
+      title: t('状态'),
+      dataIndex: 'status',
+      render: (text) => renderStatus(text),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (text, record) =>
+        record.status === 1 ? (
+          <Space>
+            <Popconfirm
+              title={t('确定通过该注销申请？')}
+              content={t('将删除账户和令牌并匿名化使用记录，此操作不可逆')}
+              okType={'danger'}
+              position={'left'}
+              onConfirm={() => processRequest(record.id, 'approve')}
+            >
+              <Button theme='light' type='danger'>
+                {t('通过')}
+              </Button>
+            </Popconfirm>
+            <Button
+              theme='light'
+              type='tertiary'
+              onClick={() => processRequest(record.id, 'reject')}
+            >
+              {t('拒绝')}
+            </Button>
+          </Space>
+        ) : (
+          <></>
+        ),
+    },
+  ];
+
+  return (
+    <SideSheet
+      placement={'left'}
+      title={<Title level={3}>{t('注销申请')}</Title>}
+      headerStyle={{ borderBottom: '1px solid var(--semi-color-border)' }}
+      bodyStyle={{ borderBottom: '1px solid var(--semi-color-border)' }}
+      visible={props.visible}
+      width={900}
+      onCancel={props.handleClose}
+    >
+      <Select
+        style={{ width: 160, marginBottom: 10 }}
+        value={status}
+        optionList={[
+          { label: t('待审核'), value: 1 },
+          { label: t('已通过'), value: 2 },
+          { label: t('已拒绝'), value: 3 },
+          { label: t('全部'), value: 0 },
+        ]}
+        onChange={(value) => {
+          setStatus(value);
+          setActivePage(1);
+        }}
+      />
+      <Table
+        columns={columns}
+        dataSource={requests}
+        loading={loading}
+        pagination={{
+          currentPage: activePage,
+          pageSize: pageSize,
+          total: total,
+          onPageChange: (page) => setActivePage(page),
+        }}
+      />
+    </SideSheet>
+  );
+};
+
+export default ErasureRequests;